@@ -0,0 +1,125 @@
+package canvas
+
+import (
+	"image/color"
+	"math"
+	"sort"
+)
+
+// Axonometric is a parallel projection of three-dimensional space onto the canvas for technical illustrations and diagrams, given by the directions and lengths on the canvas of the unit vectors along the x, y, and z axes. The x and y axes span the ground and the z axis points up.
+type Axonometric struct {
+	X, Y, Z Point
+}
+
+// Isometric is the isometric projection where the x and y axes are drawn 30 degrees below the horizontal towards the left and right respectively, and the z axis vertically. Lengths along the axes are kept as is usual for isometric drawings, which are larger than the true projection by a factor of sqrt(3/2).
+var Isometric = Axonometric{
+	X: Point{-math.Sqrt(3.0) / 2.0, -0.5},
+	Y: Point{math.Sqrt(3.0) / 2.0, -0.5},
+	Z: Point{0.0, 1.0},
+}
+
+// Dimetric is the dimetric projection with a slope of 2:1 for the x and y axes, which is common in pixel art and games because lines on the ground align with the pixel grid.
+var Dimetric = Axonometric{
+	X: Point{-1.0, -0.5},
+	Y: Point{1.0, -0.5},
+	Z: Point{0.0, 1.0},
+}
+
+// Project returns the position on the canvas of the point (x,y,z).
+func (a Axonometric) Project(x, y, z float64) Point {
+	return a.X.Mul(x).Add(a.Y.Mul(y)).Add(a.Z.Mul(z))
+}
+
+// Top returns the transformation that draws onto the ground plane at height z, where x and y are along the x and y axes.
+func (a Axonometric) Top(z float64) Matrix {
+	return Matrix{
+		{a.X.X, a.Y.X, a.Z.X * z},
+		{a.X.Y, a.Y.Y, a.Z.Y * z},
+	}
+}
+
+// Front returns the transformation that draws onto the vertical plane at y, where x and y are along the x and z axes.
+func (a Axonometric) Front(y float64) Matrix {
+	return Matrix{
+		{a.X.X, a.Z.X, a.Y.X * y},
+		{a.X.Y, a.Z.Y, a.Y.Y * y},
+	}
+}
+
+// Side returns the transformation that draws onto the vertical plane at x, where x and y are along the y and z axes.
+func (a Axonometric) Side(x float64) Matrix {
+	return Matrix{
+		{a.Y.X, a.Z.X, a.X.X * x},
+		{a.Y.Y, a.Z.Y, a.X.Y * x},
+	}
+}
+
+// view returns the direction in space towards the viewer, which is projected onto a single point.
+func (a Axonometric) view() (float64, float64, float64) {
+	// cross product of the rows of the projection
+	x := a.Y.X*a.Z.Y - a.Z.X*a.Y.Y
+	y := a.Z.X*a.X.Y - a.X.X*a.Z.Y
+	z := a.X.X*a.Y.Y - a.Y.X*a.X.Y
+	if z < 0.0 {
+		return -x, -y, -z
+	}
+	return x, y, z
+}
+
+// extrudeShading is the brightness of faces that face up, along the x axis, and along the y axis respectively, so that the faces of a prism are distinguished as in isometric illustrations.
+var extrudeShading = [3]float64{1.0, 0.85, 0.65}
+
+// Extrude returns the visible faces of the path on the ground extruded upwards by height into a prism, and their colors shaded from col by their orientation. The faces are ordered from back to front so that drawing them in order hides the faces behind, with the face on top last. Outer contours must be counter clockwise and holes clockwise, such as by Settle. Curves on the sides are flattened using Tolerance.
+func (a Axonometric) Extrude(p *Path, height float64, col color.RGBA) ([]*Path, []color.RGBA) {
+	shade := func(f float64) color.RGBA {
+		return color.RGBA{uint8(float64(col.R)*f + 0.5), uint8(float64(col.G)*f + 0.5), uint8(float64(col.B)*f + 0.5), col.A}
+	}
+
+	type face struct {
+		path  *Path
+		depth float64
+		col   color.RGBA
+	}
+	faces := []face{}
+	vx, vy, vz := a.view()
+	for _, sp := range p.Flatten().Split() {
+		coords := sp.Coords()
+		if 1 < len(coords) && coords[0].Equals(coords[len(coords)-1]) {
+			coords = coords[:len(coords)-1]
+		}
+		for i := range coords {
+			p0, p1 := coords[i], coords[(i+1)%len(coords)]
+			d := p1.Sub(p0)
+			if d.IsZero() {
+				continue
+			}
+			n := Point{d.Y, -d.X}.Norm(1.0) // outward normal
+			if n.X*vx+n.Y*vy <= Epsilon {
+				continue // faces away
+			}
+			q0, q1 := a.Project(p0.X, p0.Y, 0.0), a.Project(p1.X, p1.Y, 0.0)
+			q2, q3 := a.Project(p1.X, p1.Y, height), a.Project(p0.X, p0.Y, height)
+			side := &Path{}
+			side.MoveTo(q0.X, q0.Y)
+			side.LineTo(q1.X, q1.Y)
+			side.LineTo(q2.X, q2.Y)
+			side.LineTo(q3.X, q3.Y)
+			side.Close()
+
+			m := p0.Interpolate(p1, 0.5)
+			f := math.Abs(n.X)*extrudeShading[1] + math.Abs(n.Y)*extrudeShading[2]
+			faces = append(faces, face{side, m.X*vx + m.Y*vy + height/2.0*vz, shade(f / (math.Abs(n.X) + math.Abs(n.Y)))})
+		}
+	}
+	sort.SliceStable(faces, func(i, j int) bool { return faces[i].depth < faces[j].depth })
+
+	paths := make([]*Path, 0, len(faces)+1)
+	colors := make([]color.RGBA, 0, len(faces)+1)
+	for _, f := range faces {
+		paths = append(paths, f.path)
+		colors = append(colors, f.col)
+	}
+	paths = append(paths, p.Transform(a.Top(math.Max(0.0, height))))
+	colors = append(colors, shade(extrudeShading[0]))
+	return paths, colors
+}
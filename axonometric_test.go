@@ -0,0 +1,43 @@
+package canvas
+
+import (
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestAxonometric(t *testing.T) {
+	c := math.Sqrt(3.0) / 2.0
+	test.T(t, Isometric.Project(1.0, 0.0, 0.0), Point{-c, -0.5})
+	test.T(t, Isometric.Project(1.0, 1.0, 1.0), Point{0.0, 0.0})
+	test.T(t, Dimetric.Project(2.0, 0.0, 1.0), Point{-2.0, 0.0})
+
+	test.T(t, Isometric.Top(2.0).Dot(Point{1.0, 3.0}), Isometric.Project(1.0, 3.0, 2.0))
+	test.T(t, Isometric.Front(2.0).Dot(Point{1.0, 3.0}), Isometric.Project(1.0, 2.0, 3.0))
+	test.T(t, Isometric.Side(2.0).Dot(Point{1.0, 3.0}), Isometric.Project(2.0, 1.0, 3.0))
+}
+
+func TestAxonometricExtrude(t *testing.T) {
+	c := math.Sqrt(3.0) / 2.0
+	red := color.RGBA{200, 100, 0, 255}
+	paths, colors := Isometric.Extrude(Rectangle(1.0, 1.0), 2.0, red)
+	test.T(t, len(paths), 3) // the faces along x and y and the top
+	test.T(t, len(colors), 3)
+	test.T(t, colors[0], color.RGBA{170, 85, 0, 255})
+	test.T(t, colors[1], color.RGBA{130, 65, 0, 255})
+	test.T(t, colors[2], red)
+	test.T(t, paths[0].Bounds(), Rect{-c, -1.0, c, 2.5})
+	test.T(t, paths[2].Bounds(), Rect{-c, 1.0, 2.0 * c, 1.0})
+
+	// the sides of the front half of a cylinder
+	paths, _ = Isometric.Extrude(Circle(1.0).Translate(3.0, 3.0), 1.0, red)
+	n := len(Circle(1.0).Flatten().Coords()) - 1
+	test.That(t, math.Abs(float64(len(paths)-1)-float64(n)/2.0) <= 1.0, len(paths))
+
+	// the hole of a tube shows the walls on its far side
+	tube := Rectangle(4.0, 4.0).Append(Rectangle(2.0, 2.0).Translate(1.0, 1.0).Reverse())
+	paths, _ = Isometric.Extrude(tube, 1.0, red)
+	test.T(t, len(paths), 5)
+}
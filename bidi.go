@@ -0,0 +1,614 @@
+package canvas
+
+import (
+	"sort"
+
+	"golang.org/x/text/unicode/bidi"
+)
+
+// TextDirection is the base direction of the paragraphs of a text, which determines the order in which runs of left-to-right and right-to-left text are displayed, see RichText.SetDirection.
+type TextDirection int
+
+// see TextDirection
+const (
+	AutoDirection TextDirection = iota // direction of the first strong character of each paragraph, or left-to-right if it has none
+	LeftToRight
+	RightToLeft
+)
+
+// maxBidiDepth is the maximum explicit embedding level.
+const maxBidiDepth = 125
+
+// bidiBrackets maps the opening to the closing brackets that are paired by the bidirectional algorithm.
+var bidiBrackets = map[rune]rune{}
+
+// bidiMirrors maps characters to their mirrored glyph in right-to-left text, such as brackets and guillemets.
+var bidiMirrors = map[rune]rune{
+	'<': '>', '>': '<',
+	'«': '»', '»': '«',
+	'‹': '›', '›': '‹',
+	'≤': '≥', '≥': '≤',
+	'≪': '≫', '≫': '≪',
+}
+
+func init() {
+	// pairs of opening and closing brackets, and ranges of consecutive pairs
+	pairs := [][2]rune{
+		{'(', ')'}, {'[', ']'}, {'{', '}'}, {0x2329, 0x232A}, {0x27C5, 0x27C6}, {0x29FC, 0x29FD},
+		{0xFF08, 0xFF09}, {0xFF3B, 0xFF3D}, {0xFF5B, 0xFF5D}, {0xFF5F, 0xFF60}, {0xFF62, 0xFF63},
+	}
+	for _, span := range [][2]rune{
+		{0x0F3A, 0x0F3D}, {0x169B, 0x169C}, {0x2045, 0x2046}, {0x207D, 0x207E}, {0x208D, 0x208E},
+		{0x2308, 0x230B}, {0x2768, 0x2775}, {0x27E6, 0x27EF}, {0x2983, 0x2998}, {0x29D8, 0x29DB},
+		{0x2E22, 0x2E29}, {0x3008, 0x3011}, {0x3014, 0x301B}, {0xFE59, 0xFE5E},
+	} {
+		for r := span[0]; r < span[1]; r += 2 {
+			pairs = append(pairs, [2]rune{r, r + 1})
+		}
+	}
+	for _, pair := range pairs {
+		bidiBrackets[pair[0]] = pair[1]
+		bidiMirrors[pair[0]] = pair[1]
+		bidiMirrors[pair[1]] = pair[0]
+	}
+}
+
+func bidiClass(r rune) bidi.Class {
+	props, _ := bidi.LookupRune(r)
+	return props.Class()
+}
+
+func isBidiIsolateInitiator(c bidi.Class) bool {
+	return c == bidi.LRI || c == bidi.RLI || c == bidi.FSI
+}
+
+// isBidiRemoved returns true for the explicit embedding characters and boundary neutrals, which are ignored when resolving the levels of the other characters.
+func isBidiRemoved(c bidi.Class) bool {
+	return c == bidi.RLE || c == bidi.LRE || c == bidi.RLO || c == bidi.LRO || c == bidi.PDF || c == bidi.BN
+}
+
+// hasBidi returns true if the text has characters that are displayed right-to-left or that change the direction, which makes a left-to-right paragraph differ from its logical order.
+func hasBidi(s string) bool {
+	for _, r := range s {
+		switch bidiClass(r) {
+		case bidi.R, bidi.AL, bidi.AN, bidi.RLE, bidi.RLO, bidi.RLI, bidi.FSI:
+			return true
+		}
+	}
+	return false
+}
+
+// bidiFirstStrong returns the level of the direction of the first strong character that is not inside an isolate, or -1 if there is none.
+func bidiFirstStrong(runes []rune) int {
+	isolates := 0
+	for _, r := range runes {
+		switch bidiClass(r) {
+		case bidi.L:
+			if isolates == 0 {
+				return 0
+			}
+		case bidi.R, bidi.AL:
+			if isolates == 0 {
+				return 1
+			}
+		case bidi.LRI, bidi.RLI, bidi.FSI:
+			isolates++
+		case bidi.PDI:
+			if 0 < isolates {
+				isolates--
+			}
+		case bidi.B:
+			return -1
+		}
+	}
+	return -1
+}
+
+// bidiParagraphLevel returns the embedding level of a paragraph, which is odd for right-to-left paragraphs.
+func bidiParagraphLevel(s string, dir TextDirection) uint8 {
+	if dir == RightToLeft || dir == AutoDirection && bidiFirstStrong([]rune(s)) == 1 {
+		return 1
+	}
+	return 0
+}
+
+// bidiLevels returns the embedding levels of the runes of a line of a paragraph following the Unicode Bidirectional Algorithm (UAX #9), for the explicit embeddings, overrides, and isolates, the resolution of weak and neutral types including bracket pairs, and the reset of trailing whitespace. The resolution is restricted to the line instead of the paragraph.
+func bidiLevels(runes []rune, paragraph uint8) []uint8 {
+	n := len(runes)
+	original := make([]bidi.Class, n)
+	for i, r := range runes {
+		original[i] = bidiClass(r)
+	}
+	classes := make([]bidi.Class, n)
+	copy(classes, original)
+
+	// match isolate initiators and their PDIs (BD9)
+	matches := make([]int, n)
+	isolates := []int{}
+	for i, c := range original {
+		matches[i] = -1
+		if isBidiIsolateInitiator(c) {
+			isolates = append(isolates, i)
+		} else if c == bidi.PDI && 0 < len(isolates) {
+			j := isolates[len(isolates)-1]
+			isolates = isolates[:len(isolates)-1]
+			matches[i], matches[j] = j, i
+		} else if c == bidi.B {
+			isolates = isolates[:0]
+		}
+	}
+
+	// explicit levels and directions (X1-X8)
+	type status struct {
+		level    uint8
+		override bidi.Class // ON when not overridden
+		isolate  bool
+	}
+	levels := make([]uint8, n)
+	stack := []status{{paragraph, bidi.ON, false}}
+	overflowIsolates, overflowEmbeddings, validIsolates := 0, 0, 0
+	for i, c := range original {
+		top := stack[len(stack)-1]
+		levels[i] = top.level
+		switch c {
+		case bidi.RLE, bidi.LRE, bidi.RLO, bidi.LRO:
+			level := nextBidiLevel(top.level, c == bidi.RLE || c == bidi.RLO)
+			if level <= maxBidiDepth && overflowIsolates == 0 && overflowEmbeddings == 0 {
+				override := bidi.ON
+				if c == bidi.RLO {
+					override = bidi.R
+				} else if c == bidi.LRO {
+					override = bidi.L
+				}
+				stack = append(stack, status{level, override, false})
+			} else if overflowIsolates == 0 {
+				overflowEmbeddings++
+			}
+		case bidi.RLI, bidi.LRI, bidi.FSI:
+			if top.override != bidi.ON {
+				classes[i] = top.override
+			}
+			rtl := c == bidi.RLI
+			if c == bidi.FSI {
+				end := n
+				if matches[i] != -1 {
+					end = matches[i]
+				}
+				rtl = bidiFirstStrong(runes[i+1:end]) == 1
+			}
+			level := nextBidiLevel(top.level, rtl)
+			if level <= maxBidiDepth && overflowIsolates == 0 && overflowEmbeddings == 0 {
+				validIsolates++
+				stack = append(stack, status{level, bidi.ON, true})
+			} else {
+				overflowIsolates++
+			}
+		case bidi.PDI:
+			if 0 < overflowIsolates {
+				overflowIsolates--
+			} else if 0 < validIsolates {
+				overflowEmbeddings = 0
+				for !stack[len(stack)-1].isolate {
+					stack = stack[:len(stack)-1]
+				}
+				stack = stack[:len(stack)-1]
+				validIsolates--
+			}
+			top = stack[len(stack)-1]
+			levels[i] = top.level
+			if top.override != bidi.ON {
+				classes[i] = top.override
+			}
+		case bidi.PDF:
+			if 0 < overflowIsolates {
+				// ignored
+			} else if 0 < overflowEmbeddings {
+				overflowEmbeddings--
+			} else if !top.isolate && 2 <= len(stack) {
+				stack = stack[:len(stack)-1]
+			}
+		case bidi.B:
+			levels[i] = paragraph
+		case bidi.BN:
+		default:
+			if top.override != bidi.ON {
+				classes[i] = top.override
+			}
+		}
+	}
+
+	// level runs of the characters that are not removed (X9), which are joined into isolating run sequences (X10)
+	runs := [][]int{}
+	runAt := map[int]int{}
+	prev := -1
+	for i, c := range original {
+		if isBidiRemoved(c) {
+			continue
+		}
+		if prev == -1 || levels[prev] != levels[i] {
+			runAt[i] = len(runs)
+			runs = append(runs, []int{})
+		}
+		runs[len(runs)-1] = append(runs[len(runs)-1], i)
+		prev = i
+	}
+	for _, run := range runs {
+		if original[run[0]] == bidi.PDI && matches[run[0]] != -1 {
+			continue // continues the sequence of its isolate initiator
+		}
+		seq := append([]int{}, run...)
+		for {
+			last := seq[len(seq)-1]
+			if isBidiIsolateInitiator(original[last]) && matches[last] != -1 {
+				if k, ok := runAt[matches[last]]; ok {
+					seq = append(seq, runs[k]...)
+					continue
+				}
+			}
+			break
+		}
+		resolveBidiSequence(runes, original, classes, levels, seq, paragraph)
+	}
+
+	// removed characters take the level of the preceding character, and whitespace at the end of the line and before separators is reset to the paragraph level (L1)
+	for i, c := range original {
+		if isBidiRemoved(c) {
+			levels[i] = paragraph
+			if 0 < i {
+				levels[i] = levels[i-1]
+			}
+		}
+	}
+	trailing := true
+	for i := n - 1; 0 <= i; i-- {
+		switch c := original[i]; c {
+		case bidi.S, bidi.B:
+			levels[i] = paragraph
+			trailing = true
+		case bidi.WS, bidi.LRI, bidi.RLI, bidi.FSI, bidi.PDI:
+			if trailing {
+				levels[i] = paragraph
+			}
+		default:
+			if trailing && isBidiRemoved(c) {
+				levels[i] = paragraph
+			} else {
+				trailing = false
+			}
+		}
+	}
+	return levels
+}
+
+// nextBidiLevel returns the least odd level for right-to-left or the least even level for left-to-right that is greater than level.
+func nextBidiLevel(level uint8, rtl bool) uint8 {
+	if rtl {
+		return (level + 1) | 1
+	}
+	return (level + 2) &^ 1
+}
+
+func bidiDirection(level uint8) bidi.Class {
+	if level%2 == 1 {
+		return bidi.R
+	}
+	return bidi.L
+}
+
+// bidiStrong returns the strong direction of a resolved type, where numbers are right-to-left, or ON for neutrals.
+func bidiStrong(c bidi.Class) bidi.Class {
+	switch c {
+	case bidi.L:
+		return bidi.L
+	case bidi.R, bidi.AL, bidi.EN, bidi.AN:
+		return bidi.R
+	}
+	return bidi.ON
+}
+
+// resolveBidiSequence resolves the weak types (W1-W7), the bracket pairs (N0), the neutral types (N1-N2), and the implicit levels (I1-I2) of an isolating run sequence.
+func resolveBidiSequence(runes []rune, original, classes []bidi.Class, levels []uint8, seq []int, paragraph uint8) {
+	level := levels[seq[0]]
+	before, after := paragraph, paragraph
+	for i := seq[0] - 1; 0 <= i; i-- {
+		if !isBidiRemoved(original[i]) {
+			before = levels[i]
+			break
+		}
+	}
+	if last := seq[len(seq)-1]; !isBidiIsolateInitiator(original[last]) {
+		for i := last + 1; i < len(original); i++ {
+			if !isBidiRemoved(original[i]) {
+				after = levels[i]
+				break
+			}
+		}
+	}
+	if before < level {
+		before = level
+	}
+	if after < level {
+		after = level
+	}
+	sos, eos := bidiDirection(before), bidiDirection(after)
+	embedding := bidiDirection(level)
+
+	types := make([]bidi.Class, len(seq))
+	for k, i := range seq {
+		types[k] = classes[i]
+	}
+
+	// W1: non-spacing marks take the type of the preceding character
+	prevType := sos
+	for k, c := range types {
+		if c == bidi.NSM {
+			if isBidiIsolateInitiator(prevType) || prevType == bidi.PDI {
+				types[k] = bidi.ON
+			} else {
+				types[k] = prevType
+			}
+		}
+		prevType = types[k]
+	}
+
+	// W2 and W3: European numbers after Arabic letters are Arabic numbers, and Arabic letters are right-to-left
+	strong := sos
+	for k, c := range types {
+		switch c {
+		case bidi.L, bidi.R, bidi.AL:
+			strong = c
+		case bidi.EN:
+			if strong == bidi.AL {
+				types[k] = bidi.AN
+			}
+		}
+	}
+	for k, c := range types {
+		if c == bidi.AL {
+			types[k] = bidi.R
+		}
+	}
+
+	// W4: single separators between numbers of the same type
+	for k := 1; k+1 < len(types); k++ {
+		if types[k] == bidi.ES && types[k-1] == bidi.EN && types[k+1] == bidi.EN {
+			types[k] = bidi.EN
+		} else if types[k] == bidi.CS && (types[k-1] == bidi.EN || types[k-1] == bidi.AN) && types[k+1] == types[k-1] {
+			types[k] = types[k-1]
+		}
+	}
+
+	// W5 and W6: terminators adjacent to European numbers, and remaining separators and terminators are neutral
+	for k := 0; k < len(types); k++ {
+		if types[k] != bidi.ET {
+			continue
+		}
+		j := k
+		for j < len(types) && types[j] == bidi.ET {
+			j++
+		}
+		c := bidi.ON
+		if 0 < k && types[k-1] == bidi.EN || j < len(types) && types[j] == bidi.EN {
+			c = bidi.EN
+		}
+		for ; k < j; k++ {
+			types[k] = c
+		}
+		k--
+	}
+	for k, c := range types {
+		if c == bidi.ES || c == bidi.ET || c == bidi.CS {
+			types[k] = bidi.ON
+		}
+	}
+
+	// W7: European numbers in left-to-right context are left-to-right
+	strong = sos
+	for k, c := range types {
+		switch c {
+		case bidi.L, bidi.R:
+			strong = c
+		case bidi.EN:
+			if strong == bidi.L {
+				types[k] = bidi.L
+			}
+		}
+	}
+
+	// N0: paired brackets take the direction of their content, or of their context if the content has the opposite direction
+	type pair struct{ open, close int }
+	pairs := []pair{}
+	type opening struct {
+		close rune
+		k     int
+	}
+	openings := []opening{}
+Brackets:
+	for k, i := range seq {
+		if types[k] != bidi.ON {
+			continue
+		}
+		r := runes[i]
+		if r == 0x3008 || r == 0x3009 {
+			r -= 0x3008 - 0x2329 // canonical equivalents of the angle brackets
+		}
+		if close, ok := bidiBrackets[r]; ok {
+			if len(openings) == 63 {
+				break Brackets
+			}
+			openings = append(openings, opening{close, k})
+		} else {
+			for j := len(openings) - 1; 0 <= j; j-- {
+				if openings[j].close == r {
+					pairs = append(pairs, pair{openings[j].k, k})
+					openings = openings[:j]
+					break
+				}
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].open < pairs[j].open })
+	for _, p := range pairs {
+		found := bidi.ON
+		for k := p.open + 1; k < p.close; k++ {
+			if c := bidiStrong(types[k]); c == embedding {
+				found = embedding
+				break
+			} else if c != bidi.ON {
+				found = c
+			}
+		}
+		if found == bidi.ON {
+			continue
+		} else if found != embedding {
+			context := sos
+			for k := p.open - 1; 0 <= k; k-- {
+				if c := bidiStrong(types[k]); c != bidi.ON {
+					context = c
+					break
+				}
+			}
+			if context != found {
+				found = embedding
+			}
+		}
+		for _, k := range []int{p.open, p.close} {
+			types[k] = found
+			for k++; k < len(types) && original[seq[k]] == bidi.NSM; k++ {
+				types[k] = found
+			}
+		}
+	}
+
+	// N1 and N2: neutrals between characters of the same direction take that direction, and otherwise the embedding direction
+	for k := 0; k < len(types); {
+		switch types[k] {
+		case bidi.B, bidi.S, bidi.WS, bidi.ON, bidi.LRI, bidi.RLI, bidi.FSI, bidi.PDI:
+		default:
+			k++
+			continue
+		}
+		j := k
+		for j < len(types) {
+			c := types[j]
+			if c != bidi.B && c != bidi.S && c != bidi.WS && c != bidi.ON && !isBidiIsolateInitiator(c) && c != bidi.PDI {
+				break
+			}
+			j++
+		}
+		start, end := sos, eos
+		if 0 < k {
+			start = bidiStrong(types[k-1])
+		}
+		if j < len(types) {
+			end = bidiStrong(types[j])
+		}
+		c := embedding
+		if start == end {
+			c = start
+		}
+		for ; k < j; k++ {
+			types[k] = c
+		}
+	}
+
+	// I1 and I2: implicit levels
+	for k, i := range seq {
+		c := types[k]
+		if levels[i]%2 == 0 {
+			if c == bidi.R {
+				levels[i]++
+			} else if c == bidi.AN || c == bidi.EN {
+				levels[i] += 2
+			}
+		} else if c == bidi.L || c == bidi.EN || c == bidi.AN {
+			levels[i]++
+		}
+	}
+}
+
+// bidiSplit splits the spans of a line into runs of the same embedding level, where the line is part of a paragraph with the given embedding level.
+func bidiSplit(spans []textSpan, paragraph uint8) []textSpan {
+	text := ""
+	for _, span := range spans {
+		text += span.altText
+	}
+	if paragraph == 0 && !hasBidi(text) {
+		return spans
+	}
+
+	runes := []rune{}
+	for _, r := range text {
+		runes = append(runes, r)
+	}
+	levels := bidiLevels(runes, paragraph)
+
+	k := 0 // index into levels
+	split := make([]textSpan, 0, len(spans))
+	for _, span := range spans {
+		if span.altText == "" {
+			split = append(split, span)
+			continue
+		}
+		start, n := 0, 0
+		dx := span.dx
+		for i := range span.altText {
+			if n != 0 && levels[k] != levels[k-1] {
+				piece := newTextSpan(span.ff, span.altText[:i], start)
+				piece.dx, piece.level = dx, levels[k-1]
+				split = append(split, piece)
+				dx += piece.width
+				start = i
+			}
+			k++
+			n++
+		}
+		if start == 0 {
+			span.level = levels[k-1]
+			split = append(split, span)
+		} else if start < len(span.altText) {
+			piece := newTextSpan(span.ff, span.altText, start)
+			piece.dx, piece.level = dx, levels[k-1]
+			split = append(split, piece)
+		}
+	}
+	return split
+}
+
+// bidiReorder reorders the spans of a line from logical to visual order by reversing the runs at each level down to the lowest odd level (L2), and places them consecutively from the start of the line.
+func bidiReorder(spans []textSpan) {
+	highest, lowest := uint8(0), uint8(maxBidiDepth+1)
+	for _, span := range spans {
+		if highest < span.level {
+			highest = span.level
+		}
+		if span.level < lowest {
+			lowest = span.level
+		}
+	}
+	if highest == 0 {
+		return
+	}
+
+	x := spans[0].dx
+	for level := highest; lowest|1 <= level; level-- {
+		for i := 0; i < len(spans); {
+			if spans[i].level < level {
+				i++
+				continue
+			}
+			j := i
+			for j < len(spans) && level <= spans[j].level {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				spans[a], spans[b] = spans[b], spans[a]
+			}
+			i = j
+		}
+	}
+	for i := range spans {
+		spans[i].dx = x
+		x += spans[i].width
+	}
+}
@@ -0,0 +1,112 @@
+package canvas
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+)
+
+// Bitmap is a 1-bit raster image packed as eight pixels per byte, with the left-most pixel in the most significant bit and every row padded to whole bytes, where set bits are black dots. This is the format of thermal printers and of many e-ink displays.
+type Bitmap struct {
+	Width, Height int
+	Stride        int // number of bytes per row
+	Pix           []byte
+}
+
+// NewBitmap converts an image to black and white dots using the dithering.
+func NewBitmap(img image.Image, dithering Dithering) *Bitmap {
+	bounds := img.Bounds()
+	paletted := image.NewPaletted(bounds, GrayPalette(2))
+	dithering.Drawer().Draw(paletted, bounds, img, bounds.Min)
+	return newBitmap(paletted)
+}
+
+// newBitmap packs a paletted image of which the palette is black and white.
+func newBitmap(paletted *image.Paletted) *Bitmap {
+	bounds := paletted.Bounds()
+	b := &Bitmap{
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+		Stride: (bounds.Dx() + 7) / 8,
+	}
+	b.Pix = make([]byte, b.Stride*b.Height)
+	for y := 0; y < b.Height; y++ {
+		for x := 0; x < b.Width; x++ {
+			if paletted.ColorIndexAt(bounds.Min.X+x, bounds.Min.Y+y) == 0 {
+				b.Pix[y*b.Stride+x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+	}
+	return b
+}
+
+// At returns true if the pixel at (x,y), with the origin in the top-left, is a black dot.
+func (b *Bitmap) At(x, y int) bool {
+	if x < 0 || b.Width <= x || y < 0 || b.Height <= y {
+		return false
+	}
+	return b.Pix[y*b.Stride+x/8]&(0x80>>uint(x%8)) != 0
+}
+
+// Invert inverts all dots, as is needed for displays where set bits are white.
+func (b *Bitmap) Invert() {
+	for i := range b.Pix {
+		b.Pix[i] = ^b.Pix[i]
+	}
+}
+
+// WriteBitmapBands rasterizes the canvas with given DPM (dots-per-millimeter) into 1-bit bands of at most height rows from top to bottom, and calls f for each band with the row of the drawing at which it starts. Only one band is rasterized at a time, so that long drawings such as receipts use little memory. Ordered dithering continues across bands, while error diffusion starts anew for each band.
+func (c *Canvas) WriteBitmapBands(dpm float64, height int, dithering Dithering, f func(y int, band *Bitmap) error) error {
+	if height <= 0 {
+		return fmt.Errorf("band height must be positive")
+	}
+	w, h := int(c.W*dpm+0.5), int(c.H*dpm+0.5)
+	tile := image.NewRGBA(image.Rect(0, 0, w, height))
+	for y0 := 0; y0 < h; y0 += height {
+		draw.Draw(tile, tile.Bounds(), image.NewUniform(White), image.Point{}, draw.Src)
+
+		// band position in mm, the Y-axis points up
+		y := float64(h-y0-height) / dpm
+		view := Identity.Translate(0.0, -y)
+		ras := NewRasterizer(tile, dpm)
+		for _, k := range c.Query(Rect{0.0, y, c.W, float64(height) / dpm}) {
+			c.layers[k].render(ras, view)
+		}
+
+		// dither at the position of the band in the drawing
+		bounds := image.Rect(0, y0, w, y0+height)
+		if h < bounds.Max.Y {
+			bounds.Max.Y = h
+		}
+		paletted := image.NewPaletted(bounds, GrayPalette(2))
+		dithering.Drawer().Draw(paletted, bounds, tile, image.Point{})
+		if err := f(y0, newBitmap(paletted)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteESCPOS rasterizes the canvas with given DPM (dots-per-millimeter) and prints it on a thermal printer as ESC/POS raster bit images (GS v 0), in bands of at most height rows that are rasterized one at a time, see WriteBitmapBands. The width of the canvas should fit the printer, which is typically 384 dots for 58mm and 576 dots for 80mm paper at 8 dots per millimeter (203 DPI). The printer is not initialized and the paper is not cut.
+func (c *Canvas) WriteESCPOS(w io.Writer, dpm float64, height int, dithering Dithering) error {
+	if 2303 < height {
+		return fmt.Errorf("band height must be at most 2303 for ESC/POS")
+	}
+	return c.WriteBitmapBands(dpm, height, dithering, func(_ int, band *Bitmap) error {
+		return band.WriteESCPOS(w)
+	})
+}
+
+// WriteESCPOS writes the bitmap as an ESC/POS raster bit image (GS v 0) at normal density.
+func (b *Bitmap) WriteESCPOS(w io.Writer) error {
+	if 0xFFFF < b.Stride || 0xFFFF < b.Height {
+		return fmt.Errorf("bitmap too large for ESC/POS")
+	}
+	header := []byte{0x1D, 'v', '0', 0, byte(b.Stride), byte(b.Stride >> 8), byte(b.Height), byte(b.Height >> 8)}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(b.Pix)
+	return err
+}
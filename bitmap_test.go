@@ -0,0 +1,58 @@
+package canvas
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestBitmap(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 10, 2))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	img.SetGray(0, 0, color.Gray{0})
+	img.SetGray(9, 1, color.Gray{0})
+
+	b := NewBitmap(img, NoDithering)
+	test.T(t, b.Stride, 2)
+	test.Bytes(t, b.Pix, []byte{0x80, 0x00, 0x00, 0x40})
+	test.That(t, b.At(0, 0) && b.At(9, 1) && !b.At(1, 0) && !b.At(10, 1), "dots")
+
+	buf := &bytes.Buffer{}
+	test.Error(t, b.WriteESCPOS(buf))
+	test.Bytes(t, buf.Bytes(), []byte{0x1D, 'v', '0', 0, 2, 0, 2, 0, 0x80, 0x00, 0x00, 0x40})
+
+	b.Invert()
+	test.Bytes(t, b.Pix, []byte{0x7F, 0xFF, 0xFF, 0xBF})
+}
+
+func TestWriteBitmapBands(t *testing.T) {
+	// a black rectangle at the bottom of a drawing of 16 by 20 dots
+	c := New(16.0, 20.0)
+	ctx := NewContext(c)
+	ctx.DrawPath(0.0, 0.0, Rectangle(8.0, 4.0))
+
+	ys := []int{}
+	bands := []*Bitmap{}
+	test.Error(t, c.WriteBitmapBands(1.0, 8, NoDithering, func(y int, band *Bitmap) error {
+		ys = append(ys, y)
+		bands = append(bands, band)
+		return nil
+	}))
+	test.T(t, ys, []int{0, 8, 16})
+	test.T(t, bands[2].Height, 4) // the last band is shorter
+	test.That(t, !bands[0].At(0, 0) && !bands[1].At(0, 7), "white")
+	test.That(t, bands[2].At(0, 0) && bands[2].At(7, 3) && !bands[2].At(8, 3), "black rectangle")
+
+	buf := &bytes.Buffer{}
+	test.Error(t, c.WriteESCPOS(buf, 1.0, 8, OrderedDithering))
+	test.T(t, bytes.Count(buf.Bytes(), []byte{0x1D, 'v', '0', 0, 2, 0}), 3)
+	test.T(t, buf.Len(), 3*8+2*(8*2)+4*2)
+
+	test.That(t, c.WriteBitmapBands(1.0, 0, NoDithering, nil) != nil, "band height")
+	test.That(t, c.WriteESCPOS(buf, 1.0, 3000, NoDithering) != nil, "band height for ESC/POS")
+}
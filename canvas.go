@@ -6,8 +6,11 @@ import (
 	"image/draw"
 	"image/gif"
 	"image/jpeg"
-	"image/png"
+	"math"
 	"os"
+	"sort"
+	"sync"
+	"time"
 )
 
 const mmPerPt = 0.3527777777777778
@@ -36,6 +39,10 @@ type Style struct {
 	DashOffset   float64
 	Dashes       []float64
 	FillRule
+	FillSpot   *SpotColor    // printed instead of FillColor by PDF and EPS when set
+	StrokeSpot *SpotColor    // printed instead of StrokeColor by PDF and EPS when set
+	FillMesh   *MeshGradient // painted instead of FillColor when set, see Context.SetFillMesh
+	Overprint  bool          // fills and strokes overprint the inks underneath for PDF and EPS
 }
 
 // DefaultStyle is the default style for paths. It fills the path with a black color.
@@ -69,11 +76,13 @@ type Context struct {
 	styleStack []Style
 	view       Matrix
 	viewStack  []Matrix
+	theme      Theme
+	link       string
 }
 
 // NewContext returns a new Context which is a wrapper around a Renderer. Context maintains state for the current path, path style, and view transformation matrix.
 func NewContext(r Renderer) *Context {
-	return &Context{r, &Path{}, DefaultStyle, nil, Identity, nil}
+	return &Context{r, &Path{}, DefaultStyle, nil, Identity, nil, LightTheme, ""}
 }
 
 // Width returns the width of the canvas.
@@ -180,16 +189,36 @@ func (c *Context) ShearAbout(sx, sy, x, y float64) {
 	c.view = c.view.Mul(Identity.ShearAbout(sx, sy, x, y))
 }
 
+// UserToDevice converts the point (x,y) in the current view to device pixels at a resolution of dpm (dots-per-millimeter), which have their origin at the top-left of the canvas and their y-axis pointing down as in the images of the rasterizer.
+func (c *Context) UserToDevice(x, y, dpm float64) (float64, float64) {
+	p := c.deviceView(dpm).Dot(Point{x, y})
+	return p.X, p.Y
+}
+
+// DeviceToUser converts the point (x,y) in device pixels at a resolution of dpm (dots-per-millimeter) to the current view, eg. to hit test a mouse position or to annotate a pixel of a rendered image, see UserToDevice.
+func (c *Context) DeviceToUser(x, y, dpm float64) (float64, float64) {
+	p := c.deviceView(dpm).Inv().Dot(Point{x, y})
+	return p.X, p.Y
+}
+
+// deviceView returns the transformation from the current view to device pixels.
+func (c *Context) deviceView(dpm float64) Matrix {
+	return Identity.Translate(0.0, c.Height()*dpm).Scale(dpm, -dpm).Mul(c.view)
+}
+
 // SetFillColor sets the color to be used for filling operations.
 func (c *Context) SetFillColor(col color.Color) {
 	r, g, b, a := col.RGBA()
 	c.Style.FillColor = color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+	c.Style.FillSpot = nil
+	c.Style.FillMesh = nil
 }
 
 // SetStrokeColor sets the color to be used for stroking operations.
 func (c *Context) SetStrokeColor(col color.Color) {
 	r, g, b, a := col.RGBA()
 	c.Style.StrokeColor = color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+	c.Style.StrokeSpot = nil
 }
 
 // SetStrokeWidth sets the width in mm for stroking operations.
@@ -218,9 +247,82 @@ func (c *Context) SetFillRule(rule FillRule) {
 	c.Style.FillRule = rule
 }
 
-// ResetStyle resets the draw state to its default (colors, stroke widths, dashes, ...).
+// ResetStyle resets the draw state to the default of the theme (colors, stroke widths, dashes, ...).
 func (c *Context) ResetStyle() {
-	c.Style = DefaultStyle
+	c.Style = c.theme.Style()
+}
+
+// SetTheme sets the theme that styles are resolved against and resets the style to that of the theme, the default is LightTheme.
+func (c *Context) SetTheme(theme Theme) {
+	c.theme = theme
+	c.Style = theme.Style()
+}
+
+// Theme returns the theme of the context, see SetTheme.
+func (c *Context) Theme() Theme {
+	return c.theme
+}
+
+// DrawBackground fills the whole canvas with the background color of the theme.
+func (c *Context) DrawBackground() {
+	w, h := c.Size()
+	style := DefaultStyle
+	style.FillColor = c.theme.Background
+	c.RenderPath(Rectangle(w, h), style, Identity)
+}
+
+// anchorer is a renderer that registers named points, see Canvas.SetAnchor.
+type anchorer interface {
+	SetAnchor(string, float64, float64)
+	Anchor(string) (Point, bool)
+}
+
+// SetAnchor registers a named point at (x,y) in the current view, which is retrieved in canvas coordinates by Anchor, eg. to connect elements that were drawn before. It does nothing if the renderer does not register anchors, which only Canvas does.
+func (c *Context) SetAnchor(name string, x, y float64) {
+	if r, ok := c.Renderer.(anchorer); ok {
+		p := c.view.Dot(Point{x, y})
+		r.SetAnchor(name, p.X, p.Y)
+	}
+}
+
+// SetAnchors registers named points relative to position (x,y) in the current view, such as the ports of a shape drawn by DrawPath at the same position, see SetAnchor.
+func (c *Context) SetAnchors(x, y float64, anchors map[string]Point) {
+	for name, p := range anchors {
+		c.SetAnchor(name, x+p.X, y+p.Y)
+	}
+}
+
+// Anchor returns the named point in canvas coordinates, or false if it does not exist or if the renderer does not register anchors, see SetAnchor. Use View().Inv() to convert it to the coordinates of the current view.
+func (c *Context) Anchor(name string) (Point, bool) {
+	if r, ok := c.Renderer.(anchorer); ok {
+		return r.Anchor(name)
+	}
+	return Point{}, false
+}
+
+// linker is a renderer that attaches hyperlinks to the elements that are rendered after setLink, where bounds are those of the next element in the coordinates of the renderer. An empty URL ends the link.
+type linker interface {
+	setLink(url string, bounds Rect)
+}
+
+// SetLink attaches a hyperlink to the URL to all elements that are drawn after it, until it is set to an empty URL. Links are written as <a> elements in SVG and as link annotations over the bounds of the elements in PDF, while Canvas keeps them with its layers, see Canvas.LinkRegions for raster output. Other renderers ignore links.
+func (c *Context) SetLink(url string) {
+	c.link = url
+}
+
+// RenderPath renders a path to the renderer with the link of the context, see SetLink.
+func (c *Context) RenderPath(path *Path, style Style, m Matrix) {
+	layer{path: path, m: m, style: style, link: c.link}.render(c.Renderer, Identity)
+}
+
+// RenderText renders a text object to the renderer with the link of the context, see SetLink.
+func (c *Context) RenderText(text *Text, m Matrix) {
+	layer{text: text, m: m, link: c.link}.render(c.Renderer, Identity)
+}
+
+// RenderImage renders an image to the renderer with the link of the context, see SetLink.
+func (c *Context) RenderImage(img image.Image, m Matrix) {
+	layer{img: img, m: m, link: c.link}.render(c.Renderer, Identity)
 }
 
 // Pos returns the current position of the path, which is the end point of the last command.
@@ -312,24 +414,28 @@ func (c *Context) DrawPath(x, y float64, paths ...*Path) {
 	}
 }
 
-// DrawText draws text at position (x,y) using the current draw state. In particular, it only uses the current affine transformation matrix.
+// DrawText draws text at position (x,y) using the current draw state. In particular, it only uses the current affine transformation matrix. Text effects of the font faces are drawn behind the text, see FontFace.WithEffects.
 func (c *Context) DrawText(x, y float64, texts ...*Text) {
 	m := c.view.Translate(x, y)
 	for _, text := range texts {
 		if text.Empty() {
 			continue
 		}
+		text.renderEffects(c.Renderer, m)
 		c.RenderText(text, m)
 	}
 }
 
-// DrawImage draws an image at position (x,y), using an image encoding (Lossy or Lossless) and DPM (dots-per-millimeter). A higher DPM will draw a smaller image.
+// DrawImage draws an image at position (x,y), using an image encoding (Lossy or Lossless) and DPM (dots-per-millimeter). A higher DPM will draw a smaller image. A JPEGImage is drawn upright according to its EXIF orientation, so that its width and height are swapped when it is rotated by 90 degrees.
 func (c *Context) DrawImage(x, y float64, img image.Image, dpm float64) {
 	if img.Bounds().Size().Eq(image.Point{}) {
 		return
 	}
 
 	m := c.view.Translate(x, y).Scale(1.0/dpm, 1.0/dpm)
+	if jpg, ok := img.(*JPEGImage); ok {
+		m = m.Mul(jpg.orientation())
+	}
 	c.RenderImage(img, m)
 }
 
@@ -344,13 +450,47 @@ type layer struct {
 	img  image.Image
 
 	m     Matrix
-	style Style // only for path
+	style Style  // only for path
+	link  string // URL of a hyperlink, see Context.SetLink
+}
+
+// Bounds returns the bounding box of the layer in canvas coordinates, including the stroke width for paths.
+func (l layer) Bounds() Rect {
+	bounds := Rect{}
+	if l.path != nil {
+		bounds = l.path.Bounds()
+		if l.style.StrokeColor.A != 0 && 0.0 < l.style.StrokeWidth {
+			bounds.X -= l.style.StrokeWidth / 2.0
+			bounds.Y -= l.style.StrokeWidth / 2.0
+			bounds.W += l.style.StrokeWidth
+			bounds.H += l.style.StrokeWidth
+		}
+	} else if l.text != nil {
+		bounds = l.text.Bounds()
+	} else if l.img != nil {
+		size := l.img.Bounds().Size()
+		bounds = Rect{0.0, 0.0, float64(size.X), float64(size.Y)}
+	}
+	return bounds.Transform(l.m)
 }
 
-// Canvas stores all drawing operations as layers that can be re-rendered to other renderers.
+// Canvas stores all drawing operations as layers that can be re-rendered to other renderers. Drawing to a canvas is safe for concurrent use, but rendering or exporting it is not. To draw from multiple goroutines in a deterministic order, use NewLayer.
 type Canvas struct {
-	layers []layer
-	W, H   float64
+	mu      sync.Mutex
+	layers  []layer
+	groups  []canvasGroup    // layers drawn independently, merged on export
+	index   *Quadtree        // spatial index of layers, built on first query
+	dirty   []Rect           // areas changed since the last redraw
+	track   bool             // new layers are added to dirty, from the first redraw on
+	shared  bool             // layers are referenced by a snapshot, copy before changing in place
+	anchors map[string]Point // named points in canvas coordinates, see SetAnchor
+	link    string           // of the layers that are added
+	W, H    float64
+}
+
+type canvasGroup struct {
+	pos    int // position in layers at which it is merged
+	canvas *Canvas
 }
 
 // New returns a new Canvas that records all drawing operations into layers. The canvas can then be rendered to any other renderer.
@@ -370,31 +510,172 @@ func (c *Canvas) Size() (float64, float64) {
 // RenderPath renders a path to the canvas using a style and a transformation matrix.
 func (c *Canvas) RenderPath(path *Path, style Style, m Matrix) {
 	path = path.Copy()
-	c.layers = append(c.layers, layer{path: path, m: m, style: style})
+	c.addLayer(layer{path: path, m: m, style: style})
 }
 
 // RenderText renders a text object to the canvas using a transformation matrix.
 func (c *Canvas) RenderText(text *Text, m Matrix) {
-	c.layers = append(c.layers, layer{text: text, m: m})
+	c.addLayer(layer{text: text, m: m})
 }
 
 // RenderImage renders an image to the canvas using a transformation matrix.
 func (c *Canvas) RenderImage(img image.Image, m Matrix) {
-	c.layers = append(c.layers, layer{img: img, m: m})
+	c.addLayer(layer{img: img, m: m})
+}
+
+func (c *Canvas) addLayer(l layer) {
+	c.mu.Lock()
+	l.link = c.link
+	if c.index != nil || c.track {
+		bounds := l.Bounds()
+		if c.index != nil {
+			c.index.Insert(bounds, len(c.layers))
+		}
+		if c.track {
+			c.invalidate(bounds)
+		}
+	}
+	c.layers = append(c.layers, l)
+	c.mu.Unlock()
+}
+
+// redrawMaxDirty is the number of changed areas above which they are merged into their bounds.
+const redrawMaxDirty = 256
+
+// invalidate adds a changed area for Redraw, where many areas are merged into one that covers them all. The canvas must be locked.
+func (c *Canvas) invalidate(rect Rect) {
+	if rect.W == 0.0 && rect.H == 0.0 {
+		return
+	} else if redrawMaxDirty <= len(c.dirty) {
+		union := c.dirty[0]
+		for _, r := range c.dirty[1:] {
+			union = union.Add(r)
+		}
+		c.dirty = append(c.dirty[:0], union)
+	}
+	c.dirty = append(c.dirty, rect)
+}
+
+func (c *Canvas) setLink(url string, bounds Rect) {
+	c.mu.Lock()
+	c.link = url
+	c.mu.Unlock()
+}
+
+// NewLayer returns a new canvas of the same size that buffers its drawing operations independently of c, so that each goroutine can draw onto its own layer concurrently. The layer is merged into c when c is rendered or exported, with its drawing operations at the position where the layer was created, ie. above what was drawn onto c before and below what was drawn after. Drawing onto a layer must be finished before c is rendered or exported, though it may continue afterwards, in which case the new operations are merged upon the next export.
+func (c *Canvas) NewLayer() *Canvas {
+	sub := New(c.W, c.H)
+	c.mu.Lock()
+	c.groups = append(c.groups, canvasGroup{len(c.layers), sub})
+	c.mu.Unlock()
+	return sub
+}
+
+// merge moves the drawing operations of all layers created with NewLayer into c.
+func (c *Canvas) merge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	offset := 0
+	for i := range c.groups {
+		group := &c.groups[i]
+		group.canvas.merge()
+		group.pos += offset
+
+		sub := group.canvas
+		sub.mu.Lock()
+		n := len(sub.layers)
+		if 0 < n {
+			layers := make([]layer, 0, len(c.layers)+n)
+			layers = append(layers, c.layers[:group.pos]...)
+			layers = append(layers, sub.layers...)
+			layers = append(layers, c.layers[group.pos:]...)
+			c.layers = layers
+			if c.track {
+				for _, l := range sub.layers {
+					c.invalidate(l.Bounds())
+				}
+			}
+			if sub.shared {
+				sub.layers, sub.shared = nil, false
+			} else {
+				sub.layers = sub.layers[:0]
+			}
+			sub.index = nil
+			c.index = nil
+		}
+		if 0 < len(sub.anchors) {
+			if c.anchors == nil {
+				c.anchors = map[string]Point{}
+			}
+			for name, p := range sub.anchors {
+				c.anchors[name] = p
+			}
+			sub.anchors = nil
+		}
+		sub.mu.Unlock()
+
+		group.pos += n
+		offset += n
+	}
 }
 
 // Empty return true if the canvas is empty.
 func (c *Canvas) Empty() bool {
+	c.merge()
 	return len(c.layers) == 0
 }
 
-// Reset empties the canvas.
+// Reset empties the canvas, including its layers created with NewLayer.
 func (c *Canvas) Reset() {
-	c.layers = c.layers[:0]
+	c.mu.Lock()
+	if c.shared {
+		c.layers, c.shared = []layer{}, false
+	} else {
+		c.layers = c.layers[:0]
+	}
+	c.groups = nil
+	c.index = nil
+	c.anchors = nil
+	c.mu.Unlock()
+	c.Invalidate(Rect{0.0, 0.0, c.W, c.H})
+}
+
+// SetAnchor registers a named point in canvas coordinates, such as the port of a node shape, which is retrieved later by Anchor so that connectors between elements can be routed without recomputing their geometry. An anchor of the same name is replaced, see Context.SetAnchor to register points in the coordinates of a draw call.
+func (c *Canvas) SetAnchor(name string, x, y float64) {
+	c.mu.Lock()
+	if c.anchors == nil {
+		c.anchors = map[string]Point{}
+	}
+	c.anchors[name] = Point{x, y}
+	c.mu.Unlock()
+}
+
+// Anchor returns the named point in canvas coordinates, including the anchors of its layers created with NewLayer, or false if it does not exist.
+func (c *Canvas) Anchor(name string) (Point, bool) {
+	c.merge()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.anchors[name]
+	return p, ok
+}
+
+// Anchors returns the names of the anchors in sorted order.
+func (c *Canvas) Anchors() []string {
+	c.merge()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	names := make([]string, 0, len(c.anchors))
+	for name := range c.anchors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // Fit shrinks the canvas size so all elements fit. The elements are translated towards the origin when any left/bottom margins exist and the canvas size is decreased if any margins exist. It will maintain a given margin.
 func (c *Canvas) Fit(margin float64) {
+	c.merge()
 	if len(c.layers) == 0 {
 		c.W = 2 * margin
 		c.H = 2 * margin
@@ -404,33 +685,74 @@ func (c *Canvas) Fit(margin float64) {
 	rect := Rect{}
 	// TODO: slow when we have many paths (see Graph example)
 	for i, l := range c.layers {
-		bounds := Rect{}
-		if l.path != nil {
-			bounds = l.path.Bounds()
-			if l.style.StrokeColor.A != 0 && 0.0 < l.style.StrokeWidth {
-				bounds.X -= l.style.StrokeWidth / 2.0
-				bounds.Y -= l.style.StrokeWidth / 2.0
-				bounds.W += l.style.StrokeWidth
-				bounds.H += l.style.StrokeWidth
-			}
-		} else if l.text != nil {
-			bounds = l.text.Bounds()
-		} else if l.img != nil {
-			size := l.img.Bounds().Size()
-			bounds = Rect{0.0, 0.0, float64(size.X), float64(size.Y)}
-		}
-		bounds = bounds.Transform(l.m)
+		bounds := l.Bounds()
 		if i == 0 {
 			rect = bounds
 		} else {
 			rect = rect.Add(bounds)
 		}
 	}
+	if c.shared {
+		c.layers, c.shared = append([]layer{}, c.layers...), false
+	}
 	for i := range c.layers {
 		c.layers[i].m = Identity.Translate(-rect.X+margin, -rect.Y+margin).Mul(c.layers[i].m)
 	}
+	for name, p := range c.anchors {
+		c.anchors[name] = Point{p.X - rect.X + margin, p.Y - rect.Y + margin}
+	}
 	c.W = rect.W + 2*margin
 	c.H = rect.H + 2*margin
+	c.index = nil
+	c.Invalidate(Rect{0.0, 0.0, c.W, c.H})
+}
+
+func (c *Canvas) buildIndex() {
+	c.merge()
+	if c.index != nil {
+		return
+	}
+	c.index = NewQuadtree(Rect{0.0, 0.0, c.W, c.H})
+	for i, l := range c.layers {
+		c.index.Insert(l.Bounds(), i)
+	}
+}
+
+// Query returns the indices of the layers whose bounds overlap with rect, in drawing order. It uses a spatial index that is built on the first call, which makes it suitable for viewport culling and label collision detection on large scenes.
+func (c *Canvas) Query(rect Rect) []int {
+	c.buildIndex()
+	ids := c.index.Query(rect)
+	sort.Ints(ids)
+	return ids
+}
+
+// HitTest returns the indices of the layers that cover the point (x,y), with the top-most layer first. Paths are tested against their filled area (using their fill rule) or their stroke, while text and images are tested against their bounds.
+func (c *Canvas) HitTest(x, y float64) []int {
+	c.buildIndex()
+	hits := []int{}
+	c.index.Search(Rect{x, y, 0.0, 0.0}, func(i int) bool {
+		if c.layers[i].hit(Point{x, y}) {
+			hits = append(hits, i)
+		}
+		return true
+	})
+	sort.Sort(sort.Reverse(sort.IntSlice(hits)))
+	return hits
+}
+
+func (l layer) hit(p Point) bool {
+	if l.path == nil {
+		return true // bounds were checked already
+	}
+	p = l.m.Inv().Dot(p)
+	if l.style.FillColor.A != 0 && l.path.Interior(p.X, p.Y, l.style.FillRule) {
+		return true
+	}
+	if l.style.StrokeColor.A != 0 && 0.0 < l.style.StrokeWidth {
+		stroke := l.path.Stroke(l.style.StrokeWidth, l.style.StrokeCapper, l.style.StrokeJoiner)
+		return stroke.Interior(p.X, p.Y, NonZero)
+	}
+	return false
 }
 
 // Render renders the accumulated canvas drawing operations to another renderer.
@@ -439,15 +761,39 @@ func (c *Canvas) Render(r Renderer) {
 	if viewer, ok := r.(interface{ View() Matrix }); ok {
 		view = viewer.View()
 	}
+	c.merge()
 	for _, l := range c.layers {
-		m := view.Mul(l.m)
-		if l.path != nil {
-			r.RenderPath(l.path, l.style, m)
-		} else if l.text != nil {
-			r.RenderText(l.text, m)
-		} else if l.img != nil {
-			r.RenderImage(l.img, m)
-		}
+		l.render(r, view)
+	}
+}
+
+// RenderViewport renders only the layers that overlap with viewport (in canvas coordinates) to another renderer. Layers are found using the spatial index, see Query.
+func (c *Canvas) RenderViewport(r Renderer, viewport Rect) {
+	view := Identity
+	if viewer, ok := r.(interface{ View() Matrix }); ok {
+		view = viewer.View()
+	}
+	for _, i := range c.Query(viewport) {
+		c.layers[i].render(r, view)
+	}
+}
+
+func (l layer) render(r Renderer, view Matrix) {
+	m := view.Mul(l.m)
+	linker, ok := r.(linker)
+	if ok && l.link != "" {
+		l.m = m
+		linker.setLink(l.link, l.Bounds())
+	}
+	if l.path != nil {
+		r.RenderPath(l.path, l.style, m)
+	} else if l.text != nil {
+		r.RenderText(l.text, m)
+	} else if l.img != nil {
+		r.RenderImage(l.img, m)
+	}
+	if ok && l.link != "" {
+		linker.setLink("", Rect{})
 	}
 }
 
@@ -514,7 +860,145 @@ func (c *Canvas) WriteImage(dpm float64) *image.RGBA {
 	return img
 }
 
-// SavePNG saves the canvas to a PNG file.
+// WriteImageWithLimits is like WriteImage but enforces the resource limits while rasterizing, see Rasterizer.SetLimits. The size of the image is checked before it is allocated. It returns an error wrapping ErrLimitExceeded when a limit is exceeded.
+func (c *Canvas) WriteImageWithLimits(dpm float64, limits Limits) (*image.RGBA, error) {
+	w, h := c.W*dpm, c.H*dpm
+	if b := newBudget(limits); !b.checkSize(w, h) {
+		return nil, b.err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, int(w+0.5), int(h+0.5)))
+	draw.Draw(img, img.Bounds(), image.NewUniform(White), image.Point{}, draw.Src)
+
+	ras := NewRasterizer(img, dpm)
+	ras.SetLimits(limits)
+	c.Render(ras)
+	if err := ras.Err(); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// WriteImageWithDeadline is like WriteImage but degrades the quality of drawing progressively to finish rasterizing by the deadline, see Rasterizer.SetDeadline. It returns the reductions of quality that were applied.
+func (c *Canvas) WriteImageWithDeadline(dpm float64, deadline time.Time) (*image.RGBA, Degradation) {
+	img := image.NewRGBA(image.Rect(0, 0, int(c.W*dpm+0.5), int(c.H*dpm+0.5)))
+	draw.Draw(img, img.Bounds(), image.NewUniform(White), image.Point{}, draw.Src)
+
+	c.merge()
+	ras := NewRasterizer(img, dpm)
+	ras.SetDeadline(deadline, len(c.layers))
+	c.Render(ras)
+	return img, ras.Degraded()
+}
+
+// WritePalettedImage is like WriteImage but reduces the colors to a palette using the dithering, see Quantize. This is used for outputs with few colors such as GIF, 1-bit, or e-ink images, which are saved as paletted images by png.Encode or EncodePNG.
+func (c *Canvas) WritePalettedImage(dpm float64, palette color.Palette, dithering Dithering) *image.Paletted {
+	return Quantize(c.WriteImage(dpm), palette, dithering)
+}
+
+// Invalidate marks the area rect (in mm) as changed so that it will be redrawn by Redraw. Rendering to the canvas invalidates the bounds of the new layers automatically.
+func (c *Canvas) Invalidate(rect Rect) {
+	c.mu.Lock()
+	c.invalidate(rect)
+	c.mu.Unlock()
+}
+
+// redrawTileSize is the size in pixels of the tiles that are redrawn by Redraw.
+const redrawTileSize = 64
+
+// Redraw re-rasterizes only the invalidated areas of the canvas onto img with given DPM (dots-per-millimeter), which must hold the previous rendering of the canvas at the same DPM. The image is divided into tiles and only the tiles that overlap with an invalidated area are redrawn, using only the layers that overlap with them (see Query). Changes are tracked from the first call on, which redraws all layers. It returns the redrawn areas of the image so that interactive backends can update only those, and clears the invalidated areas.
+func (c *Canvas) Redraw(img draw.Image, dpm float64) []image.Rectangle {
+	c.merge()
+	if !c.track {
+		// changes are tracked from the first redraw on, before which all layers have changed
+		c.mu.Lock()
+		c.track = true
+		for _, l := range c.layers {
+			c.invalidate(l.Bounds())
+		}
+		c.mu.Unlock()
+	}
+	if len(c.dirty) == 0 {
+		return nil
+	}
+
+	bounds := img.Bounds()
+	size := bounds.Size()
+	nx := (size.X + redrawTileSize - 1) / redrawTileSize
+	ny := (size.Y + redrawTileSize - 1) / redrawTileSize
+	tiles := make([]bool, nx*ny)
+	for _, rect := range c.dirty {
+		// convert to tile coordinates, add a pixel for anti-aliasing
+		x0 := int(math.Floor(rect.X*dpm)) - 1
+		x1 := int(math.Ceil((rect.X+rect.W)*dpm)) + 1
+		y0 := size.Y - int(math.Ceil((rect.Y+rect.H)*dpm)) - 1
+		y1 := size.Y - int(math.Floor(rect.Y*dpm)) + 1
+		if x1 < 0 || size.X <= x0 || y1 < 0 || size.Y <= y0 {
+			continue
+		}
+		x0, x1 = clampInt(x0/redrawTileSize, 0, nx-1), clampInt(x1/redrawTileSize, 0, nx-1)
+		y0, y1 = clampInt(y0/redrawTileSize, 0, ny-1), clampInt(y1/redrawTileSize, 0, ny-1)
+		for j := y0; j <= y1; j++ {
+			for i := x0; i <= x1; i++ {
+				tiles[j*nx+i] = true
+			}
+		}
+	}
+	c.dirty = c.dirty[:0]
+
+	redrawn := []image.Rectangle{}
+	tile := image.NewRGBA(image.Rect(0, 0, redrawTileSize, redrawTileSize))
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			if !tiles[j*nx+i] {
+				continue
+			}
+			r := image.Rect(i*redrawTileSize, j*redrawTileSize, (i+1)*redrawTileSize, (j+1)*redrawTileSize).Intersect(image.Rect(0, 0, size.X, size.Y))
+			draw.Draw(tile, tile.Bounds(), image.NewUniform(White), image.Point{}, draw.Src)
+
+			// tile position in mm, the Y-axis points up
+			x := float64(r.Min.X) / dpm
+			y := float64(size.Y-r.Min.Y-redrawTileSize) / dpm
+			view := Identity.Translate(-x, -y)
+			ras := NewRasterizer(tile, dpm)
+			for _, k := range c.Query(Rect{x, y, redrawTileSize / dpm, redrawTileSize / dpm}) {
+				c.layers[k].render(ras, view)
+			}
+
+			r = r.Add(bounds.Min)
+			draw.Draw(img, r, tile, image.Point{}, draw.Src)
+			redrawn = append(redrawn, r)
+		}
+	}
+	return redrawn
+}
+
+// RedrawLoop maintains a rasterization of the canvas on img with given DPM (dots-per-millimeter) for interactive backends. It first draws the whole canvas, and then for every function received from updates it calls the function to change the canvas (together with any other pending updates), redraws the invalidated areas and calls flush with the redrawn areas of the image. Since all updates run on the loop's goroutine, other goroutines can safely change the canvas by sending updates. It returns when updates is closed.
+func (c *Canvas) RedrawLoop(img draw.Image, dpm float64, updates <-chan func(*Canvas), flush func([]image.Rectangle)) {
+	c.Invalidate(Rect{0.0, 0.0, c.W, c.H})
+	if redrawn := c.Redraw(img, dpm); 0 < len(redrawn) {
+		flush(redrawn)
+	}
+	for update := range updates {
+		update(c)
+	pending:
+		for {
+			select {
+			case update, ok := <-updates:
+				if !ok {
+					break pending
+				}
+				update(c)
+			default:
+				break pending
+			}
+		}
+		if redrawn := c.Redraw(img, dpm); 0 < len(redrawn) {
+			flush(redrawn)
+		}
+	}
+}
+
+// SavePNG saves the canvas to a PNG file, which records the DPM so that it is printed at the size of the canvas. Use EncodePNG to add text metadata or a color profile.
 func (c *Canvas) SavePNG(filename string, dpm float64) error {
 	f, err := os.Create(filename)
 	if err != nil {
@@ -523,7 +1007,7 @@ func (c *Canvas) SavePNG(filename string, dpm float64) error {
 
 	img := c.WriteImage(dpm)
 	// TODO: optimization: cache img until canvas changes
-	if err = png.Encode(f, img); err != nil {
+	if err = EncodePNG(f, img, PNGOptions{DPM: dpm}); err != nil {
 		f.Close()
 		return err
 	}
@@ -545,7 +1029,7 @@ func (c *Canvas) SaveJPG(filename string, dpm float64, opts *jpeg.Options) error
 	return f.Close()
 }
 
-// SaveGIF saves the canvas to a GIF file.
+// SaveGIF saves the canvas to a GIF file. The palette and dithering can be chosen by the Quantizer and Drawer of the options, such as MedianCut and OrderedDithering.Drawer().
 func (c *Canvas) SaveGIF(filename string, dpm float64, opts *gif.Options) error {
 	f, err := os.Create(filename)
 	if err != nil {
@@ -2,6 +2,7 @@ package canvas
 
 import (
 	"image"
+	"sync"
 	"testing"
 
 	"github.com/tdewolff/test"
@@ -65,3 +66,164 @@ func TestCanvasFit(t *testing.T) {
 	test.Float(t, c.W, 20)
 	test.Float(t, c.H, 20)
 }
+
+func TestCanvasQuery(t *testing.T) {
+	c := New(100, 100)
+	ctx := NewContext(c)
+	ctx.DrawPath(10.0, 10.0, Rectangle(10.0, 10.0))
+	ctx.DrawPath(50.0, 50.0, Circle(10.0))
+	ctx.DrawPath(15.0, 15.0, Rectangle(10.0, 10.0))
+	ctx.DrawPath(200.0, 200.0, Rectangle(10.0, 10.0)) // outside canvas
+
+	test.T(t, c.Query(Rect{0.0, 0.0, 30.0, 30.0}), []int{0, 2})
+	test.T(t, c.Query(Rect{0.0, 0.0, 300.0, 300.0}), []int{0, 1, 2, 3})
+	test.T(t, c.Query(Rect{80.0, 0.0, 10.0, 10.0}), []int{})
+
+	test.T(t, c.HitTest(17.0, 17.0), []int{2, 0})
+	test.T(t, c.HitTest(12.0, 12.0), []int{0})
+	test.T(t, c.HitTest(41.0, 41.0), []int{}) // within bounds of circle but outside its area
+	test.T(t, c.HitTest(205.0, 205.0), []int{3})
+
+	// index is updated for new layers
+	ctx.DrawPath(80.0, 0.0, Rectangle(10.0, 10.0))
+	test.T(t, c.Query(Rect{80.0, 0.0, 10.0, 10.0}), []int{4})
+}
+
+func TestCanvasRedraw(t *testing.T) {
+	c := New(50, 50)
+	ctx := NewContext(c)
+	ctx.SetFillColor(Red)
+	ctx.DrawPath(5.0, 5.0, Circle(10.0))
+	ctx.SetFillColor(Blue)
+	ctx.DrawPath(20.0, 30.0, Rectangle(25.0, 10.0))
+	test.T(t, len(c.dirty), 0) // tracked only from the first redraw on
+
+	equalImages := func(a, b *image.RGBA) bool {
+		for i := range a.Pix {
+			if d := int(a.Pix[i]) - int(b.Pix[i]); d < -1 || 1 < d {
+				return false
+			}
+		}
+		return true
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	test.T(t, len(c.Redraw(img, 2.0)), 3) // only tiles touched by layers
+	c.Invalidate(Rect{0.0, 0.0, c.W, c.H})
+	test.T(t, len(c.Redraw(img, 2.0)), 4)
+	test.That(t, equalImages(img, c.WriteImage(2.0)))
+	test.T(t, len(c.Redraw(img, 2.0)), 0)
+
+	ctx.SetFillColor(Green)
+	ctx.DrawPath(40.0, 40.0, Rectangle(5.0, 5.0))
+	test.T(t, c.Redraw(img, 2.0), []image.Rectangle{image.Rect(64, 0, 100, 64)})
+	test.That(t, equalImages(img, c.WriteImage(2.0)))
+
+	c.Invalidate(Rect{0.0, 0.0, 10.0, 10.0})
+	test.T(t, c.Redraw(img, 2.0), []image.Rectangle{image.Rect(0, 64, 64, 100)})
+
+	for i := 0; i < 2*redrawMaxDirty; i++ {
+		ctx.DrawPath(float64(i%10), 0.0, Rectangle(1.0, 1.0))
+	}
+	test.That(t, len(c.dirty) <= redrawMaxDirty)
+	test.T(t, c.Redraw(img, 2.0), []image.Rectangle{image.Rect(0, 64, 64, 100)})
+	test.That(t, equalImages(img, c.WriteImage(2.0)))
+
+	updates := make(chan func(*Canvas), 2)
+	updates <- func(c *Canvas) {
+		NewContext(c).DrawPath(2.0, 2.0, Rectangle(2.0, 2.0))
+	}
+	close(updates)
+	flushes := 0
+	c.RedrawLoop(img, 2.0, updates, func([]image.Rectangle) {
+		flushes++
+	})
+	test.T(t, flushes, 2)
+	test.That(t, equalImages(img, c.WriteImage(2.0)))
+}
+
+func TestCanvasLayers(t *testing.T) {
+	c := New(100, 100)
+	NewContext(c).DrawPath(0.0, 0.0, Rectangle(1.0, 1.0))
+
+	layers := []*Canvas{}
+	for i := 0; i < 4; i++ {
+		layers = append(layers, c.NewLayer())
+	}
+	NewContext(c).DrawPath(0.0, 0.0, Rectangle(2.0, 2.0))
+
+	var wg sync.WaitGroup
+	for i, layer := range layers {
+		wg.Add(1)
+		go func(i int, layer *Canvas) {
+			defer wg.Done()
+			ctx := NewContext(layer)
+			for j := 0; j < 100; j++ {
+				ctx.DrawPath(float64(10*i), float64(j), Rectangle(1.0, 1.0))
+			}
+		}(i, layer)
+	}
+	wg.Wait()
+
+	test.That(t, !c.Empty())
+	test.T(t, len(c.layers), 402)
+	test.T(t, c.layers[0].path.Bounds().W, 1.0)
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 100; j++ {
+			test.T(t, c.layers[1+100*i+j].m, Identity.Translate(float64(10*i), float64(j)))
+		}
+	}
+	test.T(t, c.layers[401].path.Bounds().W, 2.0)
+
+	// drawing continues after merging
+	NewContext(layers[0]).DrawPath(50.0, 50.0, Rectangle(1.0, 1.0))
+	test.T(t, c.Query(Rect{50.0, 50.0, 1.0, 1.0}), []int{101})
+}
+
+func TestCanvasAnchors(t *testing.T) {
+	c := New(100, 100)
+	ctx := NewContext(c)
+	ctx.Translate(10.0, 20.0)
+	ctx.DrawPath(5.0, 5.0, Rectangle(20.0, 10.0))
+	ctx.SetAnchors(5.0, 5.0, map[string]Point{
+		"port-left":  {0.0, 5.0},
+		"port-right": {20.0, 5.0},
+	})
+	layer := NewContext(c.NewLayer())
+	layer.SetAnchor("center", 50.0, 50.0)
+
+	p, ok := ctx.Anchor("port-right")
+	test.That(t, ok)
+	test.T(t, p, Point{35.0, 30.0})
+	test.T(t, c.Anchors(), []string{"center", "port-left", "port-right"})
+	_, ok = c.Anchor("missing")
+	test.That(t, !ok)
+
+	s := c.Snapshot()
+	c.Fit(1.0)
+	p, _ = c.Anchor("port-left")
+	test.T(t, p, Point{1.0, 6.0})
+
+	c.Restore(s)
+	p, _ = c.Anchor("port-left")
+	test.T(t, p, Point{15.0, 30.0})
+
+	c.Reset()
+	test.T(t, c.Anchors(), []string{})
+}
+
+func TestContextDevice(t *testing.T) {
+	ctx := NewContext(New(100, 50))
+	ctx.Translate(10.0, 20.0)
+	ctx.Scale(2.0, 2.0)
+
+	x, y := ctx.UserToDevice(5.0, 5.0, 2.0)
+	test.Float(t, x, 40.0)
+	test.Float(t, y, 40.0)
+	x, y = ctx.DeviceToUser(40.0, 40.0, 2.0)
+	test.Float(t, x, 5.0)
+	test.Float(t, y, 5.0)
+	x, y = ctx.DeviceToUser(0.0, 0.0, 2.0)
+	test.Float(t, x, -5.0)
+	test.Float(t, y, 15.0)
+}
@@ -0,0 +1,226 @@
+package canvas
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// cborSelfDescribe is the tag that marks the start of a CBOR file.
+const cborSelfDescribe = 55799
+
+// cborMaxDepth is the maximum nesting of arrays and maps that is decoded.
+const cborMaxDepth = 64
+
+// appendCBOR appends the CBOR encoding (RFC 8949) of v, which is nil, a bool, an int, a float64, a string, a []byte, a []float64, a []interface{}, or a map[string]interface{}, whose keys are sorted.
+func appendCBOR(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xF6)
+	case bool:
+		if v {
+			return append(b, 0xF5)
+		}
+		return append(b, 0xF4)
+	case int:
+		if v < 0 {
+			return appendCBORHead(b, 1, uint64(-(v + 1)))
+		}
+		return appendCBORHead(b, 0, uint64(v))
+	case float64:
+		n := math.Float64bits(v)
+		return append(b, 0xFB, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32), byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	case string:
+		b = appendCBORHead(b, 3, uint64(len(v)))
+		return append(b, v...)
+	case []byte:
+		b = appendCBORHead(b, 2, uint64(len(v)))
+		return append(b, v...)
+	case []float64:
+		b = appendCBORHead(b, 4, uint64(len(v)))
+		for _, f := range v {
+			b = appendCBOR(b, f)
+		}
+		return b
+	case []interface{}:
+		b = appendCBORHead(b, 4, uint64(len(v)))
+		for _, item := range v {
+			b = appendCBOR(b, item)
+		}
+		return b
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		b = appendCBORHead(b, 5, uint64(len(v)))
+		for _, key := range keys {
+			b = appendCBOR(b, key)
+			b = appendCBOR(b, v[key])
+		}
+		return b
+	}
+	panic(fmt.Sprintf("unsupported CBOR type %T", v))
+}
+
+// appendCBORHead appends the initial byte of a major type with its argument.
+func appendCBORHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	if n < 24 {
+		return append(b, major|byte(n))
+	} else if n <= math.MaxUint8 {
+		return append(b, major|24, byte(n))
+	} else if n <= math.MaxUint16 {
+		return append(b, major|25, byte(n>>8), byte(n))
+	} else if n <= math.MaxUint32 {
+		return append(b, major|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	b = append(b, major|27)
+	return append(b, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32), byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+// cborDecoder decodes CBOR data items into nil, bool, int, float64, string, []byte, []interface{}, and map[string]interface{} values. Tags are skipped, and indefinite lengths are not supported.
+type cborDecoder struct {
+	b   []byte
+	pos int
+}
+
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if len(d.b) <= d.pos {
+		return nil, fmt.Errorf("unexpected end of data")
+	} else if cborMaxDepth < depth {
+		return nil, fmt.Errorf("nested too deeply")
+	}
+	major, info := d.b[d.pos]>>5, d.b[d.pos]&0x1F
+	d.pos++
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		case 25:
+			v, err := d.read(2)
+			if err != nil {
+				return nil, err
+			}
+			return float16ToFloat64(binary.BigEndian.Uint16(v)), nil
+		case 26:
+			v, err := d.read(4)
+			if err != nil {
+				return nil, err
+			}
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(v))), nil
+		case 27:
+			v, err := d.read(8)
+			if err != nil {
+				return nil, err
+			}
+			return math.Float64frombits(binary.BigEndian.Uint64(v)), nil
+		}
+		return nil, fmt.Errorf("unsupported simple value %d", info)
+	}
+
+	var n uint64
+	if info < 24 {
+		n = uint64(info)
+	} else if info <= 27 {
+		v, err := d.read(1 << (info - 24))
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range v {
+			n = n<<8 | uint64(c)
+		}
+	} else {
+		return nil, fmt.Errorf("unsupported length")
+	}
+
+	switch major {
+	case 0:
+		if math.MaxInt32 < n {
+			return nil, fmt.Errorf("integer out of range")
+		}
+		return int(n), nil
+	case 1:
+		if math.MaxInt32 < n {
+			return nil, fmt.Errorf("integer out of range")
+		}
+		return -int(n) - 1, nil
+	case 2, 3:
+		if uint64(len(d.b)-d.pos) < n {
+			return nil, fmt.Errorf("unexpected end of data")
+		}
+		v, _ := d.read(int(n))
+		if major == 3 {
+			return string(v), nil
+		}
+		return append([]byte{}, v...), nil
+	case 4:
+		if uint64(len(d.b)-d.pos) < n {
+			return nil, fmt.Errorf("unexpected end of data") // each item is at least one byte
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	case 5:
+		if uint64(len(d.b)-d.pos) < 2*n {
+			return nil, fmt.Errorf("unexpected end of data")
+		}
+		items := make(map[string]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("map key must be a string")
+			}
+			if items[k], err = d.decode(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return d.decode(depth + 1) // tag
+}
+
+func (d *cborDecoder) read(n int) ([]byte, error) {
+	if len(d.b)-d.pos < n {
+		return nil, fmt.Errorf("unexpected end of data")
+	}
+	v := d.b[d.pos : d.pos+n]
+	d.pos += n
+	return v, nil
+}
+
+// float16ToFloat64 converts a half-precision float.
+func float16ToFloat64(h uint16) float64 {
+	exp, mant := int(h>>10)&0x1F, float64(h&0x3FF)
+	f := 0.0
+	if exp == 0 {
+		f = math.Ldexp(mant, -24)
+	} else if exp == 0x1F {
+		f = math.Inf(1)
+		if mant != 0 {
+			f = math.NaN()
+		}
+	} else {
+		f = math.Ldexp(mant+1024.0, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
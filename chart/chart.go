@@ -0,0 +1,230 @@
+package chart
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/tdewolff/canvas"
+)
+
+// DefaultColors is the palette used for series, and is the categorical Tableau 10 palette.
+var DefaultColors = canvas.Tableau10
+
+// Coordinates maps data coordinates to canvas coordinates.
+type Coordinates interface {
+	Pos(x, y float64) canvas.Point
+}
+
+// Cartesian are the coordinates of a rectangular plot area of Width by Height millimeters, with its origin at the lower-left corner.
+type Cartesian struct {
+	X, Y          Scale
+	Width, Height float64
+}
+
+// Pos returns the canvas coordinates of a data point.
+func (c Cartesian) Pos(x, y float64) canvas.Point {
+	return canvas.Point{X: c.X.Map(x) * c.Width, Y: c.Y.Map(y) * c.Height}
+}
+
+// Series is a data series that can be drawn in a chart.
+type Series interface {
+	// Extent returns the range of the data, used to choose the scales of a chart automatically.
+	Extent() canvas.Rect
+	Draw(ctx *canvas.Context, coord Coordinates)
+}
+
+// Chart is a chart of data series with axes. The plot area is Width by Height millimeters, and axes, labels, and the title are drawn outside of it.
+type Chart struct {
+	Width, Height float64
+
+	X, Y                  Scale // chosen automatically when nil, see NewLinearScale
+	Title, XLabel, YLabel string
+	Grid                  bool // draw grid lines at the ticks
+
+	Font      *canvas.FontFamily // no text is drawn when nil
+	FontSize  float64            // in points
+	TextColor color.RGBA
+	AxisColor color.RGBA
+	GridColor color.RGBA
+	LineWidth float64 // width of the axes in millimeters
+	TickSize  float64 // length of the ticks in millimeters
+
+	Series []Series
+}
+
+// New returns a new chart with a plot area of width by height millimeters.
+func New(width, height float64) *Chart {
+	return &Chart{
+		Width:     width,
+		Height:    height,
+		FontSize:  8.0,
+		TextColor: canvas.Black,
+		AxisColor: canvas.Black,
+		GridColor: color.RGBA{0xdd, 0xdd, 0xdd, 0xff},
+		LineWidth: 0.2,
+		TickSize:  1.5,
+	}
+}
+
+// SetTheme sets the font, the colors of text, axes, and grid lines, and the width of the axes from a theme, and colors the scatter, line, bar, and area series of the chart by the palette of the theme in order. Series that are added afterwards keep their colors.
+func (c *Chart) SetTheme(theme canvas.Theme) {
+	c.Font = theme.Font
+	c.FontSize = theme.FontSize
+	c.TextColor = theme.Foreground
+	c.AxisColor = theme.Foreground
+	c.GridColor = theme.Muted
+	c.LineWidth = theme.LineWidth
+	for i, series := range c.Series {
+		switch s := series.(type) {
+		case *Scatter:
+			s.Style.FillColor = theme.Color(i)
+		case *Line:
+			s.Style.StrokeColor = theme.Color(i)
+		case *Bar:
+			s.Style.FillColor = theme.Color(i)
+		case *Area:
+			s.Style.FillColor = theme.Color(i)
+		}
+	}
+}
+
+// Add adds data series to the chart, which are drawn in order.
+func (c *Chart) Add(series ...Series) {
+	c.Series = append(c.Series, series...)
+}
+
+// Scales returns the scales of the chart, where nil scales are chosen to fit the extent of all series.
+func (c *Chart) Scales() (Scale, Scale) {
+	x, y := c.X, c.Y
+	if x == nil || y == nil {
+		extent := canvas.Rect{}
+		for i, series := range c.Series {
+			if i == 0 {
+				extent = series.Extent()
+			} else {
+				extent = extent.Add(series.Extent())
+			}
+		}
+		if x == nil {
+			x = NewLinearScale(extent.X, extent.X+extent.W)
+		}
+		if y == nil {
+			y = NewLinearScale(extent.Y, extent.Y+extent.H)
+		}
+	}
+	return x, y
+}
+
+// Coordinates returns the coordinates of the plot area.
+func (c *Chart) Coordinates() Cartesian {
+	x, y := c.Scales()
+	return Cartesian{x, y, c.Width, c.Height}
+}
+
+func (c *Chart) face(scale float64) (canvas.FontFace, bool) {
+	if c.Font == nil {
+		return canvas.FontFace{}, false
+	}
+	return c.Font.Face(scale*c.FontSize, c.TextColor, canvas.FontRegular, canvas.FontNormal), true
+}
+
+// Draw draws the chart with the lower-left corner of the plot area at (x,y).
+func (c *Chart) Draw(ctx *canvas.Context, x, y float64) {
+	coord := c.Coordinates()
+	ctx.Push()
+	defer ctx.Pop()
+	ctx.Translate(x, y)
+
+	// grid
+	xTicks, yTicks := coord.X.Ticks(), coord.Y.Ticks()
+	if c.Grid {
+		grid := &canvas.Path{}
+		for _, tick := range xTicks {
+			if pos := coord.X.Map(tick.Value) * c.Width; inRange(pos, c.Width) {
+				grid.MoveTo(pos, 0.0)
+				grid.LineTo(pos, c.Height)
+			}
+		}
+		for _, tick := range yTicks {
+			if pos := coord.Y.Map(tick.Value) * c.Height; inRange(pos, c.Height) {
+				grid.MoveTo(0.0, pos)
+				grid.LineTo(c.Width, pos)
+			}
+		}
+		strokeStyle(ctx, c.GridColor, c.LineWidth/2.0)
+		ctx.DrawPath(0.0, 0.0, grid)
+	}
+
+	for _, series := range c.Series {
+		ctx.Push()
+		series.Draw(ctx, coord)
+		ctx.Pop()
+	}
+
+	// axes and ticks
+	axes := &canvas.Path{}
+	axes.MoveTo(0.0, c.Height)
+	axes.LineTo(0.0, 0.0)
+	axes.LineTo(c.Width, 0.0)
+	for _, tick := range xTicks {
+		if pos := coord.X.Map(tick.Value) * c.Width; inRange(pos, c.Width) {
+			axes.MoveTo(pos, 0.0)
+			axes.LineTo(pos, -c.TickSize)
+		}
+	}
+	for _, tick := range yTicks {
+		if pos := coord.Y.Map(tick.Value) * c.Height; inRange(pos, c.Height) {
+			axes.MoveTo(0.0, pos)
+			axes.LineTo(-c.TickSize, pos)
+		}
+	}
+	strokeStyle(ctx, c.AxisColor, c.LineWidth)
+	ctx.DrawPath(0.0, 0.0, axes)
+
+	// labels
+	face, ok := c.face(1.0)
+	if !ok {
+		return
+	}
+	metrics := face.Metrics()
+	margin := c.TickSize + metrics.XHeight/2.0
+	yLabelWidth := 0.0
+	for _, tick := range xTicks {
+		if pos := coord.X.Map(tick.Value) * c.Width; inRange(pos, c.Width) {
+			ctx.DrawText(pos, -margin-metrics.Ascent, canvas.NewTextLine(face, tick.Label, canvas.Center))
+		}
+	}
+	for _, tick := range yTicks {
+		if pos := coord.Y.Map(tick.Value) * c.Height; inRange(pos, c.Height) {
+			ctx.DrawText(-margin, pos-metrics.XHeight/2.0, canvas.NewTextLine(face, tick.Label, canvas.Right))
+			yLabelWidth = math.Max(yLabelWidth, face.TextWidth(tick.Label))
+		}
+	}
+	if c.XLabel != "" {
+		ctx.DrawText(c.Width/2.0, -margin-metrics.LineHeight-metrics.Ascent, canvas.NewTextLine(face, c.XLabel, canvas.Center))
+	}
+	if c.YLabel != "" {
+		ctx.Push()
+		ctx.Translate(-margin-yLabelWidth-metrics.LineHeight+metrics.Ascent, c.Height/2.0)
+		ctx.Rotate(90.0)
+		ctx.DrawText(0.0, 0.0, canvas.NewTextLine(face, c.YLabel, canvas.Center))
+		ctx.Pop()
+	}
+	if c.Title != "" {
+		title, _ := c.face(1.25)
+		ctx.DrawText(c.Width/2.0, c.Height+metrics.LineHeight/2.0+title.Metrics().Descent, canvas.NewTextLine(title, c.Title, canvas.Center))
+	}
+}
+
+// inRange returns true if the position lies within [0,size] plus a small margin for rounding errors.
+func inRange(pos, size float64) bool {
+	return -1e-6 <= pos && pos <= size+1e-6
+}
+
+// strokeStyle sets the context to stroke lines of the given color and width without filling.
+func strokeStyle(ctx *canvas.Context, col color.RGBA, width float64) {
+	ctx.SetFillColor(canvas.Transparent)
+	ctx.SetStrokeColor(col)
+	ctx.SetStrokeWidth(width)
+	ctx.SetDashes(0.0)
+}
@@ -0,0 +1,104 @@
+package chart
+
+import (
+	"image"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+// recorder is a renderer that records the paths and texts that are drawn.
+type recorder struct {
+	paths  []*canvas.Path
+	styles []canvas.Style
+	texts  int
+}
+
+func (r *recorder) Size() (float64, float64) {
+	return 200.0, 200.0
+}
+
+func (r *recorder) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	r.paths = append(r.paths, path.Transform(m))
+	r.styles = append(r.styles, style)
+}
+
+func (r *recorder) RenderText(text *canvas.Text, m canvas.Matrix) {
+	r.texts++
+}
+
+func (r *recorder) RenderImage(img image.Image, m canvas.Matrix) {}
+
+func TestChart(t *testing.T) {
+	c := New(100.0, 50.0)
+	line := NewLine([]float64{0, 1, 2}, []float64{0, 10, 5})
+	c.Add(line, NewScatter([]float64{0.5}, []float64{2.5}))
+	x, y := c.Scales()
+	test.T(t, x.(*LinearScale).Max, 2.0)
+	test.T(t, y.(*LinearScale).Max, 10.0)
+
+	r := &recorder{}
+	c.Draw(canvas.NewContext(r), 20.0, 10.0)
+	test.T(t, len(r.paths), 3) // line, marker, axes
+	test.T(t, r.paths[0], canvas.MustParseSVG("M20 10L70 60L120 35"))
+	test.T(t, r.paths[1].Bounds(), canvas.Rect{X: 44.5, Y: 22.0, W: 1.0, H: 1.0})
+	test.T(t, r.texts, 0) // no font
+
+	// labels
+	family := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+	c.Font = family
+	c.Grid = true
+	c.Title, c.XLabel, c.YLabel = "Title", "x", "y"
+	r = &recorder{}
+	c.Draw(canvas.NewContext(r), 20.0, 10.0)
+	test.T(t, len(r.paths), 4)
+	test.T(t, r.texts, len(x.Ticks())+len(y.Ticks())+3)
+}
+
+func TestChartTheme(t *testing.T) {
+	c := New(100.0, 50.0)
+	c.Add(NewLine([]float64{0, 1}, []float64{0, 1}), NewBar([]float64{0, 1}, []float64{1, 2}))
+	c.Grid = true
+	c.SetTheme(canvas.DarkTheme)
+	test.T(t, c.TextColor, canvas.DarkTheme.Foreground)
+
+	r := &recorder{}
+	c.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, len(r.styles), 5) // grid, line, two bars, axes
+	test.T(t, r.styles[0].StrokeColor, canvas.DarkTheme.Muted)
+	test.T(t, r.styles[1].StrokeColor, canvas.DarkTheme.Palette[0])
+	test.T(t, r.styles[2].FillColor, canvas.DarkTheme.Palette[1])
+	test.T(t, r.styles[4].StrokeColor, canvas.DarkTheme.Foreground)
+}
+
+func TestBar(t *testing.T) {
+	c := New(40.0, 20.0)
+	c.X = NewCategoryScale("a", "b")
+	values := NewBar([]float64{0, 1}, []float64{5, 10})
+	stacked := NewBar([]float64{0, 1}, []float64{7, 20})
+	stacked.Base = values.Y
+	c.Add(values, stacked)
+	test.T(t, c.Coordinates().Y.(*LinearScale).Max, 20.0)
+
+	r := &recorder{}
+	c.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, r.paths[0], canvas.MustParseSVG("M2 0L18 0L18 5L2 5z"))
+	test.T(t, r.paths[3], canvas.MustParseSVG("M22 10L38 10L38 20L22 20z"))
+
+	horizontal := NewBar([]float64{0, 1}, []float64{5, 10})
+	horizontal.Horizontal = true
+	test.T(t, horizontal.Extent(), canvas.Rect{X: 0.0, Y: -0.4, W: 10.0, H: 1.8})
+}
+
+func TestArea(t *testing.T) {
+	area := NewArea([]float64{0, 1, 2}, []float64{1, 2, 1})
+	area.Base = []float64{0.5, 0.5, 0.5}
+	test.T(t, area.Extent(), canvas.Rect{X: 0.0, Y: 0.5, W: 2.0, H: 1.5})
+
+	coord := Cartesian{&LinearScale{Min: 0, Max: 2}, &LinearScale{Min: 0, Max: 2}, 2.0, 2.0}
+	r := &recorder{}
+	area.Draw(canvas.NewContext(r), coord)
+	test.T(t, r.paths[0], canvas.MustParseSVG("M0 1L1 2L2 1L2 0.5L1 0.5L0 0.5z"))
+}
@@ -0,0 +1,183 @@
+package chart
+
+import (
+	"image/color"
+
+	"github.com/tdewolff/canvas"
+)
+
+// ChordGroup is a group of a laid out chord diagram, which runs clockwise from the angle Start to End in degrees.
+type ChordGroup struct {
+	Name       string
+	Value      float64
+	Start, End float64
+}
+
+// ChordRibbon is a ribbon of a laid out chord diagram between the groups Source and Target, where the flow from Source to Target is at least the flow in the opposite direction. Its ends run clockwise between the angles in degrees, and the ends have zero width when there is no flow from that group.
+type ChordRibbon struct {
+	Source, Target           int
+	SourceStart, SourceEnd   float64
+	TargetStart, TargetEnd   float64
+	SourceValue, TargetValue float64
+}
+
+// Path returns the ribbon on a circle of radius r around the origin, bounded by arcs along the circle and quadratic Béziers through the center.
+func (ribbon ChordRibbon) Path(r float64) *canvas.Path {
+	coord := Polar{&LinearScale{Min: 0.0, Max: 360.0}, &LinearScale{Min: 0.0, Max: 1.0}, r, 0.0, false}
+	p := &canvas.Path{}
+	start := coord.Pos(ribbon.SourceStart, 1.0)
+	p.MoveTo(start.X, start.Y)
+	coord.arc(p, ribbon.SourceStart, ribbon.SourceEnd, 1.0)
+	if ribbon.Source != ribbon.Target {
+		pos := coord.Pos(ribbon.TargetStart, 1.0)
+		p.QuadTo(0.0, 0.0, pos.X, pos.Y)
+		coord.arc(p, ribbon.TargetStart, ribbon.TargetEnd, 1.0)
+	}
+	p.QuadTo(0.0, 0.0, start.X, start.Y)
+	p.Close()
+	return p
+}
+
+// Chord is a chord diagram of the flows between groups on a circle with a radius of Radius millimeters, where Matrix[i][j] is the flow from group i to group j. Each group is an arc whose length is proportional to its total outgoing flow, and ribbons connect each pair of groups with ends whose widths are proportional to the flows in both directions.
+type Chord struct {
+	Radius float64
+	Names  []string
+	Matrix [][]float64
+
+	Padding   float64 // space between the groups in degrees
+	Thickness float64 // thickness of the group arcs in millimeters
+
+	Colors  []color.RGBA // colors of the groups, ribbons have the color of their source
+	Opacity float64      // opacity of the ribbons
+
+	Font      *canvas.FontFamily // no text is drawn when nil
+	FontSize  float64            // in points
+	TextColor color.RGBA
+}
+
+// NewChord returns a chord diagram with a radius of radius millimeters with the given groups and flows between them.
+func NewChord(radius float64, names []string, matrix [][]float64) *Chord {
+	return &Chord{
+		Radius:    radius,
+		Names:     names,
+		Matrix:    matrix,
+		Padding:   2.0,
+		Thickness: 3.0,
+		Colors:    DefaultColors,
+		Opacity:   0.6,
+		FontSize:  8.0,
+		TextColor: canvas.Black,
+	}
+}
+
+func (c *Chord) value(i, j int) float64 {
+	if i < len(c.Matrix) && j < len(c.Matrix[i]) && 0.0 < c.Matrix[i][j] {
+		return c.Matrix[i][j]
+	}
+	return 0.0
+}
+
+// Layout returns the groups and ribbons, with the first group starting at the top. The number of groups is the length of Names.
+func (c *Chord) Layout() ([]ChordGroup, []ChordRibbon) {
+	n := len(c.Names)
+	total := 0.0
+	groups := make([]ChordGroup, n)
+	for i := range groups {
+		groups[i].Name = c.Names[i]
+		for j := 0; j < n; j++ {
+			groups[i].Value += c.value(i, j)
+		}
+		total += groups[i].Value
+	}
+	if total == 0.0 {
+		return groups, []ChordRibbon{}
+	}
+
+	// each group is divided into subgroups for the flows to each of the groups
+	k := (360.0 - float64(n)*c.Padding) / total
+	subgroups := make([][][2]float64, n)
+	angle := 90.0
+	for i := range groups {
+		groups[i].Start = angle
+		subgroups[i] = make([][2]float64, n)
+		for j := 0; j < n; j++ {
+			subgroups[i][j] = [2]float64{angle, angle - c.value(i, j)*k}
+			angle -= c.value(i, j) * k
+		}
+		groups[i].End = angle
+		angle -= c.Padding
+	}
+
+	ribbons := []ChordRibbon{}
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			source, target := i, j
+			if c.value(i, j) < c.value(j, i) {
+				source, target = j, i
+			}
+			if c.value(source, target) == 0.0 {
+				continue
+			}
+			ribbons = append(ribbons, ChordRibbon{
+				Source:      source,
+				Target:      target,
+				SourceStart: subgroups[source][target][0],
+				SourceEnd:   subgroups[source][target][1],
+				TargetStart: subgroups[target][source][0],
+				TargetEnd:   subgroups[target][source][1],
+				SourceValue: c.value(source, target),
+				TargetValue: c.value(target, source),
+			})
+		}
+	}
+	return groups, ribbons
+}
+
+func (c *Chord) color(i int) color.RGBA {
+	if len(c.Colors) == 0 {
+		return DefaultColors[i%len(DefaultColors)]
+	}
+	return c.Colors[i%len(c.Colors)]
+}
+
+// Draw draws the diagram with its center at (x,y), with the names of the groups along the outside of their arcs. Ribbons are filled with the flat color of their source, since canvas has no gradient fills.
+func (c *Chord) Draw(ctx *canvas.Context, x, y float64) {
+	groups, ribbons := c.Layout()
+	ctx.Push()
+	defer ctx.Pop()
+	ctx.Translate(x, y)
+
+	for _, ribbon := range ribbons {
+		ctx.Style = fillStyle(translucent(c.color(ribbon.Source), c.Opacity))
+		ctx.DrawPath(0.0, 0.0, ribbon.Path(c.Radius))
+	}
+
+	outer := c.Radius + c.Thickness
+	coord := Polar{&LinearScale{Min: 0.0, Max: 360.0}, &LinearScale{Min: 0.0, Max: outer}, outer, 0.0, false}
+	for i, group := range groups {
+		if group.Value == 0.0 {
+			continue
+		}
+		p := &canvas.Path{}
+		start := coord.Pos(group.Start, outer)
+		p.MoveTo(start.X, start.Y)
+		coord.arc(p, group.Start, group.End, outer)
+		end := coord.Pos(group.End, c.Radius)
+		p.LineTo(end.X, end.Y)
+		coord.arc(p, group.End, group.Start, c.Radius)
+		p.Close()
+		ctx.Style = fillStyle(c.color(i))
+		ctx.DrawPath(0.0, 0.0, p)
+	}
+
+	if c.Font == nil {
+		return
+	}
+	face := c.Font.Face(c.FontSize, c.TextColor, canvas.FontRegular, canvas.FontNormal)
+	r := outer + 1.5*face.Metrics().XHeight
+	for _, group := range groups {
+		if group.Value != 0.0 {
+			drawTextOnArc(ctx, face, group.Name, r, (group.Start+group.End)/2.0)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package chart
+
+import (
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestChord(t *testing.T) {
+	c := NewChord(10.0, []string{"a", "b", "c"}, [][]float64{{1, 1, 0}, {3, 0, 0}})
+	c.Padding = 0.0
+	groups, ribbons := c.Layout()
+	test.T(t, groups, []ChordGroup{{"a", 2.0, 90.0, -54.0}, {"b", 3.0, -54.0, -270.0}, {"c", 0.0, -270.0, -270.0}})
+	test.T(t, ribbons, []ChordRibbon{
+		{0, 0, 90.0, 18.0, 90.0, 18.0, 1.0, 1.0},
+		{1, 0, -54.0, -270.0, 18.0, -54.0, 3.0, 1.0},
+	})
+
+	self := ribbons[0].Path(10.0)
+	test.T(t, self.StartPos(), canvas.Point{X: 0.0, Y: 10.0})
+	test.That(t, self.Closed())
+
+	r := &recorder{}
+	c.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, len(r.paths), 4) // two ribbons, two groups
+	test.T(t, r.styles[2].FillColor, DefaultColors[0])
+	test.T(t, r.styles[3].FillColor, DefaultColors[1])
+
+	c.Names = nil
+	groups, ribbons = c.Layout()
+	test.T(t, len(groups), 0)
+	test.T(t, len(ribbons), 0)
+}
@@ -0,0 +1,269 @@
+package chart
+
+import (
+	"image/color"
+	"math"
+)
+
+// ColorSpace is the color space in which colors are interpolated.
+type ColorSpace int
+
+// see ColorSpace
+const (
+	RGB ColorSpace = iota // sRGB, which is fast but not perceptually uniform
+	Lab                   // CIELAB, where equal steps are about equally visible
+	LCh                   // the polar form of CIELAB, which interpolates the hue along the shortest way around the color wheel
+)
+
+// Colormap maps values in [0,1] to colors.
+type Colormap interface {
+	At(t float64) color.RGBA
+}
+
+// Ramp is a continuous colormap through the colors at equal distances, interpolated in the color space Space. Ramps are used for sequential and diverging data.
+type Ramp struct {
+	Colors []color.RGBA
+	Space  ColorSpace
+}
+
+// At returns the color at t, which is clamped to [0,1].
+func (r Ramp) At(t float64) color.RGBA {
+	if len(r.Colors) == 0 {
+		return color.RGBA{}
+	} else if len(r.Colors) == 1 || t <= 0.0 || math.IsNaN(t) {
+		return r.Colors[0]
+	} else if 1.0 <= t {
+		return r.Colors[len(r.Colors)-1]
+	}
+	t *= float64(len(r.Colors) - 1)
+	i := int(t)
+	return interpolateColorSpace(r.Space, r.Colors[i], r.Colors[i+1], t-float64(i))
+}
+
+// Reverse returns the ramp from high to low.
+func (r Ramp) Reverse() Ramp {
+	colors := make([]color.RGBA, len(r.Colors))
+	for i, col := range r.Colors {
+		colors[len(colors)-1-i] = col
+	}
+	return Ramp{colors, r.Space}
+}
+
+// Samples returns n colors at equal distances along the ramp, including both ends.
+func (r Ramp) Samples(n int) Palette {
+	colors := make(Palette, n)
+	for i := range colors {
+		t := 0.5
+		if 1 < n {
+			t = float64(i) / float64(n-1)
+		}
+		colors[i] = r.At(t)
+	}
+	return colors
+}
+
+// Palette is a discrete colormap, where [0,1] is divided into equal steps for each color. Palettes are used for categorical data and for classes of data.
+type Palette []color.RGBA
+
+// At returns the color of the step containing t, which is clamped to [0,1].
+func (p Palette) At(t float64) color.RGBA {
+	if len(p) == 0 {
+		return color.RGBA{}
+	}
+	i := int(t * float64(len(p)))
+	if i < 0 || math.IsNaN(t) {
+		i = 0
+	} else if len(p) <= i {
+		i = len(p) - 1
+	}
+	return p[i]
+}
+
+// Color returns the i-th color, repeating the colors when i is out of range.
+func (p Palette) Color(i int) color.RGBA {
+	if len(p) == 0 {
+		return color.RGBA{}
+	}
+	i %= len(p)
+	if i < 0 {
+		i += len(p)
+	}
+	return p[i]
+}
+
+// ColorScale maps data values to colors, where Scale maps the values to [0,1] and Colormap maps those to colors. The ticks of Scale are used by color bars.
+type ColorScale struct {
+	Scale    Scale
+	Colormap Colormap
+}
+
+// NewColorScale returns a color scale from min to max.
+func NewColorScale(min, max float64, colormap Colormap) ColorScale {
+	return ColorScale{&LinearScale{Min: min, Max: max, NumTicks: 5}, colormap}
+}
+
+// NewDivergingColorScale returns a color scale from min to max where center maps to the middle of the colormap, see DivergingScale.
+func NewDivergingColorScale(min, center, max float64, colormap Colormap) ColorScale {
+	return ColorScale{&DivergingScale{min, center, max}, colormap}
+}
+
+// Color returns the color of a value.
+func (s ColorScale) Color(v float64) color.RGBA {
+	return s.Colormap.At(s.Scale.Map(v))
+}
+
+// DivergingScale is a scale from Min to Max that maps Center to 0.5, and is linear on either side of Center.
+type DivergingScale struct {
+	Min, Center, Max float64
+}
+
+// Map maps a value to [0,1].
+func (s *DivergingScale) Map(v float64) float64 {
+	if v < s.Center {
+		if s.Center == s.Min {
+			return 0.0
+		}
+		return 0.5 * (v - s.Min) / (s.Center - s.Min)
+	}
+	if s.Max == s.Center {
+		return 1.0
+	}
+	return 0.5 + 0.5*(v-s.Center)/(s.Max-s.Center)
+}
+
+// Ticks returns ticks at round values.
+func (s *DivergingScale) Ticks() []Tick {
+	return (&LinearScale{Min: s.Min, Max: s.Max}).Ticks()
+}
+
+// interpolateColorSpace interpolates between two colors in the given color space.
+func interpolateColorSpace(space ColorSpace, a, b color.RGBA, t float64) color.RGBA {
+	if space == RGB {
+		return interpolateColor(a, b, t)
+	}
+	la, alphaA := toLab(a)
+	lb, alphaB := toLab(b)
+	if space == LCh {
+		ca, cb := math.Hypot(la[1], la[2]), math.Hypot(lb[1], lb[2])
+		ha, hb := math.Atan2(la[2], la[1]), math.Atan2(lb[2], lb[1])
+		if ca < 1e-3 {
+			ha = hb // the hue of grays is undefined
+		} else if cb < 1e-3 {
+			hb = ha
+		}
+		if math.Pi < hb-ha {
+			hb -= 2.0 * math.Pi
+		} else if hb-ha < -math.Pi {
+			hb += 2.0 * math.Pi
+		}
+		c, h := ca+t*(cb-ca), ha+t*(hb-ha)
+		l := la[0] + t*(lb[0]-la[0])
+		return fromLab([3]float64{l, c * math.Cos(h), c * math.Sin(h)}, alphaA+t*(alphaB-alphaA))
+	}
+	var lab [3]float64
+	for i := range lab {
+		lab[i] = la[i] + t*(lb[i]-la[i])
+	}
+	return fromLab(lab, alphaA+t*(alphaB-alphaA))
+}
+
+// toLab returns the CIELAB coordinates for the D65 white point and the alpha in [0,1] of a color.
+func toLab(col color.RGBA) ([3]float64, float64) {
+	if col.A == 0 {
+		return [3]float64{}, 0.0
+	}
+	alpha := float64(col.A) / 255.0
+	linear := func(c uint8) float64 {
+		v := float64(c) / 255.0 / alpha // undo premultiplied alpha
+		if v <= 0.04045 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	r, g, b := linear(col.R), linear(col.G), linear(col.B)
+	x := (0.4124564*r + 0.3575761*g + 0.1804375*b) / 0.95047
+	y := 0.2126729*r + 0.7151522*g + 0.0721750*b
+	z := (0.0193339*r + 0.1191920*g + 0.9503041*b) / 1.08883
+	f := func(t float64) float64 {
+		if 216.0/24389.0 < t {
+			return math.Cbrt(t)
+		}
+		return t*841.0/108.0 + 4.0/29.0
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+	return [3]float64{116.0*fy - 16.0, 500.0 * (fx - fy), 200.0 * (fy - fz)}, alpha
+}
+
+// fromLab returns the color of CIELAB coordinates and alpha in [0,1], clamping colors outside of sRGB.
+func fromLab(lab [3]float64, alpha float64) color.RGBA {
+	finv := func(t float64) float64 {
+		if 6.0/29.0 < t {
+			return t * t * t
+		}
+		return 108.0 / 841.0 * (t - 4.0/29.0)
+	}
+	fy := (lab[0] + 16.0) / 116.0
+	x := 0.95047 * finv(fy+lab[1]/500.0)
+	y := finv(fy)
+	z := 1.08883 * finv(fy-lab[2]/200.0)
+	encode := func(v float64) uint8 {
+		if v <= 0.0031308 {
+			v *= 12.92
+		} else {
+			v = 1.055*math.Pow(v, 1.0/2.4) - 0.055
+		}
+		v = math.Max(0.0, math.Min(1.0, v))
+		return uint8(v*alpha*255.0 + 0.5)
+	}
+	return color.RGBA{
+		encode(3.2404542*x - 1.5371385*y - 0.4985314*z),
+		encode(-0.9692660*x + 1.8760108*y + 0.0415560*z),
+		encode(0.0556434*x - 0.2040259*y + 1.0572252*z),
+		uint8(alpha*255.0 + 0.5),
+	}
+}
+
+// hexColors returns the colors of the hexadecimal RGB values.
+func hexColors(hex ...uint32) []color.RGBA {
+	colors := make([]color.RGBA, len(hex))
+	for i, h := range hex {
+		colors[i] = color.RGBA{uint8(h >> 16), uint8(h >> 8), uint8(h), 0xff}
+	}
+	return colors
+}
+
+// Perceptually uniform sequential colormaps of matplotlib, sampled and interpolated in CIELAB.
+var (
+	Viridis = Ramp{hexColors(0x440154, 0x472d7b, 0x3b528b, 0x2c728e, 0x21918c, 0x28ae80, 0x5ec962, 0xaddc30, 0xfde725), Lab}
+	Magma   = Ramp{hexColors(0x000004, 0x1c1044, 0x4f127b, 0x812581, 0xb5367a, 0xe55964, 0xfb8761, 0xfec287, 0xfcfdbf), Lab}
+	Cividis = Ramp{hexColors(0x00224e, 0x123570, 0x3b496c, 0x575d6d, 0x707173, 0x8a8678, 0xa59c74, 0xc3b369, 0xe1cc55, 0xfee838), Lab}
+)
+
+// Sequential ColorBrewer colormaps by Cynthia Brewer, with their nine classes interpolated in CIELAB. Use Samples for fewer classes.
+var (
+	Blues   = Ramp{hexColors(0xf7fbff, 0xdeebf7, 0xc6dbef, 0x9ecae1, 0x6baed6, 0x4292c6, 0x2171b5, 0x08519c, 0x08306b), Lab}
+	Greens  = Ramp{hexColors(0xf7fcf5, 0xe5f5e0, 0xc7e9c0, 0xa1d99b, 0x74c476, 0x41ab5d, 0x238b45, 0x006d2c, 0x00441b), Lab}
+	Greys   = Ramp{hexColors(0xffffff, 0xf0f0f0, 0xd9d9d9, 0xbdbdbd, 0x969696, 0x737373, 0x525252, 0x252525, 0x000000), Lab}
+	Oranges = Ramp{hexColors(0xfff5eb, 0xfee6ce, 0xfdd0a2, 0xfdae6b, 0xfd8d3c, 0xf16913, 0xd94801, 0xa63603, 0x7f2704), Lab}
+	Purples = Ramp{hexColors(0xfcfbfd, 0xefedf5, 0xdadaeb, 0xbcbddc, 0x9e9ac8, 0x807dba, 0x6a51a3, 0x54278f, 0x3f007d), Lab}
+	Reds    = Ramp{hexColors(0xfff5f0, 0xfee0d2, 0xfcbba1, 0xfc9272, 0xfb6a4a, 0xef3b2c, 0xcb181d, 0xa50f15, 0x67000d), Lab}
+	YlGnBu  = Ramp{hexColors(0xffffd9, 0xedf8b1, 0xc7e9b4, 0x7fcdbb, 0x41b6c4, 0x1d91c0, 0x225ea8, 0x253494, 0x081d58), Lab}
+	YlOrRd  = Ramp{hexColors(0xffffcc, 0xffeda0, 0xfed976, 0xfeb24c, 0xfd8d3c, 0xfc4e2a, 0xe31a1c, 0xbd0026, 0x800026), Lab}
+)
+
+// Diverging ColorBrewer colormaps by Cynthia Brewer, with their eleven classes interpolated in CIELAB.
+var (
+	BrBG     = Ramp{hexColors(0x543005, 0x8c510a, 0xbf812d, 0xdfc27d, 0xf6e8c3, 0xf5f5f5, 0xc7eae5, 0x80cdc1, 0x35978f, 0x01665e, 0x003c30), Lab}
+	PiYG     = Ramp{hexColors(0x8e0152, 0xc51b7d, 0xde77ae, 0xf1b6da, 0xfde0ef, 0xf7f7f7, 0xe6f5d0, 0xb8e186, 0x7fbc41, 0x4d9221, 0x276419), Lab}
+	RdBu     = Ramp{hexColors(0x67001f, 0xb2182b, 0xd6604d, 0xf4a582, 0xfddbc7, 0xf7f7f7, 0xd1e5f0, 0x92c5de, 0x4393c3, 0x2166ac, 0x053061), Lab}
+	RdYlGn   = Ramp{hexColors(0xa50026, 0xd73027, 0xf46d43, 0xfdae61, 0xfee08b, 0xffffbf, 0xd9ef8b, 0xa6d96a, 0x66bd63, 0x1a9850, 0x006837), Lab}
+	Spectral = Ramp{hexColors(0x9e0142, 0xd53e4f, 0xf46d43, 0xfdae61, 0xfee08b, 0xffffbf, 0xe6f598, 0xabdda4, 0x66c2a5, 0x3288bd, 0x5e4fa2), Lab}
+)
+
+// Qualitative ColorBrewer palettes by Cynthia Brewer, for categories.
+var (
+	Dark2  = Palette(hexColors(0x1b9e77, 0xd95f02, 0x7570b3, 0xe7298a, 0x66a61e, 0xe6ab02, 0xa6761d, 0x666666))
+	Paired = Palette(hexColors(0xa6cee3, 0x1f78b4, 0xb2df8a, 0x33a02c, 0xfb9a99, 0xe31a1c, 0xfdbf6f, 0xff7f00, 0xcab2d6, 0x6a3d9a, 0xffff99, 0xb15928))
+	Set1   = Palette(hexColors(0xe41a1c, 0x377eb8, 0x4daf4a, 0x984ea3, 0xff7f00, 0xffff33, 0xa65628, 0xf781bf, 0x999999))
+	Set2   = Palette(hexColors(0x66c2a5, 0xfc8d62, 0x8da0cb, 0xe78ac3, 0xa6d854, 0xffd92f, 0xe5c494, 0xb3b3b3))
+)
@@ -0,0 +1,75 @@
+package chart
+
+import (
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestLab(t *testing.T) {
+	lab, alpha := toLab(canvas.White)
+	test.Float(t, alpha, 1.0)
+	test.That(t, math.Abs(lab[0]-100.0) < 1e-3 && math.Abs(lab[1]) < 1e-3 && math.Abs(lab[2]) < 1e-3, "white")
+
+	for _, col := range []color.RGBA{canvas.Black, canvas.White, canvas.Red, {0x12, 0x34, 0x56, 0xff}, {0x40, 0x20, 0x10, 0x80}} {
+		lab, alpha := toLab(col)
+		test.T(t, fromLab(lab, alpha), col)
+	}
+}
+
+func TestRamp(t *testing.T) {
+	ramp := Ramp{[]color.RGBA{canvas.Black, canvas.White}, RGB}
+	test.T(t, ramp.At(-1.0), canvas.Black)
+	test.T(t, ramp.At(0.5), color.RGBA{0x80, 0x80, 0x80, 0xff})
+	test.T(t, ramp.At(2.0), canvas.White)
+	ramp.Space = Lab
+	test.T(t, ramp.At(0.5), color.RGBA{0x77, 0x77, 0x77, 0xff}) // L = 50
+	test.T(t, ramp.Samples(3), Palette{canvas.Black, {0x77, 0x77, 0x77, 0xff}, canvas.White})
+	test.T(t, ramp.Reverse().At(0.0), canvas.White)
+	test.T(t, Viridis.At(0.5), Viridis.Colors[4])
+
+	// LCh interpolates hues the shortest way around
+	a := fromLab([3]float64{50.0, 40.0 * math.Cos(-0.2), 40.0 * math.Sin(-0.2)}, 1.0)
+	b := fromLab([3]float64{50.0, 40.0 * math.Cos(0.2), 40.0 * math.Sin(0.2)}, 1.0)
+	lab, _ := toLab(Ramp{[]color.RGBA{a, b}, LCh}.At(0.5))
+	test.That(t, 35.0 < lab[1] && math.Abs(lab[2]) < 1.0, "hue near zero")
+	lab, _ = toLab(Ramp{[]color.RGBA{a, b}, Lab}.At(0.5))
+	test.That(t, lab[1] < 40.0, "Lab loses chroma")
+
+	// grays take the hue of the other color
+	lab, _ = toLab(Ramp{[]color.RGBA{canvas.Gray, canvas.Red}, LCh}.At(0.5))
+	labRed, _ := toLab(canvas.Red)
+	test.That(t, math.Abs(math.Atan2(lab[2], lab[1])-math.Atan2(labRed[2], labRed[1])) < 0.05, "hue of red")
+}
+
+func TestPalette(t *testing.T) {
+	p := Palette{canvas.Red, canvas.Green, canvas.Blue}
+	test.T(t, p.At(0.0), canvas.Red)
+	test.T(t, p.At(0.5), canvas.Green)
+	test.T(t, p.At(1.0), canvas.Blue)
+	test.T(t, p.Color(4), canvas.Green)
+	test.T(t, p.Color(-1), canvas.Blue)
+	test.T(t, Palette{}.At(0.5), color.RGBA{})
+}
+
+func TestColorScale(t *testing.T) {
+	s := &DivergingScale{-1.0, 0.0, 4.0}
+	test.Float(t, s.Map(-1.0), 0.0)
+	test.Float(t, s.Map(0.0), 0.5)
+	test.Float(t, s.Map(2.0), 0.75)
+	test.Float(t, s.Map(4.0), 1.0)
+	test.T(t, len(s.Ticks()), 6)
+
+	scale := NewDivergingColorScale(-1.0, 0.0, 4.0, RdBu)
+	test.T(t, scale.Color(0.0), RdBu.Colors[5])
+	test.T(t, scale.Color(10.0), RdBu.Colors[10])
+	test.T(t, NewColorScale(0.0, 10.0, Set1).Color(5.0), Set1[4])
+
+	test.Float(t, invertScale(s, nil, 0.75), 2.0)
+	test.Float(t, invertScale(&LinearScale{Min: 2.0, Max: 4.0}, nil, 0.5), 3.0)
+	test.Float(t, invertScale(&LogScale{1.0, 100.0}, nil, 0.5), 10.0)
+	test.Float(t, invertScale(NewCategoryScale("a", "b"), []Tick{{0.0, "a"}, {1.0, "b"}}, 0.5), 0.5)
+}
@@ -0,0 +1,281 @@
+package chart
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Isolines returns the lines along which the values of the grid equal level, in data coordinates, using marching squares between the centers of the cells. Values are interpolated linearly along the edges between cell centers, and saddle points are resolved by the mean of the four corners. Closed lines end with their first point, and cells with missing values are skipped.
+func (g Grid) Isolines(level float64) [][]canvas.Point {
+	nx, _ := g.Size()
+
+	// each crossing lies on an edge between two cell centers, with 2*(j*nx+i) the horizontal edge and 2*(j*nx+i)+1 the vertical edge starting at (i,j)
+	points := map[int]canvas.Point{}
+	neighbors := map[int][]int{}
+	order := []int{}
+	crossing := func(id, i0, j0, i1, j1 int) {
+		if _, ok := points[id]; ok {
+			return
+		}
+		v0, v1 := g.Value(i0, j0), g.Value(i1, j1)
+		t := (level - v0) / (v1 - v0)
+		x, y := g.Pos(float64(i0)+t*float64(i1-i0), float64(j0)+t*float64(j1-j0))
+		points[id] = canvas.Point{X: x, Y: y}
+		order = append(order, id)
+	}
+	segment := func(a, b int) {
+		neighbors[a] = append(neighbors[a], b)
+		neighbors[b] = append(neighbors[b], a)
+	}
+
+	for j := 0; j+1 < len(g.Values); j++ {
+		for i := 0; i+1 < nx; i++ {
+			// corners counter clockwise from the lower-left
+			a, b, c, d := g.Value(i, j), g.Value(i+1, j), g.Value(i+1, j+1), g.Value(i, j+1)
+			if math.IsNaN(a) || math.IsNaN(b) || math.IsNaN(c) || math.IsNaN(d) {
+				continue
+			}
+			bottom, right, top, left := 2*(j*nx+i), 2*(j*nx+i+1)+1, 2*((j+1)*nx+i), 2*(j*nx+i)+1
+			edges := []int{}
+			if (level <= a) != (level <= b) {
+				crossing(bottom, i, j, i+1, j)
+				edges = append(edges, bottom)
+			}
+			if (level <= b) != (level <= c) {
+				crossing(right, i+1, j, i+1, j+1)
+				edges = append(edges, right)
+			}
+			if (level <= d) != (level <= c) {
+				crossing(top, i, j+1, i+1, j+1)
+				edges = append(edges, top)
+			}
+			if (level <= a) != (level <= d) {
+				crossing(left, i, j, i, j+1)
+				edges = append(edges, left)
+			}
+			if len(edges) == 2 {
+				segment(edges[0], edges[1])
+			} else if len(edges) == 4 {
+				// saddle: separate the corners that are on the other side than the center
+				if (level <= (a+b+c+d)/4.0) == (level <= a) {
+					segment(bottom, right)
+					segment(top, left)
+				} else {
+					segment(left, bottom)
+					segment(right, top)
+				}
+			}
+		}
+	}
+
+	lines := [][]canvas.Point{}
+	visited := map[int]bool{}
+	follow := func(start int) {
+		line := []canvas.Point{points[start]}
+		visited[start] = true
+		prev, cur := -1, start
+		for {
+			next := -1
+			for _, id := range neighbors[cur] {
+				if id != prev && (!visited[id] || id == start && 2 < len(line)) {
+					next = id
+					break
+				}
+			}
+			if next == -1 {
+				break
+			}
+			line = append(line, points[next])
+			if next == start {
+				break
+			}
+			visited[next] = true
+			prev, cur = cur, next
+		}
+		if 1 < len(line) {
+			lines = append(lines, line)
+		}
+	}
+	for _, id := range order {
+		if !visited[id] && len(neighbors[id]) == 1 {
+			follow(id) // open lines that end at the boundary or at missing values
+		}
+	}
+	for _, id := range order {
+		if !visited[id] {
+			follow(id) // closed lines
+		}
+	}
+	return lines
+}
+
+// Contour is a series that draws the isolines of a grid at each level, with the levels as labels along the lines. Labels are placed about LabelSpacing apart on stretches of the lines that are nearly straight, and are left out where they would overlap other labels. The lines are broken where they are labeled.
+type Contour struct {
+	Grid
+	Levels []Tick       // values of the isolines and their labels
+	Style  canvas.Style // style of the lines
+	Colors Colormap     // colors of the lines from the lowest to the highest level at equal distances, the color of Style is used when nil
+
+	Font         *canvas.FontFamily // no labels are drawn when nil
+	FontSize     float64            // in points
+	TextColor    color.RGBA
+	LabelSpacing float64 // distance between labels along a line in millimeters
+}
+
+// NewContour returns a contour series of the values with about n levels at round values inside the range of the values, see NewGrid.
+func NewContour(values [][]float64, n int) *Contour {
+	grid := NewGrid(values)
+	min, max := grid.Range()
+	levels := []Tick{}
+	for _, tick := range (&LinearScale{min, max, n}).Ticks() {
+		if min < tick.Value && tick.Value < max {
+			levels = append(levels, tick)
+		}
+	}
+	return &Contour{
+		Grid:         grid,
+		Levels:       levels,
+		Style:        lineStyle(canvas.Black, 0.3),
+		FontSize:     6.0,
+		TextColor:    canvas.Black,
+		LabelSpacing: 50.0,
+	}
+}
+
+// Draw draws the series.
+func (s *Contour) Draw(ctx *canvas.Context, coord Coordinates) {
+	var face canvas.FontFace
+	if s.Font != nil {
+		face = s.Font.Face(s.FontSize, s.TextColor, canvas.FontRegular, canvas.FontNormal)
+	}
+	placed := []labelPlacement{}
+	for k, level := range s.Levels {
+		p := &canvas.Path{}
+		for _, line := range s.Isolines(level.Value) {
+			for i := range line {
+				line[i] = coord.Pos(line[i].X, line[i].Y)
+			}
+			var pieces [][]canvas.Point
+			if s.Font == nil || level.Label == "" {
+				pieces = [][]canvas.Point{line}
+			} else {
+				var labels []labelPlacement
+				pieces, labels = placeLineLabels(line, face.TextWidth(level.Label), face.Metrics().XHeight, s.LabelSpacing, placed)
+				for _, label := range labels {
+					ctx.Push()
+					ctx.Translate(label.pos.X, label.pos.Y)
+					ctx.Rotate(label.angle)
+					ctx.DrawText(0.0, -face.Metrics().XHeight/2.0, canvas.NewTextLine(face, level.Label, canvas.Center))
+					ctx.Pop()
+				}
+				placed = append(placed, labels...)
+			}
+			for _, piece := range pieces {
+				for i, pos := range piece {
+					if i == 0 {
+						p.MoveTo(pos.X, pos.Y)
+					} else {
+						p.LineTo(pos.X, pos.Y)
+					}
+				}
+			}
+		}
+
+		ctx.Style = s.Style
+		if s.Colors != nil {
+			t := 0.5
+			if 1 < len(s.Levels) {
+				t = float64(k) / float64(len(s.Levels)-1)
+			}
+			ctx.Style.StrokeColor = s.Colors.At(t)
+		}
+		ctx.DrawPath(0.0, 0.0, p)
+	}
+}
+
+// labelPlacement is a label on a line, centered at pos and rotated by angle in degrees, where radius is half its width including padding.
+type labelPlacement struct {
+	pos    canvas.Point
+	angle  float64
+	radius float64
+}
+
+// placeLineLabels places labels of the given width along a line in canvas coordinates about spacing apart, and returns the pieces of the line between the labels. Labels are placed only if the line is at least three times as long as the label, if the line is nearly straight beneath the label, and if the label does not overlap any of the placed labels. Labels are rotated to follow the line and are never upside down.
+func placeLineLabels(line []canvas.Point, width, padding, spacing float64, placed []labelPlacement) ([][]canvas.Point, []labelPlacement) {
+	lengths := make([]float64, len(line))
+	for i := 1; i < len(line); i++ {
+		lengths[i] = lengths[i-1] + line[i].Sub(line[i-1]).Length()
+	}
+	length := lengths[len(lengths)-1]
+	half := width/2.0 + padding
+	if length < 3.0*width || width <= 0.0 {
+		return [][]canvas.Point{line}, nil
+	}
+
+	n := 1
+	if 0.0 < spacing {
+		n = int(math.Max(1.0, math.Floor(length/spacing+0.5)))
+	}
+	labels := []labelPlacement{}
+	gaps := [][2]float64{}
+	for k := 0; k < n; k++ {
+		s := (float64(k) + 0.5) * length / float64(n)
+		a, b := pointAlong(line, lengths, s-width/2.0), pointAlong(line, lengths, s+width/2.0)
+		if b.Sub(a).Length() < 0.9*width {
+			continue // too curved
+		}
+		label := labelPlacement{pointAlong(line, lengths, s), 0.0, half}
+		overlaps := false
+		for _, other := range append(placed, labels...) {
+			if label.pos.Sub(other.pos).Length() < label.radius+other.radius {
+				overlaps = true
+				break
+			}
+		}
+		if overlaps {
+			continue
+		}
+		if b.X < a.X {
+			a, b = b, a
+		}
+		label.angle = b.Sub(a).Angle() * 180.0 / math.Pi
+		labels = append(labels, label)
+		gaps = append(gaps, [2]float64{s - half, s + half})
+	}
+
+	pieces := [][]canvas.Point{}
+	start := 0.0
+	for _, gap := range gaps {
+		pieces = append(pieces, subline(line, lengths, start, gap[0]))
+		start = gap[1]
+	}
+	pieces = append(pieces, subline(line, lengths, start, length))
+	return pieces, labels
+}
+
+// pointAlong returns the point at distance s along the line, where lengths are the distances of the points along the line.
+func pointAlong(line []canvas.Point, lengths []float64, s float64) canvas.Point {
+	for i := 1; i < len(line); i++ {
+		if s <= lengths[i] || i == len(line)-1 {
+			t := 0.0
+			if lengths[i] != lengths[i-1] {
+				t = (s - lengths[i-1]) / (lengths[i] - lengths[i-1])
+			}
+			return line[i-1].Interpolate(line[i], math.Max(0.0, math.Min(1.0, t)))
+		}
+	}
+	return line[0]
+}
+
+// subline returns the part of the line between the distances s0 and s1 along the line.
+func subline(line []canvas.Point, lengths []float64, s0, s1 float64) []canvas.Point {
+	points := []canvas.Point{pointAlong(line, lengths, s0)}
+	for i, pos := range line {
+		if s0 < lengths[i] && lengths[i] < s1 {
+			points = append(points, pos)
+		}
+	}
+	return append(points, pointAlong(line, lengths, s1))
+}
@@ -0,0 +1,89 @@
+package chart
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestIsolines(t *testing.T) {
+	g := NewGrid([][]float64{{0, 0, 0}, {0, 1, 0}, {0, 0, 0}})
+	lines := g.Isolines(0.5)
+	test.T(t, len(lines), 1)
+	test.T(t, len(lines[0]), 5)
+	test.T(t, lines[0][0], lines[0][4]) // closed
+	test.T(t, lines[0][0], canvas.Point{X: 1.0, Y: 0.5})
+
+	// open line through the grid
+	lines = NewGrid([][]float64{{0, 1}, {0, 2}}).Isolines(0.5)
+	test.T(t, lines, [][]canvas.Point{{{X: 0.5, Y: 0.0}, {X: 0.25, Y: 1.0}}})
+
+	// saddles
+	g = NewGrid([][]float64{{1, 0}, {0, 1}})
+	test.T(t, len(g.Isolines(0.4)), 2)
+	test.T(t, len(g.Isolines(0.6)), 2)
+
+	// missing values
+	g = NewGrid([][]float64{{0, 1, 0}, {0, math.NaN(), 0}})
+	test.T(t, len(g.Isolines(0.5)), 0)
+}
+
+func TestPlaceLineLabels(t *testing.T) {
+	line := []canvas.Point{{X: 0.0, Y: 0.0}, {X: 50.0, Y: 0.0}, {X: 100.0, Y: 0.0}}
+	pieces, labels := placeLineLabels(line, 10.0, 1.0, 50.0, nil)
+	test.T(t, len(labels), 2)
+	test.T(t, labels[0].pos, canvas.Point{X: 25.0, Y: 0.0})
+	test.Float(t, labels[0].angle, 0.0)
+	test.T(t, pieces, [][]canvas.Point{
+		{{X: 0.0, Y: 0.0}, {X: 19.0, Y: 0.0}},
+		{{X: 31.0, Y: 0.0}, {X: 50.0, Y: 0.0}, {X: 69.0, Y: 0.0}},
+		{{X: 81.0, Y: 0.0}, {X: 100.0, Y: 0.0}},
+	})
+
+	// never upside down
+	_, labels = placeLineLabels([]canvas.Point{{X: 100.0, Y: 0.0}, {X: 0.0, Y: 0.0}}, 10.0, 1.0, 50.0, nil)
+	test.Float(t, labels[0].angle, 0.0)
+
+	// overlap
+	_, labels = placeLineLabels(line, 10.0, 1.0, 50.0, []labelPlacement{{canvas.Point{X: 25.0, Y: 2.0}, 0.0, 6.0}})
+	test.T(t, len(labels), 1)
+	test.T(t, labels[0].pos, canvas.Point{X: 75.0, Y: 0.0})
+
+	// too short or curved
+	_, labels = placeLineLabels(line[:2], 20.0, 1.0, 50.0, nil)
+	test.T(t, len(labels), 0)
+	_, labels = placeLineLabels([]canvas.Point{{X: 0.0, Y: 0.0}, {X: 20.0, Y: 0.0}, {X: 20.0, Y: 20.0}}, 10.0, 1.0, 50.0, nil)
+	test.T(t, len(labels), 0)
+}
+
+func TestContour(t *testing.T) {
+	values := make([][]float64, 21)
+	for j := range values {
+		values[j] = make([]float64, 21)
+		for i := range values[j] {
+			values[j][i] = math.Hypot(float64(i-10), float64(j-10))
+		}
+	}
+	s := NewContour(values, 5)
+	test.T(t, len(s.Levels), 7)
+	test.T(t, s.Levels[0], Tick{2.0, "2"})
+	test.T(t, s.Extent(), canvas.Rect{X: -0.5, Y: -0.5, W: 21.0, H: 21.0})
+
+	coord := Cartesian{&LinearScale{Min: -0.5, Max: 20.5}, &LinearScale{Min: -0.5, Max: 20.5}, 100.0, 100.0}
+	r := &recorder{}
+	s.Draw(canvas.NewContext(r), coord)
+	test.T(t, len(r.paths), 7)
+	test.T(t, r.texts, 0) // no font
+
+	family := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+	s.Font = family
+	s.Colors = Viridis
+	r = &recorder{}
+	s.Draw(canvas.NewContext(r), coord)
+	test.T(t, len(r.paths), 7)
+	test.That(t, 7 <= r.texts, "expected labels on every level")
+	test.T(t, r.styles[6].StrokeColor, Viridis.Colors[8])
+}
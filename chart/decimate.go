@@ -0,0 +1,111 @@
+package chart
+
+import (
+	"math"
+
+	"github.com/tdewolff/canvas"
+)
+
+// m4 returns the indices of the points to keep when reducing consecutive points in the same column of the given width along x to the first, lowest, highest, and last point, in their original order.
+func m4(n int, x, y func(int) float64, width float64) []int {
+	indices := []int{}
+	for i := 0; i < n; {
+		column := math.Floor(x(i) / width)
+		first, last, lowest, highest := i, i, i, i
+		for i++; i < n && math.Floor(x(i)/width) == column; i++ {
+			last = i
+			if y(i) < y(lowest) {
+				lowest = i
+			}
+			if y(highest) < y(i) {
+				highest = i
+			}
+		}
+		if highest < lowest {
+			lowest, highest = highest, lowest
+		}
+		for _, j := range []int{first, lowest, highest, last} {
+			if len(indices) == 0 || indices[len(indices)-1] != j {
+				indices = append(indices, j)
+			}
+		}
+	}
+	return indices
+}
+
+// M4 decimates a series by keeping only the first, lowest, highest, and last point of the consecutive points in each column of the given width along x, after Jugel et al. A line through the decimated points is drawn the same as the line through all points when the columns are no wider than a pixel, but has at most four points per column. The series is usually sorted by x, such as a time series.
+func M4(x, y []float64, width float64) ([]float64, []float64) {
+	n := len(x)
+	if len(y) < n {
+		n = len(y)
+	}
+	if width <= 0.0 {
+		return x[:n], y[:n]
+	}
+	indices := m4(n, func(i int) float64 { return x[i] - x[0] }, func(i int) float64 { return y[i] }, width)
+	xs, ys := make([]float64, len(indices)), make([]float64, len(indices))
+	for k, i := range indices {
+		xs[k], ys[k] = x[i], y[i]
+	}
+	return xs, ys
+}
+
+// LTTB decimates a series to n points using largest triangle three buckets, after Steinarsson. The first and last point are kept, and the other points are divided into n-2 buckets of which the point is kept that forms the largest triangle with the previously kept point and the average of the next bucket. Unlike M4, it keeps the overall shape of a series rather than drawing it the same, and is suited for reducing a series to a fixed number of points regardless of the resolution.
+func LTTB(x, y []float64, n int) ([]float64, []float64) {
+	m := len(x)
+	if len(y) < m {
+		m = len(y)
+	}
+	if m <= n || n < 3 {
+		return x[:m], y[:m]
+	}
+
+	xs, ys := make([]float64, 0, n), make([]float64, 0, n)
+	xs, ys = append(xs, x[0]), append(ys, y[0])
+	every := float64(m-2) / float64(n-2)
+	a := 0
+	for k := 0; k < n-2; k++ {
+		// average of the next bucket, which is the last point for the last bucket
+		next0, next1 := int(float64(k+1)*every)+1, int(float64(k+2)*every)+1
+		if m-1 < next1 {
+			next1 = m - 1
+		}
+		if next1 <= next0 {
+			next0, next1 = m-1, m
+		}
+		avgX, avgY := 0.0, 0.0
+		for i := next0; i < next1; i++ {
+			avgX += x[i]
+			avgY += y[i]
+		}
+		avgX /= float64(next1 - next0)
+		avgY /= float64(next1 - next0)
+
+		area, best := -1.0, int(float64(k)*every)+1
+		for i := best; i < int(float64(k+1)*every)+1 && i < m-1; i++ {
+			// twice the area of the triangle
+			if d := math.Abs((x[a]-avgX)*(y[i]-y[a]) - (x[a]-x[i])*(avgY-y[a])); area < d {
+				area, best = d, i
+			}
+		}
+		xs, ys = append(xs, x[best]), append(ys, y[best])
+		a = best
+	}
+	return append(xs, x[m-1]), append(ys, y[m-1])
+}
+
+// decimatePoints reduces points in canvas coordinates with M4 for columns of the given width in millimeters.
+func decimatePoints(points []canvas.Point, width float64) []canvas.Point {
+	if width <= 0.0 {
+		return points
+	}
+	indices := m4(len(points), func(i int) float64 { return points[i].X }, func(i int) float64 { return points[i].Y }, width)
+	if len(indices) == len(points) {
+		return points
+	}
+	decimated := make([]canvas.Point, len(indices))
+	for k, i := range indices {
+		decimated[k] = points[i]
+	}
+	return decimated
+}
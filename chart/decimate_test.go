@@ -0,0 +1,52 @@
+package chart
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestM4(t *testing.T) {
+	x := []float64{0.0, 0.1, 0.2, 0.3, 0.4, 1.0, 1.5, 2.5}
+	y := []float64{1.0, 5.0, -2.0, 3.0, 2.0, 0.0, 1.0, 4.0}
+	xs, ys := M4(x, y, 1.0)
+	test.T(t, xs, []float64{0.0, 0.1, 0.2, 0.4, 1.0, 1.5, 2.5})
+	test.T(t, ys, []float64{1.0, 5.0, -2.0, 2.0, 0.0, 1.0, 4.0})
+
+	xs, ys = M4(x, y[:3], 0.0)
+	test.T(t, len(xs), 3)
+	test.T(t, len(ys), 3)
+}
+
+func TestLTTB(t *testing.T) {
+	x := []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	y := []float64{0, 0, 9, 0, 0, 0, 0, -9, 0, 0}
+	xs, ys := LTTB(x, y, 4)
+	test.T(t, xs, []float64{0, 2, 7, 9})
+	test.T(t, ys, []float64{0, 9, -9, 0})
+
+	xs, _ = LTTB(x, y, 20)
+	test.T(t, len(xs), 10)
+}
+
+func TestLineDecimation(t *testing.T) {
+	n := 100000
+	x, y := make([]float64, n), make([]float64, n)
+	for i := range x {
+		x[i] = float64(i)
+		y[i] = math.Sin(float64(i)/1000.0) + 0.1*math.Sin(float64(i)*1.7)
+	}
+	line := NewLine(x, y)
+	coord := Cartesian{&LinearScale{Min: 0.0, Max: float64(n)}, &LinearScale{Min: -1.5, Max: 1.5}, 100.0, 50.0}
+	p := line.Path(coord)
+	test.That(t, len(canvas.PolylineFromPath(p).Coords()) <= 4*5000, "decimated to four points per column")
+
+	line.Resolution = 0.0
+	full := line.Path(coord)
+	test.T(t, len(canvas.PolylineFromPath(full).Coords()), n)
+	test.T(t, p.Bounds(), full.Bounds())
+	test.T(t, p.StartPos(), full.StartPos())
+	test.T(t, p.Pos(), full.Pos())
+}
@@ -0,0 +1,369 @@
+package chart
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Task is a row of a Gantt chart, which is a bar from Start to End or a milestone at Start when End is not after Start.
+type Task struct {
+	Name       string
+	Start, End time.Time
+	Color      color.RGBA // uses the colors of the chart when zero
+}
+
+// Milestone returns true if the task is a milestone.
+func (t Task) Milestone() bool {
+	return !t.End.After(t.Start)
+}
+
+// Gantt is a timeline of tasks, with a column of names on the left that are wrapped to fit, and a date axis at the top whose ticks are days, weeks, or months depending on DayWidth. Long timelines can be split into pages, which each repeat the column of names.
+type Gantt struct {
+	Tasks      []Task
+	Start, End time.Time // range of the timeline, chosen to include all tasks in whole days when zero
+
+	DayWidth   float64 // in millimeters
+	RowHeight  float64 // minimum height of the rows in millimeters, rows grow to fit wrapped names
+	LabelWidth float64 // width of the column of names in millimeters
+
+	Colors    []color.RGBA
+	Font      *canvas.FontFamily // no text is drawn when nil
+	FontSize  float64            // in points
+	TextColor color.RGBA
+	AxisColor color.RGBA
+	GridColor color.RGBA
+	LineWidth float64 // width of the grid lines in millimeters
+}
+
+// NewGantt returns a Gantt chart of the tasks with a day width of 4 millimeters.
+func NewGantt(tasks []Task) *Gantt {
+	return &Gantt{
+		Tasks:      tasks,
+		DayWidth:   4.0,
+		RowHeight:  7.0,
+		LabelWidth: 35.0,
+		Colors:     DefaultColors,
+		FontSize:   8.0,
+		TextColor:  canvas.Black,
+		AxisColor:  canvas.Black,
+		GridColor:  color.RGBA{0xdd, 0xdd, 0xdd, 0xff},
+		LineWidth:  0.2,
+	}
+}
+
+// midnight returns the start of the day of t.
+func midnight(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// Range returns the range of the timeline.
+func (g *Gantt) Range() (time.Time, time.Time) {
+	start, end := g.Start, g.End
+	if start.IsZero() || end.IsZero() {
+		var first, last time.Time
+		for i, task := range g.Tasks {
+			taskEnd := task.End
+			if task.Milestone() {
+				taskEnd = task.Start
+			}
+			if i == 0 || task.Start.Before(first) {
+				first = task.Start
+			}
+			if i == 0 || taskEnd.After(last) {
+				last = taskEnd
+			}
+		}
+		if start.IsZero() {
+			start = midnight(first)
+		}
+		if end.IsZero() {
+			end = midnight(last)
+			if !end.After(last) || !end.After(start) {
+				end = end.AddDate(0, 0, 1)
+			}
+		}
+	}
+	return start, end
+}
+
+// days returns the number of days between two times, which is not an integer for times that are not at midnight and may be off by an hour for daylight saving time.
+func days(from, to time.Time) float64 {
+	return to.Sub(from).Hours() / 24.0
+}
+
+func (g *Gantt) face() (canvas.FontFace, bool) {
+	if g.Font == nil {
+		return canvas.FontFace{}, false
+	}
+	return g.Font.Face(g.FontSize, g.TextColor, canvas.FontRegular, canvas.FontNormal), true
+}
+
+// tickFace returns the font face of the date axis, which is smaller than the names.
+func (g *Gantt) tickFace() (canvas.FontFace, bool) {
+	if g.Font == nil {
+		return canvas.FontFace{}, false
+	}
+	return g.Font.Face(0.75*g.FontSize, g.TextColor, canvas.FontRegular, canvas.FontNormal), true
+}
+
+// padding returns the space around text.
+func (g *Gantt) padding() float64 {
+	if face, ok := g.face(); ok {
+		return face.Metrics().XHeight
+	}
+	return 1.0
+}
+
+// rows returns the heights of the rows and the wrapped names.
+func (g *Gantt) rows() ([]float64, []*canvas.Text) {
+	heights := make([]float64, len(g.Tasks))
+	texts := make([]*canvas.Text, len(g.Tasks))
+	face, hasFont := g.face()
+	pad := g.padding()
+	for i, task := range g.Tasks {
+		heights[i] = g.RowHeight
+		if hasFont {
+			texts[i] = canvas.NewTextBox(face, task.Name, g.LabelWidth-2.0*pad, 0.0, canvas.Left, canvas.Top, 0.0, 0.0)
+			heights[i] = math.Max(heights[i], texts[i].Height()+pad)
+		}
+	}
+	return heights, texts
+}
+
+// tierHeight returns the height of one of the two tiers of the date axis.
+func (g *Gantt) tierHeight() float64 {
+	if face, ok := g.tickFace(); ok {
+		return face.Metrics().LineHeight + face.Metrics().XHeight
+	}
+	return g.RowHeight / 2.0
+}
+
+// Size returns the width and height of the chart.
+func (g *Gantt) Size() (float64, float64) {
+	start, end := g.Range()
+	heights, _ := g.rows()
+	height := 2.0 * g.tierHeight()
+	for _, h := range heights {
+		height += h
+	}
+	return g.LabelWidth + days(start, end)*g.DayWidth, height
+}
+
+// Draw draws the chart with its lower-left corner at (x,y).
+func (g *Gantt) Draw(ctx *canvas.Context, x, y float64) {
+	start, end := g.Range()
+	g.DrawRange(ctx, x, y, start, end)
+}
+
+// dateTick is a position on the date axis.
+type dateTick struct {
+	t     time.Time
+	label string
+}
+
+// ticks returns the ticks of the lower and upper tiers of the date axis in [from,to], where the ticks before from are included for the labels of the spans that start before from.
+func (g *Gantt) ticks(from, to time.Time) ([]dateTick, []dateTick) {
+	lower, upper := []dateTick{}, []dateTick{}
+	if g.DayWidth < 1.0 {
+		// months and years
+		for t := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location()); !t.After(to); t = t.AddDate(0, 1, 0) {
+			lower = append(lower, dateTick{t, t.Format("Jan")})
+			if t.Month() == time.January || len(upper) == 0 {
+				upper = append(upper, dateTick{time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, t.Location()), strconv.Itoa(t.Year())})
+			}
+		}
+		return lower, upper
+	}
+
+	// days or weeks, and months
+	for t := midnight(from).AddDate(0, 0, -6); !t.After(to); t = t.AddDate(0, 0, 1) {
+		if 4.0 <= g.DayWidth || t.Weekday() == time.Monday {
+			lower = append(lower, dateTick{t, strconv.Itoa(t.Day())})
+		}
+		if t.Day() == 1 || len(upper) == 0 {
+			upper = append(upper, dateTick{time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()), t.Format("January 2006")})
+		}
+	}
+	return lower, upper
+}
+
+// DrawRange draws the part of the chart between from and to, with its lower-left corner at (x,y). The column of names is always drawn, and tasks are cut off at from and to.
+func (g *Gantt) DrawRange(ctx *canvas.Context, x, y float64, from, to time.Time) {
+	heights, texts := g.rows()
+	tier := g.tierHeight()
+	height := 2.0 * tier
+	for _, h := range heights {
+		height += h
+	}
+	width := days(from, to) * g.DayWidth
+	pos := func(t time.Time) float64 {
+		return g.LabelWidth + days(from, t)*g.DayWidth
+	}
+	visible := func(t time.Time) bool {
+		return !t.Before(from) && !t.After(to)
+	}
+
+	ctx.Push()
+	defer ctx.Pop()
+	ctx.Translate(x, y)
+
+	// grid lines between rows and at the ticks of the lower tier
+	lower, upper := g.ticks(from, to)
+	grid := &canvas.Path{}
+	rowTop := height - 2.0*tier
+	for _, h := range heights {
+		rowTop -= h
+		grid.MoveTo(0.0, rowTop)
+		grid.LineTo(g.LabelWidth+width, rowTop)
+	}
+	for _, tick := range lower {
+		if visible(tick.t) {
+			grid.MoveTo(pos(tick.t), 0.0)
+			grid.LineTo(pos(tick.t), height-tier)
+		}
+	}
+	strokeStyle(ctx, g.GridColor, g.LineWidth)
+	ctx.DrawPath(0.0, 0.0, grid)
+
+	// bars and milestones
+	rowTop = height - 2.0*tier
+	for i, task := range g.Tasks {
+		col := task.Color
+		if col == (color.RGBA{}) {
+			if len(g.Colors) == 0 {
+				col = DefaultColors[i%len(DefaultColors)]
+			} else {
+				col = g.Colors[i%len(g.Colors)]
+			}
+		}
+		center := rowTop - heights[i]/2.0
+		size := 0.6 * g.RowHeight
+		ctx.Style = fillStyle(col)
+		if task.Milestone() {
+			if visible(task.Start) {
+				diamond := &canvas.Path{}
+				diamond.MoveTo(0.0, size/2.0)
+				diamond.LineTo(size/2.0, 0.0)
+				diamond.LineTo(0.0, -size/2.0)
+				diamond.LineTo(-size/2.0, 0.0)
+				diamond.Close()
+				ctx.DrawPath(pos(task.Start), center, diamond)
+			}
+		} else if task.Start.Before(to) && task.End.After(from) {
+			x0 := pos(task.Start)
+			if task.Start.Before(from) {
+				x0 = pos(from)
+			}
+			x1 := pos(task.End)
+			if task.End.After(to) {
+				x1 = pos(to)
+			}
+			ctx.DrawPath(x0, center-size/2.0, canvas.Rectangle(x1-x0, size))
+		}
+		rowTop -= heights[i]
+	}
+
+	// axes
+	axes := &canvas.Path{}
+	axes.MoveTo(0.0, height)
+	axes.LineTo(g.LabelWidth+width, height)
+	axes.MoveTo(0.0, height-2.0*tier)
+	axes.LineTo(g.LabelWidth+width, height-2.0*tier)
+	axes.MoveTo(g.LabelWidth, 0.0)
+	axes.LineTo(g.LabelWidth, height)
+	for _, tick := range upper {
+		if visible(tick.t) {
+			axes.MoveTo(pos(tick.t), height-2.0*tier)
+			axes.LineTo(pos(tick.t), height)
+		}
+	}
+	strokeStyle(ctx, g.AxisColor, g.LineWidth)
+	ctx.DrawPath(0.0, 0.0, axes)
+
+	face, ok := g.tickFace()
+	if !ok {
+		return
+	}
+	rowTop = height - 2.0*tier
+	for i, text := range texts {
+		ctx.DrawText(g.padding(), rowTop-(heights[i]-text.Height())/2.0, text)
+		rowTop -= heights[i]
+	}
+	pad := face.Metrics().XHeight / 2.0
+	y0 := tier/2.0 - face.Metrics().XHeight/2.0
+	drawTier := func(ticks []dateTick, top float64, align canvas.TextAlign) {
+		for k, tick := range ticks {
+			next := to
+			if k+1 < len(ticks) && ticks[k+1].t.Before(to) {
+				next = ticks[k+1].t
+			}
+			left := tick.t
+			if left.Before(from) {
+				if top != height {
+					continue // only the upper tier labels spans that start before from
+				}
+				left = from
+			}
+			if !next.After(left) {
+				continue
+			}
+			// draw the label when it fits in the visible part of its span
+			if x0, x1 := pos(left), pos(next); face.TextWidth(tick.label)+pad <= x1-x0 {
+				x := x0 + pad
+				if align == canvas.Center {
+					x = (x0 + x1) / 2.0
+				}
+				ctx.DrawText(x, top-tier+y0, canvas.NewTextLine(face, tick.label, align))
+			}
+		}
+	}
+	drawTier(upper, height, canvas.Left)
+	if 4.0 <= g.DayWidth {
+		drawTier(lower, height-tier, canvas.Center)
+	} else {
+		drawTier(lower, height-tier, canvas.Left)
+	}
+}
+
+// PageRanges returns the ranges of the timeline for pages where the timeline, excluding the column of names, is at most width millimeters wide. Pages start at midnight and contain at least one day.
+func (g *Gantt) PageRanges(width float64) [][2]time.Time {
+	start, end := g.Range()
+	n := int(math.Floor((width - g.LabelWidth) / g.DayWidth))
+	if n < 1 {
+		n = 1
+	}
+	ranges := [][2]time.Time{}
+	for from := start; from.Before(end); {
+		to := midnight(from).AddDate(0, 0, n)
+		if end.Before(to) {
+			to = end
+		}
+		ranges = append(ranges, [2]time.Time{from, to})
+		from = to
+	}
+	return ranges
+}
+
+// WritePDF writes the chart as a PDF with pages of width by height millimeters and the given margin, where the timeline is split into as many pages as needed. It returns an error when the rows do not fit the height of the page.
+func (g *Gantt) WritePDF(w io.Writer, width, height, margin float64) error {
+	_, h := g.Size()
+	if height-2.0*margin < h {
+		return fmt.Errorf("chart of %.1fmm high does not fit page", h)
+	}
+	pdf := canvas.NewPDF(w, width, height)
+	for i, r := range g.PageRanges(width - 2.0*margin) {
+		if 0 < i {
+			pdf.NewPage(width, height)
+		}
+		ctx := canvas.NewContext(pdf)
+		g.DrawRange(ctx, margin, height-margin-h, r[0], r[1])
+	}
+	return pdf.Close()
+}
@@ -0,0 +1,103 @@
+package chart
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func date(month time.Month, d, hour int) time.Time {
+	return time.Date(2023, month, d, hour, 0, 0, 0, time.UTC)
+}
+
+func TestGantt(t *testing.T) {
+	g := NewGantt([]Task{
+		{Name: "a", Start: date(time.January, 30, 12), End: date(time.February, 3, 0)},
+		{Name: "b", Start: date(time.February, 5, 0)},
+	})
+	test.That(t, !g.Tasks[0].Milestone())
+	test.That(t, g.Tasks[1].Milestone())
+
+	start, end := g.Range()
+	test.T(t, start, date(time.January, 30, 0))
+	test.T(t, end, date(time.February, 6, 0))
+	w, h := g.Size()
+	test.Float(t, w, 35.0+7*4.0)
+	test.Float(t, h, 2*3.5+2*7.0)
+
+	r := &recorder{}
+	g.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, len(r.paths), 4) // grid, bar, milestone, axes
+	test.T(t, r.paths[1].Bounds(), canvas.Rect{X: 37.0, Y: 8.4, W: 14.0, H: 4.2})
+	test.T(t, r.paths[2].Bounds(), canvas.Rect{X: 56.9, Y: 1.4, W: 4.2, H: 4.2})
+	test.T(t, r.texts, 0) // no font
+
+	// bars are cut off at the range
+	r = &recorder{}
+	g.DrawRange(canvas.NewContext(r), 0.0, 0.0, date(time.February, 2, 0), date(time.February, 5, 0))
+	test.T(t, len(r.paths), 4)
+	test.T(t, r.paths[1].Bounds(), canvas.Rect{X: 35.0, Y: 8.4, W: 4.0, H: 4.2})
+	r = &recorder{}
+	g.DrawRange(canvas.NewContext(r), 0.0, 0.0, date(time.February, 3, 0), date(time.February, 4, 0))
+	test.T(t, len(r.paths), 2) // grid, axes
+}
+
+func TestGanttTicks(t *testing.T) {
+	g := NewGantt(nil)
+	lower, upper := g.ticks(date(time.January, 30, 0), date(time.February, 2, 0))
+	test.T(t, len(lower), 10) // from six days before
+	test.T(t, lower[9].label, "2")
+	test.T(t, len(upper), 2)
+	test.T(t, upper[1].label, "February 2023")
+
+	g.DayWidth = 2.0
+	lower, _ = g.ticks(date(time.January, 30, 0), date(time.February, 14, 0))
+	test.T(t, len(lower), 3) // Mondays
+	test.T(t, lower[0].t, date(time.January, 30, 0))
+
+	g.DayWidth = 0.5
+	lower, upper = g.ticks(date(time.November, 15, 0), date(time.December, 31, 0))
+	test.T(t, len(lower), 2)
+	test.T(t, lower[0].label, "Nov")
+	test.T(t, upper[0].label, "2023")
+}
+
+func TestGanttLabels(t *testing.T) {
+	family := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+
+	g := NewGantt([]Task{
+		{Name: "short", Start: date(time.March, 1, 0), End: date(time.March, 20, 0)},
+		{Name: "a much longer name that is wrapped over lines", Start: date(time.March, 5, 0), End: date(time.March, 10, 0)},
+	})
+	g.Font = family
+	heights, texts := g.rows()
+	test.Float(t, heights[0], g.RowHeight)
+	test.That(t, g.RowHeight < heights[1], "long names must wrap")
+	test.That(t, texts[1].Bounds().W <= g.LabelWidth)
+
+	r := &recorder{}
+	g.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.That(t, 2+1+19 <= r.texts, "expected names, month, and days")
+}
+
+func TestGanttPages(t *testing.T) {
+	g := NewGantt([]Task{
+		{Name: "a", Start: date(time.January, 30, 12), End: date(time.February, 3, 0)},
+		{Name: "b", Start: date(time.February, 5, 0)},
+	})
+	test.T(t, g.PageRanges(35.0+12.0), [][2]time.Time{
+		{date(time.January, 30, 0), date(time.February, 2, 0)},
+		{date(time.February, 2, 0), date(time.February, 5, 0)},
+		{date(time.February, 5, 0), date(time.February, 6, 0)},
+	})
+	test.T(t, len(g.PageRanges(0.0)), 7)
+
+	buf := &bytes.Buffer{}
+	test.Error(t, g.WritePDF(buf, 57.0, 40.0, 5.0))
+	test.That(t, 0 < buf.Len())
+	test.That(t, g.WritePDF(&bytes.Buffer{}, 57.0, 20.0, 5.0) != nil, "rows must not fit")
+}
@@ -0,0 +1,111 @@
+package chart
+
+import (
+	"math"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Grid is a two-dimensional grid of values, where Values[j][i] is the value at column i of row j, with rows from bottom to top. The grid covers the range Rect in data coordinates, so that each value is the center of a cell of equal size. NaN values are missing.
+type Grid struct {
+	Values [][]float64
+	Rect   canvas.Rect
+}
+
+// NewGrid returns a grid of the values, where the value at column i and row j is centered at (i,j) in data coordinates.
+func NewGrid(values [][]float64) Grid {
+	nx, ny := gridSize(values)
+	return Grid{values, canvas.Rect{X: -0.5, Y: -0.5, W: float64(nx), H: float64(ny)}}
+}
+
+// gridSize returns the number of columns and rows, where the number of columns is that of the longest row.
+func gridSize(values [][]float64) (int, int) {
+	nx := 0
+	for _, row := range values {
+		if nx < len(row) {
+			nx = len(row)
+		}
+	}
+	return nx, len(values)
+}
+
+// Size returns the number of columns and rows.
+func (g Grid) Size() (int, int) {
+	return gridSize(g.Values)
+}
+
+// Value returns the value at column i and row j, or NaN when it is outside the grid.
+func (g Grid) Value(i, j int) float64 {
+	if j < 0 || len(g.Values) <= j || i < 0 || len(g.Values[j]) <= i {
+		return math.NaN()
+	}
+	return g.Values[j][i]
+}
+
+// Pos returns the data coordinates of the center of the cell at column i and row j, which may be fractional.
+func (g Grid) Pos(i, j float64) (float64, float64) {
+	nx, ny := g.Size()
+	return g.Rect.X + (i+0.5)*g.Rect.W/float64(nx), g.Rect.Y + (j+0.5)*g.Rect.H/float64(ny)
+}
+
+// Range returns the smallest and largest value, ignoring NaN values.
+func (g Grid) Range() (float64, float64) {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, row := range g.Values {
+		for _, v := range row {
+			if !math.IsNaN(v) {
+				min, max = math.Min(min, v), math.Max(max, v)
+			}
+		}
+	}
+	if max < min {
+		return 0.0, 0.0
+	}
+	return min, max
+}
+
+// Extent returns the range of the grid.
+func (g Grid) Extent() canvas.Rect {
+	return g.Rect
+}
+
+// Heatmap is a series that draws each value of a grid as a cell filled with the color of its value.
+type Heatmap struct {
+	Grid
+	Colors ColorScale
+}
+
+// NewHeatmap returns a heatmap of the values, see NewGrid, with the Viridis colormap over the range of the values.
+func NewHeatmap(values [][]float64) *Heatmap {
+	grid := NewGrid(values)
+	min, max := grid.Range()
+	return &Heatmap{grid, NewColorScale(min, max, Viridis)}
+}
+
+// heatmapOverlap is the fraction of a cell by which cells overlap their right and upper neighbors.
+const heatmapOverlap = 0.1
+
+// Draw draws the series. Cells are drawn as polygons through the transformed corners, so that they also fit non-Cartesian coordinates.
+func (s *Heatmap) Draw(ctx *canvas.Context, coord Coordinates) {
+	nx, ny := s.Size()
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			v := s.Value(i, j)
+			if math.IsNaN(v) {
+				continue
+			}
+			// overlap the neighbors that are drawn later to hide antialiasing seams
+			dx, dy := 0.0, 0.0
+			if !math.IsNaN(s.Value(i+1, j)) {
+				dx = heatmapOverlap
+			}
+			if !math.IsNaN(s.Value(i, j+1)) {
+				dy = heatmapOverlap
+			}
+			x0, y0 := s.Pos(float64(i)-0.5, float64(j)-0.5)
+			x1, y1 := s.Pos(float64(i)+0.5+dx, float64(j)+0.5+dy)
+			ctx.Style = fillStyle(s.Colors.Color(v))
+			ctx.DrawPath(0.0, 0.0, polygon(coord, false, [][2]float64{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}))
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package chart
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestGrid(t *testing.T) {
+	g := NewGrid([][]float64{{0, 1, 2}, {3, math.NaN()}})
+	nx, ny := g.Size()
+	test.T(t, nx, 3)
+	test.T(t, ny, 2)
+	test.T(t, g.Extent(), canvas.Rect{X: -0.5, Y: -0.5, W: 3.0, H: 2.0})
+	test.That(t, math.IsNaN(g.Value(1, 1)))
+	test.That(t, math.IsNaN(g.Value(2, 1)), "missing values at the end of short rows")
+	x, y := g.Pos(2.0, 1.0)
+	test.Float(t, x, 2.0)
+	test.Float(t, y, 1.0)
+	min, max := g.Range()
+	test.Float(t, min, 0.0)
+	test.Float(t, max, 3.0)
+}
+
+func TestHeatmap(t *testing.T) {
+	s := NewHeatmap([][]float64{{0, 1}, {2, math.NaN()}})
+	test.T(t, s.Colors.Color(0.0), Viridis.Colors[0])
+	test.T(t, s.Colors.Color(1.0), Viridis.Colors[4])
+
+	coord := Cartesian{&LinearScale{Min: -0.5, Max: 1.5}, &LinearScale{Min: -0.5, Max: 1.5}, 20.0, 20.0}
+	r := &recorder{}
+	s.Draw(canvas.NewContext(r), coord)
+	test.T(t, len(r.paths), 3) // missing value is not drawn
+	test.T(t, r.paths[1].Bounds(), canvas.Rect{X: 10.0, Y: 0.0, W: 10.0, H: 10.0})
+	test.T(t, r.styles[2].FillColor, Viridis.Colors[8])
+}
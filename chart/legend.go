@@ -0,0 +1,227 @@
+package chart
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/tdewolff/canvas"
+)
+
+// colorBarSlices is the number of slices of flat colors that approximate the gradient of a color bar.
+const colorBarSlices = 128
+
+// ColorBar is a legend of a color scale, which is a bar of Width by Height millimeters filled with the colors of the scale, with the ticks of the scale along its long side. The bar is vertical when it is higher than it is wide, with low values at the bottom, and otherwise horizontal with low values at the left.
+type ColorBar struct {
+	ColorScale
+	Width, Height float64
+	Title         string
+
+	Font      *canvas.FontFamily // no text is drawn when nil
+	FontSize  float64            // in points
+	TextColor color.RGBA
+	AxisColor color.RGBA
+	LineWidth float64 // width of the outline and ticks in millimeters
+	TickSize  float64 // length of the ticks in millimeters
+}
+
+// NewColorBar returns a color bar of width by height millimeters for the color scale.
+func NewColorBar(scale ColorScale, width, height float64) *ColorBar {
+	return &ColorBar{
+		ColorScale: scale,
+		Width:      width,
+		Height:     height,
+		FontSize:   8.0,
+		TextColor:  canvas.Black,
+		AxisColor:  canvas.Black,
+		LineWidth:  0.2,
+		TickSize:   1.0,
+	}
+}
+
+// Draw draws the color bar with the lower-left corner of the bar at (x,y). Ticks and labels are drawn to the right of vertical bars and below horizontal bars, and the title above the bar.
+func (b *ColorBar) Draw(ctx *canvas.Context, x, y float64) {
+	vertical := b.Width < b.Height
+	length := b.Width
+	if vertical {
+		length = b.Height
+	}
+	ctx.Push()
+	defer ctx.Pop()
+	ctx.Translate(x, y)
+
+	ticks := b.Scale.Ticks()
+	for i := 0; i < colorBarSlices; i++ {
+		t0, t1 := float64(i)/colorBarSlices, float64(i+1)/colorBarSlices
+		ctx.Style = fillStyle(b.Colormap.At(b.Scale.Map(invertScale(b.Scale, ticks, (t0+t1)/2.0))))
+		if i+1 < colorBarSlices {
+			t1 += 0.5 / colorBarSlices // overlap to hide antialiasing seams
+		}
+		if vertical {
+			ctx.DrawPath(0.0, t0*length, canvas.Rectangle(b.Width, (t1-t0)*length))
+		} else {
+			ctx.DrawPath(t0*length, 0.0, canvas.Rectangle((t1-t0)*length, b.Height))
+		}
+	}
+
+	outline := canvas.Rectangle(b.Width, b.Height)
+	for _, tick := range ticks {
+		if pos := b.Scale.Map(tick.Value) * length; inRange(pos, length) {
+			if vertical {
+				outline.MoveTo(b.Width, pos)
+				outline.LineTo(b.Width+b.TickSize, pos)
+			} else {
+				outline.MoveTo(pos, 0.0)
+				outline.LineTo(pos, -b.TickSize)
+			}
+		}
+	}
+	strokeStyle(ctx, b.AxisColor, b.LineWidth)
+	ctx.DrawPath(0.0, 0.0, outline)
+
+	if b.Font == nil {
+		return
+	}
+	face := b.Font.Face(b.FontSize, b.TextColor, canvas.FontRegular, canvas.FontNormal)
+	metrics := face.Metrics()
+	margin := b.TickSize + metrics.XHeight/2.0
+	for _, tick := range ticks {
+		if pos := b.Scale.Map(tick.Value) * length; inRange(pos, length) {
+			if vertical {
+				ctx.DrawText(b.Width+margin, pos-metrics.XHeight/2.0, canvas.NewTextLine(face, tick.Label, canvas.Left))
+			} else {
+				ctx.DrawText(pos, -margin-metrics.Ascent, canvas.NewTextLine(face, tick.Label, canvas.Center))
+			}
+		}
+	}
+	if b.Title != "" {
+		ctx.DrawText(0.0, b.Height+metrics.XHeight/2.0+metrics.Descent, canvas.NewTextLine(face, b.Title, canvas.Left))
+	}
+}
+
+// invertScale returns the value that the scale maps to t, where scales other than linear, logarithmic, and diverging scales are assumed to be linear between their first and last tick.
+func invertScale(scale Scale, ticks []Tick, t float64) float64 {
+	switch scale := scale.(type) {
+	case *LinearScale:
+		return scale.Min + t*(scale.Max-scale.Min)
+	case *LogScale:
+		return scale.Min * math.Pow(scale.Max/scale.Min, t)
+	case *DivergingScale:
+		if t < 0.5 {
+			return scale.Min + 2.0*t*(scale.Center-scale.Min)
+		}
+		return scale.Center + (2.0*t-1.0)*(scale.Max-scale.Center)
+	}
+	if len(ticks) == 0 {
+		return t
+	}
+	return ticks[0].Value + t*(ticks[len(ticks)-1].Value-ticks[0].Value)
+}
+
+// LegendEntry is an entry of a legend, with a swatch drawn in Style.
+type LegendEntry struct {
+	Label string
+	Style canvas.Style
+}
+
+// Legend is a list of entries from top to bottom, each with a square swatch and a label that is wrapped to fit LabelWidth.
+type Legend struct {
+	Entries    []LegendEntry
+	Title      string
+	SwatchSize float64 // in millimeters
+	LabelWidth float64 // in millimeters
+
+	Font      *canvas.FontFamily // no text is drawn when nil
+	FontSize  float64            // in points
+	TextColor color.RGBA
+}
+
+// NewLegend returns a legend for the labels with swatches filled with the colors of the palette.
+func NewLegend(labels []string, colors Palette) *Legend {
+	entries := make([]LegendEntry, len(labels))
+	for i, label := range labels {
+		entries[i] = LegendEntry{label, fillStyle(colors.Color(i))}
+	}
+	return &Legend{
+		Entries:    entries,
+		SwatchSize: 3.0,
+		LabelWidth: 30.0,
+		FontSize:   8.0,
+		TextColor:  canvas.Black,
+	}
+}
+
+// layout returns the wrapped labels and the heights of the title and the entries.
+func (l *Legend) layout() (*canvas.Text, []*canvas.Text, []float64) {
+	var title *canvas.Text
+	texts := make([]*canvas.Text, len(l.Entries))
+	heights := make([]float64, len(l.Entries))
+	if l.Font == nil {
+		for i := range heights {
+			heights[i] = 1.5 * l.SwatchSize
+		}
+		return title, texts, heights
+	}
+	face := l.Font.Face(l.FontSize, l.TextColor, canvas.FontRegular, canvas.FontNormal)
+	gap := face.Metrics().XHeight
+	if l.Title != "" {
+		title = canvas.NewTextBox(face, l.Title, l.SwatchSize+gap+l.LabelWidth, 0.0, canvas.Left, canvas.Top, 0.0, 0.0)
+	}
+	for i, entry := range l.Entries {
+		texts[i] = canvas.NewTextBox(face, entry.Label, l.LabelWidth, 0.0, canvas.Left, canvas.Top, 0.0, 0.0)
+		heights[i] = math.Max(l.SwatchSize, texts[i].Height()) + gap/2.0
+	}
+	return title, texts, heights
+}
+
+// Size returns the width and height of the legend.
+func (l *Legend) Size() (float64, float64) {
+	title, _, heights := l.layout()
+	width, height := l.SwatchSize, 0.0
+	if l.Font != nil {
+		width += l.Font.Face(l.FontSize, l.TextColor, canvas.FontRegular, canvas.FontNormal).Metrics().XHeight + l.LabelWidth
+	}
+	if title != nil {
+		height += title.Height()
+	}
+	for _, h := range heights {
+		height += h
+	}
+	return width, height
+}
+
+// Draw draws the legend with its lower-left corner at (x,y).
+func (l *Legend) Draw(ctx *canvas.Context, x, y float64) {
+	title, texts, heights := l.layout()
+	_, height := l.Size()
+	ctx.Push()
+	defer ctx.Pop()
+	ctx.Translate(x, y)
+
+	top := height
+	if title != nil {
+		ctx.DrawText(0.0, top, title)
+		top -= title.Height()
+	}
+	gap, lineHeight := 0.0, 0.0
+	if l.Font != nil {
+		metrics := l.Font.Face(l.FontSize, l.TextColor, canvas.FontRegular, canvas.FontNormal).Metrics()
+		gap, lineHeight = metrics.XHeight, metrics.LineHeight
+	}
+	for i, entry := range l.Entries {
+		// center short labels on the swatch, and the swatch on the first line of long labels
+		swatchTop, textTop := top, top
+		if texts[i] != nil {
+			if textHeight := texts[i].Height(); textHeight <= l.SwatchSize {
+				textTop -= (l.SwatchSize - textHeight) / 2.0
+			} else if l.SwatchSize < lineHeight {
+				swatchTop -= (lineHeight - l.SwatchSize) / 2.0
+			}
+		}
+		ctx.Style = entry.Style
+		ctx.DrawPath(0.0, swatchTop-l.SwatchSize, canvas.Rectangle(l.SwatchSize, l.SwatchSize))
+		if texts[i] != nil {
+			ctx.DrawText(l.SwatchSize+gap, textTop, texts[i])
+		}
+		top -= heights[i]
+	}
+}
@@ -0,0 +1,55 @@
+package chart
+
+import (
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestColorBar(t *testing.T) {
+	b := NewColorBar(NewColorScale(0.0, 10.0, Viridis), 4.0, 64.0)
+	r := &recorder{}
+	b.Draw(canvas.NewContext(r), 10.0, 10.0)
+	test.T(t, len(r.paths), colorBarSlices+1) // slices, outline with ticks
+	test.T(t, r.styles[0].FillColor, Viridis.At(0.5/colorBarSlices))
+	test.T(t, r.paths[colorBarSlices-1].Bounds(), canvas.Rect{X: 10.0, Y: 73.5, W: 4.0, H: 0.5})
+	test.T(t, r.paths[colorBarSlices].Bounds(), canvas.Rect{X: 10.0, Y: 10.0, W: 5.0, H: 64.0})
+	test.T(t, r.texts, 0) // no font
+
+	family := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+	b.Font = family
+	b.Title = "value"
+	b.Width, b.Height = 64.0, 4.0
+	r = &recorder{}
+	b.Draw(canvas.NewContext(r), 10.0, 10.0)
+	test.T(t, r.paths[colorBarSlices].Bounds(), canvas.Rect{X: 10.0, Y: 9.0, W: 64.0, H: 5.0})
+	test.T(t, r.texts, 6+1) // ticks and title
+}
+
+func TestLegend(t *testing.T) {
+	l := NewLegend([]string{"a", "b", "c"}, Set2)
+	w, h := l.Size()
+	test.Float(t, w, 3.0)
+	test.Float(t, h, 3*4.5)
+	r := &recorder{}
+	l.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, len(r.paths), 3)
+	test.T(t, r.paths[0].Bounds(), canvas.Rect{X: 0.0, Y: 10.5, W: 3.0, H: 3.0})
+	test.T(t, r.styles[2].FillColor, Set2[2])
+
+	family := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+	l.Font = family
+	_, short := l.Size()
+	l.Entries[1].Label = "a label that is much longer than the width of the labels"
+	l.Title = "Legend"
+	_, long := l.Size()
+	test.That(t, short < long, "long labels must wrap")
+	r = &recorder{}
+	l.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, len(r.paths), 3)
+	test.T(t, r.texts, 4)
+	test.That(t, r.paths[0].Bounds().Y+3.0 <= long, "swatches below the top")
+}
@@ -0,0 +1,376 @@
+package chart
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Polar are the coordinates of a circular plot area with a radius of Radius millimeters centered at the origin, where x maps to the angle and y to the distance from the center.
+type Polar struct {
+	Angle, Radial Scale
+	Radius        float64
+	Start         float64 // angle in degrees where the angle scale starts, counter clockwise from the positive x-axis
+	Clockwise     bool    // the angle scale runs clockwise
+}
+
+// Theta returns the angle in degrees of a data value along the angle scale.
+func (c Polar) Theta(x float64) float64 {
+	if c.Clockwise {
+		return c.Start - c.Angle.Map(x)*360.0
+	}
+	return c.Start + c.Angle.Map(x)*360.0
+}
+
+// Pos returns the canvas coordinates of a data point.
+func (c Polar) Pos(x, y float64) canvas.Point {
+	theta := c.Theta(x) * math.Pi / 180.0
+	r := c.Radial.Map(y) * c.Radius
+	return canvas.Point{X: r * math.Cos(theta), Y: r * math.Sin(theta)}
+}
+
+// arc adds a circular arc at data value y from x0 to x1 to the path, which must end at the position of (x0,y).
+func (c Polar) arc(p *canvas.Path, x0, x1, y float64) {
+	if r := c.Radial.Map(y) * c.Radius; 0.0 < r {
+		p.Arc(r, r, 0.0, c.Theta(x0), c.Theta(x1))
+	}
+}
+
+// PolarChart is a chart of data series in polar coordinates, such as pie and radar charts. The plot area is a circle with a radius of Radius millimeters, and labels and the title are drawn outside of it.
+type PolarChart struct {
+	Radius float64
+
+	Angle, Radial Scale // chosen automatically when nil, see Scales
+	Start         float64
+	Clockwise     bool
+	Title         string
+	Axes          bool // draw the outer circle with labels at the angle ticks and along the spoke at the start
+	Grid          bool // draw circles and spokes at the ticks
+
+	Font         *canvas.FontFamily // no text is drawn when nil
+	FontSize     float64            // in points
+	CurvedLabels bool               // labels of series follow the circle, see Pie.Labels
+	TextColor    color.RGBA
+	AxisColor    color.RGBA
+	GridColor    color.RGBA
+	LineWidth    float64 // width of the axes in millimeters
+
+	Series []Series
+}
+
+// NewPolar returns a new polar chart with a radius of radius millimeters, whose angle scale starts at the top and runs clockwise.
+func NewPolar(radius float64) *PolarChart {
+	return &PolarChart{
+		Radius:    radius,
+		Start:     90.0,
+		Clockwise: true,
+		Axes:      true,
+		FontSize:  8.0,
+		TextColor: canvas.Black,
+		AxisColor: canvas.Black,
+		GridColor: color.RGBA{0xdd, 0xdd, 0xdd, 0xff},
+		LineWidth: 0.2,
+	}
+}
+
+// NewPie returns a polar chart, without axes, of a pie series.
+func NewPie(radius float64, values []float64, labels ...string) *PolarChart {
+	c := NewPolar(radius)
+	c.Axes = false
+	pie := NewPieSeries(values)
+	pie.Labels = labels
+	c.Add(pie)
+	return c
+}
+
+// Add adds data series to the chart, which are drawn in order.
+func (c *PolarChart) Add(series ...Series) {
+	c.Series = append(c.Series, series...)
+}
+
+// Scales returns the scales of the chart, where nil scales are chosen to fit the extent of all series. The angle scale spans the extent exactly so that one full circle equals the width of the extent, and the radial scale starts at zero.
+func (c *PolarChart) Scales() (Scale, Scale) {
+	angle, radial := c.Angle, c.Radial
+	if angle == nil || radial == nil {
+		extent := canvas.Rect{}
+		for i, series := range c.Series {
+			if i == 0 {
+				extent = series.Extent()
+			} else {
+				extent = extent.Add(series.Extent())
+			}
+		}
+		if angle == nil {
+			angle = &LinearScale{extent.X, extent.X + extent.W, 0}
+		}
+		if radial == nil {
+			radial = NewLinearScale(0.0, extent.Y+extent.H)
+		}
+	}
+	return angle, radial
+}
+
+// Coordinates returns the coordinates of the plot area.
+func (c *PolarChart) Coordinates() Polar {
+	angle, radial := c.Scales()
+	return Polar{angle, radial, c.Radius, c.Start, c.Clockwise}
+}
+
+func (c *PolarChart) face(scale float64) (canvas.FontFace, bool) {
+	if c.Font == nil {
+		return canvas.FontFace{}, false
+	}
+	return c.Font.Face(scale*c.FontSize, c.TextColor, canvas.FontRegular, canvas.FontNormal), true
+}
+
+// Draw draws the chart with the center of the plot area at (x,y).
+func (c *PolarChart) Draw(ctx *canvas.Context, x, y float64) {
+	coord := c.Coordinates()
+	ctx.Push()
+	defer ctx.Pop()
+	ctx.Translate(x, y)
+
+	// the angle ticks at the start and end of the scale coincide
+	angleTicks := []Tick{}
+	for _, tick := range coord.Angle.Ticks() {
+		if pos := coord.Angle.Map(tick.Value); -1e-6 <= pos && pos < 1.0-1e-6 {
+			angleTicks = append(angleTicks, tick)
+		}
+	}
+	radialTicks := []Tick{}
+	for _, tick := range coord.Radial.Ticks() {
+		if pos := coord.Radial.Map(tick.Value); 1e-6 < pos && pos <= 1.0+1e-6 {
+			radialTicks = append(radialTicks, tick)
+		}
+	}
+
+	if c.Grid {
+		grid := &canvas.Path{}
+		for _, tick := range radialTicks {
+			r := coord.Radial.Map(tick.Value) * c.Radius
+			grid = grid.Append(canvas.Circle(r))
+		}
+		for _, tick := range angleTicks {
+			theta := coord.Theta(tick.Value) * math.Pi / 180.0
+			grid.MoveTo(0.0, 0.0)
+			grid.LineTo(c.Radius*math.Cos(theta), c.Radius*math.Sin(theta))
+		}
+		strokeStyle(ctx, c.GridColor, c.LineWidth/2.0)
+		ctx.DrawPath(0.0, 0.0, grid)
+	}
+
+	face, hasFont := c.face(1.0)
+	for _, series := range c.Series {
+		ctx.Push()
+		series.Draw(ctx, coord)
+		ctx.Pop()
+
+		if labeler, ok := series.(labeler); ok && hasFont {
+			for _, label := range labeler.labels() {
+				if c.CurvedLabels {
+					r := coord.Radial.Map(label.y) * c.Radius
+					drawTextOnArc(ctx, face, label.text, r, coord.Theta(label.x))
+				} else {
+					pos := coord.Pos(label.x, label.y)
+					ctx.DrawText(pos.X, pos.Y-face.Metrics().XHeight/2.0, canvas.NewTextLine(face, label.text, canvas.Center))
+				}
+			}
+		}
+	}
+
+	if c.Axes {
+		strokeStyle(ctx, c.AxisColor, c.LineWidth)
+		ctx.DrawPath(0.0, 0.0, canvas.Circle(c.Radius))
+	}
+
+	if !hasFont {
+		return
+	}
+	metrics := face.Metrics()
+	margin := metrics.XHeight
+	if c.Axes {
+		for _, tick := range angleTicks {
+			theta := coord.Theta(tick.Value) * math.Pi / 180.0
+			cos, sin := math.Cos(theta), math.Sin(theta)
+			align := canvas.Center
+			if 0.2 < cos {
+				align = canvas.Left
+			} else if cos < -0.2 {
+				align = canvas.Right
+			}
+			r := c.Radius + margin
+			ctx.DrawText(r*cos, r*sin+(sin-1.0)*metrics.Ascent/2.0, canvas.NewTextLine(face, tick.Label, align))
+		}
+		theta := c.Start * math.Pi / 180.0
+		cos, sin := math.Cos(theta), math.Sin(theta)
+		for _, tick := range radialTicks {
+			r := coord.Radial.Map(tick.Value) * c.Radius
+			ctx.DrawText(r*cos+margin/2.0*sin, r*sin-margin/2.0*cos-metrics.Ascent, canvas.NewTextLine(face, tick.Label, canvas.Left))
+		}
+	}
+	if c.Title != "" {
+		top := c.Radius + metrics.LineHeight/2.0
+		if c.Axes {
+			top += margin + metrics.LineHeight
+		}
+		title, _ := c.face(1.25)
+		ctx.DrawText(0.0, top+title.Metrics().Descent, canvas.NewTextLine(title, c.Title, canvas.Center))
+	}
+}
+
+// label is a text label at a position in data coordinates.
+type label struct {
+	text string
+	x, y float64
+}
+
+// labeler is implemented by series that have labels, which are drawn by the chart using its font.
+type labeler interface {
+	labels() []label
+}
+
+// drawTextOnArc draws the text centered at angle theta in degrees on a circle of radius r around the origin. The text reads clockwise in the upper half and counter clockwise in the lower half so that it is never upside down. Canvas does not lay out text along paths, so each glyph is placed and rotated separately, which ignores kerning between glyphs.
+func drawTextOnArc(ctx *canvas.Context, face canvas.FontFace, text string, r, theta float64) {
+	if r <= 0.0 {
+		return
+	}
+	dir := -1.0 // clockwise
+	if math.Sin(theta*math.Pi/180.0) < 0.0 {
+		dir = 1.0
+	}
+	xHeight := face.Metrics().XHeight
+	offset := -face.TextWidth(text) / 2.0
+	for _, c := range text {
+		s := string(c)
+		w := face.TextWidth(s)
+		phi := theta + dir*(offset+w/2.0)/r*180.0/math.Pi
+		ctx.Push()
+		ctx.Rotate(phi)
+		ctx.Translate(r, 0.0)
+		ctx.Rotate(dir * 90.0)
+		ctx.DrawText(0.0, -xHeight/2.0, canvas.NewTextLine(face, s, canvas.Center))
+		ctx.Pop()
+		offset += w
+	}
+}
+
+////////////////////////////////////////////////////////////////
+
+// Pie is a series of slices whose angles are proportional to the values, to be drawn in polar coordinates. It is a donut when Inner is larger than zero.
+type Pie struct {
+	Values []float64
+	Labels []string // drawn at the middle of each slice
+	Inner  float64  // inner radius as a fraction of the outer radius
+	Colors []color.RGBA
+	Style  canvas.Style // style of the slices with a fill color that is replaced by Colors
+}
+
+// NewPieSeries returns a pie series in DefaultColors with white lines between the slices.
+func NewPieSeries(values []float64) *Pie {
+	style := lineStyle(canvas.White, 0.3)
+	style.StrokeJoiner = canvas.BevelJoin
+	return &Pie{Values: values, Colors: DefaultColors, Style: style}
+}
+
+// NewDonut returns a pie series with an inner radius of half the outer radius.
+func NewDonut(values []float64) *Pie {
+	pie := NewPieSeries(values)
+	pie.Inner = 0.5
+	return pie
+}
+
+// Extent returns an angle range of the sum of the values and a radial range of one.
+func (s *Pie) Extent() canvas.Rect {
+	sum := 0.0
+	for _, v := range s.Values {
+		sum += math.Max(v, 0.0)
+	}
+	return canvas.Rect{W: sum, H: 1.0}
+}
+
+// Draw draws the series, which requires polar coordinates.
+func (s *Pie) Draw(ctx *canvas.Context, coord Coordinates) {
+	polar, ok := coord.(Polar)
+	if !ok {
+		return
+	}
+	x := 0.0
+	for i, v := range s.Values {
+		if v <= 0.0 {
+			continue
+		}
+		p := &canvas.Path{}
+		start := polar.Pos(x, 1.0)
+		p.MoveTo(start.X, start.Y)
+		polar.arc(p, x, x+v, 1.0)
+		end := polar.Pos(x+v, s.Inner)
+		p.LineTo(end.X, end.Y)
+		polar.arc(p, x+v, x, s.Inner)
+		p.Close()
+
+		ctx.Style = s.Style
+		if 0 < len(s.Colors) {
+			ctx.Style.FillColor = s.Colors[i%len(s.Colors)]
+		}
+		ctx.DrawPath(0.0, 0.0, p)
+		x += v
+	}
+}
+
+func (s *Pie) labels() []label {
+	labels := []label{}
+	x := 0.0
+	for i, v := range s.Values {
+		if v <= 0.0 {
+			continue
+		}
+		if i < len(s.Labels) && s.Labels[i] != "" {
+			labels = append(labels, label{s.Labels[i], x + v/2.0, (1.0 + s.Inner) / 2.0})
+		}
+		x += v
+	}
+	return labels
+}
+
+// Radar is a series of values on evenly spaced spokes connected by a closed polyline, to be drawn in polar coordinates. The value with index i lies on the angle i.
+type Radar struct {
+	Values []float64
+	Style  canvas.Style
+}
+
+// NewRadar returns a radar series in the first color of DefaultColors, filled with a translucent version of it.
+func NewRadar(values []float64) *Radar {
+	style := lineStyle(DefaultColors[0], 0.5)
+	style.FillColor = translucent(DefaultColors[0], 0.25)
+	return &Radar{values, style}
+}
+
+// Extent returns an angle range of the number of values and a radial range from zero to the largest value.
+func (s *Radar) Extent() canvas.Rect {
+	max := 0.0
+	for _, v := range s.Values {
+		max = math.Max(max, v)
+	}
+	return canvas.Rect{W: float64(len(s.Values)), H: max}
+}
+
+// Draw draws the series.
+func (s *Radar) Draw(ctx *canvas.Context, coord Coordinates) {
+	points := make([][2]float64, len(s.Values))
+	for i, v := range s.Values {
+		points[i] = [2]float64{float64(i), v}
+	}
+	ctx.Style = s.Style
+	ctx.DrawPath(0.0, 0.0, polygon(coord, false, points))
+}
+
+// translucent returns the color with its opacity multiplied by alpha.
+func translucent(col color.RGBA, alpha float64) color.RGBA {
+	return color.RGBA{
+		uint8(float64(col.R)*alpha + 0.5),
+		uint8(float64(col.G)*alpha + 0.5),
+		uint8(float64(col.B)*alpha + 0.5),
+		uint8(float64(col.A)*alpha + 0.5),
+	}
+}
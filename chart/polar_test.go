@@ -0,0 +1,70 @@
+package chart
+
+import (
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestPolar(t *testing.T) {
+	coord := Polar{&LinearScale{Min: 0, Max: 4}, &LinearScale{Min: 0, Max: 1}, 10.0, 90.0, true}
+	test.Float(t, coord.Theta(1.0), 0.0)
+	test.T(t, coord.Pos(0.0, 1.0), canvas.Point{X: 0.0, Y: 10.0})
+	test.T(t, coord.Pos(1.0, 0.5), canvas.Point{X: 5.0, Y: 0.0})
+	test.T(t, coord.Pos(3.0, 1.0), canvas.Point{X: -10.0, Y: 0.0})
+
+	coord.Clockwise = false
+	test.T(t, coord.Pos(1.0, 1.0), canvas.Point{X: -10.0, Y: 0.0})
+}
+
+func TestPie(t *testing.T) {
+	c := NewPie(10.0, []float64{1, 0, 3}, "a", "b", "c")
+	angle, radial := c.Scales()
+	test.T(t, angle.(*LinearScale).Max, 4.0)
+	test.T(t, radial.(*LinearScale).Max, 1.0)
+
+	r := &recorder{}
+	c.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, len(r.paths), 2)
+	test.T(t, r.paths[0], canvas.MustParseSVG("M0 10A10 10 0 0 0 10 0L0 0z"))
+	test.T(t, r.paths[1], canvas.MustParseSVG("M10 0A10 10 0 1 0 0 10L0 0z"))
+	test.T(t, r.styles[0].FillColor, DefaultColors[0])
+	test.T(t, r.styles[1].FillColor, DefaultColors[2])
+	test.T(t, r.texts, 0) // no font
+
+	donut := NewDonut([]float64{2, 2})
+	test.T(t, donut.labels(), []label{})
+	r = &recorder{}
+	donut.Draw(canvas.NewContext(r), c.Coordinates())
+	test.T(t, r.paths[0], canvas.MustParseSVG("M0 10A10 10 0 0 0 0 -10L0 -5A5 5 0 0 1 0 5z"))
+
+	// labels
+	family := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+	c.Font = family
+	c.Title = "Title"
+	r = &recorder{}
+	c.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, r.texts, 3) // a, c, title
+
+	c.CurvedLabels = true
+	r = &recorder{}
+	c.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, r.texts, 3) // one glyph per label and the title
+}
+
+func TestRadar(t *testing.T) {
+	c := NewPolar(10.0)
+	c.Grid = true
+	c.Add(NewRadar([]float64{10, 5, 10, 5}))
+	coord := c.Coordinates()
+	test.T(t, coord.Angle.(*LinearScale).Max, 4.0)
+	test.T(t, coord.Radial.(*LinearScale).Max, 10.0)
+
+	r := &recorder{}
+	c.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, len(r.paths), 3) // grid, radar, outer circle
+	test.T(t, r.paths[1], canvas.MustParseSVG("M0 10L5 0L0 -10L-5 0z"))
+	test.T(t, r.paths[2], canvas.Circle(10.0))
+}
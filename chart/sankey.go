@@ -0,0 +1,334 @@
+package chart
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Flow is a flow of Value from the node with index Source to the node with index Target.
+type Flow struct {
+	Source, Target int
+	Value          float64
+}
+
+// SankeyNode is a node of a laid out Sankey diagram.
+type SankeyNode struct {
+	Name   string
+	Column int
+	Value  float64
+	Rect   canvas.Rect
+}
+
+// SankeyLink is a flow of a laid out Sankey diagram, which runs from (X0,Y0) at the right side of the source node to (X1,Y1) at the left side of the target node, where Y0 and Y1 are at the middle of the link.
+type SankeyLink struct {
+	Flow
+	X0, Y0, X1, Y1 float64
+	Width          float64
+}
+
+// points returns the control points of the upper or lower edge of the link.
+func (l SankeyLink) points(upper bool) [4]canvas.Point {
+	d := l.Width / 2.0
+	if !upper {
+		d = -d
+	}
+	xm := (l.X0 + l.X1) / 2.0
+	return [4]canvas.Point{{X: l.X0, Y: l.Y0 + d}, {X: xm, Y: l.Y0 + d}, {X: xm, Y: l.Y1 + d}, {X: l.X1, Y: l.Y1 + d}}
+}
+
+// Path returns the ribbon of the link, bounded by cubic Béziers with horizontal tangents at both ends.
+func (l SankeyLink) Path() *canvas.Path {
+	return l.slice(0.0, 1.0)
+}
+
+// slice returns the part of the ribbon between the parameters t0 and t1 of its Béziers. Since the Béziers of both edges share the x-coordinates of their control points, the slice runs between two vertical lines.
+func (l SankeyLink) slice(t0, t1 float64) *canvas.Path {
+	upper := cubicSegment(l.points(true), t0, t1)
+	lower := cubicSegment(l.points(false), t0, t1)
+	p := &canvas.Path{}
+	p.MoveTo(upper[0].X, upper[0].Y)
+	p.CubeTo(upper[1].X, upper[1].Y, upper[2].X, upper[2].Y, upper[3].X, upper[3].Y)
+	p.LineTo(lower[3].X, lower[3].Y)
+	p.CubeTo(lower[2].X, lower[2].Y, lower[1].X, lower[1].Y, lower[0].X, lower[0].Y)
+	p.Close()
+	return p
+}
+
+// cubicSegment returns the control points of the part of the cubic Bézier between the parameters t0 and t1, using its blossom.
+func cubicSegment(p [4]canvas.Point, t0, t1 float64) [4]canvas.Point {
+	blossom := func(ts ...float64) canvas.Point {
+		q := p
+		for k, t := range ts {
+			for i := 0; i < 3-k; i++ {
+				q[i] = q[i].Interpolate(q[i+1], t)
+			}
+		}
+		return q[0]
+	}
+	return [4]canvas.Point{blossom(t0, t0, t0), blossom(t0, t0, t1), blossom(t0, t1, t1), blossom(t1, t1, t1)}
+}
+
+// Sankey is a flow diagram of Width by Height millimeters, where nodes are placed in columns and connected by ribbons whose widths are proportional to the flows between them. Nodes without outgoing flows are placed in the last column.
+type Sankey struct {
+	Width, Height float64
+	Nodes         []string
+	Flows         []Flow
+
+	NodeWidth   float64 // in millimeters
+	NodePadding float64 // minimum vertical space between nodes in millimeters
+	Iterations  int     // number of passes that move nodes towards the nodes they are connected to
+
+	Colors   []color.RGBA // colors of the nodes
+	Opacity  float64      // opacity of the ribbons
+	Gradient int          // number of slices of the ribbons with colors interpolated from the source to the target node, ribbons have the color of the source when less than two
+
+	Font      *canvas.FontFamily // no text is drawn when nil
+	FontSize  float64            // in points
+	TextColor color.RGBA
+}
+
+// NewSankey returns a Sankey diagram of width by height millimeters with the given nodes and flows between them.
+func NewSankey(width, height float64, nodes []string, flows []Flow) *Sankey {
+	return &Sankey{
+		Width:       width,
+		Height:      height,
+		Nodes:       nodes,
+		Flows:       flows,
+		NodeWidth:   4.0,
+		NodePadding: 3.0,
+		Iterations:  6,
+		Colors:      DefaultColors,
+		Opacity:     0.5,
+		FontSize:    8.0,
+		TextColor:   canvas.Black,
+	}
+}
+
+// Layout returns the positions of the nodes and links with the origin at the lower-left corner. It returns an error when a flow refers to a nonexistent node or when the flows contain a cycle.
+func (s *Sankey) Layout() ([]SankeyNode, []SankeyLink, error) {
+	n := len(s.Nodes)
+	in, out := make([]float64, n), make([]float64, n)
+	outgoing, incoming := make([][]int, n), make([][]int, n)
+	for i, flow := range s.Flows {
+		if flow.Source < 0 || n <= flow.Source || flow.Target < 0 || n <= flow.Target {
+			return nil, nil, fmt.Errorf("flow %d refers to nonexistent node", i)
+		}
+		out[flow.Source] += flow.Value
+		in[flow.Target] += flow.Value
+		outgoing[flow.Source] = append(outgoing[flow.Source], i)
+		incoming[flow.Target] = append(incoming[flow.Target], i)
+	}
+
+	// columns by longest path from the sources
+	nodes := make([]SankeyNode, n)
+	degree := make([]int, n)
+	queue := []int{}
+	for i := range nodes {
+		nodes[i].Name = s.Nodes[i]
+		nodes[i].Value = math.Max(in[i], out[i])
+		degree[i] = len(incoming[i])
+		if degree[i] == 0 {
+			queue = append(queue, i)
+		}
+	}
+	depth := 0
+	for k := 0; k < len(queue); k++ {
+		i := queue[k]
+		for _, f := range outgoing[i] {
+			j := s.Flows[f].Target
+			if nodes[j].Column < nodes[i].Column+1 {
+				nodes[j].Column = nodes[i].Column + 1
+				if depth < nodes[j].Column {
+					depth = nodes[j].Column
+				}
+			}
+			if degree[j]--; degree[j] == 0 {
+				queue = append(queue, j)
+			}
+		}
+	}
+	if len(queue) < n {
+		return nil, nil, fmt.Errorf("flows contain a cycle")
+	}
+	columns := make([][]int, depth+1)
+	for i := range nodes {
+		if len(outgoing[i]) == 0 && 0 < len(incoming[i]) {
+			nodes[i].Column = depth
+		}
+		columns[nodes[i].Column] = append(columns[nodes[i].Column], i)
+	}
+
+	// vertical scale that fits the fullest column
+	padding := s.NodePadding
+	for _, column := range columns {
+		if 1 < len(column) {
+			padding = math.Min(padding, s.Height/2.0/float64(len(column)-1))
+		}
+	}
+	ky := math.Inf(1)
+	for _, column := range columns {
+		sum := 0.0
+		for _, i := range column {
+			sum += nodes[i].Value
+		}
+		if 0.0 < sum {
+			ky = math.Min(ky, (s.Height-float64(len(column)-1)*padding)/sum)
+		}
+	}
+	if math.IsInf(ky, 1) {
+		ky = 0.0
+	}
+
+	// positions measured from the top
+	top := make([]float64, n)
+	height := make([]float64, n)
+	for c, column := range columns {
+		t := 0.0
+		for _, i := range column {
+			height[i] = nodes[i].Value * ky
+			top[i], t = t, t+height[i]+padding
+			if 0 < depth {
+				nodes[i].Rect.X = float64(c) * (s.Width - s.NodeWidth) / float64(depth)
+			}
+		}
+	}
+	center := func(i int) float64 {
+		return top[i] + height[i]/2.0
+	}
+	resolve := func(column []int) {
+		sort.SliceStable(column, func(a, b int) bool { return top[column[a]] < top[column[b]] })
+		t := 0.0
+		for _, i := range column {
+			top[i] = math.Max(top[i], t)
+			t = top[i] + height[i] + padding
+		}
+		t = s.Height
+		for k := len(column) - 1; 0 <= k; k-- {
+			i := column[k]
+			top[i] = math.Min(top[i], t-height[i])
+			t = top[i] - padding
+		}
+	}
+	relax := func(column []int, flows [][]int, other func(Flow) int) {
+		for _, i := range column {
+			sum, weight := 0.0, 0.0
+			for _, f := range flows[i] {
+				sum += center(other(s.Flows[f])) * s.Flows[f].Value
+				weight += s.Flows[f].Value
+			}
+			if 0.0 < weight {
+				top[i] = sum/weight - height[i]/2.0
+			}
+		}
+		resolve(column)
+	}
+	for k := 0; k < s.Iterations; k++ {
+		for c := 1; c < len(columns); c++ {
+			relax(columns[c], incoming, func(flow Flow) int { return flow.Source })
+		}
+		for c := len(columns) - 2; 0 <= c; c-- {
+			relax(columns[c], outgoing, func(flow Flow) int { return flow.Target })
+		}
+	}
+
+	// links are stacked at their nodes in the order of the nodes at the other end
+	links := make([]SankeyLink, len(s.Flows))
+	for i, flow := range s.Flows {
+		links[i].Flow = flow
+		links[i].Width = flow.Value * ky
+		links[i].X0 = nodes[flow.Source].Rect.X + s.NodeWidth
+		links[i].X1 = nodes[flow.Target].Rect.X
+	}
+	for i := range nodes {
+		sort.SliceStable(outgoing[i], func(a, b int) bool {
+			return center(s.Flows[outgoing[i][a]].Target) < center(s.Flows[outgoing[i][b]].Target)
+		})
+		t := top[i]
+		for _, f := range outgoing[i] {
+			links[f].Y0 = s.Height - t - links[f].Width/2.0
+			t += links[f].Width
+		}
+		sort.SliceStable(incoming[i], func(a, b int) bool {
+			return center(s.Flows[incoming[i][a]].Source) < center(s.Flows[incoming[i][b]].Source)
+		})
+		t = top[i]
+		for _, f := range incoming[i] {
+			links[f].Y1 = s.Height - t - links[f].Width/2.0
+			t += links[f].Width
+		}
+		nodes[i].Rect.Y = s.Height - top[i] - height[i]
+		nodes[i].Rect.W = s.NodeWidth
+		nodes[i].Rect.H = height[i]
+	}
+	return nodes, links, nil
+}
+
+func (s *Sankey) color(i int) color.RGBA {
+	if len(s.Colors) == 0 {
+		return DefaultColors[i%len(DefaultColors)]
+	}
+	return s.Colors[i%len(s.Colors)]
+}
+
+// Draw draws the diagram with its lower-left corner at (x,y). Gradients are approximated by slicing each ribbon into Gradient parts of flat colors, since canvas has no gradient fills. Opaque slices overlap to hide the seams between them, but translucent slices may show hairline seams due to anti-aliasing.
+func (s *Sankey) Draw(ctx *canvas.Context, x, y float64) error {
+	nodes, links, err := s.Layout()
+	if err != nil {
+		return err
+	}
+	ctx.Push()
+	defer ctx.Pop()
+	ctx.Translate(x, y)
+
+	for _, link := range links {
+		if link.Width <= 0.0 {
+			continue
+		}
+		from, to := s.color(link.Source), s.color(link.Target)
+		if s.Gradient < 2 {
+			ctx.Style = fillStyle(translucent(from, s.Opacity))
+			ctx.DrawPath(0.0, 0.0, link.Path())
+			continue
+		}
+		for i := 0; i < s.Gradient; i++ {
+			t0, t1 := float64(i)/float64(s.Gradient), float64(i+1)/float64(s.Gradient)
+			col := interpolateColor(from, to, (t0+t1)/2.0)
+			if 1.0 <= s.Opacity && i+1 < s.Gradient {
+				t1 += 0.5 / float64(s.Gradient) // overlap opaque slices to hide seams
+			}
+			ctx.Style = fillStyle(translucent(col, s.Opacity))
+			ctx.DrawPath(0.0, 0.0, link.slice(t0, t1))
+		}
+	}
+	for i, node := range nodes {
+		ctx.Style = fillStyle(s.color(i))
+		ctx.DrawPath(node.Rect.X, node.Rect.Y, canvas.Rectangle(node.Rect.W, node.Rect.H))
+	}
+
+	if s.Font == nil {
+		return nil
+	}
+	face := s.Font.Face(s.FontSize, s.TextColor, canvas.FontRegular, canvas.FontNormal)
+	metrics := face.Metrics()
+	for _, node := range nodes {
+		ty := node.Rect.Y + node.Rect.H/2.0 - metrics.XHeight/2.0
+		if node.Rect.X+node.Rect.W/2.0 < s.Width/2.0 {
+			ctx.DrawText(node.Rect.X+node.Rect.W+metrics.XHeight, ty, canvas.NewTextLine(face, node.Name, canvas.Left))
+		} else {
+			ctx.DrawText(node.Rect.X-metrics.XHeight, ty, canvas.NewTextLine(face, node.Name, canvas.Right))
+		}
+	}
+	return nil
+}
+
+// interpolateColor interpolates linearly between two colors.
+func interpolateColor(a, b color.RGBA, t float64) color.RGBA {
+	lerp := func(x, y uint8) uint8 {
+		return uint8(float64(x) + t*(float64(y)-float64(x)) + 0.5)
+	}
+	return color.RGBA{lerp(a.R, b.R), lerp(a.G, b.G), lerp(a.B, b.B), lerp(a.A, b.A)}
+}
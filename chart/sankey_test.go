@@ -0,0 +1,50 @@
+package chart
+
+import (
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestSankey(t *testing.T) {
+	s := NewSankey(50.0, 20.0, []string{"a", "b", "c"}, []Flow{{0, 2, 1}, {1, 2, 1}})
+	s.NodePadding = 2.0
+	nodes, links, err := s.Layout()
+	test.Error(t, err)
+	test.T(t, nodes[0].Rect, canvas.Rect{X: 0.0, Y: 11.0, W: 4.0, H: 9.0})
+	test.T(t, nodes[1].Rect, canvas.Rect{X: 0.0, Y: 0.0, W: 4.0, H: 9.0})
+	test.T(t, nodes[2].Rect, canvas.Rect{X: 46.0, Y: 1.0, W: 4.0, H: 18.0})
+	test.T(t, nodes[2].Column, 1)
+	test.T(t, nodes[2].Value, 2.0)
+	test.T(t, links[0], SankeyLink{Flow{0, 2, 1}, 4.0, 15.5, 46.0, 14.5, 9.0})
+	test.T(t, links[1], SankeyLink{Flow{1, 2, 1}, 4.0, 4.5, 46.0, 5.5, 9.0})
+	test.T(t, links[0].Path(), canvas.MustParseSVG("M4 20C25 20 25 19 46 19L46 10C25 10 25 11 4 11z"))
+
+	// sinks are placed in the last column
+	s = NewSankey(50.0, 20.0, []string{"a", "b", "c", "d"}, []Flow{{0, 1, 1}, {1, 2, 1}, {0, 3, 1}})
+	nodes, _, err = s.Layout()
+	test.Error(t, err)
+	test.T(t, nodes[3].Column, 2)
+	test.T(t, nodes[3].Rect.X, 46.0)
+
+	s.Gradient = 4
+	r := &recorder{}
+	test.Error(t, s.Draw(canvas.NewContext(r), 0.0, 0.0))
+	test.T(t, len(r.paths), 3*4+4)
+	test.T(t, r.texts, 0) // no font
+
+	s.Flows = append(s.Flows, Flow{2, 0, 1})
+	_, _, err = s.Layout()
+	test.T(t, err != nil, true)
+	s.Flows = []Flow{{0, 4, 1}}
+	_, _, err = s.Layout()
+	test.T(t, err != nil, true)
+}
+
+func TestCubicSegment(t *testing.T) {
+	p := [4]canvas.Point{{X: 0, Y: 0}, {X: 1, Y: 2}, {X: 3, Y: 2}, {X: 4, Y: 0}}
+	test.T(t, cubicSegment(p, 0.0, 1.0), p)
+	test.T(t, cubicSegment(p, 0.0, 0.5)[3], canvas.Point{X: 2.0, Y: 1.5})
+	test.T(t, cubicSegment(p, 0.5, 1.0), [4]canvas.Point{{X: 2, Y: 1.5}, {X: 2.75, Y: 1.5}, {X: 3.5, Y: 1}, {X: 4, Y: 0}})
+}
@@ -0,0 +1,172 @@
+// Package chart draws charts such as scatter, line, bar, and area plots onto a canvas, using scales that map data values to positions and axes with ticks and labels.
+package chart
+
+import (
+	"math"
+	"strconv"
+)
+
+// Tick is a position along an axis with a label.
+type Tick struct {
+	Value float64
+	Label string
+}
+
+// Scale maps data values to positions in [0,1] along an axis, and returns the ticks to show along the axis.
+type Scale interface {
+	Map(v float64) float64
+	Ticks() []Tick
+}
+
+// maxTicks is the maximum number of ticks of a linear scale, which limits the ticks of huge ranges or of many requested ticks.
+const maxTicks = 1000
+
+// LinearScale is a linear scale from Min to Max.
+type LinearScale struct {
+	Min, Max float64
+	NumTicks int // approximate number of ticks, defaults to 5
+}
+
+// NewLinearScale returns a linear scale that includes min and max and is extended to round tick values at both ends.
+func NewLinearScale(min, max float64) *LinearScale {
+	if max < min {
+		min, max = max, min
+	}
+	if min == max {
+		if min == 0.0 {
+			min, max = -1.0, 1.0
+		} else {
+			d := math.Abs(min) / 10.0
+			min, max = min-d, max+d
+		}
+	}
+	step := tickStep(min, max, 5)
+	return &LinearScale{math.Floor(min/step) * step, math.Ceil(max/step) * step, 5}
+}
+
+// Map maps a value to [0,1].
+func (s *LinearScale) Map(v float64) float64 {
+	if s.Max == s.Min {
+		return 0.5
+	}
+	return (v - s.Min) / (s.Max - s.Min)
+}
+
+// Ticks returns ticks at round values.
+func (s *LinearScale) Ticks() []Tick {
+	n := s.NumTicks
+	if n <= 0 {
+		n = 5
+	}
+	min, max := math.Min(s.Min, s.Max), math.Max(s.Min, s.Max)
+	if min == max {
+		return []Tick{{min, formatTick(min, 1.0)}}
+	}
+	step := tickStep(min, max, n)
+	first, last := math.Ceil(min/step-1e-9), math.Floor(max/step+1e-9)
+	count := last - first + 1.0
+	if !(0.0 < count) {
+		return []Tick{} // non-finite bounds
+	}
+	count = math.Min(count, maxTicks)
+	ticks := []Tick{}
+	for i := 0; i < int(count); i++ {
+		v := (first + float64(i)) * step
+		if v == 0.0 {
+			v = 0.0 // avoid negative zero
+		}
+		ticks = append(ticks, Tick{v, formatTick(v, step)})
+	}
+	return ticks
+}
+
+// LogScale is a logarithmic scale from Min to Max, which must be positive.
+type LogScale struct {
+	Min, Max float64
+}
+
+// NewLogScale returns a logarithmic scale that includes min and max and is extended to powers of ten at both ends. Non-positive bounds are clamped, max to one and min to a decade below max.
+func NewLogScale(min, max float64) *LogScale {
+	if max < min {
+		min, max = max, min
+	}
+	if max <= 0.0 {
+		max = 1.0
+	}
+	if min <= 0.0 {
+		min = max / 10.0
+	}
+	min = math.Pow(10.0, math.Floor(math.Log10(min)))
+	max = math.Pow(10.0, math.Ceil(math.Log10(max)))
+	if min == max {
+		max *= 10.0
+	}
+	return &LogScale{min, max}
+}
+
+// Map maps a value to [0,1].
+func (s *LogScale) Map(v float64) float64 {
+	return math.Log(v/s.Min) / math.Log(s.Max/s.Min)
+}
+
+// Ticks returns ticks at the powers of ten.
+func (s *LogScale) Ticks() []Tick {
+	ticks := []Tick{}
+	eMin, eMax := math.Log10(s.Min), math.Log10(s.Max)
+	if math.IsInf(eMin, 0) || math.IsNaN(eMin) || math.IsInf(eMax, 0) || math.IsNaN(eMax) {
+		return ticks
+	}
+	for e := math.Ceil(eMin - 1e-9); e <= eMax+1e-9; e++ {
+		v := math.Pow(10.0, e)
+		ticks = append(ticks, Tick{v, strconv.FormatFloat(v, 'g', -1, 64)})
+	}
+	return ticks
+}
+
+// CategoryScale is a scale for categories, where the category with index i has the value i and is centered in its band.
+type CategoryScale struct {
+	Categories []string
+}
+
+// NewCategoryScale returns a scale for the given categories.
+func NewCategoryScale(categories ...string) *CategoryScale {
+	return &CategoryScale{categories}
+}
+
+// Map maps the index of a category to [0,1].
+func (s *CategoryScale) Map(v float64) float64 {
+	if len(s.Categories) == 0 {
+		return 0.5
+	}
+	return (v + 0.5) / float64(len(s.Categories))
+}
+
+// Ticks returns a tick for each category.
+func (s *CategoryScale) Ticks() []Tick {
+	ticks := make([]Tick, len(s.Categories))
+	for i, category := range s.Categories {
+		ticks[i] = Tick{float64(i), category}
+	}
+	return ticks
+}
+
+// tickStep returns a step of 1, 2, or 5 times a power of ten that divides [min,max] into about n intervals.
+func tickStep(min, max float64, n int) float64 {
+	raw := (max - min) / float64(n)
+	magnitude := math.Pow(10.0, math.Floor(math.Log10(raw)))
+	switch residual := raw / magnitude; {
+	case residual < 1.5:
+		return magnitude
+	case residual < 3.0:
+		return 2.0 * magnitude
+	case residual < 7.0:
+		return 5.0 * magnitude
+	}
+	return 10.0 * magnitude
+}
+
+// formatTick formats a tick value with as many decimals as the step requires.
+func formatTick(v, step float64) string {
+	decimals := int(math.Max(0.0, -math.Floor(math.Log10(step)+1e-9)))
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
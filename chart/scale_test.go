@@ -0,0 +1,61 @@
+package chart
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestLinearScale(t *testing.T) {
+	s := NewLinearScale(0.3, 9.2)
+	test.T(t, s.Min, 0.0)
+	test.T(t, s.Max, 10.0)
+	test.Float(t, s.Map(5.0), 0.5)
+	test.T(t, s.Ticks(), []Tick{{0, "0"}, {2, "2"}, {4, "4"}, {6, "6"}, {8, "8"}, {10, "10"}})
+
+	s = NewLinearScale(-0.012, 0.047)
+	test.Float(t, s.Min, -0.02)
+	test.Float(t, s.Max, 0.05)
+	test.T(t, s.Ticks()[0], Tick{-0.02, "-0.02"})
+	test.T(t, s.Ticks()[2], Tick{0.0, "0.00"})
+
+	// degenerate ranges
+	s = NewLinearScale(0.0, 0.0)
+	test.T(t, s.Min, -1.0)
+	test.T(t, s.Max, 1.0)
+	test.That(t, NewLinearScale(5.0, 5.0).Min < 5.0)
+	test.T(t, (&LinearScale{Min: 1.0, Max: 1.0}).Map(1.0), 0.5)
+
+	// ranges far from zero, such as timestamps in nanoseconds, where the tick indices exceed the precision of floats
+	ticks := NewLinearScale(1e18, 1e18+256).Ticks()
+	test.That(t, 0 < len(ticks) && len(ticks) <= maxTicks, len(ticks))
+	test.T(t, len((&LinearScale{Min: 0.0, Max: 1.0, NumTicks: 1e9}).Ticks()), maxTicks)
+	test.T(t, len((&LinearScale{Min: 0.0, Max: math.Inf(1)}).Ticks()), 0)
+}
+
+func TestLogScale(t *testing.T) {
+	s := NewLogScale(3.0, 250.0)
+	test.T(t, s.Min, 1.0)
+	test.T(t, s.Max, 1000.0)
+	test.Float(t, s.Map(10.0), 1.0/3.0)
+	test.T(t, s.Ticks(), []Tick{{1, "1"}, {10, "10"}, {100, "100"}, {1000, "1000"}})
+
+	// non-positive bounds
+	s = NewLogScale(0.0, 100.0)
+	test.T(t, s.Min, 10.0)
+	test.T(t, s.Max, 100.0)
+	test.T(t, len(s.Ticks()), 2)
+	s = NewLogScale(-5.0, 0.0)
+	test.T(t, s.Min, 0.1)
+	test.T(t, s.Max, 1.0)
+	test.T(t, len((&LogScale{0.0, 100.0}).Ticks()), 0)
+	test.T(t, len((&LogScale{1.0, math.Inf(1)}).Ticks()), 0)
+}
+
+func TestCategoryScale(t *testing.T) {
+	s := NewCategoryScale("a", "b", "c", "d")
+	test.Float(t, s.Map(0.0), 0.125)
+	test.Float(t, s.Map(3.0), 0.875)
+	test.T(t, s.Ticks()[1], Tick{1, "b"})
+}
@@ -0,0 +1,227 @@
+package chart
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/tdewolff/canvas"
+)
+
+// extent returns the range of the data points.
+func extent(xs, ys []float64) canvas.Rect {
+	n := len(xs)
+	if len(ys) < n {
+		n = len(ys)
+	}
+	if n == 0 {
+		return canvas.Rect{}
+	}
+	xmin, xmax, ymin, ymax := xs[0], xs[0], ys[0], ys[0]
+	for i := 1; i < n; i++ {
+		xmin, xmax = math.Min(xmin, xs[i]), math.Max(xmax, xs[i])
+		ymin, ymax = math.Min(ymin, ys[i]), math.Max(ymax, ys[i])
+	}
+	return canvas.Rect{X: xmin, Y: ymin, W: xmax - xmin, H: ymax - ymin}
+}
+
+// fillStyle returns a style that fills with the given color without stroking.
+func fillStyle(col color.RGBA) canvas.Style {
+	style := canvas.DefaultStyle
+	style.FillColor = col
+	style.StrokeColor = canvas.Transparent
+	return style
+}
+
+// lineStyle returns a style that strokes with the given color and width without filling.
+func lineStyle(col color.RGBA, width float64) canvas.Style {
+	style := canvas.DefaultStyle
+	style.FillColor = canvas.Transparent
+	style.StrokeColor = col
+	style.StrokeWidth = width
+	style.StrokeCapper = canvas.RoundCap
+	style.StrokeJoiner = canvas.RoundJoin
+	return style
+}
+
+// Scatter is a series of markers at the data points.
+type Scatter struct {
+	X, Y   []float64
+	Marker *canvas.Path // centered at the origin
+	Style  canvas.Style
+}
+
+// NewScatter returns a scatter series with circular markers of 1 mm in the first color of DefaultColors.
+func NewScatter(x, y []float64) *Scatter {
+	return &Scatter{x, y, canvas.Circle(0.5), fillStyle(DefaultColors[0])}
+}
+
+// Extent returns the range of the data.
+func (s *Scatter) Extent() canvas.Rect {
+	return extent(s.X, s.Y)
+}
+
+// Draw draws the series.
+func (s *Scatter) Draw(ctx *canvas.Context, coord Coordinates) {
+	ctx.Style = s.Style
+	for i := 0; i < len(s.X) && i < len(s.Y); i++ {
+		pos := coord.Pos(s.X[i], s.Y[i])
+		ctx.DrawPath(pos.X, pos.Y, s.Marker)
+	}
+}
+
+// Line is a series of data points connected by lines.
+type Line struct {
+	X, Y       []float64
+	Smooth     bool    // connect the points by smooth cubic Béziers
+	Resolution float64 // width in millimeters of the columns to which points are decimated, see M4, or zero to keep all points
+	Style      canvas.Style
+}
+
+// NewLine returns a line series with a width of 0.5 mm in the first color of DefaultColors. Points are decimated at a resolution of 0.02 mm, which is finer than printers resolve, so that long series draw the same with paths of at most four points per 0.02 mm.
+func NewLine(x, y []float64) *Line {
+	return &Line{x, y, false, 0.02, lineStyle(DefaultColors[0], 0.5)}
+}
+
+// Extent returns the range of the data.
+func (s *Line) Extent() canvas.Rect {
+	return extent(s.X, s.Y)
+}
+
+// Path returns the line in canvas coordinates.
+func (s *Line) Path(coord Coordinates) *canvas.Path {
+	points := []canvas.Point{}
+	for i := 0; i < len(s.X) && i < len(s.Y); i++ {
+		points = append(points, coord.Pos(s.X[i], s.Y[i]))
+	}
+	polyline := &canvas.Polyline{}
+	for _, pos := range decimatePoints(points, s.Resolution) {
+		polyline.Add(pos.X, pos.Y)
+	}
+	if s.Smooth {
+		return polyline.Smoothen()
+	}
+	return polyline.ToPath()
+}
+
+// Draw draws the series.
+func (s *Line) Draw(ctx *canvas.Context, coord Coordinates) {
+	ctx.Style = s.Style
+	ctx.DrawPath(0.0, 0.0, s.Path(coord))
+}
+
+// Bar is a series of bars from Base to the data values, where X is usually the index of a category of a CategoryScale.
+type Bar struct {
+	X, Y       []float64
+	Base       []float64 // base of each bar, zero if nil, which allows stacking bars
+	Width      float64   // width of the bars in data units
+	Offset     float64   // offset of the bars in data units, eg. to group bars next to each other
+	Horizontal bool      // bars extend along the x-axis and X and Y are swapped
+	Style      canvas.Style
+}
+
+// NewBar returns a bar series with bars of 0.8 data units wide in the first color of DefaultColors.
+func NewBar(x, y []float64) *Bar {
+	return &Bar{X: x, Y: y, Width: 0.8, Style: fillStyle(DefaultColors[0])}
+}
+
+func (s *Bar) base(i int) float64 {
+	if i < len(s.Base) {
+		return s.Base[i]
+	}
+	return 0.0
+}
+
+// Extent returns the range of the data including the bar widths and bases.
+func (s *Bar) Extent() canvas.Rect {
+	xs, ys := []float64{}, []float64{}
+	for i := 0; i < len(s.X) && i < len(s.Y); i++ {
+		x := s.X[i] + s.Offset
+		xs = append(xs, x-s.Width/2.0, x+s.Width/2.0)
+		ys = append(ys, s.base(i), s.Y[i])
+	}
+	if s.Horizontal {
+		xs, ys = ys, xs
+	}
+	return extent(xs, ys)
+}
+
+// Draw draws the series.
+func (s *Bar) Draw(ctx *canvas.Context, coord Coordinates) {
+	ctx.Style = s.Style
+	for i := 0; i < len(s.X) && i < len(s.Y); i++ {
+		x := s.X[i] + s.Offset
+		ctx.DrawPath(0.0, 0.0, polygon(coord, s.Horizontal, [][2]float64{
+			{x - s.Width/2.0, s.base(i)},
+			{x + s.Width/2.0, s.base(i)},
+			{x + s.Width/2.0, s.Y[i]},
+			{x - s.Width/2.0, s.Y[i]},
+		}))
+	}
+}
+
+// Area is a series that fills the area between the data points and Base.
+type Area struct {
+	X, Y  []float64
+	Base  []float64 // base at each data point, zero if nil, which allows stacking areas
+	Style canvas.Style
+}
+
+// NewArea returns an area series in the first color of DefaultColors.
+func NewArea(x, y []float64) *Area {
+	return &Area{X: x, Y: y, Style: fillStyle(DefaultColors[0])}
+}
+
+// Extent returns the range of the data including the base.
+func (s *Area) Extent() canvas.Rect {
+	xs, ys := []float64{}, []float64{}
+	for i := 0; i < len(s.X) && i < len(s.Y); i++ {
+		base := 0.0
+		if i < len(s.Base) {
+			base = s.Base[i]
+		}
+		xs = append(xs, s.X[i], s.X[i])
+		ys = append(ys, s.Y[i], base)
+	}
+	return extent(xs, ys)
+}
+
+// Draw draws the series.
+func (s *Area) Draw(ctx *canvas.Context, coord Coordinates) {
+	n := len(s.X)
+	if len(s.Y) < n {
+		n = len(s.Y)
+	}
+	points := make([][2]float64, 0, 2*n)
+	for i := 0; i < n; i++ {
+		points = append(points, [2]float64{s.X[i], s.Y[i]})
+	}
+	for i := n - 1; 0 <= i; i-- {
+		base := 0.0
+		if i < len(s.Base) {
+			base = s.Base[i]
+		}
+		points = append(points, [2]float64{s.X[i], base})
+	}
+	ctx.Style = s.Style
+	ctx.DrawPath(0.0, 0.0, polygon(coord, false, points))
+}
+
+// polygon returns the closed polygon through the data points in canvas coordinates, swapping x and y if transposed.
+func polygon(coord Coordinates, transposed bool, points [][2]float64) *canvas.Path {
+	p := &canvas.Path{}
+	for i, point := range points {
+		if transposed {
+			point[0], point[1] = point[1], point[0]
+		}
+		pos := coord.Pos(point[0], point[1])
+		if i == 0 {
+			p.MoveTo(pos.X, pos.Y)
+		} else {
+			p.LineTo(pos.X, pos.Y)
+		}
+	}
+	if 0 < len(points) {
+		p.Close()
+	}
+	return p
+}
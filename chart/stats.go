@@ -0,0 +1,314 @@
+package chart
+
+import (
+	"math"
+	"sort"
+
+	"github.com/tdewolff/canvas"
+)
+
+// sorted returns a sorted copy of the samples without NaNs.
+func sorted(samples []float64) []float64 {
+	s := make([]float64, 0, len(samples))
+	for _, v := range samples {
+		if !math.IsNaN(v) {
+			s = append(s, v)
+		}
+	}
+	sort.Float64s(s)
+	return s
+}
+
+// quantile returns the q-th quantile of sorted samples, interpolating linearly between the closest ranks.
+func quantile(s []float64, q float64) float64 {
+	if len(s) == 0 {
+		return math.NaN()
+	}
+	h := q * float64(len(s)-1)
+	i := int(math.Floor(h))
+	if len(s)-1 <= i {
+		return s[len(s)-1]
+	}
+	return s[i] + (h-float64(i))*(s[i+1]-s[i])
+}
+
+// mean and standard deviation of the samples.
+func meanStdDev(s []float64) (float64, float64) {
+	mean := 0.0
+	for _, v := range s {
+		mean += v
+	}
+	mean /= float64(len(s))
+	variance := 0.0
+	for _, v := range s {
+		variance += (v - mean) * (v - mean)
+	}
+	if 1 < len(s) {
+		variance /= float64(len(s) - 1)
+	}
+	return mean, math.Sqrt(variance)
+}
+
+////////////////////////////////////////////////////////////////
+
+// Histogram is a series of bars that count the samples that fall into each bin.
+type Histogram struct {
+	Edges   []float64 // bin edges, with one more edge than there are bins
+	Counts  []float64 // number of samples in each bin, or the density if normalized
+	Density bool      // whether the counts are normalized so that the total area is one
+	Style   canvas.Style
+}
+
+// NewHistogram returns a histogram of the samples with the given number of bins of equal width, or uses Sturges' rule if bins is zero or negative.
+func NewHistogram(samples []float64, bins int) *Histogram {
+	s := sorted(samples)
+	if bins <= 0 {
+		bins = 1
+		if 0 < len(s) {
+			bins = int(math.Ceil(math.Log2(float64(len(s))))) + 1
+		}
+	}
+	min, max := 0.0, 1.0
+	if 0 < len(s) {
+		min, max = s[0], s[len(s)-1]
+		if min == max {
+			min, max = min-0.5, max+0.5
+		}
+	}
+
+	edges := make([]float64, bins+1)
+	for i := range edges {
+		edges[i] = min + (max-min)*float64(i)/float64(bins)
+	}
+	return NewHistogramEdges(samples, edges)
+}
+
+// NewHistogramEdges returns a histogram of the samples with the given bin edges, which must be increasing. The last bin includes its upper edge, and samples outside the edges are not counted. With fewer than two edges there are no bins.
+func NewHistogramEdges(samples []float64, edges []float64) *Histogram {
+	if len(edges) < 2 {
+		return &Histogram{edges, []float64{}, false, fillStyle(DefaultColors[0])}
+	}
+	counts := make([]float64, len(edges)-1)
+	for _, v := range samples {
+		if math.IsNaN(v) || v < edges[0] || edges[len(edges)-1] < v {
+			continue
+		}
+		i := sort.SearchFloat64s(edges, v) // first edge >= v
+		if i == len(edges) || edges[i] != v {
+			i--
+		}
+		if i == len(counts) {
+			i-- // include upper edge
+		}
+		counts[i]++
+	}
+	return &Histogram{edges, counts, false, fillStyle(DefaultColors[0])}
+}
+
+// Normalize normalizes the counts so that the total area of the bars is one, ie. it becomes a probability density.
+func (h *Histogram) Normalize() *Histogram {
+	total := 0.0
+	for _, count := range h.Counts {
+		total += count
+	}
+	if !h.Density && 0.0 < total {
+		for i := range h.Counts {
+			h.Counts[i] /= total * (h.Edges[i+1] - h.Edges[i])
+		}
+	}
+	h.Density = true
+	return h
+}
+
+// Extent returns the range of the bins and counts.
+func (h *Histogram) Extent() canvas.Rect {
+	max := 0.0
+	for _, count := range h.Counts {
+		max = math.Max(max, count)
+	}
+	if len(h.Edges) == 0 {
+		return canvas.Rect{H: max}
+	}
+	return canvas.Rect{X: h.Edges[0], W: h.Edges[len(h.Edges)-1] - h.Edges[0], H: max}
+}
+
+// Draw draws the series.
+func (h *Histogram) Draw(ctx *canvas.Context, coord Coordinates) {
+	ctx.Style = h.Style
+	for i, count := range h.Counts {
+		if count == 0.0 {
+			continue
+		}
+		ctx.DrawPath(0.0, 0.0, polygon(coord, false, [][2]float64{
+			{h.Edges[i], 0.0},
+			{h.Edges[i+1], 0.0},
+			{h.Edges[i+1], count},
+			{h.Edges[i], count},
+		}))
+	}
+}
+
+////////////////////////////////////////////////////////////////
+
+// BoxPlot is a box-and-whisker plot of samples at position X. The box ranges from the first to the third quartile with a line at the median, the whiskers extend to the most extreme samples within 1.5 times the interquartile range from the box, and samples beyond the whiskers are drawn as outliers.
+type BoxPlot struct {
+	X              float64
+	Width          float64 // width of the box in data units
+	Q1, Median, Q3 float64
+	WhiskerLow     float64
+	WhiskerHigh    float64
+	Outliers       []float64
+	Style          canvas.Style // style of the box
+	LineStyle      canvas.Style // style of the median, whiskers, and the outline of the box
+	Marker         *canvas.Path // marker of outliers
+	MarkerStyle    canvas.Style
+}
+
+// NewBoxPlot returns a box plot of the samples at position x.
+func NewBoxPlot(x float64, samples []float64) *BoxPlot {
+	s := sorted(samples)
+	b := &BoxPlot{
+		X:           x,
+		Width:       0.5,
+		Q1:          quantile(s, 0.25),
+		Median:      quantile(s, 0.5),
+		Q3:          quantile(s, 0.75),
+		Style:       fillStyle(DefaultColors[0]),
+		LineStyle:   lineStyle(canvas.Black, 0.3),
+		Marker:      canvas.Circle(0.5),
+		MarkerStyle: lineStyle(canvas.Black, 0.2),
+	}
+	b.LineStyle.StrokeCapper = canvas.ButtCap
+	b.LineStyle.StrokeJoiner = canvas.MiterJoin
+
+	iqr := b.Q3 - b.Q1
+	b.WhiskerLow, b.WhiskerHigh = b.Q1, b.Q3
+	for _, v := range s {
+		if v < b.Q1-1.5*iqr || b.Q3+1.5*iqr < v {
+			b.Outliers = append(b.Outliers, v)
+		} else {
+			b.WhiskerLow = math.Min(b.WhiskerLow, v)
+			b.WhiskerHigh = math.Max(b.WhiskerHigh, v)
+		}
+	}
+	return b
+}
+
+// Extent returns the range of the box, whiskers, and outliers.
+func (b *BoxPlot) Extent() canvas.Rect {
+	low, high := b.WhiskerLow, b.WhiskerHigh
+	for _, v := range b.Outliers {
+		low, high = math.Min(low, v), math.Max(high, v)
+	}
+	return canvas.Rect{X: b.X - b.Width/2.0, Y: low, W: b.Width, H: high - low}
+}
+
+// Draw draws the series.
+func (b *BoxPlot) Draw(ctx *canvas.Context, coord Coordinates) {
+	if math.IsNaN(b.Median) {
+		return
+	}
+	x0, x1 := b.X-b.Width/2.0, b.X+b.Width/2.0
+	box := polygon(coord, false, [][2]float64{{x0, b.Q1}, {x1, b.Q1}, {x1, b.Q3}, {x0, b.Q3}})
+	ctx.Style = b.Style
+	ctx.DrawPath(0.0, 0.0, box)
+
+	lines := &canvas.Path{}
+	line := func(xa, ya, xb, yb float64) {
+		a, b := coord.Pos(xa, ya), coord.Pos(xb, yb)
+		lines.MoveTo(a.X, a.Y)
+		lines.LineTo(b.X, b.Y)
+	}
+	line(x0, b.Median, x1, b.Median)
+	line(b.X, b.Q3, b.X, b.WhiskerHigh)
+	line(b.X, b.Q1, b.X, b.WhiskerLow)
+	line(b.X-b.Width/4.0, b.WhiskerHigh, b.X+b.Width/4.0, b.WhiskerHigh)
+	line(b.X-b.Width/4.0, b.WhiskerLow, b.X+b.Width/4.0, b.WhiskerLow)
+	ctx.Style = b.LineStyle
+	ctx.DrawPath(0.0, 0.0, box, lines)
+
+	ctx.Style = b.MarkerStyle
+	for _, v := range b.Outliers {
+		pos := coord.Pos(b.X, v)
+		ctx.DrawPath(pos.X, pos.Y, b.Marker)
+	}
+}
+
+////////////////////////////////////////////////////////////////
+
+// Violin is a violin plot of samples at position X, which shows their distribution as a kernel density estimate mirrored around X.
+type Violin struct {
+	X       float64
+	Width   float64   // maximum width in data units
+	Values  []float64 // values at which the density is estimated
+	Density []float64 // density at each value
+	Style   canvas.Style
+}
+
+// violinSamples is the number of values at which the density is estimated.
+const violinSamples = 100
+
+// NewViolin returns a violin plot of the samples at position x, using a Gaussian kernel density estimate with the given bandwidth, or Silverman's rule of thumb if bandwidth is zero. The density is estimated within three bandwidths beyond the extreme samples.
+func NewViolin(x float64, samples []float64, bandwidth float64) *Violin {
+	v := &Violin{X: x, Width: 0.8, Style: fillStyle(DefaultColors[0])}
+	s := sorted(samples)
+	if len(s) == 0 {
+		return v
+	}
+	if bandwidth <= 0.0 {
+		_, sd := meanStdDev(s)
+		iqr := quantile(s, 0.75) - quantile(s, 0.25)
+		spread := sd
+		if 0.0 < iqr {
+			spread = math.Min(sd, iqr/1.34)
+		}
+		bandwidth = 0.9 * spread * math.Pow(float64(len(s)), -0.2)
+		if bandwidth <= 0.0 {
+			bandwidth = 1.0 // all samples are equal
+		}
+	}
+
+	min, max := s[0]-3.0*bandwidth, s[len(s)-1]+3.0*bandwidth
+	v.Values = make([]float64, violinSamples)
+	v.Density = make([]float64, violinSamples)
+	norm := 1.0 / (float64(len(s)) * bandwidth * math.Sqrt(2.0*math.Pi))
+	for i := range v.Values {
+		t := min + (max-min)*float64(i)/float64(violinSamples-1)
+		density := 0.0
+		for _, sample := range s {
+			z := (t - sample) / bandwidth
+			density += math.Exp(-z * z / 2.0)
+		}
+		v.Values[i], v.Density[i] = t, density*norm
+	}
+	return v
+}
+
+// Extent returns the range of the estimated density.
+func (v *Violin) Extent() canvas.Rect {
+	if len(v.Values) == 0 {
+		return canvas.Rect{X: v.X - v.Width/2.0, W: v.Width}
+	}
+	return canvas.Rect{X: v.X - v.Width/2.0, Y: v.Values[0], W: v.Width, H: v.Values[len(v.Values)-1] - v.Values[0]}
+}
+
+// Draw draws the series.
+func (v *Violin) Draw(ctx *canvas.Context, coord Coordinates) {
+	max := 0.0
+	for _, density := range v.Density {
+		max = math.Max(max, density)
+	}
+	if max == 0.0 {
+		return
+	}
+
+	points := make([][2]float64, 0, 2*len(v.Values))
+	for i, value := range v.Values {
+		points = append(points, [2]float64{v.X + v.Density[i]/max*v.Width/2.0, value})
+	}
+	for i := len(v.Values) - 1; 0 <= i; i-- {
+		points = append(points, [2]float64{v.X - v.Density[i]/max*v.Width/2.0, v.Values[i]})
+	}
+	ctx.Style = v.Style
+	ctx.DrawPath(0.0, 0.0, polygon(coord, false, points))
+}
@@ -0,0 +1,89 @@
+package chart
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestQuantile(t *testing.T) {
+	s := sorted([]float64{5, 1, math.NaN(), 3, 2, 4})
+	test.T(t, s, []float64{1, 2, 3, 4, 5})
+	test.Float(t, quantile(s, 0.0), 1.0)
+	test.Float(t, quantile(s, 0.25), 2.0)
+	test.Float(t, quantile(s, 0.5), 3.0)
+	test.Float(t, quantile(s, 0.9), 4.6)
+	test.Float(t, quantile(s, 1.0), 5.0)
+	test.That(t, math.IsNaN(quantile(nil, 0.5)))
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram([]float64{0, 1, 1, 2, 3, 4, 4, 4}, 4)
+	test.T(t, h.Edges, []float64{0, 1, 2, 3, 4})
+	test.T(t, h.Counts, []float64{1, 2, 1, 4})
+	test.T(t, h.Extent(), canvas.Rect{X: 0, Y: 0, W: 4, H: 4})
+
+	h.Normalize()
+	area := 0.0
+	for i, count := range h.Counts {
+		area += count * (h.Edges[i+1] - h.Edges[i])
+	}
+	test.Float(t, area, 1.0)
+
+	h = NewHistogramEdges([]float64{-1, 0, 0.5, 10, 11}, []float64{0, 1, 10})
+	test.T(t, h.Counts, []float64{2, 1})
+
+	// Sturges' rule
+	test.T(t, len(NewHistogram(make([]float64, 100), 0).Counts), 8)
+	test.T(t, NewHistogram(nil, 0).Counts, []float64{0})
+	test.T(t, NewHistogramEdges([]float64{1, 2}, nil).Counts, []float64{})
+	test.T(t, NewHistogramEdges([]float64{1, 2}, []float64{1}).Extent(), canvas.Rect{X: 1})
+
+	r := &recorder{}
+	NewHistogram([]float64{0, 1, 1}, 2).Draw(canvas.NewContext(r), Cartesian{&LinearScale{Min: 0, Max: 1}, &LinearScale{Min: 0, Max: 2}, 2.0, 2.0})
+	test.T(t, r.paths[0], canvas.MustParseSVG("M0 0L1 0L1 1L0 1z"))
+	test.T(t, r.paths[1], canvas.MustParseSVG("M1 0L2 0L2 2L1 2z"))
+}
+
+func TestBoxPlot(t *testing.T) {
+	b := NewBoxPlot(1.0, []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 30})
+	test.Float(t, b.Q1, 3.25)
+	test.Float(t, b.Median, 5.5)
+	test.Float(t, b.Q3, 7.75)
+	test.Float(t, b.WhiskerLow, 1.0)
+	test.Float(t, b.WhiskerHigh, 9.0)
+	test.T(t, b.Outliers, []float64{30})
+	test.T(t, b.Extent(), canvas.Rect{X: 0.75, Y: 1.0, W: 0.5, H: 29.0})
+
+	r := &recorder{}
+	b.Draw(canvas.NewContext(r), Cartesian{&LinearScale{Min: 0, Max: 2}, &LinearScale{Min: 0, Max: 30}, 2.0, 30.0})
+	test.T(t, len(r.paths), 4) // box, outline, whiskers and median, outlier
+}
+
+func TestViolin(t *testing.T) {
+	rnd := rand.New(rand.NewSource(0))
+	samples := make([]float64, 1000)
+	for i := range samples {
+		samples[i] = rnd.NormFloat64()
+	}
+	v := NewViolin(0.0, samples, 0.0)
+	test.T(t, len(v.Values), violinSamples)
+
+	// the density integrates to one and peaks near the mean
+	area, peak := 0.0, 0
+	for i := 1; i < len(v.Values); i++ {
+		area += (v.Density[i] + v.Density[i-1]) / 2.0 * (v.Values[i] - v.Values[i-1])
+		if v.Density[peak] < v.Density[i] {
+			peak = i
+		}
+	}
+	test.That(t, math.Abs(area-1.0) < 0.01, "area")
+	test.That(t, math.Abs(v.Values[peak]) < 0.3, "peak")
+	test.That(t, math.Abs(v.Density[peak]-1.0/math.Sqrt(2.0*math.Pi)) < 0.05, "peak density")
+
+	v = NewViolin(0.0, []float64{2, 2, 2}, 0.0)
+	test.T(t, v.Extent(), canvas.Rect{X: -0.4, Y: -1.0, W: 0.8, H: 6.0})
+}
@@ -0,0 +1,144 @@
+package chart
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/tdewolff/canvas"
+)
+
+// SunburstSegment is a node of a laid out sunburst, which runs clockwise from Start to End in degrees from the top, between the radii Inner and Outer in millimeters.
+type SunburstSegment struct {
+	Tree         *Tree
+	Depth        int // zero for the root
+	Index        int // index of the ancestor at depth one, used for its color
+	Start, End   float64
+	Inner, Outer float64
+}
+
+// Sunburst is a radial layout of a hierarchy with a radius of Radius millimeters, where the root is a disk in the center and each level below is a ring of arc segments. The angle of each segment is proportional to its value and lies within the angle of its parent.
+type Sunburst struct {
+	Radius float64
+	Root   *Tree
+
+	Colors []color.RGBA // colors of the nodes at depth one, which are lightened for their descendants
+	Style  canvas.Style // style of the segments with a fill color that is replaced by Colors
+
+	Font        *canvas.FontFamily // no text is drawn when nil
+	FontSize    float64            // largest size of the labels in points
+	MinFontSize float64            // smallest size of the labels in points, labels that do not fit at this size are not drawn
+	TextColor   color.RGBA
+}
+
+// NewSunburst returns a sunburst with a radius of radius millimeters of the hierarchy.
+func NewSunburst(radius float64, root *Tree) *Sunburst {
+	style := fillStyle(canvas.Black)
+	style.StrokeColor = canvas.White
+	style.StrokeWidth = 0.3
+	style.StrokeJoiner = canvas.BevelJoin
+	return &Sunburst{
+		Radius:      radius,
+		Root:        root,
+		Colors:      DefaultColors,
+		Style:       style,
+		FontSize:    8.0,
+		MinFontSize: 4.0,
+		TextColor:   canvas.Black,
+	}
+}
+
+// Layout returns the segments of all nodes with a positive value, parents before their children. All rings have the same width.
+func (s *Sunburst) Layout() []SunburstSegment {
+	segments := []SunburstSegment{}
+	if s.Root == nil || s.Root.Sum() <= 0.0 {
+		return segments
+	}
+	width := s.Radius / float64(s.Root.depth()+1)
+	var layout func(*Tree, int, int, float64, float64)
+	layout = func(node *Tree, depth, index int, start, end float64) {
+		inner := 0.0
+		if 0 < depth {
+			inner = float64(depth) * width
+		}
+		segments = append(segments, SunburstSegment{node, depth, index, start, end, inner, inner + width})
+
+		sum := node.Sum()
+		angle := start
+		for i, child := range node.Children {
+			value := child.Sum()
+			if value <= 0.0 {
+				continue
+			}
+			if depth == 0 {
+				index = i
+			}
+			next := angle + value/sum*(end-start)
+			layout(child, depth+1, index, angle, next)
+			angle = next
+		}
+	}
+	layout(s.Root, 0, 0, 0.0, 360.0)
+	return segments
+}
+
+// Path returns the segment centered at the origin, which is a disk for the root.
+func (segment SunburstSegment) Path() *canvas.Path {
+	if segment.Depth == 0 {
+		return canvas.Circle(segment.Outer)
+	}
+	coord := Polar{&LinearScale{Min: 0.0, Max: 360.0}, &LinearScale{Min: 0.0, Max: 1.0}, 1.0, 90.0, true}
+	p := &canvas.Path{}
+	start := coord.Pos(segment.Start, segment.Outer)
+	p.MoveTo(start.X, start.Y)
+	coord.arc(p, segment.Start, segment.End, segment.Outer)
+	end := coord.Pos(segment.End, segment.Inner)
+	p.LineTo(end.X, end.Y)
+	coord.arc(p, segment.End, segment.Start, segment.Inner)
+	p.Close()
+	return p
+}
+
+// Draw draws the sunburst with its center at (x,y). Segments are labeled with their name along the middle of their ring, at the largest font size between MinFontSize and FontSize at which it fits within the segment. The root is labeled horizontally.
+func (s *Sunburst) Draw(ctx *canvas.Context, x, y float64) {
+	segments := s.Layout()
+	ctx.Push()
+	defer ctx.Pop()
+	ctx.Translate(x, y)
+
+	for _, segment := range segments {
+		ctx.Style = s.Style
+		ctx.Style.FillColor = canvas.White
+		if 0 < segment.Depth {
+			ctx.Style.FillColor = hierarchyColor(s.Colors, segment.Index, segment.Depth)
+		}
+		ctx.DrawPath(0.0, 0.0, segment.Path())
+	}
+
+	if s.Font == nil {
+		return
+	}
+	for _, segment := range segments {
+		if segment.Tree.Name == "" {
+			continue
+		}
+
+		// font sizes scale the text width and line height linearly
+		face := s.Font.Face(s.FontSize, s.TextColor, canvas.FontRegular, canvas.FontNormal)
+		width, height := face.TextWidth(segment.Tree.Name), face.Metrics().LineHeight
+		r := (segment.Inner + segment.Outer) / 2.0
+		length := 2.0 * r // available length along the ring, or the diameter of the root
+		if 0 < segment.Depth {
+			length = (segment.End - segment.Start) * math.Pi / 180.0 * r
+		}
+		size := s.FontSize * math.Min(1.0, math.Min(0.9*length/width, 0.9*(segment.Outer-segment.Inner)/height))
+		if size < s.MinFontSize {
+			continue
+		}
+		face = s.Font.Face(size, s.TextColor, canvas.FontRegular, canvas.FontNormal)
+		if segment.Depth == 0 {
+			ctx.DrawText(0.0, -face.Metrics().XHeight/2.0, canvas.NewTextLine(face, segment.Tree.Name, canvas.Center))
+		} else {
+			drawTextOnArc(ctx, face, segment.Tree.Name, r, 90.0-(segment.Start+segment.End)/2.0)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package chart
+
+import (
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestSunburst(t *testing.T) {
+	root := &Tree{Name: "root", Children: []*Tree{
+		{Name: "a", Children: []*Tree{{Name: "a1", Value: 1}, {Name: "a2", Value: 2}}},
+		{Name: "b", Value: 1},
+	}}
+	s := NewSunburst(30.0, root)
+	segments := s.Layout()
+	test.T(t, segments, []SunburstSegment{
+		{root, 0, 0, 0.0, 360.0, 0.0, 10.0},
+		{root.Children[0], 1, 0, 0.0, 270.0, 10.0, 20.0},
+		{root.Children[0].Children[0], 2, 0, 0.0, 90.0, 20.0, 30.0},
+		{root.Children[0].Children[1], 2, 0, 90.0, 270.0, 20.0, 30.0},
+		{root.Children[1], 1, 1, 270.0, 360.0, 10.0, 20.0},
+	})
+	test.T(t, segments[0].Path(), canvas.Circle(10.0))
+	test.T(t, segments[2].Path(), canvas.MustParseSVG("M0 30A30 30 0 0 0 30 0L20 0A20 20 0 0 1 0 20z"))
+
+	r := &recorder{}
+	s.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, len(r.paths), 5)
+	test.T(t, r.styles[4].FillColor, DefaultColors[1])
+
+	family := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+	s.Font = family
+	r = &recorder{}
+	s.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, r.texts, 1+1+2+2+1) // root horizontally, and each glyph along the rings
+
+	s.MinFontSize = 100.0
+	r = &recorder{}
+	s.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, r.texts, 0)
+}
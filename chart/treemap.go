@@ -0,0 +1,229 @@
+package chart
+
+import (
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Tree is a node of a hierarchy for treemaps and sunbursts. The value of a node without children is its Value, and the value of a node with children is the sum of the values of its children.
+type Tree struct {
+	Name     string
+	Value    float64
+	Children []*Tree
+}
+
+// Sum returns the value of the node.
+func (t *Tree) Sum() float64 {
+	if len(t.Children) == 0 {
+		return math.Max(t.Value, 0.0)
+	}
+	sum := 0.0
+	for _, child := range t.Children {
+		sum += child.Sum()
+	}
+	return sum
+}
+
+// depth returns the number of levels below the node.
+func (t *Tree) depth() int {
+	depth := 0
+	for _, child := range t.Children {
+		if d := child.depth() + 1; depth < d {
+			depth = d
+		}
+	}
+	return depth
+}
+
+// hierarchyColor returns the color of a node at depth one or more, which is the color of its ancestor at depth one, the index-th color of colors, lightened for each level below.
+func hierarchyColor(colors []color.RGBA, index, depth int) color.RGBA {
+	if len(colors) == 0 {
+		colors = DefaultColors
+	}
+	col := colors[index%len(colors)]
+	return interpolateColor(col, canvas.White, math.Min(0.6, 0.2*float64(depth-1)))
+}
+
+// TreemapCell is a node of a laid out treemap.
+type TreemapCell struct {
+	Tree  *Tree
+	Depth int // zero for the root
+	Index int // index of the ancestor at depth one, used for its color
+	Rect  canvas.Rect
+}
+
+// Treemap is a squarified treemap of Width by Height millimeters, where each node of a hierarchy is a rectangle whose area is proportional to its value, inside the rectangle of its parent. Siblings are placed in rows that keep their rectangles close to squares, after Bruls, Huizing, and van Wijk.
+type Treemap struct {
+	Width, Height float64
+	Root          *Tree
+	Padding       float64 // space between the rectangles of parents and their children in millimeters
+
+	Colors []color.RGBA // colors of the nodes at depth one, which are lightened for their descendants
+	Style  canvas.Style // style of the rectangles with a fill color that is replaced by Colors
+
+	Font        *canvas.FontFamily // no text is drawn when nil
+	FontSize    float64            // largest size of the labels in points
+	MinFontSize float64            // smallest size of the labels in points, labels that do not fit at this size are not drawn
+	TextColor   color.RGBA
+}
+
+// NewTreemap returns a treemap of width by height millimeters of the hierarchy.
+func NewTreemap(width, height float64, root *Tree) *Treemap {
+	style := fillStyle(canvas.Black)
+	style.StrokeColor = canvas.White
+	style.StrokeWidth = 0.3
+	return &Treemap{
+		Width:       width,
+		Height:      height,
+		Root:        root,
+		Padding:     0.5,
+		Colors:      DefaultColors,
+		Style:       style,
+		FontSize:    10.0,
+		MinFontSize: 4.0,
+		TextColor:   canvas.Black,
+	}
+}
+
+// Layout returns the rectangles of all nodes with a positive value, parents before their children, with the origin at the lower-left corner.
+func (t *Treemap) Layout() []TreemapCell {
+	cells := []TreemapCell{}
+	if t.Root == nil || t.Root.Sum() <= 0.0 {
+		return cells
+	}
+	var layout func(*Tree, int, int, canvas.Rect)
+	layout = func(node *Tree, depth, index int, r canvas.Rect) {
+		cells = append(cells, TreemapCell{node, depth, index, r})
+		if 0 < depth {
+			inset := math.Min(t.Padding, math.Min(r.W, r.H)/4.0)
+			r = canvas.Rect{X: r.X + inset, Y: r.Y + inset, W: r.W - 2.0*inset, H: r.H - 2.0*inset}
+		}
+		children := []*Tree{}
+		indices := []int{}
+		for i, child := range node.Children {
+			if 0.0 < child.Sum() {
+				children = append(children, child)
+				indices = append(indices, i)
+			}
+		}
+		for k, rect := range squarify(children, r) {
+			if depth == 0 {
+				index = indices[k]
+			}
+			layout(children[k], depth+1, index, rect)
+		}
+	}
+	layout(t.Root, 0, 0, canvas.Rect{W: t.Width, H: t.Height})
+	return cells
+}
+
+// squarify divides the rectangle among the nodes proportional to their values, returning the rectangles in the order of the nodes.
+func squarify(nodes []*Tree, r canvas.Rect) []canvas.Rect {
+	rects := make([]canvas.Rect, len(nodes))
+	total := 0.0
+	order := make([]int, len(nodes))
+	areas := make([]float64, len(nodes))
+	for i, node := range nodes {
+		order[i] = i
+		areas[i] = node.Sum()
+		total += areas[i]
+	}
+	if total <= 0.0 {
+		return rects
+	}
+	for i := range areas {
+		areas[i] *= r.W * r.H / total
+	}
+	sort.SliceStable(order, func(a, b int) bool { return areas[order[b]] < areas[order[a]] })
+
+	// worst returns the largest aspect ratio of a row of areas along a side of length w
+	worst := func(row []int, w float64) float64 {
+		sum, min, max := 0.0, math.Inf(1), 0.0
+		for _, i := range row {
+			sum += areas[i]
+			min, max = math.Min(min, areas[i]), math.Max(max, areas[i])
+		}
+		return math.Max(w*w*max/(sum*sum), sum*sum/(w*w*min))
+	}
+	for 0 < len(order) {
+		w := math.Min(r.W, r.H)
+		n := 1
+		for n < len(order) && worst(order[:n+1], w) <= worst(order[:n], w) {
+			n++
+		}
+		row := order[:n]
+		sum := 0.0
+		for _, i := range row {
+			sum += areas[i]
+		}
+
+		// lay out the row along the shorter side, from the top-left
+		if r.H <= r.W {
+			width := 0.0
+			if 0.0 < r.H {
+				width = sum / r.H
+			}
+			y := r.Y + r.H
+			for _, i := range row {
+				h := areas[i] / sum * r.H
+				y -= h
+				rects[i] = canvas.Rect{X: r.X, Y: y, W: width, H: h}
+			}
+			r = canvas.Rect{X: r.X + width, Y: r.Y, W: r.W - width, H: r.H}
+		} else {
+			height := 0.0
+			if 0.0 < r.W {
+				height = sum / r.W
+			}
+			x := r.X
+			for _, i := range row {
+				w := areas[i] / sum * r.W
+				rects[i] = canvas.Rect{X: x, Y: r.Y + r.H - height, W: w, H: height}
+				x += w
+			}
+			r = canvas.Rect{X: r.X, Y: r.Y, W: r.W, H: r.H - height}
+		}
+		order = order[n:]
+	}
+	return rects
+}
+
+// Draw draws the treemap with its lower-left corner at (x,y). Leaves are labeled with their name at the largest font size between MinFontSize and FontSize at which it fits inside their rectangle, see canvas.FitText.
+func (t *Treemap) Draw(ctx *canvas.Context, x, y float64) {
+	cells := t.Layout()
+	ctx.Push()
+	defer ctx.Pop()
+	ctx.Translate(x, y)
+
+	for _, cell := range cells {
+		if cell.Depth == 0 {
+			continue
+		}
+		ctx.Style = t.Style
+		ctx.Style.FillColor = hierarchyColor(t.Colors, cell.Index, cell.Depth)
+		ctx.DrawPath(cell.Rect.X, cell.Rect.Y, canvas.Rectangle(cell.Rect.W, cell.Rect.H))
+	}
+
+	if t.Font == nil {
+		return
+	}
+	face := t.Font.Face(t.FontSize, t.TextColor, canvas.FontRegular, canvas.FontNormal)
+	for _, cell := range cells {
+		if len(cell.Tree.Children) != 0 || cell.Tree.Name == "" {
+			continue
+		}
+		margin := face.Metrics().XHeight / 2.0
+		box := canvas.Rect{X: cell.Rect.X + margin, Y: cell.Rect.Y + margin, W: cell.Rect.W - 2.0*margin, H: cell.Rect.H - 2.0*margin}
+		if box.W <= 0.0 || box.H <= 0.0 {
+			continue
+		}
+		text, _ := canvas.FitText(box, cell.Tree.Name, face, t.MinFontSize, t.FontSize)
+		if bounds := text.Bounds(); text.Empty() || box.W < bounds.W || box.H < text.Height() {
+			continue
+		}
+		ctx.DrawText(box.X, box.Y+box.H, text)
+	}
+}
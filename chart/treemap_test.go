@@ -0,0 +1,65 @@
+package chart
+
+import (
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestTreeSum(t *testing.T) {
+	tree := &Tree{Value: 100, Children: []*Tree{{Value: 1}, {Value: -1}, {Children: []*Tree{{Value: 2}, {Value: 3}}}}}
+	test.Float(t, tree.Sum(), 6.0)
+	test.T(t, tree.depth(), 2)
+}
+
+func TestSquarify(t *testing.T) {
+	nodes := []*Tree{}
+	for _, v := range []float64{2, 6, 4, 6, 3, 2, 1} {
+		nodes = append(nodes, &Tree{Value: v})
+	}
+	rects := squarify(nodes, canvas.Rect{W: 6.0, H: 4.0})
+	test.T(t, rects[1], canvas.Rect{X: 0.0, Y: 2.0, W: 3.0, H: 2.0})
+	test.T(t, rects[3], canvas.Rect{X: 0.0, Y: 0.0, W: 3.0, H: 2.0})
+	for i, rect := range rects {
+		test.Float(t, rect.W*rect.H, nodes[i].Value)
+		test.That(t, -1e-9 <= rect.X && rect.X+rect.W <= 6.0+1e-9 && -1e-9 <= rect.Y && rect.Y+rect.H <= 4.0+1e-9, "inside")
+	}
+}
+
+func TestTreemap(t *testing.T) {
+	root := &Tree{Name: "root", Children: []*Tree{
+		{Name: "a", Children: []*Tree{{Name: "a1", Value: 1}, {Name: "a2", Value: 1}}},
+		{Name: "b", Value: 2},
+		{Name: "c", Value: 0},
+	}}
+	tm := NewTreemap(40.0, 20.0, root)
+	cells := tm.Layout()
+	test.T(t, len(cells), 5)
+	test.T(t, cells[0].Rect, canvas.Rect{W: 40.0, H: 20.0})
+	test.T(t, cells[1].Rect, canvas.Rect{W: 20.0, H: 20.0})
+	test.T(t, cells[2].Rect, canvas.Rect{X: 0.5, Y: 10.0, W: 19.0, H: 9.5})
+	test.T(t, cells[4].Tree.Name, "b")
+	test.T(t, cells[4].Index, 1)
+	test.T(t, cells[3].Index, 0)
+
+	r := &recorder{}
+	tm.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, len(r.paths), 4)
+	test.T(t, r.styles[0].FillColor, DefaultColors[0])
+	test.T(t, r.styles[1].FillColor, hierarchyColor(DefaultColors, 0, 2))
+
+	family := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+	tm.Font = family
+	r = &recorder{}
+	tm.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, r.texts, 3) // leaves
+
+	tm.Root.Children[1].Name = "anunbreakablewordthatismuchtoolongtofit"
+	r = &recorder{}
+	tm.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, r.texts, 2)
+
+	test.T(t, len(NewTreemap(10.0, 10.0, &Tree{}).Layout()), 0)
+}
@@ -0,0 +1,313 @@
+// Command canvas renders an SVG document or a text to PDF, SVG, EPS, PNG, JPEG, or GIF.
+//
+// Usage:
+//
+//	canvas [flags] [input.svg]
+//
+// The SVG document is read from the input file, or from standard input when it is omitted or "-". When -text is given, the text is rendered in the font of -font instead, which is a font file or the name of a font installed on the system. Text elements of SVG documents are drawn in the font of -font, and only when it is given, as text or as outlines with -outline. The output is written to the file of -o, or to standard output, in the format given by -f or by the extension of the output file. With -safe, the resource limits of canvas.SafeLimits are enforced for untrusted input. The colors of PNG and GIF output are reduced by -colors to a palette chosen by median cut, or to gray levels with -gray, using the dithering of -dither. Sizes are in millimeters, for example:
+//
+//	canvas -o logo.png -dpi 300 -width 50 logo.svg
+//	canvas -o label.png -dpi 203 -colors 2 -gray -dither ordered label.svg
+//	canvas -text "Hello world" -font DejaVuSerif.ttf -size 24 -margin 2 -f pdf > hello.pdf
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tdewolff/canvas"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "canvas:", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the command line arguments and converts the input to the output.
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("canvas", flag.ContinueOnError)
+	output := flags.String("o", "-", "output file, or - for standard output")
+	format := flags.String("f", "", "output format: pdf, svg, eps, png, jpg, or gif (default from the output file extension)")
+	dpi := flags.Float64("dpi", 96.0, "resolution of PNG, JPEG, and GIF output in dots per inch")
+	width := flags.Float64("width", 0.0, "width of the output, or of the text box, in millimeters (default natural size)")
+	height := flags.Float64("height", 0.0, "height of the output, or of the text box, in millimeters (default natural size)")
+	margin := flags.Float64("margin", 0.0, "margin around the output in millimeters")
+	background := flags.String("background", "", "background color (default transparent, white for PNG, JPEG, and GIF)")
+	text := flags.String("text", "", "text to render instead of an SVG document")
+	fontName := flags.String("font", "", "font file or system font name of the text, or of the text elements of SVG documents")
+	size := flags.Float64("size", 12.0, "font size of the text in points")
+	textColor := flags.String("color", "black", "color of the text")
+	align := flags.String("align", "left", "alignment of the text: left, center, right, or justify")
+	outline := flags.Bool("outline", false, "draw the text elements of SVG documents as outlines")
+	safe := flags.Bool("safe", false, "enforce resource limits for untrusted input, see canvas.SafeLimits")
+	colors := flags.Int("colors", 0, "number of colors of PNG and GIF output (default all colors for PNG, 256 for GIF)")
+	gray := flags.Bool("gray", false, "use evenly spaced gray levels as the palette of -colors, such as 2 for 1-bit output")
+	dither := flags.String("dither", "floyd-steinberg", "dithering of reduced colors: none, ordered, or floyd-steinberg")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *format == "" {
+		if *output == "-" {
+			return fmt.Errorf("output format must be given by -f when writing to standard output")
+		}
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*output)), ".")
+	}
+	if *format == "jpeg" {
+		*format = "jpg"
+	}
+	if *format != "pdf" && *format != "svg" && *format != "eps" && *format != "png" && *format != "jpg" && *format != "gif" {
+		return fmt.Errorf("unknown output format '%s'", *format)
+	}
+
+	pal := paletteOptions{colors: *colors, gray: *gray}
+	switch *dither {
+	case "none":
+		pal.dithering = canvas.NoDithering
+	case "ordered":
+		pal.dithering = canvas.OrderedDithering
+	case "floyd-steinberg":
+		pal.dithering = canvas.FloydSteinbergDithering
+	default:
+		return fmt.Errorf("unknown dithering '%s'", *dither)
+	}
+	if *colors < 0 || 256 < *colors || (*colors != 0 || *gray) && *colors < 2 {
+		return fmt.Errorf("number of colors must be between 2 and 256")
+	} else if *colors != 0 && *format != "png" && *format != "gif" {
+		return fmt.Errorf("colors can only be reduced for PNG and GIF output")
+	}
+
+	limits := canvas.Limits{}
+	if *safe {
+		limits = canvas.SafeLimits
+	}
+
+	var c *canvas.Canvas
+	var err error
+	if *text != "" {
+		c, err = renderText(*text, *fontName, *size, *textColor, *align, *width, *height)
+	} else {
+		opts := canvas.SVGOptions{Limits: limits, TextToPaths: *outline}
+		if *fontName != "" {
+			family, err := loadFont(*fontName)
+			if err != nil {
+				return err
+			}
+			opts.Fonts = canvas.NewFontRegistry()
+			opts.Fonts.Add("font", family)
+		}
+		c, err = readSVG(flags.Arg(0), stdin, *width, *height, opts)
+	}
+	if err != nil {
+		return err
+	}
+	if *margin != 0.0 || *background != "" {
+		c = frame(c, *margin, *background)
+	}
+
+	w := stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	if err := write(bw, c, *format, *dpi/25.4, limits, pal); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// readSVG reads the SVG document from filename, or from stdin when it is empty or "-", with the options, and scales it to fit the width and height when given.
+func readSVG(filename string, stdin io.Reader, width, height float64, opts canvas.SVGOptions) (*canvas.Canvas, error) {
+	r := stdin
+	if filename != "" && filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	c, err := canvas.ReadSVGWithOptions(bufio.NewReader(r), opts)
+	if err != nil {
+		return nil, err
+	}
+	if width <= 0.0 && height <= 0.0 {
+		return c, nil
+	}
+
+	scale := math.Inf(1)
+	if 0.0 < width {
+		scale = width / c.W
+	}
+	if 0.0 < height {
+		scale = math.Min(scale, height/c.H)
+	}
+	scaled := canvas.New(c.W*scale, c.H*scale)
+	c.Render(transformed{scaled, canvas.Identity.Scale(scale, scale)})
+	return scaled, nil
+}
+
+// renderText renders the text in the font and returns a canvas that fits the text. The text is wrapped to fit the width when it is given.
+func renderText(text, fontName string, size float64, textColor, align string, width, height float64) (*canvas.Canvas, error) {
+	if fontName == "" {
+		return nil, fmt.Errorf("font must be given by -font to render text")
+	}
+	col, err := canvas.ParseCSSColor(textColor)
+	if err != nil {
+		return nil, err
+	}
+	halign := canvas.Left
+	switch align {
+	case "left":
+	case "center":
+		halign = canvas.Center
+	case "right":
+		halign = canvas.Right
+	case "justify":
+		halign = canvas.Justify
+	default:
+		return nil, fmt.Errorf("unknown alignment '%s'", align)
+	}
+
+	family, err := loadFont(fontName)
+	if err != nil {
+		return nil, err
+	}
+	face := family.Face(size, col, canvas.FontRegular, canvas.FontNormal)
+
+	// the text box has its origin at the top-left, and the text extends downwards
+	t := canvas.NewTextBox(face, strings.Replace(text, `\n`, "\n", -1), width, height, halign, canvas.Top, 0.0, 0.0)
+	bounds := t.Bounds()
+	if 0.0 < width {
+		bounds.X, bounds.W = 0.0, width
+	}
+	if 0.0 < height {
+		bounds.Y, bounds.H = -height, height
+	}
+	c := canvas.New(bounds.W, bounds.H)
+	ctx := canvas.NewContext(c)
+	ctx.DrawText(-bounds.X, -bounds.Y, t)
+	return c, nil
+}
+
+// loadFont loads the regular style of a font from a file, or of a font installed on the system.
+func loadFont(fontName string) (*canvas.FontFamily, error) {
+	family := canvas.NewFontFamily("font")
+	if _, err := os.Stat(fontName); err == nil {
+		if err := family.LoadFontFile(fontName, canvas.FontRegular); err != nil {
+			return nil, err
+		}
+	} else if err := family.LoadLocalFont(fontName, canvas.FontRegular); err != nil {
+		return nil, err
+	}
+	return family, nil
+}
+
+// frame returns the canvas with a margin around it, and drawn onto a background color.
+func frame(c *canvas.Canvas, margin float64, background string) *canvas.Canvas {
+	framed := canvas.New(c.W+2.0*margin, c.H+2.0*margin)
+	if background != "" {
+		if col, err := canvas.ParseCSSColor(background); err == nil && col.A != 0 {
+			ctx := canvas.NewContext(framed)
+			ctx.SetFillColor(col)
+			ctx.DrawPath(0.0, 0.0, canvas.Rectangle(framed.W, framed.H))
+		}
+	}
+	c.Render(transformed{framed, canvas.Identity.Translate(margin, margin)})
+	return framed
+}
+
+// paletteOptions are the options to reduce the colors of raster output.
+type paletteOptions struct {
+	colors    int // zero keeps all colors
+	gray      bool
+	dithering canvas.Dithering
+}
+
+// quantize reduces the colors of the image to the palette of the options.
+func (pal paletteOptions) quantize(img image.Image) *image.Paletted {
+	palette := canvas.GrayPalette(pal.colors)
+	if !pal.gray {
+		palette = canvas.MedianCut{}.Quantize(make(color.Palette, 0, pal.colors), img)
+	}
+	return canvas.Quantize(img, palette, pal.dithering)
+}
+
+// write writes the canvas to w in the format, where dpm is the resolution of raster formats in dots per millimeter that are rasterized within the limits, and of which the colors are reduced by the palette options.
+func write(w io.Writer, c *canvas.Canvas, format string, dpm float64, limits canvas.Limits, pal paletteOptions) error {
+	switch format {
+	case "pdf":
+		pdf := canvas.NewPDF(w, c.W, c.H)
+		c.Render(pdf)
+		return pdf.Close()
+	case "svg":
+		svg := canvas.NewSVG(w, c.W, c.H)
+		c.Render(svg)
+		return svg.Close()
+	case "eps":
+		c.Render(canvas.NewEPS(w, c.W, c.H))
+		return nil
+	case "png", "jpg", "gif":
+		img, err := c.WriteImageWithLimits(dpm, limits)
+		if err != nil {
+			return err
+		} else if format == "gif" {
+			if pal.colors == 0 {
+				pal.colors = 256
+			}
+			return gif.Encode(w, pal.quantize(img), nil)
+		} else if format == "png" {
+			opts := canvas.PNGOptions{DPM: dpm}
+			if pal.colors != 0 {
+				return canvas.EncodePNG(w, pal.quantize(img), opts)
+			}
+			return canvas.EncodePNG(w, img, opts)
+		}
+		return jpeg.Encode(w, img, nil)
+	}
+	return fmt.Errorf("unknown output format '%s'", format)
+}
+
+// transformed renders onto a canvas transformed by view, where the widths of strokes are scaled along since the canvas strokes paths after transforming them.
+type transformed struct {
+	*canvas.Canvas
+	view canvas.Matrix
+}
+
+func (r transformed) View() canvas.Matrix {
+	return r.view
+}
+
+func (r transformed) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	if scale := math.Sqrt(math.Abs(r.view.Det())); scale != 1.0 {
+		style.StrokeWidth *= scale
+		style.DashOffset *= scale
+		dashes := make([]float64, len(style.Dashes))
+		for i, dash := range style.Dashes {
+			dashes[i] = dash * scale
+		}
+		style.Dashes = dashes
+		if miter, ok := style.StrokeJoiner.(canvas.MiterJoiner); ok {
+			miter.Limit *= scale // relative to the stroke width
+			style.StrokeJoiner = miter
+		}
+	}
+	r.Canvas.RenderPath(path, style, m)
+}
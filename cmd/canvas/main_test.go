@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg" width="40" height="20"><rect width="20" height="20" fill="red"/></svg>`
+
+func TestRunSVG(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	test.Error(t, run([]string{"-f", "svg", "-width", "20"}, strings.NewReader(testSVG), stdout))
+	test.That(t, strings.HasPrefix(stdout.String(), `<svg version="1.1" width="20mm" height="10mm"`), stdout.String())
+	test.That(t, strings.Contains(stdout.String(), `<path d="M0 0H10V10H0z" fill="#f00"/>`), stdout.String())
+
+	dir, err := ioutil.TempDir("", "canvas")
+	test.Error(t, err)
+	defer os.RemoveAll(dir)
+	input, output := filepath.Join(dir, "in.svg"), filepath.Join(dir, "out.png")
+	test.Error(t, ioutil.WriteFile(input, []byte(testSVG), 0644))
+	test.Error(t, run([]string{"-o", output, "-dpi", "25.4", "-margin", "1", input}, nil, nil))
+	f, err := os.Open(output)
+	test.Error(t, err)
+	defer f.Close()
+	img, err := png.Decode(f)
+	test.Error(t, err)
+	test.T(t, img.Bounds().Dx(), 13) // 40px is 10.58mm, with margins 12.58mm
+	r, _, _, _ := img.At(4, 2).RGBA()
+	test.T(t, r, uint32(0xffff))
+}
+
+func TestRunText(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	test.Error(t, run([]string{"-f", "pdf", "-text", "Hello", "-font", "../../font/DejaVuSerif.ttf"}, nil, stdout))
+	test.That(t, strings.HasPrefix(stdout.String(), "%PDF"))
+}
+
+func TestRunSVGText(t *testing.T) {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" width="40" height="20"><text y="15">Hello</text></svg>`
+	stdout := &bytes.Buffer{}
+	test.Error(t, run([]string{"-f", "svg", "-font", "../../font/DejaVuSerif.ttf"}, strings.NewReader(svg), stdout))
+	test.That(t, strings.Contains(stdout.String(), `>Hello</tspan>`), stdout.String())
+
+	stdout.Reset()
+	test.Error(t, run([]string{"-f", "svg", "-font", "../../font/DejaVuSerif.ttf", "-outline"}, strings.NewReader(svg), stdout))
+	test.That(t, !strings.Contains(stdout.String(), `<text`) && strings.Contains(stdout.String(), `<path`), stdout.String())
+}
+
+func TestRunPalette(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	test.Error(t, run([]string{"-f", "png", "-colors", "2", "-gray", "-dither", "ordered"}, strings.NewReader(testSVG), stdout))
+	img, err := png.Decode(stdout)
+	test.Error(t, err)
+	paletted, ok := img.(*image.Paletted)
+	test.That(t, ok, "paletted PNG")
+	test.T(t, len(paletted.Palette), 2)
+
+	stdout.Reset()
+	test.Error(t, run([]string{"-f", "gif", "-colors", "4"}, strings.NewReader(testSVG), stdout))
+	img, err = gif.Decode(stdout)
+	test.Error(t, err)
+	test.That(t, len(img.(*image.Paletted).Palette) <= 4, "GIF with four colors")
+
+	test.That(t, run([]string{"-f", "jpg", "-colors", "2"}, strings.NewReader(testSVG), nil) != nil, "JPEG has all colors")
+	test.That(t, run([]string{"-f", "png", "-colors", "1"}, strings.NewReader(testSVG), nil) != nil, "needs at least two colors")
+	test.That(t, run([]string{"-f", "png", "-dither", "random"}, strings.NewReader(testSVG), nil) != nil, "dithering is unknown")
+}
+
+func TestRunErrors(t *testing.T) {
+	test.That(t, run([]string{"in.svg"}, nil, nil) != nil, "format is missing")
+	test.That(t, run([]string{"-f", "webp"}, strings.NewReader(testSVG), nil) != nil, "format is unknown")
+	test.That(t, run([]string{"-f", "pdf", "-text", "Hello"}, nil, nil) != nil, "font is missing")
+	test.That(t, run([]string{"-f", "pdf"}, strings.NewReader("<html/>"), nil) != nil, "not an SVG")
+	test.That(t, run([]string{"-f", "png", "-dpi", "100000", "-safe"}, strings.NewReader(testSVG), &bytes.Buffer{}) != nil, "too many pixels")
+}
@@ -155,3 +155,154 @@ var (
 	Yellow               = color.RGBA{0xff, 0xff, 0x00, 0xff} // rgb(255, 255, 0)
 	Yellowgreen          = color.RGBA{0x9a, 0xcd, 0x32, 0xff} // rgb(154, 205, 50)
 )
+
+// cssColors are the named colors of CSS.
+var cssColors = map[string]color.RGBA{
+	"aliceblue":            Aliceblue,
+	"antiquewhite":         Antiquewhite,
+	"aqua":                 Aqua,
+	"aquamarine":           Aquamarine,
+	"azure":                Azure,
+	"beige":                Beige,
+	"bisque":               Bisque,
+	"black":                Black,
+	"blanchedalmond":       Blanchedalmond,
+	"blue":                 Blue,
+	"blueviolet":           Blueviolet,
+	"brown":                Brown,
+	"burlywood":            Burlywood,
+	"cadetblue":            Cadetblue,
+	"chartreuse":           Chartreuse,
+	"chocolate":            Chocolate,
+	"coral":                Coral,
+	"cornflowerblue":       Cornflowerblue,
+	"cornsilk":             Cornsilk,
+	"crimson":              Crimson,
+	"cyan":                 Cyan,
+	"darkblue":             Darkblue,
+	"darkcyan":             Darkcyan,
+	"darkgoldenrod":        Darkgoldenrod,
+	"darkgray":             Darkgray,
+	"darkgreen":            Darkgreen,
+	"darkgrey":             Darkgrey,
+	"darkkhaki":            Darkkhaki,
+	"darkmagenta":          Darkmagenta,
+	"darkolivegreen":       Darkolivegreen,
+	"darkorange":           Darkorange,
+	"darkorchid":           Darkorchid,
+	"darkred":              Darkred,
+	"darksalmon":           Darksalmon,
+	"darkseagreen":         Darkseagreen,
+	"darkslateblue":        Darkslateblue,
+	"darkslategray":        Darkslategray,
+	"darkslategrey":        Darkslategrey,
+	"darkturquoise":        Darkturquoise,
+	"darkviolet":           Darkviolet,
+	"deeppink":             Deeppink,
+	"deepskyblue":          Deepskyblue,
+	"dimgray":              Dimgray,
+	"dimgrey":              Dimgrey,
+	"dodgerblue":           Dodgerblue,
+	"firebrick":            Firebrick,
+	"floralwhite":          Floralwhite,
+	"forestgreen":          Forestgreen,
+	"fuchsia":              Fuchsia,
+	"gainsboro":            Gainsboro,
+	"ghostwhite":           Ghostwhite,
+	"gold":                 Gold,
+	"goldenrod":            Goldenrod,
+	"gray":                 Gray,
+	"green":                Green,
+	"greenyellow":          Greenyellow,
+	"grey":                 Grey,
+	"honeydew":             Honeydew,
+	"hotpink":              Hotpink,
+	"indianred":            Indianred,
+	"indigo":               Indigo,
+	"ivory":                Ivory,
+	"khaki":                Khaki,
+	"lavender":             Lavender,
+	"lavenderblush":        Lavenderblush,
+	"lawngreen":            Lawngreen,
+	"lemonchiffon":         Lemonchiffon,
+	"lightblue":            Lightblue,
+	"lightcoral":           Lightcoral,
+	"lightcyan":            Lightcyan,
+	"lightgoldenrodyellow": Lightgoldenrodyellow,
+	"lightgray":            Lightgray,
+	"lightgreen":           Lightgreen,
+	"lightgrey":            Lightgrey,
+	"lightpink":            Lightpink,
+	"lightsalmon":          Lightsalmon,
+	"lightseagreen":        Lightseagreen,
+	"lightskyblue":         Lightskyblue,
+	"lightslategray":       Lightslategray,
+	"lightslategrey":       Lightslategrey,
+	"lightsteelblue":       Lightsteelblue,
+	"lightyellow":          Lightyellow,
+	"lime":                 Lime,
+	"limegreen":            Limegreen,
+	"linen":                Linen,
+	"magenta":              Magenta,
+	"maroon":               Maroon,
+	"mediumaquamarine":     Mediumaquamarine,
+	"mediumblue":           Mediumblue,
+	"mediumorchid":         Mediumorchid,
+	"mediumpurple":         Mediumpurple,
+	"mediumseagreen":       Mediumseagreen,
+	"mediumslateblue":      Mediumslateblue,
+	"mediumspringgreen":    Mediumspringgreen,
+	"mediumturquoise":      Mediumturquoise,
+	"mediumvioletred":      Mediumvioletred,
+	"midnightblue":         Midnightblue,
+	"mintcream":            Mintcream,
+	"mistyrose":            Mistyrose,
+	"moccasin":             Moccasin,
+	"navajowhite":          Navajowhite,
+	"navy":                 Navy,
+	"oldlace":              Oldlace,
+	"olive":                Olive,
+	"olivedrab":            Olivedrab,
+	"orange":               Orange,
+	"orangered":            Orangered,
+	"orchid":               Orchid,
+	"palegoldenrod":        Palegoldenrod,
+	"palegreen":            Palegreen,
+	"paleturquoise":        Paleturquoise,
+	"palevioletred":        Palevioletred,
+	"papayawhip":           Papayawhip,
+	"peachpuff":            Peachpuff,
+	"peru":                 Peru,
+	"pink":                 Pink,
+	"plum":                 Plum,
+	"powderblue":           Powderblue,
+	"purple":               Purple,
+	"red":                  Red,
+	"rosybrown":            Rosybrown,
+	"royalblue":            Royalblue,
+	"saddlebrown":          Saddlebrown,
+	"salmon":               Salmon,
+	"sandybrown":           Sandybrown,
+	"seagreen":             Seagreen,
+	"seashell":             Seashell,
+	"sienna":               Sienna,
+	"silver":               Silver,
+	"skyblue":              Skyblue,
+	"slateblue":            Slateblue,
+	"slategray":            Slategray,
+	"slategrey":            Slategrey,
+	"snow":                 Snow,
+	"springgreen":          Springgreen,
+	"steelblue":            Steelblue,
+	"tan":                  Tan,
+	"teal":                 Teal,
+	"thistle":              Thistle,
+	"tomato":               Tomato,
+	"turquoise":            Turquoise,
+	"violet":               Violet,
+	"wheat":                Wheat,
+	"white":                White,
+	"whitesmoke":           Whitesmoke,
+	"yellow":               Yellow,
+	"yellowgreen":          Yellowgreen,
+}
@@ -0,0 +1,155 @@
+package canvas
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrConflictingConstraints is returned when the constraints of a layout cannot be satisfied at the same time.
+var ErrConflictingConstraints = errors.New("conflicting constraints")
+
+// LayoutElement is an element that is positioned by the constraints of a layout, given by the bounds of its content, such as a group, legend, or annotation. Elements are only translated.
+type LayoutElement struct {
+	bounds Rect
+	offset Point
+}
+
+// Bounds returns the bounds of the element at its position.
+func (e *LayoutElement) Bounds() Rect {
+	return e.bounds.Move(e.offset)
+}
+
+// Offset returns the translation of the element from the bounds it was added with, which is the position at which to draw its content, eg. by ctx.DrawPath(offset.X, offset.Y, p).
+func (e *LayoutElement) Offset() Point {
+	return e.offset
+}
+
+// Matrix returns the translation of the element as a transformation, eg. for ctx.ComposeView.
+func (e *LayoutElement) Matrix() Matrix {
+	return Identity.Translate(e.offset.X, e.offset.Y)
+}
+
+// moveTo moves the element along one axis so that its position x (horizontally) or y (vertically) at the fraction t of its width or height equals pos. It returns true if the element moved.
+func (e *LayoutElement) moveTo(vertical bool, t, pos float64) bool {
+	r := e.Bounds()
+	var d float64
+	if vertical {
+		d = pos - (r.Y + t*r.H)
+		e.offset.Y += d
+	} else {
+		d = pos - (r.X + t*r.W)
+		e.offset.X += d
+	}
+	return Epsilon < math.Abs(d)
+}
+
+// edge returns the position of the element along one axis at the fraction t of its width or height.
+func (e *LayoutElement) edge(vertical bool, t float64) float64 {
+	r := e.Bounds()
+	if vertical {
+		return r.Y + t*r.H
+	}
+	return r.X + t*r.W
+}
+
+// alignFraction returns the fraction of the width or height of an alignment, where Left and Bottom are at zero and Right and Top are at one.
+func alignFraction(align TextAlign) float64 {
+	switch align {
+	case Right, Top:
+		return 1.0
+	case Center:
+		return 0.5
+	}
+	return 0.0
+}
+
+// constraint moves elements to satisfy it relative to its references, and returns true if any element moved.
+type constraint func() bool
+
+// ConstraintLayout positions elements relative to each other by constraints that align their edges or centers, center them in other elements, attach them to the sides of other elements, or distribute them with spacing, instead of computing their coordinates by hand. Each constraint moves its elements relative to its reference elements, which are not moved by that constraint but may be moved by others. Elements are positioned when calling Solve.
+type ConstraintLayout struct {
+	constraints []constraint
+}
+
+// NewConstraintLayout returns a new layout without constraints.
+func NewConstraintLayout() *ConstraintLayout {
+	return &ConstraintLayout{}
+}
+
+// Add adds an element with the bounds of its content, eg. the bounds of a path or text, or Rect{0, 0, W, H} of a canvas.
+func (l *ConstraintLayout) Add(bounds Rect) *LayoutElement {
+	return &LayoutElement{bounds: bounds}
+}
+
+// AlignHorizontal aligns the left edges (Left), centers (Center), or right edges (Right) of the elements with those of ref.
+func (l *ConstraintLayout) AlignHorizontal(align TextAlign, ref *LayoutElement, elements ...*LayoutElement) {
+	l.align(false, alignFraction(align), ref, elements)
+}
+
+// AlignVertical aligns the top edges (Top), centers (Center), or bottom edges (Bottom) of the elements with those of ref.
+func (l *ConstraintLayout) AlignVertical(align TextAlign, ref *LayoutElement, elements ...*LayoutElement) {
+	l.align(true, alignFraction(align), ref, elements)
+}
+
+func (l *ConstraintLayout) align(vertical bool, t float64, ref *LayoutElement, elements []*LayoutElement) {
+	l.constraints = append(l.constraints, func() bool {
+		moved := false
+		for _, e := range elements {
+			if e != ref && e.moveTo(vertical, t, ref.edge(vertical, t)) {
+				moved = true
+			}
+		}
+		return moved
+	})
+}
+
+// CenterIn centers the elements both horizontally and vertically in container.
+func (l *ConstraintLayout) CenterIn(container *LayoutElement, elements ...*LayoutElement) {
+	l.align(false, 0.5, container, elements)
+	l.align(true, 0.5, container, elements)
+}
+
+// Attach places an element outside of a side of ref (Left, Right, Top, or Bottom) with a gap in between, such as a legend to the right of a chart. It only constrains the axis of that side, so that the element can be aligned along the other axis by AlignHorizontal or AlignVertical.
+func (l *ConstraintLayout) Attach(e *LayoutElement, side TextAlign, ref *LayoutElement, gap float64) {
+	l.constraints = append(l.constraints, func() bool {
+		switch side {
+		case Left:
+			return e.moveTo(false, 1.0, ref.edge(false, 0.0)-gap)
+		case Right:
+			return e.moveTo(false, 0.0, ref.edge(false, 1.0)+gap)
+		case Top:
+			return e.moveTo(true, 0.0, ref.edge(true, 1.0)+gap)
+		}
+		return e.moveTo(true, 1.0, ref.edge(true, 0.0)-gap)
+	})
+}
+
+// DistributeHorizontal places the elements from left to right with spacing in between, starting at the position of the first element.
+func (l *ConstraintLayout) DistributeHorizontal(spacing float64, elements ...*LayoutElement) {
+	for i := 1; i < len(elements); i++ {
+		l.Attach(elements[i], Right, elements[i-1], spacing)
+	}
+}
+
+// DistributeVertical places the elements from top to bottom with spacing in between, starting at the position of the first element.
+func (l *ConstraintLayout) DistributeVertical(spacing float64, elements ...*LayoutElement) {
+	for i := 1; i < len(elements); i++ {
+		l.Attach(elements[i], Bottom, elements[i-1], spacing)
+	}
+}
+
+// Solve positions the elements by applying the constraints in the order they were added, repeatedly until all are satisfied, so that the order of the constraints does not matter when an element depends on elements that are positioned by later constraints. It returns ErrConflictingConstraints if the constraints move elements in a cycle, in which case the elements are left as positioned by the last pass.
+func (l *ConstraintLayout) Solve() error {
+	for pass := 0; pass <= len(l.constraints); pass++ {
+		moved := false
+		for _, c := range l.constraints {
+			if c() {
+				moved = true
+			}
+		}
+		if !moved {
+			return nil
+		}
+	}
+	return ErrConflictingConstraints
+}
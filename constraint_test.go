@@ -0,0 +1,73 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestConstraintLayout(t *testing.T) {
+	l := NewConstraintLayout()
+	chart := l.Add(Rect{10.0, 10.0, 100.0, 50.0})
+	legend := l.Add(Rect{0.0, 0.0, 20.0, 10.0})
+	title := l.Add(Rect{0.0, 0.0, 40.0, 8.0})
+	l.Attach(legend, Right, chart, 5.0)
+	l.AlignVertical(Top, chart, legend)
+	l.Attach(title, Top, chart, 2.0)
+	l.AlignHorizontal(Center, chart, title)
+	test.Error(t, l.Solve())
+	test.T(t, chart.Bounds(), Rect{10.0, 10.0, 100.0, 50.0})
+	test.T(t, legend.Bounds(), Rect{115.0, 50.0, 20.0, 10.0})
+	test.T(t, title.Bounds(), Rect{40.0, 62.0, 40.0, 8.0})
+	test.T(t, title.Offset(), Point{40.0, 62.0})
+	test.T(t, title.Matrix(), Identity.Translate(40.0, 62.0))
+
+	l = NewConstraintLayout()
+	page := l.Add(Rect{0.0, 0.0, 200.0, 100.0})
+	box := l.Add(Rect{5.0, 5.0, 20.0, 10.0})
+	l.CenterIn(page, box)
+	test.Error(t, l.Solve())
+	test.T(t, box.Bounds(), Rect{90.0, 45.0, 20.0, 10.0})
+	test.T(t, box.Offset(), Point{85.0, 40.0})
+}
+
+func TestConstraintLayoutDistribute(t *testing.T) {
+	l := NewConstraintLayout()
+	a := l.Add(Rect{10.0, 0.0, 10.0, 10.0})
+	b := l.Add(Rect{0.0, 0.0, 20.0, 5.0})
+	c := l.Add(Rect{0.0, 0.0, 5.0, 20.0})
+	l.DistributeHorizontal(2.0, a, b, c)
+	l.AlignVertical(Bottom, a, b, c)
+	test.Error(t, l.Solve())
+	test.T(t, b.Bounds(), Rect{22.0, 0.0, 20.0, 5.0})
+	test.T(t, c.Bounds(), Rect{44.0, 0.0, 5.0, 20.0})
+
+	l = NewConstraintLayout()
+	a = l.Add(Rect{0.0, 50.0, 10.0, 10.0})
+	b = l.Add(Rect{0.0, 0.0, 20.0, 5.0})
+	c = l.Add(Rect{0.0, 0.0, 5.0, 20.0})
+	l.AlignHorizontal(Right, a, b, c)
+	l.DistributeVertical(1.0, a, b, c)
+	test.Error(t, l.Solve())
+	test.T(t, b.Bounds(), Rect{-10.0, 44.0, 20.0, 5.0})
+	test.T(t, c.Bounds(), Rect{5.0, 23.0, 5.0, 20.0})
+}
+
+func TestConstraintLayoutOrder(t *testing.T) {
+	// constraints that depend on elements positioned by later constraints
+	l := NewConstraintLayout()
+	a := l.Add(Rect{0.0, 0.0, 10.0, 10.0})
+	b := l.Add(Rect{0.0, 0.0, 10.0, 10.0})
+	c := l.Add(Rect{0.0, 0.0, 10.0, 10.0})
+	l.Attach(c, Right, b, 1.0)
+	l.Attach(b, Right, a, 1.0)
+	test.Error(t, l.Solve())
+	test.T(t, c.Bounds(), Rect{22.0, 0.0, 10.0, 10.0})
+
+	l = NewConstraintLayout()
+	a = l.Add(Rect{0.0, 0.0, 10.0, 10.0})
+	b = l.Add(Rect{0.0, 0.0, 10.0, 10.0})
+	l.Attach(b, Right, a, 1.0)
+	l.Attach(a, Right, b, 1.0)
+	test.T(t, l.Solve(), ErrConflictingConstraints)
+}
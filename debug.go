@@ -0,0 +1,204 @@
+package canvas
+
+import (
+	"image/color"
+	"math"
+)
+
+// DebugOptions are the overlays that Canvas.Debug draws on top of the drawing. Each overlay has its own color.
+type DebugOptions struct {
+	BoundingBoxes bool    // of paths including their stroke, texts, and images, in red
+	ControlPoints bool    // end points of the segments of paths as squares and their control points as circles with handles, in magenta
+	TextBoxes     bool    // of the lines of texts from the top of the first to the bottom of the last line, in green
+	Baselines     bool    // of the lines of texts, in blue
+	GlyphBoxes    bool    // advances of glyphs from their descent to their ascent, in dark orange
+	Rulers        bool    // ticks every 1, 5, and 10mm along the bottom and left edges of the canvas, in gray
+	Guides        float64 // spacing in mm of a grid of guides over the canvas in light gray, zero for none
+	LineWidth     float64 // in mm
+}
+
+// DefaultDebugOptions draws all overlays except for the guides.
+var DefaultDebugOptions = DebugOptions{
+	BoundingBoxes: true,
+	ControlPoints: true,
+	TextBoxes:     true,
+	Baselines:     true,
+	GlyphBoxes:    true,
+	Rulers:        true,
+	LineWidth:     0.1,
+}
+
+// Debug returns a new canvas that draws the canvas with overlays of the geometry of its layers, which is useful to diagnose layout problems such as misplaced text or paths with unexpected control points. The overlays are drawn on top of all layers, in canvas coordinates, so that their line width and the size of their markers do not depend on the transformations of the layers.
+func (c *Canvas) Debug(options DebugOptions) *Canvas {
+	c.merge()
+	debug := New(c.W, c.H)
+	debug.layers = append(debug.layers, c.layers...)
+
+	lineWidth := options.LineWidth
+	if lineWidth <= 0.0 {
+		lineWidth = DefaultDebugOptions.LineWidth
+	}
+	stroke := func(p *Path, col color.RGBA) {
+		if p.Empty() {
+			return
+		}
+		style := DefaultStyle
+		style.FillColor = Transparent
+		style.StrokeColor = col
+		style.StrokeWidth = lineWidth
+		debug.RenderPath(p, style, Identity)
+	}
+
+	if 0.0 < options.Guides {
+		p := &Path{}
+		for x := options.Guides; x < c.W; x += options.Guides {
+			p.MoveTo(x, 0.0)
+			p.LineTo(x, c.H)
+		}
+		for y := options.Guides; y < c.H; y += options.Guides {
+			p.MoveTo(0.0, y)
+			p.LineTo(c.W, y)
+		}
+		stroke(p, Lightgray)
+	}
+	if options.Rulers {
+		p := &Path{}
+		tick := func(i int) float64 {
+			if i%10 == 0 {
+				return 3.0
+			} else if i%5 == 0 {
+				return 2.0
+			}
+			return 1.0
+		}
+		for i := 0; float64(i) <= c.W; i++ {
+			p.MoveTo(float64(i), 0.0)
+			p.LineTo(float64(i), tick(i))
+		}
+		for i := 0; float64(i) <= c.H; i++ {
+			p.MoveTo(0.0, float64(i))
+			p.LineTo(tick(i), float64(i))
+		}
+		stroke(p, Gray)
+	}
+
+	size := 4.0 * lineWidth // of the markers of control points
+	for _, l := range c.layers {
+		if options.BoundingBoxes {
+			if l.img != nil {
+				size := l.img.Bounds().Size()
+				stroke(Rect{0.0, 0.0, float64(size.X), float64(size.Y)}.ToPath().Transform(l.m), Red)
+			} else {
+				stroke(l.Bounds().ToPath(), Red)
+			}
+		}
+		if l.path != nil && options.ControlPoints {
+			stroke(debugControlPoints(l.path.Transform(l.m), size), Magenta)
+		} else if l.text != nil {
+			m := l.m.Mul(l.text.lineView())
+			if options.GlyphBoxes {
+				stroke(l.text.debugGlyphBoxes().Transform(m), Darkorange)
+			}
+			if options.Baselines {
+				stroke(l.text.debugBaselines().Transform(m), Blue)
+			}
+			if options.TextBoxes {
+				stroke(l.text.debugTextBox().ToPath().Transform(m), Green)
+			}
+		}
+	}
+	return debug
+}
+
+// debugControlPoints returns squares at the end points of the segments of a path, and circles at their control points connected to the end points by handles.
+func debugControlPoints(p *Path, size float64) *Path {
+	markers := &Path{}
+	end := func(p Point) {
+		markers = markers.Append(Rectangle(size, size).Translate(p.X-size/2.0, p.Y-size/2.0))
+	}
+	control := func(cp, p Point) {
+		markers.MoveTo(p.X, p.Y)
+		markers.LineTo(cp.X, cp.Y)
+		markers = markers.Append(Circle(size/2.0).Translate(cp.X, cp.Y))
+	}
+	p.Iterate(
+		func(_, p Point) { end(p) },
+		func(_, p Point) { end(p) },
+		func(p0, cp, p1 Point) {
+			control(cp, p0)
+			control(cp, p1)
+			end(p1)
+		},
+		func(p0, cp1, cp2, p1 Point) {
+			control(cp1, p0)
+			control(cp2, p1)
+			end(p1)
+		},
+		func(_ Point, _, _, _ float64, _, _ bool, p Point) { end(p) },
+		func(_, _ Point) {},
+	)
+	return markers
+}
+
+// debugTextBox returns the box of the lines of the text in line coordinates, from the top of the first line to the bottom of the last line including their line spacing.
+func (t *Text) debugTextBox() Rect {
+	box, first := Rect{}, true
+	for _, line := range t.lines {
+		x0, x1 := line.extent()
+		if x1 < x0 {
+			continue // no spans
+		}
+		top, _, _, bottom := line.Heights()
+		r := Rect{x0, line.y - bottom, x1 - x0, top + bottom}
+		if first {
+			box, first = r, false
+		} else {
+			box = box.Add(r)
+		}
+	}
+	return box
+}
+
+// extent returns the left and right of the spans of the line.
+func (l line) extent() (float64, float64) {
+	x0, x1 := math.Inf(1), math.Inf(-1)
+	for _, span := range l.spans {
+		x0 = math.Min(x0, span.dx)
+		x1 = math.Max(x1, span.dx+span.width)
+	}
+	return x0, x1
+}
+
+// debugBaselines returns the base lines of the lines of the text in line coordinates.
+func (t *Text) debugBaselines() *Path {
+	p := &Path{}
+	for _, line := range t.lines {
+		if x0, x1 := line.extent(); x0 < x1 {
+			p.MoveTo(x0, line.y)
+			p.LineTo(x1, line.y)
+		}
+	}
+	return p
+}
+
+// debugGlyphBoxes returns the advance boxes of the glyphs of the text from the descent to the ascent of their font face, in line coordinates.
+func (t *Text) debugGlyphBoxes() *Path {
+	p := &Path{}
+	for _, line := range t.lines {
+		for _, span := range line.spans {
+			metrics := span.ff.Metrics()
+			stretch := 1.0 + span.glyphStretch
+			x := span.dx
+			for _, cluster := range span.glyphClusters() {
+				for _, g := range cluster.glyphs {
+					if g.XAdvance != 0.0 {
+						p = p.Append(Rect{x, line.y - metrics.Descent, g.XAdvance * stretch, metrics.Ascent + metrics.Descent}.ToPath())
+					}
+					x += g.XAdvance * stretch
+				}
+				x += span.glyphSpacing + cluster.spacing
+			}
+		}
+	}
+	return p
+}
@@ -0,0 +1,51 @@
+package canvas
+
+import (
+	"image"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestCanvasDebug(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	c := New(20.0, 10.0)
+	ctx := NewContext(c)
+	p := &Path{}
+	p.MoveTo(0.0, 0.0)
+	p.QuadTo(5.0, 5.0, 10.0, 0.0)
+	ctx.DrawPath(2.0, 3.0, p)
+	ctx.DrawText(1.0, 8.0, NewTextLine(face, "AB", Left))
+	ctx.DrawImage(15.0, 0.0, image.NewRGBA(image.Rect(0, 0, 2, 1)), 1.0)
+
+	debug := c.Debug(DefaultDebugOptions)
+	test.T(t, len(c.layers), 3)
+	test.T(t, len(debug.layers), 11) // drawing, rulers, box and control points of the path, box, glyphs, base line, and text box of the text, and box of the image
+	colors := []interface{}{}
+	for _, l := range debug.layers[3:] {
+		test.T(t, l.style.FillColor, Transparent)
+		test.Float(t, l.style.StrokeWidth, 0.1)
+		colors = append(colors, l.style.StrokeColor)
+	}
+	test.T(t, colors, []interface{}{Gray, Red, Magenta, Red, Darkorange, Blue, Green, Red})
+
+	test.T(t, debug.layers[4].path.Bounds(), Rect{2.0, 3.0, 10.0, 2.5})
+	test.T(t, debug.layers[5].path.Bounds(), Rect{1.8, 2.8, 10.4, 5.4}) // squares at the end points and a circle at the control point
+	metrics := face.Metrics()
+	width := face.TextWidth("AB")
+	test.T(t, debug.layers[7].path.Bounds(), Rect{1.0, 8.0 - metrics.Descent, width, metrics.Ascent + metrics.Descent})
+	test.T(t, len(debug.layers[7].path.Split()), 2)
+	test.T(t, debug.layers[8].path.Bounds(), Rect{1.0, 8.0, width, 0.0})
+	spacing := metrics.LineHeight - metrics.Ascent - metrics.Descent
+	test.T(t, debug.layers[9].path.Bounds(), Rect{1.0, 8.0 - metrics.Descent - spacing, width, metrics.LineHeight + spacing})
+	test.T(t, debug.layers[10].path.Bounds(), Rect{15.0, 0.0, 2.0, 1.0})
+
+	// guides only
+	debug = c.Debug(DebugOptions{Guides: 5.0})
+	test.T(t, len(debug.layers), 4)
+	test.T(t, len(debug.layers[3].path.Split()), 4) // at x=5, 10, 15 and y=5
+	test.Float(t, debug.layers[3].style.StrokeWidth, 0.1)
+}
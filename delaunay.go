@@ -0,0 +1,206 @@
+package canvas
+
+import (
+	"math"
+	"sort"
+)
+
+// Triangulation is a Delaunay triangulation of a set of points, such that no point lies inside the circumcircle of any triangle. Triangles refer to the indices of Points and are in counter clockwise order.
+type Triangulation struct {
+	Points    []Point
+	Triangles [][3]int
+}
+
+// Delaunay returns the Delaunay triangulation of the points using the Bowyer-Watson algorithm. Duplicate points are not part of any triangle, and if all points are collinear there are no triangles.
+func Delaunay(points []Point) *Triangulation {
+	tri := &Triangulation{Points: points}
+	if len(points) < 3 {
+		return tri
+	}
+
+	// process points from left to right so that triangles whose circumcircle lies to the left of the current point are final
+	order := make([]int, len(points))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := points[order[i]], points[order[j]]
+		return a.X < b.X || a.X == b.X && a.Y < b.Y
+	})
+
+	// super triangle that contains all points, its vertices are at indices n, n+1, n+2
+	bounds := polygonBounds(points)
+	size := math.Max(math.Max(bounds.W, bounds.H), 1.0)
+	center := Point{bounds.X + bounds.W/2.0, bounds.Y + bounds.H/2.0}
+	n := len(points)
+	pos := append(append([]Point{}, points...),
+		Point{center.X - 1000.0*size, center.Y - 500.0*size},
+		Point{center.X + 1000.0*size, center.Y - 500.0*size},
+		Point{center.X, center.Y + 1000.0*size},
+	)
+
+	type triangle struct {
+		v      [3]int
+		center Point
+		r2     float64 // squared radius of the circumcircle
+	}
+	newTriangle := func(a, b, c int) triangle {
+		center, r2 := circumcircle(pos[a], pos[b], pos[c])
+		return triangle{[3]int{a, b, c}, center, r2}
+	}
+
+	open := []triangle{newTriangle(n, n+1, n+2)}
+	closed := []triangle{}
+	for k, i := range order {
+		p := pos[i]
+		if 0 < k && p == pos[order[k-1]] {
+			continue // duplicate
+		}
+
+		// remove triangles whose circumcircle contains p and collect the boundary of the hole
+		type edge struct{ a, b int }
+		edges := map[edge]int{}
+		boundary := []edge{}
+		j := 0
+		for _, t := range open {
+			dx := p.X - t.center.X
+			if 0.0 < dx && t.r2 < dx*dx {
+				closed = append(closed, t)
+				continue
+			} else if d := p.Sub(t.center); d.X*d.X+d.Y*d.Y < t.r2 {
+				for m := 0; m < 3; m++ {
+					e := edge{t.v[m], t.v[(m+1)%3]}
+					if _, ok := edges[edge{e.b, e.a}]; ok {
+						edges[edge{e.b, e.a}]++ // shared by two removed triangles
+					} else {
+						edges[e] = 0
+						boundary = append(boundary, e)
+					}
+				}
+				continue
+			}
+			open[j] = t
+			j++
+		}
+		open = open[:j]
+
+		// connect the boundary of the hole to p
+		for _, e := range boundary {
+			if edges[e] == 0 {
+				open = append(open, newTriangle(e.a, e.b, i))
+			}
+		}
+	}
+
+	for _, t := range append(closed, open...) {
+		if t.v[0] < n && t.v[1] < n && t.v[2] < n {
+			tri.Triangles = append(tri.Triangles, t.v)
+		}
+	}
+	return tri
+}
+
+// circumcircle returns the center and squared radius of the circle through a, b, and c, which is infinite for collinear points.
+func circumcircle(a, b, c Point) (Point, float64) {
+	ab, ac := b.Sub(a), c.Sub(a)
+	d := 2.0 * ab.PerpDot(ac)
+	if d == 0.0 {
+		return a, math.Inf(1)
+	}
+	lab, lac := ab.Dot(ab), ac.Dot(ac)
+	center := Point{(ac.Y*lab - ab.Y*lac) / d, (ab.X*lac - ac.X*lab) / d}
+	return a.Add(center), center.Dot(center)
+}
+
+// Path returns the triangles as closed subpaths.
+func (t *Triangulation) Path() *Path {
+	p := &Path{}
+	for _, tri := range t.Triangles {
+		a, b, c := t.Points[tri[0]], t.Points[tri[1]], t.Points[tri[2]]
+		p.MoveTo(a.X, a.Y)
+		p.LineTo(b.X, b.Y)
+		p.LineTo(c.X, c.Y)
+		p.Close()
+	}
+	return p
+}
+
+// Edges returns the unique edges of the triangulation as pairs of point indices, with the smallest index first.
+func (t *Triangulation) Edges() [][2]int {
+	seen := map[[2]int]bool{}
+	edges := [][2]int{}
+	for _, tri := range t.Triangles {
+		for m := 0; m < 3; m++ {
+			e := [2]int{tri[m], tri[(m+1)%3]}
+			if e[1] < e[0] {
+				e[0], e[1] = e[1], e[0]
+			}
+			if !seen[e] {
+				seen[e] = true
+				edges = append(edges, e)
+			}
+		}
+	}
+	return edges
+}
+
+// Voronoi returns the Voronoi cell of each point clipped to bounds, ie. the area that is closer to that point than to any other. Cells are in the same order as the points and are returned as closed counter clockwise paths. The cell of a duplicate point is empty.
+func (t *Triangulation) Voronoi(bounds Rect) []*Path {
+	// the cell of a point is bounded by the bisectors with its neighbours in the triangulation
+	neighbours := make([][]int, len(t.Points))
+	for _, e := range t.Edges() {
+		neighbours[e[0]] = append(neighbours[e[0]], e[1])
+		neighbours[e[1]] = append(neighbours[e[1]], e[0])
+	}
+	if len(t.Triangles) == 0 {
+		// collinear points
+		for i := range t.Points {
+			for j := range t.Points {
+				if i != j {
+					neighbours[i] = append(neighbours[i], j)
+				}
+			}
+		}
+	}
+
+	first := map[Point]int{}
+	cells := make([]*Path, len(t.Points))
+	for i, p := range t.Points {
+		cells[i] = &Path{}
+		if j, ok := first[p]; ok && j != i {
+			continue // duplicate
+		}
+		first[p] = i
+
+		cell := []Point{{bounds.X, bounds.Y}, {bounds.X + bounds.W, bounds.Y}, {bounds.X + bounds.W, bounds.Y + bounds.H}, {bounds.X, bounds.Y + bounds.H}}
+		for _, j := range neighbours[i] {
+			if q := t.Points[j]; q != p {
+				cell = clipHalfPlane(cell, p.Interpolate(q, 0.5), p.Sub(q))
+			}
+		}
+		if 2 < len(cell) {
+			cells[i].MoveTo(cell[0].X, cell[0].Y)
+			for _, c := range cell[1:] {
+				cells[i].LineTo(c.X, c.Y)
+			}
+			cells[i].Close()
+		}
+	}
+	return cells
+}
+
+// clipHalfPlane clips a convex polygon to the half-plane of points x with (x-p)·n >= 0.
+func clipHalfPlane(coords []Point, p, n Point) []Point {
+	clipped := []Point{}
+	for i, a := range coords {
+		b := coords[(i+1)%len(coords)]
+		da, db := a.Sub(p).Dot(n), b.Sub(p).Dot(n)
+		if 0.0 <= da {
+			clipped = append(clipped, a)
+		}
+		if da < 0.0 && 0.0 < db || 0.0 < da && db < 0.0 {
+			clipped = append(clipped, a.Interpolate(b, da/(da-db)))
+		}
+	}
+	return clipped
+}
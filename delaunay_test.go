@@ -0,0 +1,75 @@
+package canvas
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestDelaunay(t *testing.T) {
+	test.T(t, len(Delaunay([]Point{{0, 0}, {1, 0}}).Triangles), 0)
+	test.T(t, len(Delaunay([]Point{{0, 0}, {1, 0}, {2, 0}}).Triangles), 0) // collinear
+	test.T(t, Delaunay([]Point{{0, 0}, {1, 0}, {0, 1}}).Triangles, [][3]int{{2, 0, 1}})
+	test.T(t, Delaunay([]Point{{0, 0}, {1, 0}, {0, 1}, {1, 0}}).Triangles, [][3]int{{2, 0, 1}}) // duplicate
+
+	// square with center point
+	tri := Delaunay([]Point{{0, 0}, {2, 0}, {2, 2}, {0, 2}, {1, 1}})
+	test.T(t, len(tri.Triangles), 4)
+	test.T(t, len(tri.Edges()), 8)
+
+	rnd := rand.New(rand.NewSource(0))
+	points := make([]Point, 200)
+	for i := range points {
+		points[i] = Point{100.0 * rnd.Float64(), 100.0 * rnd.Float64()}
+	}
+	tri = Delaunay(points)
+
+	// a triangulation of n points with h points on the convex hull has 2n-2-h triangles
+	test.T(t, len(tri.Triangles), 2*len(points)-2-len(convexHull(points)))
+	for _, v := range tri.Triangles {
+		a, b, c := points[v[0]], points[v[1]], points[v[2]]
+		test.That(t, 0.0 < b.Sub(a).PerpDot(c.Sub(a)), "counter clockwise")
+		center, r2 := circumcircle(a, b, c)
+		for i, p := range points {
+			if i != v[0] && i != v[1] && i != v[2] {
+				d := p.Sub(center)
+				test.That(t, r2*(1.0-1e-9) < d.Dot(d), "empty circumcircle")
+			}
+		}
+	}
+}
+
+func TestVoronoi(t *testing.T) {
+	tri := Delaunay([]Point{{1, 1}, {3, 1}, {1, 3}, {3, 3}})
+	cells := tri.Voronoi(Rect{0, 0, 4, 4})
+	test.T(t, len(cells), 4)
+	test.T(t, cells[0].Bounds(), Rect{0, 0, 2, 2})
+	test.T(t, cells[3].Bounds(), Rect{2, 2, 2, 2})
+
+	// collinear and duplicate points
+	cells = Delaunay([]Point{{1, 2}, {2, 2}, {3, 2}, {3, 2}}).Voronoi(Rect{0, 0, 4, 4})
+	test.T(t, cells[1].Bounds(), Rect{1.5, 0, 1, 4})
+	test.That(t, cells[3].Empty())
+
+	// cells partition the bounds and contain only points that are closest to their site
+	rnd := rand.New(rand.NewSource(0))
+	points := make([]Point, 100)
+	for i := range points {
+		points[i] = Point{100.0 * rnd.Float64(), 100.0 * rnd.Float64()}
+	}
+	cells = Delaunay(points).Voronoi(Rect{0, 0, 100, 100})
+	area := 0.0
+	for i, cell := range cells {
+		test.That(t, cell.CCW())
+		area += polygonArea(cell)
+		for _, coord := range cell.Coords() {
+			dist := coord.Sub(points[i]).Length()
+			for _, p := range points {
+				test.That(t, dist <= coord.Sub(p).Length()+1e-9, "closest site")
+			}
+		}
+	}
+	test.That(t, math.Abs(area-100.0*100.0) < 1e-6, "total area")
+}
@@ -0,0 +1,288 @@
+package canvas
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Document is a sequence of pages, each a canvas of its own size, that is written as a multi-page PDF. When the document is imposed or printed on paper, the sheets are written instead.
+type Document struct {
+	Pages  []*Canvas
+	Sheets []Sheet // sides of the printed sheets, see Impose and Print
+}
+
+// NewDocument returns a document of the pages.
+func NewDocument(pages ...*Canvas) *Document {
+	return &Document{
+		Pages: pages,
+	}
+}
+
+// AddPage appends a new page of width by height millimeters and returns its canvas.
+func (d *Document) AddPage(width, height float64) *Canvas {
+	c := New(width, height)
+	d.Pages = append(d.Pages, c)
+	return c
+}
+
+// PaperSize is the size of a standard paper of width by height millimeters in portrait orientation.
+type PaperSize struct {
+	W, H float64
+}
+
+// standard paper sizes of ISO 216 and North America
+var (
+	A0     = PaperSize{841.0, 1189.0}
+	A1     = PaperSize{594.0, 841.0}
+	A2     = PaperSize{420.0, 594.0}
+	A3     = PaperSize{297.0, 420.0}
+	A4     = PaperSize{210.0, 297.0}
+	A5     = PaperSize{148.0, 210.0}
+	A6     = PaperSize{105.0, 148.0}
+	Letter = PaperSize{215.9, 279.4}
+	Legal  = PaperSize{215.9, 355.6}
+)
+
+// PaperSizes are the paper sizes from which the smallest that fits a page is selected by Document.Print, from small to large.
+var PaperSizes = []PaperSize{A6, A5, A4, A3, A2, A1, A0}
+
+// Orientation is the orientation of a paper.
+type Orientation int
+
+// see Orientation
+const (
+	AutoOrientation Orientation = iota // landscape for pages that are wider than high, and portrait otherwise
+	Portrait
+	Landscape
+)
+
+// Oriented returns the width and height of the paper in the given orientation, which is portrait for AutoOrientation.
+func (paper PaperSize) Oriented(orientation Orientation) (float64, float64) {
+	w, h := math.Min(paper.W, paper.H), math.Max(paper.W, paper.H)
+	if orientation == Landscape {
+		return h, w
+	}
+	return w, h
+}
+
+// AddPaperPage appends a new page of a standard paper size in the given orientation and returns its canvas.
+func (d *Document) AddPaperPage(paper PaperSize, orientation Orientation) *Canvas {
+	return d.AddPage(paper.Oriented(orientation))
+}
+
+// Sheet is one side of a printed sheet of width by height millimeters with the pages placed on it.
+type Sheet struct {
+	W, H  float64
+	Pages []SheetPage
+}
+
+// SheetPage is a page placed on a sheet, where M transforms the page to the sheet.
+type SheetPage struct {
+	Page *Canvas
+	M    Matrix
+}
+
+// Imposition defines how the pages of a document are arranged on sheets.
+type Imposition int
+
+// see Imposition
+const (
+	NUp     Imposition = iota // columns by rows of pages per sheet in reading order
+	Booklet                   // two pages side by side on both sides of sheets that are folded in half and nested, printed duplex flipping on the short edge
+)
+
+// ImposeLayout is the layout of sheets for Impose.
+type ImposeLayout struct {
+	Imposition     Imposition
+	Columns, Rows  int     // pages per sheet for NUp, booklets have two columns and one row
+	SheetW, SheetH float64 // size of the sheets in mm, zero fits the pages
+	Gap            float64 // between the pages in mm, which is not applied at the fold of booklets
+	Signature      int     // number of sheets that are folded together for booklets, zero folds all sheets together
+}
+
+// Impose arranges the pages of the document onto the sides of sheets, which are written instead of the pages. All pages are placed in cells of the size of the largest page and are scaled down uniformly when the cells do not fit the sheet. For NUp, pages are centered in their cells. Booklets are padded with blank pages to a multiple of four pages per signature, and their pages are aligned at the fold. Impose replaces previously imposed sheets.
+func (d *Document) Impose(layout ImposeLayout) error {
+	columns, rows := layout.Columns, layout.Rows
+	if layout.Imposition == Booklet {
+		columns, rows = 2, 1
+	} else if columns <= 0 || rows <= 0 {
+		return fmt.Errorf("columns and rows must be positive")
+	}
+	if layout.Signature < 0 || layout.Gap < 0.0 || layout.SheetW < 0.0 || layout.SheetH < 0.0 {
+		return fmt.Errorf("signature, gap, and sheet size must not be negative")
+	} else if len(d.Pages) == 0 {
+		return fmt.Errorf("document has no pages")
+	}
+
+	cw, ch := 0.0, 0.0 // size of the cells
+	for _, page := range d.Pages {
+		cw = math.Max(cw, page.W)
+		ch = math.Max(ch, page.H)
+	}
+	gapX := layout.Gap
+	if layout.Imposition == Booklet {
+		gapX = 0.0
+	}
+	w := float64(columns)*cw + float64(columns-1)*gapX
+	h := float64(rows)*ch + float64(rows-1)*layout.Gap
+	sheetW, sheetH := layout.SheetW, layout.SheetH
+	if sheetW == 0.0 {
+		sheetW = w
+	}
+	if sheetH == 0.0 {
+		sheetH = h
+	}
+	scale := math.Min(1.0, math.Min(sheetW/w, sheetH/h))
+	x0, y0 := (sheetW-scale*w)/2.0, (sheetH-scale*h)/2.0 // the grid is centered on the sheet
+
+	// slots of pages per side of a sheet, where blank pages are nil
+	slots := [][]*Canvas{}
+	if layout.Imposition == Booklet {
+		signature := layout.Signature
+		if signature == 0 {
+			signature = (len(d.Pages) + 3) / 4
+		}
+		page := func(i int) *Canvas {
+			if i < len(d.Pages) {
+				return d.Pages[i]
+			}
+			return nil
+		}
+		for start := 0; start < len(d.Pages); start += 4 * signature {
+			last := start + 4*signature - 1
+			for i := 0; i < signature; i++ {
+				slots = append(slots, []*Canvas{page(last - 2*i), page(start + 2*i)})         // front
+				slots = append(slots, []*Canvas{page(start + 2*i + 1), page(last - 2*i - 1)}) // back
+			}
+		}
+	} else {
+		n := columns * rows
+		for start := 0; start < len(d.Pages); start += n {
+			end := start + n
+			if len(d.Pages) < end {
+				end = len(d.Pages)
+			}
+			slots = append(slots, d.Pages[start:end])
+		}
+	}
+
+	d.Sheets = make([]Sheet, 0, len(slots))
+	for _, pages := range slots {
+		sheet := Sheet{W: sheetW, H: sheetH}
+		for i, page := range pages {
+			if page == nil {
+				continue
+			}
+			col, row := i%columns, i/columns
+			x := float64(col) * (cw + gapX)
+			y := float64(rows-1-row) * (ch + layout.Gap)
+			if layout.Imposition == Booklet {
+				if col == 0 {
+					x += cw - page.W // left page at the fold
+				}
+				y += (ch - page.H) / 2.0
+			} else {
+				x += (cw - page.W) / 2.0
+				y += (ch - page.H) / 2.0
+			}
+			m := Identity.Translate(x0, y0).Scale(scale, scale).Translate(x, y)
+			sheet.Pages = append(sheet.Pages, SheetPage{page, m})
+		}
+		d.Sheets = append(d.Sheets, sheet)
+	}
+	return nil
+}
+
+// PageSetup is the paper on which the pages of a document are printed, see Print.
+type PageSetup struct {
+	Paper       PaperSize   // zero selects the smallest of PaperSizes that fits each page within the margins
+	Orientation Orientation // AutoOrientation selects the orientation of each page by its aspect ratio
+	Margin      float64     // space on every side of the paper in mm
+	FitToPage   bool        // scale down pages that don't fit within the margins, otherwise they are clipped by the paper
+}
+
+// Print places every page of the document on a sheet of paper, which are written instead of the pages. Pages are centered within the margins of the paper, and are scaled down uniformly to fit when FitToPage is set. When no paper size is given, each page is printed on the smallest of PaperSizes that fits it within the margins, or on the largest when none fits. Print replaces previously imposed sheets.
+func (d *Document) Print(setup PageSetup) error {
+	if setup.Margin < 0.0 || setup.Paper.W < 0.0 || setup.Paper.H < 0.0 {
+		return fmt.Errorf("margin and paper size must not be negative")
+	} else if len(d.Pages) == 0 {
+		return fmt.Errorf("document has no pages")
+	}
+
+	d.Sheets = make([]Sheet, 0, len(d.Pages))
+	for _, page := range d.Pages {
+		orientation := setup.Orientation
+		if orientation == AutoOrientation {
+			orientation = Portrait
+			if page.H < page.W {
+				orientation = Landscape
+			}
+		}
+
+		paper := setup.Paper
+		if paper.W == 0.0 || paper.H == 0.0 {
+			for _, paper = range PaperSizes {
+				if w, h := paper.Oriented(orientation); page.W <= w-2.0*setup.Margin && page.H <= h-2.0*setup.Margin {
+					break
+				}
+			}
+		}
+		w, h := paper.Oriented(orientation)
+		aw, ah := w-2.0*setup.Margin, h-2.0*setup.Margin
+		if aw <= 0.0 || ah <= 0.0 {
+			return fmt.Errorf("margin must be smaller than half of the paper size")
+		}
+
+		scale := 1.0
+		if setup.FitToPage && (aw < page.W || ah < page.H) {
+			scale = math.Min(aw/page.W, ah/page.H)
+		}
+		x, y := (w-scale*page.W)/2.0, (h-scale*page.H)/2.0
+		m := Identity.Translate(x, y).Scale(scale, scale)
+		d.Sheets = append(d.Sheets, Sheet{W: w, H: h, Pages: []SheetPage{{page, m}}})
+	}
+	return nil
+}
+
+// WritePDF writes the document as a PDF with a page for every page, or for every side of the sheets when it is imposed. Placed pages are clipped to their size and each page is embedded once, see PDF.RenderCanvas.
+func (d *Document) WritePDF(w io.Writer) error {
+	if d.Sheets == nil {
+		if len(d.Pages) == 0 {
+			return fmt.Errorf("document has no pages")
+		}
+		pdf := NewPDF(w, d.Pages[0].W, d.Pages[0].H)
+		for i, page := range d.Pages {
+			if 0 < i {
+				pdf.NewPage(page.W, page.H)
+			}
+			page.Render(pdf)
+		}
+		return pdf.Close()
+	}
+
+	if len(d.Sheets) == 0 {
+		return fmt.Errorf("document has no sheets")
+	}
+	pdf := NewPDF(w, d.Sheets[0].W, d.Sheets[0].H)
+	for i, sheet := range d.Sheets {
+		if 0 < i {
+			pdf.NewPage(sheet.W, sheet.H)
+		}
+		for _, placed := range sheet.Pages {
+			pdf.RenderCanvas(placed.Page, placed.M)
+		}
+	}
+	return pdf.Close()
+}
+
+// SavePDF saves the document to a PDF file, see WritePDF.
+func (d *Document) SavePDF(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return d.WritePDF(f)
+}
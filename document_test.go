@@ -0,0 +1,123 @@
+package canvas
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestDocumentNUp(t *testing.T) {
+	d := NewDocument()
+	for i := 0; i < 5; i++ {
+		d.AddPage(100.0, 50.0)
+	}
+	d.Pages[4].W = 80.0
+
+	test.Error(t, d.Impose(ImposeLayout{Imposition: NUp, Columns: 2, Rows: 2, Gap: 10.0}))
+	test.T(t, len(d.Sheets), 2)
+	test.Float(t, d.Sheets[0].W, 210.0)
+	test.Float(t, d.Sheets[0].H, 110.0)
+	test.T(t, len(d.Sheets[0].Pages), 4)
+	test.T(t, d.Sheets[0].Pages[0].Page, d.Pages[0])
+	test.T(t, d.Sheets[0].Pages[0].M.Dot(Point{}), Point{0.0, 60.0}) // top-left
+	test.T(t, d.Sheets[0].Pages[3].M.Dot(Point{}), Point{110.0, 0.0})
+	test.T(t, len(d.Sheets[1].Pages), 1)
+	test.T(t, d.Sheets[1].Pages[0].M.Dot(Point{}), Point{10.0, 60.0}) // centered in its cell
+
+	// pages are scaled down to fit the sheet
+	test.Error(t, d.Impose(ImposeLayout{Imposition: NUp, Columns: 2, Rows: 2, SheetW: 105.0, SheetH: 100.0, Gap: 10.0}))
+	test.T(t, d.Sheets[0].Pages[3].M.Dot(Point{100.0, 50.0}), Point{105.0, 47.5}) // at half size, centered vertically
+
+	test.That(t, d.Impose(ImposeLayout{Imposition: NUp}) != nil, "no columns and rows")
+	test.That(t, NewDocument().Impose(ImposeLayout{Imposition: Booklet}) != nil, "no pages")
+}
+
+func TestDocumentBooklet(t *testing.T) {
+	d := NewDocument()
+	for i := 0; i < 6; i++ {
+		d.AddPage(100.0, 150.0)
+	}
+	test.Error(t, d.Impose(ImposeLayout{Imposition: Booklet}))
+	test.T(t, len(d.Sheets), 4) // two sheets of eight pages, of which two are blank
+
+	order := [][]int{}
+	for _, sheet := range d.Sheets {
+		test.Float(t, sheet.W, 200.0)
+		pages := []int{}
+		for _, placed := range sheet.Pages {
+			for i, page := range d.Pages {
+				if placed.Page == page {
+					pages = append(pages, i)
+				}
+			}
+		}
+		order = append(order, pages)
+	}
+	test.T(t, order, [][]int{{0}, {1}, {5, 2}, {3, 4}})
+	test.T(t, d.Sheets[0].Pages[0].M.Dot(Point{}), Point{100.0, 0.0}) // right of the fold
+
+	// signatures of one sheet
+	test.Error(t, d.Impose(ImposeLayout{Imposition: Booklet, Signature: 1}))
+	test.T(t, len(d.Sheets), 4)
+	test.T(t, d.Sheets[0].Pages[0].Page, d.Pages[3])
+	test.T(t, d.Sheets[2].Pages[0].Page, d.Pages[4])
+}
+
+func TestDocumentPrint(t *testing.T) {
+	d := NewDocument()
+	d.AddPaperPage(A4, Landscape)
+	d.AddPage(100.0, 120.0)
+	d.AddPage(500.0, 300.0)
+	test.Float(t, d.Pages[0].W, 297.0)
+	test.Float(t, d.Pages[0].H, 210.0)
+
+	test.Error(t, d.Print(PageSetup{Paper: A4, Margin: 10.0, FitToPage: true}))
+	test.T(t, len(d.Sheets), 3)
+	test.Float(t, d.Sheets[0].W, 297.0)                  // landscape
+	p := d.Sheets[0].Pages[0].M.Dot(Point{297.0, 210.0}) // scaled to the height within the margins
+	test.Float(t, p.X, 148.5+297.0*95.0/210.0)
+	test.Float(t, p.Y, 200.0)
+	test.Float(t, d.Sheets[1].W, 210.0) // portrait and centered
+	test.T(t, d.Sheets[1].Pages[0].M.Dot(Point{}), Point{55.0, 88.5})
+	p = d.Sheets[2].Pages[0].M.Dot(Point{500.0, 300.0}) // scaled to the width within the margins
+	test.Float(t, p.X, 287.0)
+	test.Float(t, p.Y, 105.0+300.0*138.5/500.0)
+
+	// smallest paper that fits
+	test.Error(t, d.Print(PageSetup{Margin: 5.0}))
+	test.Float(t, d.Sheets[1].W, 148.0) // A5
+	test.Float(t, d.Sheets[1].H, 210.0)
+	test.Float(t, d.Sheets[2].W, 594.0) // A2 in landscape
+	test.Float(t, d.Sheets[2].H, 420.0)
+	test.T(t, d.Sheets[2].Pages[0].M, Identity.Translate(47.0, 60.0))
+
+	w, h := Letter.Oriented(Landscape)
+	test.Float(t, w, 279.4)
+	test.Float(t, h, 215.9)
+
+	test.That(t, d.Print(PageSetup{Paper: A6, Margin: 60.0}) != nil, "margin too large")
+	test.That(t, NewDocument().Print(PageSetup{Paper: A4}) != nil, "no pages")
+}
+
+func TestDocumentWritePDF(t *testing.T) {
+	d := NewDocument()
+	for i := 0; i < 3; i++ {
+		ctx := NewContext(d.AddPage(100.0, 50.0))
+		ctx.DrawPath(0.0, 0.0, Rectangle(200.0, 10.0)) // clipped to the page when imposed
+	}
+
+	buf := &bytes.Buffer{}
+	test.Error(t, d.WritePDF(buf))
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("/Count 3")), "three pages")
+	test.T(t, bytes.Count(buf.Bytes(), []byte("/Subtype /Form")), 0)
+
+	test.Error(t, d.Impose(ImposeLayout{Imposition: NUp, Columns: 1, Rows: 2}))
+	buf.Reset()
+	test.Error(t, d.WritePDF(buf))
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("/Count 2")), "two sheets")
+	test.T(t, bytes.Count(buf.Bytes(), []byte("/Subtype /Form")), 3)
+	pages, err := ReadPDFPages(bytes.NewReader(buf.Bytes()))
+	test.Error(t, err)
+	test.T(t, len(pages), 2)
+}
@@ -5,6 +5,7 @@ import (
 	"image"
 	"image/color"
 	"io"
+	"strings"
 )
 
 var psEllipseDef = `/ellipse {
@@ -41,13 +42,33 @@ type EPS struct {
 	w             io.Writer
 	width, height float64
 	color         color.RGBA
+	overprint     bool
 }
 
 // NewEPS creates an encapsulated PostScript renderer.
 func NewEPS(w io.Writer, width, height float64) *EPS {
-	fmt.Fprintf(w, "%%!PS-Adobe-3.0 EPSF-3.0\n%%%%BoundingBox: 0 0 %v %v\n", dec(width), dec(height))
+	return NewEPSWithMarks(w, width, height, PrepressMarks{})
+}
+
+// NewEPSWithMarks creates an encapsulated PostScript renderer that draws the prepress marks around the drawing, and clips the drawing to its bleed. The bounding box is enlarged to hold them. Registration marks are drawn in the All separation color so that they print on every plate.
+func NewEPSWithMarks(w io.Writer, width, height float64, marks PrepressMarks) *EPS {
+	margin := marks.margin()
+	fmt.Fprintf(w, "%%!PS-Adobe-3.0 EPSF-3.0\n%%%%BoundingBox: 0 0 %v %v\n", dec(width+2.0*margin), dec(height+2.0*margin))
 	fmt.Fprintf(w, psEllipseDef)
 	// TODO: (EPS) generate and add preview
+	if 0.0 < margin {
+		fmt.Fprintf(w, " %v %v translate", dec(margin), dec(margin))
+		for _, mark := range marks.marks(width, height) {
+			if mark.registration {
+				fmt.Fprintf(w, " [/Separation (All) /DeviceCMYK {dup dup dup}] setcolorspace 1 setcolor")
+			} else {
+				fmt.Fprintf(w, " %v %v %v %v setcmykcolor", dec(mark.cmyk[0]), dec(mark.cmyk[1]), dec(mark.cmyk[2]), dec(mark.cmyk[3]))
+			}
+			fmt.Fprintf(w, " %v fill", mark.path.ToPS())
+		}
+		bleed := marks.Bleed
+		fmt.Fprintf(w, " %v %v %v %v rectclip 0 0 0 setrgbcolor", dec(-bleed), dec(-bleed), dec(width+2.0*bleed), dec(height+2.0*bleed))
+	}
 
 	return &EPS{
 		w:      w,
@@ -57,6 +78,23 @@ func NewEPS(w io.Writer, width, height float64) *EPS {
 	}
 }
 
+// psStringReplacer escapes the characters of literal strings in PostScript.
+var psStringReplacer = strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`)
+
+// setSpotColor sets the tint of the spot color in a Separation color space, with its process color as alternate.
+func (r *EPS) setSpotColor(spot SpotColor) {
+	c, m, y, k := dec(spot.CMYK[0]), dec(spot.CMYK[1]), dec(spot.CMYK[2]), dec(spot.CMYK[3])
+	fmt.Fprintf(r.w, " [/Separation (%v) /DeviceCMYK {dup %v mul exch dup %v mul exch dup %v mul exch %v mul}] setcolorspace %v setcolor", psStringReplacer.Replace(spot.Name), c, m, y, k, dec(spot.Tint))
+	r.color = Transparent // is never set as a color
+}
+
+func (r *EPS) setOverprint(overprint bool) {
+	if overprint != r.overprint {
+		fmt.Fprintf(r.w, " %v setoverprint", overprint)
+		r.overprint = overprint
+	}
+}
+
 func (r *EPS) setColor(color color.RGBA) {
 	if color != r.color {
 		fmt.Fprintf(r.w, " %v %v %v setrgbcolor", dec(float64(color.R)/255.0), dec(float64(color.G)/255.0), dec(float64(color.B)/255.0))
@@ -72,7 +110,16 @@ func (r *EPS) RenderPath(path *Path, style Style, m Matrix) {
 	// TODO: (EPS) test ellipse, rotations etc
 	// TODO: (EPS) add drawState support
 	// TODO: (EPS) use dither to fake transparency
-	r.setColor(style.FillColor)
+	if style.FillMesh != nil {
+		renderMesh(r, path, style, m, meshPieceSize)
+		return
+	}
+	r.setOverprint(style.Overprint)
+	if style.FillSpot != nil {
+		r.setSpotColor(*style.FillSpot)
+	} else {
+		r.setColor(style.FillColor)
+	}
 	r.w.Write([]byte(" "))
 	r.w.Write([]byte(path.Transform(m).ToPS()))
 	r.w.Write([]byte(" fill"))
@@ -3,6 +3,8 @@ package canvas
 import (
 	"bytes"
 	"testing"
+
+	"github.com/tdewolff/test"
 )
 
 func TestEPS(t *testing.T) {
@@ -11,3 +13,29 @@ func TestEPS(t *testing.T) {
 	eps.setColor(Red)
 	//test.String(t, string(w.Bytes()), "")
 }
+
+func TestEPSPrepressMarks(t *testing.T) {
+	w := &bytes.Buffer{}
+	NewEPSWithMarks(w, 100.0, 50.0, DefaultPrepressMarks)
+	test.That(t, bytes.Contains(w.Bytes(), []byte("%%BoundingBox: 0 0 116 66\n")), "bounding box")
+	test.That(t, bytes.Contains(w.Bytes(), []byte(" 8 8 translate")), "drawing moved")
+	test.That(t, bytes.HasSuffix(w.Bytes(), []byte(" -3 -3 106 56 rectclip 0 0 0 setrgbcolor")), "drawing clipped to bleed")
+	test.T(t, bytes.Count(w.Bytes(), []byte("setcmykcolor")), 10)
+
+	w.Reset()
+	NewEPSWithMarks(w, 100.0, 50.0, PrepressMarks{})
+	test.That(t, bytes.HasSuffix(w.Bytes(), []byte("} def")), "no marks")
+}
+
+func TestEPSSpotColors(t *testing.T) {
+	w := &bytes.Buffer{}
+	eps := NewEPS(w, 100.0, 50.0)
+	style := DefaultStyle
+	style.FillSpot = &SpotColor{"Gold (metallic)", [4]float64{0.0, 0.2, 0.6, 0.2}, 1.0}
+	style.Overprint = true
+	eps.RenderPath(Rectangle(10.0, 10.0), style, Identity)
+	test.That(t, bytes.Contains(w.Bytes(), []byte(" true setoverprint [/Separation (Gold \\(metallic\\)) /DeviceCMYK {dup 0 mul exch dup .2 mul exch dup .6 mul exch .2 mul}] setcolorspace 1 setcolor")), w.String())
+
+	eps.RenderPath(Rectangle(10.0, 10.0), DefaultStyle, Identity)
+	test.That(t, bytes.Contains(w.Bytes(), []byte(" false setoverprint 0 0 0 setrgbcolor")), w.String())
+}
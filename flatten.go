@@ -0,0 +1,265 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/draw"
+)
+
+// flatRegion is an opaque area of the flattened drawing that is either filled by a color, or covered by an opaque image that fills its area.
+type flatRegion struct {
+	p   *Path // in canvas coordinates, without overlaps
+	col color.RGBA
+	img *image.RGBA
+	m   Matrix // only for img
+}
+
+// flattener keeps track of the visible opaque regions of the drawing while it is flattened.
+type flattener struct {
+	regions []flatRegion
+	layers  []layer // flattened layers
+}
+
+// FlattenTransparency returns a new canvas that draws the same as the canvas but without transparency, for printers and PostScript that do not support it. Opaque drawing is kept as is. Translucent fills, strokes, and text are divided into the regions where they overlap the drawing underneath, each filled by the precomputed color of the translucent color composited onto it, and onto white paper where there is no drawing. Where translucent drawing overlaps an image, or where an image is translucent, the drawing is rasterized at the resolution of the image into an opaque image.
+func (c *Canvas) FlattenTransparency() *Canvas {
+	c.merge()
+
+	// opaque layers only need to be tracked when translucent drawing follows in their area
+	translucent := []Rect{}
+	ends := make([]int, len(c.layers)) // number of translucent layers up to and including each layer
+	for i, l := range c.layers {
+		if !l.opaque() {
+			translucent = append(translucent, l.Bounds())
+		}
+		ends[i] = len(translucent)
+	}
+
+	f := &flattener{}
+	for i, l := range c.layers {
+		if l.opaque() {
+			f.layers = append(f.layers, l)
+			bounds := l.Bounds()
+			for _, r := range translucent[ends[i]:] {
+				if r.Overlaps(bounds) {
+					f.addOpaque(l)
+					break
+				}
+			}
+			continue
+		}
+
+		if l.path != nil {
+			path := l.path.Transform(l.m)
+			if l.style.FillColor.A != 0 {
+				f.addFill(path.Settle(l.style.FillRule), l.style.FillColor)
+			}
+			if l.style.StrokeColor.A != 0 && 0.0 < l.style.StrokeWidth {
+				if 0 < len(l.style.Dashes) {
+					path = path.Dash(l.style.DashOffset, l.style.Dashes...)
+				}
+				f.addFill(path.Stroke(l.style.StrokeWidth, l.style.StrokeCapper, l.style.StrokeJoiner).Settle(NonZero), l.style.StrokeColor)
+			}
+		} else if l.text != nil {
+			paths, colors := l.text.ToPaths()
+			for j, path := range paths {
+				f.addFill(path.Transform(l.m).Settle(NonZero), colors[j])
+			}
+		} else if l.img != nil {
+			size := l.img.Bounds().Size()
+			f.rasterize(l, Rect{0.0, 0.0, float64(size.X), float64(size.Y)}.ToPath().Transform(l.m), l.m, size.X, size.Y)
+		}
+	}
+
+	flat := New(c.W, c.H)
+	flat.layers = f.layers
+	return flat
+}
+
+// opaque returns true if the layer is drawn without transparency.
+func (l layer) opaque() bool {
+	if l.path != nil {
+		stroked := l.style.StrokeColor.A != 0 && 0.0 < l.style.StrokeWidth
+		return (l.style.FillColor.A == 0 || l.style.FillColor.A == 255) && (!stroked || l.style.StrokeColor.A == 255)
+	} else if l.text != nil {
+		for _, line := range l.text.lines {
+			for _, span := range line.spans {
+				if span.ff.color.A != 255 {
+					return false
+				}
+			}
+			for _, deco := range line.decos {
+				if deco.ff.color.A != 255 {
+					return false
+				}
+			}
+		}
+		return true
+	} else if l.img != nil {
+		if img, ok := l.img.(interface{ Opaque() bool }); ok {
+			return img.Opaque()
+		}
+		bounds := l.img.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if _, _, _, a := l.img.At(x, y).RGBA(); a != 0xffff {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// add adds an opaque region on top of the others.
+func (f *flattener) add(region flatRegion) {
+	bounds := region.p.Bounds()
+	k := 0
+	for _, r := range f.regions {
+		if r.p.Bounds().Overlaps(bounds) {
+			r.p = r.p.Not(region.p)
+		}
+		if !r.p.Empty() {
+			f.regions[k] = r
+			k++
+		}
+	}
+	f.regions = append(f.regions[:k], region)
+}
+
+// addOpaque adds the areas of an opaque layer that is drawn as is.
+func (f *flattener) addOpaque(l layer) {
+	if l.path != nil {
+		path := l.path.Transform(l.m)
+		if l.style.FillColor.A != 0 {
+			f.add(flatRegion{p: path.Settle(l.style.FillRule), col: l.style.FillColor})
+		}
+		if l.style.StrokeColor.A != 0 && 0.0 < l.style.StrokeWidth {
+			if 0 < len(l.style.Dashes) {
+				path = path.Dash(l.style.DashOffset, l.style.Dashes...)
+			}
+			f.add(flatRegion{p: path.Stroke(l.style.StrokeWidth, l.style.StrokeCapper, l.style.StrokeJoiner).Settle(NonZero), col: l.style.StrokeColor})
+		}
+	} else if l.text != nil {
+		paths, colors := l.text.ToPaths()
+		for i, path := range paths {
+			f.add(flatRegion{p: path.Transform(l.m).Settle(NonZero), col: colors[i]})
+		}
+	} else if l.img != nil {
+		img := image.NewRGBA(image.Rect(0, 0, l.img.Bounds().Dx(), l.img.Bounds().Dy()))
+		draw.Draw(img, img.Bounds(), l.img, l.img.Bounds().Min, draw.Src)
+		size := img.Bounds().Size()
+		f.add(flatRegion{p: Rect{0.0, 0.0, float64(size.X), float64(size.Y)}.ToPath().Transform(l.m), img: img, m: l.m})
+	}
+}
+
+// addFill adds a translucent fill of the area p without overlaps.
+func (f *flattener) addFill(p *Path, col color.RGBA) {
+	if p.Empty() {
+		return
+	}
+
+	// rasterize where it overlaps images, in the pixels of the image
+	images := []flatRegion{}
+	for _, r := range f.regions {
+		if r.img != nil {
+			images = append(images, r)
+		}
+	}
+	bounds := p.Bounds()
+	for _, r := range images {
+		if !r.p.Bounds().Overlaps(bounds) {
+			continue
+		}
+		overlap := r.p.And(p)
+		if overlap.Empty() {
+			continue
+		}
+		size := r.img.Bounds().Size()
+		pixels := overlap.Transform(r.m.Inv()).Bounds()
+		x0, y0 := clampInt(int(math.Floor(pixels.X)), 0, size.X), clampInt(int(math.Floor(pixels.Y)), 0, size.Y)
+		x1, y1 := clampInt(int(math.Ceil(pixels.X+pixels.W)), 0, size.X), clampInt(int(math.Ceil(pixels.Y+pixels.H)), 0, size.Y)
+		if x1 <= x0 || y1 <= y0 {
+			continue
+		}
+		m := r.m.Translate(float64(x0), float64(y0))
+		area := Rect{0.0, 0.0, float64(x1 - x0), float64(y1 - y0)}.ToPath().Transform(m)
+		style := DefaultStyle
+		style.FillColor = col
+		f.rasterize(layer{path: p, m: Identity, style: style}, area, m, x1-x0, y1-y0)
+		if p = p.Not(area); p.Empty() {
+			return
+		}
+		bounds = p.Bounds()
+	}
+
+	// divide into the regions it overlaps, and the remainder on white paper
+	fill := func(q *Path, col color.RGBA) {
+		style := DefaultStyle
+		style.FillColor = col
+		f.layers = append(f.layers, layer{path: q, m: Identity, style: style})
+	}
+	regions := []flatRegion{}
+	rest := p
+	for _, r := range f.regions {
+		if r.img != nil || !r.p.Bounds().Overlaps(bounds) {
+			continue // slivers along rasterized areas
+		}
+		if overlap := r.p.And(p); !overlap.Empty() {
+			composite := compositeOver(col, r.col)
+			fill(overlap, composite)
+			regions = append(regions, flatRegion{p: overlap, col: composite})
+			rest = rest.Not(overlap)
+		}
+	}
+	if !rest.Empty() {
+		composite := compositeOver(col, White)
+		fill(rest, composite)
+		regions = append(regions, flatRegion{p: rest, col: composite})
+	}
+	for _, region := range regions {
+		f.add(region)
+	}
+}
+
+// rasterize draws the layer onto the drawing underneath it into an opaque image of w by h pixels that covers the area, where m transforms the pixels to canvas coordinates.
+func (f *flattener) rasterize(l layer, area *Path, m Matrix, w, h int) {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff // white paper
+	}
+	r := NewRasterizer(img, 1.0)
+	view := m.Inv()
+	bounds := area.Bounds()
+	for _, region := range f.regions {
+		if !region.p.Bounds().Overlaps(bounds) {
+			continue
+		} else if region.img != nil {
+			r.RenderImage(region.img, view.Mul(region.m))
+		} else {
+			style := DefaultStyle
+			style.FillColor = region.col
+			r.RenderPath(region.p, style, view)
+		}
+	}
+	if l.path != nil {
+		r.RenderPath(l.path, l.style, view.Mul(l.m))
+	} else if l.img != nil {
+		r.RenderImage(l.img, view.Mul(l.m))
+	}
+
+	f.layers = append(f.layers, layer{img: img, m: m})
+	f.add(flatRegion{p: area, img: img, m: m})
+}
+
+// compositeOver returns the translucent color composited onto the opaque color.
+func compositeOver(src, dst color.RGBA) color.RGBA {
+	a := 255 - uint32(src.A)
+	return color.RGBA{
+		uint8(uint32(src.R) + (uint32(dst.R)*a+127)/255),
+		uint8(uint32(src.G) + (uint32(dst.G)*a+127)/255),
+		uint8(uint32(src.B) + (uint32(dst.B)*a+127)/255),
+		255,
+	}
+}
@@ -0,0 +1,86 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestFlattenTransparency(t *testing.T) {
+	blue := color.RGBA{0, 0, 128, 128} // translucent
+	test.T(t, compositeOver(blue, Red), color.RGBA{127, 0, 128, 255})
+	test.T(t, compositeOver(blue, White), color.RGBA{127, 127, 255, 255})
+
+	c := New(20.0, 10.0)
+	ctx := NewContext(c)
+	ctx.SetFillColor(Red)
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	ctx.SetFillColor(blue)
+	ctx.DrawPath(5.0, 0.0, Rectangle(10.0, 10.0))
+
+	flat := c.FlattenTransparency()
+	test.T(t, len(flat.layers), 3)
+	test.T(t, flat.layers[0].path, c.layers[0].path) // opaque drawing is kept
+	for _, l := range flat.layers {
+		test.That(t, l.opaque(), "opaque")
+	}
+	test.T(t, flat.layers[1].style.FillColor, color.RGBA{127, 0, 128, 255})
+	test.T(t, flat.layers[1].path.Bounds(), Rect{5.0, 0.0, 5.0, 10.0})
+	test.T(t, flat.layers[2].style.FillColor, color.RGBA{127, 127, 255, 255})
+	test.T(t, flat.layers[2].path.Bounds(), Rect{10.0, 0.0, 5.0, 10.0})
+
+	img, flatImg := c.WriteImage(1.0), flat.WriteImage(1.0)
+	for _, x := range []int{2, 7, 12, 17} {
+		test.T(t, flatImg.RGBAAt(x, 5), img.RGBAAt(x, 5))
+	}
+
+	// opaque drawing only is not changed
+	c = New(20.0, 10.0)
+	ctx = NewContext(c)
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	test.T(t, len(c.FlattenTransparency().layers), 1)
+}
+
+func TestFlattenTransparencyImages(t *testing.T) {
+	green := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for i := range green.Pix {
+		green.Pix[i] = []uint8{0x00, 0xff, 0x00, 0xff}[i%4]
+	}
+	blue := color.RGBA{0, 0, 128, 128}
+
+	// translucent drawing over an image is rasterized where it overlaps
+	c := New(20.0, 10.0)
+	ctx := NewContext(c)
+	ctx.DrawImage(0.0, 0.0, green, 1.0)
+	ctx.SetFillColor(blue)
+	ctx.DrawPath(5.0, 0.0, Rectangle(10.0, 10.0))
+	flat := c.FlattenTransparency()
+	test.T(t, len(flat.layers), 3) // image, rasterized overlap, and fill on paper
+	for _, l := range flat.layers {
+		test.That(t, l.opaque(), "opaque")
+	}
+	overlap := flat.layers[1].img.(*image.RGBA)
+	test.T(t, overlap.Bounds().Size(), image.Point{5, 10})
+	test.T(t, overlap.RGBAAt(2, 5), compositeOver(blue, color.RGBA{0, 255, 0, 255}))
+	test.T(t, flat.layers[1].Bounds(), Rect{5.0, 0.0, 5.0, 10.0})
+	test.T(t, flat.layers[2].path.Bounds(), Rect{10.0, 0.0, 5.0, 10.0})
+
+	// a translucent image is rasterized onto the drawing underneath
+	translucent := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for i := range translucent.Pix {
+		translucent.Pix[i] = []uint8{0x00, 0x00, 0x80, 0x80}[i%4]
+	}
+	c = New(20.0, 10.0)
+	ctx = NewContext(c)
+	ctx.SetFillColor(Red)
+	ctx.DrawPath(0.0, 0.0, Rectangle(20.0, 5.0))
+	ctx.DrawImage(5.0, 0.0, translucent, 1.0)
+	flat = c.FlattenTransparency()
+	test.T(t, len(flat.layers), 2)
+	rasterized := flat.layers[1].img.(*image.RGBA)
+	test.That(t, rasterized.Opaque(), "opaque")
+	test.T(t, rasterized.RGBAAt(5, 8), compositeOver(blue, Red))
+	test.T(t, rasterized.RGBAAt(5, 2), compositeOver(blue, White))
+}
@@ -0,0 +1,317 @@
+package canvas
+
+import (
+	"math"
+)
+
+// FlexDirection is the direction in which the items of a flex box are placed.
+type FlexDirection int
+
+// see FlexDirection
+const (
+	FlexRow    FlexDirection = iota // from left to right
+	FlexColumn                      // from top to bottom
+)
+
+// FlexContent is the content of a flex item, such as a sub-canvas, a text box, or a nested flex box.
+type FlexContent interface {
+	// Size returns the preferred width and height of the content in millimeters when laid out at the given width, or at its natural width when width is zero.
+	Size(width float64) (float64, float64)
+
+	// Draw draws the content in the rectangle assigned by the layout.
+	Draw(ctx *Context, rect Rect)
+}
+
+// FlexItem is an item of a flex box.
+type FlexItem struct {
+	Content FlexContent
+	Basis   float64 // size along the main axis in millimeters before growing or shrinking, zero uses the size of the content
+	Grow    float64 // fraction of the free space that is added to the item
+	Shrink  float64 // fraction of the overflow that is removed from the item, relative to its basis
+}
+
+// FlexBox is a layout container that places its items in a row or column, which wrap onto multiple lines when they don't fit, similar to the CSS flexible box layout. Items grow or shrink to fill the box along the main axis, and are aligned or stretched along the cross axis, so that figures of multiple charts and text boxes adapt to any page size. A flex box can be the content of an item of another flex box.
+type FlexBox struct {
+	Direction FlexDirection
+	Wrap      bool      // place items on multiple lines when they don't fit
+	Gap       float64   // space between items and between lines in millimeters
+	Padding   float64   // space inside the box around the items in millimeters
+	Justify   TextAlign // placement along the main axis: Left or Top at the start, Center, Right or Bottom at the end, or Justify to space them evenly
+	Align     TextAlign // placement along the cross axis within a line: Left or Top at the start, Center, Right or Bottom at the end, or Justify to stretch them
+	Items     []*FlexItem
+}
+
+// NewFlexBox returns a new flex box in the given direction, where items are placed at the start and stretched along the cross axis.
+func NewFlexBox(direction FlexDirection) *FlexBox {
+	return &FlexBox{
+		Direction: direction,
+		Justify:   Left,
+		Align:     Justify,
+	}
+}
+
+// Add adds an item with the given content and grow and shrink factors, and returns it so that its basis can be set.
+func (b *FlexBox) Add(content FlexContent, grow, shrink float64) *FlexItem {
+	item := &FlexItem{
+		Content: content,
+		Grow:    grow,
+		Shrink:  shrink,
+	}
+	b.Items = append(b.Items, item)
+	return item
+}
+
+// Size returns the size of the box that fits its items at the given width, or at their natural width when width is zero.
+func (b *FlexBox) Size(width float64) (float64, float64) {
+	if b.Direction == FlexRow {
+		_, w, h := b.layout(width, 0.0)
+		return w, h
+	}
+	_, h, w := b.layout(0.0, width)
+	return w, h
+}
+
+// Layout returns the rectangles of the items when the box is laid out at width by height with its lower-left corner at the origin. When height is zero, the box takes the height of its items.
+func (b *FlexBox) Layout(width, height float64) []Rect {
+	var rects []Rect
+	var h float64
+	if b.Direction == FlexRow {
+		rects, _, h = b.layout(width, height)
+	} else {
+		rects, h, _ = b.layout(height, width)
+	}
+	if height == 0.0 {
+		height = h
+	}
+	for i := range rects {
+		// convert from top-down coordinates
+		rects[i].Y = height - rects[i].Y - rects[i].H
+	}
+	return rects
+}
+
+// Draw draws the items of the box laid out in rect.
+func (b *FlexBox) Draw(ctx *Context, rect Rect) {
+	for i, r := range b.Layout(rect.W, rect.H) {
+		b.Items[i].Content.Draw(ctx, r.Move(Point{rect.X, rect.Y}))
+	}
+}
+
+// itemSize returns the main and cross size of an item, given the size along the main axis (zero for its preferred size) and the space along the cross axis (zero for no limit).
+func (b *FlexBox) itemSize(item *FlexItem, main, cross float64) (float64, float64) {
+	if b.Direction == FlexRow {
+		w, h := item.Content.Size(main)
+		if main == 0.0 {
+			main = w
+		}
+		return main, h
+	}
+	w, _ := item.Content.Size(0.0)
+	if 0.0 < cross && (b.Align == Justify || cross < w) {
+		w = cross
+	}
+	_, h := item.Content.Size(w)
+	if main == 0.0 {
+		main = h
+	}
+	return main, w
+}
+
+// layout lays out the items in the box of the given main and cross size, where zero is no limit, and returns the rectangles of the items in top-down coordinates together with the main and cross size of the box.
+func (b *FlexBox) layout(main, cross float64) ([]Rect, float64, float64) {
+	pad := b.Padding
+	innerMain, innerCross := math.Max(0.0, main-2.0*pad), math.Max(0.0, cross-2.0*pad)
+	if main == 0.0 {
+		innerMain = math.Inf(1)
+	}
+
+	// hypothetical main sizes
+	bases := make([]float64, len(b.Items))
+	for i, item := range b.Items {
+		bases[i] = item.Basis
+		if bases[i] == 0.0 {
+			bases[i], _ = b.itemSize(item, 0.0, innerCross)
+		}
+	}
+
+	// break into lines
+	lines := [][2]int{} // start and end index of the items of each line
+	start, size := 0, 0.0
+	for i := range b.Items {
+		if b.Wrap && start < i && innerMain < size+b.Gap+bases[i] {
+			lines = append(lines, [2]int{start, i})
+			start, size = i, 0.0
+		}
+		if start < i {
+			size += b.Gap
+		}
+		size += bases[i]
+	}
+	if start < len(b.Items) {
+		lines = append(lines, [2]int{start, len(b.Items)})
+	}
+
+	rects := make([]Rect, len(b.Items))
+	usedMain, usedCross := 0.0, 0.0
+	for j, line := range lines {
+		items, bases := b.Items[line[0]:line[1]], bases[line[0]:line[1]]
+
+		// grow or shrink along the main axis
+		free := innerMain - b.Gap*float64(len(items)-1)
+		grow, shrink := 0.0, 0.0
+		for i, item := range items {
+			free -= bases[i]
+			grow += item.Grow
+			shrink += item.Shrink * bases[i]
+		}
+		if math.IsInf(free, 1) {
+			free = 0.0
+		}
+		sizes := make([]float64, len(items))
+		for i, item := range items {
+			sizes[i] = bases[i]
+			if 0.0 < free && 0.0 < grow {
+				sizes[i] += free * item.Grow / grow
+			} else if free < 0.0 && 0.0 < shrink {
+				sizes[i] = math.Max(0.0, sizes[i]+free*item.Shrink*bases[i]/shrink)
+			}
+		}
+		if (0.0 < free && 0.0 < grow) || (free < 0.0 && 0.0 < shrink) {
+			free = innerMain - b.Gap*float64(len(items)-1)
+			for _, size := range sizes {
+				free -= size
+			}
+		}
+
+		// justify along the main axis
+		pos, gap := 0.0, b.Gap
+		switch b.Justify {
+		case Center:
+			pos = free / 2.0
+		case Right, Bottom:
+			pos = free
+		case Justify:
+			if 0.0 < free && 1 < len(items) {
+				gap += free / float64(len(items)-1)
+			}
+		}
+
+		// align along the cross axis
+		crosses := make([]float64, len(items))
+		lineCross := 0.0
+		for i, item := range items {
+			_, crosses[i] = b.itemSize(item, sizes[i], innerCross)
+			lineCross = math.Max(lineCross, crosses[i])
+		}
+		if len(lines) == 1 && 0.0 < cross {
+			lineCross = innerCross
+		}
+		if 0 < j {
+			usedCross += b.Gap
+		}
+		for i := range items {
+			offset := 0.0
+			switch b.Align {
+			case Center:
+				offset = (lineCross - crosses[i]) / 2.0
+			case Right, Bottom:
+				offset = lineCross - crosses[i]
+			case Justify:
+				crosses[i] = lineCross
+			}
+
+			r := Rect{pad + pos, pad + usedCross + offset, sizes[i], crosses[i]}
+			if b.Direction == FlexColumn {
+				r = Rect{r.Y, r.X, r.H, r.W}
+			}
+			rects[line[0]+i] = r
+			pos += sizes[i] + gap
+		}
+		usedMain = math.Max(usedMain, pos-gap)
+		usedCross += lineCross
+	}
+	if 0.0 < main {
+		usedMain = main
+	} else {
+		usedMain += 2.0 * pad
+	}
+	if 0.0 < cross {
+		usedCross = cross
+	} else {
+		usedCross += 2.0 * pad
+	}
+	return rects, usedMain, usedCross
+}
+
+type flexCanvas struct {
+	c *Canvas
+}
+
+// NewFlexCanvas returns content that draws a canvas scaled uniformly to fit and centered in its rectangle. Its preferred size is the size of the canvas.
+func NewFlexCanvas(c *Canvas) FlexContent {
+	return flexCanvas{c}
+}
+
+func (f flexCanvas) Size(width float64) (float64, float64) {
+	if width == 0.0 || f.c.W == 0.0 {
+		return f.c.W, f.c.H
+	}
+	return width, width * f.c.H / f.c.W
+}
+
+func (f flexCanvas) Draw(ctx *Context, rect Rect) {
+	if f.c.W == 0.0 || f.c.H == 0.0 {
+		return
+	}
+	scale := math.Min(rect.W/f.c.W, rect.H/f.c.H)
+	x := rect.X + (rect.W-scale*f.c.W)/2.0
+	y := rect.Y + (rect.H-scale*f.c.H)/2.0
+	ctx.Push()
+	ctx.ComposeView(Identity.Translate(x, y).Scale(scale, scale))
+	f.c.Render(ctx)
+	ctx.Pop()
+}
+
+type flexText struct {
+	rt             *RichText
+	halign, valign TextAlign
+}
+
+// NewFlexText returns content that lays out rich text in its rectangle with the given horizontal and vertical alignment, see RichText.ToText. Its preferred size is the size of the text on a single line, or the height of the text wrapped at the given width.
+func NewFlexText(rt *RichText, halign, valign TextAlign) FlexContent {
+	return flexText{rt, halign, valign}
+}
+
+func (f flexText) Size(width float64) (float64, float64) {
+	text := f.rt.ToText(width, 0.0, f.halign, Top, 0.0, 0.0)
+	if width == 0.0 {
+		width = text.Width()
+	}
+	return width, text.Height()
+}
+
+func (f flexText) Draw(ctx *Context, rect Rect) {
+	text := f.rt.ToText(rect.W, rect.H, f.halign, f.valign, 0.0, 0.0)
+	ctx.DrawText(rect.X, rect.Y+rect.H, text)
+}
+
+type flexFunc struct {
+	width, height float64
+	draw          func(*Context, Rect)
+}
+
+// NewFlexFunc returns content of a preferred size of width by height millimeters that is drawn by a function in its rectangle, eg. to draw a chart at the assigned size. Its preferred height does not depend on the width it is laid out at.
+func NewFlexFunc(width, height float64, draw func(*Context, Rect)) FlexContent {
+	return flexFunc{width, height, draw}
+}
+
+func (f flexFunc) Size(width float64) (float64, float64) {
+	if width == 0.0 {
+		width = f.width
+	}
+	return width, f.height
+}
+
+func (f flexFunc) Draw(ctx *Context, rect Rect) {
+	f.draw(ctx, rect)
+}
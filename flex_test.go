@@ -0,0 +1,106 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func flexBlock(width, height float64) FlexContent {
+	return NewFlexFunc(width, height, func(*Context, Rect) {})
+}
+
+func TestFlexBoxRow(t *testing.T) {
+	box := NewFlexBox(FlexRow)
+	box.Gap = 2.0
+	box.Padding = 1.0
+	box.Add(flexBlock(10.0, 10.0), 0.0, 0.0)
+	box.Add(flexBlock(20.0, 10.0), 1.0, 0.0)
+	box.Add(flexBlock(10.0, 10.0), 3.0, 0.0).Basis = 6.0
+	rects := box.Layout(58.0, 30.0)
+	test.T(t, rects[0], Rect{1.0, 1.0, 10.0, 28.0})
+	test.T(t, rects[1], Rect{13.0, 1.0, 24.0, 28.0})
+	test.T(t, rects[2], Rect{39.0, 1.0, 18.0, 28.0})
+
+	box.Align = Center
+	box.Items[1].Grow, box.Items[2].Grow = 0.0, 0.0
+	box.Justify = Justify
+	rects = box.Layout(58.0, 30.0)
+	test.T(t, rects[1], Rect{21.0, 10.0, 20.0, 10.0})
+	test.T(t, rects[2], Rect{51.0, 10.0, 6.0, 10.0})
+
+	w, h := box.Size(0.0)
+	test.Float(t, w, 42.0)
+	test.Float(t, h, 12.0)
+}
+
+func TestFlexBoxShrink(t *testing.T) {
+	box := NewFlexBox(FlexRow)
+	box.Add(flexBlock(30.0, 10.0), 0.0, 1.0)
+	box.Add(flexBlock(10.0, 10.0), 0.0, 1.0)
+	box.Add(flexBlock(10.0, 10.0), 0.0, 0.0)
+	rects := box.Layout(42.0, 10.0)
+	test.T(t, rects[0], Rect{0.0, 0.0, 24.0, 10.0})
+	test.T(t, rects[1], Rect{24.0, 0.0, 8.0, 10.0})
+	test.T(t, rects[2], Rect{32.0, 0.0, 10.0, 10.0})
+}
+
+func TestFlexBoxWrap(t *testing.T) {
+	box := NewFlexBox(FlexRow)
+	box.Wrap = true
+	box.Gap = 2.0
+	box.Align = Top
+	box.Add(flexBlock(20.0, 10.0), 1.0, 0.0)
+	box.Add(flexBlock(20.0, 5.0), 1.0, 0.0)
+	box.Add(flexBlock(20.0, 8.0), 1.0, 0.0)
+	rects := box.Layout(50.0, 0.0)
+	test.T(t, rects[0], Rect{0.0, 10.0, 24.0, 10.0})
+	test.T(t, rects[1], Rect{26.0, 15.0, 24.0, 5.0})
+	test.T(t, rects[2], Rect{0.0, 0.0, 50.0, 8.0})
+
+	w, h := box.Size(50.0)
+	test.Float(t, w, 50.0)
+	test.Float(t, h, 20.0)
+}
+
+func TestFlexBoxColumn(t *testing.T) {
+	inner := NewFlexBox(FlexRow)
+	inner.Add(flexBlock(10.0, 10.0), 1.0, 0.0)
+	inner.Add(flexBlock(10.0, 10.0), 1.0, 0.0)
+
+	box := NewFlexBox(FlexColumn)
+	box.Gap = 5.0
+	box.Add(flexBlock(40.0, 10.0), 0.0, 0.0)
+	box.Add(inner, 1.0, 0.0)
+	rects := box.Layout(80.0, 100.0)
+	test.T(t, rects[0], Rect{0.0, 90.0, 80.0, 10.0})
+	test.T(t, rects[1], Rect{0.0, 0.0, 80.0, 85.0})
+	test.T(t, inner.Layout(rects[1].W, rects[1].H)[1], Rect{40.0, 0.0, 40.0, 85.0})
+
+	box.Align = Right
+	rects = box.Layout(80.0, 0.0)
+	test.T(t, rects[0], Rect{40.0, 15.0, 40.0, 10.0})
+	test.T(t, rects[1], Rect{60.0, 0.0, 20.0, 10.0})
+}
+
+func TestFlexBoxDraw(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	sub := New(10.0, 5.0)
+	NewContext(sub).DrawPath(0.0, 0.0, Rectangle(10.0, 5.0))
+
+	box := NewFlexBox(FlexRow)
+	box.Add(NewFlexCanvas(sub), 0.0, 0.0).Basis = 20.0
+	box.Add(NewFlexText(NewRichText().Add(face, "text"), Left, Top), 1.0, 0.0)
+	w, h := box.Size(0.0)
+	test.Float(t, w, 20.0+face.TextWidth("text"))
+	test.Float(t, h, face.Metrics().LineHeight)
+
+	c := New(w, h)
+	box.Draw(NewContext(c), Rect{0.0, 0.0, w, h})
+	test.T(t, len(c.layers), 2)
+	test.T(t, c.layers[0].m, Identity.Translate(0.0, (h-10.0)/2.0).Scale(2.0, 2.0))
+	test.T(t, c.layers[1].m, Identity.Translate(20.0, h))
+}
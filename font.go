@@ -1,13 +1,22 @@
 package canvas
 
 import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"unicode"
 	"unicode/utf8"
 
 	canvasFont "github.com/tdewolff/canvas/font"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
 )
 
 // TypographicOptions are the options that can be enabled to make typographic or ligature substitutions automatically.
@@ -24,26 +33,80 @@ const (
 
 // Font defines a font of type TTF or OTF which which a FontFace can be generated for use in text drawing operations.
 type Font struct {
-	// TODO: extend to fully read in sfnt data and read liga tables, generate Raw font data (base on used glyphs), etc
+	// TODO: extend to fully read in sfnt data, generate Raw font data (base on used glyphs), etc
 	name     string
 	mimetype string
 	raw      []byte
 	sfnt     *sfnt.Font
+	style    FontStyle                   // of the weight class and slant of the OS/2 or head table
+	kerning  *canvasFont.Kerning         // nil without kerning in the GPOS table
+	shaper   *canvasFont.Shaper          // nil if the layout tables are broken
+	vertical *canvasFont.VerticalMetrics // nil without vertical metrics
+	cff      *canvasFont.CFF             // nil without CFF outlines
+
+	svgGlyphs    *canvasFont.SVGGlyphs    // nil without an SVG table
+	colorGlyphs  *canvasFont.ColorGlyphs  // nil without a COLR table
+	colorBitmaps *canvasFont.ColorBitmaps // nil without an sbix or CBDT table
+	colorMu      sync.Mutex
+	colorCache   map[sfnt.GlyphIndex]*colorGlyph
+
+	axes      []FontAxis // nil for static fonts
+	instances []FontInstance
 
 	// TODO: use sub/superscript Unicode transformations in ToPath etc. if they exist
-	typography  bool
-	ligatures   []textSubstitution
-	superscript []textSubstitution
-	subscript   []textSubstitution
+	options        TypographicOptions
+	typography     bool
+	rules          TypographicRules
+	features       map[string][]textSubstitution // ligatures of the GSUB table per feature
+	ligatureGlyphs map[rune]sfnt.GlyphIndex      // ligatures without code point, mapped from private use runes
+	ligatures      []textSubstitution
+	superscript    []textSubstitution
+	subscript      []textSubstitution
+
+	substitute      GlyphSubstitution
+	substituteIndex GlyphIndexSubstitution
+}
+
+// FontAxis is a variation axis of a variable font, such as wght for the weight, with its range of values.
+type FontAxis struct {
+	Tag, Name         string
+	Min, Default, Max float64
+	Hidden            bool // should not be shown in user interfaces
+}
+
+// FontInstance is a named instance of a variable font, such as Bold, with the values of its axes.
+type FontInstance struct {
+	Name string
+	Axes map[string]float64
 }
 
+// GlyphSubstitution is a callback that replaces a rune given its neighbouring runes, which are zero at the start or end of the string. It returns the rune to use instead, or r to leave it unchanged. For example, map a hyphen to a minus sign in numeric contexts.
+type GlyphSubstitution func(prev, r, next rune) rune
+
+// GlyphIndexSubstitution is a callback that remaps the glyph index of a rune, eg. to swap in alternate glyphs that have no Unicode code point. It returns the glyph index to use instead, or index to leave it unchanged.
+type GlyphIndexSubstitution func(r rune, index uint16) uint16
+
 func parseFont(name string, b []byte) (*Font, error) {
 	mimetype, err := canvasFont.Mimetype(b)
 	if err != nil {
 		return nil, err
 	}
 
-	sfntFont, err := canvasFont.ParseFont(b)
+	sfntBytes, sfntMimetype, err := canvasFont.ToSFNT(b)
+	if err != nil {
+		return nil, err
+	}
+	if mimetype == "font/collection" {
+		// the first font of a collection is used by itself
+		b, mimetype = sfntBytes, sfntMimetype
+	}
+	if outlined, err := canvasFont.OutlineBitmaps(sfntBytes); err != nil {
+		return nil, err
+	} else if outlined != nil {
+		// bitmap-only fonts are used with outlines of their bitmaps
+		sfntBytes, b, mimetype = outlined, outlined, "font/truetype"
+	}
+	sfntFont, err := canvasFont.ParseSFNT(sfntBytes)
 	if err != nil {
 		return nil, err
 	}
@@ -53,13 +116,80 @@ func parseFont(name string, b []byte) (*Font, error) {
 		mimetype: mimetype,
 		raw:      b,
 		sfnt:     (*sfnt.Font)(sfntFont),
+		style:    parseFontStyle(sfntBytes),
 	}
+	f.rules = DefaultTypographicRules
 	f.superscript = f.supportedSubstitutions(superscriptSubstitutes)
 	f.subscript = f.supportedSubstitutions(subscriptSubstitutes)
+	f.parseLigatures(sfntBytes)
+	if kerning, err := canvasFont.ParseKerning(sfntBytes); err == nil {
+		f.kerning = kerning // ignore broken GPOS tables
+	}
+	if shaper, err := canvasFont.ParseShaper(sfntBytes); err == nil {
+		f.shaper = shaper // ignore broken layout tables
+	}
+	if cff, err := canvasFont.ParseCFF(sfntBytes); err == nil {
+		f.cff = cff // ignore broken CFF tables, which sfnt may still read
+	}
+	if vertical, err := canvasFont.ParseVerticalMetrics(sfntBytes); err == nil {
+		f.vertical = vertical // ignore broken vhea and vmtx tables
+	}
+	if svgGlyphs, err := canvasFont.ParseSVGGlyphs(sfntBytes); err == nil {
+		f.svgGlyphs = svgGlyphs // ignore broken SVG tables
+	}
+	if colorGlyphs, err := canvasFont.ParseColorGlyphs(sfntBytes); err == nil {
+		f.colorGlyphs = colorGlyphs // ignore broken COLR and CPAL tables
+	}
+	if colorBitmaps, err := canvasFont.ParseColorBitmaps(sfntBytes); err == nil {
+		f.colorBitmaps = colorBitmaps // ignore broken sbix, CBLC, and CBDT tables
+	}
+	if axes, instances, err := canvasFont.ParseVariationAxes(sfntBytes); err == nil {
+		f.parseVariationAxes(axes, instances) // ignore broken fvar tables
+	}
 	f.Use(0)
 	return f, nil
 }
 
+// parseFontStyle returns the style of an SFNT font from the weight class and the italic and oblique flags of the OS/2 table, or from the bold and italic flags of the head table if the font has no OS/2 table.
+func parseFontStyle(b []byte) FontStyle {
+	if os2, err := canvasFont.SFNTTable(b, "OS/2"); err == nil && 64 <= len(os2) {
+		style := FontRegular
+		if weight := binary.BigEndian.Uint16(os2[4:]); weight != 0 {
+			style = styleOfWeight(int(weight))
+		}
+		if fsSelection := binary.BigEndian.Uint16(os2[62:]); fsSelection&0x0201 != 0 {
+			style |= FontItalic
+		}
+		return style
+	}
+	style := FontRegular
+	if head, err := canvasFont.SFNTTable(b, "head"); err == nil && 46 <= len(head) {
+		macStyle := binary.BigEndian.Uint16(head[44:])
+		if macStyle&0x0001 != 0 {
+			style = FontBold
+		}
+		if macStyle&0x0002 != 0 {
+			style |= FontItalic
+		}
+	}
+	return style
+}
+
+func (f *Font) parseVariationAxes(axes []canvasFont.VariationAxis, instances []canvasFont.NamedInstance) {
+	buffer := &sfnt.Buffer{}
+	for _, axis := range axes {
+		name, err := f.sfnt.Name(buffer, sfnt.NameID(axis.NameID))
+		if err != nil || name == "" {
+			name = axis.Tag
+		}
+		f.axes = append(f.axes, FontAxis{axis.Tag, name, axis.Min, axis.Default, axis.Max, axis.Hidden})
+	}
+	for _, instance := range instances {
+		name, _ := f.sfnt.Name(buffer, sfnt.NameID(instance.NameID))
+		f.instances = append(f.instances, FontInstance{name, instance.Coordinates})
+	}
+}
+
 // Name returns the name of the font.
 func (f *Font) Name() string {
 	return f.name
@@ -111,7 +241,7 @@ func (f *Font) toIndices(s string) []uint16 {
 	runes := []rune(s)
 	indices := make([]uint16, len(runes))
 	for i, r := range runes {
-		index, err := f.sfnt.GlyphIndex(buffer, r)
+		index, err := f.glyphIndex(buffer, r)
 		if err == nil {
 			indices[i] = uint16(index)
 		}
@@ -119,20 +249,262 @@ func (f *Font) toIndices(s string) []uint16 {
 	return indices
 }
 
+// glyphIndex returns the glyph index for a rune, applying the glyph index substitution if set.
+func (f *Font) glyphIndex(buffer *sfnt.Buffer, r rune) (sfnt.GlyphIndex, error) {
+	index, ok := f.ligatureGlyphs[r]
+	var err error
+	if !ok {
+		index, err = f.sfnt.GlyphIndex(buffer, r)
+	}
+	if err != nil || f.substituteIndex == nil {
+		return index, err
+	}
+	return sfnt.GlyphIndex(f.substituteIndex(r, uint16(index))), nil
+}
+
+// colorGlyph is a glyph of the SVG or COLR table that is drawn by filled paths in their colors, or a glyph of the sbix or CBDT table that is drawn by an image, in font units with the y-axis pointing up.
+type colorGlyph struct {
+	paths      []*Path
+	colors     []color.RGBA
+	foreground []bool // paths filled with the color of the text
+
+	img image.Image
+	m   Matrix // from the pixels of the image
+}
+
+// hasColorGlyphs returns true if the font has an SVG, COLR, sbix, or CBDT table.
+func (f *Font) hasColorGlyphs() bool {
+	return f.svgGlyphs != nil || f.colorGlyphs != nil || f.colorBitmaps != nil
+}
+
+// colorGlyph returns the color glyph for a glyph index from the SVG, COLR, sbix, or CBDT table in that order, or nil if it has none or if it cannot be drawn, in which case the outline of the glyph is used. The documents of the SVG table are drawn and the images of the sbix and CBDT tables are decoded when their glyphs are first used, within the SafeLimits, where images are not drawn in SVG documents.
+func (f *Font) colorGlyph(index sfnt.GlyphIndex) *colorGlyph {
+	if !f.hasColorGlyphs() {
+		return nil
+	}
+	f.colorMu.Lock()
+	defer f.colorMu.Unlock()
+	if glyph, ok := f.colorCache[index]; ok {
+		return glyph
+	}
+
+	var glyph *colorGlyph
+	if f.svgGlyphs != nil {
+		glyph = f.svgGlyph(index)
+	}
+	if glyph == nil && f.colorGlyphs != nil {
+		glyph = f.colrGlyph(index)
+	}
+	if glyph == nil && f.colorBitmaps != nil {
+		glyph = f.bitmapGlyph(index)
+	}
+	if f.colorCache == nil {
+		f.colorCache = map[sfnt.GlyphIndex]*colorGlyph{}
+	}
+	f.colorCache[index] = glyph
+	return glyph
+}
+
+func (f *Font) svgGlyph(index sfnt.GlyphIndex) *colorGlyph {
+	units := float64(f.sfnt.UnitsPerEm())
+	doc, err := f.svgGlyphs.Document(uint16(index))
+	if err != nil || doc == nil {
+		return nil
+	}
+	id := "glyph" + strconv.Itoa(int(index))
+	c, err := readSVGElement(bytes.NewReader(doc), id, Identity.Scale(1.0, -1.0), [2]float64{units, units}, SafeLimits)
+	if err != nil || c == nil {
+		return nil
+	}
+
+	glyph := &colorGlyph{}
+	for _, l := range c.layers {
+		if l.path == nil {
+			continue
+		}
+		p := l.path.Transform(l.m)
+		if l.style.FillColor.A != 0 {
+			glyph.paths = append(glyph.paths, p.Settle(l.style.FillRule))
+			glyph.colors = append(glyph.colors, l.style.FillColor)
+			glyph.foreground = append(glyph.foreground, false)
+		}
+		if l.style.StrokeColor.A != 0 && 0.0 < l.style.StrokeWidth {
+			if 0 < len(l.style.Dashes) {
+				p = p.Dash(l.style.DashOffset, l.style.Dashes...)
+			}
+			glyph.paths = append(glyph.paths, p.Stroke(l.style.StrokeWidth, l.style.StrokeCapper, l.style.StrokeJoiner).Settle(NonZero))
+			glyph.colors = append(glyph.colors, l.style.StrokeColor)
+			glyph.foreground = append(glyph.foreground, false)
+		}
+	}
+	return glyph
+}
+
+func (f *Font) colrGlyph(index sfnt.GlyphIndex) *colorGlyph {
+	layers := f.colorGlyphs.Layers(uint16(index))
+	if layers == nil {
+		return nil
+	}
+
+	palette := f.colorGlyphs.Palette(0)
+	buffer := &sfnt.Buffer{}
+	glyph := &colorGlyph{}
+	for _, layer := range layers {
+		p, err := f.glyphPath(buffer, sfnt.GlyphIndex(layer.GlyphID))
+		if err != nil {
+			return nil
+		}
+		col, foreground := Transparent, layer.PaletteIndex == canvasFont.ForegroundColor
+		if !foreground {
+			if len(palette) <= int(layer.PaletteIndex) {
+				return nil
+			}
+			col = color.RGBAModel.Convert(palette[layer.PaletteIndex]).(color.RGBA)
+		}
+		glyph.paths = append(glyph.paths, p)
+		glyph.colors = append(glyph.colors, col)
+		glyph.foreground = append(glyph.foreground, foreground)
+	}
+	return glyph
+}
+
+func (f *Font) bitmapGlyph(index sfnt.GlyphIndex) *colorGlyph {
+	bitmap, ok := f.colorBitmaps.Bitmap(uint16(index))
+	if !ok || bitmap.PPEM == 0 {
+		return nil
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(bitmap.Data))
+	if err != nil || int64(SafeLimits.MaxPixels) < int64(config.Width)*int64(config.Height) {
+		return nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(bitmap.Data))
+	if err != nil {
+		return nil
+	}
+	scale := float64(f.sfnt.UnitsPerEm()) / float64(bitmap.PPEM)
+	return &colorGlyph{
+		img: img,
+		m:   Identity.Scale(scale, scale).Translate(float64(bitmap.X), float64(bitmap.Y)),
+	}
+}
+
+// glyphPath returns the outline of a glyph in font units with the y-axis pointing up.
+func (f *Font) glyphPath(buffer *sfnt.Buffer, index sfnt.GlyphIndex) (*Path, error) {
+	segments, err := f.loadGlyph(buffer, index, toI26_6(float64(f.sfnt.UnitsPerEm())))
+	if err != nil {
+		return nil, err
+	}
+	p := &Path{}
+	for _, segment := range segments {
+		switch segment.Op {
+		case sfnt.SegmentOpMoveTo:
+			if !p.Empty() {
+				p.Close()
+			}
+			p.MoveTo(fromI26_6(segment.Args[0].X), -fromI26_6(segment.Args[0].Y))
+		case sfnt.SegmentOpLineTo:
+			p.LineTo(fromI26_6(segment.Args[0].X), -fromI26_6(segment.Args[0].Y))
+		case sfnt.SegmentOpQuadTo:
+			p.QuadTo(fromI26_6(segment.Args[0].X), -fromI26_6(segment.Args[0].Y), fromI26_6(segment.Args[1].X), -fromI26_6(segment.Args[1].Y))
+		case sfnt.SegmentOpCubeTo:
+			p.CubeTo(fromI26_6(segment.Args[0].X), -fromI26_6(segment.Args[0].Y), fromI26_6(segment.Args[1].X), -fromI26_6(segment.Args[1].Y), fromI26_6(segment.Args[2].X), -fromI26_6(segment.Args[2].Y))
+		}
+	}
+	if !p.Empty() {
+		p.Close()
+	}
+	return p, nil
+}
+
+// loadGlyph returns the outline of a glyph at the size of ppem with the y-axis pointing down, as sfnt.LoadGlyph does. CFF outlines are interpreted from the CFF table, as sfnt does not support all of its charstring operators.
+func (f *Font) loadGlyph(buffer *sfnt.Buffer, index sfnt.GlyphIndex, ppem fixed.Int26_6) ([]sfnt.Segment, error) {
+	if f.cff == nil {
+		return f.sfnt.LoadGlyph(buffer, index, ppem, nil)
+	}
+	outline, err := f.cff.Glyph(uint16(index))
+	if err != nil {
+		return nil, err
+	}
+	scale := float64(ppem) / float64(f.sfnt.UnitsPerEm())
+	segments := make([]sfnt.Segment, len(outline))
+	for i, s := range outline {
+		switch s.Op {
+		case canvasFont.SegmentMoveTo:
+			segments[i].Op = sfnt.SegmentOpMoveTo
+		case canvasFont.SegmentLineTo:
+			segments[i].Op = sfnt.SegmentOpLineTo
+		case canvasFont.SegmentCubeTo:
+			segments[i].Op = sfnt.SegmentOpCubeTo
+		}
+		for j, arg := range s.Args {
+			segments[i].Args[j] = fixed.Point26_6{
+				X: fixed.Int26_6(math.Round(arg[0] * scale)),
+				Y: fixed.Int26_6(math.Round(-arg[1] * scale)),
+			}
+		}
+	}
+	return segments, nil
+}
+
+// kern returns the kerning between two glyphs at the size of ppem, from the GPOS table if it has kerning, or from the kern table otherwise. It is rounded to whole units of ppem for full hinting.
+func (f *Font) kern(buffer *sfnt.Buffer, left, right sfnt.GlyphIndex, ppem fixed.Int26_6, hinting font.Hinting) (fixed.Int26_6, error) {
+	if f.kerning == nil {
+		return f.sfnt.Kern(buffer, left, right, ppem, hinting)
+	}
+
+	kern := f.scaleUnits(int32(f.kerning.Kern(uint16(left), uint16(right))), ppem)
+	if hinting == font.HintingFull {
+		kern = (kern + 32) &^ 63
+	}
+	return kern, nil
+}
+
+// scaleUnits scales and rounds a distance in font units to the size of ppem as sfnt does.
+func (f *Font) scaleUnits(v int32, ppem fixed.Int26_6) fixed.Int26_6 {
+	units := int64(f.sfnt.UnitsPerEm())
+	x := int64(v) * int64(ppem)
+	if 0 <= x {
+		x += units / 2
+	} else {
+		x -= units / 2
+	}
+	return fixed.Int26_6(x / units)
+}
+
+// SetSubstitution sets a callback that replaces runes when text is added, after the typographic substitutions have been applied. Pass nil to remove it.
+func (f *Font) SetSubstitution(substitute GlyphSubstitution) {
+	f.substitute = substitute
+}
+
+// SetGlyphIndexSubstitution sets a callback that remaps glyph indices when glyphs are measured and drawn. Pass nil to remove it. Note that SVG text output refers to characters and not glyphs and is thus not affected, use Text.ToPaths instead.
+func (f *Font) SetGlyphIndexSubstitution(substitute GlyphIndexSubstitution) {
+	f.substituteIndex = substitute
+}
+
+func (f *Font) substituteGlyphs(s string) string {
+	if f.substitute == nil {
+		return s
+	}
+	runes := []rune(s)
+	dst := make([]rune, len(runes))
+	for i, r := range runes {
+		var prev, next rune
+		if 0 < i {
+			prev = runes[i-1]
+		}
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		dst[i] = f.substitute(prev, r, next)
+	}
+	return string(dst)
+}
+
 type textSubstitution struct {
 	src string
 	dst rune
 }
 
-// TODO: read from liga tables in OpenType (clig, dlig, hlig) with rlig default enabled
-var commonLigatures = []textSubstitution{
-	{"ffi", '\uFB03'},
-	{"ffl", '\uFB04'},
-	{"ff", '\uFB00'},
-	{"fi", '\uFB01'},
-	{"fl", '\uFB02'},
-}
-
 var superscriptSubstitutes = []textSubstitution{
 	{"0", '\u2070'},
 	{"i", '\u2071'},
@@ -193,16 +565,134 @@ func (f *Font) supportedSubstitutions(substitutions []textSubstitution) []textSu
 	return supported
 }
 
-// Use enables typographic options on the font such as ligatures.
+// ligatureRunes is the first private use rune to which ligature glyphs without a code point are mapped.
+const ligatureRunes = '\U000F0000'
+
+// parseLigatures reads the ligature substitutions of the GSUB table of the font for each feature. Ligatures of glyphs without a code point are skipped, and ligature glyphs without a code point are mapped from private use runes.
+func (f *Font) parseLigatures(b []byte) {
+	f.features = map[string][]textSubstitution{}
+	f.ligatureGlyphs = map[rune]sfnt.GlyphIndex{}
+	features, err := canvasFont.ParseLigatures(b)
+	if err != nil || len(features) == 0 {
+		return // ignore broken GSUB tables
+	}
+
+	// reverse mapping of the glyphs of the basic multilingual plane
+	buffer := &sfnt.Buffer{}
+	runes := map[uint16]rune{}
+	for r := rune(0xFFFF); 0 < r; r-- {
+		if 0xD800 <= r && r < 0xE000 {
+			continue // surrogates
+		} else if index, err := f.sfnt.GlyphIndex(buffer, r); err == nil && index != 0 {
+			runes[uint16(index)] = r // prefer the lowest rune
+		}
+	}
+
+	tags := []string{}
+	for tag := range features {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags) // deterministic private use runes
+
+	private := rune(ligatureRunes)
+	for _, tag := range tags {
+		substitutions := []textSubstitution{}
+	Ligatures:
+		for _, ligature := range features[tag] {
+			src := make([]rune, len(ligature.Components))
+			for i, component := range ligature.Components {
+				r, ok := runes[component]
+				if !ok {
+					continue Ligatures
+				}
+				src[i] = r
+			}
+			dst, ok := runes[ligature.Glyph]
+			if !ok {
+				for index, err := f.sfnt.GlyphIndex(buffer, private); err == nil && index != 0; index, err = f.sfnt.GlyphIndex(buffer, private) {
+					private++ // used by the font
+				}
+				dst = private
+				runes[ligature.Glyph] = dst
+				f.ligatureGlyphs[dst] = sfnt.GlyphIndex(ligature.Glyph)
+				private++
+			}
+			substitutions = append(substitutions, textSubstitution{string(src), dst})
+		}
+		f.features[tag] = substitutions
+	}
+}
+
+// Use enables typographic options on the font such as ligatures. Ligatures are read from the GSUB table of the font, where required ligatures (rlig) are used unless NoRequiredLigatures is set, CommonLigatures uses the standard and contextual ligatures (liga, clig), DiscretionaryLigatures uses dlig, and HistoricalLigatures uses hlig. Contextual substitutions of these features are applied when the text is shaped, see FontFace.Shape, and other features are enabled per font face by FontFace.WithFeatures.
 func (f *Font) Use(options TypographicOptions) {
+	f.options = options
 	if options&NoTypography == 0 {
 		f.typography = true
 	}
 
 	f.ligatures = []textSubstitution{}
+	if options&NoRequiredLigatures == 0 {
+		f.ligatures = append(f.ligatures, f.features["rlig"]...)
+	}
 	if options&CommonLigatures != 0 {
-		f.ligatures = append(f.ligatures, f.supportedSubstitutions(commonLigatures)...)
+		f.ligatures = append(f.ligatures, f.features["liga"]...)
+		f.ligatures = append(f.ligatures, f.features["clig"]...)
+	}
+	if options&DiscretionaryLigatures != 0 {
+		f.ligatures = append(f.ligatures, f.features["dlig"]...)
+	}
+	if options&HistoricalLigatures != 0 {
+		f.ligatures = append(f.ligatures, f.features["hlig"]...)
+	}
+
+	// substitute longer ligatures first, since they are applied to the whole string in order
+	sort.SliceStable(f.ligatures, func(i, j int) bool {
+		return len(f.ligatures[j].src) < len(f.ligatures[i].src)
+	})
+}
+
+// Features returns the tags of the OpenType features of the GSUB and GPOS tables of the font in sorted order, such as "smcp" for small capitals or "ss01" for the first stylistic set, which are enabled by FontFace.WithFeatures. It returns nil if the font has no layout tables.
+func (f *Font) Features() []string {
+	if f.shaper == nil {
+		return nil
 	}
+	return f.shaper.Features()
+}
+
+// Axes returns the variation axes of a variable font, or nil for static fonts.
+func (f *Font) Axes() []FontAxis {
+	return f.axes
+}
+
+// Instances returns the named instances of a variable font, such as Bold or Condensed, whose axes can be passed to Variation.
+func (f *Font) Instances() []FontInstance {
+	return f.instances
+}
+
+// Variation returns the static font of a variable font for the given values of its axes, such as wght for the weight or wdth for the width, where axes that are not given have their default value. The returned font has the same typographic options, rules, and substitutions. Only TrueType outlines are varied, not CFF2 outlines.
+func (f *Font) Variation(axes map[string]float64) (*Font, error) {
+	sfntBytes, _, err := canvasFont.ToSFNT(f.raw)
+	if err != nil {
+		return nil, err
+	}
+	b, err := canvasFont.Instantiate(sfntBytes, axes)
+	if err != nil {
+		return nil, err
+	}
+	font, err := parseFont(f.name, b)
+	if err != nil {
+		return nil, err
+	}
+	font.Use(f.options)
+	font.rules = f.rules
+	font.substitute = f.substitute
+	font.substituteIndex = f.substituteIndex
+	return font, nil
+}
+
+// SetTypographicRules sets the typographic substitution rules used when typography is enabled, the default is DefaultTypographicRules.
+func (f *Font) SetTypographicRules(rules TypographicRules) {
+	f.rules = rules
 }
 
 func (f *Font) substituteLigatures(s string) string {
@@ -212,86 +702,34 @@ func (f *Font) substituteLigatures(s string) string {
 	return s
 }
 
-func (f *Font) substituteTypography(s string, inSingleQuote, inDoubleQuote bool) (string, bool, bool) {
-	// TODO: typography substitution should maybe not be part of this package (or of Font)
-	if f.typography {
-		var rPrev, r rune
-		var i, size int
-		for {
-			rPrev = r
-			i += size
-			if i >= len(s) {
-				break
-			}
-
-			r, size = utf8.DecodeRuneInString(s[i:])
-			if i+2 < len(s) && s[i] == '.' && s[i+1] == '.' && s[i+2] == '.' {
-				s, size = stringReplace(s, i, 3, "\u2026") // ellipsis
-				continue
-			} else if i+4 < len(s) && s[i] == '.' && s[i+1] == ' ' && s[i+2] == '.' && s[i+3] == ' ' && s[i+4] == '.' {
-				s, size = stringReplace(s, i, 5, "\u2026") // ellipsis
-				continue
-			} else if i+2 < len(s) && s[i] == '-' && s[i+1] == '-' && s[i+2] == '-' {
-				s, size = stringReplace(s, i, 3, "\u2014") // em-dash
-				continue
-			} else if i+1 < len(s) && s[i] == '-' && s[i+1] == '-' {
-				s, size = stringReplace(s, i, 2, "\u2013") // en-dash
-				continue
-			} else if i+2 < len(s) && s[i] == '(' && s[i+1] == 'c' && s[i+2] == ')' {
-				s, size = stringReplace(s, i, 3, "\u00A9") // copyright
-				continue
-			} else if i+2 < len(s) && s[i] == '(' && s[i+1] == 'r' && s[i+2] == ')' {
-				s, size = stringReplace(s, i, 3, "\u00AE") // registered
-				continue
-			} else if i+3 < len(s) && s[i] == '(' && s[i+1] == 't' && s[i+2] == 'm' && s[i+3] == ')' {
-				s, size = stringReplace(s, i, 4, "\u2122") // trademark
-				continue
-			}
-
-			// quotes
-			if s[i] == '"' || s[i] == '\'' {
-				var rNext rune
-				if i+1 < len(s) {
-					rNext, _ = utf8.DecodeRuneInString(s[i+1:])
-				}
-				if s[i] == '"' {
-					s, size = quoteReplace(s, i, rPrev, r, rNext, &inDoubleQuote)
-					continue
-				} else {
-					s, size = quoteReplace(s, i, rPrev, r, rNext, &inSingleQuote)
-					continue
-				}
-			}
-
-			// fractions
-			if i+2 < len(s) && s[i+1] == '/' && isWordBoundary(rPrev) && rPrev != '/' {
-				var rNext rune
-				if i+3 < len(s) {
-					rNext, _ = utf8.DecodeRuneInString(s[i+3:])
-				}
-				if isWordBoundary(rNext) && rNext != '/' {
-					if s[i] == '1' && s[i+2] == '2' {
-						s, size = stringReplace(s, i, 3, "\u00BD") // 1/2
-						continue
-					} else if s[i] == '1' && s[i+2] == '4' {
-						s, size = stringReplace(s, i, 3, "\u00BC") // 1/4
-						continue
-					} else if s[i] == '3' && s[i+2] == '4' {
-						s, size = stringReplace(s, i, 3, "\u00BE") // 3/4
-						continue
-					} else if s[i] == '+' && s[i+2] == '-' {
-						s, size = stringReplace(s, i, 3, "\u00B1") // +/-
-						continue
-					}
-				}
+// expandLigatureGlyphs replaces the ligatures that have no code point by their components, for output formats that refer to characters.
+func (f *Font) expandLigatureGlyphs(s string) string {
+	if len(f.ligatureGlyphs) == 0 {
+		return s
+	}
+	for _, substitutions := range f.features {
+		for _, stn := range substitutions {
+			if _, ok := f.ligatureGlyphs[stn.dst]; ok {
+				s = strings.ReplaceAll(s, string(stn.dst), stn.src)
 			}
 		}
 	}
-	return s, inSingleQuote, inDoubleQuote
+	return s
+}
+
+func (f *Font) substituteTypography(s string, ctx *TypographicContext) string {
+	// TODO: typography substitution should maybe not be part of this package (or of Font)
+	if f.typography {
+		s = substituteTypography(s, f.rules, true, ctx)
+	} else if 0 < len(s) {
+		ctx.prev, _ = utf8.DecodeLastRuneInString(s)
+	}
+	s = f.substituteGlyphs(s)
+	return s
 }
 
 // from https://github.com/russross/blackfriday/blob/11635eb403ff09dbc3a6b5a007ab5ab09151c229/smartypants.go#L42
-func quoteReplace(s string, i int, prev, quote, next rune, isOpen *bool) (string, int) {
+func quoteReplace(s string, i int, prev, quote, next rune, isOpen *bool, prevScript, nextScript textScript) (string, int) {
 	switch {
 	case prev == 0 && next == 0:
 		// context is not any help here, so toggle
@@ -343,16 +781,22 @@ func quoteReplace(s string, i int, prev, quote, next rune, isOpen *bool) (string
 		*isOpen = false
 	}
 
+	// opening quotes take the script of the quoted text, closing quotes of the preceding text
+	script := prevScript
+	if *isOpen {
+		script = nextScript
+	}
+	marks := quoteMarks[script]
 	if quote == '"' {
 		if *isOpen {
-			return stringReplace(s, i, 1, "\u201C")
+			return stringReplace(s, i, 1, marks[0])
 		}
-		return stringReplace(s, i, 1, "\u201D")
+		return stringReplace(s, i, 1, marks[1])
 	} else if quote == '\'' {
 		if *isOpen {
-			return stringReplace(s, i, 1, "\u2018")
+			return stringReplace(s, i, 1, marks[2])
 		}
-		return stringReplace(s, i, 1, "\u2019")
+		return stringReplace(s, i, 1, marks[3])
 	}
 	return s, 1
 }
@@ -0,0 +1,438 @@
+package font
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// bitmapGlyph is a glyph of a bitmap strike, with its pixels in rows from the top.
+type bitmapGlyph struct {
+	width, height      int
+	bearingX, bearingY int // from the origin to the left and top of the bitmap in pixels
+	pixels             []bool
+}
+
+// OutlineBitmaps returns the SFNT font (TTF or OTF) with TrueType outlines for the glyphs of its largest bitmap strike in the EBLC and EBDT tables, where each pixel becomes a square of the outline, so that bitmap-only fonts can be drawn, embedded, and rasterized at any size. It returns nil if the font has outlines or has no bitmap strikes. Composite bitmaps (image formats 8 and 9) are not supported.
+// See https://learn.microsoft.com/en-us/typography/opentype/spec/eblc
+func OutlineBitmaps(b []byte) ([]byte, error) {
+	glyf, err := SFNTTable(b, "glyf")
+	if err != nil {
+		return nil, err
+	}
+	cff, err := SFNTTable(b, "CFF ")
+	if err != nil {
+		return nil, err
+	} else if 0 < len(glyf) || cff != nil {
+		return nil, nil
+	}
+	eblc, err := SFNTTable(b, "EBLC")
+	if err != nil {
+		return nil, err
+	}
+	ebdt, err := SFNTTable(b, "EBDT")
+	if err != nil {
+		return nil, err
+	} else if eblc == nil || ebdt == nil {
+		return nil, nil
+	}
+	head, err := SFNTTable(b, "head")
+	if err != nil {
+		return nil, err
+	}
+	maxp, err := SFNTTable(b, "maxp")
+	if err != nil {
+		return nil, err
+	} else if len(head) < 54 || len(maxp) < 6 {
+		return nil, ErrInvalidFontData
+	}
+	unitsPerEm := float64(binary.BigEndian.Uint16(head[18:]))
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+
+	ppemX, ppemY, glyphs, err := parseBitmapStrike(eblc, ebdt)
+	if err != nil {
+		return nil, err
+	} else if glyphs == nil {
+		return nil, nil
+	}
+
+	// glyph outlines in font units
+	scaleX, scaleY := unitsPerEm/float64(ppemX), unitsPerEm/float64(ppemY)
+	wGlyf := newBinaryWriter([]byte{})
+	wLoca := newBinaryWriter(make([]byte, 0, 4*(numGlyphs+1)))
+	maxPoints, maxContours := 0, 0
+	for i := 0; i < numGlyphs; i++ {
+		wLoca.WriteUint32(wGlyf.Len())
+		glyph, ok := glyphs[uint16(i)]
+		if !ok {
+			continue
+		}
+		contours := glyph.contours()
+		if len(contours) == 0 {
+			continue
+		}
+
+		xMin, yMin, xMax, yMax := math.MaxInt16, math.MaxInt16, math.MinInt16, math.MinInt16
+		numPoints := 0
+		for _, contour := range contours {
+			for j := range contour {
+				contour[j][0] = int(math.Round(float64(contour[j][0]) * scaleX))
+				contour[j][1] = int(math.Round(float64(contour[j][1]) * scaleY))
+				xMin, xMax = minInt(xMin, contour[j][0]), maxInt(xMax, contour[j][0])
+				yMin, yMax = minInt(yMin, contour[j][1]), maxInt(yMax, contour[j][1])
+			}
+			numPoints += len(contour)
+		}
+		if math.MaxUint16 < numPoints || xMin < math.MinInt16 || yMin < math.MinInt16 || math.MaxInt16 < xMax || math.MaxInt16 < yMax {
+			return nil, ErrInvalidFontData
+		}
+		maxPoints, maxContours = maxInt(maxPoints, numPoints), maxInt(maxContours, len(contours))
+
+		wGlyf.WriteInt16(int16(len(contours)))
+		wGlyf.WriteInt16(int16(xMin))
+		wGlyf.WriteInt16(int16(yMin))
+		wGlyf.WriteInt16(int16(xMax))
+		wGlyf.WriteInt16(int16(yMax))
+		end := -1
+		for _, contour := range contours {
+			end += len(contour)
+			wGlyf.WriteUint16(uint16(end))
+		}
+		wGlyf.WriteUint16(0) // instructionLength
+		for j := 0; j < numPoints; j++ {
+			wGlyf.WriteByte(0x01) // ON_CURVE_POINT, with coordinates of two bytes
+		}
+		for k := 0; k < 2; k++ {
+			prev := 0
+			for _, contour := range contours {
+				for _, point := range contour {
+					wGlyf.WriteInt16(int16(point[k] - prev))
+					prev = point[k]
+				}
+			}
+		}
+		for wGlyf.Len()%4 != 0 {
+			wGlyf.WriteByte(0)
+		}
+	}
+	wLoca.WriteUint32(wGlyf.Len())
+
+	head = append([]byte{}, head...)
+	binary.BigEndian.PutUint16(head[50:], 1) // indexToLocFormat
+	maxp = append(maxp[:6:6], make([]byte, 26)...)
+	binary.BigEndian.PutUint32(maxp, 0x00010000) // version 1.0 for TrueType outlines
+	binary.BigEndian.PutUint16(maxp[6:], uint16(maxPoints))
+	binary.BigEndian.PutUint16(maxp[8:], uint16(maxContours))
+	binary.BigEndian.PutUint16(maxp[14:], 2) // maxZones
+	return writeSFNT(b, map[string][]byte{
+		"glyf": wGlyf.Bytes(),
+		"loca": wLoca.Bytes(),
+		"head": head,
+		"maxp": maxp,
+	})
+}
+
+// bitmapStrike is a strike of the EBLC or CBLC table, which are the locations of the bitmaps of the glyphs at one resolution.
+type bitmapStrike struct {
+	ppemX, ppemY, bitDepth                           byte
+	indexSubTableArrayOffset, numberOfIndexSubTables uint32
+}
+
+// largestBitmapStrike returns the strike of the EBLC or CBLC table of the given major version with the largest resolution and one of the bit depths, or false if there are none.
+func largestBitmapStrike(eblc []byte, version uint16, bitDepths ...byte) (bitmapStrike, bool, error) {
+	r := newBinaryReader(eblc)
+	majorVersion := r.ReadUint16()
+	_ = r.ReadUint16() // minorVersion
+	numSizes := r.ReadUint32()
+	if r.EOF() || majorVersion != version || r.Len()/48 < numSizes {
+		return bitmapStrike{}, false, ErrInvalidFontData
+	}
+
+	found := false
+	strike := bitmapStrike{}
+	for i := 0; i < int(numSizes); i++ {
+		rSize := readerAt(eblc, 8+48*uint32(i))
+		offset := rSize.ReadUint32()
+		_ = rSize.ReadUint32() // indexTablesSize
+		count := rSize.ReadUint32()
+		_ = rSize.ReadBytes(32) // colorRef, hori, vert, startGlyphIndex, endGlyphIndex
+		x, y, depth := rSize.ReadByte(), rSize.ReadByte(), rSize.ReadByte()
+		supported := false
+		for _, bitDepth := range bitDepths {
+			if depth == bitDepth {
+				supported = true
+			}
+		}
+		if !supported || x == 0 || y == 0 {
+			continue
+		} else if !found || strike.ppemY < y {
+			found = true
+			strike = bitmapStrike{x, y, depth, offset, count}
+		}
+	}
+	return strike, found, nil
+}
+
+// glyphs calls fn for each glyph of the strike with its image format and its data in the EBDT or CBDT table, and with the big glyph metrics of the index subtable for image formats without metrics.
+func (strike bitmapStrike) glyphs(eblc, ebdt []byte, fn func(id, imageFormat uint16, data, metrics []byte) error) error {
+	r := readerAt(eblc, strike.indexSubTableArrayOffset)
+	if r.Len()/8 < strike.numberOfIndexSubTables {
+		return ErrInvalidFontData
+	}
+	for i := 0; i < int(strike.numberOfIndexSubTables); i++ {
+		firstGlyphIndex := r.ReadUint16()
+		lastGlyphIndex := r.ReadUint16()
+		offset := strike.indexSubTableArrayOffset + r.ReadUint32()
+		if lastGlyphIndex < firstGlyphIndex {
+			return ErrInvalidFontData
+		}
+		n := int(lastGlyphIndex-firstGlyphIndex) + 1
+
+		// glyph IDs with their offset and size in EBDT
+		rSub := readerAt(eblc, offset)
+		indexFormat := rSub.ReadUint16()
+		imageFormat := rSub.ReadUint16()
+		imageDataOffset := rSub.ReadUint32()
+		ids := []uint16{}
+		offsets := []uint32{}
+		var metrics []byte // big glyph metrics in EBLC
+		switch indexFormat {
+		case 1, 3:
+			for j := 0; j <= n; j++ {
+				if j < n {
+					ids = append(ids, firstGlyphIndex+uint16(j))
+				}
+				if indexFormat == 1 {
+					offsets = append(offsets, rSub.ReadUint32())
+				} else {
+					offsets = append(offsets, uint32(rSub.ReadUint16()))
+				}
+			}
+		case 2, 5:
+			imageSize := rSub.ReadUint32()
+			metrics = rSub.ReadBytes(8)
+			if indexFormat == 2 {
+				for j := 0; j < n; j++ {
+					ids = append(ids, firstGlyphIndex+uint16(j))
+				}
+			} else {
+				numGlyphs := rSub.ReadUint32()
+				if rSub.Len()/2 < numGlyphs {
+					return ErrInvalidFontData
+				}
+				for j := 0; j < int(numGlyphs); j++ {
+					ids = append(ids, rSub.ReadUint16())
+				}
+			}
+			if uint64(len(ebdt)) < uint64(len(ids))*uint64(imageSize) {
+				return ErrInvalidFontData
+			}
+			for j := 0; j <= len(ids); j++ {
+				offsets = append(offsets, uint32(j)*imageSize)
+			}
+		case 4:
+			numGlyphs := rSub.ReadUint32()
+			if rSub.Len()/4 < numGlyphs {
+				return ErrInvalidFontData
+			}
+			for j := 0; j <= int(numGlyphs); j++ {
+				id := rSub.ReadUint16()
+				if j < int(numGlyphs) {
+					ids = append(ids, id)
+				}
+				offsets = append(offsets, uint32(rSub.ReadUint16()))
+			}
+		default:
+			return ErrInvalidFontData
+		}
+		if rSub.EOF() {
+			return ErrInvalidFontData
+		}
+
+		for j, id := range ids {
+			if offsets[j+1] < offsets[j] {
+				return ErrInvalidFontData
+			} else if offsets[j] == offsets[j+1] {
+				continue // no bitmap
+			}
+			start, end := uint64(imageDataOffset)+uint64(offsets[j]), uint64(imageDataOffset)+uint64(offsets[j+1])
+			if uint64(len(ebdt)) < end {
+				return ErrInvalidFontData
+			}
+			if err := fn(id, imageFormat, ebdt[start:end], metrics); err != nil {
+				return err
+			}
+		}
+	}
+	if r.EOF() {
+		return ErrInvalidFontData
+	}
+	return nil
+}
+
+// parseBitmapStrike returns the resolution and glyphs of the strike with the largest resolution, or nil if there are none.
+func parseBitmapStrike(eblc, ebdt []byte) (int, int, map[uint16]bitmapGlyph, error) {
+	strike, ok, err := largestBitmapStrike(eblc, 2, 1, 2, 4, 8)
+	if err != nil || !ok {
+		return 0, 0, nil, err
+	}
+
+	glyphs := map[uint16]bitmapGlyph{}
+	err = strike.glyphs(eblc, ebdt, func(id, imageFormat uint16, data, metrics []byte) error {
+		glyph, ok, err := parseBitmapGlyph(data, imageFormat, metrics, int(strike.bitDepth))
+		if err != nil {
+			return err
+		} else if ok {
+			glyphs[id] = glyph
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	return int(strike.ppemX), int(strike.ppemY), glyphs, nil
+}
+
+// parseBitmapGlyph parses the glyph metrics and bitmap of an image format of EBDT, where pixels are set if they are at least half of the maximum value for bit depths larger than one. It returns false for unsupported image formats.
+func parseBitmapGlyph(b []byte, imageFormat uint16, metrics []byte, bitDepth int) (bitmapGlyph, bool, error) {
+	r := newBinaryReader(b)
+	switch imageFormat {
+	case 1, 2: // small glyph metrics
+		metrics = r.ReadBytes(5)
+	case 6, 7: // big glyph metrics
+		metrics = r.ReadBytes(8)
+	case 5: // metrics in EBLC
+		if metrics == nil {
+			return bitmapGlyph{}, false, ErrInvalidFontData
+		}
+	default:
+		return bitmapGlyph{}, false, nil
+	}
+	if r.EOF() {
+		return bitmapGlyph{}, false, ErrInvalidFontData
+	}
+	glyph := bitmapGlyph{
+		height:   int(metrics[0]),
+		width:    int(metrics[1]),
+		bearingX: int(int8(metrics[2])),
+		bearingY: int(int8(metrics[3])),
+	}
+
+	byteAligned := imageFormat == 1 || imageFormat == 6
+	rowBits := glyph.width * bitDepth
+	if byteAligned {
+		rowBits = (rowBits + 7) &^ 7
+	}
+	data := r.ReadBytes(r.Len())
+	if len(data)*8 < rowBits*glyph.height {
+		return bitmapGlyph{}, false, ErrInvalidFontData
+	}
+	mask := byte(1<<uint(bitDepth) - 1)
+	glyph.pixels = make([]bool, glyph.width*glyph.height)
+	for y := 0; y < glyph.height; y++ {
+		for x := 0; x < glyph.width; x++ {
+			bit := y*rowBits + x*bitDepth
+			v := data[bit/8] >> uint(8-bitDepth-bit%8) & mask
+			glyph.pixels[y*glyph.width+x] = mask/2 < v
+		}
+	}
+	return glyph, true, nil
+}
+
+func (glyph bitmapGlyph) set(x, y int) bool {
+	return 0 <= x && x < glyph.width && 0 <= y && y < glyph.height && glyph.pixels[y*glyph.width+x]
+}
+
+// contours returns the clockwise outer contours and counter clockwise inner contours of the set pixels, in pixels from the origin with the y-axis pointing up. Contours only touch at their corners and have no collinear points.
+func (glyph bitmapGlyph) contours() [][][2]int {
+	// edges between set and unset pixels by their start point, with the set pixel to the right
+	edges := map[[2]int][][2]int{}
+	addEdge := func(x0, y0, x1, y1 int) {
+		edges[[2]int{x0, y0}] = append(edges[[2]int{x0, y0}], [2]int{x1, y1})
+	}
+	for y := 0; y < glyph.height; y++ {
+		for x := 0; x < glyph.width; x++ {
+			if !glyph.set(x, y) {
+				continue
+			}
+			x0, y1 := glyph.bearingX+x, glyph.bearingY-y
+			x1, y0 := x0+1, y1-1
+			if !glyph.set(x-1, y) {
+				addEdge(x0, y0, x0, y1)
+			}
+			if !glyph.set(x, y-1) {
+				addEdge(x0, y1, x1, y1)
+			}
+			if !glyph.set(x+1, y) {
+				addEdge(x1, y1, x1, y0)
+			}
+			if !glyph.set(x, y+1) {
+				addEdge(x1, y0, x0, y0)
+			}
+		}
+	}
+
+	// start contours at the bottom-left to be deterministic
+	starts := make([][2]int, 0, len(edges))
+	for start := range edges {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool {
+		return starts[i][1] < starts[j][1] || starts[i][1] == starts[j][1] && starts[i][0] < starts[j][0]
+	})
+
+	contours := [][][2]int{}
+	for _, start := range starts {
+		for 0 < len(edges[start]) {
+			contour := [][2]int{start}
+			prev, cur := start, start
+			for {
+				// turn right where two contours touch, so that they stay separate
+				next := edges[cur]
+				k := 0
+				for i, end := range next {
+					dx0, dy0 := cur[0]-prev[0], cur[1]-prev[1]
+					dx1, dy1 := end[0]-cur[0], end[1]-cur[1]
+					if dx0*dy1-dy0*dx1 < 0 {
+						k = i
+					}
+				}
+				end := next[k]
+				edges[cur] = append(next[:k:k], next[k+1:]...)
+				if len(edges[cur]) == 0 {
+					delete(edges, cur)
+				}
+				prev, cur = cur, end
+				if cur == start {
+					break
+				}
+				contour = append(contour, cur)
+			}
+
+			// remove collinear points
+			points := [][2]int{}
+			for i, p := range contour {
+				a, c := contour[(i+len(contour)-1)%len(contour)], contour[(i+1)%len(contour)]
+				if (p[0]-a[0])*(c[1]-p[1])-(p[1]-a[1])*(c[0]-p[0]) != 0 {
+					points = append(points, p)
+				}
+			}
+			contours = append(contours, points)
+		}
+	}
+	return contours
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a < b {
+		return b
+	}
+	return a
+}
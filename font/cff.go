@@ -0,0 +1,914 @@
+package font
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// SegmentOp is the operation of a segment of a glyph outline.
+type SegmentOp int
+
+// SegmentOp values.
+const (
+	SegmentMoveTo SegmentOp = iota
+	SegmentLineTo
+	SegmentCubeTo
+)
+
+// Segment is a segment of a glyph outline in font units with the y-axis pointing up. Args holds the end point for SegmentMoveTo and SegmentLineTo, and the two control points and the end point for SegmentCubeTo.
+type Segment struct {
+	Op   SegmentOp
+	Args [3][2]float64
+}
+
+// CFF are the glyph outlines of the CFF table, which are Type 2 charstrings of PostScript outlines.
+type CFF struct {
+	charStrings [][]byte
+	globalSubrs [][]byte
+	localSubrs  [][][]byte // of each font DICT
+	fdSelect    []uint8    // font DICT of each glyph
+	charset     []uint16   // SID of each glyph, for accented characters of seac
+	matrix      [6]float64 // from charstring space to font units
+}
+
+const (
+	maxCFFStack     = 48 // argument stack of Type 2 charstrings
+	maxCFFSubrDepth = 10
+)
+
+// ParseCFF parses the CFF table of an SFNT font (OTF) with PostScript outlines, including CID-keyed fonts with multiple font DICTs. It returns nil if the font has no CFF table. The charstrings are interpreted by Glyph, where flex, arithmetic, and seac operators are supported. CFF2 tables of variable fonts are not supported.
+// See https://adobe-type-tools.github.io/font-tech-notes/pdfs/5176.CFF.pdf
+func ParseCFF(b []byte) (*CFF, error) {
+	cff, err := SFNTTable(b, "CFF ")
+	if err != nil || cff == nil {
+		return nil, err
+	}
+	head, err := SFNTTable(b, "head")
+	if err != nil {
+		return nil, err
+	} else if len(head) < 20 {
+		return nil, ErrInvalidFontData
+	}
+	unitsPerEm := float64(newBinaryReader(head[18:]).ReadUint16())
+	if unitsPerEm == 0.0 {
+		return nil, ErrInvalidFontData
+	}
+
+	if len(cff) < 4 || cff[0] != 1 {
+		return nil, fmt.Errorf("unsupported CFF version")
+	}
+	hdrSize := uint32(cff[2])
+	_, pos, err := parseCFFIndex(cff, hdrSize) // Name INDEX
+	if err != nil {
+		return nil, err
+	}
+	topDicts, pos, err := parseCFFIndex(cff, pos)
+	if err != nil {
+		return nil, err
+	} else if len(topDicts) != 1 {
+		return nil, ErrInvalidFontData
+	}
+	_, pos, err = parseCFFIndex(cff, pos) // String INDEX
+	if err != nil {
+		return nil, err
+	}
+	globalSubrs, _, err := parseCFFIndex(cff, pos)
+	if err != nil {
+		return nil, err
+	}
+
+	topDict, err := parseCFFDict(topDicts[0])
+	if err != nil {
+		return nil, err
+	} else if charstringType, ok := topDict[1206]; ok && (len(charstringType) != 1 || charstringType[0] != 2) {
+		return nil, fmt.Errorf("unsupported CFF charstring type")
+	}
+	charStringsOffset, ok := topDict[17]
+	if !ok || len(charStringsOffset) != 1 {
+		return nil, ErrInvalidFontData
+	}
+	charStrings, _, err := parseCFFIndex(cff, uint32(charStringsOffset[0]))
+	if err != nil {
+		return nil, err
+	} else if len(charStrings) == 0 {
+		return nil, ErrInvalidFontData
+	}
+
+	outlines := &CFF{
+		charStrings: charStrings,
+		globalSubrs: globalSubrs,
+		matrix:      [6]float64{0.001, 0.0, 0.0, 0.001, 0.0, 0.0},
+	}
+	if matrix, ok := topDict[1207]; ok && len(matrix) == 6 {
+		copy(outlines.matrix[:], matrix)
+	}
+	for i := range outlines.matrix {
+		outlines.matrix[i] *= unitsPerEm
+	}
+
+	if _, ok := topDict[1230]; ok {
+		// CID-keyed font with a font DICT for each group of glyphs
+		fdArrayOffset, ok := topDict[1236]
+		if !ok || len(fdArrayOffset) != 1 {
+			return nil, ErrInvalidFontData
+		}
+		fdArray, _, err := parseCFFIndex(cff, uint32(fdArrayOffset[0]))
+		if err != nil {
+			return nil, err
+		} else if len(fdArray) == 0 || 256 < len(fdArray) {
+			return nil, ErrInvalidFontData
+		}
+		for _, fd := range fdArray {
+			fontDict, err := parseCFFDict(fd)
+			if err != nil {
+				return nil, err
+			}
+			subrs, err := parseCFFPrivate(cff, fontDict)
+			if err != nil {
+				return nil, err
+			}
+			outlines.localSubrs = append(outlines.localSubrs, subrs)
+		}
+		fdSelectOffset, ok := topDict[1237]
+		if !ok || len(fdSelectOffset) != 1 {
+			return nil, ErrInvalidFontData
+		}
+		if outlines.fdSelect, err = parseCFFFDSelect(cff, uint32(fdSelectOffset[0]), len(charStrings), len(fdArray)); err != nil {
+			return nil, err
+		}
+	} else {
+		subrs, err := parseCFFPrivate(cff, topDict)
+		if err != nil {
+			return nil, err
+		}
+		outlines.localSubrs = [][][]byte{subrs}
+		charsetOffset := 0.0
+		if offset, ok := topDict[15]; ok && len(offset) == 1 {
+			charsetOffset = offset[0]
+		}
+		if outlines.charset, err = parseCFFCharset(cff, charsetOffset, len(charStrings)); err != nil {
+			return nil, err
+		}
+	}
+	return outlines, nil
+}
+
+// parseCFFIndex returns the objects of the INDEX at pos and the position after the INDEX.
+func parseCFFIndex(b []byte, pos uint32) ([][]byte, uint32, error) {
+	r := readerAt(b, pos)
+	count := r.ReadUint16()
+	if r.EOF() {
+		return nil, 0, ErrInvalidFontData
+	} else if count == 0 {
+		return [][]byte{}, pos + 2, nil
+	}
+	offSize := r.ReadByte()
+	if offSize < 1 || 4 < offSize {
+		return nil, 0, ErrInvalidFontData
+	}
+	offsets := make([]uint32, count+1)
+	for i := range offsets {
+		for _, c := range r.ReadBytes(uint32(offSize)) {
+			offsets[i] = offsets[i]<<8 | uint32(c)
+		}
+		if offsets[i] == 0 || 0 < i && offsets[i] < offsets[i-1] {
+			return nil, 0, ErrInvalidFontData
+		}
+	}
+	data := pos + r.Pos() - 1 // offsets are relative to the byte before the object data
+	if r.EOF() || uint32(len(b))-data < offsets[count] {
+		return nil, 0, ErrInvalidFontData
+	}
+	objects := make([][]byte, count)
+	for i := range objects {
+		objects[i] = b[data+offsets[i] : data+offsets[i+1]]
+	}
+	return objects, data + offsets[count], nil
+}
+
+// parseCFFDict returns the operands of the operators of a DICT, where two-byte operators are numbered from 1200.
+func parseCFFDict(b []byte) (map[int][]float64, error) {
+	dict := map[int][]float64{}
+	operands := []float64{}
+	for i := 0; i < len(b); {
+		b0 := b[i]
+		if b0 <= 21 {
+			op := int(b0)
+			i++
+			if b0 == 12 {
+				if len(b) <= i {
+					return nil, ErrInvalidFontData
+				}
+				op = 1200 + int(b[i])
+				i++
+			}
+			dict[op] = operands
+			operands = []float64{}
+			continue
+		} else if 48 <= len(operands) {
+			return nil, ErrInvalidFontData
+		}
+
+		var v float64
+		switch {
+		case b0 == 28 && i+2 < len(b):
+			v = float64(int16(uint16(b[i+1])<<8 | uint16(b[i+2])))
+			i += 3
+		case b0 == 29 && i+4 < len(b):
+			v = float64(int32(uint32(b[i+1])<<24 | uint32(b[i+2])<<16 | uint32(b[i+3])<<8 | uint32(b[i+4])))
+			i += 5
+		case b0 == 30:
+			// real number of nibbles
+			s := []byte{}
+			i++
+			for end := false; !end; i++ {
+				if len(b) <= i {
+					return nil, ErrInvalidFontData
+				}
+				for _, nibble := range []byte{b[i] >> 4, b[i] & 0x0F} {
+					if nibble <= 9 {
+						s = append(s, '0'+nibble)
+					} else if nibble == 0xA {
+						s = append(s, '.')
+					} else if nibble == 0xB {
+						s = append(s, 'E')
+					} else if nibble == 0xC {
+						s = append(s, 'E', '-')
+					} else if nibble == 0xE {
+						s = append(s, '-')
+					} else if nibble == 0xF {
+						end = true
+						break
+					}
+				}
+			}
+			var err error
+			if v, err = strconv.ParseFloat(string(s), 64); err != nil {
+				return nil, ErrInvalidFontData
+			}
+		case 32 <= b0 && b0 <= 246:
+			v = float64(int(b0) - 139)
+			i++
+		case 247 <= b0 && b0 <= 250 && i+1 < len(b):
+			v = float64((int(b0)-247)*256 + int(b[i+1]) + 108)
+			i += 2
+		case 251 <= b0 && b0 <= 254 && i+1 < len(b):
+			v = float64(-(int(b0)-251)*256 - int(b[i+1]) - 108)
+			i += 2
+		default:
+			return nil, ErrInvalidFontData
+		}
+		operands = append(operands, v)
+	}
+	return dict, nil
+}
+
+// parseCFFPrivate returns the local subroutines of the Private DICT of a top or font DICT.
+func parseCFFPrivate(b []byte, dict map[int][]float64) ([][]byte, error) {
+	private, ok := dict[18]
+	if !ok {
+		return [][]byte{}, nil
+	} else if len(private) != 2 || private[0] < 0.0 || private[1] < 0.0 || float64(len(b)) < private[0]+private[1] {
+		return nil, ErrInvalidFontData
+	}
+	size, offset := uint32(private[0]), uint32(private[1])
+	privateDict, err := parseCFFDict(b[offset : offset+size])
+	if err != nil {
+		return nil, err
+	}
+	subrsOffset, ok := privateDict[19]
+	if !ok || len(subrsOffset) != 1 || subrsOffset[0] == 0.0 {
+		return [][]byte{}, nil
+	} else if subrsOffset[0] < 0.0 {
+		return nil, ErrInvalidFontData
+	}
+	subrs, _, err := parseCFFIndex(b, offset+uint32(subrsOffset[0]))
+	return subrs, err
+}
+
+// parseCFFFDSelect returns the font DICT of each glyph of the FDSelect data of formats 0 and 3.
+func parseCFFFDSelect(b []byte, pos uint32, numGlyphs, numFontDicts int) ([]uint8, error) {
+	r := readerAt(b, pos)
+	fdSelect := make([]uint8, numGlyphs)
+	switch r.ReadByte() {
+	case 0:
+		copy(fdSelect, r.ReadBytes(uint32(numGlyphs)))
+	case 3:
+		nRanges := r.ReadUint16()
+		first := r.ReadUint16()
+		for i := 0; i < int(nRanges); i++ {
+			fd := r.ReadByte()
+			next := r.ReadUint16()
+			if next < first || numGlyphs < int(next) {
+				return nil, ErrInvalidFontData
+			}
+			for j := first; j < next; j++ {
+				fdSelect[j] = fd
+			}
+			first = next
+		}
+	default:
+		return nil, fmt.Errorf("unsupported CFF FDSelect format")
+	}
+	if r.EOF() {
+		return nil, ErrInvalidFontData
+	}
+	for _, fd := range fdSelect {
+		if numFontDicts <= int(fd) {
+			return nil, ErrInvalidFontData
+		}
+	}
+	return fdSelect, nil
+}
+
+// parseCFFCharset returns the SID of each glyph of the charset at offset, or of the ISOAdobe charset for offset 0. The Expert charsets are not supported and return nil.
+func parseCFFCharset(b []byte, offset float64, numGlyphs int) ([]uint16, error) {
+	charset := make([]uint16, numGlyphs)
+	if offset == 0.0 {
+		for i := range charset {
+			charset[i] = uint16(i)
+		}
+		return charset, nil
+	} else if offset <= 2.0 {
+		return nil, nil
+	}
+	r := readerAt(b, uint32(offset))
+	format := r.ReadByte()
+	for i := 1; i < numGlyphs && !r.EOF(); {
+		if format == 0 {
+			charset[i] = r.ReadUint16()
+			i++
+			continue
+		} else if 2 < format {
+			return nil, fmt.Errorf("unsupported CFF charset format")
+		}
+		first := r.ReadUint16()
+		nLeft := uint16(r.ReadByte())
+		if format == 2 {
+			nLeft = nLeft<<8 | uint16(r.ReadByte())
+		}
+		for j := 0; j <= int(nLeft) && i < numGlyphs; j++ {
+			charset[i] = first + uint16(j)
+			i++
+		}
+	}
+	if r.EOF() {
+		return nil, ErrInvalidFontData
+	}
+	return charset, nil
+}
+
+// NumGlyphs returns the number of glyphs.
+func (cff *CFF) NumGlyphs() int {
+	return len(cff.charStrings)
+}
+
+// Glyph returns the outline of a glyph in font units with the y-axis pointing up, of which each contour is explicitly closed by a line to its start. Hints are ignored.
+// See https://adobe-type-tools.github.io/font-tech-notes/pdfs/5177.Type2.pdf
+func (cff *CFF) Glyph(id uint16) ([]Segment, error) {
+	if cff.NumGlyphs() <= int(id) {
+		return nil, fmt.Errorf("invalid glyph ID")
+	}
+	interp := &charstringInterpreter{cff: cff, fd: 0}
+	if cff.fdSelect != nil {
+		interp.fd = int(cff.fdSelect[id])
+	}
+	if err := interp.run(cff.charStrings[id], 0); err != nil {
+		return nil, err
+	}
+	interp.closePath()
+	if interp.seac != nil {
+		// accented character of a base and an accent glyph of the standard encoding
+		return cff.seac(interp.seac)
+	}
+	return interp.segments, nil
+}
+
+// seac returns the outline of an accented character of the endchar operator, with the accent offset by (adx,ady).
+func (cff *CFF) seac(args []float64) ([]Segment, error) {
+	adx, ady := args[0], args[1]
+	base, ok := cff.standardGlyph(args[2])
+	if !ok {
+		return nil, ErrInvalidFontData
+	}
+	accent, ok := cff.standardGlyph(args[3])
+	if !ok {
+		return nil, ErrInvalidFontData
+	}
+	segments := []Segment{}
+	for i, id := range []uint16{base, accent} {
+		interp := &charstringInterpreter{cff: cff}
+		if i == 1 {
+			interp.x, interp.y = adx, ady
+		}
+		if err := interp.run(cff.charStrings[id], 0); err != nil {
+			return nil, err
+		} else if interp.seac != nil {
+			return nil, ErrInvalidFontData
+		}
+		interp.closePath()
+		segments = append(segments, interp.segments...)
+	}
+	return segments, nil
+}
+
+// standardGlyph returns the glyph of a character code of the standard encoding.
+func (cff *CFF) standardGlyph(code float64) (uint16, bool) {
+	if code < 0.0 || 256.0 <= code {
+		return 0, false
+	}
+	sid := cffStandardEncoding(int(code))
+	if sid == 0 {
+		return 0, false
+	}
+	for id, glyphSID := range cff.charset {
+		if glyphSID == sid {
+			return uint16(id), true
+		}
+	}
+	return 0, false
+}
+
+// cffStandardEncoding returns the SID of the standard string of a character code of the standard encoding, or zero if the code is not encoded.
+func cffStandardEncoding(code int) uint16 {
+	if 32 <= code && code <= 126 {
+		return uint16(code - 31)
+	}
+	codes := []int{
+		161, 162, 163, 164, 165, 166, 167, 168, 169, 170, 171, 172, 173, 174, 175,
+		177, 178, 179, 180, 182, 183, 184, 185, 186, 187, 188, 189, 191,
+		193, 194, 195, 196, 197, 198, 199, 200, 202, 203, 205, 206, 207, 208,
+		225, 227, 232, 233, 234, 235, 241, 245, 248, 249, 250, 251,
+	}
+	for i, c := range codes {
+		if c == code {
+			return uint16(96 + i)
+		}
+	}
+	return 0
+}
+
+// charstringInterpreter interprets the Type 2 charstring of a glyph into outline segments.
+type charstringInterpreter struct {
+	cff *CFF
+	fd  int // font DICT of the local subroutines
+
+	stack     []float64
+	transient [32]float64
+	nStems    int
+	seenWidth bool
+	seac      []float64 // arguments of an accented character
+
+	x, y           float64
+	startX, startY float64
+	open, ended    bool
+	segments       []Segment
+}
+
+// point returns the current point in font units.
+func (p *charstringInterpreter) point(x, y float64) [2]float64 {
+	m := p.cff.matrix
+	return [2]float64{m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]}
+}
+
+func (p *charstringInterpreter) closePath() {
+	if p.open && (p.x != p.startX || p.y != p.startY) {
+		p.segments = append(p.segments, Segment{Op: SegmentLineTo, Args: [3][2]float64{p.point(p.startX, p.startY)}})
+	}
+	p.open = false
+}
+
+func (p *charstringInterpreter) moveTo(dx, dy float64) {
+	p.closePath()
+	p.x += dx
+	p.y += dy
+	p.startX, p.startY = p.x, p.y
+	p.segments = append(p.segments, Segment{Op: SegmentMoveTo, Args: [3][2]float64{p.point(p.x, p.y)}})
+	p.open = true
+}
+
+func (p *charstringInterpreter) lineTo(dx, dy float64) {
+	if !p.open {
+		p.moveTo(0.0, 0.0)
+	}
+	p.x += dx
+	p.y += dy
+	p.segments = append(p.segments, Segment{Op: SegmentLineTo, Args: [3][2]float64{p.point(p.x, p.y)}})
+}
+
+func (p *charstringInterpreter) curveTo(dxa, dya, dxb, dyb, dxc, dyc float64) {
+	if !p.open {
+		p.moveTo(0.0, 0.0)
+	}
+	segment := Segment{Op: SegmentCubeTo}
+	p.x += dxa
+	p.y += dya
+	segment.Args[0] = p.point(p.x, p.y)
+	p.x += dxb
+	p.y += dyb
+	segment.Args[1] = p.point(p.x, p.y)
+	p.x += dxc
+	p.y += dyc
+	segment.Args[2] = p.point(p.x, p.y)
+	p.segments = append(p.segments, segment)
+}
+
+// width removes the optional width of the first stack-clearing operator from the bottom of the stack, which is there if the stack has more arguments than the operator takes, or for n < 0 if the stack has an odd number of arguments.
+func (p *charstringInterpreter) width(n int) {
+	if p.seenWidth {
+		return
+	}
+	p.seenWidth = true
+	if n < 0 && len(p.stack)%2 == 1 || 0 <= n && n < len(p.stack) {
+		p.stack = p.stack[1:]
+	}
+}
+
+func (p *charstringInterpreter) pop() float64 {
+	if len(p.stack) == 0 {
+		return 0.0
+	}
+	v := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+	return v
+}
+
+// cffSubr returns the subroutine of the biased index.
+func cffSubr(subrs [][]byte, index float64) ([]byte, bool) {
+	bias := 32768
+	if len(subrs) < 1240 {
+		bias = 107
+	} else if len(subrs) < 33900 {
+		bias = 1131
+	}
+	i := int(index) + bias
+	if i < 0 || len(subrs) <= i {
+		return nil, false
+	}
+	return subrs[i], true
+}
+
+// run interprets a charstring or a subroutine, until its return or the endchar operator of the charstring.
+func (p *charstringInterpreter) run(b []byte, depth int) error {
+	if maxCFFSubrDepth < depth {
+		return ErrInvalidFontData
+	}
+	for i := 0; i < len(b); {
+		b0 := b[i]
+		i++
+		if 32 <= b0 || b0 == 28 {
+			var v float64
+			switch {
+			case b0 == 28 && i+1 < len(b):
+				v = float64(int16(uint16(b[i])<<8 | uint16(b[i+1])))
+				i += 2
+			case 32 <= b0 && b0 <= 246:
+				v = float64(int(b0) - 139)
+			case 247 <= b0 && b0 <= 250 && i < len(b):
+				v = float64((int(b0)-247)*256 + int(b[i]) + 108)
+				i++
+			case 251 <= b0 && b0 <= 254 && i < len(b):
+				v = float64(-(int(b0)-251)*256 - int(b[i]) - 108)
+				i++
+			case b0 == 255 && i+3 < len(b):
+				v = float64(int32(uint32(b[i])<<24|uint32(b[i+1])<<16|uint32(b[i+2])<<8|uint32(b[i+3]))) / 65536.0
+				i += 4
+			default:
+				return ErrInvalidFontData
+			}
+			if maxCFFStack <= len(p.stack) {
+				return ErrInvalidFontData
+			}
+			p.stack = append(p.stack, v)
+			continue
+		}
+
+		args := p.stack
+		switch b0 {
+		case 1, 3, 18, 23: // hstem, vstem, hstemhm, vstemhm
+			p.width(-1)
+			p.nStems += len(p.stack) / 2
+		case 19, 20: // hintmask, cntrmask
+			p.width(-1)
+			p.nStems += len(p.stack) / 2
+			i += (p.nStems + 7) / 8
+		case 21: // rmoveto
+			p.width(2)
+			if len(p.stack) < 2 {
+				return ErrInvalidFontData
+			}
+			p.moveTo(p.stack[0], p.stack[1])
+		case 22: // hmoveto
+			p.width(1)
+			if len(p.stack) < 1 {
+				return ErrInvalidFontData
+			}
+			p.moveTo(p.stack[0], 0.0)
+		case 4: // vmoveto
+			p.width(1)
+			if len(p.stack) < 1 {
+				return ErrInvalidFontData
+			}
+			p.moveTo(0.0, p.stack[0])
+		case 5: // rlineto
+			for j := 0; j+1 < len(args); j += 2 {
+				p.lineTo(args[j], args[j+1])
+			}
+		case 6, 7: // hlineto, vlineto
+			horizontal := b0 == 6
+			for j := 0; j < len(args); j++ {
+				if horizontal {
+					p.lineTo(args[j], 0.0)
+				} else {
+					p.lineTo(0.0, args[j])
+				}
+				horizontal = !horizontal
+			}
+		case 8, 24: // rrcurveto, rcurveline
+			j := 0
+			for ; j+5 < len(args); j += 6 {
+				p.curveTo(args[j], args[j+1], args[j+2], args[j+3], args[j+4], args[j+5])
+			}
+			if b0 == 24 && j+1 < len(args) {
+				p.lineTo(args[j], args[j+1])
+			}
+		case 25: // rlinecurve
+			j := 0
+			for ; j+7 < len(args); j += 2 {
+				p.lineTo(args[j], args[j+1])
+			}
+			if j+5 < len(args) {
+				p.curveTo(args[j], args[j+1], args[j+2], args[j+3], args[j+4], args[j+5])
+			}
+		case 26, 27: // vvcurveto, hhcurveto
+			d1, j := 0.0, 0
+			if len(args)%2 == 1 {
+				d1, j = args[0], 1
+			}
+			for ; j+3 < len(args); j += 4 {
+				if b0 == 26 {
+					p.curveTo(d1, args[j], args[j+1], args[j+2], 0.0, args[j+3])
+				} else {
+					p.curveTo(args[j], d1, args[j+1], args[j+2], args[j+3], 0.0)
+				}
+				d1 = 0.0
+			}
+		case 30, 31: // vhcurveto, hvcurveto
+			horizontal := b0 == 31
+			for j := 0; j+3 < len(args); j += 4 {
+				last := 0.0
+				if len(args)-j == 5 {
+					last = args[j+4]
+				}
+				if horizontal {
+					p.curveTo(args[j], 0.0, args[j+1], args[j+2], last, args[j+3])
+				} else {
+					p.curveTo(0.0, args[j], args[j+1], args[j+2], args[j+3], last)
+				}
+				horizontal = !horizontal
+			}
+		case 10, 29: // callsubr, callgsubr
+			subrs := p.cff.globalSubrs
+			if b0 == 10 {
+				subrs = p.cff.localSubrs[p.fd]
+			}
+			s, ok := cffSubr(subrs, p.pop())
+			if !ok {
+				return ErrInvalidFontData
+			}
+			if err := p.run(s, depth+1); err != nil {
+				return err
+			} else if p.ended {
+				return nil // endchar in subroutine
+			}
+			continue
+		case 11: // return
+			return nil
+		case 14: // endchar
+			if !p.seenWidth && (len(p.stack) == 1 || len(p.stack) == 5) {
+				p.stack = p.stack[1:]
+			}
+			p.seenWidth = true
+			if len(p.stack) == 4 {
+				p.seac = append([]float64{}, p.stack...)
+			}
+			p.ended = true
+			p.stack = p.stack[:0]
+			return nil
+		case 12: // escape
+			if len(b) <= i {
+				return ErrInvalidFontData
+			}
+			b1 := b[i]
+			i++
+			if err := p.escape(b1); err != nil {
+				return err
+			}
+			continue
+		default:
+			return ErrInvalidFontData
+		}
+		p.stack = p.stack[:0]
+	}
+	return nil
+}
+
+// escape interprets the two-byte operators of flex hints and arithmetic, where arithmetic operators leave their result on the stack.
+func (p *charstringInterpreter) escape(op byte) error {
+	args := p.stack
+	switch op {
+	case 0: // dotsection, deprecated
+	case 34: // hflex
+		if len(args) < 7 {
+			return ErrInvalidFontData
+		}
+		p.curveTo(args[0], 0.0, args[1], args[2], args[3], 0.0)
+		p.curveTo(args[4], 0.0, args[5], -args[2], args[6], 0.0)
+	case 35: // flex
+		if len(args) < 12 {
+			return ErrInvalidFontData
+		}
+		p.curveTo(args[0], args[1], args[2], args[3], args[4], args[5])
+		p.curveTo(args[6], args[7], args[8], args[9], args[10], args[11])
+	case 36: // hflex1
+		if len(args) < 9 {
+			return ErrInvalidFontData
+		}
+		p.curveTo(args[0], args[1], args[2], args[3], args[4], 0.0)
+		p.curveTo(args[5], 0.0, args[6], args[7], args[8], -(args[1] + args[3] + args[7]))
+	case 37: // flex1
+		if len(args) < 11 {
+			return ErrInvalidFontData
+		}
+		dx, dy := 0.0, 0.0
+		for j := 0; j < 10; j += 2 {
+			dx += args[j]
+			dy += args[j+1]
+		}
+		dx6, dy6 := args[10], -dy
+		if math.Abs(dx) <= math.Abs(dy) {
+			dx6, dy6 = -dx, args[10]
+		}
+		p.curveTo(args[0], args[1], args[2], args[3], args[4], args[5])
+		p.curveTo(args[6], args[7], args[8], args[9], dx6, dy6)
+	default:
+		return p.arithmetic(op)
+	}
+	p.stack = p.stack[:0]
+	return nil
+}
+
+// arithmetic interprets the arithmetic and storage operators.
+func (p *charstringInterpreter) arithmetic(op byte) error {
+	boolean := func(b bool) float64 {
+		if b {
+			return 1.0
+		}
+		return 0.0
+	}
+	switch op {
+	case 3, 4, 10, 11, 12, 15, 24, 28: // and, or, add, sub, div, eq, mul, exch
+		if len(p.stack) < 2 {
+			return ErrInvalidFontData
+		}
+		b, a := p.pop(), p.pop()
+		switch op {
+		case 3:
+			p.stack = append(p.stack, boolean(a != 0.0 && b != 0.0))
+		case 4:
+			p.stack = append(p.stack, boolean(a != 0.0 || b != 0.0))
+		case 10:
+			p.stack = append(p.stack, a+b)
+		case 11:
+			p.stack = append(p.stack, a-b)
+		case 12:
+			if b == 0.0 {
+				return ErrInvalidFontData
+			}
+			p.stack = append(p.stack, a/b)
+		case 15:
+			p.stack = append(p.stack, boolean(a == b))
+		case 24:
+			p.stack = append(p.stack, a*b)
+		case 28:
+			p.stack = append(p.stack, b, a)
+		}
+	case 5, 9, 14, 26, 18, 27: // not, abs, neg, sqrt, drop, dup
+		if len(p.stack) < 1 {
+			return ErrInvalidFontData
+		}
+		a := p.pop()
+		switch op {
+		case 5:
+			p.stack = append(p.stack, boolean(a == 0.0))
+		case 9:
+			p.stack = append(p.stack, math.Abs(a))
+		case 14:
+			p.stack = append(p.stack, -a)
+		case 26:
+			p.stack = append(p.stack, math.Sqrt(math.Abs(a)))
+		case 27:
+			if maxCFFStack <= len(p.stack)+1 {
+				return ErrInvalidFontData
+			}
+			p.stack = append(p.stack, a, a)
+		}
+	case 20: // put
+		if len(p.stack) < 2 {
+			return ErrInvalidFontData
+		}
+		i, v := p.pop(), p.pop()
+		if i < 0.0 || float64(len(p.transient)) <= i {
+			return ErrInvalidFontData
+		}
+		p.transient[int(i)] = v
+	case 21: // get
+		if len(p.stack) < 1 {
+			return ErrInvalidFontData
+		}
+		i := p.pop()
+		if i < 0.0 || float64(len(p.transient)) <= i {
+			return ErrInvalidFontData
+		}
+		p.stack = append(p.stack, p.transient[int(i)])
+	case 22: // ifelse
+		if len(p.stack) < 4 {
+			return ErrInvalidFontData
+		}
+		v2, v1, s2, s1 := p.pop(), p.pop(), p.pop(), p.pop()
+		if v2 < v1 {
+			s1 = s2
+		}
+		p.stack = append(p.stack, s1)
+	case 23: // random
+		p.stack = append(p.stack, 0.5) // deterministic value in (0,1]
+	case 29: // index
+		if len(p.stack) < 1 {
+			return ErrInvalidFontData
+		}
+		i := int(p.pop())
+		if i < 0 {
+			i = 0
+		}
+		if len(p.stack) <= i {
+			return ErrInvalidFontData
+		}
+		p.stack = append(p.stack, p.stack[len(p.stack)-1-i])
+	case 30: // roll
+		if len(p.stack) < 2 {
+			return ErrInvalidFontData
+		}
+		j, n := int(p.pop()), int(p.pop())
+		if n <= 0 || len(p.stack) < n {
+			return ErrInvalidFontData
+		}
+		elems := p.stack[len(p.stack)-n:]
+		j = ((j % n) + n) % n
+		rolled := append(append([]float64{}, elems[n-j:]...), elems[:n-j]...)
+		copy(elems, rolled)
+	default:
+		return ErrInvalidFontData
+	}
+	return nil
+}
+
+// stubCFF returns a CFF table with glyphs that have no outline, so that fonts of which sfnt does not support the CFF table can be parsed by sfnt for their other tables, while their outlines are read by ParseCFF.
+func stubCFF(numGlyphs int) []byte {
+	index := func(w *binaryWriter, objects ...[]byte) {
+		w.WriteUint16(uint16(len(objects)))
+		if len(objects) == 0 {
+			return
+		}
+		w.WriteByte(4) // offSize
+		offset := uint32(1)
+		w.WriteUint32(offset)
+		for _, object := range objects {
+			offset += uint32(len(object))
+			w.WriteUint32(offset)
+		}
+		for _, object := range objects {
+			w.WriteBytes(object)
+		}
+	}
+
+	charStrings := make([][]byte, numGlyphs)
+	for i := range charStrings {
+		charStrings[i] = []byte{14} // endchar
+	}
+	const topDictLength = 9
+	charStringsOffset := 4 + (2 + 1 + 8 + 1) + (2 + 1 + 8 + topDictLength) + 2 + 2
+
+	w := newBinaryWriter([]byte{})
+	w.WriteBytes([]byte{1, 0, 4, 4}) // major, minor, hdrSize, offSize
+	index(w, []byte("A"))
+	topDict := newBinaryWriter([]byte{})
+	topDict.WriteByte(29)
+	topDict.WriteUint32(uint32(charStringsOffset))
+	topDict.WriteBytes([]byte{17, 139, 139, 18}) // CharStrings, and an empty Private DICT
+	index(w, topDict.Bytes())
+	index(w) // String INDEX
+	index(w) // Global Subr INDEX
+	index(w, charStrings...)
+	return w.Bytes()
+}
@@ -0,0 +1,284 @@
+package font
+
+import (
+	"encoding/binary"
+	"image/color"
+	"sort"
+)
+
+// ColorLayer is a layer of a color glyph of the COLR table, which is the outline of another glyph filled with a color of the palette.
+type ColorLayer struct {
+	GlyphID      uint16
+	PaletteIndex uint16 // ForegroundColor for the color of the text
+}
+
+// ForegroundColor is the palette index of layers that are filled with the color of the text.
+const ForegroundColor = 0xFFFF
+
+type baseGlyphRecord struct {
+	glyphID, firstLayerIndex, numLayers uint16
+}
+
+// ColorGlyphs are the layers of the COLR table and the palettes of the CPAL table, which draw glyphs in color by stacking the outlines of other glyphs.
+type ColorGlyphs struct {
+	baseGlyphs []baseGlyphRecord // sorted by glyph ID
+	layers     []ColorLayer
+	palettes   [][]color.NRGBA
+}
+
+// ParseColorGlyphs parses the base glyphs and layers of the COLR table and the palettes of the CPAL table of an SFNT font (TTF or OTF). It returns nil if the font has no COLR table. Only the layers of version 0 are supported, which are also present in tables of version 1 for compatibility.
+// See https://learn.microsoft.com/en-us/typography/opentype/spec/colr
+func ParseColorGlyphs(b []byte) (*ColorGlyphs, error) {
+	colr, err := SFNTTable(b, "COLR")
+	if err != nil || colr == nil {
+		return nil, err
+	}
+	cpal, err := SFNTTable(b, "CPAL")
+	if err != nil {
+		return nil, err
+	} else if cpal == nil {
+		return nil, ErrInvalidFontData
+	}
+
+	r := newBinaryReader(colr)
+	_ = r.ReadUint16() // version
+	numBaseGlyphRecords := r.ReadUint16()
+	baseGlyphRecordsOffset := r.ReadUint32()
+	layerRecordsOffset := r.ReadUint32()
+	numLayerRecords := r.ReadUint16()
+	if r.EOF() {
+		return nil, ErrInvalidFontData
+	}
+
+	glyphs := &ColorGlyphs{
+		baseGlyphs: make([]baseGlyphRecord, numBaseGlyphRecords),
+		layers:     make([]ColorLayer, numLayerRecords),
+	}
+	r = readerAt(colr, baseGlyphRecordsOffset)
+	for i := range glyphs.baseGlyphs {
+		record := baseGlyphRecord{
+			glyphID:         r.ReadUint16(),
+			firstLayerIndex: r.ReadUint16(),
+			numLayers:       r.ReadUint16(),
+		}
+		if r.EOF() || 0 < i && record.glyphID <= glyphs.baseGlyphs[i-1].glyphID {
+			return nil, ErrInvalidFontData
+		} else if numLayerRecords < record.firstLayerIndex || numLayerRecords-record.firstLayerIndex < record.numLayers {
+			return nil, ErrInvalidFontData
+		}
+		glyphs.baseGlyphs[i] = record
+	}
+	r = readerAt(colr, layerRecordsOffset)
+	for i := range glyphs.layers {
+		glyphs.layers[i] = ColorLayer{
+			GlyphID:      r.ReadUint16(),
+			PaletteIndex: r.ReadUint16(),
+		}
+	}
+	if r.EOF() {
+		return nil, ErrInvalidFontData
+	}
+
+	// See https://learn.microsoft.com/en-us/typography/opentype/spec/cpal
+	r = newBinaryReader(cpal)
+	_ = r.ReadUint16() // version
+	numPaletteEntries := r.ReadUint16()
+	numPalettes := r.ReadUint16()
+	numColorRecords := r.ReadUint16()
+	colorRecordsArrayOffset := r.ReadUint32()
+	if r.EOF() || numPalettes == 0 || uint32(len(cpal)) < colorRecordsArrayOffset || uint32(len(cpal))-colorRecordsArrayOffset < 4*uint32(numColorRecords) {
+		return nil, ErrInvalidFontData
+	}
+	records := cpal[colorRecordsArrayOffset:]
+	glyphs.palettes = make([][]color.NRGBA, numPalettes)
+	for i := range glyphs.palettes {
+		colorRecordIndex := r.ReadUint16()
+		if r.EOF() || numColorRecords < colorRecordIndex || numColorRecords-colorRecordIndex < numPaletteEntries {
+			return nil, ErrInvalidFontData
+		}
+		palette := make([]color.NRGBA, numPaletteEntries)
+		for j := range palette {
+			record := records[4*(int(colorRecordIndex)+j):]
+			palette[j] = color.NRGBA{record[2], record[1], record[0], record[3]} // BGRA
+		}
+		glyphs.palettes[i] = palette
+	}
+	return glyphs, nil
+}
+
+// Layers returns the layers of a glyph from bottom to top, or nil if the glyph is not a color glyph.
+func (c *ColorGlyphs) Layers(glyphID uint16) []ColorLayer {
+	i := sort.Search(len(c.baseGlyphs), func(i int) bool {
+		return glyphID <= c.baseGlyphs[i].glyphID
+	})
+	if i == len(c.baseGlyphs) || c.baseGlyphs[i].glyphID != glyphID {
+		return nil
+	}
+	record := c.baseGlyphs[i]
+	return c.layers[record.firstLayerIndex : record.firstLayerIndex+record.numLayers]
+}
+
+// NumPalettes returns the number of palettes, which is at least one.
+func (c *ColorGlyphs) NumPalettes() int {
+	return len(c.palettes)
+}
+
+// Palette returns the colors of a palette, or of the first palette if it does not exist.
+func (c *ColorGlyphs) Palette(i int) []color.NRGBA {
+	if i < 0 || len(c.palettes) <= i {
+		i = 0
+	}
+	return c.palettes[i]
+}
+
+// ColorBitmap is an image of a glyph of the sbix or CBDT table, such as of color emoji, placed with its lower-left corner at X,Y pixels from the origin of the glyph at a resolution of PPEM pixels per em.
+type ColorBitmap struct {
+	Data []byte // PNG image, or JPEG or TIFF for the sbix table
+	PPEM uint16
+	X, Y int16
+}
+
+// ColorBitmaps are the images of the glyphs of the largest strike of the sbix table, or of the CBLC and CBDT tables.
+type ColorBitmaps struct {
+	bitmaps map[uint16]ColorBitmap
+}
+
+// ParseColorBitmaps parses the images of the glyphs of the strike with the largest resolution of the sbix table, or of the CBLC and CBDT tables if the font has no sbix table. It returns nil if the font has neither. Only PNG images are supported for the CBDT table (image formats 17, 18, and 19), and masks of the sbix table are ignored.
+// See https://learn.microsoft.com/en-us/typography/opentype/spec/sbix and https://learn.microsoft.com/en-us/typography/opentype/spec/cbdt
+func ParseColorBitmaps(b []byte) (*ColorBitmaps, error) {
+	sbix, err := SFNTTable(b, "sbix")
+	if err != nil {
+		return nil, err
+	} else if sbix != nil {
+		maxp, err := SFNTTable(b, "maxp")
+		if err != nil {
+			return nil, err
+		} else if len(maxp) < 6 {
+			return nil, ErrInvalidFontData
+		}
+		return parseSbix(sbix, binary.BigEndian.Uint16(maxp[4:]))
+	}
+
+	cblc, err := SFNTTable(b, "CBLC")
+	if err != nil {
+		return nil, err
+	}
+	cbdt, err := SFNTTable(b, "CBDT")
+	if err != nil || cblc == nil || cbdt == nil {
+		return nil, err
+	}
+	strike, ok, err := largestBitmapStrike(cblc, 3, 32)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	bitmaps := &ColorBitmaps{map[uint16]ColorBitmap{}}
+	err = strike.glyphs(cblc, cbdt, func(id, imageFormat uint16, data, metrics []byte) error {
+		r := newBinaryReader(data)
+		switch imageFormat {
+		case 17: // small glyph metrics
+			metrics = r.ReadBytes(5)
+		case 18: // big glyph metrics
+			metrics = r.ReadBytes(8)
+		case 19: // metrics in CBLC
+			if metrics == nil {
+				return ErrInvalidFontData
+			}
+		default:
+			return nil
+		}
+		dataLen := r.ReadUint32()
+		if r.EOF() || r.Len() < dataLen {
+			return ErrInvalidFontData
+		}
+		height, bearingX, bearingY := int16(metrics[0]), int16(int8(metrics[2])), int16(int8(metrics[3]))
+		bitmaps.bitmaps[id] = ColorBitmap{
+			Data: r.ReadBytes(dataLen),
+			PPEM: uint16(strike.ppemY),
+			X:    bearingX,
+			Y:    bearingY - height,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bitmaps, nil
+}
+
+func parseSbix(sbix []byte, numGlyphs uint16) (*ColorBitmaps, error) {
+	r := newBinaryReader(sbix)
+	_ = r.ReadUint16() // version
+	_ = r.ReadUint16() // flags
+	numStrikes := r.ReadUint32()
+	if r.EOF() || r.Len()/4 < numStrikes {
+		return nil, ErrInvalidFontData
+	}
+
+	var strike []byte
+	var ppem uint16
+	for i := 0; i < int(numStrikes); i++ {
+		offset := r.ReadUint32()
+		rStrike := readerAt(sbix, offset)
+		strikePPEM := rStrike.ReadUint16()
+		_ = rStrike.ReadUint16() // ppi
+		if rStrike.EOF() || rStrike.Len()/4 <= uint32(numGlyphs) {
+			return nil, ErrInvalidFontData
+		} else if strike == nil || ppem < strikePPEM {
+			strike, ppem = sbix[offset:], strikePPEM
+		}
+	}
+	if strike == nil {
+		return nil, nil
+	}
+
+	bitmaps := &ColorBitmaps{map[uint16]ColorBitmap{}}
+	r = newBinaryReader(strike[4:])
+	start := r.ReadUint32()
+	for id := uint16(0); id < numGlyphs; id++ {
+		end := r.ReadUint32()
+		if end < start || uint32(len(strike)) < end {
+			return nil, ErrInvalidFontData
+		} else if start == end {
+			continue // no image
+		}
+		rGlyph := newBinaryReader(strike[start:end])
+		originOffsetX := rGlyph.ReadInt16()
+		originOffsetY := rGlyph.ReadInt16()
+		graphicType := rGlyph.ReadString(4)
+		if rGlyph.EOF() {
+			return nil, ErrInvalidFontData
+		}
+		start = end
+
+		switch graphicType {
+		case "png ", "jpg ", "tiff":
+			bitmaps.bitmaps[id] = ColorBitmap{
+				Data: rGlyph.ReadBytes(rGlyph.Len()),
+				PPEM: ppem,
+				X:    originOffsetX,
+				Y:    originOffsetY,
+			}
+		}
+	}
+
+	// glyphs that use the image of another glyph
+	r = newBinaryReader(strike[4:])
+	start = r.ReadUint32()
+	for id := uint16(0); id < numGlyphs; id++ {
+		end := r.ReadUint32()
+		if start+10 <= end && string(strike[start+4:start+8]) == "dupe" {
+			if bitmap, ok := bitmaps.bitmaps[binary.BigEndian.Uint16(strike[start+8:])]; ok {
+				bitmaps.bitmaps[id] = bitmap
+			}
+		}
+		start = end
+	}
+	return bitmaps, nil
+}
+
+// Bitmap returns the image of a glyph, or false if the glyph has no image.
+func (c *ColorBitmaps) Bitmap(glyphID uint16) (ColorBitmap, bool) {
+	bitmap, ok := c.bitmaps[glyphID]
+	return bitmap, ok
+}
@@ -24,6 +24,8 @@ func Mimetype(b []byte) (string, error) {
 		return "font/truetype", nil
 	} else if tag == "OTTO" {
 		return "font/opentype", nil
+	} else if tag == "ttcf" {
+		return "font/collection", nil
 	} else if 36 < len(b) && binary.LittleEndian.Uint16(b[34:36]) == 0x504C {
 		return "font/eot", nil
 	}
@@ -52,6 +54,11 @@ func ToSFNT(b []byte) ([]byte, string, error) {
 		if err != nil {
 			return nil, "", fmt.Errorf("EOT: %w", err)
 		}
+	} else if mimetype == "font/collection" {
+		b, err = ParseCollection(b, 0)
+		if err != nil {
+			return nil, "", fmt.Errorf("TTC: %w", err)
+		}
 	}
 
 	mimetype, err = Mimetype(b)
@@ -61,7 +68,7 @@ func ToSFNT(b []byte) ([]byte, string, error) {
 	return b, mimetype, nil
 }
 
-// ParseFont parses a byte slice and recognized whether it is a TTF, OTF, WOFF, WOFF2, or EOT font format. It will return the parsed font and its mimetype.
+// ParseFont parses a byte slice and recognized whether it is a TTF, OTF, WOFF, WOFF2, or EOT font format, or a TTC collection of which the first font is used. It will return the parsed font and its mimetype.
 func ParseFont(b []byte) (*Font, error) {
 	sfntBytes, _, err := ToSFNT(b)
 	if err != nil {
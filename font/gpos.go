@@ -0,0 +1,219 @@
+package font
+
+// Kerning holds the pair adjustments of the kern feature of the GPOS table in font units, see ParseKerning.
+type Kerning struct {
+	lookups [][]pairSubtable
+}
+
+// pairSubtable is a subtable of a pair adjustment lookup.
+type pairSubtable struct {
+	pairs map[uint32]int16 // format 1, by the first and second glyph
+
+	// format 2
+	coverage       map[uint16]bool
+	class1, class2 map[uint16]uint16
+	class2Count    int
+	values         []int16 // by class of the first and second glyph
+}
+
+// ParseKerning parses the pair adjustments of the kern feature of the GPOS table of an SFNT font (TTF or OTF), for both glyph pairs (format 1) and glyph classes (format 2). Only the horizontal advance of the first glyph is used. It returns nil if the font has no kerning in the GPOS table, in which case the legacy kern table may be used instead.
+// See https://learn.microsoft.com/en-us/typography/opentype/spec/gpos
+func ParseKerning(b []byte) (*Kerning, error) {
+	gpos, err := SFNTTable(b, "GPOS")
+	if err != nil || gpos == nil {
+		return nil, err
+	}
+
+	features, err := parseFeatureLookups(gpos)
+	if err != nil {
+		return nil, err
+	}
+	kerning := &Kerning{}
+	for _, lookup := range features["kern"] {
+		subtables, err := parsePairLookup(gpos, lookup)
+		if err != nil {
+			return nil, err
+		} else if 0 < len(subtables) {
+			kerning.lookups = append(kerning.lookups, subtables)
+		}
+	}
+	if len(kerning.lookups) == 0 {
+		return nil, nil
+	}
+	return kerning, nil
+}
+
+// Kern returns the adjustment of the advance of the left glyph when followed by the right glyph, in font units.
+func (k *Kerning) Kern(left, right uint16) int16 {
+	kern := int16(0)
+	for _, subtables := range k.lookups {
+		// the first subtable that covers the pair is applied
+		for _, subtable := range subtables {
+			if subtable.pairs != nil {
+				if v, ok := subtable.pairs[uint32(left)<<16|uint32(right)]; ok {
+					kern += v
+					break
+				}
+			} else if subtable.coverage[left] {
+				kern += subtable.values[int(subtable.class1[left])*subtable.class2Count+int(subtable.class2[right])]
+				break
+			}
+		}
+	}
+	return kern
+}
+
+// valueRecordSize returns the size in bytes of a value record and the offset of its XAdvance field, or -1 if it has none.
+func valueRecordSize(valueFormat uint16) (uint32, int) {
+	size := uint32(0)
+	xAdvance := -1
+	for bit := uint16(0); bit < 8; bit++ {
+		if valueFormat&(1<<bit) != 0 {
+			if bit == 2 {
+				xAdvance = int(size)
+			}
+			size += 2
+		}
+	}
+	return size, xAdvance
+}
+
+func parsePairLookup(b []byte, offset uint32) ([]pairSubtable, error) {
+	lookupType, subTableOffsets, err := parseLookupSubtables(b, offset, 9)
+	if err != nil {
+		return nil, err
+	}
+	subtables := []pairSubtable{}
+	if lookupType != 2 {
+		return subtables, nil
+	}
+	for _, subTableOffset := range subTableOffsets {
+		r := readerAt(b, subTableOffset)
+		posFormat := r.ReadUint16()
+		coverage, err := parseCoverage(b, subTableOffset+uint32(r.ReadUint16()))
+		if err != nil {
+			return nil, err
+		}
+		valueFormat1 := r.ReadUint16()
+		valueFormat2 := r.ReadUint16()
+		size1, xAdvance := valueRecordSize(valueFormat1)
+		size2, _ := valueRecordSize(valueFormat2)
+		value := func(record []byte) int16 {
+			if record == nil || xAdvance < 0 {
+				return 0
+			}
+			return int16(uint16(record[xAdvance])<<8 | uint16(record[xAdvance+1]))
+		}
+
+		if posFormat == 1 {
+			pairSetCount := r.ReadUint16()
+			if r.EOF() || len(coverage) < int(pairSetCount) {
+				return nil, ErrInvalidFontData
+			}
+			subtable := pairSubtable{pairs: map[uint32]int16{}}
+			for i := 0; i < int(pairSetCount); i++ {
+				rSet := readerAt(b, subTableOffset+uint32(r.ReadUint16()))
+				pairValueCount := rSet.ReadUint16()
+				for j := 0; j < int(pairValueCount); j++ {
+					secondGlyph := rSet.ReadUint16()
+					v := value(rSet.ReadBytes(size1))
+					_ = rSet.ReadBytes(size2)
+					key := uint32(coverage[i])<<16 | uint32(secondGlyph)
+					if _, ok := subtable.pairs[key]; !ok {
+						subtable.pairs[key] = v
+					}
+				}
+				if rSet.EOF() {
+					return nil, ErrInvalidFontData
+				}
+			}
+			if r.EOF() {
+				return nil, ErrInvalidFontData
+			}
+			subtables = append(subtables, subtable)
+		} else if posFormat == 2 {
+			class1, err := parseClassDef(b, subTableOffset+uint32(r.ReadUint16()))
+			if err != nil {
+				return nil, err
+			}
+			class2, err := parseClassDef(b, subTableOffset+uint32(r.ReadUint16()))
+			if err != nil {
+				return nil, err
+			}
+			class1Count := r.ReadUint16()
+			class2Count := r.ReadUint16()
+			if r.EOF() {
+				return nil, ErrInvalidFontData
+			}
+			subtable := pairSubtable{
+				coverage:    map[uint16]bool{},
+				class1:      class1,
+				class2:      class2,
+				class2Count: int(class2Count),
+				values:      make([]int16, int(class1Count)*int(class2Count)),
+			}
+			for _, glyph := range coverage {
+				subtable.coverage[glyph] = true
+			}
+			for i := range subtable.values {
+				subtable.values[i] = value(r.ReadBytes(size1))
+				_ = r.ReadBytes(size2)
+			}
+			if r.EOF() {
+				return nil, ErrInvalidFontData
+			}
+			for _, class := range class1 {
+				if class1Count <= class {
+					return nil, ErrInvalidFontData
+				}
+			}
+			for _, class := range class2 {
+				if class2Count <= class {
+					return nil, ErrInvalidFontData
+				}
+			}
+			if class1Count == 0 || class2Count == 0 {
+				return nil, ErrInvalidFontData // class 0 must exist
+			}
+			subtables = append(subtables, subtable)
+		} else {
+			return nil, ErrInvalidFontData
+		}
+	}
+	return subtables, nil
+}
+
+// parseClassDef returns the classes of the glyphs of a class definition table, where glyphs that are not included are of class zero.
+func parseClassDef(b []byte, offset uint32) (map[uint16]uint16, error) {
+	r := readerAt(b, offset)
+	classFormat := r.ReadUint16()
+	classes := map[uint16]uint16{}
+	if classFormat == 1 {
+		startGlyphID := uint32(r.ReadUint16())
+		glyphCount := r.ReadUint16()
+		for i := 0; i < int(glyphCount); i++ {
+			if class := r.ReadUint16(); class != 0 {
+				classes[uint16(startGlyphID+uint32(i))] = class
+			}
+		}
+	} else if classFormat == 2 {
+		classRangeCount := r.ReadUint16()
+		for i := 0; i < int(classRangeCount); i++ {
+			startGlyphID := uint32(r.ReadUint16())
+			endGlyphID := uint32(r.ReadUint16())
+			class := r.ReadUint16()
+			if endGlyphID < startGlyphID {
+				return nil, ErrInvalidFontData
+			}
+			for glyph := startGlyphID; glyph <= endGlyphID && class != 0; glyph++ {
+				classes[uint16(glyph)] = class
+			}
+		}
+	} else {
+		return nil, ErrInvalidFontData
+	}
+	if r.EOF() {
+		return nil, ErrInvalidFontData
+	}
+	return classes, nil
+}
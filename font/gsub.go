@@ -0,0 +1,275 @@
+package font
+
+import (
+	"sort"
+)
+
+// Ligature is a ligature substitution of a sequence of glyphs by a single glyph.
+type Ligature struct {
+	Components []uint16 // glyph indices
+	Glyph      uint16
+}
+
+// SFNTTable returns the table of an SFNT font (TTF or OTF) with the given tag, or nil if it does not exist.
+func SFNTTable(b []byte, tag string) ([]byte, error) {
+	r := newBinaryReader(b)
+	_ = r.ReadUint32() // sfntVersion
+	numTables := r.ReadUint16()
+	_ = r.ReadBytes(6) // searchRange, entrySelector, rangeShift
+	for i := 0; i < int(numTables); i++ {
+		recordTag := r.ReadString(4)
+		_ = r.ReadUint32() // checksum
+		offset := r.ReadUint32()
+		length := r.ReadUint32()
+		if r.EOF() {
+			return nil, ErrInvalidFontData
+		} else if recordTag == tag {
+			if uint32(len(b)) < offset || uint32(len(b))-offset < length {
+				return nil, ErrInvalidFontData
+			}
+			return b[offset : offset+length], nil
+		}
+	}
+	if r.EOF() {
+		return nil, ErrInvalidFontData
+	}
+	return nil, nil
+}
+
+// ParseLigatures parses the ligature substitutions of the GSUB table of an SFNT font (TTF or OTF) for each feature tag, such as "liga", "clig", "dlig", "hlig", and "rlig". The features of the default language system of each script are used, and the ligatures of a feature are in order of the lookups. Substitutions of other types, such as contextual substitutions, are ignored.
+// See https://learn.microsoft.com/en-us/typography/opentype/spec/gsub
+func ParseLigatures(b []byte) (map[string][]Ligature, error) {
+	gsub, err := SFNTTable(b, "GSUB")
+	if err != nil {
+		return nil, err
+	} else if gsub == nil {
+		return map[string][]Ligature{}, nil
+	}
+
+	features, err := parseFeatureLookups(gsub)
+	if err != nil {
+		return nil, err
+	}
+	ligatures := map[string][]Ligature{}
+	for tag, lookups := range features {
+		for _, lookup := range lookups {
+			ligs, err := parseLigatureLookup(gsub, lookup)
+			if err != nil {
+				return nil, err
+			} else if 0 < len(ligs) {
+				ligatures[tag] = append(ligatures[tag], ligs...)
+			}
+		}
+	}
+	return ligatures, nil
+}
+
+// parseFeatureLookups returns the offsets of the lookups of each feature tag of a GSUB or GPOS table, in order of the lookups. The features of the default language system of each script are used.
+func parseFeatureLookups(b []byte) (map[string][]uint32, error) {
+	r := newBinaryReader(b)
+	majorVersion := r.ReadUint16()
+	_ = r.ReadUint16() // minorVersion
+	scriptListOffset := uint32(r.ReadUint16())
+	featureListOffset := uint32(r.ReadUint16())
+	lookupListOffset := uint32(r.ReadUint16())
+	if r.EOF() || majorVersion != 1 {
+		return nil, ErrInvalidFontData
+	}
+
+	// features of the default language systems
+	features := map[uint16]bool{}
+	r = readerAt(b, scriptListOffset)
+	scriptCount := r.ReadUint16()
+	for i := 0; i < int(scriptCount); i++ {
+		_ = r.ReadString(4) // scriptTag
+		scriptOffset := scriptListOffset + uint32(r.ReadUint16())
+		rScript := readerAt(b, scriptOffset)
+		defaultLangSysOffset := uint32(rScript.ReadUint16())
+		if rScript.EOF() || defaultLangSysOffset == 0 {
+			continue
+		}
+		rLangSys := readerAt(b, scriptOffset+defaultLangSysOffset)
+		_ = rLangSys.ReadUint16() // lookupOrderOffset
+		requiredFeatureIndex := rLangSys.ReadUint16()
+		if requiredFeatureIndex != 0xFFFF {
+			features[requiredFeatureIndex] = true
+		}
+		featureIndexCount := rLangSys.ReadUint16()
+		for j := 0; j < int(featureIndexCount); j++ {
+			features[rLangSys.ReadUint16()] = true
+		}
+		if rLangSys.EOF() {
+			return nil, ErrInvalidFontData
+		}
+	}
+	if r.EOF() {
+		return nil, ErrInvalidFontData
+	}
+
+	// lookups of the features
+	indices := map[string][]int{}
+	r = readerAt(b, featureListOffset)
+	featureCount := r.ReadUint16()
+	for i := 0; i < int(featureCount); i++ {
+		featureTag := r.ReadString(4)
+		featureOffset := featureListOffset + uint32(r.ReadUint16())
+		if !features[uint16(i)] {
+			continue
+		}
+		rFeature := readerAt(b, featureOffset)
+		_ = rFeature.ReadUint16() // featureParamsOffset
+		lookupIndexCount := rFeature.ReadUint16()
+		for j := 0; j < int(lookupIndexCount); j++ {
+			indices[featureTag] = append(indices[featureTag], int(rFeature.ReadUint16()))
+		}
+		if rFeature.EOF() {
+			return nil, ErrInvalidFontData
+		}
+	}
+	if r.EOF() {
+		return nil, ErrInvalidFontData
+	}
+
+	r = readerAt(b, lookupListOffset)
+	lookupCount := r.ReadUint16()
+	lookupOffsets := make([]uint32, lookupCount)
+	for i := range lookupOffsets {
+		lookupOffsets[i] = lookupListOffset + uint32(r.ReadUint16())
+	}
+	if r.EOF() {
+		return nil, ErrInvalidFontData
+	}
+
+	lookups := map[string][]uint32{}
+	for tag, tagIndices := range indices {
+		sort.Ints(tagIndices)
+		for k, index := range tagIndices {
+			if 0 < k && index == tagIndices[k-1] {
+				continue // feature listed by several scripts
+			} else if len(lookupOffsets) <= index {
+				return nil, ErrInvalidFontData
+			}
+			lookups[tag] = append(lookups[tag], lookupOffsets[index])
+		}
+	}
+	return lookups, nil
+}
+
+// parseLookupSubtables returns the type and the offsets of the subtables of a lookup, where subtables of the extension lookup type are resolved.
+func parseLookupSubtables(b []byte, offset uint32, extensionType uint16) (uint16, []uint32, error) {
+	r := readerAt(b, offset)
+	lookupType := r.ReadUint16()
+	_ = r.ReadUint16() // lookupFlag
+	subTableCount := r.ReadUint16()
+	subTableOffsets := make([]uint32, 0, subTableCount)
+	for i := 0; i < int(subTableCount); i++ {
+		subTableOffset := offset + uint32(r.ReadUint16())
+		if lookupType == extensionType {
+			rExt := readerAt(b, subTableOffset)
+			_ = rExt.ReadUint16() // format
+			subTableType := rExt.ReadUint16()
+			subTableOffset += rExt.ReadUint32()
+			if rExt.EOF() || subTableType == extensionType || (i != 0 && subTableType != lookupType) {
+				return 0, nil, ErrInvalidFontData
+			}
+			lookupType = subTableType
+		}
+		subTableOffsets = append(subTableOffsets, subTableOffset)
+	}
+	if r.EOF() {
+		return 0, nil, ErrInvalidFontData
+	}
+	return lookupType, subTableOffsets, nil
+}
+
+func parseLigatureLookup(b []byte, offset uint32) ([]Ligature, error) {
+	lookupType, subTableOffsets, err := parseLookupSubtables(b, offset, 7)
+	if err != nil {
+		return nil, err
+	}
+	ligatures := []Ligature{}
+	if lookupType != 4 {
+		return ligatures, nil
+	}
+	for _, subTableOffset := range subTableOffsets {
+		rSub := readerAt(b, subTableOffset)
+		substFormat := rSub.ReadUint16()
+		coverage, err := parseCoverage(b, subTableOffset+uint32(rSub.ReadUint16()))
+		if err != nil {
+			return nil, err
+		}
+		ligatureSetCount := rSub.ReadUint16()
+		if rSub.EOF() || substFormat != 1 || len(coverage) < int(ligatureSetCount) {
+			return nil, ErrInvalidFontData
+		}
+		for j := 0; j < int(ligatureSetCount); j++ {
+			ligatureSetOffset := subTableOffset + uint32(rSub.ReadUint16())
+			rSet := readerAt(b, ligatureSetOffset)
+			ligatureCount := rSet.ReadUint16()
+			for k := 0; k < int(ligatureCount); k++ {
+				rLig := readerAt(b, ligatureSetOffset+uint32(rSet.ReadUint16()))
+				glyph := rLig.ReadUint16()
+				componentCount := rLig.ReadUint16()
+				if componentCount == 0 {
+					return nil, ErrInvalidFontData
+				}
+				components := make([]uint16, componentCount)
+				components[0] = coverage[j]
+				for l := 1; l < int(componentCount); l++ {
+					components[l] = rLig.ReadUint16()
+				}
+				if rLig.EOF() {
+					return nil, ErrInvalidFontData
+				}
+				ligatures = append(ligatures, Ligature{components, glyph})
+			}
+			if rSet.EOF() {
+				return nil, ErrInvalidFontData
+			}
+		}
+		if rSub.EOF() {
+			return nil, ErrInvalidFontData
+		}
+	}
+	return ligatures, nil
+}
+
+// parseCoverage returns the glyph indices of a coverage table in order of their coverage index.
+func parseCoverage(b []byte, offset uint32) ([]uint16, error) {
+	r := readerAt(b, offset)
+	coverageFormat := r.ReadUint16()
+	glyphs := []uint16{}
+	if coverageFormat == 1 {
+		glyphCount := r.ReadUint16()
+		for i := 0; i < int(glyphCount); i++ {
+			glyphs = append(glyphs, r.ReadUint16())
+		}
+	} else if coverageFormat == 2 {
+		rangeCount := r.ReadUint16()
+		for i := 0; i < int(rangeCount); i++ {
+			startGlyphID := r.ReadUint16()
+			endGlyphID := r.ReadUint16()
+			startCoverageIndex := r.ReadUint16()
+			if endGlyphID < startGlyphID || int(startCoverageIndex) != len(glyphs) {
+				return nil, ErrInvalidFontData
+			}
+			for glyph := uint32(startGlyphID); glyph <= uint32(endGlyphID); glyph++ {
+				glyphs = append(glyphs, uint16(glyph))
+			}
+		}
+	} else {
+		return nil, ErrInvalidFontData
+	}
+	if r.EOF() {
+		return nil, ErrInvalidFontData
+	}
+	return glyphs, nil
+}
+
+// readerAt returns a reader at the offset of b, which is at EOF if the offset is out of range.
+func readerAt(b []byte, offset uint32) *binaryReader {
+	if uint32(len(b)) < offset {
+		return &binaryReader{nil, 0, true}
+	}
+	return newBinaryReader(b[offset:])
+}
@@ -4,7 +4,17 @@ import (
 	"golang.org/x/image/font/sfnt"
 )
 
+// ParseSFNT parses an SFNT font (TTF or OTF). Fonts with a CFF table that sfnt does not support, such as those with many subroutines or font DICTs, are parsed with glyphs that have no outlines, which must then be read by ParseCFF.
 func ParseSFNT(b []byte) (*Font, error) {
 	font, err := sfnt.Parse(b)
+	if err != nil {
+		if cff, errCFF := ParseCFF(b); errCFF == nil && cff != nil {
+			if stub, errStub := writeSFNT(b, map[string][]byte{"CFF ": stubCFF(cff.NumGlyphs())}); errStub == nil {
+				if font, errStub := sfnt.Parse(stub); errStub == nil {
+					return (*Font)(font), nil
+				}
+			}
+		}
+	}
 	return (*Font)(font), err
 }
@@ -0,0 +1,946 @@
+package font
+
+import (
+	"encoding/binary"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Glyph is a glyph of a glyph run that is shaped by a Shaper, with its advance and offset in font units, where the y-axis points up.
+type Glyph struct {
+	ID       uint16
+	Cluster  int    // position of the first character that the glyph represents, which is kept by substitutions
+	Mask     uint32 // features that apply to the glyph, see Feature
+	XAdvance int32
+	XOffset  int32
+	YOffset  int32
+
+	component int // component of the preceding ligature that a mark belongs to, starting at one, or zero for the last component
+}
+
+// Feature is a feature of the GSUB or GPOS table, such as liga or kern, which applies to the glyphs whose mask shares a bit with the mask of the feature.
+type Feature struct {
+	Tag  string
+	Mask uint32
+}
+
+// Shaper applies the glyph substitutions of the GSUB table and the glyph positioning of the GPOS table of an SFNT font to glyph runs, see ParseShaper.
+type Shaper struct {
+	gsub, gpos *layoutTable // nil if the font has no such table
+
+	gdef                                             []byte
+	glyphClassDef, markAttachClassDef, markGlyphSets uint32 // offsets in the GDEF table, or zero
+
+	mu      sync.Mutex
+	lookups map[string][]featureLookup // by table, script, and features
+}
+
+// layoutTable is a GSUB or GPOS table with its scripts, features, and lookups.
+type layoutTable struct {
+	b        []byte
+	gpos     bool
+	scripts  map[string][]uint16 // feature indices of the default language system by script tag, where the first is the required feature or 0xFFFF
+	features []layoutFeature
+	lookups  []uint32 // offsets
+}
+
+type layoutFeature struct {
+	tag     string
+	lookups []uint16
+}
+
+// featureLookup is a lookup that applies to the glyphs whose mask shares a bit with mask.
+type featureLookup struct {
+	index uint16
+	mask  uint32
+}
+
+// lookup is a lookup table with its subtables, where extension subtables are resolved.
+type lookup struct {
+	typ       uint16
+	flag      uint16
+	markSet   uint16
+	subtables []uint32
+}
+
+// maxLookupDepth is the maximum nesting of lookups that are applied by contextual lookups, so that recursive lookups of crafted fonts terminate.
+const maxLookupDepth = 8
+
+// ParseShaper parses the GSUB, GPOS, and GDEF tables of an SFNT font (TTF or OTF). The shaper applies no substitutions or positioning for tables that are missing. Lookups are applied from the data of the tables when glyph runs are shaped.
+// See https://learn.microsoft.com/en-us/typography/opentype/spec/chapter2
+func ParseShaper(b []byte) (*Shaper, error) {
+	s := &Shaper{
+		lookups: map[string][]featureLookup{},
+	}
+	for _, tag := range []string{"GSUB", "GPOS"} {
+		table, err := SFNTTable(b, tag)
+		if err != nil {
+			return nil, err
+		} else if table == nil {
+			continue
+		}
+		t, err := parseLayoutTable(table, tag == "GPOS")
+		if err != nil {
+			return nil, err
+		} else if tag == "GSUB" {
+			s.gsub = t
+		} else {
+			s.gpos = t
+		}
+	}
+
+	gdef, err := SFNTTable(b, "GDEF")
+	if err != nil {
+		return nil, err
+	} else if gdef != nil {
+		r := newBinaryReader(gdef)
+		majorVersion := r.ReadUint16()
+		minorVersion := r.ReadUint16()
+		s.glyphClassDef = uint32(r.ReadUint16())
+		_ = r.ReadUint16() // attachListOffset
+		_ = r.ReadUint16() // ligCaretListOffset
+		s.markAttachClassDef = uint32(r.ReadUint16())
+		if 2 <= minorVersion {
+			s.markGlyphSets = uint32(r.ReadUint16())
+		}
+		if r.EOF() || majorVersion != 1 {
+			return nil, ErrInvalidFontData
+		}
+		s.gdef = gdef
+	}
+	return s, nil
+}
+
+func parseLayoutTable(b []byte, gpos bool) (*layoutTable, error) {
+	r := newBinaryReader(b)
+	majorVersion := r.ReadUint16()
+	_ = r.ReadUint16() // minorVersion
+	scriptListOffset := uint32(r.ReadUint16())
+	featureListOffset := uint32(r.ReadUint16())
+	lookupListOffset := uint32(r.ReadUint16())
+	if r.EOF() || majorVersion != 1 {
+		return nil, ErrInvalidFontData
+	}
+	t := &layoutTable{
+		b:       b,
+		gpos:    gpos,
+		scripts: map[string][]uint16{},
+	}
+
+	r = readerAt(b, scriptListOffset)
+	scriptCount := r.ReadUint16()
+	for i := 0; i < int(scriptCount); i++ {
+		scriptTag := r.ReadString(4)
+		scriptOffset := scriptListOffset + uint32(r.ReadUint16())
+		defaultLangSysOffset := uint32(uint16At(b, scriptOffset))
+		if defaultLangSysOffset == 0 {
+			continue
+		}
+		rLangSys := readerAt(b, scriptOffset+defaultLangSysOffset)
+		_ = rLangSys.ReadUint16() // lookupOrderOffset
+		indices := []uint16{rLangSys.ReadUint16()}
+		featureIndexCount := rLangSys.ReadUint16()
+		for j := 0; j < int(featureIndexCount); j++ {
+			indices = append(indices, rLangSys.ReadUint16())
+		}
+		if rLangSys.EOF() {
+			return nil, ErrInvalidFontData
+		}
+		t.scripts[scriptTag] = indices
+	}
+
+	r = readerAt(b, featureListOffset)
+	featureCount := r.ReadUint16()
+	for i := 0; i < int(featureCount); i++ {
+		feature := layoutFeature{tag: r.ReadString(4)}
+		rFeature := readerAt(b, featureListOffset+uint32(r.ReadUint16()))
+		_ = rFeature.ReadUint16() // featureParamsOffset
+		lookupIndexCount := rFeature.ReadUint16()
+		for j := 0; j < int(lookupIndexCount); j++ {
+			feature.lookups = append(feature.lookups, rFeature.ReadUint16())
+		}
+		if rFeature.EOF() {
+			return nil, ErrInvalidFontData
+		}
+		t.features = append(t.features, feature)
+	}
+
+	r = readerAt(b, lookupListOffset)
+	lookupCount := r.ReadUint16()
+	for i := 0; i < int(lookupCount); i++ {
+		t.lookups = append(t.lookups, lookupListOffset+uint32(r.ReadUint16()))
+	}
+	if r.EOF() {
+		return nil, ErrInvalidFontData
+	}
+	return t, nil
+}
+
+// HasScript returns true if the GSUB or GPOS table has the script, such as "arab" or "dev2".
+func (s *Shaper) HasScript(script string) bool {
+	for _, t := range []*layoutTable{s.gsub, s.gpos} {
+		if t != nil {
+			if _, ok := t.scripts[script]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Features returns the tags of the features of the GSUB and GPOS tables in sorted order, such as "liga", "smcp", or "ss01".
+func (s *Shaper) Features() []string {
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, t := range []*layoutTable{s.gsub, s.gpos} {
+		if t == nil {
+			continue
+		}
+		for _, feature := range t.features {
+			if !seen[feature.tag] {
+				seen[feature.tag] = true
+				tags = append(tags, feature.tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// featureLookups returns the lookups of the table for the features of the default language system of the script, in the order in which they are applied. Scripts that the font does not have fall back to its default script.
+func (s *Shaper) featureLookups(t *layoutTable, script string, features []Feature) []featureLookup {
+	sb := strings.Builder{}
+	if t.gpos {
+		sb.WriteString("GPOS ")
+	} else {
+		sb.WriteString("GSUB ")
+	}
+	sb.WriteString(script)
+	for _, feature := range features {
+		sb.WriteString(" " + feature.Tag + ":" + strconv.FormatUint(uint64(feature.Mask), 16))
+	}
+	key := sb.String()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if lookups, ok := s.lookups[key]; ok {
+		return lookups
+	}
+
+	indices, ok := t.scripts[script]
+	for _, fallback := range []string{"DFLT", "dflt", "latn"} {
+		if !ok {
+			indices, ok = t.scripts[fallback]
+		}
+	}
+	masks := map[uint16]uint32{}
+	for k, index := range indices {
+		if len(t.features) <= int(index) {
+			continue // also the missing required feature
+		}
+		feature := t.features[index]
+		mask := uint32(0)
+		if k == 0 {
+			mask = 0xFFFFFFFF // required feature
+		}
+		for _, f := range features {
+			if f.Tag == feature.tag {
+				mask |= f.Mask
+			}
+		}
+		if mask != 0 {
+			for _, lookup := range feature.lookups {
+				masks[lookup] |= mask
+			}
+		}
+	}
+	lookups := make([]featureLookup, 0, len(masks))
+	for index, mask := range masks {
+		lookups = append(lookups, featureLookup{index, mask})
+	}
+	sort.Slice(lookups, func(i, j int) bool {
+		return lookups[i].index < lookups[j].index
+	})
+	s.lookups[key] = lookups
+	return lookups
+}
+
+// Substitute applies the substitutions of the GSUB table for the features of the script, such as "arab" or "latn", to the glyphs in logical order. Glyphs that are replaced by several glyphs keep their cluster and mask, and ligatures keep the cluster of their first component. All substitution types are supported, where alternate substitutions use the first alternate.
+func (s *Shaper) Substitute(glyphs []Glyph, script string, features []Feature) []Glyph {
+	if s.gsub == nil {
+		return glyphs
+	}
+	run := &shapeRun{s: s, t: s.gsub, glyphs: glyphs}
+	for _, lookup := range s.featureLookups(s.gsub, script, features) {
+		run.applyLookup(lookup.index, lookup.mask)
+	}
+	return run.glyphs
+}
+
+// Position applies the positioning of the GPOS table for the features of the script to the glyphs in logical order, whose advances must be set. Marks that are attached to base glyphs, ligatures, or other marks get a zero advance and are offset to their anchor. Cursive attachment is not supported.
+func (s *Shaper) Position(glyphs []Glyph, script string, features []Feature) {
+	if s.gpos == nil {
+		return
+	}
+	run := &shapeRun{s: s, t: s.gpos, glyphs: glyphs}
+	for _, lookup := range s.featureLookups(s.gpos, script, features) {
+		run.applyLookup(lookup.index, lookup.mask)
+	}
+}
+
+// glyphClass returns the class of the glyph from the GDEF table, which is 1 for base glyphs, 2 for ligatures, 3 for marks, 4 for components, or 0 if unknown.
+func (s *Shaper) glyphClass(glyph uint16) uint16 {
+	if s.glyphClassDef == 0 {
+		return 0
+	}
+	return classAt(s.gdef, s.glyphClassDef, glyph)
+}
+
+// isMark returns true if the glyph is a mark according to the GDEF table.
+func (s *Shaper) isMark(glyph uint16) bool {
+	return s.glyphClass(glyph) == 3
+}
+
+// shapeRun applies the lookups of a table to a glyph run.
+type shapeRun struct {
+	s      *Shaper
+	t      *layoutTable
+	glyphs []Glyph
+	depth  int
+}
+
+func (run *shapeRun) lookup(index uint16) (lookup, bool) {
+	if len(run.t.lookups) <= int(index) {
+		return lookup{}, false
+	}
+	offset := run.t.lookups[index]
+	extensionType := uint16(7)
+	if run.t.gpos {
+		extensionType = 9
+	}
+	typ, subtables, err := parseLookupSubtables(run.t.b, offset, extensionType)
+	if err != nil {
+		return lookup{}, false
+	}
+	l := lookup{
+		typ:       typ,
+		flag:      uint16At(run.t.b, offset+2),
+		subtables: subtables,
+	}
+	if l.flag&0x0010 != 0 {
+		l.markSet = uint16At(run.t.b, offset+6+2*uint32(len(subtables)))
+	}
+	return l, true
+}
+
+// ignore returns true if the lookup skips the glyph by its flags, such as marks when IgnoreMarks is set.
+func (run *shapeRun) ignore(l lookup, glyph uint16) bool {
+	if l.flag&0xFF1E == 0 {
+		return false
+	}
+	class := run.s.glyphClass(glyph)
+	if class == 1 && l.flag&0x0002 != 0 || class == 2 && l.flag&0x0004 != 0 || class == 3 && l.flag&0x0008 != 0 {
+		return true
+	} else if class == 3 && l.flag&0x0010 != 0 {
+		if run.s.markGlyphSets == 0 {
+			return false
+		}
+		sets := run.s.markGlyphSets
+		if uint16At(run.s.gdef, sets+2) <= l.markSet {
+			return true
+		}
+		offset := sets + binary.BigEndian.Uint32(bytesAt(run.s.gdef, sets+4+4*uint32(l.markSet), 4))
+		return coverageIndex(run.s.gdef, offset, glyph) < 0
+	} else if class == 3 && l.flag&0xFF00 != 0 && run.s.markAttachClassDef != 0 {
+		return classAt(run.s.gdef, run.s.markAttachClassDef, glyph) != l.flag>>8
+	}
+	return false
+}
+
+// next returns the position of the next glyph after i that is not skipped by the lookup, or the length of the run.
+func (run *shapeRun) next(l lookup, i int) int {
+	for i++; i < len(run.glyphs) && run.ignore(l, run.glyphs[i].ID); i++ {
+	}
+	return i
+}
+
+// prev returns the position of the previous glyph before i that is not skipped by the lookup, or -1.
+func (run *shapeRun) prev(l lookup, i int) int {
+	for i--; 0 <= i && run.ignore(l, run.glyphs[i].ID); i-- {
+	}
+	return i
+}
+
+// applyLookup applies the lookup to the glyphs of the run that have a bit of mask.
+func (run *shapeRun) applyLookup(index uint16, mask uint32) {
+	l, ok := run.lookup(index)
+	if !ok {
+		return
+	}
+	if !run.t.gpos && l.typ == 8 {
+		// reverse chaining substitutions are applied from the end
+		for i := len(run.glyphs) - 1; 0 <= i; i-- {
+			if run.glyphs[i].Mask&mask != 0 && !run.ignore(l, run.glyphs[i].ID) {
+				run.applySubtables(l, i)
+			}
+		}
+		return
+	}
+	for i := 0; i < len(run.glyphs); {
+		if run.glyphs[i].Mask&mask != 0 && !run.ignore(l, run.glyphs[i].ID) {
+			if next, ok := run.applySubtables(l, i); ok {
+				i = next
+				continue
+			}
+		}
+		i++
+	}
+}
+
+// applyLookupAt applies the lookup at a single position for contextual lookups.
+func (run *shapeRun) applyLookupAt(index uint16, i int) {
+	l, ok := run.lookup(index)
+	if !ok || maxLookupDepth <= run.depth || len(run.glyphs) <= i || run.ignore(l, run.glyphs[i].ID) {
+		return
+	}
+	run.depth++
+	run.applySubtables(l, i)
+	run.depth--
+}
+
+// applySubtables applies the first subtable of the lookup that matches at position i, and returns the position to continue at.
+func (run *shapeRun) applySubtables(l lookup, i int) (int, bool) {
+	for _, subtable := range l.subtables {
+		var next int
+		var ok bool
+		if run.t.gpos {
+			next, ok = run.position(l, subtable, i)
+		} else {
+			next, ok = run.substitute(l, subtable, i)
+		}
+		if ok {
+			return next, true
+		}
+	}
+	return i, false
+}
+
+func (run *shapeRun) substitute(l lookup, sub uint32, i int) (int, bool) {
+	b := run.t.b
+	format := uint16At(b, sub)
+	glyph := &run.glyphs[i]
+	switch l.typ {
+	case 1: // single
+		k := coverageIndex(b, sub+uint32(uint16At(b, sub+2)), glyph.ID)
+		if k < 0 {
+			return i, false
+		} else if format == 1 {
+			glyph.ID += uint16At(b, sub+4) // modulo 65536
+		} else if format == 2 && k < int(uint16At(b, sub+4)) {
+			glyph.ID = uint16At(b, sub+6+2*uint32(k))
+		} else {
+			return i, false
+		}
+		return i + 1, true
+	case 2: // multiple
+		k := coverageIndex(b, sub+uint32(uint16At(b, sub+2)), glyph.ID)
+		if k < 0 || format != 1 || int(uint16At(b, sub+4)) <= k {
+			return i, false
+		}
+		seq := sub + uint32(uint16At(b, sub+6+2*uint32(k)))
+		n := int(uint16At(b, seq))
+		glyphs := make([]Glyph, n)
+		for j := range glyphs {
+			glyphs[j] = *glyph
+			glyphs[j].ID = uint16At(b, seq+2+2*uint32(j))
+		}
+		run.glyphs = append(run.glyphs[:i], append(glyphs, run.glyphs[i+1:]...)...)
+		return i + n, true
+	case 3: // alternate
+		k := coverageIndex(b, sub+uint32(uint16At(b, sub+2)), glyph.ID)
+		if k < 0 || format != 1 || int(uint16At(b, sub+4)) <= k {
+			return i, false
+		}
+		set := sub + uint32(uint16At(b, sub+6+2*uint32(k)))
+		if uint16At(b, set) == 0 {
+			return i, false
+		}
+		glyph.ID = uint16At(b, set+2)
+		return i + 1, true
+	case 4: // ligature
+		k := coverageIndex(b, sub+uint32(uint16At(b, sub+2)), glyph.ID)
+		if k < 0 || format != 1 || int(uint16At(b, sub+4)) <= k {
+			return i, false
+		}
+		set := sub + uint32(uint16At(b, sub+6+2*uint32(k)))
+		for j := 0; j < int(uint16At(b, set)); j++ {
+			lig := set + uint32(uint16At(b, set+2+2*uint32(j)))
+			n := int(uint16At(b, lig+2))
+			positions, ok := run.matchInput(l, i, n, func(pos, k int) bool {
+				return run.glyphs[pos].ID == uint16At(b, lig+4+2*uint32(k-1))
+			})
+			if !ok || n == 0 {
+				continue
+			}
+			glyph.ID = uint16At(b, lig)
+			for k := 1; k < len(positions); k++ {
+				for pos := positions[k-1] + 1; pos < positions[k]; pos++ {
+					run.glyphs[pos].component = k // skipped marks belong to the preceding component
+				}
+			}
+			for k := len(positions) - 1; 0 < k; k-- {
+				run.glyphs = append(run.glyphs[:positions[k]], run.glyphs[positions[k]+1:]...)
+			}
+			return i + 1, true
+		}
+		return i, false
+	case 5, 6:
+		return run.context(l, sub, i, l.typ == 6)
+	case 8: // reverse chaining single
+		k := coverageIndex(b, sub+uint32(uint16At(b, sub+2)), glyph.ID)
+		if k < 0 || format != 1 {
+			return i, false
+		}
+		r := readerAt(b, sub+4)
+		backtrack := make([]uint32, r.ReadUint16())
+		for j := range backtrack {
+			backtrack[j] = sub + uint32(r.ReadUint16())
+		}
+		lookahead := make([]uint32, r.ReadUint16())
+		for j := range lookahead {
+			lookahead[j] = sub + uint32(r.ReadUint16())
+		}
+		glyphCount := r.ReadUint16()
+		if r.EOF() || int(glyphCount) <= k {
+			return i, false
+		}
+		coverage := func(offsets []uint32) func(int, int) bool {
+			return func(pos, k int) bool {
+				return 0 <= coverageIndex(b, offsets[k], run.glyphs[pos].ID)
+			}
+		}
+		if !run.matchBacktrack(l, i, len(backtrack), coverage(backtrack)) || !run.matchLookahead(l, i, len(lookahead), coverage(lookahead)) {
+			return i, false
+		}
+		r = readerAt(b, r.Pos()+sub+4+2*uint32(k))
+		glyph.ID = r.ReadUint16()
+		return i - 1, true
+	}
+	return i, false
+}
+
+func (run *shapeRun) position(l lookup, sub uint32, i int) (int, bool) {
+	b := run.t.b
+	format := uint16At(b, sub)
+	switch l.typ {
+	case 1: // single
+		k := coverageIndex(b, sub+uint32(uint16At(b, sub+2)), run.glyphs[i].ID)
+		if k < 0 {
+			return i, false
+		}
+		valueFormat := uint16At(b, sub+4)
+		if format == 1 {
+			run.applyValue(i, sub+6, valueFormat)
+		} else if format == 2 && k < int(uint16At(b, sub+6)) {
+			run.applyValue(i, sub+8+uint32(k)*valueSize(valueFormat), valueFormat)
+		} else {
+			return i, false
+		}
+		return i + 1, true
+	case 2: // pair
+		k := coverageIndex(b, sub+uint32(uint16At(b, sub+2)), run.glyphs[i].ID)
+		j := run.next(l, i)
+		if k < 0 || len(run.glyphs) <= j {
+			return i, false
+		}
+		valueFormat1, valueFormat2 := uint16At(b, sub+4), uint16At(b, sub+6)
+		size1, size2 := valueSize(valueFormat1), valueSize(valueFormat2)
+		var record uint32
+		if format == 1 {
+			if int(uint16At(b, sub+8)) <= k {
+				return i, false
+			}
+			set := sub + uint32(uint16At(b, sub+10+2*uint32(k)))
+			n := int(uint16At(b, set))
+			size := 2 + size1 + size2
+			second := run.glyphs[j].ID
+			m := sort.Search(n, func(m int) bool {
+				return second <= uint16At(b, set+2+uint32(m)*size)
+			})
+			if n <= m || uint16At(b, set+2+uint32(m)*size) != second {
+				return i, false
+			}
+			record = set + 2 + uint32(m)*size + 2
+		} else if format == 2 {
+			class1 := classAt(b, sub+uint32(uint16At(b, sub+8)), run.glyphs[i].ID)
+			class2 := classAt(b, sub+uint32(uint16At(b, sub+10)), run.glyphs[j].ID)
+			class1Count, class2Count := uint16At(b, sub+12), uint16At(b, sub+14)
+			if class1Count <= class1 || class2Count <= class2 {
+				return i, false
+			}
+			record = sub + 16 + (uint32(class1)*uint32(class2Count)+uint32(class2))*(size1+size2)
+		} else {
+			return i, false
+		}
+		run.applyValue(i, record, valueFormat1)
+		run.applyValue(j, record+size1, valueFormat2)
+		if valueFormat2 != 0 {
+			return j + 1, true
+		}
+		return j, true
+	case 4, 5, 6: // mark to base, mark to ligature, mark to mark
+		markIndex := coverageIndex(b, sub+uint32(uint16At(b, sub+2)), run.glyphs[i].ID)
+		if markIndex < 0 || format != 1 {
+			return i, false
+		}
+		j := i - 1
+		if l.typ == 6 {
+			j = run.prev(l, i)
+			if j < 0 || !run.s.isMark(run.glyphs[j].ID) {
+				return i, false
+			}
+		} else {
+			for 0 <= j && run.s.isMark(run.glyphs[j].ID) {
+				j--
+			}
+			if j < 0 {
+				return i, false
+			}
+		}
+		baseIndex := coverageIndex(b, sub+uint32(uint16At(b, sub+4)), run.glyphs[j].ID)
+		classCount := uint32(uint16At(b, sub+6))
+		markArray := sub + uint32(uint16At(b, sub+8))
+		baseArray := sub + uint32(uint16At(b, sub+10))
+		if baseIndex < 0 || int(uint16At(b, markArray)) <= markIndex || int(uint16At(b, baseArray)) <= baseIndex {
+			return i, false
+		}
+		class := uint32(uint16At(b, markArray+2+4*uint32(markIndex)))
+		markAnchor := markArray + uint32(uint16At(b, markArray+2+4*uint32(markIndex)+2))
+		if classCount <= class {
+			return i, false
+		}
+		var baseAnchor uint32
+		if l.typ == 5 {
+			attach := baseArray + uint32(uint16At(b, baseArray+2+2*uint32(baseIndex)))
+			components := uint32(uint16At(b, attach))
+			component := uint32(run.glyphs[i].component)
+			if component == 0 || components < component {
+				component = components
+			}
+			if component == 0 {
+				return i, false
+			}
+			offset := uint32(uint16At(b, attach+2+2*((component-1)*classCount+class)))
+			if offset == 0 {
+				return i, false
+			}
+			baseAnchor = attach + offset
+		} else {
+			offset := uint32(uint16At(b, baseArray+2+2*(uint32(baseIndex)*classCount+class)))
+			if offset == 0 {
+				return i, false
+			}
+			baseAnchor = baseArray + offset
+		}
+		run.attach(i, j, markAnchor, baseAnchor)
+		return i + 1, true
+	case 7, 8:
+		return run.context(l, sub, i, l.typ == 8)
+	}
+	return i, false
+}
+
+// valueSize returns the size of a value record in bytes.
+func valueSize(valueFormat uint16) uint32 {
+	size := uint32(0)
+	for bit := uint16(0); bit < 8; bit++ {
+		if valueFormat&(1<<bit) != 0 {
+			size += 2
+		}
+	}
+	return size
+}
+
+// applyValue adds the placement and advance of a value record to the glyph at position i. Device tables are ignored.
+func (run *shapeRun) applyValue(i int, record uint32, valueFormat uint16) {
+	glyph := &run.glyphs[i]
+	for bit := uint16(0); bit < 4; bit++ {
+		if valueFormat&(1<<bit) == 0 {
+			continue
+		}
+		v := int32(int16(uint16At(run.t.b, record)))
+		switch bit {
+		case 0:
+			glyph.XOffset += v
+		case 1:
+			glyph.YOffset += v
+		case 2:
+			glyph.XAdvance += v
+		}
+		record += 2
+	}
+}
+
+// attach places the mark at position i on its anchor of the glyph at position j.
+func (run *shapeRun) attach(i, j int, markAnchor, baseAnchor uint32) {
+	b := run.t.b
+	markX, markY := int32(int16(uint16At(b, markAnchor+2))), int32(int16(uint16At(b, markAnchor+4)))
+	baseX, baseY := int32(int16(uint16At(b, baseAnchor+2))), int32(int16(uint16At(b, baseAnchor+4)))
+
+	mark := &run.glyphs[i]
+	mark.XAdvance = 0
+	advance := int32(0) // from the base to the mark
+	for k := j; k < i; k++ {
+		advance += run.glyphs[k].XAdvance
+	}
+	mark.XOffset = run.glyphs[j].XOffset + baseX - markX - advance
+	mark.YOffset = run.glyphs[j].YOffset + baseY - markY
+}
+
+// context applies the (chained) contextual substitution or positioning subtable at position i, and returns the position after its input sequence.
+func (run *shapeRun) context(l lookup, sub uint32, i int, chained bool) (int, bool) {
+	b := run.t.b
+	format := uint16At(b, sub)
+	glyph := run.glyphs[i].ID
+	if format == 3 {
+		r := readerAt(b, sub+2)
+		var backtrack, lookahead []uint32
+		if chained {
+			backtrack = make([]uint32, r.ReadUint16())
+			for k := range backtrack {
+				backtrack[k] = sub + uint32(r.ReadUint16())
+			}
+		}
+		input := make([]uint32, r.ReadUint16())
+		var recordCount uint16
+		if !chained {
+			recordCount = r.ReadUint16()
+		}
+		for k := range input {
+			input[k] = sub + uint32(r.ReadUint16())
+		}
+		if chained {
+			lookahead = make([]uint32, r.ReadUint16())
+			for k := range lookahead {
+				lookahead[k] = sub + uint32(r.ReadUint16())
+			}
+			recordCount = r.ReadUint16()
+		}
+		if r.EOF() || len(input) == 0 || coverageIndex(b, input[0], glyph) < 0 {
+			return i, false
+		}
+		coverage := func(offsets []uint32) func(int, int) bool {
+			return func(pos, k int) bool {
+				return 0 <= coverageIndex(b, offsets[k], run.glyphs[pos].ID)
+			}
+		}
+		return run.applyRule(l, i, sub+2+r.Pos(), len(input), recordCount, coverage(input), len(backtrack), coverage(backtrack), len(lookahead), coverage(lookahead))
+	}
+
+	k := coverageIndex(b, sub+uint32(uint16At(b, sub+2)), glyph)
+	if k < 0 {
+		return i, false
+	}
+	var set uint32
+	var input, backtrack, lookahead func(int) uint16 // value of the glyph at a position
+	if format == 1 {
+		if int(uint16At(b, sub+4)) <= k {
+			return i, false
+		}
+		set = uint32(uint16At(b, sub+6+2*uint32(k)))
+		id := func(pos int) uint16 {
+			return run.glyphs[pos].ID
+		}
+		input, backtrack, lookahead = id, id, id
+	} else if format == 2 {
+		classes := func(offset uint32) func(int) uint16 {
+			return func(pos int) uint16 {
+				return classAt(b, sub+offset, run.glyphs[pos].ID)
+			}
+		}
+		sets := sub + 6
+		if chained {
+			backtrack = classes(uint32(uint16At(b, sub+4)))
+			input = classes(uint32(uint16At(b, sub+6)))
+			lookahead = classes(uint32(uint16At(b, sub+8)))
+			sets = sub + 10
+		} else {
+			input = classes(uint32(uint16At(b, sub+4)))
+		}
+		class := input(i)
+		if uint16At(b, sets) <= class {
+			return i, false
+		}
+		set = uint32(uint16At(b, sets+2+2*uint32(class)))
+	} else {
+		return i, false
+	}
+	if set == 0 {
+		return i, false
+	}
+	set += sub
+
+	for j := 0; j < int(uint16At(b, set)); j++ {
+		start := set + uint32(uint16At(b, set+2+2*uint32(j)))
+		r := readerAt(b, start)
+		var backtrackValues, lookaheadValues []uint16
+		if chained {
+			backtrackValues = make([]uint16, r.ReadUint16())
+			for k := range backtrackValues {
+				backtrackValues[k] = r.ReadUint16()
+			}
+		}
+		inputValues := make([]uint16, r.ReadUint16())
+		var recordCount uint16
+		if !chained {
+			recordCount = r.ReadUint16()
+		}
+		for k := 1; k < len(inputValues); k++ {
+			inputValues[k] = r.ReadUint16()
+		}
+		if chained {
+			lookaheadValues = make([]uint16, r.ReadUint16())
+			for k := range lookaheadValues {
+				lookaheadValues[k] = r.ReadUint16()
+			}
+			recordCount = r.ReadUint16()
+		}
+		if r.EOF() || len(inputValues) == 0 {
+			continue
+		}
+		matches := func(values []uint16, value func(int) uint16) func(int, int) bool {
+			return func(pos, k int) bool {
+				return value(pos) == values[k]
+			}
+		}
+		if next, ok := run.applyRule(l, i, start+r.Pos(), len(inputValues), recordCount, matches(inputValues, input), len(backtrackValues), matches(backtrackValues, backtrack), len(lookaheadValues), matches(lookaheadValues, lookahead)); ok {
+			return next, true
+		}
+	}
+	return i, false
+}
+
+// applyRule matches the input sequence at position i whose first glyph is already matched, and the backtrack and lookahead sequences around it, and applies the sequence lookup records. It returns the position after the input sequence.
+func (run *shapeRun) applyRule(l lookup, i int, records uint32, inputCount int, recordCount uint16, input func(int, int) bool, backtrackCount int, backtrack func(int, int) bool, lookaheadCount int, lookahead func(int, int) bool) (int, bool) {
+	positions, ok := run.matchInput(l, i, inputCount, input)
+	if !ok || !run.matchBacktrack(l, i, backtrackCount, backtrack) || !run.matchLookahead(l, positions[len(positions)-1], lookaheadCount, lookahead) {
+		return i, false
+	}
+	for k := 0; k < int(recordCount); k++ {
+		sequenceIndex := int(uint16At(run.t.b, records+4*uint32(k)))
+		lookupIndex := uint16At(run.t.b, records+4*uint32(k)+2)
+		if len(positions) <= sequenceIndex {
+			continue
+		}
+		n := len(run.glyphs)
+		run.applyLookupAt(lookupIndex, positions[sequenceIndex])
+		if d := len(run.glyphs) - n; d != 0 {
+			// substitutions that change the number of glyphs shift the positions that follow
+			for m := sequenceIndex + 1; m < len(positions); m++ {
+				positions[m] += d
+				if positions[m] <= positions[m-1] {
+					positions[m] = positions[m-1] + 1
+				}
+			}
+		}
+	}
+	next := positions[len(positions)-1] + 1
+	if len(run.glyphs) < next {
+		next = len(run.glyphs)
+	}
+	return next, true
+}
+
+// matchInput returns the positions of the input sequence of n glyphs at position i, where match returns true if the glyph at a position matches the k-th glyph of the sequence. The first glyph is not matched.
+func (run *shapeRun) matchInput(l lookup, i, n int, match func(int, int) bool) ([]int, bool) {
+	positions := []int{i}
+	for k := 1; k < n; k++ {
+		i = run.next(l, i)
+		if len(run.glyphs) <= i || !match(i, k) {
+			return nil, false
+		}
+		positions = append(positions, i)
+	}
+	return positions, true
+}
+
+// matchBacktrack returns true if the n glyphs before position i match in reverse order.
+func (run *shapeRun) matchBacktrack(l lookup, i, n int, match func(int, int) bool) bool {
+	for k := 0; k < n; k++ {
+		if i = run.prev(l, i); i < 0 || !match(i, k) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchLookahead returns true if the n glyphs after position i match.
+func (run *shapeRun) matchLookahead(l lookup, i, n int, match func(int, int) bool) bool {
+	for k := 0; k < n; k++ {
+		if i = run.next(l, i); len(run.glyphs) <= i || !match(i, k) {
+			return false
+		}
+	}
+	return true
+}
+
+// coverageIndex returns the coverage index of the glyph in the coverage table at offset, or -1 if it is not covered.
+func coverageIndex(b []byte, offset uint32, glyph uint16) int {
+	switch uint16At(b, offset) {
+	case 1:
+		n := int(uint16At(b, offset+2))
+		i := sort.Search(n, func(i int) bool {
+			return glyph <= uint16At(b, offset+4+2*uint32(i))
+		})
+		if i < n && uint16At(b, offset+4+2*uint32(i)) == glyph {
+			return i
+		}
+	case 2:
+		n := int(uint16At(b, offset+2))
+		i := sort.Search(n, func(i int) bool {
+			return glyph <= uint16At(b, offset+4+6*uint32(i)+2) // endGlyphID
+		})
+		if record := offset + 4 + 6*uint32(i); i < n && uint16At(b, record) <= glyph {
+			return int(uint16At(b, record+4)) + int(glyph-uint16At(b, record))
+		}
+	}
+	return -1
+}
+
+// classAt returns the class of the glyph in the class definition table at offset, which is zero for glyphs that are not included.
+func classAt(b []byte, offset uint32, glyph uint16) uint16 {
+	switch uint16At(b, offset) {
+	case 1:
+		start := uint16At(b, offset+2)
+		if start <= glyph && glyph-start < uint16At(b, offset+4) {
+			return uint16At(b, offset+6+2*uint32(glyph-start))
+		}
+	case 2:
+		n := int(uint16At(b, offset+2))
+		i := sort.Search(n, func(i int) bool {
+			return glyph <= uint16At(b, offset+4+6*uint32(i)+2) // endGlyphID
+		})
+		if record := offset + 4 + 6*uint32(i); i < n && uint16At(b, record) <= glyph {
+			return uint16At(b, record+4)
+		}
+	}
+	return 0
+}
+
+// uint16At returns the big-endian uint16 at the offset of b, or zero if it is out of range.
+func uint16At(b []byte, offset uint32) uint16 {
+	if uint32(len(b)) < 2 || uint32(len(b))-2 < offset {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b[offset:])
+}
+
+// bytesAt returns n bytes at the offset of b, which are zero if they are out of range.
+func bytesAt(b []byte, offset, n uint32) []byte {
+	if uint32(len(b)) < n || uint32(len(b))-n < offset {
+		return make([]byte, n)
+	}
+	return b[offset : offset+n]
+}
@@ -0,0 +1,184 @@
+package font
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// SubsetSFNT returns the SFNT font (TTF or OTF) where the outlines of all glyphs other than the given glyphs, the glyphs of which they are composed, and the .notdef glyph are removed. Glyph indices are retained so that the font can be used for the same text, and all tables other than the glyph outlines are kept as is. Only TrueType outlines (glyf) are removed, fonts with CFF outlines are returned unchanged.
+func SubsetSFNT(b []byte, glyphs []uint16) ([]byte, error) {
+	head, err := SFNTTable(b, "head")
+	if err != nil {
+		return nil, err
+	}
+	loca, err := SFNTTable(b, "loca")
+	if err != nil {
+		return nil, err
+	}
+	glyf, err := SFNTTable(b, "glyf")
+	if err != nil {
+		return nil, err
+	}
+	maxp, err := SFNTTable(b, "maxp")
+	if err != nil {
+		return nil, err
+	} else if glyf == nil || loca == nil {
+		return b, nil // CFF outlines
+	} else if head == nil || len(head) < 54 || maxp == nil || len(maxp) < 6 {
+		return nil, ErrInvalidFontData
+	}
+
+	// glyph offsets in glyf
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+	longOffsets := binary.BigEndian.Uint16(head[50:]) == 1
+	offsets := make([]uint32, numGlyphs+1)
+	for i := range offsets {
+		if longOffsets && 4*i+4 <= len(loca) {
+			offsets[i] = binary.BigEndian.Uint32(loca[4*i:])
+		} else if !longOffsets && 2*i+2 <= len(loca) {
+			offsets[i] = 2 * uint32(binary.BigEndian.Uint16(loca[2*i:]))
+		} else {
+			return nil, ErrInvalidFontData
+		}
+		if uint32(len(glyf)) < offsets[i] || 0 < i && offsets[i] < offsets[i-1] {
+			return nil, ErrInvalidFontData
+		}
+	}
+
+	// add the components of composite glyphs
+	used := make([]bool, numGlyphs)
+	queue := append([]uint16{0}, glyphs...)
+	for 0 < len(queue) {
+		glyph := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if numGlyphs <= int(glyph) || used[glyph] {
+			continue
+		}
+		used[glyph] = true
+
+		data := glyf[offsets[glyph]:offsets[glyph+1]]
+		if len(data) < 10 || 0 <= int16(binary.BigEndian.Uint16(data)) {
+			continue // empty or simple glyph
+		}
+		r := newBinaryReader(data[10:])
+		for {
+			flags := r.ReadUint16()
+			queue = append(queue, r.ReadUint16())
+			if flags&0x0001 != 0 { // ARG_1_AND_2_ARE_WORDS
+				_ = r.ReadBytes(4)
+			} else {
+				_ = r.ReadBytes(2)
+			}
+			if flags&0x0008 != 0 { // WE_HAVE_A_SCALE
+				_ = r.ReadBytes(2)
+			} else if flags&0x0040 != 0 { // WE_HAVE_AN_X_AND_Y_SCALE
+				_ = r.ReadBytes(4)
+			} else if flags&0x0080 != 0 { // WE_HAVE_A_TWO_BY_TWO
+				_ = r.ReadBytes(8)
+			}
+			if r.EOF() {
+				return nil, ErrInvalidFontData
+			} else if flags&0x0020 == 0 { // MORE_COMPONENTS
+				break
+			}
+		}
+	}
+
+	// new glyf and loca tables with long offsets
+	wGlyf := newBinaryWriter([]byte{})
+	wLoca := newBinaryWriter(make([]byte, 0, 4*(numGlyphs+1)))
+	for i := 0; i < numGlyphs; i++ {
+		wLoca.WriteUint32(wGlyf.Len())
+		if used[i] {
+			wGlyf.WriteBytes(glyf[offsets[i]:offsets[i+1]])
+			for wGlyf.Len()%4 != 0 {
+				wGlyf.WriteByte(0)
+			}
+		}
+	}
+	wLoca.WriteUint32(wGlyf.Len())
+	head = append([]byte{}, head...)
+	binary.BigEndian.PutUint16(head[50:], 1) // indexToLocFormat
+	return writeSFNT(b, map[string][]byte{
+		"glyf": wGlyf.Bytes(),
+		"loca": wLoca.Bytes(),
+		"head": head,
+	})
+}
+
+// writeSFNT returns the SFNT font with some of its tables replaced or added, or removed when replaced by nil, and recalculates the checksums.
+func writeSFNT(b []byte, replace map[string][]byte) ([]byte, error) {
+	r := newBinaryReader(b)
+	flavor := r.ReadUint32()
+	numTables := r.ReadUint16()
+	_ = r.ReadBytes(6) // searchRange, entrySelector, rangeShift
+	tags := []string{}
+	tables := map[string][]byte{}
+	for i := 0; i < int(numTables); i++ {
+		tag := r.ReadString(4)
+		_ = r.ReadUint32() // checksum
+		offset := r.ReadUint32()
+		length := r.ReadUint32()
+		if r.EOF() || uint32(len(b)) < offset || uint32(len(b))-offset < length {
+			return nil, ErrInvalidFontData
+		}
+		tables[tag] = b[offset : offset+length]
+		if data, ok := replace[tag]; ok {
+			if data == nil {
+				continue
+			}
+			tables[tag] = data
+		}
+		tags = append(tags, tag)
+	}
+	for tag, data := range replace {
+		if _, ok := tables[tag]; !ok && data != nil {
+			tags = append(tags, tag)
+			tables[tag] = data
+		}
+	}
+	sort.Strings(tags)
+
+	entrySelector := uint16(0)
+	for 1<<(entrySelector+1) <= len(tags) {
+		entrySelector++
+	}
+	searchRange := uint16(16 << entrySelector)
+
+	w := newBinaryWriter([]byte{})
+	w.WriteUint32(flavor)
+	w.WriteUint16(uint16(len(tags)))
+	w.WriteUint16(searchRange)
+	w.WriteUint16(entrySelector)
+	w.WriteUint16(uint16(16*len(tags)) - searchRange)
+
+	iCheckSumAdjustment := uint32(0)
+	offset := uint32(12 + 16*len(tags))
+	for _, tag := range tags {
+		data := tables[tag]
+		padded := make([]byte, (len(data)+3)&^3)
+		copy(padded, data)
+		if tag == "head" {
+			if len(padded) < 12 {
+				return nil, ErrInvalidFontData
+			}
+			binary.BigEndian.PutUint32(padded[8:], 0) // checkSumAdjustment
+			iCheckSumAdjustment = offset + 8
+		}
+		tables[tag] = padded
+		w.WriteString(tag)
+		w.WriteUint32(calcChecksum(padded))
+		w.WriteUint32(offset)
+		w.WriteUint32(uint32(len(data)))
+		offset += uint32(len(padded))
+	}
+	for _, tag := range tags {
+		w.WriteBytes(tables[tag])
+	}
+
+	buf := w.Bytes()
+	if iCheckSumAdjustment != 0 {
+		binary.BigEndian.PutUint32(buf[iCheckSumAdjustment:], 0xB1B0AFBA-calcChecksum(buf))
+	}
+	return buf, nil
+}
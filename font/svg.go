@@ -0,0 +1,84 @@
+package font
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"sort"
+)
+
+// SVGGlyphs are the SVG documents of the SVG table, which draw glyphs in color.
+type SVGGlyphs struct {
+	list    []byte // SVG document list
+	records []svgDocumentRecord
+}
+
+type svgDocumentRecord struct {
+	startGlyphID, endGlyphID uint16
+	offset, length           uint32
+}
+
+// ParseSVGGlyphs parses the document list of the SVG table of an SFNT font (TTF or OTF). It returns nil if the font has no SVG table.
+// See https://learn.microsoft.com/en-us/typography/opentype/spec/svg
+func ParseSVGGlyphs(b []byte) (*SVGGlyphs, error) {
+	table, err := SFNTTable(b, "SVG ")
+	if err != nil || table == nil {
+		return nil, err
+	}
+	r := newBinaryReader(table)
+	_ = r.ReadUint16() // version
+	svgDocumentListOffset := r.ReadUint32()
+	if r.EOF() {
+		return nil, ErrInvalidFontData
+	}
+
+	list := readerAt(table, svgDocumentListOffset)
+	numEntries := list.ReadUint16()
+	glyphs := &SVGGlyphs{
+		list:    table[svgDocumentListOffset:],
+		records: make([]svgDocumentRecord, numEntries),
+	}
+	for i := range glyphs.records {
+		record := svgDocumentRecord{
+			startGlyphID: list.ReadUint16(),
+			endGlyphID:   list.ReadUint16(),
+			offset:       list.ReadUint32(),
+			length:       list.ReadUint32(),
+		}
+		if list.EOF() || record.endGlyphID < record.startGlyphID || 0 < i && record.startGlyphID <= glyphs.records[i-1].endGlyphID {
+			return nil, ErrInvalidFontData
+		} else if uint32(len(glyphs.list)) < record.offset || uint32(len(glyphs.list))-record.offset < record.length {
+			return nil, ErrInvalidFontData
+		}
+		glyphs.records[i] = record
+	}
+	return glyphs, nil
+}
+
+// Document returns the SVG document that draws a glyph, where the glyph is the element with id glyphN for glyph index N, or nil if the glyph has no document. Documents that are compressed by gzip are decompressed, up to MaxMemory bytes.
+func (s *SVGGlyphs) Document(glyphID uint16) ([]byte, error) {
+	i := sort.Search(len(s.records), func(i int) bool {
+		return glyphID <= s.records[i].endGlyphID
+	})
+	if i == len(s.records) || glyphID < s.records[i].startGlyphID {
+		return nil, nil
+	}
+	record := s.records[i]
+	doc := s.list[record.offset : record.offset+record.length]
+	if !bytes.HasPrefix(doc, []byte{0x1F, 0x8B}) {
+		return doc, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(doc))
+	if err != nil {
+		return nil, err
+	}
+	doc, err = ioutil.ReadAll(io.LimitReader(r, int64(MaxMemory)+1))
+	if err != nil {
+		return nil, err
+	} else if int64(MaxMemory) < int64(len(doc)) {
+		return nil, ErrExceedsMemory
+	}
+	return doc, nil
+}
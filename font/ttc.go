@@ -0,0 +1,55 @@
+package font
+
+import (
+	"fmt"
+)
+
+// NumCollectionFonts returns the number of fonts in a TrueType or OpenType collection (TTC or OTC).
+func NumCollectionFonts(b []byte) (int, error) {
+	r := newBinaryReader(b)
+	if r.ReadString(4) != "ttcf" {
+		return 0, fmt.Errorf("not a font collection")
+	}
+	_ = r.ReadUint32() // majorVersion, minorVersion
+	numFonts := r.ReadUint32()
+	if r.EOF() || r.Len()/4 < numFonts {
+		return 0, ErrInvalidFontData
+	}
+	return int(numFonts), nil
+}
+
+// ParseCollection returns the SFNT font (TTF or OTF) at index of a TrueType or OpenType collection (TTC or OTC), with copies of the tables that it shares with the other fonts of the collection.
+// See https://learn.microsoft.com/en-us/typography/opentype/spec/otff#font-collections
+func ParseCollection(b []byte, index int) ([]byte, error) {
+	numFonts, err := NumCollectionFonts(b)
+	if err != nil {
+		return nil, err
+	} else if index < 0 || numFonts <= index {
+		return nil, fmt.Errorf("font index %d out of range of %d fonts", index, numFonts)
+	}
+	offset := readerAt(b, 12+4*uint32(index)).ReadUint32()
+
+	r := readerAt(b, offset)
+	sfntVersion := r.ReadBytes(4)
+	numTables := r.ReadUint16()
+	_ = r.ReadBytes(6) // searchRange, entrySelector, rangeShift
+	tables := map[string][]byte{}
+	for i := 0; i < int(numTables); i++ {
+		tag := r.ReadString(4)
+		_ = r.ReadUint32() // checksum
+		offset := r.ReadUint32()
+		length := r.ReadUint32()
+		if r.EOF() || uint32(len(b)) < offset || uint32(len(b))-offset < length {
+			return nil, ErrInvalidFontData
+		}
+		tables[tag] = b[offset : offset+length]
+	}
+	if r.EOF() {
+		return nil, ErrInvalidFontData
+	}
+
+	// all tables are added to an empty font
+	header := make([]byte, 12)
+	copy(header, sfntVersion)
+	return writeSFNT(header, tables)
+}
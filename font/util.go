@@ -7,6 +7,12 @@ import (
 
 var ErrInvalidFontData = fmt.Errorf("invalid font data")
 
+// ErrExceedsMemory is returned when a font decompresses to more than MaxMemory bytes.
+var ErrExceedsMemory = fmt.Errorf("memory limit exceeded")
+
+// MaxMemory is the maximum size in bytes of a decompressed WOFF or WOFF2 font, so that crafted fonts that declare or decompress to huge sizes cannot exhaust memory.
+var MaxMemory uint32 = 30 * 1024 * 1024
+
 func calcChecksum(b []byte) uint32 {
 	if len(b)%4 != 0 {
 		panic("data not multiple of four bytes")
@@ -0,0 +1,769 @@
+package font
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// VariationAxis is an axis of a variable font, such as wght for the weight, with its range of values.
+type VariationAxis struct {
+	Tag               string
+	Min, Default, Max float64
+	Hidden            bool
+	NameID            uint16 // of the name table
+}
+
+// NamedInstance is a named instance of a variable font, such as Bold, with the values of its axes.
+type NamedInstance struct {
+	Coordinates map[string]float64
+	NameID      uint16 // of the subfamily name in the name table
+}
+
+// variationTables are removed from instances of variable fonts.
+var variationTables = []string{"fvar", "gvar", "avar", "cvar", "HVAR", "VVAR", "MVAR"}
+
+// ParseVariationAxes parses the axes and named instances of the fvar table of an SFNT font (TTF or OTF). It returns no axes if the font is not a variable font.
+// See https://learn.microsoft.com/en-us/typography/opentype/spec/fvar
+func ParseVariationAxes(b []byte) ([]VariationAxis, []NamedInstance, error) {
+	fvar, err := SFNTTable(b, "fvar")
+	if err != nil || fvar == nil {
+		return nil, nil, err
+	}
+	r := newBinaryReader(fvar)
+	majorVersion := r.ReadUint16()
+	_ = r.ReadUint16() // minorVersion
+	axesArrayOffset := uint32(r.ReadUint16())
+	_ = r.ReadUint16() // reserved
+	axisCount := r.ReadUint16()
+	axisSize := r.ReadUint16()
+	instanceCount := r.ReadUint16()
+	instanceSize := r.ReadUint16()
+	if r.EOF() || majorVersion != 1 || axisSize < 20 || instanceSize < 4+4*axisCount {
+		return nil, nil, ErrInvalidFontData
+	}
+
+	axes := make([]VariationAxis, axisCount)
+	for i := range axes {
+		rAxis := readerAt(fvar, axesArrayOffset+uint32(i)*uint32(axisSize))
+		axes[i].Tag = rAxis.ReadString(4)
+		axes[i].Min = fixedToFloat64(rAxis.ReadUint32())
+		axes[i].Default = fixedToFloat64(rAxis.ReadUint32())
+		axes[i].Max = fixedToFloat64(rAxis.ReadUint32())
+		axes[i].Hidden = rAxis.ReadUint16()&0x0001 != 0
+		axes[i].NameID = rAxis.ReadUint16()
+		if rAxis.EOF() || axes[i].Default < axes[i].Min || axes[i].Max < axes[i].Default {
+			return nil, nil, ErrInvalidFontData
+		}
+	}
+	instances := make([]NamedInstance, instanceCount)
+	for i := range instances {
+		rInstance := readerAt(fvar, axesArrayOffset+uint32(axisCount)*uint32(axisSize)+uint32(i)*uint32(instanceSize))
+		instances[i].NameID = rInstance.ReadUint16()
+		_ = rInstance.ReadUint16() // flags
+		instances[i].Coordinates = map[string]float64{}
+		for _, axis := range axes {
+			instances[i].Coordinates[axis.Tag] = fixedToFloat64(rInstance.ReadUint32())
+		}
+		if rInstance.EOF() {
+			return nil, nil, ErrInvalidFontData
+		}
+	}
+	return axes, instances, nil
+}
+
+// Instantiate returns the static instance of a variable SFNT font (TTF) for the values of its axes, where axes that are not given have their default value. The glyph outlines and advances are varied by the gvar table, after normalizing the values by the avar table, and the variation tables are removed. The weight and width classes of the OS/2 table are set from the wght and wdth axes. Hinting instructions are kept as is, and variations of the HVAR, MVAR, and cvar tables, and of CFF2 outlines are not supported.
+// See https://learn.microsoft.com/en-us/typography/opentype/spec/otvaroverview
+func Instantiate(b []byte, values map[string]float64) ([]byte, error) {
+	axes, _, err := ParseVariationAxes(b)
+	if err != nil {
+		return nil, err
+	} else if axes == nil {
+		return nil, fmt.Errorf("not a variable font")
+	}
+	if cff2, err := SFNTTable(b, "CFF2"); err != nil {
+		return nil, err
+	} else if cff2 != nil {
+		return nil, fmt.Errorf("CFF2 variable fonts are not supported")
+	}
+	for tag := range values {
+		found := false
+		for _, axis := range axes {
+			found = found || axis.Tag == tag
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown variation axis %s", tag)
+		}
+	}
+
+	// normalized coordinates
+	coords := make([]float64, len(axes))
+	for i, axis := range axes {
+		v, ok := values[axis.Tag]
+		if !ok {
+			continue
+		}
+		v = math.Max(axis.Min, math.Min(axis.Max, v))
+		if v < axis.Default {
+			coords[i] = (v - axis.Default) / (axis.Default - axis.Min)
+		} else if axis.Default < v {
+			coords[i] = (v - axis.Default) / (axis.Max - axis.Default)
+		}
+	}
+	avar, err := SFNTTable(b, "avar")
+	if err != nil {
+		return nil, err
+	} else if avar != nil {
+		if err := mapAvar(avar, coords); err != nil {
+			return nil, err
+		}
+	}
+	for i := range coords {
+		coords[i] = math.Round(coords[i]*16384.0) / 16384.0 // F2DOT14
+	}
+
+	replace := map[string][]byte{}
+	for _, tag := range variationTables {
+		replace[tag] = nil
+	}
+	gvar, err := SFNTTable(b, "gvar")
+	if err != nil {
+		return nil, err
+	} else if gvar != nil {
+		if err := instantiateGlyphs(b, gvar, coords, replace); err != nil {
+			return nil, err
+		}
+	}
+
+	if os2, err := SFNTTable(b, "OS/2"); err != nil {
+		return nil, err
+	} else if 8 <= len(os2) {
+		os2 = append([]byte{}, os2...)
+		if v, ok := values["wght"]; ok {
+			binary.BigEndian.PutUint16(os2[4:], uint16(math.Max(1.0, math.Min(1000.0, math.Round(v))))) // usWeightClass
+		}
+		if v, ok := values["wdth"]; ok {
+			// usWidthClass from the width in percent
+			widths := []float64{50.0, 62.5, 75.0, 87.5, 100.0, 112.5, 125.0, 150.0, 200.0}
+			class := 1
+			for i, width := range widths {
+				if math.Abs(width-v) < math.Abs(widths[class-1]-v) {
+					class = i + 1
+				}
+			}
+			binary.BigEndian.PutUint16(os2[6:], uint16(class))
+		}
+		replace["OS/2"] = os2
+	}
+	return writeSFNT(b, replace)
+}
+
+// mapAvar maps the normalized coordinates by the segment maps of the avar table.
+func mapAvar(avar []byte, coords []float64) error {
+	r := newBinaryReader(avar)
+	majorVersion := r.ReadUint16()
+	_ = r.ReadUint32() // minorVersion, reserved
+	axisCount := r.ReadUint16()
+	if r.EOF() || majorVersion != 1 || int(axisCount) != len(coords) {
+		return ErrInvalidFontData
+	}
+	for i := range coords {
+		positionMapCount := int(r.ReadUint16())
+		from, to := make([]float64, positionMapCount), make([]float64, positionMapCount)
+		for j := 0; j < positionMapCount; j++ {
+			from[j] = f2dot14ToFloat64(r.ReadUint16())
+			to[j] = f2dot14ToFloat64(r.ReadUint16())
+			if 0 < j && from[j] < from[j-1] {
+				return ErrInvalidFontData
+			}
+		}
+		if r.EOF() {
+			return ErrInvalidFontData
+		}
+		for j := 0; j < positionMapCount; j++ {
+			if coords[i] <= from[j] {
+				if j == 0 || from[j] == coords[i] {
+					coords[i] = to[j]
+				} else {
+					coords[i] = to[j-1] + (coords[i]-from[j-1])*(to[j]-to[j-1])/(from[j]-from[j-1])
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// instantiateGlyphs varies the outlines and advances of the glyphs by the gvar table, and sets the replacements of the glyf, loca, hmtx, hhea, and head tables.
+func instantiateGlyphs(b, gvar []byte, coords []float64, replace map[string][]byte) error {
+	head, err := SFNTTable(b, "head")
+	if err != nil {
+		return err
+	}
+	hhea, err := SFNTTable(b, "hhea")
+	if err != nil {
+		return err
+	}
+	hmtx, err := SFNTTable(b, "hmtx")
+	if err != nil {
+		return err
+	}
+	maxp, err := SFNTTable(b, "maxp")
+	if err != nil {
+		return err
+	}
+	loca, err := SFNTTable(b, "loca")
+	if err != nil {
+		return err
+	}
+	glyf, err := SFNTTable(b, "glyf")
+	if err != nil {
+		return err
+	} else if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 || loca == nil || glyf == nil {
+		return ErrInvalidFontData
+	}
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+	numberOfHMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	longOffsets := binary.BigEndian.Uint16(head[50:]) == 1
+	if numberOfHMetrics == 0 || numGlyphs < numberOfHMetrics || len(hmtx) < 2*numberOfHMetrics+2*numGlyphs {
+		return ErrInvalidFontData
+	}
+
+	// gvar header
+	r := newBinaryReader(gvar)
+	majorVersion := r.ReadUint16()
+	_ = r.ReadUint16() // minorVersion
+	axisCount := r.ReadUint16()
+	sharedTupleCount := r.ReadUint16()
+	sharedTuplesOffset := r.ReadUint32()
+	glyphCount := r.ReadUint16()
+	flags := r.ReadUint16()
+	glyphVariationDataArrayOffset := r.ReadUint32()
+	if r.EOF() || majorVersion != 1 || int(axisCount) != len(coords) || int(glyphCount) != numGlyphs {
+		return ErrInvalidFontData
+	}
+	variationOffsets := make([]uint32, numGlyphs+1)
+	for i := range variationOffsets {
+		if flags&0x0001 != 0 {
+			variationOffsets[i] = r.ReadUint32()
+		} else {
+			variationOffsets[i] = 2 * uint32(r.ReadUint16())
+		}
+	}
+	sharedTuples := make([][]float64, sharedTupleCount)
+	rShared := readerAt(gvar, sharedTuplesOffset)
+	for i := range sharedTuples {
+		sharedTuples[i] = make([]float64, axisCount)
+		for j := range sharedTuples[i] {
+			sharedTuples[i][j] = f2dot14ToFloat64(rShared.ReadUint16())
+		}
+	}
+	if r.EOF() || rShared.EOF() {
+		return ErrInvalidFontData
+	}
+
+	wGlyf := newBinaryWriter([]byte{})
+	wLoca := newBinaryWriter(make([]byte, 0, 4*(numGlyphs+1)))
+	wHmtx := newBinaryWriter(make([]byte, 0, 4*numGlyphs))
+	advanceWidthMax := uint16(0)
+	for i := 0; i < numGlyphs; i++ {
+		var start, end uint32
+		if longOffsets {
+			start, end = binary.BigEndian.Uint32(loca[4*i:]), binary.BigEndian.Uint32(loca[4*i+4:])
+		} else {
+			start, end = 2*uint32(binary.BigEndian.Uint16(loca[2*i:])), 2*uint32(binary.BigEndian.Uint16(loca[2*i+2:]))
+		}
+		if (longOffsets && len(loca) < 4*i+8) || (!longOffsets && len(loca) < 2*i+4) || end < start || uint32(len(glyf)) < end {
+			return ErrInvalidFontData
+		}
+		advance := int(binary.BigEndian.Uint16(hmtx[4*minInt(i, numberOfHMetrics-1):]))
+		var lsb int
+		if i < numberOfHMetrics {
+			lsb = int(int16(binary.BigEndian.Uint16(hmtx[4*i+2:])))
+		} else {
+			lsb = int(int16(binary.BigEndian.Uint16(hmtx[4*numberOfHMetrics+2*(i-numberOfHMetrics):])))
+		}
+
+		g, err := parseGlyph(glyf[start:end])
+		if err != nil {
+			return err
+		}
+		if variationOffsets[i] < variationOffsets[i+1] {
+			data := readerAt(gvar, glyphVariationDataArrayOffset+variationOffsets[i]).ReadBytes(variationOffsets[i+1] - variationOffsets[i])
+			if data == nil {
+				return ErrInvalidFontData
+			}
+			dx, dy, err := glyphDeltas(data, g, coords, sharedTuples)
+			if err != nil {
+				return err
+			}
+
+			// phantom points of the origin and advance follow the points of the glyph
+			n := len(dx) - 4
+			origin := float64(g.xMin - lsb)
+			for j := 0; j < n; j++ {
+				g.x[j] += dx[j]
+				g.y[j] += dy[j]
+			}
+			if g.simple() && 0 < n {
+				g.xMin = int(math.Round(g.x[0]))
+				for j := 1; j < n; j++ {
+					g.xMin = minInt(g.xMin, int(math.Round(g.x[j])))
+				}
+			}
+			advance = int(math.Round(float64(advance) + dx[n+1] - dx[n]))
+			lsb = g.xMin - int(math.Round(origin+dx[n]))
+			if advance < 0 {
+				advance = 0
+			}
+		}
+
+		wLoca.WriteUint32(wGlyf.Len())
+		wGlyf.WriteBytes(g.bytes())
+		for wGlyf.Len()%4 != 0 {
+			wGlyf.WriteByte(0)
+		}
+		if math.MaxUint16 < advance || lsb < math.MinInt16 || math.MaxInt16 < lsb {
+			return ErrInvalidFontData
+		}
+		wHmtx.WriteUint16(uint16(advance))
+		wHmtx.WriteInt16(int16(lsb))
+		if advanceWidthMax < uint16(advance) {
+			advanceWidthMax = uint16(advance)
+		}
+	}
+	wLoca.WriteUint32(wGlyf.Len())
+
+	head = append([]byte{}, head...)
+	binary.BigEndian.PutUint16(head[50:], 1) // indexToLocFormat
+	hhea = append([]byte{}, hhea...)
+	binary.BigEndian.PutUint16(hhea[10:], advanceWidthMax)
+	binary.BigEndian.PutUint16(hhea[34:], uint16(numGlyphs)) // numberOfHMetrics
+	replace["glyf"] = wGlyf.Bytes()
+	replace["loca"] = wLoca.Bytes()
+	replace["hmtx"] = wHmtx.Bytes()
+	replace["hhea"] = hhea
+	replace["head"] = head
+	return nil
+}
+
+// glyphDeltas returns the deltas of the points of a glyph, followed by its four phantom points, for the normalized coordinates. Points without explicit deltas of a tuple are interpolated for simple glyphs.
+// See https://learn.microsoft.com/en-us/typography/opentype/spec/gvar
+func glyphDeltas(b []byte, g *glyph, coords []float64, sharedTuples [][]float64) ([]float64, []float64, error) {
+	numPoints := len(g.x) + 4
+	dx, dy := make([]float64, numPoints), make([]float64, numPoints)
+
+	r := newBinaryReader(b)
+	tupleVariationCount := r.ReadUint16()
+	dataOffset := uint32(r.ReadUint16())
+	rData := readerAt(b, dataOffset)
+	var sharedPoints []int
+	if tupleVariationCount&0x8000 != 0 {
+		var err error
+		if sharedPoints, err = readPackedPoints(rData, numPoints); err != nil {
+			return nil, nil, err
+		}
+	}
+	for i := 0; i < int(tupleVariationCount&0x0FFF); i++ {
+		variationDataSize := r.ReadUint16()
+		tupleIndex := r.ReadUint16()
+		peak := make([]float64, len(coords))
+		if tupleIndex&0x8000 != 0 { // EMBEDDED_PEAK_TUPLE
+			for j := range peak {
+				peak[j] = f2dot14ToFloat64(r.ReadUint16())
+			}
+		} else if int(tupleIndex&0x0FFF) < len(sharedTuples) {
+			peak = sharedTuples[tupleIndex&0x0FFF]
+		} else {
+			return nil, nil, ErrInvalidFontData
+		}
+		var start, end []float64
+		if tupleIndex&0x4000 != 0 { // INTERMEDIATE_REGION
+			start, end = make([]float64, len(coords)), make([]float64, len(coords))
+			for j := range start {
+				start[j] = f2dot14ToFloat64(r.ReadUint16())
+			}
+			for j := range end {
+				end[j] = f2dot14ToFloat64(r.ReadUint16())
+			}
+		}
+		rTuple := newBinaryReader(rData.ReadBytes(uint32(variationDataSize)))
+		if r.EOF() || rData.EOF() {
+			return nil, nil, ErrInvalidFontData
+		}
+
+		scalar := tupleScalar(coords, peak, start, end)
+		if scalar == 0.0 {
+			continue
+		}
+		points := sharedPoints
+		if tupleIndex&0x2000 != 0 { // PRIVATE_POINT_NUMBERS
+			var err error
+			if points, err = readPackedPoints(rTuple, numPoints); err != nil {
+				return nil, nil, err
+			}
+		}
+		n := numPoints
+		if points != nil {
+			n = len(points)
+		}
+		tdx, err := readPackedDeltas(rTuple, n)
+		if err != nil {
+			return nil, nil, err
+		}
+		tdy, err := readPackedDeltas(rTuple, n)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if points == nil {
+			for j := 0; j < numPoints; j++ {
+				dx[j] += scalar * tdx[j]
+				dy[j] += scalar * tdy[j]
+			}
+			continue
+		}
+		touched := make([]bool, numPoints)
+		tupleDx, tupleDy := make([]float64, numPoints), make([]float64, numPoints)
+		for j, point := range points {
+			if point < numPoints {
+				touched[point] = true
+				tupleDx[point] += tdx[j]
+				tupleDy[point] += tdy[j]
+			}
+		}
+		if g.simple() {
+			g.interpolate(touched, tupleDx, tupleDy)
+		}
+		for j := 0; j < numPoints; j++ {
+			dx[j] += scalar * tupleDx[j]
+			dy[j] += scalar * tupleDy[j]
+		}
+	}
+	return dx, dy, nil
+}
+
+// tupleScalar returns the scalar of the deltas of a tuple for the normalized coordinates, with an intermediate region given by start and end, or nil.
+func tupleScalar(coords, peak, start, end []float64) float64 {
+	scalar := 1.0
+	for i, v := range coords {
+		p := peak[i]
+		if p == 0.0 {
+			continue
+		}
+		lower, upper := math.Min(p, 0.0), math.Max(p, 0.0)
+		if start != nil {
+			lower, upper = start[i], end[i]
+			if p < lower || upper < p || lower < 0.0 && 0.0 < upper {
+				continue
+			}
+		}
+		if v == p {
+			continue
+		} else if v <= lower || upper <= v {
+			return 0.0
+		} else if v < p {
+			scalar *= (v - lower) / (p - lower)
+		} else {
+			scalar *= (v - upper) / (p - upper)
+		}
+	}
+	return scalar
+}
+
+// readPackedPoints reads packed point numbers, which are nil for all points.
+func readPackedPoints(r *binaryReader, numPoints int) ([]int, error) {
+	count := int(r.ReadByte())
+	if count == 0 {
+		return nil, nil
+	} else if count&0x80 != 0 {
+		count = (count&0x7F)<<8 | int(r.ReadByte())
+	}
+	points := make([]int, 0, count)
+	point := 0
+	for len(points) < count && !r.EOF() {
+		control := r.ReadByte()
+		for j := 0; j <= int(control&0x7F) && len(points) < count; j++ {
+			if control&0x80 != 0 { // POINTS_ARE_WORDS
+				point += int(r.ReadUint16())
+			} else {
+				point += int(r.ReadByte())
+			}
+			points = append(points, point)
+		}
+	}
+	if r.EOF() {
+		return nil, ErrInvalidFontData
+	}
+	return points, nil
+}
+
+// readPackedDeltas reads n packed deltas.
+func readPackedDeltas(r *binaryReader, n int) ([]float64, error) {
+	deltas := make([]float64, 0, n)
+	for len(deltas) < n && !r.EOF() {
+		control := r.ReadByte()
+		for j := 0; j <= int(control&0x3F) && len(deltas) < n; j++ {
+			if control&0x80 != 0 { // DELTAS_ARE_ZERO
+				deltas = append(deltas, 0.0)
+			} else if control&0x40 != 0 { // DELTAS_ARE_WORDS
+				deltas = append(deltas, float64(r.ReadInt16()))
+			} else {
+				deltas = append(deltas, float64(int8(r.ReadByte())))
+			}
+		}
+	}
+	if r.EOF() {
+		return nil, ErrInvalidFontData
+	}
+	return deltas, nil
+}
+
+////////////////////////////////////////////////////////////////
+
+// glyph is a glyph of the glyf table, with the points of a simple glyph or the offsets of the components of a composite glyph.
+type glyph struct {
+	numberOfContours       int
+	xMin, yMin, xMax, yMax int
+	endPts                 []int
+	instructions           []byte
+	flags                  []byte
+	x, y                   []float64
+
+	components []glyphComponent
+	tail       []byte // instructions of composite glyphs
+}
+
+type glyphComponent struct {
+	flags  uint16
+	index  uint16
+	xy     bool   // arguments are offsets, otherwise they are point numbers
+	args   []byte // arguments that are point numbers
+	scale  []byte
+	dx, dy float64 // offsets
+}
+
+func (g *glyph) simple() bool {
+	return g.components == nil
+}
+
+func parseGlyph(b []byte) (*glyph, error) {
+	g := &glyph{}
+	if len(b) == 0 {
+		return g, nil
+	}
+	r := newBinaryReader(b)
+	g.numberOfContours = int(r.ReadInt16())
+	g.xMin, g.yMin = int(r.ReadInt16()), int(r.ReadInt16())
+	g.xMax, g.yMax = int(r.ReadInt16()), int(r.ReadInt16())
+	if g.numberOfContours < 0 {
+		for {
+			c := glyphComponent{
+				flags: r.ReadUint16(),
+				index: r.ReadUint16(),
+			}
+			c.xy = c.flags&0x0002 != 0 // ARGS_ARE_XY_VALUES
+			if c.flags&0x0001 != 0 {   // ARG_1_AND_2_ARE_WORDS
+				if c.xy {
+					c.dx, c.dy = float64(r.ReadInt16()), float64(r.ReadInt16())
+				} else {
+					c.args = r.ReadBytes(4)
+				}
+			} else if c.xy {
+				c.dx, c.dy = float64(int8(r.ReadByte())), float64(int8(r.ReadByte()))
+			} else {
+				c.args = r.ReadBytes(2)
+			}
+			if c.flags&0x0008 != 0 { // WE_HAVE_A_SCALE
+				c.scale = r.ReadBytes(2)
+			} else if c.flags&0x0040 != 0 { // WE_HAVE_AN_X_AND_Y_SCALE
+				c.scale = r.ReadBytes(4)
+			} else if c.flags&0x0080 != 0 { // WE_HAVE_A_TWO_BY_TWO
+				c.scale = r.ReadBytes(8)
+			}
+			if r.EOF() {
+				return nil, ErrInvalidFontData
+			}
+			g.components = append(g.components, c)
+			g.x = append(g.x, c.dx)
+			g.y = append(g.y, c.dy)
+			if c.flags&0x0020 == 0 { // MORE_COMPONENTS
+				break
+			}
+		}
+		g.tail = r.ReadBytes(r.Len())
+		return g, nil
+	}
+
+	numPoints := 0
+	for i := 0; i < g.numberOfContours; i++ {
+		end := int(r.ReadUint16())
+		if end < numPoints-1 {
+			return nil, ErrInvalidFontData
+		}
+		g.endPts = append(g.endPts, end)
+		numPoints = end + 1
+	}
+	g.instructions = r.ReadBytes(uint32(r.ReadUint16()))
+	for len(g.flags) < numPoints && !r.EOF() {
+		flag := r.ReadByte()
+		g.flags = append(g.flags, flag)
+		if flag&0x08 != 0 { // REPEAT_FLAG
+			for n := r.ReadByte(); 0 < n && len(g.flags) < numPoints; n-- {
+				g.flags = append(g.flags, flag)
+			}
+		}
+	}
+	for k, coords := range []*[]float64{&g.x, &g.y} {
+		short, same := byte(0x02), byte(0x10) // X_SHORT_VECTOR, X_IS_SAME_OR_POSITIVE_X_SHORT_VECTOR
+		if k == 1 {
+			short, same = 0x04, 0x20
+		}
+		v := 0
+		for _, flag := range g.flags {
+			if flag&short != 0 {
+				if flag&same != 0 {
+					v += int(r.ReadByte())
+				} else {
+					v -= int(r.ReadByte())
+				}
+			} else if flag&same == 0 {
+				v += int(r.ReadInt16())
+			}
+			*coords = append(*coords, float64(v))
+		}
+	}
+	if r.EOF() || len(g.flags) != numPoints {
+		return nil, ErrInvalidFontData
+	}
+	return g, nil
+}
+
+// interpolate sets the deltas of the points that are not touched by interpolating between the nearest touched points of the same contour, and skips the phantom points.
+// See https://learn.microsoft.com/en-us/typography/opentype/spec/gvar#inferred-deltas-for-un-referenced-point-numbers
+func (g *glyph) interpolate(touched []bool, dx, dy []float64) {
+	start := 0
+	for _, end := range g.endPts {
+		refs := []int{}
+		for i := start; i <= end; i++ {
+			if touched[i] {
+				refs = append(refs, i)
+			}
+		}
+		if len(refs) == 1 {
+			for i := start; i <= end; i++ {
+				dx[i], dy[i] = dx[refs[0]], dy[refs[0]]
+			}
+		} else if 1 < len(refs) {
+			for k, ref := range refs {
+				next := refs[(k+1)%len(refs)]
+				for i := ref + 1; ; i++ {
+					if end < i {
+						i = start
+					}
+					if i == next {
+						break
+					}
+					dx[i] = interpolateDelta(g.x[i], g.x[ref], g.x[next], dx[ref], dx[next])
+					dy[i] = interpolateDelta(g.y[i], g.y[ref], g.y[next], dy[ref], dy[next])
+				}
+			}
+		}
+		start = end + 1
+	}
+}
+
+func interpolateDelta(v, v1, v2, d1, d2 float64) float64 {
+	if v1 == v2 {
+		if d1 == d2 {
+			return d1
+		}
+		return 0.0
+	} else if v2 < v1 {
+		v1, v2, d1, d2 = v2, v1, d2, d1
+	}
+	if v <= v1 {
+		return d1
+	} else if v2 <= v {
+		return d2
+	}
+	return d1 + (v-v1)*(d2-d1)/(v2-v1)
+}
+
+// bytes returns the glyph data, where the coordinates are rounded and the bounding box is recalculated for simple glyphs.
+func (g *glyph) bytes() []byte {
+	if g.numberOfContours == 0 && g.components == nil {
+		return []byte{}
+	}
+	w := newBinaryWriter([]byte{})
+	if !g.simple() {
+		w.WriteInt16(int16(g.numberOfContours))
+		w.WriteInt16(int16(g.xMin))
+		w.WriteInt16(int16(g.yMin))
+		w.WriteInt16(int16(g.xMax))
+		w.WriteInt16(int16(g.yMax))
+		for i, c := range g.components {
+			if c.xy {
+				w.WriteUint16(c.flags | 0x0001) // ARG_1_AND_2_ARE_WORDS
+			} else {
+				w.WriteUint16(c.flags)
+			}
+			w.WriteUint16(c.index)
+			if c.xy {
+				w.WriteInt16(roundInt16(g.x[i]))
+				w.WriteInt16(roundInt16(g.y[i]))
+			} else {
+				w.WriteBytes(c.args)
+			}
+			w.WriteBytes(c.scale)
+		}
+		w.WriteBytes(g.tail)
+		return w.Bytes()
+	}
+
+	x, y := make([]int16, len(g.x)), make([]int16, len(g.y))
+	for i := range x {
+		x[i], y[i] = roundInt16(g.x[i]), roundInt16(g.y[i])
+		if i == 0 {
+			g.xMin, g.xMax, g.yMin, g.yMax = int(x[0]), int(x[0]), int(y[0]), int(y[0])
+		}
+		g.xMin, g.xMax = minInt(g.xMin, int(x[i])), maxInt(g.xMax, int(x[i]))
+		g.yMin, g.yMax = minInt(g.yMin, int(y[i])), maxInt(g.yMax, int(y[i]))
+	}
+	w.WriteInt16(int16(g.numberOfContours))
+	w.WriteInt16(int16(g.xMin))
+	w.WriteInt16(int16(g.yMin))
+	w.WriteInt16(int16(g.xMax))
+	w.WriteInt16(int16(g.yMax))
+	for _, end := range g.endPts {
+		w.WriteUint16(uint16(end))
+	}
+	w.WriteUint16(uint16(len(g.instructions)))
+	w.WriteBytes(g.instructions)
+	for _, flag := range g.flags {
+		w.WriteByte(flag & 0x41) // ON_CURVE_POINT and OVERLAP_SIMPLE, with coordinates of two bytes
+	}
+	prev := int16(0)
+	for _, v := range x {
+		w.WriteInt16(v - prev)
+		prev = v
+	}
+	prev = 0
+	for _, v := range y {
+		w.WriteInt16(v - prev)
+		prev = v
+	}
+	return w.Bytes()
+}
+
+func roundInt16(v float64) int16 {
+	return int16(math.Max(math.MinInt16, math.Min(math.MaxInt16, math.Round(v))))
+}
+
+func fixedToFloat64(v uint32) float64 {
+	return float64(int32(v)) / 65536.0
+}
+
+func f2dot14ToFloat64(v uint16) float64 {
+	return float64(int16(v)) / 16384.0
+}
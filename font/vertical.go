@@ -0,0 +1,107 @@
+package font
+
+import (
+	"sort"
+)
+
+// VerticalMetrics are the vertical advances and origins of the glyphs of the vhea, vmtx, and VORG tables, used for vertical writing.
+type VerticalMetrics struct {
+	advances        []uint16 // of the long vertical metrics, the last applies to the remaining glyphs
+	topSideBearings []int16
+
+	defaultOriginY int16
+	originGlyphs   []uint16 // sorted glyph IDs of the VORG table
+	originsY       []int16
+	hasOrigins     bool
+}
+
+// ParseVerticalMetrics parses the vertical metrics of the vhea and vmtx tables of an SFNT font (TTF or OTF), and the vertical origins of the VORG table if it exists. It returns nil if the font has no vertical metrics.
+// See https://learn.microsoft.com/en-us/typography/opentype/spec/vmtx
+func ParseVerticalMetrics(b []byte) (*VerticalMetrics, error) {
+	vhea, err := SFNTTable(b, "vhea")
+	if err != nil {
+		return nil, err
+	}
+	vmtx, err := SFNTTable(b, "vmtx")
+	if err != nil || vhea == nil || vmtx == nil {
+		return nil, err
+	}
+	maxp, err := SFNTTable(b, "maxp")
+	if err != nil {
+		return nil, err
+	} else if len(vhea) < 36 || len(maxp) < 6 {
+		return nil, ErrInvalidFontData
+	}
+
+	numGlyphs := newBinaryReader(maxp[4:]).ReadUint16()
+	numOfLongVerMetrics := newBinaryReader(vhea[34:]).ReadUint16()
+	if numOfLongVerMetrics == 0 || numGlyphs < numOfLongVerMetrics {
+		return nil, ErrInvalidFontData
+	} else if uint32(len(vmtx)) < 4*uint32(numOfLongVerMetrics)+2*uint32(numGlyphs-numOfLongVerMetrics) {
+		return nil, ErrInvalidFontData
+	}
+
+	metrics := &VerticalMetrics{
+		advances:        make([]uint16, numOfLongVerMetrics),
+		topSideBearings: make([]int16, numGlyphs),
+	}
+	r := newBinaryReader(vmtx)
+	for i := range metrics.advances {
+		metrics.advances[i] = r.ReadUint16()
+		metrics.topSideBearings[i] = r.ReadInt16()
+	}
+	for i := int(numOfLongVerMetrics); i < int(numGlyphs); i++ {
+		metrics.topSideBearings[i] = r.ReadInt16()
+	}
+
+	// See https://learn.microsoft.com/en-us/typography/opentype/spec/vorg
+	vorg, err := SFNTTable(b, "VORG")
+	if err != nil || vorg == nil {
+		return metrics, err
+	}
+	r = newBinaryReader(vorg)
+	_ = r.ReadUint16() // majorVersion
+	_ = r.ReadUint16() // minorVersion
+	metrics.defaultOriginY = r.ReadInt16()
+	numVertOriginYMetrics := r.ReadUint16()
+	if r.EOF() || r.Len() < 4*uint32(numVertOriginYMetrics) {
+		return nil, ErrInvalidFontData
+	}
+	metrics.originGlyphs = make([]uint16, numVertOriginYMetrics)
+	metrics.originsY = make([]int16, numVertOriginYMetrics)
+	for i := range metrics.originGlyphs {
+		metrics.originGlyphs[i] = r.ReadUint16()
+		metrics.originsY[i] = r.ReadInt16()
+		if 0 < i && metrics.originGlyphs[i] <= metrics.originGlyphs[i-1] {
+			return nil, ErrInvalidFontData
+		}
+	}
+	metrics.hasOrigins = true
+	return metrics, nil
+}
+
+// Advance returns the vertical advance and the top side bearing of a glyph in font units.
+func (m *VerticalMetrics) Advance(glyphID uint16) (uint16, int16) {
+	advance := m.advances[len(m.advances)-1]
+	if int(glyphID) < len(m.advances) {
+		advance = m.advances[glyphID]
+	}
+	if len(m.topSideBearings) <= int(glyphID) {
+		return advance, 0
+	}
+	return advance, m.topSideBearings[glyphID]
+}
+
+// OriginY returns the y-coordinate of the vertical origin of a glyph in font units from the VORG table, or false if the font has no VORG table, in which case the vertical origin is at the top side bearing above the top of the glyph.
+func (m *VerticalMetrics) OriginY(glyphID uint16) (int16, bool) {
+	if !m.hasOrigins {
+		return 0, false
+	}
+	i := sort.Search(len(m.originGlyphs), func(i int) bool {
+		return glyphID <= m.originGlyphs[i]
+	})
+	if i < len(m.originGlyphs) && m.originGlyphs[i] == glyphID {
+		return m.originsY[i], true
+	}
+	return m.defaultOriginY, true
+}
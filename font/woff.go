@@ -113,6 +113,8 @@ func ParseWOFF(b []byte) ([]byte, error) {
 	}
 	if tablePos.HasOverlap() {
 		return nil, ErrInvalidFontData
+	} else if MaxMemory < totalSfntSize {
+		return nil, ErrExceedsMemory
 	}
 
 	var searchRange uint16 = 1
@@ -155,7 +157,7 @@ func ParseWOFF(b []byte) ([]byte, error) {
 			if err != nil {
 				return nil, fmt.Errorf("%s: %v", table.tag, err)
 			}
-			if _, err = io.Copy(&buf, r); err != nil {
+			if _, err = io.Copy(&buf, io.LimitReader(r, int64(table.origLength)+1)); err != nil {
 				return nil, fmt.Errorf("%s: %v", table.tag, err)
 			}
 			if err = r.Close(); err != nil {
@@ -128,9 +128,13 @@ func ParseWOFF2(b []byte) ([]byte, error) {
 		return nil, ErrInvalidFontData
 	}
 
+	if MaxMemory < uncompressedSize || MaxMemory < totalSfntSize {
+		return nil, ErrExceedsMemory
+	}
+
 	var dataBuf bytes.Buffer
 	rBrotli, _ := brotli.NewReader(bytes.NewReader(data), nil) // err is always nil
-	io.Copy(&dataBuf, rBrotli)
+	io.Copy(&dataBuf, io.LimitReader(rBrotli, int64(uncompressedSize)+1))
 	if err := rBrotli.Close(); err != nil {
 		return nil, fmt.Errorf("brotli: %v", err)
 	}
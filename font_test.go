@@ -1,10 +1,23 @@
 package canvas
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
 	"io/ioutil"
+	"sort"
+	"strings"
 	"testing"
 
+	canvasFont "github.com/tdewolff/canvas/font"
 	"github.com/tdewolff/test"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
 )
 
 // TODO: move to font directory
@@ -44,6 +57,73 @@ func TestParseWOFF(t *testing.T) {
 	font, err := parseFont("dejavu-serif", b)
 	test.Error(t, err)
 	test.That(t, font.sfnt.UnitsPerEm() == 2048)
+	mimetype, raw := font.Raw()
+	test.T(t, mimetype, "font/woff")
+	test.T(t, raw, b) // embedded as is
+}
+
+func TestParseWOFF2(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.woff2")
+	test.Error(t, err)
+
+	font, err := parseFont("dejavu-serif", b)
+	test.Error(t, err)
+	test.That(t, font.sfnt.UnitsPerEm() == 2048)
+	mimetype, raw := font.Raw()
+	test.T(t, mimetype, "font/woff2")
+	test.T(t, raw, b) // embedded as is
+
+	family := NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFont(b, FontRegular))
+	_, advance := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal).ToPath("A")
+	test.That(t, 0.0 < advance)
+}
+
+func TestParseTTC(t *testing.T) {
+	// collection of two fonts that do not share tables
+	ttc := []byte{'t', 't', 'c', 'f', 0, 1, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0}
+	fonts := [][]byte{}
+	for i, filename := range []string{"font/DejaVuSerif.ttf", "font/EBGaramond12-Regular.otf"} {
+		b, err := ioutil.ReadFile(filename)
+		test.Error(t, err)
+		fonts = append(fonts, b)
+
+		base := uint32(len(ttc))
+		binary.BigEndian.PutUint32(ttc[12+4*i:], base)
+		b = append([]byte{}, b...)
+		for j := 0; j < int(binary.BigEndian.Uint16(b[4:])); j++ {
+			offset := b[12+16*j+8:]
+			binary.BigEndian.PutUint32(offset, binary.BigEndian.Uint32(offset)+base)
+		}
+		ttc = append(ttc, b...)
+		ttc = append(ttc, make([]byte, (4-len(ttc)%4)%4)...)
+	}
+
+	n, err := canvasFont.NumCollectionFonts(ttc)
+	test.Error(t, err)
+	test.T(t, n, 2)
+	names, err := FontCollectionNames(ttc)
+	test.Error(t, err)
+	test.T(t, names, []string{"DejaVu Serif", "EB Garamond 12 Regular"})
+
+	f, err := parseFont("collection", ttc) // the first font
+	test.Error(t, err)
+	mimetype, raw := f.Raw()
+	test.T(t, mimetype, "font/truetype")
+	test.That(t, 0 < len(raw) && len(raw) < len(ttc))
+
+	family := NewFontFamily("collection")
+	test.Error(t, family.LoadFontCollection(ttc, FontRegular, 1))
+	mimetype, _ = family.fonts[FontRegular].Raw()
+	test.T(t, mimetype, "font/opentype")
+	garamond := NewFontFamily("garamond")
+	test.Error(t, garamond.LoadFont(fonts[1], FontRegular))
+	p, _ := family.Face(12.0, Black, FontRegular, FontNormal).ToPath("A")
+	q, _ := garamond.Face(12.0, Black, FontRegular, FontNormal).ToPath("A")
+	test.That(t, p.Equals(q))
+
+	test.That(t, family.LoadFontCollection(ttc, FontBold, 2) != nil, "out of range")
+	test.That(t, family.LoadFontCollection(fonts[0], FontBold, 0) != nil, "not a collection")
 }
 
 func TestSubstitutes(t *testing.T) {
@@ -56,8 +136,766 @@ func TestSubstitutes(t *testing.T) {
 	font.Use(CommonLigatures)
 
 	test.String(t, font.substituteLigatures("fi fl ffi ffl"), "ﬁ ﬂ ﬃ ﬄ")
-	s, inSingleQuote, inDoubleQuote := font.substituteTypography(`... . . . --- -- (c) (r) (tm) 1/2 1/4 3/4 +/- '' ""`, false, false)
+	ctx := NewTypographicContext()
+	s := font.substituteTypography(`... . . . --- -- (c) (r) (tm) 1/2 1/4 3/4 +/- '' ""`, ctx)
 	test.String(t, s, "… … — – © ® ™ ½ ¼ ¾ ± ‘’ “”")
-	test.That(t, !inSingleQuote)
-	test.That(t, !inDoubleQuote)
+	test.That(t, !ctx.inSingleQuote)
+	test.That(t, !ctx.inDoubleQuote)
+}
+
+func TestLigatures(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+
+	font, err := parseFont("dejavu-serif", b)
+	test.Error(t, err)
+	test.String(t, font.substituteLigatures("first"), "first")
+	font.Use(DiscretionaryLigatures)
+	test.String(t, font.substituteLigatures("first"), "firﬆ")
+	font.Use(CommonLigatures | DiscretionaryLigatures)
+	test.String(t, font.substituteLigatures("first"), "ﬁrﬆ")
+
+	// ligature glyphs without code point
+	b, err = ioutil.ReadFile("font/EBGaramond12-Regular.otf")
+	test.Error(t, err)
+
+	font, err = parseFont("eb-garamond", b)
+	test.Error(t, err)
+	font.Use(HistoricalLigatures)
+	s := font.substituteLigatures("act")
+	test.T(t, len([]rune(s)), 2)
+	test.T(t, font.toIndices(s), []uint16{font.toIndices("a")[0], 1998})
+	test.String(t, font.expandLigatureGlyphs(s), "act")
+}
+
+func TestKerning(t *testing.T) {
+	for _, filename := range []string{"font/DejaVuSerif.ttf", "font/EBGaramond12-Regular.otf"} {
+		b, err := ioutil.ReadFile(filename)
+		test.Error(t, err)
+
+		f, err := parseFont("font", b)
+		test.Error(t, err)
+		test.That(t, f.kerning != nil, "kerning in GPOS table")
+
+		// both fonts have equal kerning in the legacy kern table
+		buffer := &sfnt.Buffer{}
+		ppem := toI26_6(float64(f.sfnt.UnitsPerEm()))
+		for r0 := 'A'; r0 <= 'z'; r0++ {
+			for r1 := 'A'; r1 <= 'z'; r1++ {
+				i0, _ := f.glyphIndex(buffer, r0)
+				i1, _ := f.glyphIndex(buffer, r1)
+				kern, err := f.kern(buffer, i0, i1, ppem, font.HintingNone)
+				test.Error(t, err)
+				legacy, _ := f.sfnt.Kern(buffer, i0, i1, ppem, font.HintingNone)
+				test.T(t, kern, legacy, string([]rune{r0, r1}))
+			}
+		}
+	}
+
+	family := NewFontFamily("eb-garamond")
+	test.Error(t, family.LoadFontFile("font/EBGaramond12-Regular.otf", FontRegular))
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	test.That(t, face.Kerning('A', 'V') < 0.0, "kerns AV")
+	test.Float(t, face.TextWidth("AV"), face.TextWidth("A")+face.TextWidth("V")+face.Kerning('A', 'V'))
+}
+
+func TestShape(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular))
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	glyphs := face.Shape("AV")
+	test.T(t, len(glyphs), 2)
+	test.T(t, glyphs[1].Cluster, 1)
+	test.Float(t, glyphs[0].XAdvance+glyphs[1].XAdvance, face.TextWidth("A")+face.TextWidth("V")+face.Kerning('A', 'V'))
+
+	// combining marks are attached to their base by GPOS into one cluster
+	glyphs = face.Shape("e\u0301")
+	test.T(t, len(glyphs), 2)
+	test.T(t, glyphs[1].Cluster, 0)
+	test.Float(t, glyphs[1].XAdvance, 0.0)
+	decomposed, _ := face.ToPath("e\u0301")
+	precomposed, _ := face.ToPath("\u00E9")
+	test.T(t, decomposed.Bounds(), precomposed.Bounds())
+
+	// ligatures of GSUB without code point
+	family = NewFontFamily("eb-garamond")
+	test.Error(t, family.LoadFontFile("font/EBGaramond12-Regular.otf", FontRegular))
+	family.Use(HistoricalLigatures)
+	face = family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	glyphs = face.Shape("act")
+	test.T(t, len(glyphs), 2)
+	test.T(t, glyphs[1].ID, uint16(1998))
+	test.T(t, glyphs[1].Cluster, 1)
+}
+
+func TestShapeFeatures(t *testing.T) {
+	family := NewFontFamily("eb-garamond")
+	test.Error(t, family.LoadFontFile("font/EBGaramond12-Regular.otf", FontRegular))
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	features := face.font.Features()
+	test.That(t, 0 < len(features) && features[0] == "c2sc")
+	test.That(t, sort.StringsAreSorted(features))
+
+	// small capitals substitute only the lowercase letters
+	glyphs := face.Shape("Hafi")
+	smcp := face.WithFeatures("smcp").Shape("Hafi")
+	test.T(t, len(smcp), 4)
+	test.T(t, smcp[0].ID, glyphs[0].ID)
+	test.That(t, smcp[1].ID != glyphs[1].ID)
+
+	// tabular figures have equal advances
+	tnum := face.WithFeatures("tnum").Shape("1234")
+	for _, glyph := range tnum[1:] {
+		test.Float(t, glyph.XAdvance, tnum[0].XAdvance)
+	}
+	test.That(t, face.Shape("1")[0].XAdvance != face.Shape("4")[0].XAdvance)
+
+	// ligatures are enabled and kerning is disabled
+	test.T(t, face.WithFeatures("liga").Shape("fi")[0].ID, uint16(2999))
+	test.T(t, face.WithFeatures("liga", "-liga").Shape("fi")[0].ID, uint16(71))
+	test.T(t, face.WithFeatures("-liga", "liga").Shape("fi")[0].ID, uint16(2999))
+	test.Float(t, face.WithFeatures("-kern").TextWidth("AV"), face.TextWidth("A")+face.TextWidth("V"))
+	test.That(t, !face.WithFeatures("smcp").Equals(face))
+
+	// features of the font face are written to SVG for the text that the browser shapes
+	buf := &bytes.Buffer{}
+	svg := NewSVG(buf, 100.0, 100.0)
+	svg.EmbedFonts(false)
+	rt := NewRichText()
+	rt.Add(face, "1234 ")
+	rt.Add(face.WithFeatures("smcp", "-kern"), "Hafi")
+	svg.RenderText(rt.ToText(100.0, 100.0, Left, Top, 0.0, 0.0), Identity)
+	test.Error(t, svg.Close())
+	test.That(t, strings.Contains(buf.String(), `font-feature-settings:'smcp','kern' 0">Hafi`), buf.String())
+}
+
+func TestShapeArabicForms(t *testing.T) {
+	// GSUB table of the arab script with the isol, fina, medi, and init features, which substitute glyph 1 by glyph 10, 11, 12, and 13 respectively
+	w := &bytes.Buffer{}
+	binary.Write(w, binary.BigEndian, []uint16{1, 0, 10, 36, 86})
+	binary.Write(w, binary.BigEndian, []uint16{1})
+	w.WriteString("arab")
+	binary.Write(w, binary.BigEndian, []uint16{8, 4, 0, 0, 0xFFFF, 4, 0, 1, 2, 3})
+	binary.Write(w, binary.BigEndian, []uint16{4})
+	for i, tag := range []string{"isol", "fina", "medi", "init"} {
+		w.WriteString(tag)
+		binary.Write(w, binary.BigEndian, []uint16{uint16(26 + 6*i)})
+	}
+	for i := 0; i < 4; i++ {
+		binary.Write(w, binary.BigEndian, []uint16{0, 1, uint16(i)})
+	}
+	binary.Write(w, binary.BigEndian, []uint16{4, 10, 32, 54, 76})
+	for i := 0; i < 4; i++ {
+		binary.Write(w, binary.BigEndian, []uint16{1, 0, 1, 8, 2, 8, 1, uint16(10 + i), 1, 1, 1})
+	}
+	shaper, err := canvasFont.ParseShaper(writeTestSFNT(map[string][]byte{"GSUB": w.Bytes()}))
+	test.Error(t, err)
+	test.That(t, shaper.HasScript("arab"))
+	test.That(t, !shaper.HasScript("latn"))
+
+	// beh yeh teh, a space, and dal alef where dal does not join to the next letter
+	runes := []rune("\u0628\u064A\u062A \u062F\u0627")
+	masks := make([]uint32, len(runes))
+	arabicForms(runes, masks)
+	test.T(t, masks, []uint32{initMask, mediMask, finaMask, 0, isolMask, isolMask})
+
+	glyphs := []canvasFont.Glyph{}
+	for i, mask := range masks {
+		glyphs = append(glyphs, canvasFont.Glyph{ID: 1, Cluster: i, Mask: globalMask | mask})
+	}
+	features := (&Font{}).substitutionFeatures("arab", false)
+	ids := []uint16{}
+	for _, glyph := range shaper.Substitute(glyphs, "arab", features) {
+		ids = append(ids, glyph.ID)
+	}
+	test.T(t, ids, []uint16{13, 12, 11, 1, 10, 10})
+}
+
+func TestShapeIndicReordering(t *testing.T) {
+	// the i-matra is written before the consonant cluster, such as ssa virama ta of Devanagari
+	runes := []rune("\u0915\u093F\u0937\u094D\u091F\u093F")
+	clusters := []int{0, 3, 6, 9, 12, 15}
+	reorderIndic(runes, clusters)
+	test.T(t, string(runes), "\u093F\u0915\u093F\u0937\u094D\u091F")
+	test.T(t, clusters, []int{0, 0, 6, 6, 9, 12})
+}
+
+func TestVerticalMetrics(t *testing.T) {
+	maxp := &bytes.Buffer{}
+	binary.Write(maxp, binary.BigEndian, []uint16{0, 0x5000, 4})
+	vhea := make([]byte, 36)
+	binary.BigEndian.PutUint16(vhea[34:], 2) // numOfLongVerMetrics
+	vmtx := &bytes.Buffer{}
+	binary.Write(vmtx, binary.BigEndian, []int16{1000, 50, 900, 80, 20, -10})
+
+	metrics, err := canvasFont.ParseVerticalMetrics(writeTestSFNT(map[string][]byte{"maxp": maxp.Bytes(), "vhea": vhea, "vmtx": vmtx.Bytes()}))
+	test.Error(t, err)
+	advance, tsb := metrics.Advance(1)
+	test.T(t, advance, uint16(900))
+	test.T(t, tsb, int16(80))
+	advance, tsb = metrics.Advance(3)
+	test.T(t, advance, uint16(900))
+	test.T(t, tsb, int16(-10))
+	_, ok := metrics.OriginY(0)
+	test.That(t, !ok)
+
+	vorg := &bytes.Buffer{}
+	binary.Write(vorg, binary.BigEndian, []int16{1, 0, 880, 1, 2, 900})
+	metrics, err = canvasFont.ParseVerticalMetrics(writeTestSFNT(map[string][]byte{"maxp": maxp.Bytes(), "vhea": vhea, "vmtx": vmtx.Bytes(), "VORG": vorg.Bytes()}))
+	test.Error(t, err)
+	y, ok := metrics.OriginY(2)
+	test.That(t, ok)
+	test.T(t, y, int16(900))
+	y, _ = metrics.OriginY(1)
+	test.T(t, y, int16(880))
+
+	// too few metrics
+	_, err = canvasFont.ParseVerticalMetrics(writeTestSFNT(map[string][]byte{"maxp": maxp.Bytes(), "vhea": vhea, "vmtx": vmtx.Bytes()[:8]}))
+	test.T(t, err, canvasFont.ErrInvalidFontData)
+
+	metrics, err = canvasFont.ParseVerticalMetrics(writeTestSFNT(map[string][]byte{"maxp": maxp.Bytes()}))
+	test.Error(t, err)
+	test.That(t, metrics == nil)
+}
+
+func TestSubsetSFNT(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+	f, err := parseFont("dejavu-serif", b)
+	test.Error(t, err)
+
+	buffer := &sfnt.Buffer{}
+	a, _ := f.glyphIndex(buffer, 'A')
+	subset, err := canvasFont.SubsetSFNT(b, []uint16{uint16(a)})
+	test.Error(t, err)
+	test.That(t, len(subset) < len(b)/2, "smaller font")
+
+	f2, err := parseFont("subset", subset)
+	test.Error(t, err)
+	test.T(t, f2.sfnt.NumGlyphs(), f.sfnt.NumGlyphs())
+	segments, err := f2.sfnt.LoadGlyph(buffer, a, toI26_6(12.0), nil)
+	test.Error(t, err)
+	test.That(t, 0 < len(segments), "keeps used glyphs")
+	z, _ := f.glyphIndex(buffer, 'Z')
+	segments, err = f2.sfnt.LoadGlyph(buffer, z, toI26_6(12.0), nil)
+	test.Error(t, err)
+	test.T(t, len(segments), 0)
+}
+
+// writeTestSFNT returns an SFNT font of the tables, without checksums.
+func writeTestSFNT(tables map[string][]byte) []byte {
+	tags := []string{}
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	w := &bytes.Buffer{}
+	binary.Write(w, binary.BigEndian, []uint16{1, 0, uint16(len(tags)), 0, 0, 0})
+	offset := 12 + 16*len(tags)
+	for _, tag := range tags {
+		w.WriteString(tag)
+		binary.Write(w, binary.BigEndian, []uint32{0, uint32(offset), uint32(len(tables[tag]))})
+		offset += (len(tables[tag]) + 3) &^ 3
+	}
+	for _, tag := range tags {
+		w.Write(tables[tag])
+		w.Write(make([]byte, (4-len(tables[tag])%4)%4))
+	}
+	return w.Bytes()
+}
+
+// glyphBounds returns the bounds of the outline of a glyph in font units of 26.6 fixed point, with the y-axis pointing down.
+func glyphBounds(t *testing.T, f *Font, index sfnt.GlyphIndex) fixed.Rectangle26_6 {
+	segments, err := f.sfnt.LoadGlyph(nil, index, toI26_6(float64(f.sfnt.UnitsPerEm())), nil)
+	test.Error(t, err)
+	bounds := fixed.Rectangle26_6{Min: segments[0].Args[0], Max: segments[0].Args[0]}
+	for _, segment := range segments {
+		pos := segment.Args[0]
+		if pos.X < bounds.Min.X {
+			bounds.Min.X = pos.X
+		} else if bounds.Max.X < pos.X {
+			bounds.Max.X = pos.X
+		}
+		if pos.Y < bounds.Min.Y {
+			bounds.Min.Y = pos.Y
+		} else if bounds.Max.Y < pos.Y {
+			bounds.Max.Y = pos.Y
+		}
+	}
+	return bounds
+}
+
+func TestFontCFF(t *testing.T) {
+	b, err := ioutil.ReadFile("font/EBGaramond12-Regular.otf")
+	test.Error(t, err)
+	f, err := parseFont("eb-garamond", b)
+	test.Error(t, err)
+	test.That(t, f.cff != nil)
+
+	// the exclamation mark has fractional coordinates, which sfnt misreads
+	index, err := f.sfnt.GlyphIndex(nil, '!')
+	test.Error(t, err)
+	p, err := f.glyphPath(nil, index)
+	test.Error(t, err)
+	bounds := p.Bounds()
+	test.Float(t, bounds.X, 100.0)
+	test.Float(t, bounds.Y, -14.0)
+	test.Float(t, bounds.W, 120.0)
+	test.Float(t, bounds.H, 663.0)
+
+	// other glyphs are the same as for sfnt
+	index, err = f.sfnt.GlyphIndex(nil, 'o')
+	test.Error(t, err)
+	ppem := toI26_6(float64(f.sfnt.UnitsPerEm()))
+	segments, err := f.loadGlyph(nil, index, ppem)
+	test.Error(t, err)
+	sfntSegments, err := f.sfnt.LoadGlyph(nil, index, ppem, nil)
+	test.Error(t, err)
+	test.T(t, len(segments), len(sfntSegments))
+	for i := range segments {
+		test.T(t, segments[i].Op, sfntSegments[i].Op)
+		test.T(t, segments[i].Args[0], sfntSegments[i].Args[0])
+	}
+}
+
+func TestBitmapFont(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+	f, err := parseFont("dejavu-serif", b)
+	test.Error(t, err)
+	a, _ := f.glyphIndex(nil, 'A')
+	o, _ := f.glyphIndex(nil, 'O')
+
+	// strike of 8 ppem, with a bar of 2x3 pixels for A and a ring of 4x4 pixels for O
+	eblc := []byte{0, 2, 0, 0, 0, 0, 0, 1}
+	eblc = append(eblc, 0, 0, 0, 56, 0, 0, 0, 48, 0, 0, 0, 2, 0, 0, 0, 0)
+	eblc = append(eblc, make([]byte, 24)...) // hori and vert line metrics
+	eblc = append(eblc, byte(a>>8), byte(a), byte(o>>8), byte(o), 8, 8, 1, 1)
+	eblc = append(eblc, byte(a>>8), byte(a), byte(a>>8), byte(a), 0, 0, 0, 16)
+	eblc = append(eblc, byte(o>>8), byte(o), byte(o>>8), byte(o), 0, 0, 0, 32)
+	eblc = append(eblc, 0, 1, 0, 1, 0, 0, 0, 4, 0, 0, 0, 0, 0, 0, 0, 8)              // index format 1, image format 1
+	eblc = append(eblc, 0, 2, 0, 5, 0, 0, 0, 12, 0, 0, 0, 2, 4, 4, 0, 4, 4, 0, 0, 0) // index format 2, image format 5
+	ebdt := []byte{0, 2, 0, 0}
+	ebdt = append(ebdt, 3, 2, 1, 3, 4, 0xC0, 0xC0, 0xC0) // byte-aligned with small metrics
+	ebdt = append(ebdt, 0xF9, 0x9F)                      // bit-aligned
+
+	// bitmap-only font without glyf and loca tables
+	tables := map[string][]byte{"EBLC": eblc, "EBDT": ebdt}
+	for _, tag := range []string{"cmap", "head", "hhea", "hmtx", "maxp", "name", "OS/2", "post"} {
+		tables[tag], err = canvasFont.SFNTTable(b, tag)
+		test.Error(t, err)
+	}
+	tables["maxp"] = append([]byte{0, 0, 0x50, 0}, tables["maxp"][4:6]...) // version 0.5
+	bitmap := writeTestSFNT(tables)
+
+	f, err = parseFont("bitmap", bitmap)
+	test.Error(t, err)
+	test.T(t, f.mimetype, "font/truetype")
+	ppem := toI26_6(float64(f.sfnt.UnitsPerEm()))
+	test.T(t, glyphBounds(t, f, a), fixed.R(256, -768, 768, 0)) // 256 units per pixel, with the y-axis pointing down
+
+	segments, err := f.sfnt.LoadGlyph(nil, o, ppem, nil)
+	test.Error(t, err)
+	contours := 0
+	for _, segment := range segments {
+		if segment.Op == sfnt.SegmentOpMoveTo {
+			contours++
+		}
+	}
+	test.T(t, contours, 2) // with a hole
+
+	family := NewFontFamily("bitmap")
+	test.Error(t, family.LoadFont(bitmap, FontRegular))
+	face := family.Face(8.0*ptPerMm, Black, FontRegular, FontNormal)
+	p, advance := face.ToPath("O")
+	test.T(t, len(p.Split()), 2)
+	test.That(t, p.Interior(.5, .5, NonZero), "ring")
+	test.That(t, !p.Interior(2.0, 2.0, NonZero), "hole")
+	test.T(t, p.Bounds(), Rect{0.0, 0.0, 4.0, 4.0}) // pixels of 1mm
+	test.That(t, 0.0 < advance, "advance")
+}
+
+func TestVariableFont(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+	f, err := parseFont("dejavu-serif", b)
+	test.Error(t, err)
+	test.T(t, len(f.Axes()), 0)
+	_, err = f.Variation(map[string]float64{"wght": 700.0})
+	test.That(t, err != nil, "static font")
+	l, _ := f.glyphIndex(nil, 'l')
+	o, _ := f.glyphIndex(nil, 'o')
+
+	tables := map[string][]byte{}
+	for _, tag := range []string{"cmap", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "name", "OS/2", "post"} {
+		tables[tag], err = canvasFont.SFNTTable(b, tag)
+		test.Error(t, err)
+	}
+	numGlyphs := int(binary.BigEndian.Uint16(tables["maxp"][4:]))
+	test.T(t, binary.BigEndian.Uint16(tables["head"][50:]), uint16(1))
+	start := int(binary.BigEndian.Uint32(tables["loca"][4*l:])) // long offsets
+	numContours := int(binary.BigEndian.Uint16(tables["glyf"][start:]))
+	numPoints := int(binary.BigEndian.Uint16(tables["glyf"][start+10+2*(numContours-1):])) + 1
+
+	// weight axis from 100 to 900 with two named instances, where 650 is mapped halfway to normalized 0.25
+	tables["fvar"] = []byte{0, 1, 0, 0, 0, 16, 0, 2, 0, 1, 0, 20, 0, 2, 0, 8}
+	tables["fvar"] = append(tables["fvar"], 'w', 'g', 'h', 't', 0, 100, 0, 0, 1, 144, 0, 0, 3, 132, 0, 0, 0, 0, 0xFF, 0xFF)
+	tables["fvar"] = append(tables["fvar"], 0, 2, 0, 0, 1, 144, 0, 0, 0, 4, 0, 0, 3, 132, 0, 0)
+	tables["avar"] = []byte{0, 1, 0, 0, 0, 0, 0, 1, 0, 4, 0xC0, 0, 0xC0, 0, 0, 0, 0, 0, 0x20, 0, 0x10, 0, 0x40, 0, 0x40, 0}
+
+	// at the maximum weight, l moves 40 units to the right and widens by 80 units, and only the outer contour of o moves 40 units to the right
+	dataL := []byte{0, 1, 0, 10, 0, 0, 0xA0, 0, 0x40, 0, 0}
+	dx := make([]int16, numPoints+4)
+	for i := range dx[:numPoints] {
+		dx[i] = 40
+	}
+	dx[numPoints+1] = 80 // advance phantom point
+	for i := 0; i < len(dx); i += 64 {
+		n := len(dx) - i
+		if 64 < n {
+			n = 64
+		}
+		dataL = append(dataL, 0x40|byte(n-1))
+		for _, d := range dx[i : i+n] {
+			dataL = append(dataL, byte(d>>8), byte(d))
+		}
+	}
+	for i := 0; i < len(dx); i += 64 {
+		n := len(dx) - i
+		if 64 < n {
+			n = 64
+		}
+		dataL = append(dataL, 0x80|byte(n-1))
+	}
+	binary.BigEndian.PutUint16(dataL[4:], uint16(len(dataL)-10))
+	dataO := []byte{0, 1, 0, 10, 0, 7, 0xA0, 0, 0x40, 0, 1, 0, 0, 0x40, 0, 40, 0x80}
+
+	gvar := []byte{0, 1, 0, 0, 0, 1, 0, 0}
+	gvar = append(gvar, 0, 0, 0, 0, byte(numGlyphs>>8), byte(numGlyphs), 0, 1, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(gvar[8:], uint32(20+4*(numGlyphs+1)))
+	binary.BigEndian.PutUint32(gvar[16:], uint32(20+4*(numGlyphs+1)))
+	data := []byte{}
+	for i := 0; i <= numGlyphs; i++ {
+		gvar = append(gvar, byte(len(data)>>24), byte(len(data)>>16), byte(len(data)>>8), byte(len(data)))
+		if i == int(l) {
+			data = append(data, dataL...)
+		} else if i == int(o) {
+			data = append(data, dataO...)
+		}
+	}
+	tables["gvar"] = append(gvar, data...)
+	variable := writeTestSFNT(tables)
+
+	f, err = parseFont("variable", variable)
+	test.Error(t, err)
+	test.T(t, f.Axes(), []FontAxis{{"wght", "wght", 100.0, 400.0, 900.0, false}})
+	test.T(t, f.Instances(), []FontInstance{{"Book", map[string]float64{"wght": 400.0}}, {"DejaVu Serif", map[string]float64{"wght": 900.0}}})
+
+	advance := func(f *Font, index sfnt.GlyphIndex) fixed.Int26_6 {
+		adv, err := f.sfnt.GlyphAdvance(nil, index, toI26_6(float64(f.sfnt.UnitsPerEm())), font.HintingNone)
+		test.Error(t, err)
+		return adv
+	}
+	contours := func(f *Font, index sfnt.GlyphIndex) []fixed.Point26_6 {
+		segments, err := f.sfnt.LoadGlyph(nil, index, toI26_6(float64(f.sfnt.UnitsPerEm())), nil)
+		test.Error(t, err)
+		starts := []fixed.Point26_6{}
+		for _, segment := range segments {
+			if segment.Op == sfnt.SegmentOpMoveTo {
+				starts = append(starts, segment.Args[0])
+			}
+		}
+		return starts
+	}
+
+	var tests = []struct {
+		wght, dx float64
+	}{
+		{400.0, 0.0},
+		{650.0, 10.0},
+		{900.0, 40.0},
+		{2000.0, 40.0}, // clamped
+	}
+	for _, tt := range tests {
+		g, err := f.Variation(map[string]float64{"wght": tt.wght})
+		test.Error(t, err)
+		test.T(t, len(g.Axes()), 0)
+		shift := fixed.Int26_6(tt.dx * 64.0)
+		test.T(t, glyphBounds(t, g, l).Min.X, glyphBounds(t, f, l).Min.X+shift)
+		test.T(t, glyphBounds(t, g, l).Max.Y, glyphBounds(t, f, l).Max.Y)
+		test.T(t, advance(g, l), advance(f, l)+2*shift)
+		test.T(t, advance(g, o), advance(f, o))
+		starts, startsVariable := contours(f, o), contours(g, o)
+		test.T(t, len(startsVariable), 2)
+		test.T(t, startsVariable[0].X, starts[0].X+shift)
+		test.T(t, startsVariable[1], starts[1])
+	}
+	_, err = f.Variation(map[string]float64{"wdth": 75.0})
+	test.That(t, err != nil, "unknown axis")
+
+	family := NewFontFamily("variable")
+	test.Error(t, family.LoadFontVariation(variable, FontBold, f.Instances()[1].Axes))
+	test.Error(t, family.LoadFont(variable, FontRegular))
+	_, bold := family.Face(12.0*ptPerMm, Black, FontBold, FontNormal).ToPath("l")
+	_, regular := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal).ToPath("l")
+	test.That(t, regular < bold, "wider")
+	os2, err := canvasFont.SFNTTable(family.fonts[FontBold].raw, "OS/2")
+	test.Error(t, err)
+	test.T(t, binary.BigEndian.Uint16(os2[4:]), uint16(900)) // usWeightClass
+}
+
+func TestSVGGlyphs(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+	f, err := parseFont("dejavu-serif", b)
+	test.Error(t, err)
+	a, _ := f.glyphIndex(nil, 'A')
+	bb, _ := f.glyphIndex(nil, 'B')
+	test.That(t, a < bb)
+
+	// A is a red bar and a blue dot, and B is a green bar in a compressed document
+	docA := fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg"><g id="glyph%d" transform="translate(100,0)"><rect x="0" y="-1000" width="500" height="1000" fill="red"/><circle cx="1000" cy="-500" r="200" style="fill:blue"/></g></svg>`, a)
+	docB := &bytes.Buffer{}
+	gz := gzip.NewWriter(docB)
+	fmt.Fprintf(gz, `<svg xmlns="http://www.w3.org/2000/svg"><rect id="glyph%d" width="100" height="200" y="-200" fill="lime"/></svg>`, bb)
+	test.Error(t, gz.Close())
+
+	svg := []byte{0, 0, 0, 0, 0, 10, 0, 0, 0, 0, 0, 2}
+	svg = append(svg, byte(a>>8), byte(a), byte(a>>8), byte(a), 0, 0, 0, 26, 0, 0, byte(len(docA)>>8), byte(len(docA)))
+	svg = append(svg, byte(bb>>8), byte(bb), byte(bb>>8), byte(bb), 0, 0, byte((26+len(docA))>>8), byte(26+len(docA)), 0, 0, byte(docB.Len()>>8), byte(docB.Len()))
+	svg = append(svg, docA...)
+	svg = append(svg, docB.Bytes()...)
+	tables := map[string][]byte{"SVG ": svg}
+	for _, tag := range []string{"cmap", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "name", "OS/2", "post"} {
+		tables[tag], err = canvasFont.SFNTTable(b, tag)
+		test.Error(t, err)
+	}
+
+	family := NewFontFamily("svg")
+	test.Error(t, family.LoadFont(writeTestSFNT(tables), FontRegular))
+	face := family.Face(2048.0*ptPerMm, Black, FontRegular, FontNormal) // font units of 1mm
+	text := NewTextLine(face, "ABC", Left)
+	test.That(t, text.hasColorGlyphs())
+	test.That(t, !NewTextLine(face, "C", Left).hasColorGlyphs())
+
+	paths, colors := text.ToPaths()
+	test.T(t, len(paths), 4) // bar and dot of A, bar of B, and C
+	test.T(t, colors, []color.RGBA{Red, Blue, Lime, Black})
+	y := paths[0].Bounds().Y
+	test.T(t, paths[0].Bounds(), Rect{100.0, y, 500.0, 1000.0})
+	test.T(t, paths[1].Bounds(), Rect{900.0, y + 300.0, 400.0, 400.0})
+	advance := face.TextWidth("A")
+	test.Float(t, paths[2].Bounds().X, advance)
+	test.T(t, paths[2].Bounds().H, 200.0)
+	test.That(t, advance+face.TextWidth("B") < paths[3].Bounds().X, "outline of C only")
+
+	// translucent text makes its glyphs translucent
+	face = family.Face(2048.0*ptPerMm, color.RGBA{0, 0, 0, 128}, FontRegular, FontNormal)
+	_, colors = NewTextLine(face, "A", Left).ToPaths()
+	test.T(t, colors[0].A, uint8(128))
+}
+
+func TestColorGlyphs(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+	f, err := parseFont("dejavu-serif", b)
+	test.Error(t, err)
+	a, _ := f.glyphIndex(nil, 'A')
+	bb, _ := f.glyphIndex(nil, 'B')
+	c, _ := f.glyphIndex(nil, 'C')
+
+	// A is the outline of B in red of the palette below the outline of C in the color of the text
+	colr := []byte{0, 0, 0, 1, 0, 0, 0, 14, 0, 0, 0, 20, 0, 2}
+	colr = append(colr, byte(a>>8), byte(a), 0, 0, 0, 2)
+	colr = append(colr, byte(bb>>8), byte(bb), 0, 0, byte(c>>8), byte(c), 0xFF, 0xFF)
+	cpal := []byte{0, 0, 0, 1, 0, 1, 0, 1, 0, 0, 0, 14, 0, 0, 0, 0, 255, 255} // BGRA
+	tables := map[string][]byte{"COLR": colr, "CPAL": cpal}
+	for _, tag := range []string{"cmap", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "name", "OS/2", "post"} {
+		tables[tag], err = canvasFont.SFNTTable(b, tag)
+		test.Error(t, err)
+	}
+
+	family := NewFontFamily("colr")
+	test.Error(t, family.LoadFont(writeTestSFNT(tables), FontRegular))
+	face := family.Face(2048.0*ptPerMm, Blue, FontRegular, FontNormal) // font units of 1mm
+	text := NewTextLine(face, "AD", Left)
+	test.That(t, text.hasColorGlyphs())
+	test.That(t, !NewTextLine(face, "D", Left).hasColorGlyphs())
+
+	paths, colors := text.ToPaths()
+	test.T(t, len(paths), 3) // layers of A, and D
+	test.T(t, colors, []color.RGBA{Red, Blue, Blue})
+	outlines, _ := NewTextLine(face, "B", Left).ToPaths()
+	test.T(t, paths[0].Bounds(), outlines[0].Bounds())
+	test.That(t, face.TextWidth("A") < paths[2].Bounds().X, "outline of D only")
+
+	// the canvas has the base line at the descent
+	canvas, advance := face.ToCanvas("A")
+	test.Float(t, advance, face.TextWidth("A"))
+	test.Float(t, canvas.W, advance)
+	test.Float(t, canvas.H, face.Metrics().Ascent+face.Metrics().Descent)
+	test.T(t, len(canvas.layers), 2)
+	test.T(t, canvas.layers[0].style.FillColor, Red)
+	test.T(t, canvas.layers[1].style.FillColor, Blue)
+	p, _ := face.ToPath("B")
+	test.T(t, canvas.layers[0].path.Bounds(), p.Bounds().Move(Point{0.0, face.Metrics().Descent}))
+}
+
+func TestColorBitmaps(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+	f, err := parseFont("dejavu-serif", b)
+	test.Error(t, err)
+	a, _ := f.glyphIndex(nil, 'A')
+
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, Red)
+	pngData := &bytes.Buffer{}
+	test.Error(t, png.Encode(pngData, img))
+
+	// strike of 4 ppem with a PNG image of 2x2 pixels for A, one pixel right of and above the origin
+	cblc := []byte{0, 3, 0, 0, 0, 0, 0, 1}
+	cblc = append(cblc, 0, 0, 0, 56, 0, 0, 0, 24, 0, 0, 0, 1, 0, 0, 0, 0)
+	cblc = append(cblc, make([]byte, 24)...) // hori and vert line metrics
+	cblc = append(cblc, byte(a>>8), byte(a), byte(a>>8), byte(a), 4, 4, 32, 1)
+	cblc = append(cblc, byte(a>>8), byte(a), byte(a>>8), byte(a), 0, 0, 0, 8)
+	cblc = append(cblc, 0, 1, 0, 17, 0, 0, 0, 4, 0, 0, 0, 0, 0, 0, 0, byte(9+pngData.Len())) // index format 1, image format 17
+	cbdt := []byte{0, 3, 0, 0}
+	cbdt = append(cbdt, 2, 2, 1, 3, 4, 0, 0, 0, byte(pngData.Len())) // small metrics
+	cbdt = append(cbdt, pngData.Bytes()...)
+	tables := map[string][]byte{"CBLC": cblc, "CBDT": cbdt}
+	for _, tag := range []string{"cmap", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "name", "OS/2", "post"} {
+		tables[tag], err = canvasFont.SFNTTable(b, tag)
+		test.Error(t, err)
+	}
+
+	family := NewFontFamily("cbdt")
+	test.Error(t, family.LoadFont(writeTestSFNT(tables), FontRegular))
+	face := family.Face(2048.0*ptPerMm, Black, FontRegular, FontNormal) // font units of 1mm
+	text := NewTextLine(face, "A", Left)
+	test.That(t, text.hasColorGlyphs())
+
+	paths, _ := text.ToPaths()
+	test.That(t, !paths[0].Empty(), "outline of A")
+	paths, _, images := text.toPaths(true, nil)
+	test.That(t, paths[0].Empty(), "image instead of outline")
+	test.T(t, len(images), 1)
+	test.T(t, images[0].img.Bounds(), image.Rect(0, 0, 2, 2))
+
+	canvas, _ := face.ToCanvas("A")
+	test.T(t, len(canvas.layers), 1)
+	origin := canvas.layers[0].m.Dot(Point{0.0, 0.0})
+	test.T(t, origin, Point{512.0, face.Metrics().Descent + 512.0}) // pixels of 512mm
+	test.T(t, canvas.layers[0].m.Dot(Point{2.0, 2.0}).Sub(origin), Point{1024.0, 1024.0})
+
+	// sbix table of three glyphs, with the image of glyph 1 used by glyph 2
+	sbix := []byte{0, 1, 0, 1, 0, 0, 0, 1, 0, 0, 0, 12}
+	sbix = append(sbix, 0, 4, 0, 72, 0, 0, 0, 20, 0, 0, 0, 20, 0, 0, 0, byte(28+pngData.Len()), 0, 0, 0, byte(38+pngData.Len()))
+	sbix = append(sbix, 0, 1, 0xFF, 0xFE, 'p', 'n', 'g', ' ')
+	sbix = append(sbix, pngData.Bytes()...)
+	sbix = append(sbix, 0, 0, 0, 0, 'd', 'u', 'p', 'e', 0, 1)
+	bitmaps, err := canvasFont.ParseColorBitmaps(writeTestSFNT(map[string][]byte{"sbix": sbix, "maxp": {0, 0, 0x50, 0, 0, 3}}))
+	test.Error(t, err)
+	_, ok := bitmaps.Bitmap(0)
+	test.That(t, !ok, "no image")
+	bitmap, ok := bitmaps.Bitmap(1)
+	test.That(t, ok)
+	test.T(t, bitmap.PPEM, uint16(4))
+	test.T(t, bitmap.X, int16(1))
+	test.T(t, bitmap.Y, int16(-2))
+	test.T(t, bitmap.Data, pngData.Bytes())
+	dupe, _ := bitmaps.Bitmap(2)
+	test.T(t, dupe, bitmap)
+}
+
+func TestGlyphSubstitution(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+
+	font, err := parseFont("dejavu-serif", b)
+	test.Error(t, err)
+
+	font.SetSubstitution(func(prev, r, next rune) rune {
+		if r == '-' && '0' <= next && next <= '9' {
+			return '−' // minus sign
+		}
+		return r
+	})
+	s := font.substituteTypography("a-b -1", NewTypographicContext())
+	test.String(t, s, "a-b −1")
+
+	indices := font.toIndices("ab")
+	font.SetGlyphIndexSubstitution(func(r rune, index uint16) uint16 {
+		if r == 'a' {
+			return indices[1]
+		}
+		return index
+	})
+	test.T(t, font.toIndices("ab"), []uint16{indices[1], indices[1]})
+
+	font.SetGlyphIndexSubstitution(nil)
+	test.T(t, font.toIndices("ab"), indices)
+}
+
+func TestTypographicRules(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+
+	font, err := parseFont("dejavu-serif", b)
+	test.Error(t, err)
+
+	rules := append(TypographicRules{{"->", "→", false}, {"1/3", "⅓", true}, {"No.", "№", true}}, DefaultTypographicRules...)
+	font.SetTypographicRules(rules)
+	s := font.substituteTypography("a -> b, 1/3 and 1/2 -- No. 5, 11/3", NewTypographicContext())
+	test.String(t, s, "a → b, ⅓ and ½ – № 5, 11/3")
+
+	buf := &bytes.Buffer{}
+	test.Error(t, rules.Save(buf))
+	loaded, err := LoadTypographicRules(buf)
+	test.Error(t, err)
+	test.T(t, loaded, rules)
+
+	_, err = LoadTypographicRules(strings.NewReader("{"))
+	test.That(t, err != nil)
+}
+
+func TestTypographicContext(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+
+	font, err := parseFont("dejavu-serif", b)
+	test.Error(t, err)
+
+	ctx := NewTypographicContext()
+	test.String(t, font.substituteTypography(`He said "hello `, ctx), "He said “hello ")
+	test.That(t, ctx.inDoubleQuote)
+	test.String(t, font.substituteTypography(`world"`, ctx), "world”")
+	test.That(t, !ctx.inDoubleQuote)
+
+	test.String(t, font.substituteTypography(`John`, ctx), "John")
+	test.String(t, font.substituteTypography(`'s`, ctx), "’s") // apostrophe using the preceding text
+
+	ctx.Reset()
+	test.String(t, font.substituteTypography(`'s`, ctx), "‘s")
+}
+
+func TestTypographicSubstitute(t *testing.T) {
+	test.String(t, TypographicSubstitute(`"a" -- b...`), "“a” – b…")
+	test.String(t, TypographicSubstitute(`"a" -- b...`, TypographicSubstituteOptions{Rules: DashRules}), `"a" – b...`)
+	test.String(t, TypographicSubstitute(`"a" 1/2`, TypographicSubstituteOptions{SmartQuotes: true}), `“a” 1/2`)
+
+	ctx := NewTypographicContext()
+	opts := DefaultTypographicSubstituteOptions
+	opts.Context = ctx
+	test.String(t, TypographicSubstitute(`"a `, opts)+TypographicSubstitute(`b"`, opts), "“a b”")
+}
+
+func TestTypographicSubstituteRTL(t *testing.T) {
+	test.String(t, TypographicSubstitute(`צה"ל`), "צה״ל")     // gershayim
+	test.String(t, TypographicSubstitute(`ג'ירפה`), "ג׳ירפה") // geresh
+	test.String(t, TypographicSubstitute(`אמר "שלום"`), "אמר „שלום”")
+	test.String(t, TypographicSubstitute(`قال "مرحبا"`), "قال «مرحبا»")
+	test.String(t, TypographicSubstitute(`قال 'مرحبا'`), "قال ‹مرحبا›")
+	test.String(t, TypographicSubstitute(`"hello" -- "שלום"...`), "“hello” – „שלום”…")
 }
@@ -1,14 +1,22 @@
 package canvas
 
 import (
+	"fmt"
+	"image"
 	"image/color"
+	"io"
 	"io/ioutil"
 	"math"
-	"os/exec"
+	"net/http"
 	"reflect"
+	"strings"
+	"sync"
+	"time"
 
+	canvasFont "github.com/tdewolff/canvas/font"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
 )
 
 // FontStyle defines the font style to be used for the font.
@@ -28,6 +36,72 @@ const (
 	FontExtraBlack                       // 900
 )
 
+// weight returns the CSS font weight of the style, from 100 to 900.
+func (style FontStyle) weight() int {
+	if style&FontExtraLight == FontExtraLight {
+		return 100
+	} else if style&FontLight == FontLight {
+		return 200
+	} else if style&FontBook == FontBook {
+		return 300
+	} else if style&FontMedium == FontMedium {
+		return 500
+	} else if style&FontSemibold == FontSemibold {
+		return 600
+	} else if style&FontBold == FontBold {
+		return 700
+	} else if style&FontBlack == FontBlack {
+		return 800
+	} else if style&FontExtraBlack == FontExtraBlack {
+		return 900
+	}
+	return 400
+}
+
+// styleOfWeight returns the style of the closest CSS font weight.
+func styleOfWeight(weight int) FontStyle {
+	styles := []FontStyle{FontExtraLight, FontLight, FontBook, FontRegular, FontMedium, FontSemibold, FontBold, FontBlack, FontExtraBlack}
+	i := (weight+50)/100 - 1
+	if i < 0 {
+		i = 0
+	} else if len(styles) <= i {
+		i = len(styles) - 1
+	}
+	return styles[i]
+}
+
+// fauxBoldness returns the offset of the outlines relative to the font size that synthesizes the CSS font weight from a regular font.
+func fauxBoldness(weight int) float64 {
+	switch weight {
+	case 100:
+		return -0.02
+	case 200:
+		return -0.01
+	case 300:
+		return -0.005
+	case 500:
+		return 0.005
+	case 600:
+		return 0.01
+	case 700:
+		return 0.02
+	case 800:
+		return 0.03
+	case 900:
+		return 0.04
+	}
+	return 0.0
+}
+
+// FontSynthesis specifies which styles are synthesized when a font family has no font of the requested style, as with the font-synthesis property of CSS. The font of the closest style is used otherwise, see FontFamily.SetSynthesis.
+type FontSynthesis int
+
+// see FontSynthesis
+const (
+	SynthesizeWeight FontSynthesis = 1 << iota // embolden or thin the outlines of the font of the closest weight by offsetting them
+	SynthesizeItalic                           // slant the outlines of an upright font with a shear transformation
+)
+
 // FontVariant defines the font variant to be used for the font, such as subscript or smallcaps.
 type FontVariant int
 
@@ -39,49 +113,74 @@ const (
 	FontSmallcaps
 )
 
-// FontFamily contains a family of fonts (bold, italic, ...). Selecting an italic style will pick the native italic font or use faux italic if not present.
+// FontFamily contains a family of fonts (bold, italic, ...). Selecting a style that is not present will pick the font of the closest style and use faux bold and faux italic to synthesize the style, see SetSynthesis.
 type FontFamily struct {
-	name    string
-	fonts   map[FontStyle]*Font
-	options TypographicOptions
+	name      string
+	mu        sync.Mutex // guards fonts and deferred, as deferred fonts are loaded by Face
+	fonts     map[FontStyle]*Font
+	deferred  map[FontStyle]*deferredFont // fonts that are loaded when first used
+	err       error                       // error of the first deferred font that failed to load
+	options   TypographicOptions
+	rules     TypographicRules
+	synthesis FontSynthesis
+
+	substitute      GlyphSubstitution
+	substituteIndex GlyphIndexSubstitution
 }
 
 // NewFontFamily returns a new FontFamily.
 func NewFontFamily(name string) *FontFamily {
 	return &FontFamily{
-		name:  name,
-		fonts: map[FontStyle]*Font{},
+		name:      name,
+		fonts:     map[FontStyle]*Font{},
+		deferred:  map[FontStyle]*deferredFont{},
+		synthesis: SynthesizeWeight | SynthesizeItalic,
 	}
 }
 
-// LoadLocalFont loads a font from the system fonts location.
+// SetSynthesis specifies which styles are synthesized when the family has no font of a requested style, which are all styles by default. Faux bold offsets the outlines of the font of the closest weight, and faux italic slants the outlines of an upright font. The font of the closest style is used as is for styles that are not synthesized.
+func (family *FontFamily) SetSynthesis(synthesis FontSynthesis) {
+	family.mu.Lock()
+	defer family.mu.Unlock()
+	family.synthesis = synthesis
+}
+
+// LoadLocalFont loads a font of the given family, full, or PostScript name from the system fonts location, such as LoadLocalFont("DejaVu Sans", FontBold|FontItalic). The font is matched by fontconfig if it is installed, and otherwise by the names and styles of the fonts in the font directories of the operating system. If the system has no font of the style, the closest font is loaded by its own style so that the style is synthesized from it, see SetSynthesis.
 func (family *FontFamily) LoadLocalFont(name string, style FontStyle) error {
-	match := name
-	if style&FontItalic == FontItalic {
-		match += ":italic"
+	filename, index, err := findLocalFont(name, style)
+	if err != nil {
+		return err
 	}
-	if style&FontExtraLight == FontExtraLight {
-		match += ":weight=40"
-	} else if style&FontLight == FontLight {
-		match += ":weight=50"
-	} else if style&FontBook == FontBook {
-		match += ":weight=75"
-	} else if style&FontMedium == FontMedium {
-		match += ":weight=100"
-	} else if style&FontSemibold == FontSemibold {
-		match += ":weight=180"
-	} else if style&FontBold == FontBold {
-		match += ":weight=200"
-	} else if style&FontBlack == FontBlack {
-		match += ":weight=205"
-	} else if style&FontExtraBlack == FontExtraBlack {
-		match += ":weight=210"
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
 	}
-	b, err := exec.Command("fc-match", "--format=%{file}", match).Output()
+	if mimetype, _ := canvasFont.Mimetype(b); mimetype == "font/collection" {
+		if b, err = canvasFont.ParseCollection(b, index); err != nil {
+			return err
+		}
+	}
+	return family.loadMatchedFont(b, style)
+}
+
+// loadMatchedFont loads a font that was matched for a style, which is added by its own style if it differs, unless the family already has a font of that style.
+func (family *FontFamily) loadMatchedFont(b []byte, style FontStyle) error {
+	family.mu.Lock()
+	defer family.mu.Unlock()
+	font, err := parseFont(family.name, b)
 	if err != nil {
 		return err
 	}
-	return family.LoadFontFile(string(b), style)
+	if font.style != style {
+		if _, ok := family.fonts[font.style]; ok {
+			return nil
+		} else if _, ok := family.deferred[font.style]; ok {
+			return nil
+		}
+		style = font.style
+	}
+	family.addFont(font, style)
+	return nil
 }
 
 // LoadFontFile loads a font from a file.
@@ -93,27 +192,292 @@ func (family *FontFamily) LoadFontFile(filename string, style FontStyle) error {
 	return family.LoadFont(b, style)
 }
 
+// LoadFontFileDeferred registers a font by its filename, which is only read and parsed when a font face of the style is first requested, so that registering many fonts of which few are used is fast and takes little memory. If the font cannot be loaded, Face uses the closest style that can be loaded and Err returns the error, see Preload.
+func (family *FontFamily) LoadFontFileDeferred(filename string, style FontStyle) {
+	family.loadFontDeferred(func() ([]byte, error) {
+		return ioutil.ReadFile(filename)
+	}, style)
+}
+
+// fontHTTPClient downloads the fonts of LoadFontURLDeferred.
+var fontHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// LoadFontURLDeferred registers a font by its URL, which is only downloaded and parsed when a font face of the style is first requested, see LoadFontFileDeferred. Downloads time out after 30 seconds and fonts are limited to font.MaxMemory bytes.
+func (family *FontFamily) LoadFontURLDeferred(url string, style FontStyle) {
+	family.loadFontDeferred(func() ([]byte, error) {
+		resp, err := fontHTTPClient.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s: %s", url, resp.Status)
+		}
+		b, err := ioutil.ReadAll(io.LimitReader(resp.Body, int64(canvasFont.MaxMemory)+1))
+		if err != nil {
+			return nil, err
+		} else if int64(canvasFont.MaxMemory) < int64(len(b)) {
+			return nil, canvasFont.ErrExceedsMemory
+		}
+		return b, nil
+	}, style)
+}
+
+// deferredFont is a font that is read by load when it is first used, which happens once even if it is requested by multiple goroutines.
+type deferredFont struct {
+	once sync.Once
+	load func() ([]byte, error)
+	b    []byte
+	err  error
+}
+
+func (family *FontFamily) loadFontDeferred(load func() ([]byte, error), style FontStyle) {
+	family.mu.Lock()
+	defer family.mu.Unlock()
+	family.deferred[style] = &deferredFont{load: load}
+}
+
+// Preload loads all deferred fonts of the family and returns the error of the first font that failed to load, so that errors can be handled before the fonts are used, see Err.
+func (family *FontFamily) Preload() error {
+	for _, style := range family.styles() {
+		family.font(style)
+	}
+	return family.Err()
+}
+
+// Err returns the error of the first deferred font of the family that failed to load, or nil. Failed fonts are removed from the family, so that the closest style is used instead.
+func (family *FontFamily) Err() error {
+	family.mu.Lock()
+	defer family.mu.Unlock()
+	return family.err
+}
+
 // LoadFont loads a font from memory.
 func (family *FontFamily) LoadFont(b []byte, style FontStyle) error {
+	family.mu.Lock()
+	defer family.mu.Unlock()
+	return family.loadFont(b, style)
+}
+
+// LoadFontCollection loads the font at index of a TrueType or OpenType collection (TTC or OTC) from memory, see FontCollectionNames to find the index of a font by its name. LoadFont loads the first font of a collection.
+func (family *FontFamily) LoadFontCollection(b []byte, style FontStyle, index int) error {
+	sfntBytes, err := canvasFont.ParseCollection(b, index)
+	if err != nil {
+		return err
+	}
+	return family.LoadFont(sfntBytes, style)
+}
+
+// LoadFontCollectionFile loads the font at index of a TrueType or OpenType collection (TTC or OTC) from a file.
+func (family *FontFamily) LoadFontCollectionFile(filename string, style FontStyle, index int) error {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return family.LoadFontCollection(b, style, index)
+}
+
+// FontCollectionNames returns the full names of the fonts of a TrueType or OpenType collection (TTC or OTC) in order, such as "Noto Sans CJK JP Regular", or their family names if they have no full name.
+func FontCollectionNames(b []byte) ([]string, error) {
+	n, err := canvasFont.NumCollectionFonts(b)
+	if err != nil {
+		return nil, err
+	}
+	buffer := &sfnt.Buffer{}
+	names := make([]string, n)
+	for i := range names {
+		sfntBytes, err := canvasFont.ParseCollection(b, i)
+		if err != nil {
+			return nil, err
+		}
+		f, err := sfnt.Parse(sfntBytes)
+		if err != nil {
+			return nil, err
+		}
+		if names[i], err = f.Name(buffer, sfnt.NameIDFull); err != nil || names[i] == "" {
+			names[i], _ = f.Name(buffer, sfnt.NameIDFamily)
+		}
+	}
+	return names, nil
+}
+
+// LoadFontVariation loads a static font from a variable font in memory for the given values of its axes, see Font.Variation. For example, load the Bold style of a variable font with axes map[string]float64{"wght": 700.0}.
+func (family *FontFamily) LoadFontVariation(b []byte, style FontStyle, axes map[string]float64) error {
+	font, err := parseFont(family.name, b)
+	if err != nil {
+		return err
+	}
+	if font, err = font.Variation(axes); err != nil {
+		return err
+	}
+	family.mu.Lock()
+	defer family.mu.Unlock()
+	family.addFont(font, style)
+	return nil
+}
+
+func (family *FontFamily) loadFont(b []byte, style FontStyle) error {
 	font, err := parseFont(family.name, b)
 	if err != nil {
 		return err
 	}
+	family.addFont(font, style)
+	return nil
+}
+
+func (family *FontFamily) addFont(font *Font, style FontStyle) {
+	delete(family.deferred, style)
 	font.Use(family.options)
+	if family.rules != nil {
+		font.SetTypographicRules(family.rules)
+	}
+	font.SetSubstitution(family.substitute)
+	font.SetGlyphIndexSubstitution(family.substituteIndex)
 	family.fonts[style] = font
-	return nil
 }
 
 // Use specifies which typographic options shall be used, ie. whether to use common typographic substitutions and which ligatures classes to use.
 func (family *FontFamily) Use(options TypographicOptions) {
+	family.mu.Lock()
+	defer family.mu.Unlock()
 	family.options = options
 	for _, font := range family.fonts {
 		font.Use(options)
 	}
 }
 
-// Face gets the font face given by the font size (in pt).
+// SetTypographicRules sets the typographic substitution rules for all fonts in the family, the default is DefaultTypographicRules.
+func (family *FontFamily) SetTypographicRules(rules TypographicRules) {
+	family.mu.Lock()
+	defer family.mu.Unlock()
+	family.rules = rules
+	for _, font := range family.fonts {
+		font.SetTypographicRules(rules)
+	}
+}
+
+// SetSubstitution sets a callback for all fonts in the family that replaces runes when text is added, see GlyphSubstitution.
+func (family *FontFamily) SetSubstitution(substitute GlyphSubstitution) {
+	family.mu.Lock()
+	defer family.mu.Unlock()
+	family.substitute = substitute
+	for _, font := range family.fonts {
+		font.SetSubstitution(substitute)
+	}
+}
+
+// SetGlyphIndexSubstitution sets a callback for all fonts in the family that remaps glyph indices, see GlyphIndexSubstitution.
+func (family *FontFamily) SetGlyphIndexSubstitution(substitute GlyphIndexSubstitution) {
+	family.mu.Lock()
+	defer family.mu.Unlock()
+	family.substituteIndex = substitute
+	for _, font := range family.fonts {
+		font.SetGlyphIndexSubstitution(substitute)
+	}
+}
+
+// has returns true if the family has a font of the style, which may not be loaded yet.
+func (family *FontFamily) has(style FontStyle) bool {
+	family.mu.Lock()
+	defer family.mu.Unlock()
+	_, deferred := family.deferred[style]
+	return family.fonts[style] != nil || deferred
+}
+
+// font returns the font of the style, or nil if it does not exist, and loads it if it was deferred. A deferred font that fails to load is removed and its error is kept for Err.
+func (family *FontFamily) font(style FontStyle) *Font {
+	family.mu.Lock()
+	d, ok := family.deferred[style]
+	family.mu.Unlock()
+	if ok {
+		// read without holding the lock, so that a slow download does not block other goroutines using the family
+		d.once.Do(func() {
+			d.b, d.err = d.load()
+		})
+		family.mu.Lock()
+		if family.deferred[style] == d {
+			err := d.err
+			if err == nil {
+				err = family.loadFont(d.b, style)
+			}
+			if err != nil {
+				delete(family.deferred, style)
+				if family.err == nil {
+					family.err = fmt.Errorf("font %s: %w", family.name, err)
+				}
+			}
+		}
+		family.mu.Unlock()
+	}
+
+	family.mu.Lock()
+	defer family.mu.Unlock()
+	return family.fonts[style]
+}
+
+// styles returns the styles of the fonts of the family, including those that are not loaded yet.
+func (family *FontFamily) styles() []FontStyle {
+	family.mu.Lock()
+	defer family.mu.Unlock()
+	styles := []FontStyle{}
+	for style := range family.fonts {
+		styles = append(styles, style)
+	}
+	for style := range family.deferred {
+		styles = append(styles, style)
+	}
+	return styles
+}
+
+// styleOf returns the style of a font of the family.
+func (family *FontFamily) styleOf(font *Font) (FontStyle, bool) {
+	family.mu.Lock()
+	defer family.mu.Unlock()
+	for style, f := range family.fonts {
+		if f == font {
+			return style, true
+		}
+	}
+	return 0, false
+}
+
+// closestStyle returns the style of the font of the family that is closest to the given style, preferring the same slant, then the closest weight, and then the lighter weight. It returns false if the family has no fonts.
+func (family *FontFamily) closestStyle(style FontStyle) (FontStyle, bool) {
+	closest, ok := FontRegular, false
+	for _, s := range family.styles() {
+		if !ok || styleDistance(s, style) < styleDistance(closest, style) {
+			closest, ok = s, true
+		}
+	}
+	return closest, ok
+}
+
+// styleDistance returns how far a style is from the requested style, where a different slant is farther than any weight, and a lighter weight is closer than a heavier weight at the same difference.
+func styleDistance(s, style FontStyle) int {
+	d := 2 * (s.weight() - style.weight())
+	if d < 0 {
+		d = -d - 1 // prefer lighter weights
+	}
+	if s&FontItalic != style&FontItalic {
+		d += 2000
+	}
+	return d
+}
+
+// Face gets the font face given by the font size (in pt). It panics if the family has no font that can be loaded.
 func (family *FontFamily) Face(size float64, col color.Color, style FontStyle, variant FontVariant, deco ...FontDecorator) FontFace {
+	face, ok := family.face(size, col, style, variant, deco...)
+	if !ok {
+		if err := family.Err(); err != nil {
+			panic(fmt.Sprintf("requested font style not found: %v", err))
+		}
+		panic("requested font style not found")
+	}
+	return face
+}
+
+// face returns the font face like Face, or false if the family has no font that can be loaded.
+func (family *FontFamily) face(size float64, col color.Color, style FontStyle, variant FontVariant, deco ...FontDecorator) (FontFace, bool) {
 	size *= mmPerPt
 
 	scale := 1.0
@@ -121,31 +485,26 @@ func (family *FontFamily) Face(size float64, col color.Color, style FontStyle, v
 	fauxItalic := 0.0
 	fauxBold := 0.0
 
-	font := family.fonts[style]
+	font := family.font(style)
 	if font == nil {
-		font = family.fonts[FontRegular]
-		if font == nil {
-			panic("requested font style not found")
+		// deferred fonts of the closest style may fail to load too, in which case they are removed
+		var closest FontStyle
+		for font == nil {
+			var ok bool
+			if closest, ok = family.closestStyle(style); !ok {
+				return FontFace{}, false
+			}
+			font = family.font(closest)
 		}
-		if style&FontItalic != 0 {
+
+		family.mu.Lock()
+		synthesis := family.synthesis
+		family.mu.Unlock()
+		if synthesis&SynthesizeItalic != 0 && style&FontItalic != 0 && closest&FontItalic == 0 {
 			fauxItalic = 0.3
 		}
-		if style&FontExtraLight == FontExtraLight {
-			fauxBold = -0.02
-		} else if style&FontLight == FontLight {
-			fauxBold = -0.01
-		} else if style&FontBook == FontBook {
-			fauxBold = -0.005
-		} else if style&FontMedium == FontMedium {
-			fauxBold = 0.005
-		} else if style&FontSemibold == FontSemibold {
-			fauxBold = 0.01
-		} else if style&FontBold == FontBold {
-			fauxBold = 0.02
-		} else if style&FontBlack == FontBlack {
-			fauxBold = 0.03
-		} else if style&FontExtraBlack == FontExtraBlack {
-			fauxBold = 0.04
+		if synthesis&SynthesizeWeight != 0 {
+			fauxBold = fauxBoldness(style.weight()) - fauxBoldness(closest.weight())
 		}
 	}
 
@@ -173,26 +532,155 @@ func (family *FontFamily) Face(size float64, col color.Color, style FontStyle, v
 		voffset:    voffset,
 		fauxItalic: fauxItalic,
 		fauxBold:   fauxBold * size * scale,
+	}, true
+}
+
+// FontRegistry resolves font family names, such as the font-family lists of CSS, to font families.
+type FontRegistry struct {
+	families map[string]*FontFamily
+	fallback *FontFamily
+}
+
+// NewFontRegistry returns a new FontRegistry.
+func NewFontRegistry() *FontRegistry {
+	return &FontRegistry{
+		families: map[string]*FontFamily{},
+	}
+}
+
+// Add registers a font family by a name, which is matched case-insensitively. Generic names such as serif or monospace may be registered too. The first family that is added is the fallback for names that are not registered.
+func (r *FontRegistry) Add(name string, family *FontFamily) {
+	r.families[strings.ToLower(name)] = family
+	if r.fallback == nil {
+		r.fallback = family
+	}
+}
+
+// Family returns the family of the first registered name in a comma-separated list of names, which may be quoted, or the fallback family when none are registered. It returns nil when the registry is empty.
+func (r *FontRegistry) Family(names string) *FontFamily {
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if 1 < len(name) && (name[0] == '"' || name[0] == '\'') && name[0] == name[len(name)-1] {
+			name = name[1 : len(name)-1]
+		}
+		if family, ok := r.families[strings.ToLower(name)]; ok {
+			return family
+		}
+	}
+	return r.fallback
+}
+
+// Face returns a font face of the family that is resolved from the names by Family, see FontFamily.Face. When the family has neither the style nor the regular style, it uses the loaded style of the nearest weight, preferring the same slant. It returns false when no family with fonts is found.
+func (r *FontRegistry) Face(names string, size float64, col color.Color, style FontStyle, variant FontVariant, deco ...FontDecorator) (FontFace, bool) {
+	family := r.Family(names)
+	if family == nil {
+		return FontFace{}, false
+	}
+	styles := family.styles()
+	if len(styles) == 0 {
+		return FontFace{}, false
+	}
+	if !family.has(style) && !family.has(FontRegular) {
+		nearest, distance := style, math.MaxInt32
+		for _, loaded := range styles {
+			d := loaded.weight() - style.weight()
+			if d < 0 {
+				d = -d
+			}
+			if loaded&FontItalic != style&FontItalic {
+				d += 1000
+			}
+			if d < distance || d == distance && loaded < nearest {
+				nearest, distance = loaded, d
+			}
+		}
+		style = nearest
 	}
+	return family.face(size, col, style, variant, deco...)
 }
 
-// FontFace defines a font face from a given font. It allows setting the font size, its color, faux styles and font decorations.
+// FontFace defines a font face from a given font. It allows setting the font size, its color, faux styles, font decorations, and text effects.
 type FontFace struct {
 	family *FontFamily
 	font   *Font
 
-	size    float64
-	style   FontStyle
-	variant FontVariant
-	color   color.RGBA
-	deco    []FontDecorator
+	size     float64
+	style    FontStyle
+	variant  FontVariant
+	color    color.RGBA
+	deco     []FontDecorator
+	effects  []TextEffect
+	vertical bool // set upright glyphs of vertical text, see Vertical
+	hinting  FontHinting
+	dpm      float64  // resolution of the hinting in dots per mm
+	features []string // OpenType features, see WithFeatures
 
 	scale, voffset, fauxBold, fauxItalic float64 // consequences of font style and variant
 }
 
 // Equals returns true when two font face are equal. In particular this allows two adjacent text spans that use the same decoration to allow the decoration to span both elements instead of two separately.
 func (ff FontFace) Equals(other FontFace) bool {
-	return ff.font == other.font && ff.size == other.size && ff.style == other.style && ff.variant == other.variant && ff.color == other.color && reflect.DeepEqual(ff.deco, other.deco)
+	return ff.font == other.font && ff.size == other.size && ff.style == other.style && ff.variant == other.variant && ff.color == other.color && reflect.DeepEqual(ff.deco, other.deco) && reflect.DeepEqual(ff.effects, other.effects) && ff.vertical == other.vertical && ff.hinting == other.hinting && ff.dpm == other.dpm && reflect.DeepEqual(ff.features, other.features)
+}
+
+// FontHinting is the hinting of a font face, which rounds its metrics to whole pixels at the resolution of raster output so that small text looks crisper. The outlines of the glyphs themselves are not hinted.
+type FontHinting int
+
+// see FontHinting
+const (
+	NoHinting       FontHinting = iota
+	VerticalHinting             // round up the ascent, descent, line height, x-height, and cap height
+	FullHinting                 // round up the vertical metrics, and round the advances and kerning of glyphs
+)
+
+// Hinting returns the font face with hinting at a resolution of dpm dots per millimeter, which should be the resolution of the raster output, eg. face.Hinting(canvas.FullHinting, 96.0/25.4) for 96 DPI. The hinting affects the metrics, the advances and kerning of its glyphs, and thus the layout of text and the placement of glyphs by ToPath.
+func (ff FontFace) Hinting(hinting FontHinting, dpm float64) FontFace {
+	if dpm <= 0.0 {
+		hinting = NoHinting
+	}
+	ff.hinting = hinting
+	ff.dpm = dpm
+	if hinting == NoHinting {
+		ff.dpm = 0.0
+	}
+	return ff
+}
+
+// withSize returns the font face at size (in points), with all other settings retained. The offset of subscripts and superscripts and the faux boldness scale along.
+func (ff FontFace) withSize(size float64) FontFace {
+	size *= mmPerPt
+	if ff.size != 0.0 {
+		ff.voffset *= size / ff.size
+		ff.fauxBold *= size / ff.size
+	}
+	ff.size = size
+	return ff
+}
+
+// pixels returns the number of pixels per mm of full hinting, or 1 otherwise so that sfnt measures glyphs in mm.
+func (ff FontFace) pixels() float64 {
+	if ff.hinting != FullHinting {
+		return 1.0
+	}
+	return ff.dpm
+}
+
+// ppem returns the size of the font face for sfnt to measure glyphs, in pixels of full hinting or in mm otherwise.
+func (ff FontFace) ppem() fixed.Int26_6 {
+	return toI26_6(ff.size * ff.scale * ff.pixels())
+}
+
+// fromPixels converts a distance measured by sfnt at ppem to mm.
+func (ff FontFace) fromPixels(v fixed.Int26_6) float64 {
+	return fromI26_6(v) / ff.pixels()
+}
+
+// advanceHinting returns the hinting of sfnt for the advances and kerning of glyphs.
+func (ff FontFace) advanceHinting() font.Hinting {
+	if ff.hinting == FullHinting {
+		return font.HintingFull
+	}
+	return font.HintingNone
 }
 
 // Info returns the font name, size and style.
@@ -214,58 +702,48 @@ type FontMetrics struct {
 func (ff FontFace) Metrics() FontMetrics {
 	buffer := &sfnt.Buffer{}
 	m, _ := ff.font.sfnt.Metrics(buffer, toI26_6(ff.size*ff.scale), font.HintingNone)
+	round := func(v fixed.Int26_6) float64 {
+		f := math.Abs(fromI26_6(v))
+		if ff.hinting != NoHinting {
+			f = math.Ceil(f*ff.dpm) / ff.dpm // up to whole pixels
+		}
+		return f
+	}
 	return FontMetrics{
 		Size:       ff.size,
-		LineHeight: math.Abs(fromI26_6(m.Height)),
-		Ascent:     math.Abs(fromI26_6(m.Ascent)),
-		Descent:    math.Abs(fromI26_6(m.Descent)),
-		XHeight:    math.Abs(fromI26_6(m.XHeight)),
-		CapHeight:  math.Abs(fromI26_6(m.CapHeight)),
+		LineHeight: round(m.Height),
+		Ascent:     round(m.Ascent),
+		Descent:    round(m.Descent),
+		XHeight:    round(m.XHeight),
+		CapHeight:  round(m.CapHeight),
 	}
 }
 
 // Kerning returns the kerning between two runes in mm (ie. the adjustment on the advance).
 func (ff FontFace) Kerning(rPrev, rNext rune) float64 {
 	buffer := &sfnt.Buffer{}
-	prevIndex, err := ff.font.sfnt.GlyphIndex(buffer, rPrev)
+	prevIndex, err := ff.font.glyphIndex(buffer, rPrev)
 	if err != nil {
 		return 0.0
 	}
 
-	nextIndex, err := ff.font.sfnt.GlyphIndex(buffer, rNext)
+	nextIndex, err := ff.font.glyphIndex(buffer, rNext)
 	if err != nil {
 		return 0.0
 	}
 
-	kern, err := ff.font.sfnt.Kern(buffer, prevIndex, nextIndex, toI26_6(ff.size*ff.scale), font.HintingNone)
+	kern, err := ff.font.kern(buffer, prevIndex, nextIndex, ff.ppem(), ff.advanceHinting())
 	if err == nil {
-		return fromI26_6(kern)
+		return ff.fromPixels(kern)
 	}
 	return 0.0
 }
 
-// TextWidth returns the width of a given string in mm.
+// TextWidth returns the width of a given string in mm, which is the sum of the advances of its shaped glyphs.
 func (ff FontFace) TextWidth(s string) float64 {
-	buffer := &sfnt.Buffer{}
 	w := 0.0
-	var prevIndex sfnt.GlyphIndex
-	for i, r := range s {
-		index, err := ff.font.sfnt.GlyphIndex(buffer, r)
-		if err != nil {
-			continue
-		}
-
-		if i != 0 {
-			kern, err := ff.font.sfnt.Kern(buffer, prevIndex, index, toI26_6(ff.size*ff.scale), font.HintingNone)
-			if err == nil {
-				w += fromI26_6(kern)
-			}
-		}
-		advance, err := ff.font.sfnt.GlyphAdvance(buffer, index, toI26_6(ff.size*ff.scale), font.HintingNone)
-		if err == nil {
-			w += fromI26_6(advance)
-		}
-		prevIndex = index
+	for _, glyph := range ff.Shape(s) {
+		w += glyph.XAdvance
 	}
 	return w
 }
@@ -283,93 +761,146 @@ func (ff FontFace) Decorate(width float64) *Path {
 
 // ToPath converts a string to a path and also returns its advance in mm.
 func (ff FontFace) ToPath(s string) (*Path, float64) {
+	p := &Path{}
+	advance := ff.appendPath(p, s, Identity)
+	return p, advance
+}
+
+// ToCanvas converts a string to a canvas with a layer for each color glyph and also returns its advance in mm. Unlike ToPath, glyphs of color fonts such as emoji fonts are drawn in their colors, by the paths of the SVG and COLR tables or by the images of the sbix and CBDT tables. Other glyphs are drawn by their outlines in the color of the font face. The canvas is as wide as the advance and as high as the ascent plus descent of the font, with the base line at the descent.
+func (ff FontFace) ToCanvas(s string) (*Canvas, float64) {
+	metrics := ff.Metrics()
+	c := New(0.0, metrics.Ascent+metrics.Descent)
 	buffer := &sfnt.Buffer{}
 	p := &Path{}
 	x := 0.0
-	var prevIndex sfnt.GlyphIndex
-	for i, r := range s {
-		index, err := ff.font.sfnt.GlyphIndex(buffer, r)
-		if err != nil {
-			return p, 0.0
+	for _, glyph := range ff.Shape(s) {
+		index := sfnt.GlyphIndex(glyph.ID)
+		m := Identity.Translate(x+glyph.XOffset+ff.fauxItalic*glyph.YOffset, metrics.Descent+glyph.YOffset)
+		if paths, colors, ok := ff.colorGlyphPaths(index, m); ok {
+			for i, path := range paths {
+				style := DefaultStyle
+				style.FillColor = colors[i]
+				c.RenderPath(path, style, Identity)
+			}
+		} else if img, imgM, ok := ff.colorGlyphImage(index, m); ok {
+			c.RenderImage(img, imgM)
+		} else if err := ff.appendGlyph(p, buffer, index, 0.0, m); err != nil {
+			return c, 0.0
 		}
+		x += glyph.XAdvance
+	}
+	if !p.Empty() {
+		style := DefaultStyle
+		style.FillColor = ff.color
+		c.RenderPath(p, style, Identity)
+	}
+	c.W = x
+	return c, x
+}
 
-		segments, err := ff.font.sfnt.LoadGlyph(buffer, index, toI26_6(ff.size*ff.scale), nil)
-		if err != nil {
-			return p, 0.0
+// appendPath appends the shaped glyphs of s to p transformed by m, without allocating a new path, and returns the advance in mm.
+func (ff FontFace) appendPath(p *Path, s string, m Matrix) float64 {
+	buffer := &sfnt.Buffer{}
+	x := 0.0
+	for _, glyph := range ff.Shape(s) {
+		if err := ff.appendGlyph(p, buffer, sfnt.GlyphIndex(glyph.ID), 0.0, m.Translate(x+glyph.XOffset+ff.fauxItalic*glyph.YOffset, glyph.YOffset)); err != nil {
+			return 0.0
 		}
+		x += glyph.XAdvance
+	}
+	return x
+}
 
-		var start0, end Point
-		for i, segment := range segments {
-			switch segment.Op {
-			case sfnt.SegmentOpMoveTo:
-				if i != 0 && start0.Equals(end) {
-					p.Close()
-				}
-				end = fromP26_6(segment.Args[0])
-				end.X += ff.fauxItalic * -end.Y
-				p.MoveTo(x+end.X, ff.voffset-end.Y)
-				start0 = end
-			case sfnt.SegmentOpLineTo:
-				end = fromP26_6(segment.Args[0])
-				end.X += ff.fauxItalic * -end.Y
-				p.LineTo(x+end.X, ff.voffset-end.Y)
-			case sfnt.SegmentOpQuadTo:
-				cp := fromP26_6(segment.Args[0])
-				end = fromP26_6(segment.Args[1])
-				cp.X += ff.fauxItalic * -cp.Y
-				end.X += ff.fauxItalic * -end.Y
-				p.QuadTo(x+cp.X, ff.voffset-cp.Y, x+end.X, ff.voffset-end.Y)
-			case sfnt.SegmentOpCubeTo:
-				cp1 := fromP26_6(segment.Args[0])
-				cp2 := fromP26_6(segment.Args[1])
-				end = fromP26_6(segment.Args[2])
-				cp1.X += ff.fauxItalic * -cp1.Y
-				cp2.X += ff.fauxItalic * -cp2.Y
-				end.X += ff.fauxItalic * -end.Y
-				p.CubeTo(x+cp1.X, ff.voffset-cp1.Y, x+cp2.X, ff.voffset-cp2.Y, x+end.X, ff.voffset-end.Y)
-			}
-		}
-		if !p.Empty() && start0.Equals(end) {
-			p.Close()
-		}
-		if ff.fauxBold != 0.0 {
-			p = p.Offset(ff.fauxBold, NonZero)
-		}
+// appendGlyph appends the outline of a glyph to p at the horizontal position x, transformed by m.
+func (ff FontFace) appendGlyph(p *Path, buffer *sfnt.Buffer, index sfnt.GlyphIndex, x float64, m Matrix) error {
+	segments, err := ff.font.loadGlyph(buffer, index, ff.ppem())
+	if err != nil {
+		return err
+	}
+	pixels := ff.pixels()
 
-		if i != 0 {
-			kern, err := ff.font.sfnt.Kern(buffer, prevIndex, index, toI26_6(ff.size*ff.scale), font.HintingNone)
-			if err == nil {
-				x += fromI26_6(kern)
+	q := p
+	if ff.fauxBold != 0.0 {
+		q = GetPath() // the glyph is emboldened separately
+	}
+	pos := func(p Point) Point {
+		p = p.Div(pixels)
+		p.X += ff.fauxItalic * -p.Y
+		return m.Dot(Point{x + p.X, ff.voffset - p.Y})
+	}
+
+	var start0, end Point
+	for i, segment := range segments {
+		switch segment.Op {
+		case sfnt.SegmentOpMoveTo:
+			if i != 0 && start0.Equals(end) {
+				q.Close()
 			}
+			end = pos(fromP26_6(segment.Args[0]))
+			q.MoveTo(end.X, end.Y)
+			start0 = end
+		case sfnt.SegmentOpLineTo:
+			end = pos(fromP26_6(segment.Args[0]))
+			q.LineTo(end.X, end.Y)
+		case sfnt.SegmentOpQuadTo:
+			cp := pos(fromP26_6(segment.Args[0]))
+			end = pos(fromP26_6(segment.Args[1]))
+			q.QuadTo(cp.X, cp.Y, end.X, end.Y)
+		case sfnt.SegmentOpCubeTo:
+			cp1 := pos(fromP26_6(segment.Args[0]))
+			cp2 := pos(fromP26_6(segment.Args[1]))
+			end = pos(fromP26_6(segment.Args[2]))
+			q.CubeTo(cp1.X, cp1.Y, cp2.X, cp2.Y, end.X, end.Y)
 		}
-		advance, err := ff.font.sfnt.GlyphAdvance(buffer, index, toI26_6(ff.size*ff.scale), font.HintingNone)
-		if err == nil {
-			x += fromI26_6(advance)
+	}
+	if !q.Empty() && start0.Equals(end) {
+		q.Close()
+	}
+	if ff.fauxBold != 0.0 {
+		p.d = append(p.d, q.Offset(ff.fauxBold, NonZero).d...)
+		PutPath(q)
+	}
+	return nil
+}
+
+// colorGlyphPaths returns the paths and colors of the color glyph of the SVG or COLR table for a glyph index, transformed by m. It returns false if the glyph has no such color glyph and is drawn by its outline or by an image. The colors of the glyph are made as translucent as the color of the font face, and layers of the COLR table of the foreground color take the color of the font face.
+func (ff FontFace) colorGlyphPaths(index sfnt.GlyphIndex, m Matrix) ([]*Path, []color.RGBA, bool) {
+	glyph := ff.font.colorGlyph(index)
+	if glyph == nil || glyph.img != nil {
+		return nil, nil, false
+	}
+
+	m = ff.colorGlyphMatrix(m)
+	paths := make([]*Path, 0, len(glyph.paths))
+	colors := make([]color.RGBA, 0, len(glyph.colors))
+	for i, p := range glyph.paths {
+		paths = append(paths, p.Transform(m))
+		if glyph.foreground[i] {
+			colors = append(colors, ff.color)
+		} else {
+			colors = append(colors, scaleAlpha(glyph.colors[i], float64(ff.color.A)/255.0))
 		}
-		prevIndex = index
 	}
-	return p, x
+	return paths, colors, true
 }
 
-func (ff FontFace) boldness() int {
-	boldness := 400
-	if ff.style&FontExtraLight == FontExtraLight {
-		boldness = 100
-	} else if ff.style&FontLight == FontLight {
-		boldness = 200
-	} else if ff.style&FontBook == FontBook {
-		boldness = 300
-	} else if ff.style&FontMedium == FontMedium {
-		boldness = 500
-	} else if ff.style&FontSemibold == FontSemibold {
-		boldness = 600
-	} else if ff.style&FontBold == FontBold {
-		boldness = 700
-	} else if ff.style&FontBlack == FontBlack {
-		boldness = 800
-	} else if ff.style&FontExtraBlack == FontExtraBlack {
-		boldness = 900
+// colorGlyphImage returns the image of the color glyph of the sbix or CBDT table for a glyph index and the transformation from its pixels, transformed by m. It returns false if the glyph has no image.
+func (ff FontFace) colorGlyphImage(index sfnt.GlyphIndex, m Matrix) (image.Image, Matrix, bool) {
+	glyph := ff.font.colorGlyph(index)
+	if glyph == nil || glyph.img == nil {
+		return nil, Matrix{}, false
 	}
+	return glyph.img, ff.colorGlyphMatrix(m).Mul(glyph.m), true
+}
+
+// colorGlyphMatrix returns the transformation from font units of a color glyph, transformed by m.
+func (ff FontFace) colorGlyphMatrix(m Matrix) Matrix {
+	scale := ff.size * ff.scale / float64(ff.font.sfnt.UnitsPerEm())
+	return m.Translate(0.0, ff.voffset).Shear(ff.fauxItalic, 0.0).Scale(scale, scale)
+}
+
+func (ff FontFace) boldness() int {
+	boldness := ff.style.weight()
 	if ff.variant&FontSubscript != 0 || ff.variant&FontSuperscript != 0 {
 		boldness += 300
 		if 1000 < boldness {
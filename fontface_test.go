@@ -1,6 +1,12 @@
 package canvas
 
 import (
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/tdewolff/test"
@@ -26,6 +32,154 @@ func TestFontFamily(t *testing.T) {
 	test.T(t, face.boldness(), 1000)
 }
 
+func TestFontFamilySynthesis(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+
+	family := NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFont(b, FontBold))
+	test.T(t, family.fonts[FontBold].style, FontRegular)
+
+	face := family.Face(12.0*ptPerMm, Black, FontBlack|FontItalic, FontNormal)
+	test.Float(t, face.fauxBold, 0.12)
+	test.Float(t, face.fauxItalic, 0.3)
+	face = family.Face(12.0*ptPerMm, Black, FontLight, FontNormal)
+	test.Float(t, face.fauxBold, -0.36)
+
+	family.SetSynthesis(SynthesizeItalic)
+	face = family.Face(12.0*ptPerMm, Black, FontBlack|FontItalic, FontNormal)
+	test.Float(t, face.fauxBold, 0.0)
+	test.Float(t, face.fauxItalic, 0.3)
+	family.SetSynthesis(0)
+	face = family.Face(12.0*ptPerMm, Black, FontBlack|FontItalic, FontNormal)
+	test.Float(t, face.fauxItalic, 0.0)
+
+	// the closest font of the same slant
+	test.Error(t, family.LoadFont(b, FontLight|FontItalic))
+	closest, _ := family.closestStyle(FontRegular)
+	test.T(t, closest, FontBold)
+	closest, _ = family.closestStyle(FontBook | FontItalic)
+	test.T(t, closest, FontLight|FontItalic)
+
+	// a matched font of another style is added by its own style
+	family = NewFontFamily("dejavu-serif")
+	test.Error(t, family.loadMatchedFont(b, FontBold|FontItalic))
+	test.T(t, len(family.fonts), 1)
+	test.That(t, family.fonts[FontRegular] != nil)
+	face = family.Face(12.0*ptPerMm, Black, FontBold|FontItalic, FontNormal)
+	test.Float(t, face.fauxBold, 0.24)
+	test.Float(t, face.fauxItalic, 0.3)
+}
+
+func TestFontFamilyDeferred(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/DejaVuSerif.ttf" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	}))
+	defer server.Close()
+
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFileDeferred("font/DejaVuSerif.ttf", FontRegular)
+	family.LoadFontURLDeferred(server.URL+"/DejaVuSerif.ttf", FontBold)
+	family.LoadFontURLDeferred(server.URL+"/missing.ttf", FontItalic)
+	test.T(t, len(family.fonts), 0)
+
+	face := family.Face(12.0*ptPerMm, Black, FontBold, FontNormal)
+	test.T(t, len(family.fonts), 1)
+	test.Float(t, face.fauxBold, 0.0)
+	family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	test.T(t, len(family.fonts), 2)
+
+	// a font that fails to load falls back to the closest style and reports its error
+	test.Error(t, family.Err())
+	face = family.Face(12.0*ptPerMm, Black, FontItalic, FontNormal)
+	test.T(t, face.font, family.fonts[FontRegular])
+	test.Float(t, face.fauxItalic, 0.3)
+	test.That(t, family.Err() != nil && strings.Contains(family.Err().Error(), "404"), family.Err())
+
+	family = NewFontFamily("dejavu-serif")
+	family.LoadFontFileDeferred("font/DejaVuSerif.ttf", FontRegular)
+	family.LoadFontURLDeferred(server.URL+"/missing.ttf", FontBold)
+	test.That(t, family.Preload() != nil)
+	test.T(t, len(family.fonts), 1)
+	test.T(t, len(family.deferred), 0)
+
+	fonts := NewFontRegistry()
+	fonts.Add("serif", NewFontFamily("empty"))
+	_, ok := fonts.Face("serif", 12.0, Black, FontRegular, FontNormal)
+	test.That(t, !ok, "no fonts")
+
+	missing := NewFontFamily("missing")
+	missing.LoadFontURLDeferred(server.URL+"/missing.ttf", FontRegular)
+	fonts.Add("missing", missing)
+	_, ok = fonts.Face("missing", 12.0, Black, FontRegular, FontNormal)
+	test.That(t, !ok, "no fonts that load")
+
+	defer func() {
+		test.That(t, recover() != nil, "family without fonts panics")
+	}()
+	missing.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+}
+
+func TestFontConcurrency(t *testing.T) {
+	// run with -race to detect data races between goroutines laying out and rendering text
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFileDeferred("font/DejaVuSerif.ttf", FontRegular)
+
+	n := 8
+	widths := make([]float64, n)
+	images := make([][]byte, n)
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+			widths[i] = face.TextWidth("AVAfi")
+
+			c := New(30.0, 10.0)
+			ctx := NewContext(c)
+			ctx.DrawText(0.0, 5.0, NewTextLine(face, "AVAfi", Left))
+			images[i] = c.WriteImage(2.0).Pix
+		}(i)
+	}
+	wg.Wait()
+	test.T(t, len(family.fonts), 1)
+	for i := 1; i < n; i++ {
+		test.Float(t, widths[i], widths[0])
+		test.T(t, images[i], images[0])
+	}
+}
+
+func TestFontRegistry(t *testing.T) {
+	regular := NewFontFamily("dejavu-serif")
+	regular.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	bold := NewFontFamily("dejavu-serif-bold")
+	bold.LoadFontFile("font/DejaVuSerif.ttf", FontBold)
+
+	fonts := NewFontRegistry()
+	test.That(t, fonts.Family("serif") == nil, "empty registry")
+	_, ok := fonts.Face("serif", 12.0, Black, FontRegular, FontNormal)
+	test.That(t, !ok, "empty registry")
+
+	fonts.Add("DejaVu Serif", regular)
+	fonts.Add("Bold", bold)
+	test.That(t, fonts.Family(`"dejavu serif"`) == regular, "quoted name")
+	test.That(t, fonts.Family(`Unknown, 'Bold', serif`) == bold, "first registered name")
+	test.That(t, fonts.Family("sans-serif") == regular, "fallback")
+
+	// the family without a regular style uses the nearest weight
+	face, ok := fonts.Face("Bold", 12.0, Black, FontLight|FontItalic, FontNormal)
+	test.That(t, ok, "face")
+	test.T(t, face.style, FontBold)
+	test.Float(t, face.fauxItalic, 0.0)
+}
+
 func TestFontFace(t *testing.T) {
 	family := NewFontFamily("dejavu-serif")
 	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
@@ -50,6 +204,39 @@ func TestFontFace(t *testing.T) {
 	test.Float(t, width, 18.515625)
 }
 
+func TestFontHinting(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	test.That(t, !face.Equals(face.Hinting(FullHinting, 1.0)))
+	test.That(t, face.Equals(face.Hinting(FullHinting, 0.0)), "no resolution")
+
+	// vertical metrics are rounded up to whole pixels of 0.5mm
+	vertical := face.Hinting(VerticalHinting, 2.0)
+	metrics := vertical.Metrics()
+	test.Float(t, metrics.Size, 12.0)
+	test.Float(t, metrics.LineHeight, 14.0)
+	test.Float(t, metrics.Ascent, 11.5)
+	test.Float(t, metrics.Descent, 3.0)
+	test.Float(t, metrics.XHeight, 6.5)
+	test.Float(t, metrics.CapHeight, 9.0)
+	test.Float(t, vertical.Kerning('A', 'V'), -0.59375)
+	test.Float(t, vertical.TextWidth("AO"), 18.515625)
+
+	// advances and kerning are also rounded
+	full := face.Hinting(FullHinting, 2.0)
+	test.Float(t, full.Metrics().Ascent, 11.5)
+	test.Float(t, full.Kerning('A', 'V'), -0.5)
+	for _, glyph := range full.Shape("AVO") {
+		test.Float(t, glyph.XAdvance*2.0, math.Round(glyph.XAdvance*2.0))
+	}
+	p, width := full.ToPath("AO")
+	test.Float(t, width, full.TextWidth("AO"))
+	unhinted, _ := face.ToPath("AO")
+	shift := full.Shape("AO")[0].XAdvance - face.Shape("AO")[0].XAdvance
+	test.That(t, math.Abs(p.Bounds().W-unhinted.Bounds().W-shift) < 0.01, "O is moved by the rounding of the advance of A")
+}
+
 func TestFontDecoration(t *testing.T) {
 	family := NewFontFamily("dejavu-serif")
 	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
@@ -0,0 +1,189 @@
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Feature is a GeoJSON feature with a geometry and properties, see RFC 7946.
+type Feature struct {
+	ID         interface{}            `json:"id,omitempty"`
+	Geometry   *Geometry              `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Geometry is a GeoJSON geometry, which is one of Point, MultiPoint, LineString, MultiLineString, Polygon, MultiPolygon, or GeometryCollection. Its coordinates are kept as is and are decoded on use.
+type Geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates,omitempty"`
+	Geometries  []*Geometry     `json:"geometries,omitempty"`
+}
+
+// ParseGeoJSON parses a GeoJSON FeatureCollection, Feature, or Geometry, and returns its features. A bare geometry is returned as a feature without properties.
+func ParseGeoJSON(b []byte) ([]*Feature, error) {
+	var object struct {
+		Type     string     `json:"type"`
+		Features []*Feature `json:"features"`
+		Feature
+		Geometry
+	}
+	if err := json.Unmarshal(b, &object); err != nil {
+		return nil, err
+	}
+	switch object.Type {
+	case "FeatureCollection":
+		return object.Features, nil
+	case "Feature":
+		return []*Feature{&object.Feature}, nil
+	case "Point", "MultiPoint", "LineString", "MultiLineString", "Polygon", "MultiPolygon", "GeometryCollection":
+		object.Geometry.Type = object.Type
+		return []*Feature{{Geometry: &object.Geometry}}, nil
+	}
+	return nil, fmt.Errorf("bad GeoJSON: unknown type '%s'", object.Type)
+}
+
+// Path returns the path of the feature's geometry, see Geometry.Path.
+func (f *Feature) Path(proj Projection) (*canvas.Path, error) {
+	if f.Geometry == nil {
+		return &canvas.Path{}, nil
+	}
+	return f.Geometry.Path(proj)
+}
+
+// Path returns the projected lines and polygons of the geometry as a path, where line strings are open subpaths and the rings of polygons are closed subpaths. Points are not included, see Points. The rings of polygons keep their orientation, which should be counter clockwise for exterior rings and clockwise for holes, but since not all GeoJSON follows this it is safer to fill them using the EvenOdd fill rule.
+func (g *Geometry) Path(proj Projection) (*canvas.Path, error) {
+	p := &canvas.Path{}
+	if err := g.path(p, proj); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (g *Geometry) path(p *canvas.Path, proj Projection) error {
+	addLine := func(line [][]float64, closed bool) error {
+		if closed && 1 < len(line) && len(line[0]) == len(line[len(line)-1]) {
+			// rings repeat the first position at the end
+			last := len(line) - 1
+			for j := range line[0] {
+				if line[0][j] != line[last][j] {
+					last++
+					break
+				}
+			}
+			line = line[:last]
+		}
+		for i, pos := range line {
+			if len(pos) < 2 {
+				return fmt.Errorf("bad GeoJSON: position should have at least two values")
+			}
+			q := proj.Project(pos[0], pos[1])
+			if i == 0 {
+				p.MoveTo(q.X, q.Y)
+			} else {
+				p.LineTo(q.X, q.Y)
+			}
+		}
+		if closed && 0 < len(line) {
+			p.Close()
+		}
+		return nil
+	}
+
+	switch g.Type {
+	case "Point", "MultiPoint":
+		// no lines
+	case "LineString":
+		var line [][]float64
+		if err := g.decode(&line); err != nil {
+			return err
+		}
+		return addLine(line, false)
+	case "MultiLineString", "Polygon":
+		var lines [][][]float64
+		if err := g.decode(&lines); err != nil {
+			return err
+		}
+		for _, line := range lines {
+			if err := addLine(line, g.Type == "Polygon"); err != nil {
+				return err
+			}
+		}
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := g.decode(&polygons); err != nil {
+			return err
+		}
+		for _, polygon := range polygons {
+			for _, ring := range polygon {
+				if err := addLine(ring, true); err != nil {
+					return err
+				}
+			}
+		}
+	case "GeometryCollection":
+		for _, child := range g.Geometries {
+			if err := child.path(p, proj); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("bad GeoJSON: unknown geometry type '%s'", g.Type)
+	}
+	return nil
+}
+
+// Points returns the projected positions of the Point and MultiPoint geometries, eg. to draw markers.
+func (g *Geometry) Points(proj Projection) ([]canvas.Point, error) {
+	var positions [][]float64
+	switch g.Type {
+	case "Point":
+		var pos []float64
+		if err := g.decode(&pos); err != nil {
+			return nil, err
+		}
+		positions = [][]float64{pos}
+	case "MultiPoint":
+		if err := g.decode(&positions); err != nil {
+			return nil, err
+		}
+	case "GeometryCollection":
+		points := []canvas.Point{}
+		for _, child := range g.Geometries {
+			childPoints, err := child.Points(proj)
+			if err != nil {
+				return nil, err
+			}
+			points = append(points, childPoints...)
+		}
+		return points, nil
+	}
+
+	points := make([]canvas.Point, 0, len(positions))
+	for _, pos := range positions {
+		if len(pos) < 2 {
+			return nil, fmt.Errorf("bad GeoJSON: position should have at least two values")
+		}
+		points = append(points, proj.Project(pos[0], pos[1]))
+	}
+	return points, nil
+}
+
+// newGeometry returns a geometry of the given type with coordinates, which are nested slices of positions.
+func newGeometry(typ string, coordinates interface{}) (*Geometry, error) {
+	b, err := json.Marshal(coordinates)
+	if err != nil {
+		return nil, fmt.Errorf("bad %s coordinates: %v", typ, err)
+	}
+	return &Geometry{Type: typ, Coordinates: b}, nil
+}
+
+func (g *Geometry) decode(v interface{}) error {
+	if len(g.Coordinates) == 0 {
+		return fmt.Errorf("bad GeoJSON: %s without coordinates", g.Type)
+	} else if err := json.Unmarshal(g.Coordinates, v); err != nil {
+		return fmt.Errorf("bad GeoJSON: %s coordinates: %v", g.Type, err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+var identity = ProjectionFunc(func(lon, lat float64) canvas.Point {
+	return canvas.Point{X: lon, Y: lat}
+})
+
+func TestParseGeoJSON(t *testing.T) {
+	features, err := ParseGeoJSON([]byte(`{"type":"FeatureCollection","features":[
+		{"type":"Feature","id":1,"properties":{"name":"square"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]],[[2,2],[2,8],[8,8],[8,2],[2,2]]]}},
+		{"type":"Feature","properties":null,"geometry":{"type":"LineString","coordinates":[[0,0,100],[5,5,100]]}},
+		{"type":"Feature","properties":null,"geometry":{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[1,2]},{"type":"MultiLineString","coordinates":[[[0,0],[1,1]],[[2,2],[3,3]]]}]}}
+	]}`))
+	test.Error(t, err)
+	test.T(t, len(features), 3)
+	test.T(t, features[0].Properties["name"], "square")
+
+	p, err := features[0].Path(identity)
+	test.Error(t, err)
+	test.T(t, p, canvas.MustParseSVG("M0 0L10 0L10 10L0 10zM2 2L2 8L8 8L8 2z"))
+
+	p, err = features[1].Path(identity)
+	test.Error(t, err)
+	test.T(t, p, canvas.MustParseSVG("M0 0L5 5"))
+
+	p, err = features[2].Path(identity)
+	test.Error(t, err)
+	test.T(t, p, canvas.MustParseSVG("M0 0L1 1M2 2L3 3"))
+	points, err := features[2].Geometry.Points(identity)
+	test.Error(t, err)
+	test.T(t, points, []canvas.Point{{X: 1, Y: 2}})
+
+	// bare geometry
+	features, err = ParseGeoJSON([]byte(`{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[0,1]]],[[[5,5],[6,5],[5,6],[5,5]]]]}`))
+	test.Error(t, err)
+	p, err = features[0].Path(identity)
+	test.Error(t, err)
+	test.T(t, p, canvas.MustParseSVG("M0 0L1 0L0 1zM5 5L6 5L5 6z"))
+
+	_, err = ParseGeoJSON([]byte(`{"type":"Circle"}`))
+	test.That(t, err != nil)
+	features, _ = ParseGeoJSON([]byte(`{"type":"LineString","coordinates":[[0,0],[1]]}`))
+	_, err = features[0].Path(identity)
+	test.That(t, err != nil)
+}
+
+func TestProjection(t *testing.T) {
+	test.T(t, Mercator{}.Project(0.0, 0.0), canvas.Point{X: 0.0, Y: 0.0})
+	test.Float(t, Mercator{}.Project(180.0, 0.0).X, 20037508.342789244)
+	test.Float(t, Mercator{}.Project(0.0, MaxMercatorLatitude).Y, 20037508.342789244)
+	test.Float(t, Mercator{}.Project(0.0, 90.0).Y, 20037508.342789244)
+	test.Float(t, Equirectangular{}.Project(0.0, 90.0).Y, 10018754.171394622)
+	test.Float(t, Equirectangular{60.0}.Project(180.0, 0.0).X, 10018754.171394622)
+}
+
+func TestFit(t *testing.T) {
+	m := Fit(canvas.Rect{X: 10.0, Y: 10.0, W: 20.0, H: 10.0}, 100.0, 100.0)
+	test.T(t, m.Dot(canvas.Point{X: 10.0, Y: 10.0}), canvas.Point{X: 0.0, Y: 25.0})
+	test.T(t, m.Dot(canvas.Point{X: 30.0, Y: 20.0}), canvas.Point{X: 100.0, Y: 75.0})
+}
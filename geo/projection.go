@@ -0,0 +1,61 @@
+// Package geo converts geographic data such as GeoJSON into canvas paths, using a projection from longitude and latitude to the plane.
+package geo
+
+import (
+	"math"
+
+	"github.com/tdewolff/canvas"
+)
+
+// EarthRadius is the equatorial radius of the Earth in meters as defined by WGS 84.
+const EarthRadius = 6378137.0
+
+// MaxMercatorLatitude is the latitude in degrees at which the Mercator projection is cut off, such that the world becomes a square.
+const MaxMercatorLatitude = 85.05112877980659
+
+// Projection projects a longitude and latitude in degrees onto the plane, with x increasing to the east and y increasing to the north.
+type Projection interface {
+	Project(lon, lat float64) canvas.Point
+}
+
+// ProjectionFunc is a function that implements Projection.
+type ProjectionFunc func(lon, lat float64) canvas.Point
+
+// Project projects a longitude and latitude in degrees.
+func (f ProjectionFunc) Project(lon, lat float64) canvas.Point {
+	return f(lon, lat)
+}
+
+// Mercator is the (spherical) Mercator projection as used by web maps, which preserves angles. Coordinates are in meters at the equator and latitudes are clamped to MaxMercatorLatitude.
+type Mercator struct{}
+
+// Project projects a longitude and latitude in degrees.
+func (Mercator) Project(lon, lat float64) canvas.Point {
+	lat = math.Max(-MaxMercatorLatitude, math.Min(MaxMercatorLatitude, lat))
+	phi := lat * math.Pi / 180.0
+	return canvas.Point{X: EarthRadius * lon * math.Pi / 180.0, Y: EarthRadius * math.Log(math.Tan(math.Pi/4.0+phi/2.0))}
+}
+
+// Equirectangular is the equirectangular projection that maps meridians and parallels to equally spaced straight lines, with distances true along the meridians and along the standard parallel Lat0 in degrees. Coordinates are in meters, and with Lat0 equal to zero it is the plate carrée projection.
+type Equirectangular struct {
+	Lat0 float64
+}
+
+// Project projects a longitude and latitude in degrees.
+func (p Equirectangular) Project(lon, lat float64) canvas.Point {
+	return canvas.Point{X: EarthRadius * lon * math.Pi / 180.0 * math.Cos(p.Lat0*math.Pi/180.0), Y: EarthRadius * lat * math.Pi / 180.0}
+}
+
+// Fit returns the transformation matrix that scales and translates a rectangle, such as the bounds of a projected map, uniformly to fit and center within a rectangle of the given width and height with its origin at (0,0).
+func Fit(r canvas.Rect, width, height float64) canvas.Matrix {
+	if r.W == 0.0 && r.H == 0.0 {
+		return canvas.Identity.Translate(width/2.0-r.X, height/2.0-r.Y)
+	}
+	scale := math.Min(width/r.W, height/r.H)
+	if r.W == 0.0 {
+		scale = height / r.H
+	} else if r.H == 0.0 {
+		scale = width / r.W
+	}
+	return canvas.Identity.Translate(width/2.0, height/2.0).Scale(scale, scale).Translate(-r.X-r.W/2.0, -r.Y-r.H/2.0)
+}
@@ -0,0 +1,223 @@
+package geo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// shape types of ESRI shapefiles, the Z and M variants have values that are 10 and 20 higher respectively
+const (
+	shpNull       = 0
+	shpPoint      = 1
+	shpPolyLine   = 3
+	shpPolygon    = 5
+	shpMultiPoint = 8
+)
+
+// ParseShapefile parses an ESRI shapefile from the contents of its .shp file and optionally its .dbf file, and returns a feature for every shape. The attributes in the .dbf file are set as the properties of the features, for which dbf can be nil. Z and M values are ignored. Coordinates are in the coordinate system of the shapefile, as described by its .prj file, and need to be in degrees for use with the projections of this package.
+// Polygons are converted to the orientation of GeoJSON, ie. with counter clockwise exterior rings and clockwise holes, and holes belong to the exterior ring before them.
+func ParseShapefile(shp, dbf []byte) ([]*Feature, error) {
+	if len(shp) < 100 || binary.BigEndian.Uint32(shp) != 9994 {
+		return nil, fmt.Errorf("bad shapefile: invalid header")
+	}
+
+	var records []map[string]interface{}
+	if dbf != nil {
+		var err error
+		if records, err = parseDBF(dbf); err != nil {
+			return nil, err
+		}
+	}
+
+	features := []*Feature{}
+	for pos := 100; pos+8 <= len(shp); {
+		n := 2 * int(binary.BigEndian.Uint32(shp[pos+4:])) // content length is in 16-bit words
+		pos += 8
+		if len(shp) < pos+n || n < 4 {
+			return nil, fmt.Errorf("bad shapefile: record %d exceeds file", len(features)+1)
+		}
+		geometry, err := parseShape(shp[pos : pos+n])
+		if err != nil {
+			return nil, fmt.Errorf("bad shapefile: record %d: %v", len(features)+1, err)
+		}
+		pos += n
+
+		f := &Feature{Geometry: geometry}
+		if len(features) < len(records) {
+			f.Properties = records[len(features)]
+		}
+		features = append(features, f)
+	}
+	return features, nil
+}
+
+func parseShape(b []byte) (*Geometry, error) {
+	typ := int(binary.LittleEndian.Uint32(b))
+	if 10 < typ {
+		typ %= 10 // Z and M variants start with the XY values
+	}
+	b = b[4:]
+	points := func(b []byte, n int) ([][]float64, error) {
+		if len(b) < 16*n {
+			return nil, fmt.Errorf("too few points")
+		}
+		positions := make([][]float64, n)
+		for i := range positions {
+			x := math.Float64frombits(binary.LittleEndian.Uint64(b[16*i:]))
+			y := math.Float64frombits(binary.LittleEndian.Uint64(b[16*i+8:]))
+			positions[i] = []float64{x, y}
+		}
+		return positions, nil
+	}
+
+	switch typ {
+	case shpNull:
+		return nil, nil
+	case shpPoint:
+		positions, err := points(b, 1)
+		if err != nil {
+			return nil, err
+		}
+		return newGeometry("Point", positions[0])
+	case shpMultiPoint:
+		if len(b) < 36 {
+			return nil, fmt.Errorf("too short")
+		}
+		n := int(binary.LittleEndian.Uint32(b[32:]))
+		positions, err := points(b[36:], n)
+		if err != nil {
+			return nil, err
+		}
+		return newGeometry("MultiPoint", positions)
+	case shpPolyLine, shpPolygon:
+		if len(b) < 40 {
+			return nil, fmt.Errorf("too short")
+		}
+		numParts := int(binary.LittleEndian.Uint32(b[32:]))
+		numPoints := int(binary.LittleEndian.Uint32(b[36:]))
+		if len(b) < 40+4*numParts {
+			return nil, fmt.Errorf("too few parts")
+		}
+		positions, err := points(b[40+4*numParts:], numPoints)
+		if err != nil {
+			return nil, err
+		}
+		parts := make([][][]float64, numParts)
+		for i := range parts {
+			start := int(binary.LittleEndian.Uint32(b[40+4*i:]))
+			end := numPoints
+			if i+1 < numParts {
+				end = int(binary.LittleEndian.Uint32(b[40+4*(i+1):]))
+			}
+			if end < start || numPoints < end {
+				return nil, fmt.Errorf("bad part %d", i)
+			}
+			parts[i] = positions[start:end]
+		}
+
+		if typ == shpPolyLine {
+			if len(parts) == 1 {
+				return newGeometry("LineString", parts[0])
+			}
+			return newGeometry("MultiLineString", parts)
+		}
+
+		// exterior rings are clockwise and holes are counter clockwise
+		polygons := [][][][]float64{}
+		for _, ring := range parts {
+			if ringArea(ring) <= 0.0 || len(polygons) == 0 {
+				polygons = append(polygons, [][][]float64{})
+			}
+			reverseRing(ring)
+			polygons[len(polygons)-1] = append(polygons[len(polygons)-1], ring)
+		}
+		if len(polygons) == 1 {
+			return newGeometry("Polygon", polygons[0])
+		}
+		return newGeometry("MultiPolygon", polygons)
+	}
+	return nil, fmt.Errorf("unsupported shape type %d", typ)
+}
+
+// ringArea returns the signed area of a ring, which is positive for counter clockwise rings.
+func ringArea(ring [][]float64) float64 {
+	area := 0.0
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		area += a[0]*b[1] - b[0]*a[1]
+	}
+	return area / 2.0
+}
+
+func reverseRing(ring [][]float64) {
+	for i, j := 0, len(ring)-1; i < j; i, j = i+1, j-1 {
+		ring[i], ring[j] = ring[j], ring[i]
+	}
+}
+
+// parseDBF parses the records of a dBase file as used by shapefiles for its attributes. Numeric fields are converted to float64, logical fields to bool, and all other fields are strings.
+func parseDBF(b []byte) ([]map[string]interface{}, error) {
+	if len(b) < 32 {
+		return nil, fmt.Errorf("bad dbf: invalid header")
+	}
+	numRecords := int(binary.LittleEndian.Uint32(b[4:]))
+	headerLen := int(binary.LittleEndian.Uint16(b[8:]))
+	recordLen := int(binary.LittleEndian.Uint16(b[10:]))
+	if len(b) < headerLen || len(b) < headerLen+numRecords*recordLen {
+		return nil, fmt.Errorf("bad dbf: records exceed file")
+	}
+
+	type field struct {
+		name   string
+		typ    byte
+		offset int
+		length int
+	}
+	fields := []field{}
+	offset := 1 // deletion flag
+	for pos := 32; pos+32 <= headerLen && b[pos] != 0x0D; pos += 32 {
+		name := string(b[pos : pos+11])
+		if i := strings.IndexByte(name, 0); i != -1 {
+			name = name[:i]
+		}
+		length := int(b[pos+16])
+		fields = append(fields, field{name, b[pos+11], offset, length})
+		offset += length
+	}
+	if recordLen < offset {
+		return nil, fmt.Errorf("bad dbf: fields exceed record length")
+	}
+
+	records := make([]map[string]interface{}, 0, numRecords)
+	for i := 0; i < numRecords; i++ {
+		record := b[headerLen+i*recordLen : headerLen+(i+1)*recordLen]
+		properties := map[string]interface{}{}
+		for _, f := range fields {
+			s := strings.TrimSpace(string(record[f.offset : f.offset+f.length]))
+			switch f.typ {
+			case 'N', 'F':
+				if v, err := strconv.ParseFloat(s, 64); err == nil {
+					properties[f.name] = v
+				} else {
+					properties[f.name] = nil
+				}
+			case 'L':
+				switch s {
+				case "T", "t", "Y", "y":
+					properties[f.name] = true
+				case "F", "f", "N", "n":
+					properties[f.name] = false
+				default:
+					properties[f.name] = nil
+				}
+			default:
+				properties[f.name] = s
+			}
+		}
+		records = append(records, properties)
+	}
+	return records, nil
+}
@@ -0,0 +1,109 @@
+package geo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+// shapefile writes a shapefile with the given shapes, each being a shape type followed by its parts of points.
+func shapefile(shapes ...[]interface{}) []byte {
+	records := &bytes.Buffer{}
+	for i, shape := range shapes {
+		content := &bytes.Buffer{}
+		typ := shape[0].(int)
+		binary.Write(content, binary.LittleEndian, int32(typ))
+		parts := shape[1:]
+		if typ == shpPoint {
+			binary.Write(content, binary.LittleEndian, parts[0].([]float64))
+		} else if typ != shpNull {
+			n := 0
+			for _, part := range parts {
+				n += len(part.([]float64)) / 2
+			}
+			binary.Write(content, binary.LittleEndian, [4]float64{}) // bounding box
+			binary.Write(content, binary.LittleEndian, int32(len(parts)))
+			binary.Write(content, binary.LittleEndian, int32(n))
+			start := 0
+			for _, part := range parts {
+				binary.Write(content, binary.LittleEndian, int32(start))
+				start += len(part.([]float64)) / 2
+			}
+			for _, part := range parts {
+				binary.Write(content, binary.LittleEndian, part.([]float64))
+			}
+		}
+		binary.Write(records, binary.BigEndian, int32(i+1))
+		binary.Write(records, binary.BigEndian, int32(content.Len()/2))
+		records.Write(content.Bytes())
+	}
+
+	header := make([]byte, 100)
+	binary.BigEndian.PutUint32(header, 9994)
+	binary.BigEndian.PutUint32(header[24:], uint32((100+records.Len())/2))
+	binary.LittleEndian.PutUint32(header[28:], 1000)
+	return append(header, records.Bytes()...)
+}
+
+func TestParseShapefile(t *testing.T) {
+	shp := shapefile(
+		[]interface{}{shpPoint, []float64{1, 2}},
+		[]interface{}{shpPolyLine, []float64{0, 0, 5, 5}},
+		[]interface{}{shpPolygon, []float64{0, 0, 0, 10, 10, 10, 10, 0, 0, 0}, []float64{2, 2, 8, 2, 8, 8, 2, 8, 2, 2}, []float64{20, 0, 20, 10, 30, 10, 20, 0}},
+		[]interface{}{shpNull},
+	)
+
+	// dBase file with a character and a numeric field
+	dbf := make([]byte, 32+2*32+1)
+	dbf[0] = 3
+	binary.LittleEndian.PutUint32(dbf[4:], 4)
+	binary.LittleEndian.PutUint16(dbf[8:], uint16(len(dbf)))
+	binary.LittleEndian.PutUint16(dbf[10:], 1+8+4)
+	copy(dbf[32:], "NAME")
+	dbf[32+11], dbf[32+16] = 'C', 8
+	copy(dbf[64:], "POP")
+	dbf[64+11], dbf[64+16] = 'N', 4
+	dbf[96] = 0x0D
+	for _, record := range []string{" point     12", " line        ", " polygon  3.5", " null       0"} {
+		dbf = append(dbf, record...)
+	}
+
+	features, err := ParseShapefile(shp, dbf)
+	test.Error(t, err)
+	test.T(t, len(features), 4)
+	test.T(t, features[0].Properties["NAME"], "point")
+	test.T(t, features[0].Properties["POP"], 12.0)
+	test.T(t, features[1].Properties["POP"], nil)
+	test.T(t, features[2].Properties["POP"], 3.5)
+
+	points, err := features[0].Geometry.Points(identity)
+	test.Error(t, err)
+	test.T(t, points, []canvas.Point{{X: 1, Y: 2}})
+
+	p, err := features[1].Path(identity)
+	test.Error(t, err)
+	test.T(t, p, canvas.MustParseSVG("M0 0L5 5"))
+
+	// rings are reversed and holes belong to the preceding exterior ring
+	test.T(t, features[2].Geometry.Type, "MultiPolygon")
+	p, err = features[2].Path(identity)
+	test.Error(t, err)
+	test.T(t, p, canvas.MustParseSVG("M0 0L10 0L10 10L0 10zM2 2L2 8L8 8L8 2zM20 0L30 10L20 10z"))
+	test.That(t, p.Interior(1.0, 1.0, canvas.NonZero))
+	test.That(t, !p.Interior(5.0, 5.0, canvas.NonZero))
+
+	test.That(t, features[3].Geometry == nil)
+
+	features, err = ParseShapefile(shp, nil)
+	test.Error(t, err)
+	test.T(t, len(features), 4)
+
+	_, err = ParseShapefile(shp[:50], nil)
+	test.That(t, err != nil)
+	_, err = ParseShapefile(shp[:len(shp)-20], nil)
+	test.That(t, err != nil)
+	test.T(t, ringArea([][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}}), 1.0)
+}
@@ -0,0 +1,182 @@
+package geo
+
+import (
+	"container/list"
+	"fmt"
+	"image"
+	_ "image/jpeg" // register JPEG decoder for tiles
+	_ "image/png"  // register PNG decoder for tiles
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tdewolff/canvas"
+)
+
+// TileSource provides the raster tiles of the XYZ tiling scheme used by slippy maps, where zoom level z has 2^z by 2^z tiles in the Mercator projection with tile (0,0) in the north-west.
+type TileSource interface {
+	Tile(z, x, y int) (image.Image, error)
+}
+
+// TileSourceFunc is a function that implements TileSource.
+type TileSourceFunc func(z, x, y int) (image.Image, error)
+
+// Tile returns the tile at zoom level z and position (x,y).
+func (f TileSourceFunc) Tile(z, x, y int) (image.Image, error) {
+	return f(z, x, y)
+}
+
+// URLTiles fetches tiles over HTTP from a URL template, where {z}, {x}, and {y} are replaced by the zoom level and tile position, eg. https://tile.openstreetmap.org/{z}/{x}/{y}.png. Most tile servers require a descriptive UserAgent and have usage policies that forbid bulk downloading, so use it with a TileCache.
+type URLTiles struct {
+	Template  string
+	UserAgent string
+	Client    *http.Client // uses http.DefaultClient if nil
+}
+
+// Tile returns the tile at zoom level z and position (x,y).
+func (t *URLTiles) Tile(z, x, y int) (image.Image, error) {
+	url := strings.NewReplacer("{z}", strconv.Itoa(z), "{x}", strconv.Itoa(x), "{y}", strconv.Itoa(y)).Replace(t.Template)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if t.UserAgent != "" {
+		req.Header.Set("User-Agent", t.UserAgent)
+	}
+
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tile %d/%d/%d: %s", z, x, y, resp.Status)
+	}
+	img, _, err := image.Decode(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("tile %d/%d/%d: %v", z, x, y, err)
+	}
+	return img, nil
+}
+
+type tileKey struct {
+	z, x, y int
+}
+
+type tileEntry struct {
+	key tileKey
+	img image.Image
+}
+
+// TileCache caches the tiles of a source in memory and evicts the least recently used tiles when it is full. It is safe for concurrent use.
+type TileCache struct {
+	source   TileSource
+	capacity int
+
+	mu      sync.Mutex
+	entries map[tileKey]*list.Element
+	order   *list.List // most recently used at the front
+}
+
+// NewTileCache returns a cache of at most capacity tiles from source.
+func NewTileCache(source TileSource, capacity int) *TileCache {
+	return &TileCache{
+		source:   source,
+		capacity: capacity,
+		entries:  map[tileKey]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// Tile returns the tile at zoom level z and position (x,y), fetching it from the source if it is not cached. Errors are not cached.
+func (c *TileCache) Tile(z, x, y int) (image.Image, error) {
+	key := tileKey{z, x, y}
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		c.mu.Unlock()
+		return e.Value.(*tileEntry).img, nil
+	}
+	c.mu.Unlock()
+
+	img, err := c.source.Tile(z, x, y)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && 0 < c.capacity {
+		c.entries[key] = c.order.PushFront(&tileEntry{key, img})
+		for c.capacity < c.order.Len() {
+			e := c.order.Back()
+			c.order.Remove(e)
+			delete(c.entries, e.Value.(*tileEntry).key)
+		}
+	}
+	return img, nil
+}
+
+// Len returns the number of cached tiles.
+func (c *TileCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// TileZoom returns the zoom level for tiles of tileSize pixels, such that their resolution matches or exceeds the resolution of dpm dots per millimeter when drawn with the matrix m that maps Mercator coordinates to canvas coordinates. The zoom level is clamped to [0,maxZoom].
+func TileZoom(m canvas.Matrix, tileSize int, dpm float64, maxZoom int) int {
+	scale := math.Sqrt(math.Abs(m.Det()))             // millimeters per meter
+	size := 2.0 * math.Pi * EarthRadius * scale * dpm // world size in dots
+	z := int(math.Ceil(math.Log2(size / float64(tileSize))))
+	if z < 0 {
+		return 0
+	} else if maxZoom < z {
+		return maxZoom
+	}
+	return z
+}
+
+// DrawTiles draws the tiles at zoom level z that cover the rectangle r in Mercator coordinates, using the matrix m that maps Mercator coordinates to canvas coordinates, such as the one returned by Fit. Use the same matrix to draw vector overlays projected by Mercator on top. Tiles wrap around horizontally across the antimeridian.
+func DrawTiles(ctx *canvas.Context, source TileSource, z int, r canvas.Rect, m canvas.Matrix) error {
+	n := 1 << uint(z)
+	world := 2.0 * math.Pi * EarthRadius
+	size := world / float64(n) // tile size in meters
+
+	x0 := int(math.Floor((r.X + world/2.0) / size))
+	x1 := int(math.Ceil((r.X+r.W+world/2.0)/size)) - 1
+	y0 := int(math.Floor((world/2.0 - r.Y - r.H) / size))
+	y1 := int(math.Ceil((world/2.0-r.Y)/size)) - 1
+	if y0 < 0 {
+		y0 = 0
+	}
+	if n-1 < y1 {
+		y1 = n - 1
+	}
+
+	view := ctx.View().Mul(m)
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			img, err := source.Tile(z, ((x%n)+n)%n, y)
+			if err != nil {
+				return err
+			}
+			w := img.Bounds().Dx()
+			if w == 0 {
+				continue
+			}
+			// lower-left corner of the tile
+			left := -world/2.0 + float64(x)*size
+			bottom := world/2.0 - float64(y+1)*size
+			scale := size / float64(w)
+			ctx.RenderImage(img, view.Translate(left, bottom).Scale(scale, scale))
+		}
+	}
+	return nil
+}
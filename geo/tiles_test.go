@@ -0,0 +1,100 @@
+package geo
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func uniformTile(col color.RGBA) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = col.R, col.G, col.B, col.A
+	}
+	return img
+}
+
+func TestDrawTiles(t *testing.T) {
+	// tiles at zoom level 1 have a different color for each quadrant
+	colors := map[[2]int]color.RGBA{
+		{0, 0}: canvas.Red, {1, 0}: canvas.Lime,
+		{0, 1}: canvas.Blue, {1, 1}: canvas.Yellow,
+	}
+	requested := 0
+	source := TileSourceFunc(func(z, x, y int) (image.Image, error) {
+		requested++
+		if z != 1 {
+			return nil, fmt.Errorf("bad zoom level")
+		}
+		return uniformTile(colors[[2]int{x, y}]), nil
+	})
+
+	world := Mercator{}.Project(180.0, MaxMercatorLatitude)
+	r := canvas.Rect{X: -world.X, Y: -world.Y, W: 2.0 * world.X, H: 2.0 * world.Y}
+	m := Fit(r, 100.0, 100.0)
+	test.T(t, TileZoom(m, 256, 5.12, 18), 1)
+	test.T(t, TileZoom(m, 256, 100.0, 3), 3)
+
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	test.Error(t, DrawTiles(ctx, source, 1, r, m))
+	test.T(t, requested, 4)
+
+	img := c.WriteImage(1.0)
+	test.T(t, img.RGBAAt(25, 25), canvas.Red) // north-west
+	test.T(t, img.RGBAAt(75, 25), canvas.Lime)
+	test.T(t, img.RGBAAt(25, 75), canvas.Blue)
+	test.T(t, img.RGBAAt(75, 75), canvas.Yellow)
+
+	// only the tiles that overlap are drawn, and tiles wrap around horizontally
+	requested = 0
+	test.Error(t, DrawTiles(ctx, source, 1, canvas.Rect{X: world.X / 2.0, Y: world.Y / 2.0, W: world.X, H: world.Y / 4.0}, m))
+	test.T(t, requested, 2)
+
+	test.That(t, DrawTiles(ctx, source, 2, r, m) != nil)
+}
+
+func TestTileCache(t *testing.T) {
+	requested := 0
+	source := TileSourceFunc(func(z, x, y int) (image.Image, error) {
+		requested++
+		return uniformTile(canvas.Red), nil
+	})
+	cache := NewTileCache(source, 2)
+	cache.Tile(0, 0, 0)
+	cache.Tile(1, 0, 0)
+	cache.Tile(0, 0, 0)
+	test.T(t, requested, 2)
+	cache.Tile(1, 1, 0) // evicts 1/0/0
+	cache.Tile(0, 0, 0)
+	test.T(t, requested, 3)
+	cache.Tile(1, 0, 0)
+	test.T(t, requested, 4)
+	test.T(t, cache.Len(), 2)
+}
+
+func TestURLTiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/3/2/1.png" || r.Header.Get("User-Agent") != "canvas-test" {
+			http.NotFound(w, r)
+			return
+		}
+		png.Encode(w, uniformTile(canvas.Red))
+	}))
+	defer server.Close()
+
+	tiles := &URLTiles{Template: server.URL + "/{z}/{x}/{y}.png", UserAgent: "canvas-test"}
+	img, err := tiles.Tile(3, 2, 1)
+	test.Error(t, err)
+	test.T(t, img.Bounds().Dx(), 256)
+
+	_, err = tiles.Tile(3, 2, 2)
+	test.That(t, err != nil)
+}
@@ -0,0 +1,187 @@
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type topology struct {
+	Type      string `json:"type"`
+	Transform *struct {
+		Scale     [2]float64 `json:"scale"`
+		Translate [2]float64 `json:"translate"`
+	} `json:"transform"`
+	Arcs    [][][]float64             `json:"arcs"`
+	Objects map[string]topologyObject `json:"objects"`
+}
+
+type topologyObject struct {
+	Type        string                 `json:"type"`
+	ID          interface{}            `json:"id"`
+	Properties  map[string]interface{} `json:"properties"`
+	Arcs        json.RawMessage        `json:"arcs"`
+	Coordinates json.RawMessage        `json:"coordinates"`
+	Geometries  []topologyObject       `json:"geometries"`
+}
+
+// ParseTopoJSON parses a TopoJSON topology and returns the features of each of its named objects. The shared arcs are decoded, including quantized and delta-encoded arcs, and the geometries are converted to their GeoJSON equivalents. A GeometryCollection object returns a feature for each of its geometries.
+func ParseTopoJSON(b []byte) (map[string][]*Feature, error) {
+	topo := topology{}
+	if err := json.Unmarshal(b, &topo); err != nil {
+		return nil, err
+	} else if topo.Type != "Topology" {
+		return nil, fmt.Errorf("bad TopoJSON: type should be Topology")
+	}
+
+	// decode the arcs into absolute positions
+	transform := func(pos []float64) []float64 {
+		if topo.Transform == nil || len(pos) < 2 {
+			return pos
+		}
+		t := topo.Transform
+		return []float64{pos[0]*t.Scale[0] + t.Translate[0], pos[1]*t.Scale[1] + t.Translate[1]}
+	}
+	arcs := make([][][]float64, len(topo.Arcs))
+	for i, arc := range topo.Arcs {
+		arcs[i] = make([][]float64, len(arc))
+		var x, y float64
+		for j, pos := range arc {
+			if len(pos) < 2 {
+				return nil, fmt.Errorf("bad TopoJSON: position of arc %d should have at least two values", i)
+			}
+			if topo.Transform != nil {
+				x, y = x+pos[0], y+pos[1]
+				arcs[i][j] = transform([]float64{x, y})
+			} else {
+				arcs[i][j] = pos
+			}
+		}
+	}
+
+	objects := map[string][]*Feature{}
+	for name, object := range topo.Objects {
+		features := []*Feature{}
+		geometries := []topologyObject{object}
+		if object.Type == "GeometryCollection" {
+			geometries = object.Geometries
+		}
+		for _, g := range geometries {
+			geometry, err := g.geometry(arcs, transform)
+			if err != nil {
+				return nil, fmt.Errorf("bad TopoJSON: object %s: %v", name, err)
+			}
+			features = append(features, &Feature{
+				ID:         g.ID,
+				Geometry:   geometry,
+				Properties: g.Properties,
+			})
+		}
+		objects[name] = features
+	}
+	return objects, nil
+}
+
+func (g topologyObject) geometry(arcs [][][]float64, transform func([]float64) []float64) (*Geometry, error) {
+	// line joins arcs by their indices, where a negative index ~i refers to the reversed arc i
+	line := func(indices []int) ([][]float64, error) {
+		positions := [][]float64{}
+		for k, i := range indices {
+			reversed := i < 0
+			if reversed {
+				i = ^i
+			}
+			if len(arcs) <= i {
+				return nil, fmt.Errorf("arc %d does not exist", i)
+			}
+			arc := arcs[i]
+			for j := range arc {
+				if reversed {
+					j = len(arc) - 1 - j
+				}
+				if 0 < k && (j == 0 && !reversed || j == len(arc)-1 && reversed) {
+					continue // arcs share their endpoints
+				}
+				positions = append(positions, arc[j])
+			}
+		}
+		return positions, nil
+	}
+	decode := func(raw json.RawMessage, v interface{}) error {
+		if len(raw) == 0 {
+			return fmt.Errorf("%s without arcs or coordinates", g.Type)
+		}
+		return json.Unmarshal(raw, v)
+	}
+
+	switch g.Type {
+	case "Point":
+		var pos []float64
+		if err := decode(g.Coordinates, &pos); err != nil {
+			return nil, err
+		}
+		return newGeometry(g.Type, transform(pos))
+	case "MultiPoint":
+		var positions [][]float64
+		if err := decode(g.Coordinates, &positions); err != nil {
+			return nil, err
+		}
+		for i := range positions {
+			positions[i] = transform(positions[i])
+		}
+		return newGeometry(g.Type, positions)
+	case "LineString":
+		var indices []int
+		if err := decode(g.Arcs, &indices); err != nil {
+			return nil, err
+		}
+		positions, err := line(indices)
+		if err != nil {
+			return nil, err
+		}
+		return newGeometry(g.Type, positions)
+	case "MultiLineString", "Polygon":
+		var indices [][]int
+		if err := decode(g.Arcs, &indices); err != nil {
+			return nil, err
+		}
+		lines := make([][][]float64, len(indices))
+		for i := range indices {
+			var err error
+			if lines[i], err = line(indices[i]); err != nil {
+				return nil, err
+			}
+		}
+		return newGeometry(g.Type, lines)
+	case "MultiPolygon":
+		var indices [][][]int
+		if err := decode(g.Arcs, &indices); err != nil {
+			return nil, err
+		}
+		polygons := make([][][][]float64, len(indices))
+		for i := range indices {
+			polygons[i] = make([][][]float64, len(indices[i]))
+			for j := range indices[i] {
+				var err error
+				if polygons[i][j], err = line(indices[i][j]); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return newGeometry(g.Type, polygons)
+	case "GeometryCollection":
+		geometry := &Geometry{Type: g.Type}
+		for _, child := range g.Geometries {
+			childGeometry, err := child.geometry(arcs, transform)
+			if err != nil {
+				return nil, err
+			}
+			if childGeometry != nil {
+				geometry.Geometries = append(geometry.Geometries, childGeometry)
+			}
+		}
+		return geometry, nil
+	case "":
+		return nil, nil // null geometry
+	}
+	return nil, fmt.Errorf("unknown geometry type '%s'", g.Type)
+}
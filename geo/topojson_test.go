@@ -0,0 +1,59 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestParseTopoJSON(t *testing.T) {
+	// two adjacent squares sharing the arc in the middle, see the TopoJSON specification
+	objects, err := ParseTopoJSON([]byte(`{"type":"Topology",
+		"transform":{"scale":[1,1],"translate":[100,0]},
+		"arcs":[[[10,0],[0,10]],[[10,10],[-10,0],[0,-10],[10,0]],[[10,0],[10,0],[0,10],[-10,0]]],
+		"objects":{
+			"squares":{"type":"GeometryCollection","geometries":[
+				{"type":"Polygon","id":"left","arcs":[[0,1]]},
+				{"type":"Polygon","id":"right","properties":{"a":1},"arcs":[[2,-1]]},
+				{"type":null}
+			]},
+			"border":{"type":"LineString","arcs":[-1]},
+			"cities":{"type":"MultiPoint","coordinates":[[0,0],[5,5]]}
+		}}`))
+	test.Error(t, err)
+	test.T(t, len(objects), 3)
+	test.T(t, len(objects["squares"]), 3)
+	test.T(t, objects["squares"][1].ID, "right")
+	test.T(t, objects["squares"][1].Properties["a"], 1.0)
+
+	p, err := objects["squares"][0].Path(identity)
+	test.Error(t, err)
+	test.T(t, p, canvas.MustParseSVG("M110 0L110 10L100 10L100 0z"))
+	p, err = objects["squares"][1].Path(identity)
+	test.Error(t, err)
+	test.T(t, p, canvas.MustParseSVG("M110 0L120 0L120 10L110 10z"))
+	p, err = objects["squares"][2].Path(identity)
+	test.Error(t, err)
+	test.That(t, p.Empty())
+
+	p, err = objects["border"][0].Path(identity)
+	test.Error(t, err)
+	test.T(t, p, canvas.MustParseSVG("M110 10L110 0"))
+
+	points, err := objects["cities"][0].Geometry.Points(identity)
+	test.Error(t, err)
+	test.T(t, points, []canvas.Point{{X: 100, Y: 0}, {X: 105, Y: 5}})
+
+	// without transform
+	objects, err = ParseTopoJSON([]byte(`{"type":"Topology","arcs":[[[0,0],[1,0],[1,1]]],"objects":{"a":{"type":"MultiLineString","arcs":[[0],[-1]]}}}`))
+	test.Error(t, err)
+	p, err = objects["a"][0].Path(identity)
+	test.Error(t, err)
+	test.T(t, p, canvas.MustParseSVG("M0 0L1 0L1 1M1 1L1 0L0 0"))
+
+	_, err = ParseTopoJSON([]byte(`{"type":"FeatureCollection"}`))
+	test.That(t, err != nil)
+	_, err = ParseTopoJSON([]byte(`{"type":"Topology","arcs":[],"objects":{"a":{"type":"LineString","arcs":[0]}}}`))
+	test.That(t, err != nil)
+}
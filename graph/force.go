@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/tdewolff/canvas"
+)
+
+// ForceLayout is a force-directed layout after Fruchterman and Reingold, where all nodes repel each other and edges pull their nodes together, so that connected nodes end up about Distance apart. A weak pull towards the center keeps disconnected parts of the graph together. Edges are straight and have no bends.
+type ForceLayout struct {
+	Distance   float64 // ideal distance between the centers of connected nodes in millimeters
+	Iterations int
+	Seed       int64 // seed of the random initial positions, so that layouts are reproducible
+}
+
+// NewForceLayout returns a force-directed layout with an ideal edge length of 25 millimeters.
+func NewForceLayout() *ForceLayout {
+	return &ForceLayout{Distance: 25.0, Iterations: 300}
+}
+
+// Layout positions the nodes of the graph.
+func (l *ForceLayout) Layout(g *Graph) error {
+	n := len(g.Nodes)
+	if n == 0 {
+		return nil
+	}
+	k := l.Distance
+	rnd := rand.New(rand.NewSource(l.Seed))
+	size := k * math.Sqrt(float64(n))
+	pos := make([]canvas.Point, n)
+	for i := range pos {
+		pos[i] = canvas.Point{X: rnd.Float64() * size, Y: rnd.Float64() * size}
+	}
+
+	disp := make([]canvas.Point, n)
+	for iter := 0; iter < l.Iterations; iter++ {
+		temperature := size / 10.0 * (1.0 - float64(iter)/float64(l.Iterations))
+		center := canvas.Point{}
+		for i := range pos {
+			center = center.Add(pos[i])
+			disp[i] = canvas.Point{}
+		}
+		center = center.Div(float64(n))
+
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				d := pos[i].Sub(pos[j])
+				dist := d.Length()
+				if dist < 1e-3 {
+					d, dist = canvas.Point{X: 1e-3}, 1e-3
+				}
+				f := d.Mul(k * k / dist / dist) // k^2/dist along the unit vector
+				disp[i] = disp[i].Add(f)
+				disp[j] = disp[j].Sub(f)
+			}
+		}
+		for _, e := range g.Edges {
+			if e.From == e.To {
+				continue
+			}
+			d := pos[e.From].Sub(pos[e.To])
+			f := d.Mul(d.Length() / k) // dist^2/k along the unit vector
+			disp[e.From] = disp[e.From].Sub(f)
+			disp[e.To] = disp[e.To].Add(f)
+		}
+		for i := range pos {
+			disp[i] = disp[i].Add(center.Sub(pos[i]).Mul(0.1)) // gravity
+			if length := disp[i].Length(); temperature < length {
+				disp[i] = disp[i].Mul(temperature / length)
+			}
+			pos[i] = pos[i].Add(disp[i])
+		}
+	}
+
+	for i, node := range g.Nodes {
+		node.X, node.Y = pos[i].X, pos[i].Y
+	}
+	for _, e := range g.Edges {
+		e.Bends = nil
+	}
+	g.axis = freeAxis
+	g.translate()
+	return nil
+}
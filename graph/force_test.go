@@ -0,0 +1,35 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestForceLayout(t *testing.T) {
+	g := New()
+	for _, label := range []string{"a", "b", "c", "d", "e"} {
+		g.AddNode(label)
+	}
+	g.AddEdge(0, 1)
+	g.AddEdge(1, 2)
+	g.AddEdge(2, 0)
+	g.AddEdge(3, 4)
+	test.Error(t, NewForceLayout().Layout(g))
+	test.T(t, g.Bounds().X, 0.0)
+	test.T(t, g.Bounds().Y, 0.0)
+
+	dist := func(i, j int) float64 {
+		return canvas.Point{X: g.Nodes[i].X, Y: g.Nodes[i].Y}.Sub(canvas.Point{X: g.Nodes[j].X, Y: g.Nodes[j].Y}).Length()
+	}
+	test.That(t, dist(0, 1) < dist(0, 3), "connected nodes are closer")
+	test.That(t, dist(3, 4) < dist(1, 4), "connected nodes are closer")
+
+	// reproducible
+	x := g.Nodes[2].X
+	test.Error(t, NewForceLayout().Layout(g))
+	test.Float(t, g.Nodes[2].X, x)
+
+	test.Error(t, NewForceLayout().Layout(New()))
+}
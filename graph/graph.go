@@ -0,0 +1,185 @@
+// Package graph lays out graphs of nodes connected by edges, such as dependency and network diagrams, and draws them onto a canvas with routed edges and arrowheads.
+package graph
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Node is a rectangular node of a graph.
+type Node struct {
+	Label         string
+	Width, Height float64 // size in millimeters
+	X, Y          float64 // position of the center, set by a layout
+}
+
+// Edge is an edge of a graph from the node with index From to the node with index To.
+type Edge struct {
+	From, To int
+	Bends    []canvas.Point // positions that the edge passes through from From to To, set by a layout
+}
+
+// Layout positions the nodes of a graph and sets the bends of its edges.
+type Layout interface {
+	Layout(g *Graph) error
+}
+
+// Graph is a graph of nodes and edges with the style used to draw them.
+type Graph struct {
+	Nodes []*Node
+	Edges []*Edge
+
+	Directed bool // draw arrowheads at the end of the edges
+	Routing  Routing
+
+	Font      *canvas.FontFamily // no labels are drawn when nil
+	FontSize  float64            // in points
+	Padding   float64            // space around the labels in millimeters, see FitLabels
+	TextColor color.RGBA
+
+	NodeStyle    canvas.Style
+	CornerRadius float64 // of the nodes in millimeters
+	EdgeStyle    canvas.Style
+	ArrowLength  float64 // in millimeters
+	ArrowWidth   float64 // in millimeters
+
+	axis axis // set by layouts for orthogonal routing
+}
+
+// axis is the axis along which orthogonal edges leave and enter nodes.
+type axis int
+
+const (
+	freeAxis axis = iota // along the axis of the largest distance
+	verticalAxis
+	horizontalAxis
+)
+
+// New returns a new directed graph without nodes, which draws white nodes and edges with black lines.
+func New() *Graph {
+	nodeStyle := canvas.DefaultStyle
+	nodeStyle.FillColor = canvas.White
+	nodeStyle.StrokeColor = canvas.Black
+	nodeStyle.StrokeWidth = 0.3
+
+	edgeStyle := canvas.DefaultStyle
+	edgeStyle.FillColor = canvas.Transparent
+	edgeStyle.StrokeColor = canvas.Black
+	edgeStyle.StrokeWidth = 0.3
+	edgeStyle.StrokeJoiner = canvas.RoundJoin
+
+	return &Graph{
+		Directed:     true,
+		FontSize:     8.0,
+		Padding:      2.0,
+		TextColor:    canvas.Black,
+		NodeStyle:    nodeStyle,
+		CornerRadius: 1.0,
+		EdgeStyle:    edgeStyle,
+		ArrowLength:  2.5,
+		ArrowWidth:   2.0,
+	}
+}
+
+// AddNode adds a node of 20 by 8 millimeters and returns its index.
+func (g *Graph) AddNode(label string) int {
+	g.Nodes = append(g.Nodes, &Node{Label: label, Width: 20.0, Height: 8.0})
+	return len(g.Nodes) - 1
+}
+
+// AddEdge adds an edge between the nodes with indices from and to.
+func (g *Graph) AddEdge(from, to int) *Edge {
+	e := &Edge{From: from, To: to}
+	g.Edges = append(g.Edges, e)
+	return e
+}
+
+func (g *Graph) face() (canvas.FontFace, bool) {
+	if g.Font == nil {
+		return canvas.FontFace{}, false
+	}
+	return g.Font.Face(g.FontSize, g.TextColor, canvas.FontRegular, canvas.FontNormal), true
+}
+
+// FitLabels sets the size of the nodes to fit their label plus padding on all sides. It does nothing when the font is not set.
+func (g *Graph) FitLabels() {
+	face, ok := g.face()
+	if !ok {
+		return
+	}
+	height := face.Metrics().LineHeight + 2.0*g.Padding
+	for _, node := range g.Nodes {
+		node.Width = face.TextWidth(node.Label) + 2.0*g.Padding
+		node.Height = height
+	}
+}
+
+// Bounds returns the bounding box of the nodes and bends.
+func (g *Graph) Bounds() canvas.Rect {
+	if len(g.Nodes) == 0 {
+		return canvas.Rect{}
+	}
+	xmin, ymin, xmax, ymax := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, node := range g.Nodes {
+		xmin, xmax = math.Min(xmin, node.X-node.Width/2.0), math.Max(xmax, node.X+node.Width/2.0)
+		ymin, ymax = math.Min(ymin, node.Y-node.Height/2.0), math.Max(ymax, node.Y+node.Height/2.0)
+	}
+	for _, e := range g.Edges {
+		for _, bend := range e.Bends {
+			xmin, xmax = math.Min(xmin, bend.X), math.Max(xmax, bend.X)
+			ymin, ymax = math.Min(ymin, bend.Y), math.Max(ymax, bend.Y)
+		}
+	}
+	return canvas.Rect{X: xmin, Y: ymin, W: xmax - xmin, H: ymax - ymin}
+}
+
+// translate moves the graph so that the lower-left corner of its bounds is at the origin.
+func (g *Graph) translate() {
+	bounds := g.Bounds()
+	for _, node := range g.Nodes {
+		node.X -= bounds.X
+		node.Y -= bounds.Y
+	}
+	for _, e := range g.Edges {
+		for i := range e.Bends {
+			e.Bends[i].X -= bounds.X
+			e.Bends[i].Y -= bounds.Y
+		}
+	}
+}
+
+// Draw draws the graph translated by (x,y). Both layouts place the lower-left corner of the bounds at the origin.
+func (g *Graph) Draw(ctx *canvas.Context, x, y float64) {
+	ctx.Push()
+	defer ctx.Pop()
+	ctx.Translate(x, y)
+
+	arrows := &canvas.Path{}
+	for _, e := range g.Edges {
+		path, arrow := g.EdgePath(e)
+		ctx.Style = g.EdgeStyle
+		ctx.DrawPath(0.0, 0.0, path)
+		arrows = arrows.Append(arrow)
+	}
+	if !arrows.Empty() {
+		ctx.Style = canvas.DefaultStyle
+		ctx.Style.FillColor = g.EdgeStyle.StrokeColor
+		ctx.DrawPath(0.0, 0.0, arrows)
+	}
+
+	ctx.Style = g.NodeStyle
+	for _, node := range g.Nodes {
+		ctx.DrawPath(node.X-node.Width/2.0, node.Y-node.Height/2.0, canvas.RoundedRectangle(node.Width, node.Height, g.CornerRadius))
+	}
+
+	face, ok := g.face()
+	if !ok {
+		return
+	}
+	xHeight := face.Metrics().XHeight
+	for _, node := range g.Nodes {
+		ctx.DrawText(node.X, node.Y-xHeight/2.0, canvas.NewTextLine(face, node.Label, canvas.Center))
+	}
+}
@@ -0,0 +1,62 @@
+package graph
+
+import (
+	"image"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+// recorder is a renderer that records the paths and texts that are drawn.
+type recorder struct {
+	paths []*canvas.Path
+	texts int
+}
+
+func (r *recorder) Size() (float64, float64) {
+	return 200.0, 200.0
+}
+
+func (r *recorder) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	r.paths = append(r.paths, path.Transform(m))
+}
+
+func (r *recorder) RenderText(text *canvas.Text, m canvas.Matrix) {
+	r.texts++
+}
+
+func (r *recorder) RenderImage(img image.Image, m canvas.Matrix) {}
+
+func TestGraph(t *testing.T) {
+	g := New()
+	a, b := g.AddNode("a"), g.AddNode("b")
+	g.AddEdge(a, b)
+	g.Nodes[b].X, g.Nodes[b].Y = 40.0, 0.0
+	test.T(t, g.Bounds(), canvas.Rect{X: -10.0, Y: -4.0, W: 60.0, H: 8.0})
+	g.translate()
+	test.T(t, g.Nodes[a].X, 10.0)
+	test.T(t, g.Nodes[a].Y, 4.0)
+
+	r := &recorder{}
+	g.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, len(r.paths), 4) // edge, arrowhead, nodes
+	test.T(t, r.paths[0], canvas.MustParseSVG("M20 4L37.5 4"))
+	test.T(t, r.paths[1], canvas.MustParseSVG("M40 4L37.5 5L37.5 3z"))
+	test.T(t, r.texts, 0) // no font
+
+	g.Directed = false
+	r = &recorder{}
+	g.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, len(r.paths), 3)
+
+	family := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+	g.Font = family
+	g.FitLabels()
+	test.That(t, g.Nodes[a].Width < 20.0, "label fits")
+	test.Float(t, g.Nodes[a].Height, g.Nodes[b].Height)
+	r = &recorder{}
+	g.Draw(canvas.NewContext(r), 0.0, 0.0)
+	test.T(t, r.texts, 2)
+}
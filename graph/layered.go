@@ -0,0 +1,317 @@
+package graph
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Direction is the direction in which the layers of a layered layout follow each other.
+type Direction int
+
+// see Direction
+const (
+	TopToBottom Direction = iota
+	LeftToRight
+)
+
+// LayeredLayout is a layered layout after Sugiyama for directed graphs, where edges point from one layer to the next. Cycles are broken by reversing edges, nodes are assigned to layers by the longest path from the sources, edges that span multiple layers bend at each layer in between, the order of the nodes within each layer minimizes the number of edge crossings using the barycenter heuristic, and nodes are centered with respect to the nodes they are connected to.
+type LayeredLayout struct {
+	Direction    Direction
+	LayerSpacing float64 // space between layers in millimeters
+	NodeSpacing  float64 // minimum space between nodes in a layer in millimeters, half of which is used for bends
+	Iterations   int     // number of sweeps of crossing minimization and node centering
+}
+
+// NewLayeredLayout returns a layered layout from top to bottom.
+func NewLayeredLayout() *LayeredLayout {
+	return &LayeredLayout{LayerSpacing: 15.0, NodeSpacing: 8.0, Iterations: 12}
+}
+
+// arc is an edge of the acyclic graph, reversed if the edge was part of a cycle.
+type arc struct {
+	from, to int
+	reversed bool
+}
+
+// vertex is a node or a bend of an edge in a layer.
+type vertex struct {
+	node         int // -1 for bends
+	layer        int
+	breadth      float64 // size along the layer
+	depth        float64 // size across the layer
+	pos          float64 // position along the layer
+	index        int     // index in the layer
+	above, below []int   // connected vertices in the previous and next layers
+}
+
+// Layout positions the nodes of the graph and sets the bends of edges that span multiple layers. It returns an error when an edge refers to a nonexistent node.
+func (l *LayeredLayout) Layout(g *Graph) error {
+	n := len(g.Nodes)
+	for i, e := range g.Edges {
+		if e.From < 0 || n <= e.From || e.To < 0 || n <= e.To {
+			return fmt.Errorf("edge %d refers to nonexistent node", i)
+		}
+	}
+
+	// break cycles by reversing the edges that point back to a node on the DFS stack
+	arcs := make([]arc, len(g.Edges))
+	outgoing := make([][]int, n)
+	for i, e := range g.Edges {
+		arcs[i] = arc{e.From, e.To, false}
+		if e.From != e.To {
+			outgoing[e.From] = append(outgoing[e.From], i)
+		}
+	}
+	state := make([]int, n) // 0 is unvisited, 1 is on the stack, 2 is done
+	var visit func(int)
+	visit = func(u int) {
+		state[u] = 1
+		for _, i := range outgoing[u] {
+			if v := arcs[i].to; state[v] == 1 {
+				arcs[i] = arc{v, u, true}
+			} else if state[v] == 0 {
+				visit(v)
+			}
+		}
+		state[u] = 2
+	}
+	for u := 0; u < n; u++ {
+		if state[u] == 0 {
+			visit(u)
+		}
+	}
+
+	// layers by longest path from the sources
+	layer := make([]int, n)
+	degree := make([]int, n)
+	successors := make([][]int, n)
+	for _, a := range arcs {
+		if a.from != a.to {
+			degree[a.to]++
+			successors[a.from] = append(successors[a.from], a.to)
+		}
+	}
+	queue := []int{}
+	for u := 0; u < n; u++ {
+		if degree[u] == 0 {
+			queue = append(queue, u)
+		}
+	}
+	for k := 0; k < len(queue); k++ {
+		u := queue[k]
+		for _, v := range successors[u] {
+			if layer[v] < layer[u]+1 {
+				layer[v] = layer[u] + 1
+			}
+			if degree[v]--; degree[v] == 0 {
+				queue = append(queue, v)
+			}
+		}
+	}
+
+	// vertices of the nodes and of the bends of edges that span multiple layers
+	vertices := make([]*vertex, n)
+	depth := 0
+	for i, node := range g.Nodes {
+		vertices[i] = &vertex{node: i, layer: layer[i], breadth: node.Width, depth: node.Height}
+		if l.Direction == LeftToRight {
+			vertices[i].breadth, vertices[i].depth = node.Height, node.Width
+		}
+		if depth < layer[i] {
+			depth = layer[i]
+		}
+	}
+	chains := make([][]int, len(arcs)) // vertices of the bends from the start to the end of each arc
+	for i, a := range arcs {
+		if a.from == a.to {
+			continue
+		}
+		prev := a.from
+		for k := layer[a.from] + 1; k < layer[a.to]; k++ {
+			vertices = append(vertices, &vertex{node: -1, layer: k})
+			chains[i] = append(chains[i], len(vertices)-1)
+			vertices[prev].below = append(vertices[prev].below, len(vertices)-1)
+			vertices[len(vertices)-1].above = append(vertices[len(vertices)-1].above, prev)
+			prev = len(vertices) - 1
+		}
+		vertices[prev].below = append(vertices[prev].below, a.to)
+		vertices[a.to].above = append(vertices[a.to].above, prev)
+	}
+	layers := make([][]int, depth+1)
+	for i, v := range vertices {
+		v.index = len(layers[v.layer])
+		layers[v.layer] = append(layers[v.layer], i)
+	}
+
+	// minimize crossings by ordering each layer by the barycenter of the connected vertices in the previous layer, sweeping down and up alternately, and keep the best order
+	best, bestCrossings := copyLayers(layers), crossings(vertices, layers)
+	for iter := 0; iter < l.Iterations && 0 < bestCrossings; iter++ {
+		down := iter%2 == 0
+		for k := 1; k < len(layers); k++ {
+			k := k
+			if !down {
+				k = len(layers) - 1 - k
+			}
+			barycenters := make(map[int]float64, len(layers[k]))
+			for _, i := range layers[k] {
+				neighbors := vertices[i].above
+				if !down {
+					neighbors = vertices[i].below
+				}
+				barycenters[i] = float64(vertices[i].index)
+				if 0 < len(neighbors) {
+					sum := 0.0
+					for _, j := range neighbors {
+						sum += float64(vertices[j].index)
+					}
+					barycenters[i] = sum / float64(len(neighbors))
+				}
+			}
+			sort.SliceStable(layers[k], func(a, b int) bool { return barycenters[layers[k][a]] < barycenters[layers[k][b]] })
+			for index, i := range layers[k] {
+				vertices[i].index = index
+			}
+		}
+		if c := crossings(vertices, layers); c < bestCrossings {
+			best, bestCrossings = copyLayers(layers), c
+		}
+	}
+	layers = best
+	for _, layer := range layers {
+		for index, i := range layer {
+			vertices[i].index = index
+		}
+	}
+
+	// positions along the layers, centered with respect to the connected vertices
+	gap := func(a, b *vertex) float64 {
+		spacing := l.NodeSpacing
+		if a.node == -1 || b.node == -1 {
+			spacing /= 2.0
+		}
+		return (a.breadth+b.breadth)/2.0 + spacing
+	}
+	for _, layer := range layers {
+		pos := 0.0
+		for k, i := range layer {
+			if 0 < k {
+				pos += gap(vertices[layer[k-1]], vertices[i])
+			}
+			vertices[i].pos = pos
+		}
+	}
+	place := func(layer []int, desired []float64) {
+		// the average of pushing overlapping vertices right and left keeps them apart and balanced
+		right := make([]float64, len(layer))
+		left := make([]float64, len(layer))
+		for k := range layer {
+			right[k] = desired[k]
+			if 0 < k {
+				right[k] = math.Max(right[k], right[k-1]+gap(vertices[layer[k-1]], vertices[layer[k]]))
+			}
+		}
+		for k := len(layer) - 1; 0 <= k; k-- {
+			left[k] = desired[k]
+			if k < len(layer)-1 {
+				left[k] = math.Min(left[k], left[k+1]-gap(vertices[layer[k]], vertices[layer[k+1]]))
+			}
+		}
+		for k, i := range layer {
+			vertices[i].pos = (right[k] + left[k]) / 2.0
+		}
+	}
+	for iter := 0; iter < l.Iterations; iter++ {
+		down := iter%2 == 0
+		for k := 0; k < len(layers); k++ {
+			k := k
+			if !down {
+				k = len(layers) - 1 - k
+			}
+			desired := make([]float64, len(layers[k]))
+			for index, i := range layers[k] {
+				neighbors := append(append([]int{}, vertices[i].above...), vertices[i].below...)
+				desired[index] = vertices[i].pos
+				if 0 < len(neighbors) {
+					sum := 0.0
+					for _, j := range neighbors {
+						sum += vertices[j].pos
+					}
+					desired[index] = sum / float64(len(neighbors))
+				}
+			}
+			place(layers[k], desired)
+		}
+	}
+
+	// positions across the layers
+	offsets := make([]float64, len(layers))
+	offset := 0.0
+	for k, layer := range layers {
+		size := 0.0
+		for _, i := range layer {
+			size = math.Max(size, vertices[i].depth)
+		}
+		offsets[k] = offset + size/2.0
+		offset += size + l.LayerSpacing
+	}
+	point := func(v *vertex) canvas.Point {
+		if l.Direction == LeftToRight {
+			return canvas.Point{X: offsets[v.layer], Y: -v.pos}
+		}
+		return canvas.Point{X: v.pos, Y: -offsets[v.layer]}
+	}
+
+	for i, node := range g.Nodes {
+		p := point(vertices[i])
+		node.X, node.Y = p.X, p.Y
+	}
+	for i, e := range g.Edges {
+		e.Bends = nil
+		for _, j := range chains[i] {
+			e.Bends = append(e.Bends, point(vertices[j]))
+		}
+		if arcs[i].reversed {
+			for a, b := 0, len(e.Bends)-1; a < b; a, b = a+1, b-1 {
+				e.Bends[a], e.Bends[b] = e.Bends[b], e.Bends[a]
+			}
+		}
+	}
+	g.axis = verticalAxis
+	if l.Direction == LeftToRight {
+		g.axis = horizontalAxis
+	}
+	g.translate()
+	return nil
+}
+
+func copyLayers(layers [][]int) [][]int {
+	c := make([][]int, len(layers))
+	for k, layer := range layers {
+		c[k] = append([]int{}, layer...)
+	}
+	return c
+}
+
+// crossings returns the number of crossings between the edges between consecutive layers.
+func crossings(vertices []*vertex, layers [][]int) int {
+	count := 0
+	for _, layer := range layers {
+		edges := [][2]int{}
+		for _, i := range layer {
+			for _, j := range vertices[i].below {
+				edges = append(edges, [2]int{vertices[i].index, vertices[j].index})
+			}
+		}
+		for a := 0; a < len(edges); a++ {
+			for b := a + 1; b < len(edges); b++ {
+				if (edges[a][0]-edges[b][0])*(edges[a][1]-edges[b][1]) < 0 {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,59 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestLayeredLayout(t *testing.T) {
+	g := New()
+	for _, label := range []string{"a", "b", "c", "d"} {
+		g.AddNode(label)
+	}
+	g.AddEdge(0, 1)
+	g.AddEdge(1, 2)
+	long := g.AddEdge(0, 2)
+	back := g.AddEdge(2, 0)
+	g.AddEdge(3, 3)
+	test.Error(t, NewLayeredLayout().Layout(g))
+
+	// layers from top to bottom
+	test.Float(t, g.Nodes[0].Y, g.Nodes[3].Y)
+	test.Float(t, g.Nodes[0].Y-g.Nodes[1].Y, 23.0)
+	test.Float(t, g.Nodes[1].Y-g.Nodes[2].Y, 23.0)
+	test.T(t, g.Bounds().X, 0.0)
+	test.T(t, g.Bounds().Y, 0.0)
+
+	// edges spanning two layers bend once in the middle layer, in the direction of the edge
+	test.T(t, len(long.Bends), 1)
+	test.T(t, len(back.Bends), 1)
+	test.Float(t, long.Bends[0].Y, g.Nodes[1].Y)
+	test.That(t, 8.0 <= long.Bends[0].Sub(canvas.Point{X: g.Nodes[1].X, Y: g.Nodes[1].Y}).Length(), "bend next to node")
+
+	g.Routing = Orthogonal
+	points := g.Route(g.Edges[0])
+	test.Float(t, points[0].X, points[1].X) // leaves vertically
+
+	test.Error(t, (&LayeredLayout{Direction: LeftToRight, LayerSpacing: 10.0, NodeSpacing: 5.0}).Layout(g))
+	test.Float(t, g.Nodes[1].X-g.Nodes[0].X, 30.0)
+	points = g.Route(g.Edges[0])
+	test.Float(t, points[0].Y, points[1].Y) // leaves horizontally
+
+	g.AddEdge(0, 5)
+	test.T(t, NewLayeredLayout().Layout(g) != nil, true)
+}
+
+func TestLayeredLayoutCrossings(t *testing.T) {
+	// a0 -> b1 and a1 -> b0 cross in the initial order
+	g := New()
+	for _, label := range []string{"a0", "a1", "b0", "b1"} {
+		g.AddNode(label)
+	}
+	g.AddEdge(0, 3)
+	g.AddEdge(1, 2)
+	test.Error(t, NewLayeredLayout().Layout(g))
+	test.That(t, (g.Nodes[0].X < g.Nodes[1].X) == (g.Nodes[3].X < g.Nodes[2].X), "no crossing")
+	test.Float(t, g.Nodes[0].X, g.Nodes[3].X)
+}
@@ -0,0 +1,108 @@
+package graph
+
+import (
+	"math"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Routing is the way edges are routed through their bends.
+type Routing int
+
+// see Routing
+const (
+	Straight   Routing = iota // straight lines
+	Orthogonal                // horizontal and vertical lines with elbows halfway between the bends, which leave and enter nodes along the direction of the layers for layered layouts
+	Spline                    // smooth cubic Béziers
+)
+
+// clip returns the point where the line from the center of the node towards p crosses the border of the node, or the center if p lies inside the node.
+func clip(node *Node, p canvas.Point) canvas.Point {
+	c := canvas.Point{X: node.X, Y: node.Y}
+	d := p.Sub(c)
+	t := math.Inf(1)
+	if d.X != 0.0 {
+		t = math.Min(t, node.Width/2.0/math.Abs(d.X))
+	}
+	if d.Y != 0.0 {
+		t = math.Min(t, node.Height/2.0/math.Abs(d.Y))
+	}
+	if 1.0 <= t {
+		return c
+	}
+	return c.Add(d.Mul(t))
+}
+
+// orthogonal inserts elbows between the points so that all segments are horizontal or vertical. Segments along the vertical axis get a horizontal part halfway, and vice versa, where for the free axis segments that are mostly vertical are along the vertical axis.
+func orthogonal(points []canvas.Point, axis axis) []canvas.Point {
+	q := []canvas.Point{points[0]}
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		if a.X != b.X && a.Y != b.Y {
+			if axis == verticalAxis || axis == freeAxis && math.Abs(b.X-a.X) <= math.Abs(b.Y-a.Y) {
+				m := (a.Y + b.Y) / 2.0
+				q = append(q, canvas.Point{X: a.X, Y: m}, canvas.Point{X: b.X, Y: m})
+			} else {
+				m := (a.X + b.X) / 2.0
+				q = append(q, canvas.Point{X: m, Y: a.Y}, canvas.Point{X: m, Y: b.Y})
+			}
+		}
+		q = append(q, b)
+	}
+	return q
+}
+
+// Route returns the points of the edge from the border of its source node through its bends to the border of its target node. Edges from a node to itself without bends loop around the upper-right corner of the node.
+func (g *Graph) Route(e *Edge) []canvas.Point {
+	from, to := g.Nodes[e.From], g.Nodes[e.To]
+	bends := e.Bends
+	if e.From == e.To && len(bends) == 0 {
+		s := math.Max(from.Height/2.0, 4.0)
+		bends = []canvas.Point{
+			{X: from.X + from.Width/2.0 + s, Y: from.Y},
+			{X: from.X + from.Width/2.0 + s, Y: from.Y + from.Height/2.0 + s},
+			{X: from.X, Y: from.Y + from.Height/2.0 + s},
+		}
+	}
+
+	points := make([]canvas.Point, 0, len(bends)+2)
+	points = append(points, canvas.Point{X: from.X, Y: from.Y})
+	points = append(points, bends...)
+	points = append(points, canvas.Point{X: to.X, Y: to.Y})
+	if g.Routing == Orthogonal {
+		points = orthogonal(points, g.axis)
+	}
+	points[0] = clip(from, points[1])
+	points[len(points)-1] = clip(to, points[len(points)-2])
+	return points
+}
+
+// EdgePath returns the path of the edge, routed through its bends, and its arrowhead, which is empty for undirected graphs. The path ends at the base of the arrowhead, whose tip touches the border of the target node.
+func (g *Graph) EdgePath(e *Edge) (*canvas.Path, *canvas.Path) {
+	points := g.Route(e)
+	arrow := &canvas.Path{}
+	if g.Directed {
+		n := len(points)
+		tip := points[n-1]
+		dir := tip.Sub(points[n-2])
+		if !dir.IsZero() {
+			dir = dir.Norm(1.0)
+			base := tip.Sub(dir.Mul(g.ArrowLength))
+			side := dir.Rot90CCW().Mul(g.ArrowWidth / 2.0)
+			arrow.MoveTo(tip.X, tip.Y)
+			arrow.LineTo(base.X+side.X, base.Y+side.Y)
+			arrow.LineTo(base.X-side.X, base.Y-side.Y)
+			arrow.Close()
+			points[n-1] = base
+		}
+	}
+
+	polyline := &canvas.Polyline{}
+	for _, p := range points {
+		polyline.Add(p.X, p.Y)
+	}
+	if g.Routing == Spline {
+		return polyline.Smoothen(), arrow
+	}
+	return polyline.ToPath(), arrow
+}
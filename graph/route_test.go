@@ -0,0 +1,49 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestClip(t *testing.T) {
+	node := &Node{Width: 20.0, Height: 10.0, X: 0.0, Y: 0.0}
+	test.T(t, clip(node, canvas.Point{X: 20.0, Y: 0.0}), canvas.Point{X: 10.0, Y: 0.0})
+	test.T(t, clip(node, canvas.Point{X: 20.0, Y: 20.0}), canvas.Point{X: 5.0, Y: 5.0})
+	test.T(t, clip(node, canvas.Point{X: 1.0, Y: 1.0}), canvas.Point{}) // inside
+}
+
+func TestOrthogonal(t *testing.T) {
+	points := []canvas.Point{{X: 0, Y: 0}, {X: 2, Y: 10}, {X: 2, Y: 12}, {X: 12, Y: 14}}
+	test.T(t, orthogonal(points, freeAxis), []canvas.Point{{X: 0, Y: 0}, {X: 0, Y: 5}, {X: 2, Y: 5}, {X: 2, Y: 10}, {X: 2, Y: 12}, {X: 7, Y: 12}, {X: 7, Y: 14}, {X: 12, Y: 14}})
+	test.T(t, orthogonal(points, verticalAxis)[5:], []canvas.Point{{X: 2, Y: 13}, {X: 12, Y: 13}, {X: 12, Y: 14}})
+	test.T(t, orthogonal(points, horizontalAxis)[1:3], []canvas.Point{{X: 1, Y: 0}, {X: 1, Y: 10}})
+}
+
+func TestRoute(t *testing.T) {
+	g := New()
+	a, b := g.AddNode("a"), g.AddNode("b")
+	e := g.AddEdge(a, b)
+	g.Nodes[b].X, g.Nodes[b].Y = 40.0, 40.0
+	e.Bends = []canvas.Point{{X: 40.0, Y: 0.0}}
+	test.T(t, g.Route(e), []canvas.Point{{X: 10.0, Y: 0.0}, {X: 40.0, Y: 0.0}, {X: 40.0, Y: 36.0}})
+
+	g.Routing = Orthogonal
+	e.Bends = nil
+	test.T(t, g.Route(e), []canvas.Point{{X: 0.0, Y: 4.0}, {X: 0.0, Y: 20.0}, {X: 40.0, Y: 20.0}, {X: 40.0, Y: 36.0}})
+
+	g.Routing = Spline
+	e.Bends = []canvas.Point{{X: 20.0, Y: 30.0}}
+	path, arrow := g.EdgePath(e)
+	test.T(t, path.StartPos(), g.Route(e)[0])
+	test.T(t, arrow.StartPos(), canvas.Point{X: 32.0, Y: 36.0})
+	test.Float(t, path.Pos().Sub(arrow.StartPos()).Length(), g.ArrowLength)
+
+	// self loop
+	loop := g.AddEdge(a, a)
+	points := g.Route(loop)
+	test.T(t, len(points), 5)
+	test.T(t, points[0], canvas.Point{X: 10.0, Y: 0.0})
+	test.T(t, points[4], canvas.Point{X: 0.0, Y: 4.0})
+}
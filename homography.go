@@ -0,0 +1,283 @@
+package canvas
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Homography is a projective transformation matrix, such as a perspective, that maps (x,y) to ((a*x + b*y + c)/w, (d*x + e*y + f)/w) with w = g*x + h*y + i. Lines remain lines, but parallel lines need not remain parallel. Points must be in front of the vanishing line where w is positive.
+type Homography [3][3]float64
+
+// IdentityHomography is the identity projective transformation.
+var IdentityHomography = Homography{
+	{1.0, 0.0, 0.0},
+	{0.0, 1.0, 0.0},
+	{0.0, 0.0, 1.0},
+}
+
+// Homography returns the affine transformation as a projective transformation.
+func (m Matrix) Homography() Homography {
+	return Homography{
+		{m[0][0], m[0][1], m[0][2]},
+		{m[1][0], m[1][1], m[1][2]},
+		{0.0, 0.0, 1.0},
+	}
+}
+
+// QuadHomography returns the projective transformation that maps the corners of the quadrilateral src to those of dst in the same order, such as a rectangle to the corners of a surface in a photograph. No three corners of either may be collinear.
+func QuadHomography(src, dst [4]Point) (Homography, error) {
+	a, err := unitSquareHomography(src)
+	if err != nil {
+		return Homography{}, err
+	}
+	b, err := unitSquareHomography(dst)
+	if err != nil {
+		return Homography{}, err
+	}
+	return b.Mul(a.Inv()), nil
+}
+
+// unitSquareHomography returns the projective transformation that maps the unit square (0,0), (1,0), (1,1), (0,1) to the quadrilateral, see P. Heckbert, Fundamentals of Texture Mapping and Image Warping, 1989, section 2.2.3.
+func unitSquareHomography(q [4]Point) (Homography, error) {
+	for i := 0; i < 4; i++ {
+		a, b, c := q[i], q[(i+1)%4], q[(i+2)%4]
+		if math.Abs(b.Sub(a).PerpDot(c.Sub(a))) < Epsilon {
+			return Homography{}, fmt.Errorf("quadrilateral has collinear corners")
+		}
+	}
+	d1, d2 := q[1].Sub(q[2]), q[3].Sub(q[2])
+	s := q[0].Sub(q[1]).Add(q[2]).Sub(q[3])
+	det := d1.PerpDot(d2)
+	g, h := s.PerpDot(d2)/det, d1.PerpDot(s)/det
+	return Homography{
+		{q[1].X - q[0].X + g*q[1].X, q[3].X - q[0].X + h*q[3].X, q[0].X},
+		{q[1].Y - q[0].Y + g*q[1].Y, q[3].Y - q[0].Y + h*q[3].Y, q[0].Y},
+		{g, h, 1.0},
+	}, nil
+}
+
+// Mul multiplies the current matrix by the given matrix (ie. combine transformations), where q is applied first.
+func (m Homography) Mul(q Homography) Homography {
+	r := Homography{}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			r[i][j] = m[i][0]*q[0][j] + m[i][1]*q[1][j] + m[i][2]*q[2][j]
+		}
+	}
+	return r
+}
+
+// Dot applies the transformation to the point.
+func (m Homography) Dot(p Point) Point {
+	w := m[2][0]*p.X + m[2][1]*p.Y + m[2][2]
+	return Point{
+		(m[0][0]*p.X + m[0][1]*p.Y + m[0][2]) / w,
+		(m[1][0]*p.X + m[1][1]*p.Y + m[1][2]) / w,
+	}
+}
+
+// Det returns the matrix determinant.
+func (m Homography) Det() float64 {
+	return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+}
+
+// Inv returns the matrix inverse.
+func (m Homography) Inv() Homography {
+	det := m.Det()
+	if equal(det, 0.0) {
+		panic("determinant of homography is zero")
+	}
+	r := Homography{}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			// cofactor of element (j,i)
+			a, b := (j+1)%3, (j+2)%3
+			c, d := (i+1)%3, (i+2)%3
+			r[i][j] = (m[a][c]*m[b][d] - m[a][d]*m[b][c]) / det
+		}
+	}
+	return r
+}
+
+// Affine returns the transformation as an affine transformation and true if it is affine.
+func (m Homography) Affine() (Matrix, bool) {
+	if !equal(m[2][0], 0.0) || !equal(m[2][1], 0.0) || equal(m[2][2], 0.0) {
+		return Matrix{}, false
+	}
+	return Matrix{
+		{m[0][0] / m[2][2], m[0][1] / m[2][2], m[0][2] / m[2][2]},
+		{m[1][0] / m[2][2], m[1][1] / m[2][2], m[1][2] / m[2][2]},
+	}, true
+}
+
+func (m Homography) String() string {
+	return fmt.Sprintf("[%g %g %g; %g %g %g; %g %g %g]", m[0][0], m[0][1], m[0][2], m[1][0], m[1][1], m[1][2], m[2][0], m[2][1], m[2][2])
+}
+
+// Project applies the projective transformation to the path and returns a new path. Lines are mapped exactly, while curves are converted to cubic Béziers that are subdivided until they deviate less than Tolerance from the projected curve. Affine transformations are applied exactly by Transform.
+func (p *Path) Project(m Homography) *Path {
+	if affine, ok := m.Affine(); ok {
+		return p.Transform(affine)
+	}
+
+	p = p.toCubes()
+	q := &Path{}
+	var start Point
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		end := m.Dot(Point{p.d[i+cmdLen(cmd)-3], p.d[i+cmdLen(cmd)-2]})
+		switch cmd {
+		case moveToCmd:
+			q.MoveTo(end.X, end.Y)
+		case lineToCmd:
+			q.LineTo(end.X, end.Y)
+		case cubeToCmd:
+			cp1, cp2 := Point{p.d[i+1], p.d[i+2]}, Point{p.d[i+3], p.d[i+4]}
+			mapCubicBezier(q, m.Dot, start, cp1, cp2, Point{p.d[i+5], p.d[i+6]}, 0)
+		case closeCmd:
+			q.Close()
+		}
+		start = Point{p.d[i+cmdLen(cmd)-3], p.d[i+cmdLen(cmd)-2]}
+		i += cmdLen(cmd)
+	}
+	return q
+}
+
+// toCubes converts quadratic Béziers and arcs to cubic Béziers.
+func (p *Path) toCubes() *Path {
+	quad := func(p0, p1, p2 Point) *Path {
+		cp1, cp2 := quadraticToCubicBezier(p0, p1, p2)
+		q := &Path{}
+		q.MoveTo(p0.X, p0.Y)
+		q.CubeTo(cp1.X, cp1.Y, cp2.X, cp2.Y, p2.X, p2.Y)
+		return q
+	}
+	return p.replace(nil, quad, nil, arcToCube)
+}
+
+// mapMaxDepth is the maximum number of times a curve is subdivided when it is mapped by a nonlinear transformation.
+const mapMaxDepth = 16
+
+// mapCubicBezier appends the cubic Bézier mapped by f to q, approximated by cubic Béziers through the mapped control points, which are subdivided when the approximation is not within Tolerance.
+func mapCubicBezier(q *Path, f func(Point) Point, p0, p1, p2, p3 Point, depth int) {
+	q0, q1, q2, q3 := f(p0), f(p1), f(p2), f(p3)
+	if depth < mapMaxDepth {
+		for _, t := range []float64{0.25, 0.5, 0.75} {
+			if Tolerance < f(cubicBezierPos(p0, p1, p2, p3, t)).Sub(cubicBezierPos(q0, q1, q2, q3, t)).Length() {
+				a0, a1, a2, a3, b0, b1, b2, b3 := cubicBezierSplit(p0, p1, p2, p3, 0.5)
+				mapCubicBezier(q, f, a0, a1, a2, a3, depth+1)
+				mapCubicBezier(q, f, b0, b1, b2, b3, depth+1)
+				return
+			}
+		}
+	}
+	q.CubeTo(q1.X, q1.Y, q2.X, q2.Y, q3.X, q3.Y)
+}
+
+// Project returns a new canvas of the same size that draws the canvas with the projective transformation applied, such as to place a drawing onto a surface in a photograph, see QuadHomography. Strokes are converted to filled outlines and texts to paths, so that their width is foreshortened by the perspective, see Path.Project. Images are resampled bilinearly at about their own resolution.
+func (c *Canvas) Project(m Homography) *Canvas {
+	c.merge()
+	p := New(c.W, c.H)
+	for _, l := range c.layers {
+		lm := m.Mul(l.m.Homography())
+		if l.path != nil {
+			// strokes are in canvas coordinates
+			path, style := l.path.Transform(l.m), l.style
+			if style.FillColor.A != 0 {
+				fill := style
+				fill.StrokeColor = Transparent
+				p.RenderPath(path.Project(m), fill, Identity)
+			}
+			if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth {
+				stroke := path
+				if 0 < len(style.Dashes) {
+					stroke = stroke.Dash(style.DashOffset, style.Dashes...)
+				}
+				stroke = stroke.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
+				fill := DefaultStyle
+				fill.FillColor = style.StrokeColor
+				p.RenderPath(stroke.Project(m), fill, Identity)
+			}
+		} else if l.text != nil {
+			paths, colors := l.text.ToPaths()
+			for i, path := range paths {
+				style := DefaultStyle
+				style.FillColor = colors[i]
+				p.RenderPath(path.Project(lm), style, Identity)
+			}
+		} else if l.img != nil {
+			if img, im := projectImage(l.img, lm); img != nil {
+				p.RenderImage(img, im)
+			}
+		}
+	}
+	return p
+}
+
+// projectImage returns the image with the projective transformation applied, which maps the pixel coordinates of the image with the origin at its bottom-left to canvas coordinates, and the transformation of the returned image to canvas coordinates.
+func projectImage(img image.Image, m Homography) (*image.RGBA, Matrix) {
+	bounds := img.Bounds()
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	corners := [4]Point{m.Dot(Point{0.0, 0.0}), m.Dot(Point{w, 0.0}), m.Dot(Point{w, h}), m.Dot(Point{0.0, h})}
+	x0, y0, x1, y1 := corners[0].X, corners[0].Y, corners[0].X, corners[0].Y
+	area := 0.0
+	for i, corner := range corners {
+		x0, y0 = math.Min(x0, corner.X), math.Min(y0, corner.Y)
+		x1, y1 = math.Max(x1, corner.X), math.Max(y1, corner.Y)
+		area += corners[i].PerpDot(corners[(i+1)%4]) / 2.0
+	}
+	rect := Rect{x0, y0, x1 - x0, y1 - y0}
+	if math.Abs(area) < Epsilon {
+		return nil, Identity
+	}
+
+	// the resolution keeps the number of pixels of the image, of which there are at most four times as many in the bounding box
+	dpm := math.Sqrt(w * h / math.Abs(area))
+	cols, rows := int(math.Ceil(rect.W*dpm)), int(math.Ceil(rect.H*dpm))
+	if 4.0*w*h < float64(cols)*float64(rows) {
+		dpm *= math.Sqrt(4.0 * w * h / (float64(cols) * float64(rows)))
+		cols, rows = int(math.Ceil(rect.W*dpm)), int(math.Ceil(rect.H*dpm))
+	}
+
+	inv := m.Inv()
+	dst := image.NewRGBA(image.Rect(0, 0, cols, rows))
+	at := func(x, y int) color.RGBA64 {
+		if x < 0 || y < 0 || bounds.Dx() <= x || bounds.Dy() <= y {
+			return color.RGBA64{}
+		}
+		r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+		return color.RGBA64{uint16(r), uint16(g), uint16(b), uint16(a)}
+	}
+	for j := 0; j < rows; j++ {
+		for i := 0; i < cols; i++ {
+			pos := Point{rect.X + (float64(i)+0.5)/dpm, rect.Y + rect.H - (float64(j)+0.5)/dpm}
+			if inv[2][0]*pos.X+inv[2][1]*pos.Y+inv[2][2] <= 0.0 {
+				continue // beyond the vanishing line
+			}
+			src := inv.Dot(pos)
+			u, v := src.X-0.5, h-src.Y-0.5 // pixel coordinates from the top-left of pixel centers
+			x, y := int(math.Floor(u)), int(math.Floor(v))
+			if x < -1 || y < -1 || bounds.Dx() <= x || bounds.Dy() <= y {
+				continue
+			}
+			fx, fy := u-float64(x), v-float64(y)
+			c00, c10, c01, c11 := at(x, y), at(x+1, y), at(x, y+1), at(x+1, y+1)
+			lerp := func(a, b, c, d uint16) uint8 {
+				top := (1.0-fx)*float64(a) + fx*float64(b)
+				bottom := (1.0-fx)*float64(c) + fx*float64(d)
+				return uint8(((1.0-fy)*top + fy*bottom) / 257.0)
+			}
+			dst.SetRGBA(i, j, color.RGBA{
+				lerp(c00.R, c10.R, c01.R, c11.R),
+				lerp(c00.G, c10.G, c01.G, c11.G),
+				lerp(c00.B, c10.B, c01.B, c11.B),
+				lerp(c00.A, c10.A, c01.A, c11.A),
+			})
+		}
+	}
+	return dst, Identity.Translate(rect.X, rect.Y).Scale(1.0/dpm, 1.0/dpm)
+}
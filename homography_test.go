@@ -0,0 +1,75 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestHomography(t *testing.T) {
+	src := [4]Point{{0.0, 0.0}, {10.0, 0.0}, {10.0, 10.0}, {0.0, 10.0}}
+	dst := [4]Point{{2.0, 1.0}, {18.0, 3.0}, {14.0, 12.0}, {5.0, 9.0}}
+	m, err := QuadHomography(src, dst)
+	test.Error(t, err)
+	for i := range src {
+		test.T(t, m.Dot(src[i]), dst[i])
+		test.T(t, m.Inv().Dot(dst[i]), src[i])
+	}
+	test.T(t, m.Mul(m.Inv()).Dot(Point{3.0, 4.0}), Point{3.0, 4.0})
+	_, ok := m.Affine()
+	test.That(t, !ok, "perspective is not affine")
+
+	affine := Identity.Translate(1.0, 2.0).Rotate(30.0).Scale(2.0, 3.0)
+	q, ok := affine.Homography().Affine()
+	test.That(t, ok, "affine")
+	test.T(t, q, affine)
+	test.T(t, Circle(2.0).Project(affine.Homography()), Circle(2.0).Transform(affine))
+
+	_, err = QuadHomography(src, [4]Point{{0.0, 0.0}, {1.0, 1.0}, {2.0, 2.0}, {0.0, 1.0}})
+	test.That(t, err != nil, "collinear corners")
+}
+
+func TestPathProject(t *testing.T) {
+	defer func(tolerance float64) { Tolerance = tolerance }(Tolerance)
+	Tolerance = 0.01
+
+	m, _ := QuadHomography([4]Point{{0.0, 0.0}, {10.0, 0.0}, {10.0, 10.0}, {0.0, 10.0}}, [4]Point{{0.0, 0.0}, {20.0, 2.0}, {16.0, 12.0}, {4.0, 10.0}})
+
+	// lines are mapped exactly
+	p := MustParseSVG("M0 0L10 0L10 10z")
+	test.T(t, p.Project(m), MustParseSVG("M0 0L20 2L16 12z"))
+
+	// points on the projected circle map back onto the circle
+	p = Circle(3.0).Translate(5.0, 5.0).Project(m)
+	for _, pos := range p.Flatten().Coords() {
+		test.That(t, math.Abs(m.Inv().Dot(pos).Sub(Point{5.0, 5.0}).Length()-3.0) < 2.0*Tolerance, pos)
+	}
+	test.That(t, 4 < len(p.Coords()), "curves are subdivided")
+}
+
+func TestCanvasProject(t *testing.T) {
+	c := New(20.0, 20.0)
+	ctx := NewContext(c)
+	ctx.SetFillColor(Red)
+	ctx.SetStrokeColor(Blue)
+	ctx.SetStrokeWidth(1.0)
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	for i := range img.Pix {
+		img.Pix[i] = []uint8{0x00, 0xff, 0x00, 0xff}[i%4]
+	}
+	ctx.DrawImage(12.0, 0.0, img, 10.0)
+
+	m, _ := QuadHomography([4]Point{{0.0, 0.0}, {20.0, 0.0}, {20.0, 20.0}, {0.0, 20.0}}, [4]Point{{0.0, 0.0}, {20.0, 0.0}, {15.0, 10.0}, {5.0, 10.0}})
+	p := c.Project(m)
+	test.T(t, len(p.layers), 3) // fill, stroke outline, and image
+	test.T(t, p.layers[1].style.FillColor, Blue)
+
+	out := p.WriteImage(1.0)
+	test.T(t, out.RGBAAt(5, 18), color.RGBA{255, 0, 0, 255})    // fill near the bottom
+	test.T(t, out.RGBAAt(5, 3), color.RGBA{255, 255, 255, 255}) // above the horizon of the square
+	test.T(t, out.RGBAAt(14, 18), color.RGBA{0, 255, 0, 255})   // the image near the bottom
+}
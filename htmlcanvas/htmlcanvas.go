@@ -163,6 +163,9 @@ func jsAwait(v js.Value) (result js.Value, ok bool) {
 }
 
 func (r *htmlCanvas) RenderImage(img image.Image, m canvas.Matrix) {
+	if jpg, ok := img.(*canvas.JPEGImage); ok {
+		img = jpg.SRGB()
+	}
 	size := img.Bounds().Size()
 	buf := make([]byte, 4*size.X*size.Y)
 	for y := 0; y < size.Y; y++ {
@@ -0,0 +1,243 @@
+// Package httpcanvas provides an HTTP handler that renders drawings server-side, such as charts, to any of the output formats of canvas.
+package httpcanvas
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Format is an output format with its media type.
+type Format struct {
+	Name      string // name used in the format query parameter
+	MediaType string
+}
+
+// Formats are the output formats in order of preference, of which the first is the default.
+var Formats = []Format{
+	{"png", "image/png"},
+	{"svg", "image/svg+xml"},
+	{"pdf", "application/pdf"},
+	{"jpg", "image/jpeg"},
+	{"eps", "application/postscript"},
+}
+
+// Handler renders the drawing in the body of POST requests, which is an SVG document or a JSON display list as encoded by Canvas.MarshalJSON, determined by the Content-Type header. The output format is taken from the format query parameter, or negotiated from the Accept header, and raster formats are rendered at the resolution of the dpi query parameter. Responses have an ETag of the request and the output format so that clients and proxies can cache them, and requests with a matching If-None-Match header are answered with 304 Not Modified. Drawings that exceed the limits are answered with 413 Request Entity Too Large.
+type Handler struct {
+	MaxBodySize int64                // maximum size of the request body in bytes
+	Limits      canvas.Limits        // resource limits on reading the drawing and on rendering raster output
+	Fonts       *canvas.FontRegistry // fonts of the text elements of SVG documents, which are not drawn when nil
+	DPI         float64              // default resolution of raster output in dots per inch
+	MaxAge      time.Duration        // duration for which responses may be cached, they are not cached when zero
+}
+
+// NewHandler returns a handler that accepts drawings up to 10MB within canvas.SafeLimits.
+func NewHandler() *Handler {
+	return &Handler{
+		MaxBodySize: 10 << 20,
+		Limits:      canvas.SafeLimits,
+		DPI:         96.0,
+		MaxAge:      time.Hour,
+	}
+}
+
+// ServeHTTP renders the drawing of the request.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format, ok := Format{}, false
+	if name := r.URL.Query().Get("format"); name != "" {
+		for _, f := range Formats {
+			if f.Name == name || name == "jpeg" && f.Name == "jpg" {
+				format, ok = f, true
+			}
+		}
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown format '%s'", name), http.StatusBadRequest)
+			return
+		}
+	} else if format, ok = negotiate(r.Header.Get("Accept")); !ok {
+		http.Error(w, "no acceptable format", http.StatusNotAcceptable)
+		return
+	}
+	dpi := h.DPI
+	if s := r.URL.Query().Get("dpi"); s != "" {
+		var err error
+		if dpi, err = strconv.ParseFloat(s, 64); err != nil || dpi <= 0.0 {
+			http.Error(w, fmt.Sprintf("bad dpi '%s'", s), http.StatusBadRequest)
+			return
+		}
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, h.MaxBodySize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if h.MaxBodySize < int64(len(body)) {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%v\x00%s\x00", format.Name, dpi, r.Header.Get("Content-Type"))
+	hash.Write(body)
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	header := w.Header()
+	header.Set("ETag", etag)
+	header.Set("Vary", "Accept")
+	if 0 < h.MaxAge {
+		header.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(h.MaxAge/time.Second)))
+	} else {
+		header.Set("Cache-Control", "no-store")
+	}
+	if matchETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	c, err := decode(r.Header.Get("Content-Type"), body, h.Limits, h.Fonts)
+	var img *image.RGBA
+	if err == nil && (format.Name == "png" || format.Name == "jpg") {
+		img, err = c.WriteImageWithLimits(dpi/25.4, h.Limits)
+	}
+	if err != nil {
+		code := http.StatusBadRequest
+		if errors.Is(err, canvas.ErrLimitExceeded) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		fail(w, err, code)
+		return
+	}
+
+	// render the complete output before writing the response, so that failures are not sent as cacheable responses
+	buf := &bytes.Buffer{}
+	if err := write(buf, c, img, format.Name); err != nil {
+		fail(w, err, http.StatusInternalServerError)
+		return
+	}
+	header.Set("Content-Type", format.MediaType)
+	header.Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// fail answers with an error, which must not be cached.
+func fail(w http.ResponseWriter, err error, code int) {
+	w.Header().Del("ETag")
+	w.Header().Del("Cache-Control")
+	http.Error(w, err.Error(), code)
+}
+
+// decode reads the drawing of the request body, which is SVG or JSON.
+func decode(contentType string, body []byte, limits canvas.Limits, fonts *canvas.FontRegistry) (*canvas.Canvas, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil && contentType != "" {
+		return nil, err
+	}
+	switch {
+	case mediaType == "image/svg+xml":
+		return canvas.ReadSVGWithOptions(bytes.NewReader(body), canvas.SVGOptions{Limits: limits, Fonts: fonts})
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		c := &canvas.Canvas{}
+		if err := c.UnmarshalJSONWithLimits(body, limits); err != nil {
+			return nil, err
+		}
+		return c, nil
+	case contentType == "":
+		// sniff the content when no type is given
+		if trimmed := bytes.TrimSpace(body); 0 < len(trimmed) && trimmed[0] == '{' {
+			return decode("application/json", body, limits, fonts)
+		}
+		return decode("image/svg+xml", body, limits, fonts)
+	}
+	return nil, fmt.Errorf("unsupported content type '%s'", mediaType)
+}
+
+// write writes the canvas to w in the format, where img is the canvas rasterized for raster formats.
+func write(w io.Writer, c *canvas.Canvas, img image.Image, format string) error {
+	switch format {
+	case "pdf":
+		pdf := canvas.NewPDF(w, c.W, c.H)
+		c.Render(pdf)
+		return pdf.Close()
+	case "svg":
+		svg := canvas.NewSVG(w, c.W, c.H)
+		c.Render(svg)
+		return svg.Close()
+	case "eps":
+		c.Render(canvas.NewEPS(w, c.W, c.H))
+		return nil // EPS does not report errors, but it is written to a buffer that cannot fail
+	case "png":
+		return png.Encode(w, img)
+	case "jpg":
+		return jpeg.Encode(w, img, nil)
+	}
+	return fmt.Errorf("unknown format '%s'", format)
+}
+
+// negotiate returns the format with the highest quality in the Accept header, preferring formats that come first in Formats. It returns the default format when the header is empty.
+func negotiate(accept string) (Format, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return Formats[0], true
+	}
+
+	best, bestQ := Format{}, 0.0
+	for _, format := range Formats {
+		// the quality of the most specific media range that matches
+		q, specificity := 0.0, -1
+		for _, part := range strings.Split(accept, ",") {
+			mediaRange, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+			s := -1
+			if mediaRange == format.MediaType {
+				s = 2
+			} else if mediaRange == "*/*" {
+				s = 0
+			} else if strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(format.MediaType, mediaRange[:len(mediaRange)-1]) {
+				s = 1
+			}
+			if specificity < s {
+				q, specificity = 1.0, s
+				if v, ok := params["q"]; ok {
+					if q, err = strconv.ParseFloat(v, 64); err != nil {
+						q = 0.0
+					}
+				}
+			}
+		}
+		if bestQ < q {
+			best, bestQ = format, q
+		}
+	}
+	return best, 0.0 < bestQ
+}
+
+// matchETag returns true if the If-None-Match header matches the ETag.
+func matchETag(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		if tag = strings.TrimSpace(tag); tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
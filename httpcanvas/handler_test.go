@@ -0,0 +1,105 @@
+package httpcanvas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg" width="96" height="48"><rect width="48" height="48" fill="red"/></svg>`
+
+func serve(h http.Handler, target, contentType, body string, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	h := NewHandler()
+	w := serve(h, "/", "image/svg+xml", testSVG)
+	test.T(t, w.Code, http.StatusOK)
+	test.T(t, w.Header().Get("Content-Type"), "image/png")
+	test.T(t, w.Header().Get("Cache-Control"), "public, max-age=3600")
+	test.That(t, strings.HasPrefix(w.Body.String(), "\x89PNG"))
+
+	etag := w.Header().Get("ETag")
+	w = serve(h, "/", "image/svg+xml", testSVG, "If-None-Match", etag)
+	test.T(t, w.Code, http.StatusNotModified)
+	test.T(t, w.Body.Len(), 0)
+
+	w = serve(h, "/?format=svg", "image/svg+xml", testSVG)
+	test.T(t, w.Header().Get("Content-Type"), "image/svg+xml")
+	test.That(t, w.Header().Get("ETag") != etag, "ETag depends on the format")
+	test.That(t, strings.Contains(w.Body.String(), `<path d="M0 0H12.7V12.7H0z" fill="#f00"/>`), w.Body.String())
+
+	w = serve(h, "/", "application/json", `{"width":10,"height":10,"layers":[{"path":[["M",0,0],["L",10,0],["L",0,10],["Z"]]}]}`, "Accept", "application/pdf")
+	test.T(t, w.Code, http.StatusOK)
+	test.T(t, w.Header().Get("Content-Type"), "application/pdf")
+	test.That(t, strings.HasPrefix(w.Body.String(), "%PDF"))
+
+	w = serve(h, "/", "", `{"width":10,"height":10,"layers":[]}`, "Accept", "image/svg+xml")
+	test.T(t, w.Code, http.StatusOK)
+}
+
+func TestHandlerErrors(t *testing.T) {
+	h := NewHandler()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	test.T(t, w.Code, http.StatusMethodNotAllowed)
+
+	test.T(t, serve(h, "/?format=webp", "image/svg+xml", testSVG).Code, http.StatusBadRequest)
+	test.T(t, serve(h, "/?dpi=-1", "image/svg+xml", testSVG).Code, http.StatusBadRequest)
+	test.T(t, serve(h, "/", "image/svg+xml", testSVG, "Accept", "text/html").Code, http.StatusNotAcceptable)
+	test.T(t, serve(h, "/", "text/plain", testSVG).Code, http.StatusBadRequest)
+	test.T(t, serve(h, "/", "application/json", `{"layers":[{}]}`).Code, http.StatusBadRequest)
+
+	test.T(t, serve(h, "/", "application/json", `{"width":1e300,"height":0,"layers":[]}`).Code, http.StatusRequestEntityTooLarge)
+	test.T(t, serve(h, "/", "image/svg+xml", `<svg xmlns="http://www.w3.org/2000/svg" width="1e300" height="0"/>`).Code, http.StatusRequestEntityTooLarge)
+
+	// failures to encode are not cached
+	w = serve(h, "/?format=png", "application/json", `{"width":10,"height":1e-10,"layers":[]}`)
+	test.T(t, w.Code, http.StatusInternalServerError)
+	test.T(t, w.Header().Get("ETag"), "")
+	test.T(t, w.Header().Get("Cache-Control"), "")
+
+	h.Limits.MaxPixels = 1000
+	test.T(t, serve(h, "/", "image/svg+xml", testSVG).Code, http.StatusRequestEntityTooLarge)
+	test.T(t, serve(h, "/?format=svg", "image/svg+xml", testSVG).Code, http.StatusOK)
+	h.Limits.MaxSegments = 3
+	test.T(t, serve(h, "/?format=svg", "image/svg+xml", testSVG).Code, http.StatusRequestEntityTooLarge)
+	test.T(t, serve(h, "/?format=svg", "application/json", `{"width":10,"height":10,"layers":[{"path":[["M",0,0],["L",10,0],["L",0,10],["L",10,10],["Z"]]}]}`).Code, http.StatusRequestEntityTooLarge)
+	h.MaxBodySize = 10
+	test.T(t, serve(h, "/?format=svg", "image/svg+xml", testSVG).Code, http.StatusRequestEntityTooLarge)
+}
+
+func TestNegotiate(t *testing.T) {
+	var tts = []struct {
+		accept string
+		format string
+	}{
+		{"", "png"},
+		{"*/*", "png"},
+		{"application/pdf", "pdf"},
+		{"image/*;q=0.5, image/svg+xml", "svg"},
+		{"image/*, image/png;q=0", "svg"},
+		{"text/html, */*;q=0.1", "png"},
+		{"text/html", ""},
+	}
+	for _, tt := range tts {
+		t.Run(tt.accept, func(t *testing.T) {
+			format, _ := negotiate(tt.accept)
+			test.T(t, format.Name, tt.format)
+		})
+	}
+}
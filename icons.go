@@ -0,0 +1,97 @@
+package canvas
+
+import (
+	"sort"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+)
+
+// Icons are the named glyphs of an icon font, such as Font Awesome or Material Icons, whose outlines can be drawn at any size, eg. as markers of Path.Markers.
+type Icons struct {
+	font  *Font
+	names map[string]sfnt.GlyphIndex
+}
+
+// NewIcons returns the icons of a font, which are named by the glyph names of its post table, and by the words that form ligatures of the glyphs as Material Icons does. Icons that are only known by their code point are named by SetNames.
+func NewIcons(font *Font) *Icons {
+	icons := &Icons{
+		font:  font,
+		names: map[string]sfnt.GlyphIndex{},
+	}
+	buffer := &sfnt.Buffer{}
+	for i := 1; i < font.sfnt.NumGlyphs(); i++ {
+		if name, err := font.sfnt.GlyphName(buffer, sfnt.GlyphIndex(i)); err == nil && name != "" {
+			icons.names[name] = sfnt.GlyphIndex(i)
+		}
+	}
+	for _, tag := range []string{"liga", "rlig"} {
+		for _, ligature := range font.features[tag] {
+			if isIconName(ligature.src) {
+				if index, err := font.glyphIndex(buffer, ligature.dst); err == nil && index != 0 {
+					icons.names[ligature.src] = index
+				}
+			}
+		}
+	}
+	return icons
+}
+
+// isIconName returns true for words of lowercase letters, digits, underscores, and hyphens.
+func isIconName(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || 'z' < r) && (r < '0' || '9' < r) && r != '_' && r != '-' {
+			return false
+		}
+	}
+	return 1 < len(s)
+}
+
+// SetNames names icons by their code point, such as those of the stylesheets or metadata that come with Font Awesome, and overrides the names of the font. Code points that are not in the font are skipped.
+func (icons *Icons) SetNames(names map[string]rune) {
+	buffer := &sfnt.Buffer{}
+	for name, r := range names {
+		if index, err := icons.font.sfnt.GlyphIndex(buffer, r); err == nil && index != 0 {
+			icons.names[name] = index
+		}
+	}
+}
+
+// Names returns the sorted names of the icons.
+func (icons *Icons) Names() []string {
+	names := make([]string, 0, len(icons.names))
+	for name := range icons.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Path returns the outline of an icon at the font size in points, with the origin at the start of its base line as for FontFace.ToPath, and its advance in mm. It returns false if there is no icon by that name.
+func (icons *Icons) Path(name string, size float64) (*Path, float64, bool) {
+	index, ok := icons.names[name]
+	if !ok {
+		return nil, 0.0, false
+	}
+	ff := FontFace{font: icons.font, size: size * mmPerPt, scale: 1.0}
+	buffer := &sfnt.Buffer{}
+	p := &Path{}
+	if err := ff.appendGlyph(p, buffer, index, 0.0, Identity); err != nil {
+		return nil, 0.0, false
+	}
+	advance, err := icons.font.sfnt.GlyphAdvance(buffer, index, toI26_6(ff.size), font.HintingNone)
+	if err != nil {
+		return nil, 0.0, false
+	}
+	return p, fromI26_6(advance), true
+}
+
+// Marker returns the outline of an icon whose em square is size mm wide, centered at the origin by its bounding box, for use as a marker. It returns false if there is no icon by that name.
+func (icons *Icons) Marker(name string, size float64) (*Path, bool) {
+	p, _, ok := icons.Path(name, size*ptPerMm)
+	if !ok {
+		return nil, false
+	}
+	bounds := p.Bounds()
+	return p.Translate(-bounds.X-bounds.W/2.0, -bounds.Y-bounds.H/2.0), true
+}
@@ -0,0 +1,47 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestIcons(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	if err := family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular); err != nil {
+		test.Error(t, err)
+		return
+	}
+	face := family.Face(12.0, Black, FontRegular, FontNormal)
+	icons := NewIcons(face.font)
+
+	p, advance, ok := icons.Path("A", 12.0) // by the post table
+	test.That(t, ok)
+	q, advanceA := face.ToPath("A")
+	test.That(t, p.Equals(q))
+	test.Float(t, advance, advanceA)
+
+	_, _, ok = icons.Path("fi", 12.0) // by the glyph name or the ligature
+	test.That(t, ok)
+	_, _, ok = icons.Path("missing", 12.0)
+	test.That(t, !ok)
+
+	icons.SetNames(map[string]rune{"copyright": '©', "private": '\uE000'})
+	p, _, ok = icons.Path("copyright", 12.0)
+	test.That(t, ok)
+	q, _ = face.ToPath("©")
+	test.That(t, p.Equals(q))
+	_, _, ok = icons.Path("private", 12.0)
+	test.That(t, !ok, "not in the font")
+
+	names := icons.Names()
+	test.That(t, 100 < len(names))
+	test.That(t, names[0] < names[1], "sorted")
+
+	marker, ok := icons.Marker("copyright", 4.0)
+	test.That(t, ok)
+	bounds := marker.Bounds()
+	test.Float(t, bounds.X+bounds.W/2.0, 0.0)
+	test.Float(t, bounds.Y+bounds.H/2.0, 0.0)
+	test.That(t, bounds.W < 4.0 && 2.0 < bounds.W, "em square of 4mm")
+}
@@ -0,0 +1,391 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"sync"
+
+	"golang.org/x/image/draw"
+)
+
+// Resampling defines the interpolation used to scale and rotate images. NearestNeighbor is fastest and keeps pixels sharp, Bilinear is smooth, CatmullRom is sharper, and Lanczos keeps most detail when downscaling but is slowest.
+type Resampling int
+
+// see Resampling
+const (
+	CatmullRom Resampling = iota
+	NearestNeighbor
+	Bilinear
+	Lanczos
+)
+
+// lanczos is the Lanczos kernel with a support of three pixels.
+var lanczos = &draw.Kernel{Support: 3.0, At: func(t float64) float64 {
+	if t == 0.0 {
+		return 1.0
+	}
+	x := math.Pi * t
+	return 3.0 * math.Sin(x) * math.Sin(x/3.0) / (x * x)
+}}
+
+func (resampling Resampling) interpolator() draw.Interpolator {
+	switch resampling {
+	case NearestNeighbor:
+		return draw.NearestNeighbor
+	case Bilinear:
+		return draw.BiLinear
+	case Lanczos:
+		return lanczos
+	}
+	return draw.CatmullRom
+}
+
+// resizeImage returns the image scaled to w by h pixels. JPEG images keep their color profile.
+func resizeImage(img image.Image, w, h int, resampling Resampling) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	resampling.interpolator().Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	if jpg, ok := img.(*JPEGImage); ok {
+		return &JPEGImage{Image: dst, Orientation: jpg.Orientation, ICC: jpg.ICC}
+	}
+	return dst
+}
+
+// JPEGImage is a decoded JPEG image that keeps the EXIF orientation and the embedded ICC color profile of its file. Context.DrawImage draws it upright according to its orientation, and the renderers either tag the image with its color profile (PDF, SVG) or convert its colors to sRGB (rasterizer, JSON), see SRGB.
+type JPEGImage struct {
+	image.Image
+	Orientation int    // EXIF orientation from 1 to 8, where 1 is upright
+	ICC         []byte // ICC color profile or nil
+
+	data []byte // file without its EXIF segments, nil if resized
+
+	once sync.Once
+	srgb image.Image
+}
+
+// ReadJPEG decodes a JPEG image together with its EXIF orientation and ICC color profile.
+func ReadJPEG(r io.Reader) (*JPEGImage, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	img, err := jpeg.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	jpg := &JPEGImage{
+		Image:       img,
+		Orientation: 1,
+		data:        append([]byte{}, b[:2]...),
+	}
+	chunks := map[int][]byte{}
+	i := 2
+	for i+4 <= len(b) && b[i] == 0xFF {
+		marker := b[i+1]
+		if marker == 0xFF {
+			i++ // fill byte
+			continue
+		} else if marker == 0xDA || marker == 0xD9 {
+			break // start of scan or end of image
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(b[i+2:]))
+		if len(b) < end {
+			break
+		}
+		payload := b[i+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+			if orientation := exifOrientation(payload[6:]); orientation != 0 {
+				jpg.Orientation = orientation
+			}
+			i = end
+			continue
+		} else if marker == 0xE2 && bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00")) && 14 <= len(payload) {
+			chunks[int(payload[12])] = payload[14:]
+		}
+		jpg.data = append(jpg.data, b[i:end]...)
+		i = end
+	}
+	jpg.data = append(jpg.data, b[i:]...)
+
+	if 0 < len(chunks) {
+		seqs := []int{}
+		for seq := range chunks {
+			seqs = append(seqs, seq)
+		}
+		sort.Ints(seqs)
+		for _, seq := range seqs {
+			jpg.ICC = append(jpg.ICC, chunks[seq]...)
+		}
+	}
+	return jpg, nil
+}
+
+// exifOrientation returns the orientation tag of the first image file directory of EXIF data, or zero if it is absent.
+func exifOrientation(b []byte) int {
+	if len(b) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder = binary.BigEndian
+	if b[0] == 'I' && b[1] == 'I' {
+		order = binary.LittleEndian
+	} else if b[0] != 'M' || b[1] != 'M' {
+		return 0
+	}
+
+	ifd := int(order.Uint32(b[4:]))
+	if ifd < 8 || len(b) < ifd+2 {
+		return 0
+	}
+	n := int(order.Uint16(b[ifd:]))
+	for k := 0; k < n; k++ {
+		entry := ifd + 2 + k*12
+		if len(b) < entry+12 {
+			break
+		}
+		// the orientation is a SHORT of which the value is stored in the entry
+		if order.Uint16(b[entry:]) == 0x0112 && order.Uint16(b[entry+2:]) == 3 {
+			if orientation := int(order.Uint16(b[entry+8:])); 1 <= orientation && orientation <= 8 {
+				return orientation
+			}
+		}
+	}
+	return 0
+}
+
+// orientation returns the transformation from the image to its upright orientation. Both have their origin at the bottom-left with the y-axis pointing up and are measured in pixels.
+func (img *JPEGImage) orientation() Matrix {
+	size := img.Bounds().Size()
+	w, h := float64(size.X), float64(size.Y)
+	switch img.Orientation {
+	case 2: // mirrored horizontally
+		return Matrix{{-1.0, 0.0, w}, {0.0, 1.0, 0.0}}
+	case 3: // rotated by 180 degrees
+		return Matrix{{-1.0, 0.0, w}, {0.0, -1.0, h}}
+	case 4: // mirrored vertically
+		return Matrix{{1.0, 0.0, 0.0}, {0.0, -1.0, h}}
+	case 5: // mirrored horizontally and rotated by 270 degrees clockwise
+		return Matrix{{0.0, -1.0, h}, {-1.0, 0.0, w}}
+	case 6: // rotated by 90 degrees clockwise
+		return Matrix{{0.0, 1.0, 0.0}, {-1.0, 0.0, w}}
+	case 7: // mirrored horizontally and rotated by 90 degrees clockwise
+		return Matrix{{0.0, 1.0, 0.0}, {1.0, 0.0, 0.0}}
+	case 8: // rotated by 270 degrees clockwise
+		return Matrix{{0.0, -1.0, h}, {1.0, 0.0, 0.0}}
+	}
+	return Identity
+}
+
+// iccColorSpace returns the color space signature of the ICC profile, such as "RGB " or "GRAY", or an empty string if there is no valid profile.
+func (img *JPEGImage) iccColorSpace() string {
+	if len(img.ICC) < 128 {
+		return ""
+	}
+	return string(img.ICC[16:20])
+}
+
+// SRGB returns the image with its colors converted from its ICC color profile to sRGB. Only RGB and gray profiles with matrices and tone reproduction curves are converted, as used by cameras and for color spaces such as Adobe RGB and Display P3; otherwise the image is returned as is. The result is cached.
+func (img *JPEGImage) SRGB() image.Image {
+	img.once.Do(func() {
+		img.srgb = img.Image
+		if profile, err := parseICCProfile(img.ICC); err == nil {
+			img.srgb = profile.toSRGB(img.Image)
+		}
+	})
+	return img.srgb
+}
+
+// srgbImage returns the image with colors in sRGB for renderers that cannot tag images with a color profile.
+func srgbImage(img image.Image) image.Image {
+	if jpg, ok := img.(*JPEGImage); ok {
+		return jpg.SRGB()
+	}
+	return img
+}
+
+////////////////////////////////////////////////////////////////
+
+// iccProfile is an ICC profile of the matrix/TRC type that converts colors to the profile connection space CIEXYZ under D50.
+type iccProfile struct {
+	gray   bool
+	curves [3][256]float64 // from 8-bit values to linear values
+	m      [3][3]float64   // from linear values to linear sRGB
+}
+
+// xyzToSRGB converts CIEXYZ under D50 to linear sRGB, using the Bradford chromatic adaptation.
+var xyzToSRGB = [3][3]float64{
+	{3.1338561, -1.6168667, -0.4906146},
+	{-0.9787684, 1.9161415, 0.0334540},
+	{0.0719453, -0.2289914, 1.4052427},
+}
+
+func parseICCProfile(b []byte) (*iccProfile, error) {
+	if len(b) < 132 || string(b[36:40]) != "acsp" || string(b[20:24]) != "XYZ " {
+		return nil, fmt.Errorf("bad ICC profile")
+	}
+	tags := map[string][]byte{}
+	n := int(binary.BigEndian.Uint32(b[128:]))
+	for i := 0; i < n && 132+i*12+12 <= len(b); i++ {
+		entry := b[132+i*12:]
+		offset, size := binary.BigEndian.Uint32(entry[4:]), binary.BigEndian.Uint32(entry[8:])
+		if uint64(len(b)) < uint64(offset)+uint64(size) {
+			return nil, fmt.Errorf("bad ICC profile: tag out of bounds")
+		}
+		tags[string(entry[:4])] = b[offset : offset+size]
+	}
+
+	profile := &iccProfile{}
+	switch string(b[16:20]) {
+	case "GRAY":
+		profile.gray = true
+		curve, err := iccCurve(tags["kTRC"])
+		if err != nil {
+			return nil, err
+		}
+		profile.curves[0] = curve
+	case "RGB ":
+		colorants := [3][3]float64{}
+		for j, channel := range []string{"r", "g", "b"} {
+			xyz := tags[channel+"XYZ"]
+			if len(xyz) < 20 || string(xyz[:4]) != "XYZ " {
+				return nil, fmt.Errorf("bad ICC profile: no colorants, only matrix/TRC profiles are supported")
+			}
+			for i := 0; i < 3; i++ {
+				colorants[i][j] = iccFixed(xyz[8+4*i:])
+			}
+			curve, err := iccCurve(tags[channel+"TRC"])
+			if err != nil {
+				return nil, err
+			}
+			profile.curves[j] = curve
+		}
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				for k := 0; k < 3; k++ {
+					profile.m[i][j] += xyzToSRGB[i][k] * colorants[k][j]
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported ICC color space %q", b[16:20])
+	}
+	return profile, nil
+}
+
+// iccFixed returns an s15Fixed16Number.
+func iccFixed(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536.0
+}
+
+// iccCurve returns the tone reproduction curve of a curve or parametric curve tag for 8-bit values.
+func iccCurve(b []byte) ([256]float64, error) {
+	curve := [256]float64{}
+	if len(b) < 12 {
+		return curve, fmt.Errorf("bad ICC profile: no tone reproduction curve")
+	}
+	var f func(float64) float64
+	switch string(b[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(b[8:]))
+		if len(b) < 12+2*n {
+			return curve, fmt.Errorf("bad ICC profile: curve out of bounds")
+		}
+		if n == 0 {
+			f = func(x float64) float64 { return x }
+		} else if n == 1 {
+			gamma := float64(binary.BigEndian.Uint16(b[12:])) / 256.0
+			f = func(x float64) float64 { return math.Pow(x, gamma) }
+		} else {
+			f = func(x float64) float64 {
+				t := x * float64(n-1)
+				i := int(math.Min(t, float64(n-2)))
+				y0 := float64(binary.BigEndian.Uint16(b[12+2*i:]))
+				y1 := float64(binary.BigEndian.Uint16(b[14+2*i:]))
+				return (y0 + (t-float64(i))*(y1-y0)) / 65535.0
+			}
+		}
+	case "para":
+		numParams := []int{1, 3, 4, 5, 7}
+		fn := int(binary.BigEndian.Uint16(b[8:]))
+		if len(numParams) <= fn || len(b) < 12+4*numParams[fn] {
+			return curve, fmt.Errorf("bad ICC profile: bad parametric curve")
+		}
+		p := [7]float64{1.0, 1.0, 0.0, 0.0, 0.0, 0.0, 0.0}
+		for i := 0; i < numParams[fn]; i++ {
+			p[i] = iccFixed(b[12+4*i:])
+		}
+		g, a, bb, c, d, e, ff := p[0], p[1], p[2], p[3], p[4], p[5], p[6]
+		switch fn {
+		case 1:
+			d, c = -bb/a, 0.0
+		case 2:
+			d, e, ff = -bb/a, c, c
+			c = 0.0
+		}
+		f = func(x float64) float64 {
+			if fn == 0 {
+				return math.Pow(x, g)
+			} else if x < d {
+				return c*x + ff
+			}
+			return math.Pow(math.Max(0.0, a*x+bb), g) + e
+		}
+	default:
+		return curve, fmt.Errorf("bad ICC profile: unsupported curve type %q", b[:4])
+	}
+	for i := range curve {
+		curve[i] = f(float64(i) / 255.0)
+	}
+	return curve, nil
+}
+
+// toSRGB converts the colors of an image to sRGB.
+func (profile *iccProfile) toSRGB(img image.Image) image.Image {
+	encode := [4096]uint8{}
+	for i := range encode {
+		v := float64(i) / float64(len(encode)-1)
+		if v <= 0.0031308 {
+			v *= 12.92
+		} else {
+			v = 1.055*math.Pow(v, 1.0/2.4) - 0.055
+		}
+		encode[i] = uint8(v*255.0 + 0.5)
+	}
+	quantize := func(v float64) uint8 {
+		return encode[int(math.Max(0.0, math.Min(1.0, v))*float64(len(encode)-1)+0.5)]
+	}
+
+	bounds := img.Bounds()
+	if profile.gray {
+		gray := image.NewGray(bounds)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
+				gray.SetGray(x, y, color.Gray{quantize(profile.curves[0][c.Y])})
+			}
+		}
+		return gray
+	}
+
+	rgba := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			// JPEG images are opaque
+			R, G, B, _ := img.At(x, y).RGBA()
+			lin := [3]float64{profile.curves[0][R>>8], profile.curves[1][G>>8], profile.curves[2][B>>8]}
+			c := [3]float64{}
+			for i := 0; i < 3; i++ {
+				c[i] = profile.m[i][0]*lin[0] + profile.m[i][1]*lin[1] + profile.m[i][2]*lin[2]
+			}
+			rgba.SetRGBA(x, y, color.RGBA{quantize(c[0]), quantize(c[1]), quantize(c[2]), 0xFF})
+		}
+	}
+	return rgba
+}
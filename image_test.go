@@ -0,0 +1,169 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+// testICCProfile returns an RGB profile with the sRGB colorants and a linear tone reproduction curve.
+func testICCProfile() []byte {
+	tags := []string{"rXYZ", "gXYZ", "bXYZ", "rTRC", "gTRC", "bTRC"}
+	colorants := [3][3]float64{{0.4360747, 0.2225045, 0.0139322}, {0.3850649, 0.7168786, 0.0971045}, {0.1430804, 0.0606169, 0.7141733}}
+
+	b := make([]byte, 132+12*len(tags))
+	copy(b[16:], "RGB XYZ ")
+	copy(b[36:], "acsp")
+	binary.BigEndian.PutUint32(b[128:], uint32(len(tags)))
+	for i, tag := range tags {
+		data := make([]byte, 20)
+		if i < 3 {
+			copy(data, "XYZ ")
+			for j, v := range colorants[i] {
+				binary.BigEndian.PutUint32(data[8+4*j:], uint32(int32(v*65536.0+0.5)))
+			}
+		} else {
+			copy(data, "curv")
+			binary.BigEndian.PutUint32(data[8:], 1)
+			binary.BigEndian.PutUint16(data[12:], 256) // gamma of 1.0
+		}
+		entry := b[132+12*i:]
+		copy(entry, tag)
+		binary.BigEndian.PutUint32(entry[4:], uint32(len(b)))
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(data)))
+		b = append(b, data...)
+	}
+	binary.BigEndian.PutUint32(b, uint32(len(b)))
+	return b
+}
+
+// testJPEG returns a gray JPEG image of 4x2 pixels with an EXIF orientation and an ICC profile split into two chunks.
+func testJPEG(orientation int, icc []byte) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for i := range img.Pix {
+		img.Pix[i] = 128
+	}
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		panic(err)
+	}
+
+	segments := [][]byte{}
+	exif := []byte("Exif\x00\x00II\x2A\x00\x08\x00\x00\x00\x01\x00\x12\x01\x03\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+	exif[24] = byte(orientation)
+	segments = append(segments, append([]byte{0xFF, 0xE1, 0, 0}, exif...))
+	for i, chunk := range [][]byte{icc[:len(icc)/2], icc[len(icc)/2:]} {
+		segments = append(segments, append([]byte{0xFF, 0xE2, 0, 0, 'I', 'C', 'C', '_', 'P', 'R', 'O', 'F', 'I', 'L', 'E', 0, byte(i + 1), 2}, chunk...))
+	}
+
+	b := []byte{0xFF, 0xD8}
+	for _, segment := range segments {
+		binary.BigEndian.PutUint16(segment[2:], uint16(len(segment)-2))
+		b = append(b, segment...)
+	}
+	return append(b, buf.Bytes()[2:]...)
+}
+
+func TestReadJPEG(t *testing.T) {
+	icc := testICCProfile()
+	jpg, err := ReadJPEG(bytes.NewReader(testJPEG(6, icc)))
+	test.Error(t, err)
+	test.T(t, jpg.Orientation, 6)
+	test.Bytes(t, jpg.ICC, icc)
+	test.T(t, jpg.Bounds(), image.Rect(0, 0, 4, 2))
+	test.That(t, !bytes.Contains(jpg.data, []byte("Exif")), "EXIF segment removed")
+	test.That(t, bytes.Contains(jpg.data, []byte("ICC_PROFILE")), "ICC segments kept")
+
+	jpg, err = ReadJPEG(bytes.NewReader(testJPEG(0, icc)))
+	test.Error(t, err)
+	test.T(t, jpg.Orientation, 1)
+
+	_, err = ReadJPEG(strings.NewReader("not a JPEG"))
+	test.That(t, err != nil, "not a JPEG")
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	for orientation := 1; orientation <= 8; orientation++ {
+		jpg := &JPEGImage{Image: image.NewRGBA(image.Rect(0, 0, 4, 2)), Orientation: orientation}
+		m := jpg.orientation()
+		if orientation < 5 {
+			test.T(t, Rect{0.0, 0.0, 4.0, 2.0}.Transform(m), Rect{0.0, 0.0, 4.0, 2.0}, orientation)
+		} else {
+			test.T(t, Rect{0.0, 0.0, 4.0, 2.0}.Transform(m), Rect{0.0, 0.0, 2.0, 4.0}, orientation)
+		}
+	}
+
+	// the top-left pixel of the file is at the top-right when rotated by 90 degrees clockwise
+	jpg := &JPEGImage{Image: image.NewRGBA(image.Rect(0, 0, 4, 2)), Orientation: 6}
+	test.T(t, jpg.orientation().Dot(Point{0.0, 2.0}), Point{2.0, 4.0})
+	jpg.Orientation = 8
+	test.T(t, jpg.orientation().Dot(Point{0.0, 2.0}), Point{0.0, 0.0})
+
+	c := New(10.0, 10.0)
+	ctx := NewContext(c)
+	ctx.DrawImage(0.0, 0.0, jpg, 1.0)
+	test.T(t, c.layers[0].Bounds(), Rect{0.0, 0.0, 2.0, 4.0})
+}
+
+func TestJPEGSRGB(t *testing.T) {
+	jpg, err := ReadJPEG(bytes.NewReader(testJPEG(1, testICCProfile())))
+	test.Error(t, err)
+
+	// the linear gray of the profile is brighter in sRGB
+	c := color.RGBAModel.Convert(jpg.SRGB().At(0, 0)).(color.RGBA)
+	test.That(t, 186 <= c.R && c.R <= 190 && 186 <= c.G && c.G <= 190 && 186 <= c.B && c.B <= 190, c)
+	test.T(t, color.RGBAModel.Convert(srgbImage(jpg).At(0, 0)), c)
+
+	jpg.ICC[16] = 'X' // unsupported color space
+	_, err = parseICCProfile(jpg.ICC)
+	test.That(t, err != nil, "unsupported color space")
+	_, err = parseICCProfile(nil)
+	test.That(t, err != nil, "no profile")
+}
+
+func TestJPEGRenderers(t *testing.T) {
+	jpg, err := ReadJPEG(bytes.NewReader(testJPEG(6, testICCProfile())))
+	test.Error(t, err)
+
+	// PDF tags the image with its profile, which is written once
+	buf := &bytes.Buffer{}
+	pdf := NewPDF(buf, 10.0, 10.0)
+	pdf.RenderImage(jpg, Identity)
+	pdf.RenderImage(jpg, Identity.Translate(4.0, 0.0))
+	test.Error(t, pdf.Close())
+	test.T(t, bytes.Count(buf.Bytes(), []byte("/ColorSpace [/ICCBased")), 2)
+	test.T(t, bytes.Count(buf.Bytes(), []byte("/Alternate /DeviceRGB")), 1)
+
+	// SVG embeds the file without its EXIF orientation
+	buf.Reset()
+	svg := NewSVG(buf, 10.0, 10.0)
+	svg.RenderImage(jpg, Identity)
+	test.Error(t, svg.Close())
+	test.That(t, strings.Contains(buf.String(), "data:image/jpeg;base64,"), buf.String())
+}
+
+func TestResizeImage(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4, 4))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i%2) * 0xFF // columns alternating between black and white
+	}
+	for _, resampling := range []Resampling{NearestNeighbor, Bilinear, CatmullRom, Lanczos} {
+		dst := resizeImage(img, 2, 2, resampling)
+		test.T(t, dst.Bounds(), image.Rect(0, 0, 2, 2), resampling)
+	}
+	gray := color.GrayModel.Convert(resizeImage(img, 2, 2, Bilinear).At(0, 0)).(color.Gray)
+	test.That(t, 100 < gray.Y && gray.Y < 155, gray)
+	test.Float(t, lanczos.At(0.0), 1.0)
+	test.Float(t, lanczos.At(1.0), 0.0)
+
+	// JPEG images keep their color profile, but not the file
+	jpg := resizeImage(&JPEGImage{Image: img, ICC: []byte("profile")}, 2, 2, Lanczos).(*JPEGImage)
+	test.Bytes(t, jpg.ICC, []byte("profile"))
+	test.T(t, jpg.data, []byte(nil))
+}
@@ -0,0 +1,542 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MarshalJSON encodes the path as an array of segments, where each segment is an array of the SVG command followed by its numbers, eg. [["M",0,0],["L",10,0],["A",5,5,0,0,1,10,10],["Z"]]. Arc rotations are in degrees and the large and sweep flags are 0 or 1.
+func (p *Path) MarshalJSON() ([]byte, error) {
+	segs := [][]interface{}{}
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		switch cmd {
+		case moveToCmd:
+			segs = append(segs, []interface{}{"M", p.d[i+1], p.d[i+2]})
+		case lineToCmd:
+			segs = append(segs, []interface{}{"L", p.d[i+1], p.d[i+2]})
+		case quadToCmd:
+			segs = append(segs, []interface{}{"Q", p.d[i+1], p.d[i+2], p.d[i+3], p.d[i+4]})
+		case cubeToCmd:
+			segs = append(segs, []interface{}{"C", p.d[i+1], p.d[i+2], p.d[i+3], p.d[i+4], p.d[i+5], p.d[i+6]})
+		case arcToCmd:
+			large, sweep := toArcFlags(p.d[i+4])
+			seg := []interface{}{"A", p.d[i+1], p.d[i+2], p.d[i+3] * 180.0 / math.Pi, 0, 0, p.d[i+5], p.d[i+6]}
+			if large {
+				seg[4] = 1
+			}
+			if sweep {
+				seg[5] = 1
+			}
+			segs = append(segs, seg)
+		case closeCmd:
+			segs = append(segs, []interface{}{"Z"})
+		}
+		i += cmdLen(cmd)
+	}
+	return json.Marshal(segs)
+}
+
+// UnmarshalJSON decodes a path from an array of segments as encoded by MarshalJSON. Commands are absolute and must be one of M, L, Q, C, A, or Z.
+func (p *Path) UnmarshalJSON(b []byte) error {
+	var segs [][]interface{}
+	if err := json.Unmarshal(b, &segs); err != nil {
+		return err
+	}
+
+	q := &Path{}
+	for i, seg := range segs {
+		if len(seg) == 0 {
+			return fmt.Errorf("bad path: empty segment at position %d", i)
+		}
+		cmd, ok := seg[0].(string)
+		if !ok {
+			return fmt.Errorf("bad path: segment should start with command at position %d", i)
+		}
+		f := make([]float64, len(seg)-1)
+		for j, v := range seg[1:] {
+			if f[j], ok = v.(float64); !ok {
+				return fmt.Errorf("bad path: non-number in command '%s' at position %d", cmd, i)
+			}
+		}
+
+		n := map[string]int{"M": 2, "L": 2, "Q": 4, "C": 6, "A": 7, "Z": 0}
+		if _, ok := n[cmd]; !ok {
+			return fmt.Errorf("bad path: unknown command '%s' at position %d", cmd, i)
+		} else if len(f) != n[cmd] {
+			return fmt.Errorf("bad path: %d numbers should follow command '%s' at position %d", n[cmd], cmd, i)
+		}
+		switch cmd {
+		case "M":
+			q.MoveTo(f[0], f[1])
+		case "L":
+			q.LineTo(f[0], f[1])
+		case "Q":
+			q.QuadTo(f[0], f[1], f[2], f[3])
+		case "C":
+			q.CubeTo(f[0], f[1], f[2], f[3], f[4], f[5])
+		case "A":
+			if f[3] != 0.0 && f[3] != 1.0 || f[4] != 0.0 && f[4] != 1.0 {
+				return fmt.Errorf("bad path: largeArc and sweep flags should be 0 or 1 in command '%s' at position %d", cmd, i)
+			}
+			q.ArcTo(f[0], f[1], f[2], f[3] == 1.0, f[4] == 1.0, f[5], f[6])
+		case "Z":
+			q.Close()
+		}
+	}
+	*p = *q
+	return nil
+}
+
+type styleJSON struct {
+	Fill        string      `json:"fill"`
+	Stroke      string      `json:"stroke"`
+	StrokeWidth float64     `json:"strokeWidth"`
+	StrokeCap   string      `json:"strokeCap"`
+	StrokeJoin  joinerJSON  `json:"strokeJoin"`
+	DashOffset  float64     `json:"dashOffset"`
+	Dashes      []float64   `json:"dashes"`
+	FillRule    string      `json:"fillRule"`
+	FillSpot    *spotJSON   `json:"fillSpot,omitempty"`
+	StrokeSpot  *spotJSON   `json:"strokeSpot,omitempty"`
+	FillMesh    []patchJSON `json:"fillMesh,omitempty"`
+	Overprint   bool        `json:"overprint,omitempty"`
+}
+
+type spotJSON struct {
+	Name string     `json:"name"`
+	CMYK [4]float64 `json:"cmyk"`
+	Tint float64    `json:"tint"`
+}
+
+type patchJSON struct {
+	Points [24]float64 `json:"points"` // x,y of the twelve control points
+	Colors [4]string   `json:"colors"`
+}
+
+func toSpotJSON(spot *SpotColor) *spotJSON {
+	if spot == nil {
+		return nil
+	}
+	return &spotJSON{spot.Name, spot.CMYK, spot.Tint}
+}
+
+func (s *spotJSON) spotColor() *SpotColor {
+	if s == nil {
+		return nil
+	}
+	return &SpotColor{s.Name, s.CMYK, s.Tint}
+}
+
+func toMeshJSON(mesh *MeshGradient) []patchJSON {
+	if mesh == nil {
+		return nil
+	}
+	patches := make([]patchJSON, len(mesh.Patches))
+	for i, patch := range mesh.Patches {
+		for j, p := range patch.Points {
+			patches[i].Points[2*j], patches[i].Points[2*j+1] = p.X, p.Y
+		}
+		for j, col := range patch.Colors {
+			patches[i].Colors[j] = CSSColor(col).String()
+		}
+	}
+	return patches
+}
+
+func meshJSON(patches []patchJSON) (*MeshGradient, error) {
+	if patches == nil {
+		return nil, nil
+	}
+	mesh := &MeshGradient{make([]MeshPatch, len(patches))}
+	for i, patch := range patches {
+		for j := range mesh.Patches[i].Points {
+			mesh.Patches[i].Points[j] = Point{patch.Points[2*j], patch.Points[2*j+1]}
+		}
+		for j, s := range patch.Colors {
+			var err error
+			if mesh.Patches[i].Colors[j], err = ParseCSSColor(s); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return mesh, nil
+}
+
+type joinerJSON struct {
+	Type  string      `json:"type"`
+	Limit *float64    `json:"limit,omitempty"` // nil if the limit is NaN
+	Gap   *joinerJSON `json:"gap,omitempty"`
+}
+
+// UnmarshalJSON replaces rather than merges the joiner, so that a limit of the default style is not retained.
+func (j *joinerJSON) UnmarshalJSON(b []byte) error {
+	type plain joinerJSON
+	v := plain{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*j = joinerJSON(v)
+	return nil
+}
+
+func toStyleJSON(style Style) (styleJSON, error) {
+	s := styleJSON{
+		Fill:        CSSColor(style.FillColor).String(),
+		Stroke:      CSSColor(style.StrokeColor).String(),
+		StrokeWidth: style.StrokeWidth,
+		DashOffset:  style.DashOffset,
+		Dashes:      style.Dashes,
+		FillRule:    "nonzero",
+		FillSpot:    toSpotJSON(style.FillSpot),
+		StrokeSpot:  toSpotJSON(style.StrokeSpot),
+		FillMesh:    toMeshJSON(style.FillMesh),
+		Overprint:   style.Overprint,
+	}
+	if style.FillRule == EvenOdd {
+		s.FillRule = "evenodd"
+	}
+	if s.Dashes == nil {
+		s.Dashes = []float64{}
+	}
+
+	var err error
+	if s.StrokeCap, err = toCapperJSON(style.StrokeCapper); err != nil {
+		return s, err
+	}
+	s.StrokeJoin, err = toJoinerJSON(style.StrokeJoiner)
+	return s, err
+}
+
+func toCapperJSON(capper Capper) (string, error) {
+	switch capper.(type) {
+	case ButtCapper:
+		return "butt", nil
+	case RoundCapper:
+		return "round", nil
+	case SquareCapper:
+		return "square", nil
+	}
+	return "", fmt.Errorf("unsupported capper %v", capper)
+}
+
+func capperJSON(s string) (Capper, error) {
+	switch s {
+	case "butt":
+		return ButtCap, nil
+	case "round":
+		return RoundCap, nil
+	case "square":
+		return SquareCap, nil
+	}
+	return nil, fmt.Errorf("unknown capper '%s'", s)
+}
+
+func toJoinerJSON(joiner Joiner) (joinerJSON, error) {
+	var gapJoiner Joiner
+	var limit float64
+	j := joinerJSON{}
+	switch joiner := joiner.(type) {
+	case BevelJoiner:
+		j.Type = "bevel"
+		return j, nil
+	case RoundJoiner:
+		j.Type = "round"
+		return j, nil
+	case MiterJoiner:
+		j.Type = "miter"
+		gapJoiner, limit = joiner.GapJoiner, joiner.Limit
+	case ArcsJoiner:
+		j.Type = "arcs"
+		gapJoiner, limit = joiner.GapJoiner, joiner.Limit
+	default:
+		return j, fmt.Errorf("unsupported joiner %v", joiner)
+	}
+
+	if !math.IsNaN(limit) {
+		j.Limit = &limit
+	}
+	gap, err := toJoinerJSON(gapJoiner)
+	if err != nil {
+		return j, err
+	}
+	j.Gap = &gap
+	return j, nil
+}
+
+func (j joinerJSON) joiner() (Joiner, error) {
+	var gapJoiner Joiner = BevelJoin
+	if j.Gap != nil {
+		var err error
+		if gapJoiner, err = j.Gap.joiner(); err != nil {
+			return nil, err
+		}
+	}
+	limit := math.NaN()
+	if j.Limit != nil {
+		limit = *j.Limit
+	}
+
+	switch j.Type {
+	case "bevel":
+		return BevelJoin, nil
+	case "round":
+		return RoundJoin, nil
+	case "miter":
+		return MiterClipJoin(gapJoiner, limit), nil
+	case "arcs":
+		return ArcsClipJoin(gapJoiner, limit), nil
+	}
+	return nil, fmt.Errorf("unknown joiner '%s'", j.Type)
+}
+
+// MarshalJSON encodes the style as an object with the colors as CSS colors, eg. {"fill":"#000","stroke":"rgba(0,0,0,0)","strokeWidth":1,"strokeCap":"butt","strokeJoin":{"type":"miter","limit":2,"gap":{"type":"bevel"}},"dashOffset":0,"dashes":[],"fillRule":"nonzero"}. Spot colors, mesh gradients and overprinting are added as "fillSpot":{"name":"PANTONE 185 C","cmyk":[0,0.93,0.79,0],"tint":1}, "strokeSpot", "fillMesh":[{"points":[x0,y0,...,x11,y11],"colors":["#f00",...]}] and "overprint":true when set. Only the cappers and joiners of this package are supported.
+func (style Style) MarshalJSON() ([]byte, error) {
+	s, err := toStyleJSON(style)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
+}
+
+// UnmarshalJSON decodes a style as encoded by MarshalJSON. Missing fields are taken from DefaultStyle. Colors can be in hexadecimal notation (#rgb, #rgba, #rrggbb, or #rrggbbaa), use rgb(), rgba(), hsl() or hsla(), be a color name, or be none.
+func (style *Style) UnmarshalJSON(b []byte) error {
+	s, err := toStyleJSON(DefaultStyle)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	st := Style{
+		StrokeWidth: s.StrokeWidth,
+		DashOffset:  s.DashOffset,
+		Dashes:      s.Dashes,
+		FillSpot:    s.FillSpot.spotColor(),
+		StrokeSpot:  s.StrokeSpot.spotColor(),
+		Overprint:   s.Overprint,
+	}
+	if st.FillMesh, err = meshJSON(s.FillMesh); err != nil {
+		return err
+	} else if st.FillColor, err = ParseCSSColor(s.Fill); err != nil {
+		return err
+	} else if st.StrokeColor, err = ParseCSSColor(s.Stroke); err != nil {
+		return err
+	}
+
+	if st.StrokeCapper, err = capperJSON(s.StrokeCap); err != nil {
+		return err
+	} else if st.StrokeJoiner, err = s.StrokeJoin.joiner(); err != nil {
+		return err
+	}
+
+	switch s.FillRule {
+	case "nonzero":
+		st.FillRule = NonZero
+	case "evenodd":
+		st.FillRule = EvenOdd
+	default:
+		return fmt.Errorf("unknown fill rule '%s'", s.FillRule)
+	}
+	*style = st
+	return nil
+}
+
+// ParseCSSColor parses a CSS color, which is a color name, a color in hexadecimal notation, or one of rgb(), rgba(), hsl(), or hsla() with numbers or percentages, and returns the alpha-premultiplied color.
+func ParseCSSColor(s string) (color.RGBA, error) {
+	s = strings.TrimSpace(s)
+	if s == "none" || s == "transparent" {
+		return Transparent, nil
+	} else if col, ok := cssColors[strings.ToLower(s)]; ok {
+		return col, nil
+	} else if 0 < len(s) && s[0] == '#' {
+		h := s[1:]
+		if len(h) == 3 || len(h) == 4 {
+			// expand shorthand notation
+			b := make([]byte, 0, 8)
+			for i := 0; i < len(h); i++ {
+				b = append(b, h[i], h[i])
+			}
+			h = string(b)
+		}
+		if len(h) == 6 {
+			h += "ff"
+		}
+		b, err := hex.DecodeString(h)
+		if err != nil || len(b) != 4 {
+			return color.RGBA{}, fmt.Errorf("bad color '%s'", s)
+		}
+		return premultiply(float64(b[0]), float64(b[1]), float64(b[2]), float64(b[3])/255.0), nil
+	}
+
+	var name, args string
+	if open := strings.IndexByte(s, '('); open != -1 && strings.HasSuffix(s, ")") {
+		name = strings.ToLower(strings.TrimSpace(s[:open]))
+		args = s[open+1 : len(s)-1]
+	}
+	if name != "rgb" && name != "rgba" && name != "hsl" && name != "hsla" {
+		return color.RGBA{}, fmt.Errorf("bad color '%s'", s)
+	}
+	// comma separated, or space separated with the alpha after a slash
+	fields := strings.FieldsFunc(args, func(r rune) bool { return r == ',' || r == ' ' || r == '/' })
+	if len(fields) != 3 && len(fields) != 4 {
+		return color.RGBA{}, fmt.Errorf("bad color '%s'", s)
+	}
+	v := []float64{0.0, 0.0, 0.0, 1.0}
+	for i, field := range fields {
+		var err error
+		if strings.HasSuffix(field, "%") {
+			// percentages of 255 for RGB, and of one for saturation, lightness and alpha
+			if v[i], err = strconv.ParseFloat(field[:len(field)-1], 64); err == nil {
+				if i < 3 && name[0] == 'r' {
+					v[i] = v[i] * 255.0 / 100.0
+				} else {
+					v[i] /= 100.0
+				}
+			}
+		} else {
+			v[i], err = strconv.ParseFloat(strings.TrimSuffix(field, "deg"), 64)
+		}
+		if err != nil {
+			return color.RGBA{}, fmt.Errorf("bad color '%s'", s)
+		}
+	}
+	if name[0] == 'h' {
+		v[0], v[1], v[2] = hslToRGB(v[0], v[1], v[2])
+	}
+	return premultiply(v[0], v[1], v[2], v[3]), nil
+}
+
+// hslToRGB converts a color with hue h in degrees and saturation s and lightness l in [0,1] to its components r, g, b in [0,255].
+func hslToRGB(h, s, l float64) (float64, float64, float64) {
+	h = math.Mod(h, 360.0)
+	if h < 0.0 {
+		h += 360.0
+	}
+	s = math.Max(0.0, math.Min(1.0, s))
+	l = math.Max(0.0, math.Min(1.0, l))
+	component := func(n float64) float64 {
+		k := math.Mod(n+h/30.0, 12.0)
+		return 255.0 * (l - s*math.Min(l, 1.0-l)*math.Max(-1.0, math.Min(math.Min(k-3.0, 9.0-k), 1.0)))
+	}
+	return component(0.0), component(8.0), component(4.0)
+}
+
+// premultiply returns the color with components r, g, b in [0,255] and alpha a in [0,1], with the components multiplied by alpha.
+func premultiply(r, g, b, a float64) color.RGBA {
+	a = math.Max(0.0, math.Min(1.0, a))
+	component := func(c float64) uint8 {
+		return uint8(math.Round(math.Max(0.0, math.Min(255.0, c)) * a))
+	}
+	return color.RGBA{component(r), component(g), component(b), uint8(math.Round(a * 255.0))}
+}
+
+type layerJSON struct {
+	Path   *Path       `json:"path,omitempty"`
+	Style  *Style      `json:"style,omitempty"`
+	Image  string      `json:"image,omitempty"`
+	Matrix *[6]float64 `json:"matrix,omitempty"`
+}
+
+type canvasJSON struct {
+	Width  float64     `json:"width"`
+	Height float64     `json:"height"`
+	Layers []layerJSON `json:"layers"`
+}
+
+// MarshalJSON encodes the canvas as a display list of its size and its layers from bottom to top, eg. {"width":10,"height":10,"layers":[{"path":[["M",0,0],["L",10,0],["L",0,10],["Z"]],"style":{"fill":"#f00",...},"matrix":[1,0,0,1,0,0]}]}. Each layer is a path with its style, or an image encoded as a PNG data URI, transformed by a matrix [a,b,c,d,e,f] that maps (x,y) to (a*x+c*y+e, b*x+d*y+f) as in SVG. Text is converted to paths.
+func (c *Canvas) MarshalJSON() ([]byte, error) {
+	c.merge()
+	v := canvasJSON{c.W, c.H, []layerJSON{}}
+	for _, l := range c.layers {
+		m := &[6]float64{l.m[0][0], l.m[1][0], l.m[0][1], l.m[1][1], l.m[0][2], l.m[1][2]}
+		if l.path != nil {
+			style := l.style
+			v.Layers = append(v.Layers, layerJSON{Path: l.path, Style: &style, Matrix: m})
+		} else if l.text != nil {
+			paths, colors := l.text.ToPaths()
+			for i, path := range paths {
+				style := DefaultStyle
+				style.FillColor = colors[i]
+				v.Layers = append(v.Layers, layerJSON{Path: path, Style: &style, Matrix: m})
+			}
+		} else if l.img != nil {
+			b := &bytes.Buffer{}
+			if err := png.Encode(b, srgbImage(l.img)); err != nil {
+				return nil, err
+			}
+			v.Layers = append(v.Layers, layerJSON{Image: "data:image/png;base64," + base64.StdEncoding.EncodeToString(b.Bytes()), Matrix: m})
+		}
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes a canvas from a display list as encoded by MarshalJSON, replacing its size and layers. Styles default to DefaultStyle and matrices to the identity. Images can be data URIs of any registered image format.
+func (c *Canvas) UnmarshalJSON(b []byte) error {
+	return c.UnmarshalJSONWithLimits(b, Limits{})
+}
+
+// UnmarshalJSONWithLimits decodes a canvas like UnmarshalJSON and enforces the limits on the path segments and on the size of images, which are checked before they are decoded. It returns an error wrapping ErrLimitExceeded when a limit is exceeded.
+func (c *Canvas) UnmarshalJSONWithLimits(b []byte, limits Limits) error {
+	budget := newBudget(limits)
+	v := canvasJSON{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	} else if v.Width < 0.0 || v.Height < 0.0 {
+		return fmt.Errorf("bad canvas: negative size")
+	}
+
+	layers := make([]layer, 0, len(v.Layers))
+	for i, l := range v.Layers {
+		m := Identity
+		if l.Matrix != nil {
+			m = Matrix{{l.Matrix[0], l.Matrix[2], l.Matrix[4]}, {l.Matrix[1], l.Matrix[3], l.Matrix[5]}}
+		}
+		if l.Path != nil {
+			style := DefaultStyle
+			if l.Style != nil {
+				style = *l.Style
+			}
+			if !budget.addPath(l.Path, style, m) {
+				return budget.err
+			}
+			layers = append(layers, layer{path: l.Path, m: m, style: style})
+		} else if l.Image != "" {
+			comma := strings.IndexByte(l.Image, ',')
+			if !strings.HasPrefix(l.Image, "data:") || comma == -1 || !strings.HasSuffix(l.Image[:comma], ";base64") {
+				return fmt.Errorf("bad canvas: image of layer %d should be a base64 data URI", i)
+			}
+			data := l.Image[comma+1:]
+			config, _, err := image.DecodeConfig(base64.NewDecoder(base64.StdEncoding, strings.NewReader(data)))
+			if err != nil {
+				return fmt.Errorf("bad canvas: image of layer %d: %w", i, err)
+			} else if !budget.addPixels(config.Width, config.Height) {
+				return budget.err
+			}
+			img, _, err := image.Decode(base64.NewDecoder(base64.StdEncoding, strings.NewReader(data)))
+			if err != nil {
+				return fmt.Errorf("bad canvas: image of layer %d: %w", i, err)
+			}
+			layers = append(layers, layer{img: img, m: m})
+		} else {
+			return fmt.Errorf("bad canvas: layer %d should have a path or image", i)
+		}
+	}
+
+	c.Reset()
+	c.W, c.H = v.Width, v.Height
+	for _, l := range layers {
+		c.addLayer(l)
+	}
+	return nil
+}
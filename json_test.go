@@ -0,0 +1,132 @@
+package canvas
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPathJSON(t *testing.T) {
+	p := MustParseSVG("M5 0L5 10Q10 10 10 5C15 5 15 0 10 0A10 5 0 0 1 5 0zM20 20L30 20")
+	b, err := json.Marshal(p)
+	test.Error(t, err)
+	test.String(t, string(b), `[["M",5,0],["L",5,10],["Q",10,10,10,5],["C",15,5,15,0,10,0],["A",10,5,0,0,1,5,0],["Z"],["M",20,20],["L",30,20]]`)
+
+	q := &Path{}
+	test.Error(t, json.Unmarshal(b, q))
+	test.T(t, q, p)
+
+	test.Error(t, json.Unmarshal([]byte(`[]`), q))
+	test.That(t, q.Empty())
+	test.That(t, json.Unmarshal([]byte(`[[]]`), q) != nil)
+	test.That(t, json.Unmarshal([]byte(`[["X",1,2]]`), q) != nil)
+	test.That(t, json.Unmarshal([]byte(`[["L",1]]`), q) != nil)
+	test.That(t, json.Unmarshal([]byte(`[["L","1",2]]`), q) != nil)
+	test.That(t, json.Unmarshal([]byte(`[["A",5,5,0,2,0,10,0]]`), q) != nil)
+}
+
+func TestStyleJSON(t *testing.T) {
+	b, err := json.Marshal(DefaultStyle)
+	test.Error(t, err)
+	test.String(t, string(b), `{"fill":"#000","stroke":"rgba(0,0,0,0)","strokeWidth":1,"strokeCap":"butt","strokeJoin":{"type":"miter","limit":2,"gap":{"type":"bevel"}},"dashOffset":0,"dashes":[],"fillRule":"nonzero"}`)
+
+	style := Style{}
+	test.Error(t, json.Unmarshal(b, &style))
+	test.T(t, style, DefaultStyle)
+
+	// missing fields are taken from the default style
+	test.Error(t, json.Unmarshal([]byte(`{"fill":"rgba(255,0,0,0.5)","stroke":"#00f","strokeCap":"round","strokeJoin":{"type":"arcs","gap":{"type":"round"}},"dashes":[1,2],"fillRule":"evenodd"}`), &style))
+	test.T(t, style.FillColor, color.RGBA{128, 0, 0, 128})
+	test.T(t, style.StrokeColor, Blue)
+	test.T(t, style.StrokeWidth, 1.0)
+	test.T(t, style.StrokeCapper, RoundCap)
+	test.That(t, math.IsNaN(style.StrokeJoiner.(ArcsJoiner).Limit))
+	test.T(t, style.StrokeJoiner.(ArcsJoiner).GapJoiner, RoundJoin)
+	test.T(t, style.Dashes, []float64{1, 2})
+	test.T(t, style.FillRule, EvenOdd)
+
+	// spot colors, mesh gradients and overprinting
+	style = DefaultStyle
+	style.FillSpot = &SpotColor{"PANTONE 185 C", [4]float64{0.0, 0.93, 0.79, 0.0}, 1.0}
+	style.StrokeSpot = &SpotColor{"Varnish", [4]float64{0.0, 0.0, 0.0, 0.1}, 0.5}
+	style.FillMesh = NewMeshGradientGrid(Rect{0.0, 0.0, 20.0, 10.0}, [][]color.RGBA{{Red, Green, Blue}, {White, Black, Red}})
+	style.Overprint = true
+	b, err = json.Marshal(style)
+	test.Error(t, err)
+	style2 := Style{}
+	test.Error(t, json.Unmarshal(b, &style2))
+	test.T(t, *style2.FillSpot, *style.FillSpot)
+	test.T(t, *style2.StrokeSpot, *style.StrokeSpot)
+	test.T(t, *style2.FillMesh, *style.FillMesh)
+	test.T(t, style2.Overprint, true)
+	test.That(t, json.Unmarshal([]byte(`{"fillMesh":[{"colors":["reddish"]}]}`), &style) != nil)
+
+	test.That(t, json.Unmarshal([]byte(`{"strokeCap":"pointy"}`), &style) != nil)
+	test.That(t, json.Unmarshal([]byte(`{"fill":"reddish"}`), &style) != nil)
+	test.That(t, json.Unmarshal([]byte(`{"fillRule":"odd"}`), &style) != nil)
+}
+
+func TestParseCSSColor(t *testing.T) {
+	var tts = []struct {
+		s     string
+		color color.RGBA
+	}{
+		{"#0ff", Cyan},
+		{"#f0f8ff", Aliceblue},
+		{"#ffffff00", Transparent},
+		{"#f008", color.RGBA{136, 0, 0, 136}},
+		{"none", Transparent},
+		{"rgb(240, 248, 255)", Aliceblue},
+		{"rgba(255,255,51,.33333333)", color.RGBA{85, 85, 17, 85}},
+		{"AliceBlue", Aliceblue},
+		{"rgb(100% 0% 0% / 50%)", color.RGBA{128, 0, 0, 128}},
+		{"hsl(120, 100%, 25%)", Green},
+		{"hsla(300deg 100% 50% / 1)", Fuchsia},
+	}
+	for _, tt := range tts {
+		t.Run(tt.s, func(t *testing.T) {
+			c, err := ParseCSSColor(tt.s)
+			test.Error(t, err)
+			test.T(t, c, tt.color)
+		})
+	}
+
+	_, err := ParseCSSColor("#ff")
+	test.That(t, err != nil)
+	_, err = ParseCSSColor("cmyk(0,0,0,0)")
+	test.That(t, err != nil)
+}
+
+func TestCanvasJSON(t *testing.T) {
+	c := New(10.0, 20.0)
+	ctx := NewContext(c)
+	ctx.SetFillColor(Red)
+	ctx.DrawPath(1.0, 2.0, Rectangle(3.0, 4.0))
+	ctx.DrawImage(0.0, 0.0, image.NewRGBA(image.Rect(0, 0, 1, 1)), 1.0)
+
+	b, err := json.Marshal(c)
+	test.Error(t, err)
+	test.That(t, strings.HasPrefix(string(b), `{"width":10,"height":20,"layers":[{"path":[["M",0,0],["L",3,0],["L",3,4],["L",0,4],["Z"]],"style":{"fill":"#f00",`), string(b))
+
+	d := &Canvas{}
+	test.Error(t, json.Unmarshal(b, d))
+	test.Float(t, d.W, 10.0)
+	test.Float(t, d.H, 20.0)
+	test.T(t, len(d.layers), 2)
+	test.T(t, d.layers[0].path, c.layers[0].path)
+	test.T(t, d.layers[0].style.FillColor, Red)
+	test.T(t, d.layers[0].m, Identity.Translate(1.0, 2.0))
+	test.T(t, d.layers[1].img.Bounds(), image.Rect(0, 0, 1, 1))
+
+	test.Error(t, json.Unmarshal([]byte(`{"width":5,"height":5,"layers":[{"path":[["M",0,0],["L",1,0]]}]}`), d))
+	test.T(t, d.layers[0].style, DefaultStyle)
+	test.T(t, d.layers[0].m, Identity)
+	test.That(t, json.Unmarshal([]byte(`{"width":5,"height":5,"layers":[{}]}`), d) != nil)
+	test.That(t, json.Unmarshal([]byte(`{"width":5,"height":5,"layers":[{"image":"lenna.png"}]}`), d) != nil)
+	test.That(t, json.Unmarshal([]byte(`{"width":-5,"height":5}`), d) != nil)
+}
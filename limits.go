@@ -0,0 +1,171 @@
+package canvas
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrLimitExceeded is returned, wrapped, when a document or drawing exceeds a resource limit.
+var ErrLimitExceeded = errors.New("resource limit exceeded")
+
+// Limits are resource limits for services that render untrusted input, such as SVG documents uploaded by users, so that crafted input cannot exhaust their CPU or memory. They are enforced by ReadSVGWithOptions and by the Rasterizer, see Rasterizer.SetLimits. A zero value means unlimited. The size of decompressed fonts is limited separately by font.MaxMemory.
+type Limits struct {
+	MaxSegments int           // maximum number of path segments after flattening, including those generated by dashing
+	MaxPixels   int           // maximum number of pixels of raster output, and of all embedded images together
+	MaxFontSize float64       // maximum font size in points
+	Timeout     time.Duration // maximum duration of reading a document or of rendering
+}
+
+// SafeLimits are limits for safe mode, which accept any reasonable drawing.
+var SafeLimits = Limits{
+	MaxSegments: 1000000,
+	MaxPixels:   50000000,
+	MaxFontSize: 1000.0,
+	Timeout:     10 * time.Second,
+}
+
+// deadline returns the time at which the time budget is exceeded if it starts now, or the zero time when it is unlimited.
+func (limits Limits) deadline() time.Time {
+	if limits.Timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(limits.Timeout)
+}
+
+// budget keeps track of the resources used under limits.
+type budget struct {
+	Limits
+	deadline time.Time
+	segments int
+	pixels   float64 // pixels of the images so far
+	err      error
+}
+
+func newBudget(limits Limits) *budget {
+	return &budget{
+		Limits:   limits,
+		deadline: limits.deadline(),
+	}
+}
+
+// expired returns true if the time budget is exceeded.
+func (b *budget) expired() bool {
+	if b.err == nil && !b.deadline.IsZero() && time.Now().After(b.deadline) {
+		b.err = fmt.Errorf("timeout of %v: %w", b.Timeout, ErrLimitExceeded)
+	}
+	return b.err != nil
+}
+
+// addPath adds the segments of a path with given style and transformation to the budget, and returns false if a limit is exceeded.
+func (b *budget) addPath(p *Path, style Style, m Matrix) bool {
+	if b.expired() {
+		return false
+	}
+	if b.MaxSegments <= 0 {
+		return true
+	}
+	scale := math.Sqrt(math.Abs(m.Det()))
+	n := p.flatSegments(scale)
+	if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth && 0 < len(style.Dashes) {
+		// dashing may split each segment into many dashes
+		period := 0.0
+		for _, dash := range style.Dashes {
+			period += math.Abs(dash)
+		}
+		if Epsilon < period {
+			n += int(math.Min(p.Length()*scale/period*float64(len(style.Dashes)), math.MaxInt32))
+		}
+	}
+	b.segments += n
+	if b.MaxSegments < b.segments {
+		b.err = fmt.Errorf("more than %d path segments: %w", b.MaxSegments, ErrLimitExceeded)
+		return false
+	}
+	return true
+}
+
+// addPixels adds an image of w by h pixels to the budget, and returns false if the images so far exceed the limits together.
+func (b *budget) addPixels(w, h int) bool {
+	if b.MaxPixels <= 0 {
+		return true
+	}
+	b.pixels += float64(w) * float64(h)
+	if float64(b.MaxPixels) < b.pixels {
+		b.err = fmt.Errorf("images of more than %d pixels: %w", b.MaxPixels, ErrLimitExceeded)
+		return false
+	}
+	return true
+}
+
+// checkSize returns false if raster output of w by h pixels, which are rounded to whole pixels, exceeds the limits or cannot be allocated, which is not added to the pixels of the images. The size is checked before converting to integers so that huge or non-finite sizes cannot overflow.
+func (b *budget) checkSize(w, h float64) bool {
+	w, h = math.Floor(w+0.5), math.Floor(h+0.5)
+	maxSize := float64(math.MaxInt32)
+	if 0 < b.MaxPixels {
+		maxSize = math.Min(maxSize, float64(b.MaxPixels))
+	}
+	if !(0.0 <= w && w <= maxSize && 0.0 <= h && h <= maxSize) {
+		b.err = fmt.Errorf("image of %gx%g has an invalid size: %w", w, h, ErrLimitExceeded)
+		return false
+	} else if 0 < b.MaxPixels && float64(b.MaxPixels) < w*h {
+		b.err = fmt.Errorf("image of %gx%g exceeds %d pixels: %w", w, h, b.MaxPixels, ErrLimitExceeded)
+		return false
+	}
+	return true
+}
+
+// addFontSize returns false if a font size in points exceeds the limits.
+func (b *budget) addFontSize(size float64) bool {
+	if 0.0 < b.MaxFontSize && b.MaxFontSize < size {
+		b.err = fmt.Errorf("font size of %gpt exceeds %gpt: %w", size, b.MaxFontSize, ErrLimitExceeded)
+		return false
+	}
+	return true
+}
+
+// segments returns the number of segments of the path, excluding MoveTo commands.
+func (p *Path) segments() int {
+	n := 0
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		if cmd != moveToCmd {
+			n++
+		}
+		i += cmdLen(cmd)
+	}
+	return n
+}
+
+// flatSegments returns an estimate of the number of segments of the path after scaling by scale and flattening its curves using Tolerance, excluding MoveTo commands, without flattening the path. The number of lines that approximate a curve grows with the square root of its curvature, so that curves with far-away control points are flattened into many lines.
+func (p *Path) flatSegments(scale float64) int {
+	lines := func(deviation float64) float64 {
+		return math.Max(1.0, math.Ceil(math.Sqrt(deviation*scale/Tolerance)))
+	}
+	n := 0.0
+	var start Point
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		end := Point{p.d[i+cmdLen(cmd)-3], p.d[i+cmdLen(cmd)-2]}
+		switch cmd {
+		case lineToCmd, closeCmd:
+			n++
+		case quadToCmd:
+			cp := Point{p.d[i+1], p.d[i+2]}
+			n += lines(start.Sub(cp.Mul(2.0)).Add(end).Length() / 4.0)
+		case cubeToCmd:
+			cp1, cp2 := Point{p.d[i+1], p.d[i+2]}, Point{p.d[i+3], p.d[i+4]}
+			dd := math.Max(start.Sub(cp1.Mul(2.0)).Add(cp2).Length(), cp1.Sub(cp2.Mul(2.0)).Add(end).Length())
+			n += lines(0.75 * dd)
+		case arcToCmd:
+			rx, ry, phi := p.d[i+1], p.d[i+2], p.d[i+3]
+			large, sweep := toArcFlags(p.d[i+4])
+			_, _, theta0, theta1 := ellipseToCenter(start.X, start.Y, rx, ry, phi, large, sweep, end.X, end.Y)
+			n += math.Ceil(math.Abs(theta1-theta0) / 2.0 * lines(math.Max(math.Abs(rx), math.Abs(ry))/2.0))
+		}
+		start = end
+		i += cmdLen(cmd)
+	}
+	return int(math.Min(n, math.MaxInt32))
+}
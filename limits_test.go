@@ -0,0 +1,150 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPathSegments(t *testing.T) {
+	test.T(t, (&Path{}).segments(), 0)
+	test.T(t, Rectangle(1.0, 1.0).segments(), 4)
+	test.T(t, Rectangle(1.0, 1.0).Append(Rectangle(1.0, 1.0)).segments(), 8)
+
+	// curves count the lines they are flattened into
+	defer func(tolerance float64) {
+		Tolerance = tolerance
+	}(Tolerance)
+	Tolerance = 0.01
+	test.T(t, Rectangle(1.0, 1.0).flatSegments(1.0), 4)
+	test.That(t, 10000 < MustParseSVG("M0 0C1e6 1e6 -1e6 0 10 10").flatSegments(1.0))
+	test.That(t, Circle(1.0).flatSegments(1000.0) < Circle(1.0).flatSegments(1e6))
+}
+
+func TestRasterizerLimits(t *testing.T) {
+	c := New(10.0, 10.0)
+	ctx := NewContext(c)
+	ctx.DrawPath(0.0, 0.0, Rectangle(5.0, 5.0))
+	ctx.DrawPath(5.0, 5.0, Rectangle(5.0, 5.0))
+
+	_, err := c.WriteImageWithLimits(1.0, Limits{MaxSegments: 8})
+	test.Error(t, err)
+	_, err = c.WriteImageWithLimits(1.0, Limits{MaxSegments: 7})
+	test.That(t, errors.Is(err, ErrLimitExceeded), err)
+	_, err = c.WriteImageWithLimits(10.0, Limits{MaxPixels: 9999})
+	test.That(t, errors.Is(err, ErrLimitExceeded), err)
+
+	// huge sizes do not overflow when converted to pixels
+	for _, size := range [][2]float64{{1e300, 0.0}, {0.0, 1e300}, {math.Inf(1), 1.0}, {math.NaN(), 1.0}, {-10.0, 10.0}} {
+		_, err = New(size[0], size[1]).WriteImageWithLimits(1.0, Limits{})
+		test.That(t, errors.Is(err, ErrLimitExceeded), err)
+	}
+	_, err = New(1e6, 0.0).WriteImageWithLimits(1.0, Limits{MaxPixels: 9999})
+	test.That(t, errors.Is(err, ErrLimitExceeded), err)
+
+	// fine dashes generate many more segments than the path has
+	ctx.SetStrokeColor(Black)
+	ctx.SetStrokeWidth(0.1)
+	ctx.SetDashes(0.0, 0.01, 0.01)
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	_, err = c.WriteImageWithLimits(1.0, Limits{MaxSegments: 1000})
+	test.That(t, errors.Is(err, ErrLimitExceeded), err)
+
+	ras := NewRasterizer(image.NewRGBA(image.Rect(0, 0, 10, 10)), 1.0)
+	ras.SetLimits(Limits{Timeout: time.Nanosecond})
+	time.Sleep(time.Millisecond)
+	c.Render(ras)
+	test.That(t, errors.Is(ras.Err(), ErrLimitExceeded), ras.Err())
+}
+
+func TestRasterizerFontSizeLimit(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular))
+
+	c := New(10.0, 10.0)
+	ctx := NewContext(c)
+	ctx.DrawText(0.0, 0.0, NewTextLine(family.Face(12.0, Black, FontRegular, FontNormal), "a", Left))
+	_, err := c.WriteImageWithLimits(1.0, Limits{MaxFontSize: 12.0})
+	test.Error(t, err)
+	_, err = c.WriteImageWithLimits(1.0, Limits{MaxFontSize: 11.0})
+	test.That(t, errors.Is(err, ErrLimitExceeded), err)
+}
+
+func TestReadSVGLimits(t *testing.T) {
+	// the group of ten rectangles is drawn five times: once by itself and twice for each time that group b is drawn
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="10" height="10"><g id="a">` + strings.Repeat(`<rect width="1" height="1"/>`, 10) + `</g><g id="b"><use xlink:href="#a"/><use xlink:href="#a"/></g><use xlink:href="#b"/></svg>`
+	_, err := ReadSVGWithOptions(strings.NewReader(svg), SVGOptions{Limits: Limits{MaxSegments: 200}})
+	test.Error(t, err)
+	_, err = ReadSVGWithOptions(strings.NewReader(svg), SVGOptions{Limits: Limits{MaxSegments: 199}})
+	test.That(t, errors.Is(err, ErrLimitExceeded), err)
+
+	_, err = ReadSVGWithOptions(strings.NewReader(svg), SVGOptions{Limits: Limits{Timeout: time.Nanosecond}})
+	test.That(t, errors.Is(err, ErrLimitExceeded), err)
+
+	nested := `<svg xmlns="http://www.w3.org/2000/svg">` + strings.Repeat("<g>", 2000) + strings.Repeat("</g>", 2000) + `</svg>`
+	_, err = ReadSVG(strings.NewReader(nested))
+	test.That(t, err != nil, "nested too deeply")
+
+	buf := &bytes.Buffer{}
+	test.Error(t, png.Encode(buf, image.NewGray(image.Rect(0, 0, 100, 100))))
+	img := `<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"><image href="data:image/png;base64,` + base64.StdEncoding.EncodeToString(buf.Bytes()) + `"/></svg>`
+	_, err = ReadSVGWithOptions(strings.NewReader(img), SVGOptions{Limits: Limits{MaxPixels: 10000}})
+	test.Error(t, err)
+	_, err = ReadSVGWithOptions(strings.NewReader(img), SVGOptions{Limits: Limits{MaxPixels: 9999}})
+	test.That(t, errors.Is(err, ErrLimitExceeded), err)
+
+	// the pixels of all images count together
+	imgs := `<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10">` + strings.Repeat(`<image href="data:image/png;base64,`+base64.StdEncoding.EncodeToString(buf.Bytes())+`"/>`, 3) + `</svg>`
+	_, err = ReadSVGWithOptions(strings.NewReader(imgs), SVGOptions{Limits: Limits{MaxPixels: 30000}})
+	test.Error(t, err)
+	_, err = ReadSVGWithOptions(strings.NewReader(imgs), SVGOptions{Limits: Limits{MaxPixels: 29999}})
+	test.That(t, errors.Is(err, ErrLimitExceeded), err)
+
+	// a curve with a far-away control point is rejected before it is flattened and clipped
+	far := `<svg xmlns="http://www.w3.org/2000/svg" width="100" height="100"><clipPath id="c"><circle cx="50" cy="50" r="40"/></clipPath><mask id="m"><rect width="50" height="100" fill="white"/></mask><path clip-path="url(#c)" mask="url(#m)" d="M0 0C1e12 1e12 -1e12 0 100 100z"/></svg>`
+	start := time.Now()
+	_, err = ReadSVGWithOptions(strings.NewReader(far), SVGOptions{Limits: SafeLimits})
+	test.That(t, errors.Is(err, ErrLimitExceeded), err)
+	test.That(t, time.Since(start) < time.Second, time.Since(start))
+
+	// untrusted input does not panic
+	for _, svg := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg"><path d="M0 0A10 20 30"/></svg>`,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"><clipPath id="c"><rect width="10" height="10"/></clipPath><path clip-path="url(#c)" fill="none" stroke="black" stroke-dasharray="1.96 0.169" d="M2.9311424455385806 2.9708256355629152A1146.0582983941936 1.1460582983941936 70.81889966878848 0 1 1.5832827774512763 6.072534395455154"/></svg>`,
+	} {
+		_, err = ReadSVGWithOptions(strings.NewReader(svg), SVGOptions{Limits: SafeLimits})
+		test.Error(t, err)
+	}
+}
+
+func TestCanvasJSONLimits(t *testing.T) {
+	c := &Canvas{}
+	b := []byte(`{"width":10,"height":10,"layers":[{"path":[["M",0,0],["L",10,0],["L",0,10],["Z"]]}]}`)
+	test.Error(t, c.UnmarshalJSONWithLimits(b, Limits{MaxSegments: 3}))
+	err := c.UnmarshalJSONWithLimits(b, Limits{MaxSegments: 2})
+	test.That(t, errors.Is(err, ErrLimitExceeded), err)
+
+	// images that are each within the limit exceed it together
+	buf := &bytes.Buffer{}
+	test.Error(t, png.Encode(buf, image.NewGray(image.Rect(0, 0, 100, 100))))
+	img := `{"image":"data:image/png;base64,` + base64.StdEncoding.EncodeToString(buf.Bytes()) + `"}`
+	b = []byte(`{"width":10,"height":10,"layers":[` + img + `,` + img + `,` + img + `]}`)
+	test.Error(t, c.UnmarshalJSONWithLimits(b, Limits{MaxPixels: 30000}))
+	err = c.UnmarshalJSONWithLimits(b, Limits{MaxPixels: 29999})
+	test.That(t, errors.Is(err, ErrLimitExceeded), err)
+
+	// the same holds for the images drawn by the rasterizer, apart from the image that is drawn into
+	ras := NewRasterizer(image.NewRGBA(image.Rect(0, 0, 100, 100)), 1.0)
+	ras.SetLimits(Limits{MaxPixels: 29999})
+	test.Error(t, ras.Err())
+	c.Render(ras)
+	test.That(t, errors.Is(ras.Err(), ErrLimitExceeded), ras.Err())
+}
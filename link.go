@@ -0,0 +1,60 @@
+package canvas
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"io"
+	"math"
+)
+
+// LinkRegion is the area of a drawn element with a hyperlink in the pixels of a rendered image, see Canvas.LinkRegions.
+type LinkRegion struct {
+	URL  string
+	Rect image.Rectangle
+}
+
+// LinkRegions returns the areas of the layers with a hyperlink in the pixels of the image of WriteImage at a resolution of dpm (dots-per-millimeter), in drawing order, so that raster output can be made clickable such as by an HTML image map, see WriteImageMap. The areas are the bounds of the layers, see Context.SetLink.
+func (c *Canvas) LinkRegions(dpm float64) []LinkRegion {
+	c.merge()
+	h := int(c.H*dpm + 0.5)
+	regions := []LinkRegion{}
+	for _, l := range c.layers {
+		if l.link == "" {
+			continue
+		}
+		bounds := l.Bounds()
+		x0 := int(math.Floor(bounds.X * dpm))
+		x1 := int(math.Ceil((bounds.X + bounds.W) * dpm))
+		y0 := h - int(math.Ceil((bounds.Y+bounds.H)*dpm))
+		y1 := h - int(math.Floor(bounds.Y*dpm))
+		regions = append(regions, LinkRegion{l.link, image.Rect(x0, y0, x1, y1)})
+	}
+	return regions
+}
+
+// LinkAt returns the hyperlink of the top-most layer with a link that covers the point (x,y) in canvas coordinates, see HitTest, or false if there is none.
+func (c *Canvas) LinkAt(x, y float64) (string, bool) {
+	for _, i := range c.HitTest(x, y) {
+		if link := c.layers[i].link; link != "" {
+			return link, true
+		}
+	}
+	return "", false
+}
+
+// WriteImageMap writes an HTML image map of the given name with the link regions of the image of WriteImage at a resolution of dpm (dots-per-millimeter), see LinkRegions. Overlapping areas link to the top-most element, as areas that come first take precedence.
+func (c *Canvas) WriteImageMap(w io.Writer, name string, dpm float64) error {
+	regions := c.LinkRegions(dpm)
+	if _, err := fmt.Fprintf(w, `<map name="%s">`, html.EscapeString(name)); err != nil {
+		return err
+	}
+	for i := len(regions) - 1; 0 <= i; i-- {
+		r := regions[i].Rect
+		if _, err := fmt.Fprintf(w, `<area shape="rect" coords="%d,%d,%d,%d" href="%s">`, r.Min.X, r.Min.Y, r.Max.X, r.Max.Y, html.EscapeString(regions[i].URL)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "</map>")
+	return err
+}
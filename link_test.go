@@ -0,0 +1,60 @@
+package canvas
+
+import (
+	"bytes"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestCanvasLinks(t *testing.T) {
+	c := New(100, 50)
+	ctx := NewContext(c)
+	ctx.SetLink("https://example.com/?a=1&b=2")
+	ctx.DrawPath(10.0, 10.0, Rectangle(20.0, 10.0))
+	ctx.SetLink("")
+	ctx.DrawPath(15.0, 15.0, Rectangle(5.0, 5.0))
+	test.T(t, c.layers[0].link, "https://example.com/?a=1&b=2")
+	test.T(t, c.layers[1].link, "")
+
+	test.T(t, c.LinkRegions(2.0), []LinkRegion{{"https://example.com/?a=1&b=2", image.Rect(20, 60, 60, 80)}})
+	link, ok := c.LinkAt(12.0, 12.0)
+	test.That(t, ok)
+	test.T(t, link, "https://example.com/?a=1&b=2")
+	_, ok = c.LinkAt(50.0, 12.0)
+	test.That(t, !ok)
+	_, ok = c.LinkAt(16.0, 16.0) // covered by the element without a link
+	test.That(t, ok)
+
+	buf := &bytes.Buffer{}
+	test.Error(t, c.WriteImageMap(buf, "figure", 2.0))
+	test.String(t, buf.String(), `<map name="figure"><area shape="rect" coords="20,60,60,80" href="https://example.com/?a=1&amp;b=2"></map>`)
+}
+
+func TestSVGLinks(t *testing.T) {
+	c := New(100, 50)
+	ctx := NewContext(c)
+	ctx.SetLink("https://example.com/")
+	ctx.DrawPath(10.0, 10.0, Rectangle(20.0, 10.0))
+
+	buf := &bytes.Buffer{}
+	svg := NewSVG(buf, c.W, c.H)
+	c.Render(svg)
+	test.Error(t, svg.Close())
+	test.That(t, strings.Contains(buf.String(), `<a xlink:href="https://example.com/"><path d="M10 40H30V30H10z"/></a>`), buf.String())
+}
+
+func TestPDFLinks(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf := NewPDF(buf, 100.0, 50.0)
+	pdf.SetCompression(false)
+	ctx := NewContext(pdf)
+	ctx.SetLink("https://example.com/")
+	ctx.DrawPath(10.0, 10.0, Rectangle(20.0, 10.0))
+	test.Error(t, pdf.Close())
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("/Subtype /Link")), buf.String())
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("/Rect [28.346457 28.346457 85.03937 56.692913]")), buf.String())
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("/URI (https://example.com/)")), buf.String())
+}
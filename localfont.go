@@ -0,0 +1,199 @@
+package canvas
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	canvasFont "github.com/tdewolff/canvas/font"
+	"golang.org/x/image/font/sfnt"
+)
+
+// localFont is a font of the system fonts location, with the names and the style of its name and OS/2 tables.
+type localFont struct {
+	filename string
+	index    int      // of the font in a collection
+	families []string // typographic and legacy family names, normalized
+	names    []string // full and PostScript names, normalized
+	style    FontStyle
+}
+
+var systemFontsOnce sync.Once
+var systemFonts []localFont
+
+// findLocalFont returns the filename and the index in a collection of the system font that matches the name and style best. It asks fontconfig if it is installed, and otherwise scans the font directories of the operating system, which are scanned once per process.
+func findLocalFont(name string, style FontStyle) (string, int, error) {
+	if _, err := exec.LookPath("fc-match"); err == nil {
+		return matchFontconfig(name, style)
+	}
+	systemFontsOnce.Do(func() {
+		systemFonts = scanLocalFonts(systemFontDirs())
+	})
+	font, ok := matchLocalFont(systemFonts, name, style)
+	if !ok {
+		return "", 0, fmt.Errorf("font %s not found", name)
+	}
+	return font.filename, font.index, nil
+}
+
+// matchFontconfig returns the filename and the index in a collection of the closest font of fontconfig.
+func matchFontconfig(name string, style FontStyle) (string, int, error) {
+	match := name
+	if style&FontItalic == FontItalic {
+		match += ":italic"
+	}
+	if style&FontExtraLight == FontExtraLight {
+		match += ":weight=40"
+	} else if style&FontLight == FontLight {
+		match += ":weight=50"
+	} else if style&FontBook == FontBook {
+		match += ":weight=75"
+	} else if style&FontMedium == FontMedium {
+		match += ":weight=100"
+	} else if style&FontSemibold == FontSemibold {
+		match += ":weight=180"
+	} else if style&FontBold == FontBold {
+		match += ":weight=200"
+	} else if style&FontBlack == FontBlack {
+		match += ":weight=205"
+	} else if style&FontExtraBlack == FontExtraBlack {
+		match += ":weight=210"
+	}
+	b, err := exec.Command("fc-match", "--format=%{index}\n%{file}", match).Output()
+	if err != nil {
+		return "", 0, err
+	}
+	fields := strings.SplitN(string(b), "\n", 2)
+	if len(fields) != 2 {
+		return "", 0, fmt.Errorf("font %s not found", name)
+	}
+	index, _ := strconv.Atoi(fields[0])
+	return fields[1], index, nil
+}
+
+// systemFontDirs returns the directories of the system and user fonts of the operating system.
+func systemFontDirs() []string {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "windows":
+		return []string{
+			filepath.Join(os.Getenv("WINDIR"), "Fonts"),
+			filepath.Join(os.Getenv("LOCALAPPDATA"), "Microsoft", "Windows", "Fonts"),
+		}
+	case "darwin", "ios":
+		return []string{
+			"/System/Library/Fonts",
+			"/Library/Fonts",
+			filepath.Join(home, "Library", "Fonts"),
+		}
+	}
+	dirs := []string{"/usr/share/fonts", "/usr/local/share/fonts"}
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		dirs = append(dirs, filepath.Join(dataHome, "fonts"))
+	} else if home != "" {
+		dirs = append(dirs, filepath.Join(home, ".local", "share", "fonts"))
+	}
+	if home != "" {
+		dirs = append(dirs, filepath.Join(home, ".fonts"))
+	}
+	return dirs
+}
+
+// scanLocalFonts returns the TrueType and OpenType fonts and collections in the directories and their subdirectories, where unreadable files and broken fonts are skipped.
+func scanLocalFonts(dirs []string) []localFont {
+	fonts := []localFont{}
+	buffer := &sfnt.Buffer{}
+	for _, dir := range dirs {
+		filepath.Walk(dir, func(filename string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(filename)) {
+			case ".ttf", ".otf", ".ttc", ".otc":
+			default:
+				return nil
+			}
+			b, err := ioutil.ReadFile(filename)
+			if err != nil {
+				return nil
+			}
+			n := 1
+			mimetype, _ := canvasFont.Mimetype(b)
+			if mimetype == "font/collection" {
+				if n, err = canvasFont.NumCollectionFonts(b); err != nil {
+					return nil
+				}
+			}
+			for index := 0; index < n; index++ {
+				sfntBytes := b
+				if mimetype == "font/collection" {
+					if sfntBytes, err = canvasFont.ParseCollection(b, index); err != nil {
+						continue
+					}
+				}
+				f, err := sfnt.Parse(sfntBytes)
+				if err != nil {
+					continue
+				}
+				font := localFont{
+					filename: filename,
+					index:    index,
+					style:    parseFontStyle(sfntBytes),
+				}
+				for _, id := range []sfnt.NameID{sfnt.NameIDTypographicFamily, sfnt.NameIDFamily} {
+					if name, err := f.Name(buffer, id); err == nil && name != "" {
+						font.families = append(font.families, normalizeFontName(name))
+					}
+				}
+				for _, id := range []sfnt.NameID{sfnt.NameIDFull, sfnt.NameIDPostScript} {
+					if name, err := f.Name(buffer, id); err == nil && name != "" {
+						font.names = append(font.names, normalizeFontName(name))
+					}
+				}
+				fonts = append(fonts, font)
+			}
+			return nil
+		})
+	}
+	return fonts
+}
+
+// matchLocalFont returns the font of the family of the given name with the closest style, see FontFamily.Face, or the font of the given full or PostScript name. It returns false if no font has the name.
+func matchLocalFont(fonts []localFont, name string, style FontStyle) (localFont, bool) {
+	name = normalizeFontName(name)
+	match, ok := localFont{}, false
+	for _, font := range fonts {
+		for _, family := range font.families {
+			if family == name && (!ok || styleDistance(font.style, style) < styleDistance(match.style, style)) {
+				match, ok = font, true
+			}
+		}
+	}
+	if ok {
+		return match, true
+	}
+	for _, font := range fonts {
+		for _, fullName := range font.names {
+			if fullName == name {
+				return font, true
+			}
+		}
+	}
+	return localFont{}, false
+}
+
+// normalizeFontName returns the name in lower case without spaces, hyphens, and underscores, so that "DejaVu Sans" matches "DejaVuSans" and "dejavu-sans".
+func normalizeFontName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '_' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(name))
+}
@@ -0,0 +1,54 @@
+package canvas
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestScanLocalFonts(t *testing.T) {
+	fonts := scanLocalFonts([]string{"font"})
+	test.T(t, len(fonts), 2)
+
+	font, ok := matchLocalFont(fonts, "DejaVu Serif", FontBold|FontItalic)
+	test.That(t, ok)
+	test.T(t, font.filename, filepath.Join("font", "DejaVuSerif.ttf"))
+	test.T(t, font.style, FontRegular)
+
+	font, ok = matchLocalFont(fonts, "EBGaramond12-Regular", FontRegular)
+	test.That(t, ok)
+	test.T(t, font.filename, filepath.Join("font", "EBGaramond12-Regular.otf"))
+
+	_, ok = matchLocalFont(fonts, "DejaVu Sans", FontRegular)
+	test.That(t, !ok)
+}
+
+func TestMatchLocalFont(t *testing.T) {
+	fonts := []localFont{
+		{filename: "Sans.ttf", families: []string{"sans"}, names: []string{"sans", "sansregular"}, style: FontRegular},
+		{filename: "Sans-Bold.ttf", families: []string{"sans"}, names: []string{"sansbold"}, style: FontBold},
+		{filename: "Sans-BoldItalic.ttf", families: []string{"sans"}, names: []string{"sansbolditalic"}, style: FontBold | FontItalic},
+		{filename: "Sans-Light.ttf", families: []string{"sans", "sanslight"}, names: []string{"sanslight"}, style: FontLight},
+	}
+	var tests = []struct {
+		name     string
+		style    FontStyle
+		filename string
+	}{
+		{"Sans", FontRegular, "Sans.ttf"},
+		{"Sans", FontBold | FontItalic, "Sans-BoldItalic.ttf"},
+		{"Sans", FontItalic, "Sans-BoldItalic.ttf"},
+		{"Sans", FontSemibold, "Sans-Bold.ttf"},
+		{"Sans", FontBook, "Sans-Light.ttf"},
+		{"Sans Light", FontRegular, "Sans-Light.ttf"},
+		{"Sans-Bold", FontRegular, "Sans-Bold.ttf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			font, ok := matchLocalFont(fonts, tt.name, tt.style)
+			test.That(t, ok)
+			test.T(t, font.filename, tt.filename)
+		})
+	}
+}
@@ -0,0 +1,462 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"sort"
+)
+
+// LottieProperty is a property of a layer of the canvas that is animated by keyframes in a Lottie animation. The type of the keyframe values is given for each property.
+type LottieProperty int
+
+// see LottieProperty
+const (
+	LottiePosition    LottieProperty = iota // Point, offset of the layer in millimeters
+	LottieScale                             // Point, scale factors of the layer around its origin
+	LottieRotation                          // float64, counter clockwise rotation of the layer around its origin in degrees
+	LottieOpacity                           // float64, opacity of the layer between 0 and 1
+	LottieFillColor                         // color.Color, fill color of paths and text
+	LottieStrokeColor                       // color.Color, stroke color of paths
+	LottieStrokeWidth                       // float64, stroke width of paths in millimeters
+	LottiePath                              // *Path, shape of paths in the same coordinates as the path drawn, with the same number of subpaths
+)
+
+var lottiePropertyNames = []string{"position", "scale", "rotation", "opacity", "fill color", "stroke color", "stroke width", "path"}
+
+func (p LottieProperty) String() string {
+	if 0 <= int(p) && int(p) < len(lottiePropertyNames) {
+		return lottiePropertyNames[p]
+	}
+	return fmt.Sprintf("LottieProperty(%d)", int(p))
+}
+
+// Easing is a timing function between two keyframes, given by the control points (x1,y1) and (x2,y2) of a cubic Bézier from (0,0) to (1,1) such as the CSS cubic-bezier function.
+type Easing [4]float64
+
+// see Easing
+var (
+	LinearEasing = Easing{0.0, 0.0, 1.0, 1.0}
+	EaseIn       = Easing{0.42, 0.0, 1.0, 1.0}
+	EaseOut      = Easing{0.0, 0.0, 0.58, 1.0}
+	EaseInOut    = Easing{0.42, 0.0, 0.58, 1.0}
+)
+
+// Keyframe is the value of an animated property at a frame. The value is interpolated towards the next keyframe with Easing, which is linear when zero, or held until the next keyframe if Hold is set.
+type Keyframe struct {
+	Frame  float64
+	Value  interface{}
+	Easing Easing
+	Hold   bool
+}
+
+// lottieVersion is the version of the Lottie format that is written.
+const lottieVersion = "5.7.0"
+
+type lottieAnimation struct {
+	layer     int
+	property  LottieProperty
+	keyframes []Keyframe
+}
+
+// Lottie exports the layers of a canvas as a Lottie (bodymovin) animation in JSON, which is played by the Lottie players for web and mobile. Each drawing operation of the canvas becomes a layer that is still unless its properties are animated by keyframes. Paths become shape layers, text is converted to paths, and images are embedded as PNG. The animation is Frames frames long at FPS frames per second, and DPM (dots-per-millimeter) is the number of pixels of the animation per millimeter of the canvas. Lottie does not support all stroke joins, which are approximated by the closest join.
+type Lottie struct {
+	Name   string
+	FPS    float64
+	Frames int
+	DPM    float64
+
+	canvas     *Canvas
+	animations []lottieAnimation
+}
+
+// NewLottie returns a Lottie animation of the canvas of frames frames at fps frames per second, at 96 DPI.
+func NewLottie(c *Canvas, fps float64, frames int) *Lottie {
+	return &Lottie{
+		FPS:    fps,
+		Frames: frames,
+		DPM:    96.0 * inchPerMm,
+		canvas: c,
+	}
+}
+
+// Animate animates a property of the layer with the given index, which is the index of the drawing operation on the canvas, by the keyframes. It returns an error if the layer does not exist, if the property does not apply to the layer, or if a value has the wrong type.
+func (l *Lottie) Animate(index int, property LottieProperty, keyframes ...Keyframe) error {
+	l.canvas.merge()
+	if index < 0 || len(l.canvas.layers) <= index {
+		return fmt.Errorf("lottie: layer %d does not exist", index)
+	}
+	lay := l.canvas.layers[index]
+	if property == LottieFillColor && lay.img != nil || (property == LottieStrokeColor || property == LottieStrokeWidth || property == LottiePath) && lay.path == nil {
+		return fmt.Errorf("lottie: layer %d does not have a %v", index, property)
+	}
+
+	n := 0
+	if lay.path != nil {
+		n = len(lay.path.Split())
+	}
+	for _, keyframe := range keyframes {
+		ok := false
+		switch property {
+		case LottiePosition, LottieScale:
+			_, ok = keyframe.Value.(Point)
+		case LottieRotation, LottieOpacity, LottieStrokeWidth:
+			_, ok = keyframe.Value.(float64)
+		case LottieFillColor, LottieStrokeColor:
+			_, ok = keyframe.Value.(color.Color)
+		case LottiePath:
+			var p *Path
+			if p, ok = keyframe.Value.(*Path); ok && len(p.Split()) != n {
+				return fmt.Errorf("lottie: layer %d: path at frame %v has %d subpaths instead of %d", index, keyframe.Frame, len(p.Split()), n)
+			}
+		default:
+			return fmt.Errorf("lottie: unknown property %v", property)
+		}
+		if !ok {
+			return fmt.Errorf("lottie: layer %d: bad %v value %T at frame %v", index, property, keyframe.Value, keyframe.Frame)
+		}
+	}
+
+	keyframes = append([]Keyframe{}, keyframes...)
+	sort.SliceStable(keyframes, func(i, j int) bool { return keyframes[i].Frame < keyframes[j].Frame })
+	l.animations = append(l.animations, lottieAnimation{index, property, keyframes})
+	return nil
+}
+
+// Save saves the animation to a JSON file.
+func (l *Lottie) Save(filename string) error {
+	b, err := l.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// MarshalJSON encodes the animation as Lottie JSON.
+func (l *Lottie) MarshalJSON() ([]byte, error) {
+	l.canvas.merge()
+	assets := []interface{}{}
+	layers := []interface{}{}
+	for i := len(l.canvas.layers) - 1; 0 <= i; i-- {
+		// the first layer of Lottie is drawn on top
+		layer, asset, err := l.layer(i)
+		if err != nil {
+			return nil, err
+		} else if asset != nil {
+			assets = append(assets, asset)
+		}
+		layers = append(layers, layer)
+	}
+	return json.Marshal(map[string]interface{}{
+		"v":      lottieVersion,
+		"nm":     l.Name,
+		"fr":     l.FPS,
+		"ip":     0,
+		"op":     l.Frames,
+		"w":      int(l.canvas.W*l.DPM + 0.5),
+		"h":      int(l.canvas.H*l.DPM + 0.5),
+		"ddd":    0,
+		"assets": assets,
+		"layers": layers,
+	})
+}
+
+// view returns the transformation from canvas coordinates to pixels of the animation, with the y-axis pointing down.
+func (l *Lottie) view() Matrix {
+	return Identity.Scale(l.DPM, -l.DPM).Translate(0.0, -l.canvas.H)
+}
+
+// keyframes returns the keyframes of the property of the layer, or nil if it is not animated.
+func (l *Lottie) keyframes(index int, property LottieProperty) []Keyframe {
+	for i := len(l.animations) - 1; 0 <= i; i-- {
+		if a := l.animations[i]; a.layer == index && a.property == property {
+			return a.keyframes
+		}
+	}
+	return nil
+}
+
+// value returns a static value, or the animated value of the keyframes where each value is converted by f.
+func (l *Lottie) value(static interface{}, keyframes []Keyframe, f func(interface{}) interface{}) map[string]interface{} {
+	if len(keyframes) == 0 {
+		return map[string]interface{}{"a": 0, "k": static}
+	}
+	ks := make([]interface{}, len(keyframes))
+	for i, keyframe := range keyframes {
+		v := f(keyframe.Value)
+		switch v.(type) {
+		case []float64, []interface{}:
+		default:
+			v = []interface{}{v}
+		}
+		k := map[string]interface{}{"t": keyframe.Frame, "s": v}
+		if i+1 < len(keyframes) {
+			if keyframe.Hold {
+				k["h"] = 1
+			} else {
+				easing := keyframe.Easing
+				if easing == (Easing{}) {
+					easing = LinearEasing
+				}
+				k["o"] = map[string]interface{}{"x": []float64{easing[0]}, "y": []float64{easing[1]}}
+				k["i"] = map[string]interface{}{"x": []float64{easing[2]}, "y": []float64{easing[3]}}
+			}
+		}
+		ks[i] = k
+	}
+	return map[string]interface{}{"a": 1, "k": ks}
+}
+
+// layer returns the Lottie layer of the canvas layer with the given index, and its asset for images.
+func (l *Lottie) layer(index int) (map[string]interface{}, map[string]interface{}, error) {
+	lay := l.canvas.layers[index]
+	view := l.view()
+
+	// the layer is transformed around its origin, with L the linear part of the transformation from the layer to the animation
+	anchor := Point{}
+	L := Identity
+	if lay.img != nil {
+		size := lay.img.Bounds().Size()
+		anchor = Point{0.0, float64(size.Y)}
+		L = view.Mul(lay.m).Mul(Identity.Translate(0.0, float64(size.Y)).Scale(1.0, -1.0))
+	}
+	origin := view.Dot(lay.m.Dot(Point{}))
+	sx := math.Hypot(L[0][0], L[1][0])
+	sy := L.Det() / sx
+	rot := math.Atan2(L[1][0], L[0][0]) * 180.0 / math.Pi // clockwise
+
+	ks := map[string]interface{}{
+		"a": l.value(lottiePoint(anchor), nil, nil),
+		"p": l.value(lottiePoint(origin), l.keyframes(index, LottiePosition), func(v interface{}) interface{} {
+			d := v.(Point)
+			return lottiePoint(origin.Add(Point{d.X * l.DPM, -d.Y * l.DPM}))
+		}),
+		"s": l.value([]interface{}{lottieNum(100.0 * sx), lottieNum(100.0 * sy), 100}, l.keyframes(index, LottieScale), func(v interface{}) interface{} {
+			s := v.(Point)
+			return []interface{}{lottieNum(100.0 * sx * s.X), lottieNum(100.0 * sy * s.Y), 100}
+		}),
+		"r": l.value(lottieNum(rot), l.keyframes(index, LottieRotation), func(v interface{}) interface{} {
+			return lottieNum(rot - v.(float64))
+		}),
+		"o": l.value(100, l.keyframes(index, LottieOpacity), func(v interface{}) interface{} {
+			return lottieNum(100.0 * v.(float64))
+		}),
+	}
+	layer := map[string]interface{}{
+		"ddd": 0,
+		"ind": index + 1,
+		"nm":  fmt.Sprintf("Layer %d", index),
+		"sr":  1,
+		"ks":  ks,
+		"ao":  0,
+		"ip":  0,
+		"op":  l.Frames,
+		"st":  0,
+		"bm":  0,
+	}
+
+	if lay.img != nil {
+		b := &bytes.Buffer{}
+		if err := png.Encode(b, lay.img); err != nil {
+			return nil, nil, err
+		}
+		size := lay.img.Bounds().Size()
+		id := fmt.Sprintf("image_%d", index)
+		layer["ty"] = 2
+		layer["refId"] = id
+		return layer, map[string]interface{}{
+			"id": id,
+			"w":  size.X,
+			"h":  size.Y,
+			"u":  "",
+			"p":  "data:image/png;base64," + base64.StdEncoding.EncodeToString(b.Bytes()),
+			"e":  1,
+		}, nil
+	}
+
+	m := view.Mul(lay.m)
+	shapes := []interface{}{}
+	if lay.path != nil {
+		shapes = append(shapes, l.pathGroup(index, lay.path, lay.style, m))
+	} else if lay.text != nil {
+		paths, colors := lay.text.ToPaths()
+		for i, path := range paths {
+			style := DefaultStyle
+			style.FillColor = colors[i]
+			shapes = append(shapes, l.pathGroup(index, path, style, m))
+		}
+	}
+	layer["ty"] = 4
+	layer["shapes"] = shapes
+	return layer, nil, nil
+}
+
+// pathGroup returns a group of shapes that draws the path with the style, with the path transformed by m.
+func (l *Lottie) pathGroup(index int, path *Path, style Style, m Matrix) map[string]interface{} {
+	items := []interface{}{}
+	morphs := l.keyframes(index, LottiePath)
+	for i, ps := range path.Split() {
+		items = append(items, map[string]interface{}{
+			"ty": "sh",
+			"ks": l.value(lottieBezier(ps, m), morphs, func(v interface{}) interface{} {
+				return []interface{}{lottieBezier(v.(*Path).Split()[i], m)}
+			}),
+		})
+	}
+
+	fillColors := l.keyframes(index, LottieFillColor)
+	if style.FillColor.A != 0 || fillColors != nil {
+		rule := 1
+		if style.FillRule == EvenOdd {
+			rule = 2
+		}
+		items = append(items, map[string]interface{}{
+			"ty": "fl",
+			"c":  l.value(lottieColor(style.FillColor), fillColors, func(v interface{}) interface{} { return lottieColor(v.(color.Color)) }),
+			"o":  l.value(lottieAlpha(style.FillColor), fillColors, func(v interface{}) interface{} { return lottieAlpha(v.(color.Color)) }),
+			"r":  rule,
+		})
+	}
+
+	strokeColors, strokeWidths := l.keyframes(index, LottieStrokeColor), l.keyframes(index, LottieStrokeWidth)
+	if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth || strokeColors != nil || strokeWidths != nil {
+		lc := 1
+		if _, ok := style.StrokeCapper.(RoundCapper); ok {
+			lc = 2
+		} else if _, ok := style.StrokeCapper.(SquareCapper); ok {
+			lc = 3
+		}
+		lj, ml := 1, 4.0
+		if _, ok := style.StrokeJoiner.(BevelJoiner); ok {
+			lj = 3
+		} else if _, ok := style.StrokeJoiner.(RoundJoiner); ok {
+			lj = 2
+		} else if arcs, ok := style.StrokeJoiner.(ArcsJoiner); ok && !math.IsNaN(arcs.Limit) {
+			ml = arcs.Limit
+		} else if miter, ok := style.StrokeJoiner.(MiterJoiner); ok && !math.IsNaN(miter.Limit) && 0.0 < style.StrokeWidth {
+			ml = miter.Limit * 2.0 / style.StrokeWidth
+		}
+		stroke := map[string]interface{}{
+			"ty": "st",
+			"c":  l.value(lottieColor(style.StrokeColor), strokeColors, func(v interface{}) interface{} { return lottieColor(v.(color.Color)) }),
+			"o":  l.value(lottieAlpha(style.StrokeColor), strokeColors, func(v interface{}) interface{} { return lottieAlpha(v.(color.Color)) }),
+			"w":  l.value(lottieNum(style.StrokeWidth*l.DPM), strokeWidths, func(v interface{}) interface{} { return lottieNum(v.(float64) * l.DPM) }),
+			"lc": lc,
+			"lj": lj,
+			"ml": lottieNum(ml),
+		}
+		if 0 < len(style.Dashes) {
+			dashes := style.Dashes
+			if len(dashes)%2 == 1 {
+				dashes = append(dashes, dashes...)
+			}
+			d := []interface{}{}
+			for i, dash := range dashes {
+				n := "d"
+				if i%2 == 1 {
+					n = "g"
+				}
+				d = append(d, map[string]interface{}{"n": n, "v": l.value(lottieNum(dash*l.DPM), nil, nil)})
+			}
+			d = append(d, map[string]interface{}{"n": "o", "v": l.value(lottieNum(style.DashOffset*l.DPM), nil, nil)})
+			stroke["d"] = d
+		}
+		items = append(items, stroke)
+	}
+
+	items = append(items, map[string]interface{}{
+		"ty": "tr",
+		"p":  l.value([]float64{0.0, 0.0}, nil, nil),
+		"a":  l.value([]float64{0.0, 0.0}, nil, nil),
+		"s":  l.value([]float64{100.0, 100.0}, nil, nil),
+		"r":  l.value(0, nil, nil),
+		"o":  l.value(100, nil, nil),
+	})
+	return map[string]interface{}{
+		"ty": "gr",
+		"it": items,
+	}
+}
+
+// lottieBezier returns the vertices and the incoming and outgoing tangents, relative to the vertices, of a subpath transformed by m, with arcs and quadratic Béziers converted to cubic Béziers.
+func lottieBezier(p *Path, m Matrix) map[string]interface{} {
+	p = p.Transform(m).ReplaceArcs()
+	vs, is, outs := []Point{}, []Point{}, []Point{}
+	closed := false
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		end := Point{p.d[i+cmdLen(cmd)-3], p.d[i+cmdLen(cmd)-2]}
+		switch cmd {
+		case moveToCmd, lineToCmd:
+			vs, is, outs = append(vs, end), append(is, Point{}), append(outs, Point{})
+		case quadToCmd:
+			start := vs[len(vs)-1]
+			c := Point{p.d[i+1], p.d[i+2]}
+			outs[len(outs)-1] = c.Sub(start).Mul(2.0 / 3.0)
+			vs, is, outs = append(vs, end), append(is, c.Sub(end).Mul(2.0/3.0)), append(outs, Point{})
+		case cubeToCmd:
+			c1, c2 := Point{p.d[i+1], p.d[i+2]}, Point{p.d[i+3], p.d[i+4]}
+			outs[len(outs)-1] = c1.Sub(vs[len(vs)-1])
+			vs, is, outs = append(vs, end), append(is, c2.Sub(end)), append(outs, Point{})
+		case closeCmd:
+			closed = true
+		}
+		i += cmdLen(cmd)
+	}
+	if closed && 1 < len(vs) && vs[0].Equals(vs[len(vs)-1]) {
+		// the path returns to its start, which is implicit for closed shapes
+		is[0] = is[len(is)-1]
+		vs, is, outs = vs[:len(vs)-1], is[:len(is)-1], outs[:len(outs)-1]
+	}
+
+	points := func(ps []Point) [][]float64 {
+		a := make([][]float64, len(ps))
+		for i, p := range ps {
+			a[i] = []float64{lottieNum(p.X), lottieNum(p.Y)}
+		}
+		return a
+	}
+	return map[string]interface{}{
+		"c": closed,
+		"v": points(vs),
+		"i": points(is),
+		"o": points(outs),
+	}
+}
+
+// lottieColor returns the color as non-premultiplied RGBA between 0 and 1, where the alpha is given separately by the opacity.
+func lottieColor(c color.Color) []float64 {
+	col := color.RGBAModel.Convert(c).(color.RGBA)
+	if col.A == 0 {
+		return []float64{0.0, 0.0, 0.0, 1.0}
+	}
+	a := float64(col.A)
+	return []float64{lottieNum(float64(col.R) / a), lottieNum(float64(col.G) / a), lottieNum(float64(col.B) / a), 1.0}
+}
+
+// lottieAlpha returns the alpha of the color as an opacity between 0 and 100.
+func lottieAlpha(c color.Color) float64 {
+	return lottieNum(100.0 * float64(color.RGBAModel.Convert(c).(color.RGBA).A) / 255.0)
+}
+
+func lottiePoint(p Point) []float64 {
+	return []float64{lottieNum(p.X), lottieNum(p.Y)}
+}
+
+// lottieNum rounds a number to three decimals to keep the JSON small.
+func lottieNum(f float64) float64 {
+	return math.Round(f*1000.0) / 1000.0
+}
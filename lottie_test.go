@@ -0,0 +1,114 @@
+package canvas
+
+import (
+	"encoding/json"
+	"image"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func lottieJSON(t *testing.T, l *Lottie) map[string]interface{} {
+	b, err := l.MarshalJSON()
+	test.Error(t, err)
+	root := map[string]interface{}{}
+	test.Error(t, json.Unmarshal(b, &root))
+	return root
+}
+
+func TestLottie(t *testing.T) {
+	c := New(10.0, 20.0)
+	ctx := NewContext(c)
+	ctx.SetFillColor(Red)
+	ctx.DrawPath(2.0, 3.0, Rectangle(4.0, 5.0))
+	ctx.DrawImage(1.0, 1.0, image.NewRGBA(image.Rect(0, 0, 2, 4)), 2.0)
+
+	l := NewLottie(c, 30.0, 60)
+	l.DPM = 10.0
+	root := lottieJSON(t, l)
+	test.T(t, root["fr"], 30.0)
+	test.T(t, root["op"], 60.0)
+	test.T(t, root["w"], 100.0)
+	test.T(t, root["h"], 200.0)
+
+	// the first layer is drawn on top
+	layers := root["layers"].([]interface{})
+	test.T(t, len(layers), 2)
+	image := layers[0].(map[string]interface{})
+	test.T(t, image["ty"], 2.0)
+	test.T(t, image["refId"], "image_1")
+	test.String(t, lottieEncode(image["ks"]), `{"a":{"a":0,"k":[0,4]},"o":{"a":0,"k":100},"p":{"a":0,"k":[10,190]},"r":{"a":0,"k":0},"s":{"a":0,"k":[500,500,100]}}`)
+	asset := root["assets"].([]interface{})[0].(map[string]interface{})
+	test.T(t, asset["id"], "image_1")
+	test.T(t, asset["w"], 2.0)
+	test.T(t, asset["h"], 4.0)
+
+	shape := layers[1].(map[string]interface{})
+	test.T(t, shape["ty"], 4.0)
+	test.T(t, shape["ind"], 1.0)
+	items := shape["shapes"].([]interface{})[0].(map[string]interface{})["it"].([]interface{})
+	test.T(t, len(items), 3)
+	test.String(t, lottieEncode(items[0]), `{"ks":{"a":0,"k":{"c":true,"i":[[0,0],[0,0],[0,0],[0,0]],"o":[[0,0],[0,0],[0,0],[0,0]],"v":[[20,170],[60,170],[60,120],[20,120]]}},"ty":"sh"}`)
+	test.String(t, lottieEncode(items[1]), `{"c":{"a":0,"k":[1,0,0,1]},"o":{"a":0,"k":100},"r":1,"ty":"fl"}`)
+	test.T(t, items[2].(map[string]interface{})["ty"], "tr")
+}
+
+func TestLottieBezier(t *testing.T) {
+	p := &Path{}
+	p.MoveTo(0.0, 0.0)
+	p.QuadTo(3.0, 3.0, 6.0, 0.0)
+	p.CubeTo(6.0, -1.0, 1.0, -1.0, 0.0, 0.0)
+	p.Close()
+	test.String(t, lottieEncode(lottieBezier(p, Identity)), `{"c":true,"i":[[1,-1],[-2,2]],"o":[[2,2],[0,-1]],"v":[[0,0],[6,0]]}`)
+
+	p = &Path{}
+	p.MoveTo(0.0, 0.0)
+	p.LineTo(1.0, 0.0)
+	test.String(t, lottieEncode(lottieBezier(p, Identity.Scale(2.0, 2.0))), `{"c":false,"i":[[0,0],[0,0]],"o":[[0,0],[0,0]],"v":[[0,0],[2,0]]}`)
+}
+
+func TestLottieAnimate(t *testing.T) {
+	c := New(10.0, 10.0)
+	ctx := NewContext(c)
+	ctx.SetStrokeColor(Black)
+	ctx.SetStrokeWidth(0.5)
+	ctx.DrawPath(5.0, 5.0, Rectangle(1.0, 1.0))
+
+	l := NewLottie(c, 25.0, 50)
+	l.DPM = 10.0
+	test.Error(t, l.Animate(0, LottiePosition, Keyframe{Frame: 0.0, Value: Point{0.0, 0.0}, Easing: EaseInOut}, Keyframe{Frame: 50.0, Value: Point{2.0, 1.0}}))
+	test.Error(t, l.Animate(0, LottieRotation, Keyframe{Frame: 25.0, Value: 90.0, Hold: true}, Keyframe{Frame: 0.0, Value: 0.0}))
+	test.Error(t, l.Animate(0, LottieStrokeWidth, Keyframe{Frame: 0.0, Value: 1.0}, Keyframe{Frame: 10.0, Value: 2.0}))
+
+	layer := lottieJSON(t, l)["layers"].([]interface{})[0].(map[string]interface{})
+	ks := layer["ks"].(map[string]interface{})
+	test.String(t, lottieEncode(ks["p"]), `{"a":1,"k":[{"i":{"x":[0.58],"y":[1]},"o":{"x":[0.42],"y":[0]},"s":[50,50],"t":0},{"s":[70,40],"t":50}]}`)
+	test.String(t, lottieEncode(ks["r"]), `{"a":1,"k":[{"i":{"x":[1],"y":[1]},"o":{"x":[0],"y":[0]},"s":[0],"t":0},{"s":[-90],"t":25}]}`)
+
+	items := layer["shapes"].([]interface{})[0].(map[string]interface{})["it"].([]interface{})
+	stroke := items[2].(map[string]interface{})
+	test.T(t, stroke["ty"], "st")
+	test.T(t, stroke["lc"], 1.0)
+	test.T(t, stroke["lj"], 1.0)
+	test.String(t, lottieEncode(stroke["w"]), `{"a":1,"k":[{"i":{"x":[1],"y":[1]},"o":{"x":[0],"y":[0]},"s":[10],"t":0},{"s":[20],"t":10}]}`)
+}
+
+func TestLottieAnimateErrors(t *testing.T) {
+	c := New(10.0, 10.0)
+	ctx := NewContext(c)
+	ctx.DrawPath(0.0, 0.0, Rectangle(1.0, 1.0))
+	ctx.DrawImage(0.0, 0.0, image.NewRGBA(image.Rect(0, 0, 1, 1)), 1.0)
+
+	l := NewLottie(c, 25.0, 50)
+	test.That(t, l.Animate(2, LottieOpacity) != nil, "layer does not exist")
+	test.That(t, l.Animate(0, LottieOpacity, Keyframe{Value: 1}) != nil, "opacity must be a float")
+	test.That(t, l.Animate(0, LottieFillColor, Keyframe{Value: 0.5}) != nil, "fill color must be a color")
+	test.That(t, l.Animate(1, LottieFillColor, Keyframe{Value: Red}) != nil, "images have no fill")
+	test.That(t, l.Animate(0, LottiePath, Keyframe{Value: Rectangle(1.0, 1.0).Append(Circle(1.0))}) != nil, "number of subpaths must match")
+	test.Error(t, l.Animate(0, LottiePath, Keyframe{Value: Circle(1.0)}))
+}
+
+func lottieEncode(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,197 @@
+package canvas
+
+import (
+	"image/color"
+	"math"
+)
+
+// MeshPatch is a Coons patch of a mesh gradient, which is a surface bounded by four cubic Béziers that is colored by interpolating the colors of its corners. Points are the control points of the Béziers around the boundary, where Points[0], Points[3], Points[6], and Points[9] are the corners, and each Bézier goes from a corner to the next by the two control points in between, the last returning to Points[0]. Colors are the colors of the corners in the same order. This is the order of the points and colors of Coons patches in PDF.
+type MeshPatch struct {
+	Points [12]Point
+	Colors [4]color.RGBA
+}
+
+// MeshGradient is a mesh gradient of Coons patches that paints smooth transitions between the colors of many points, see Context.SetFillMesh. The patches are in the coordinates of the paths that they fill, and areas that are not covered by a patch are not painted.
+type MeshGradient struct {
+	Patches []MeshPatch
+}
+
+// NewMeshGradient returns an empty mesh gradient.
+func NewMeshGradient() *MeshGradient {
+	return &MeshGradient{}
+}
+
+// NewMeshGradientGrid returns a mesh gradient of a grid of patches with straight edges that covers a rectangle, where colors[j][i] is the color of the i-th point from the left of the j-th row from the bottom. The colors must have at least two rows of the same length of at least two.
+func NewMeshGradientGrid(rect Rect, colors [][]color.RGBA) *MeshGradient {
+	mesh := NewMeshGradient()
+	if len(colors) < 2 || len(colors[0]) < 2 {
+		return mesh
+	}
+	rows, cols := len(colors)-1, len(colors[0])-1
+	for j := 0; j < rows; j++ {
+		for i := 0; i < cols; i++ {
+			x0, x1 := rect.X+rect.W*float64(i)/float64(cols), rect.X+rect.W*float64(i+1)/float64(cols)
+			y0, y1 := rect.Y+rect.H*float64(j)/float64(rows), rect.Y+rect.H*float64(j+1)/float64(rows)
+			corners := [4]Point{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}
+			points := [12]Point{}
+			for k := 0; k < 4; k++ {
+				a, b := corners[k], corners[(k+1)%4]
+				points[3*k] = a
+				points[3*k+1] = a.Interpolate(b, 1.0/3.0)
+				points[3*k+2] = a.Interpolate(b, 2.0/3.0)
+			}
+			mesh.AddPatch(points, [4]color.RGBA{colors[j][i], colors[j][i+1], colors[j+1][i+1], colors[j+1][i]})
+		}
+	}
+	return mesh
+}
+
+// AddPatch adds a Coons patch, see MeshPatch.
+func (mesh *MeshGradient) AddPatch(points [12]Point, colors [4]color.RGBA) *MeshGradient {
+	mesh.Patches = append(mesh.Patches, MeshPatch{points, colors})
+	return mesh
+}
+
+// Bounds returns the bounding box of the control points of the patches, which contains the mesh.
+func (mesh *MeshGradient) Bounds() Rect {
+	if len(mesh.Patches) == 0 {
+		return Rect{}
+	}
+	p0 := mesh.Patches[0].Points[0]
+	xmin, xmax, ymin, ymax := p0.X, p0.X, p0.Y, p0.Y
+	for _, patch := range mesh.Patches {
+		for _, p := range patch.Points {
+			xmin, xmax = math.Min(xmin, p.X), math.Max(xmax, p.X)
+			ymin, ymax = math.Min(ymin, p.Y), math.Max(ymax, p.Y)
+		}
+	}
+	return Rect{xmin, ymin, xmax - xmin, ymax - ymin}
+}
+
+// averageColor returns the average of the colors of the corners of the patches, which is the appearance of the mesh for renderers that cannot approximate it.
+func (mesh *MeshGradient) averageColor() color.RGBA {
+	var r, g, b, a, n float64
+	for _, patch := range mesh.Patches {
+		for _, col := range patch.Colors {
+			r += float64(col.R)
+			g += float64(col.G)
+			b += float64(col.B)
+			a += float64(col.A)
+			n++
+		}
+	}
+	if n == 0 {
+		return Transparent
+	}
+	return color.RGBA{uint8(r/n + 0.5), uint8(g/n + 0.5), uint8(b/n + 0.5), uint8(a/n + 0.5)}
+}
+
+// at returns the point and the color of the patch at the parameters (u,v) in [0,1], where (0,0), (1,0), (1,1), and (0,1) are the corners. The boundaries are blended linearly and the colors are interpolated bilinearly as in PDF.
+func (patch MeshPatch) at(u, v float64) (Point, color.RGBA) {
+	ps := patch.Points
+	bottom := cubicBezierPos(ps[0], ps[1], ps[2], ps[3], u)
+	right := cubicBezierPos(ps[3], ps[4], ps[5], ps[6], v)
+	top := cubicBezierPos(ps[9], ps[8], ps[7], ps[6], u)
+	left := cubicBezierPos(ps[0], ps[11], ps[10], ps[9], v)
+	corners := ps[0].Mul((1.0 - u) * (1.0 - v)).Add(ps[3].Mul(u * (1.0 - v))).Add(ps[6].Mul(u * v)).Add(ps[9].Mul((1.0 - u) * v))
+	p := bottom.Mul(1.0 - v).Add(top.Mul(v)).Add(left.Mul(1.0 - u)).Add(right.Mul(u)).Sub(corners)
+
+	weights := [4]float64{(1.0 - u) * (1.0 - v), u * (1.0 - v), u * v, (1.0 - u) * v}
+	var r, g, b, a float64
+	for i, col := range patch.Colors {
+		r += weights[i] * float64(col.R)
+		g += weights[i] * float64(col.G)
+		b += weights[i] * float64(col.B)
+		a += weights[i] * float64(col.A)
+	}
+	return p, color.RGBA{uint8(r + 0.5), uint8(g + 0.5), uint8(b + 0.5), uint8(a + 0.5)}
+}
+
+// subdivisions returns the number of subdivisions along each parameter of the patch transformed by m, so that the colors of neighbouring pieces differ by a few levels and curved boundaries are followed, but pieces are no smaller than size in millimeters.
+func (patch MeshPatch) subdivisions(m Matrix, size float64) int {
+	diff := 0
+	for i := 0; i < 4; i++ {
+		for j := i + 1; j < 4; j++ {
+			a, b := patch.Colors[i], patch.Colors[j]
+			for _, d := range []int{int(a.R) - int(b.R), int(a.G) - int(b.G), int(a.B) - int(b.B), int(a.A) - int(b.A)} {
+				if diff < d {
+					diff = d
+				} else if diff < -d {
+					diff = -d
+				}
+			}
+		}
+	}
+	bounds := (&MeshGradient{[]MeshPatch{patch}}).Bounds().Transform(m)
+	n := math.Min(math.Max(math.Ceil(float64(diff)/4.0), 4.0), 64.0)
+	n = math.Min(n, math.Floor(math.Max(bounds.W, bounds.H)/size))
+	return int(math.Max(1.0, n))
+}
+
+// SetFillMesh sets the mesh gradient to be used for filling operations, which PDF writes as a shading of Coons patches and which SVG, EPS, and raster output approximate by pieces of flat colors. The average color of the mesh is the fill color for other renderers. The mesh is in the coordinates of the paths that are filled.
+func (c *Context) SetFillMesh(mesh *MeshGradient) {
+	c.SetFillColor(mesh.averageColor())
+	c.Style.FillMesh = mesh
+}
+
+// meshPieceSize is the size in millimeters below which patches of mesh gradients are not subdivided further for vector output, which is about two pixels at 100 DPI.
+const meshPieceSize = 0.5
+
+// renderMesh renders a path of which the fill is a mesh gradient for renderers without mesh gradients, by subdividing its patches into pieces of flat colors that are clipped to the path, followed by the stroke of the path. Pieces are no smaller than size in millimeters, which should be about two pixels of the output. Opaque pieces overlap the pieces drawn after them by half their size to hide the seams between them, translucent pieces may show hairline seams due to anti-aliasing.
+func renderMesh(r Renderer, path *Path, style Style, m Matrix, size float64) {
+	region := path
+	if style.FillRule == EvenOdd {
+		region = path.Settle(EvenOdd)
+	}
+	bounds := region.Bounds()
+
+	piece := DefaultStyle
+	piece.StrokeColor = Transparent
+	piece.Overprint = style.Overprint
+	for _, patch := range style.FillMesh.Patches {
+		opaque := true
+		for _, col := range patch.Colors {
+			opaque = opaque && col.A == 255
+		}
+		n := patch.subdivisions(m, size)
+		step := 1.0 / float64(n)
+		overlap := 0.0
+		if opaque {
+			overlap = step / 2.0
+		}
+		for j := 0; j < n; j++ {
+			for i := 0; i < n; i++ {
+				u0, v0 := float64(i)*step, float64(j)*step
+				u1, v1 := math.Min(1.0, u0+step+overlap), math.Min(1.0, v0+step+overlap)
+				p0, _ := patch.at(u0, v0)
+				p1, _ := patch.at(u1, v0)
+				p2, _ := patch.at(u1, v1)
+				p3, _ := patch.at(u0, v1)
+				_, col := patch.at(u0+step/2.0, v0+step/2.0)
+				if col.A == 0 {
+					continue
+				}
+
+				quad := &Path{}
+				quad.MoveTo(p0.X, p0.Y)
+				quad.LineTo(p1.X, p1.Y)
+				quad.LineTo(p2.X, p2.Y)
+				quad.LineTo(p3.X, p3.Y)
+				quad.Close()
+				if !quad.Bounds().Overlaps(bounds) {
+					continue
+				} else if quad = quad.And(region); quad.Empty() {
+					continue
+				}
+				piece.FillColor = col
+				r.RenderPath(quad, piece, m)
+			}
+		}
+	}
+
+	if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth {
+		style.FillColor = Transparent
+		style.FillMesh = nil
+		r.RenderPath(path, style, m)
+	}
+}
@@ -0,0 +1,79 @@
+package canvas
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestMeshGradientGrid(t *testing.T) {
+	mesh := NewMeshGradientGrid(Rect{0.0, 0.0, 20.0, 10.0}, [][]color.RGBA{{Red, Green, Blue}, {White, Black, Red}})
+	test.T(t, len(mesh.Patches), 2)
+	test.T(t, mesh.Bounds(), Rect{0.0, 0.0, 20.0, 10.0})
+	test.T(t, mesh.Patches[1].Points[0], Point{10.0, 0.0})
+	test.T(t, mesh.Patches[1].Points[6], Point{20.0, 10.0})
+	test.T(t, mesh.Patches[1].Colors, [4]color.RGBA{Green, Blue, Red, Black})
+
+	p, col := mesh.Patches[0].at(0.0, 0.0)
+	test.T(t, p, Point{0.0, 0.0})
+	test.T(t, col, Red)
+	p, col = mesh.Patches[0].at(1.0, 1.0)
+	test.T(t, p, Point{10.0, 10.0})
+	test.T(t, col, Black)
+	p, col = mesh.Patches[0].at(0.5, 0.25)
+	test.T(t, p, Point{5.0, 2.5})
+	test.T(t, col, color.RGBA{128, 80, 32, 255})
+
+	test.T(t, len(NewMeshGradientGrid(Rect{0.0, 0.0, 1.0, 1.0}, [][]color.RGBA{{Red, Green}}).Patches), 0)
+}
+
+func TestMeshGradientPatch(t *testing.T) {
+	// the bottom edge bulges downwards
+	points := [12]Point{{0.0, 0.0}, {3.0, -3.0}, {7.0, -3.0}, {10.0, 0.0}, {10.0, 3.0}, {10.0, 7.0}, {10.0, 10.0}, {7.0, 10.0}, {3.0, 10.0}, {0.0, 10.0}, {0.0, 7.0}, {0.0, 3.0}}
+	mesh := NewMeshGradient().AddPatch(points, [4]color.RGBA{Red, Red, Blue, Blue})
+	p, _ := mesh.Patches[0].at(0.5, 0.0)
+	test.T(t, p, Point{5.0, -2.25})
+	p, _ = mesh.Patches[0].at(0.5, 0.5)
+	test.T(t, p, Point{5.0, 3.875})
+	test.T(t, mesh.averageColor(), color.RGBA{128, 0, 128, 255})
+}
+
+func TestMeshGradientRender(t *testing.T) {
+	mesh := NewMeshGradientGrid(Rect{0.0, 0.0, 10.0, 10.0}, [][]color.RGBA{{Red, Blue}, {Red, Blue}})
+
+	c := New(10.0, 10.0)
+	ctx := NewContext(c)
+	ctx.SetFillMesh(mesh)
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	test.T(t, c.layers[0].style.FillColor, color.RGBA{128, 0, 128, 255})
+
+	// subdivided into pieces of colors from red on the left to blue on the right
+	img := c.WriteImage(2.0)
+	left, right := img.RGBAAt(1, 10), img.RGBAAt(18, 10)
+	test.That(t, 200 < left.R && left.B < 50, left)
+	test.That(t, right.R < 50 && 200 < right.B, right)
+
+	svg := &bytes.Buffer{}
+	c.Render(NewSVG(svg, 10.0, 10.0))
+	test.That(t, 10 < bytes.Count(svg.Bytes(), []byte("<path")))
+
+	buf := &bytes.Buffer{}
+	pdf := NewPDF(buf, 10.0, 10.0)
+	pdf.SetCompression(false)
+	c.Render(pdf)
+	test.Error(t, pdf.Close())
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("/ShadingType 6")))
+	test.That(t, bytes.Contains(buf.Bytes(), []byte(" W n /Sh0 sh Q")))
+
+	scene := &bytes.Buffer{}
+	test.Error(t, c.WriteScene(scene, SceneOptions{}))
+	c2, err := ReadScene(bytes.NewReader(scene.Bytes()))
+	test.Error(t, err)
+	test.T(t, *c2.layers[0].style.FillMesh, *mesh)
+
+	// the fill color replaces the mesh
+	ctx.SetFillColor(Green)
+	test.That(t, ctx.Style.FillMesh == nil)
+}
@@ -0,0 +1,244 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+)
+
+// Dithering defines how the colors of an image are approximated when it is reduced to a palette, such as for GIF, 1-bit, or e-ink output.
+type Dithering int
+
+// see Dithering
+const (
+	NoDithering             Dithering = iota // nearest color, which keeps flat colors and sharp edges
+	OrderedDithering                         // 8x8 Bayer matrix, which is stable between frames and on e-ink displays that refresh partially
+	FloydSteinbergDithering                  // error diffusion, which gives the most accurate colors
+)
+
+// Drawer returns the drawer that dithers the source image onto the palette of a destination *image.Paletted, which can be used with gif.Options.
+func (dithering Dithering) Drawer() draw.Drawer {
+	switch dithering {
+	case OrderedDithering:
+		return orderedDrawer{}
+	case FloydSteinbergDithering:
+		return draw.FloydSteinberg
+	}
+	return draw.Src
+}
+
+// bayer is the 8x8 Bayer threshold matrix.
+var bayer = [8][8]float64{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
+
+type orderedDrawer struct{}
+
+// Draw offsets the colors by the Bayer matrix before matching them to the palette, by the distance between the values of each channel in the palette. Destinations other than *image.Paletted are drawn without dithering.
+func (orderedDrawer) Draw(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point) {
+	paletted, ok := dst.(*image.Paletted)
+	if !ok || len(paletted.Palette) == 0 {
+		draw.Draw(dst, r, src, sp, draw.Src)
+		return
+	}
+	r = r.Intersect(dst.Bounds())
+
+	// the spread of each channel is the average distance between its distinct values in the palette
+	spread := [4]float64{}
+	for i := range spread {
+		values := map[uint32]bool{}
+		lo, hi := uint32(0xFFFF), uint32(0)
+		for _, c := range paletted.Palette {
+			v := [4]uint32{}
+			v[0], v[1], v[2], v[3] = c.RGBA()
+			values[v[i]] = true
+			if v[i] < lo {
+				lo = v[i]
+			}
+			if hi < v[i] {
+				hi = v[i]
+			}
+		}
+		if 1 < len(values) {
+			spread[i] = float64(hi-lo) / float64(len(values)-1)
+		}
+	}
+
+	clamp := func(v, spread, t float64) uint16 {
+		return uint16(clampInt(int(v+spread*t+0.5), 0, 0xFFFF))
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			sx, sy := sp.X+x-r.Min.X, sp.Y+y-r.Min.Y
+			R, G, B, A := src.At(sx, sy).RGBA()
+			t := (bayer[y%8][x%8]+0.5)/64.0 - 0.5
+			c := color.RGBA64{
+				clamp(float64(R), spread[0], t),
+				clamp(float64(G), spread[1], t),
+				clamp(float64(B), spread[2], t),
+				clamp(float64(A), spread[3], t),
+			}
+			// keep colors premultiplied
+			if c.R > c.A {
+				c.R = c.A
+			}
+			if c.G > c.A {
+				c.G = c.A
+			}
+			if c.B > c.A {
+				c.B = c.A
+			}
+			paletted.SetColorIndex(x, y, uint8(paletted.Palette.Index(c)))
+		}
+	}
+}
+
+// GrayPalette returns a palette of n evenly spaced gray levels from black to white, such as for 1-bit (n=2) or e-ink displays (n=4 or 16).
+func GrayPalette(n int) color.Palette {
+	if n < 2 {
+		n = 2
+	}
+	palette := make(color.Palette, n)
+	for i := range palette {
+		palette[i] = color.Gray{uint8(i * 0xFF / (n - 1))}
+	}
+	return palette
+}
+
+// MedianCut is a quantizer that chooses the colors of a palette by the median cut algorithm. It is a draw.Quantizer that can be used with gif.Options. Images with few colors, as is common for vector drawings, keep their exact colors.
+type MedianCut struct{}
+
+// Quantize appends up to cap(p)-len(p) colors to the palette that approximate the colors of the image.
+func (MedianCut) Quantize(p color.Palette, img image.Image) color.Palette {
+	n := cap(p) - len(p)
+	if n <= 0 {
+		return p
+	}
+
+	// count the colors, where large images are sampled
+	bounds := img.Bounds()
+	step := 1
+	for bounds.Dx()*bounds.Dy()/(step*step) > 1<<18 {
+		step++
+	}
+	counts := map[color.RGBA]int{}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			counts[color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)]++
+		}
+	}
+	colors := make([]medianCutColor, 0, len(counts))
+	for c, count := range counts {
+		colors = append(colors, medianCutColor{[4]uint8{c.R, c.G, c.B, c.A}, count})
+	}
+	sort.Slice(colors, func(i, j int) bool {
+		return colors[j].count < colors[i].count
+	})
+	if len(colors) <= n {
+		for _, c := range colors {
+			p = append(p, color.RGBA{c.v[0], c.v[1], c.v[2], c.v[3]})
+		}
+		return p
+	}
+
+	// split the box with the largest range times number of pixels at the median of its widest channel
+	boxes := [][]medianCutColor{colors}
+	for len(boxes) < n {
+		best, bestChannel, bestScore := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			channel, width := medianCutWidest(box)
+			score := width * medianCutCount(box)
+			if bestScore < score {
+				best, bestChannel, bestScore = i, channel, score
+			}
+		}
+		if best == -1 {
+			break
+		}
+
+		box := boxes[best]
+		sort.Slice(box, func(i, j int) bool {
+			return box[i].v[bestChannel] < box[j].v[bestChannel]
+		})
+		half, total := 0, medianCutCount(box)
+		median := 1
+		for ; median < len(box)-1; median++ {
+			half += box[median-1].count
+			if total <= 2*half {
+				break
+			}
+		}
+		boxes[best] = box[:median]
+		boxes = append(boxes, box[median:])
+	}
+
+	for _, box := range boxes {
+		sum := [4]int{}
+		total := medianCutCount(box)
+		for _, c := range box {
+			for i := range sum {
+				sum[i] += int(c.v[i]) * c.count
+			}
+		}
+		p = append(p, color.RGBA{
+			uint8((sum[0] + total/2) / total),
+			uint8((sum[1] + total/2) / total),
+			uint8((sum[2] + total/2) / total),
+			uint8((sum[3] + total/2) / total),
+		})
+	}
+	return p
+}
+
+type medianCutColor struct {
+	v     [4]uint8 // premultiplied RGBA
+	count int
+}
+
+func medianCutCount(box []medianCutColor) int {
+	count := 0
+	for _, c := range box {
+		count += c.count
+	}
+	return count
+}
+
+// medianCutWidest returns the channel with the largest range of values in the box and its range.
+func medianCutWidest(box []medianCutColor) (int, int) {
+	channel, width := 0, 0
+	for i := 0; i < 4; i++ {
+		lo, hi := box[0].v[i], box[0].v[i]
+		for _, c := range box[1:] {
+			if c.v[i] < lo {
+				lo = c.v[i]
+			} else if hi < c.v[i] {
+				hi = c.v[i]
+			}
+		}
+		if width < int(hi-lo) {
+			channel, width = i, int(hi-lo)
+		}
+	}
+	return channel, width
+}
+
+// Quantize reduces an image to the palette using the dithering. If the palette is empty, a palette of 256 colors is chosen by MedianCut.
+func Quantize(img image.Image, palette color.Palette, dithering Dithering) *image.Paletted {
+	if len(palette) == 0 {
+		palette = MedianCut{}.Quantize(make(color.Palette, 0, 256), img)
+	}
+	paletted := image.NewPaletted(img.Bounds(), palette)
+	dithering.Drawer().Draw(paletted, paletted.Bounds(), img, img.Bounds().Min)
+	return paletted
+}
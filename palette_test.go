@@ -0,0 +1,85 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+// testGradient returns an image of w by h pixels with a horizontal gradient from black to white.
+func testGradient(w, h int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetGray(x, y, color.Gray{uint8(x * 0xFF / (w - 1))})
+		}
+	}
+	return img
+}
+
+// testMeanGray returns the mean gray level of the pixels of an image.
+func testMeanGray(img image.Image) float64 {
+	sum := 0.0
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			sum += float64(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+		}
+	}
+	return sum / float64(bounds.Dx()*bounds.Dy())
+}
+
+func TestGrayPalette(t *testing.T) {
+	test.T(t, GrayPalette(2), color.Palette{color.Gray{0x00}, color.Gray{0xFF}})
+	test.T(t, GrayPalette(4)[1], color.Color(color.Gray{0x55}))
+	test.T(t, len(GrayPalette(0)), 2)
+}
+
+func TestQuantize(t *testing.T) {
+	for _, dithering := range []Dithering{OrderedDithering, FloydSteinbergDithering} {
+		// dithering keeps the mean gray level on a 1-bit palette
+		for _, level := range []uint8{64, 128, 192} {
+			img := image.NewGray(image.Rect(0, 0, 32, 32))
+			draw.Draw(img, img.Bounds(), image.NewUniform(color.Gray{level}), image.Point{}, draw.Src)
+			mean := testMeanGray(Quantize(img, GrayPalette(2), dithering))
+			test.That(t, math.Abs(mean-float64(level)) < 8.0, dithering, level, mean)
+		}
+	}
+
+	// without dithering the nearest color is used
+	paletted := Quantize(testGradient(64, 4), GrayPalette(2), NoDithering)
+	test.Float(t, testMeanGray(paletted.SubImage(image.Rect(0, 0, 30, 4))), 0.0)
+	test.Float(t, testMeanGray(paletted.SubImage(image.Rect(34, 0, 64, 4))), 255.0)
+
+	// ordered dithering repeats every eight pixels
+	paletted = Quantize(testGradient(64, 16), GrayPalette(2), OrderedDithering)
+	test.T(t, paletted.ColorIndexAt(10, 3), paletted.ColorIndexAt(10, 11))
+}
+
+func TestMedianCut(t *testing.T) {
+	// few colors are kept exactly, ordered by frequency
+	img := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	img.Set(0, 0, Red)
+	img.Set(1, 0, Blue)
+	img.Set(2, 0, Red)
+	img.Set(3, 0, Red)
+	palette := MedianCut{}.Quantize(make(color.Palette, 0, 16), img)
+	test.T(t, palette, color.Palette{Red, Blue})
+
+	// many colors are reduced to the capacity of the palette
+	palette = MedianCut{}.Quantize(make(color.Palette, 1, 9), testGradient(256, 1))
+	test.T(t, len(palette), 9)
+	test.T(t, palette[0], color.Color(nil))
+	for i := 2; i < len(palette); i++ {
+		prev, _, _, _ := palette[i-1].RGBA()
+		cur, _, _, _ := palette[i].RGBA()
+		test.That(t, prev != cur, "distinct colors")
+	}
+
+	paletted := Quantize(testGradient(256, 1), nil, NoDithering)
+	test.T(t, len(paletted.Palette), 256)
+}
@@ -1,16 +1,19 @@
 package canvas
 
 import (
+	"encoding/binary"
 	"fmt"
+	"hash/fnv"
 	"math"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/tdewolff/parse/v2/strconv"
 	"golang.org/x/image/vector"
 )
 
-// Tolerance is the maximum deviation from the original path in millimeters when e.g. flatting
+// Tolerance is the maximum deviation from the original path in millimeters when e.g. flatting or when approximating arcs by Béziers
 var Tolerance = 0.01
 
 // FillRule is the algorithm to specify which area is to be filled and which not, in particular when multiple subpaths overlap. The NonZero rule is the default and will fill any point that is being enclosed by an unequal number of paths winding clockwise and counter clockwise, otherwise it will not be filled. The EvenOdd rule will fill any point that is being enclosed by an uneven number of path, whichever their direction.
@@ -76,24 +79,87 @@ func (p *Path) Empty() bool {
 	return len(p.d) <= cmdLen(moveToCmd)
 }
 
-// Equals returns true if p and q are equal within tolerance Epsilon.
+// Equals returns true if p and q are equal within tolerance Epsilon, see EqualsEpsilon.
 func (p *Path) Equals(q *Path) bool {
+	return p.EqualsEpsilon(q, Epsilon)
+}
+
+// EqualsEpsilon returns true if p and q have the same commands and their coordinates, radii and rotations differ less than epsilon. The large and sweep flags of arcs must be equal.
+func (p *Path) EqualsEpsilon(q *Path, epsilon float64) bool {
 	if len(p.d) != len(q.d) {
 		return false
 	}
-	for i := 0; i < len(p.d); i++ {
-		if !equal(p.d[i], q.d[i]) {
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		n := cmdLen(cmd)
+		if q.d[i] != cmd || q.d[i+n-1] != cmd {
 			return false
 		}
+		for j := i + 1; j < i+n-1; j++ {
+			if cmd == arcToCmd && j == i+4 {
+				if p.d[j] != q.d[j] {
+					return false
+				}
+			} else if epsilon <= math.Abs(p.d[j]-q.d[j]) {
+				return false
+			}
+		}
+		i += n
 	}
 	return true
 }
 
+// Hash returns a 64-bit FNV-1a hash of the path. Paths that are exactly equal have equal hashes, but paths that are equal within Epsilon may not; use ToFixed().Hash() for a hash that is insensitive to small rounding errors.
+func (p *Path) Hash() uint64 {
+	h := fnv.New64a()
+	b := make([]byte, 8*len(p.d))
+	for i, v := range p.d {
+		if v == 0.0 {
+			v = 0.0 // positive and negative zero are equal
+		}
+		binary.LittleEndian.PutUint64(b[8*i:], math.Float64bits(v))
+	}
+	h.Write(b)
+	return h.Sum64()
+}
+
 // Closed returns true if the last subpath of p is a closed path.
 func (p *Path) Closed() bool {
 	return 0 < len(p.d) && p.d[len(p.d)-1] == closeCmd
 }
 
+var pathPool = sync.Pool{
+	New: func() interface{} {
+		return &Path{}
+	},
+}
+
+// GetPath returns an empty path from a pool of paths, reusing the memory of paths that were returned by PutPath. This avoids allocations when many temporary paths are built, such as when converting large amounts of text to paths.
+func GetPath() *Path {
+	return pathPool.Get().(*Path)
+}
+
+// PutPath resets p and returns it to the pool of paths used by GetPath. The path must not be used afterwards.
+func PutPath(p *Path) {
+	p.Reset()
+	pathPool.Put(p)
+}
+
+// Reset empties the path while retaining its allocated memory, so that it can be reused to build a new path.
+func (p *Path) Reset() {
+	p.d = p.d[:0]
+}
+
+// Grow makes sure there is memory allocated for at least n more commands without reallocation, which can be used as a pre-sizing hint when the size of the path is known beforehand.
+func (p *Path) Grow(n int) {
+	n *= cmdLen(cubeToCmd)
+	if cap(p.d)-len(p.d) < n {
+		d := make([]float64, len(p.d), len(p.d)+n)
+		copy(d, p.d)
+		p.d = d
+	}
+}
+
 // Copy returns a copy of p.
 func (p *Path) Copy() *Path {
 	q := &Path{}
@@ -214,6 +280,10 @@ func (p *Path) LineTo(x, y float64) *Path {
 	start := p.Pos()
 	end := Point{x, y}
 	if start.Equals(end) {
+		if 0 < len(p.d) && p.d[len(p.d)-1] == moveToCmd {
+			// keep zero-length subpaths, which are capped when stroked
+			p.d = append(p.d, lineToCmd, end.X, end.Y, lineToCmd)
+		}
 		return p
 	} else if cmdLen(lineToCmd) <= len(p.d) && p.d[len(p.d)-1] == lineToCmd {
 		prevStart := Point{}
@@ -227,6 +297,7 @@ func (p *Path) LineTo(x, y float64) *Path {
 		}
 	}
 
+	p.trimZeroLength()
 	if len(p.d) == 0 {
 		p.MoveTo(0.0, 0.0)
 	} else if p.d[len(p.d)-1] == closeCmd {
@@ -236,6 +307,20 @@ func (p *Path) LineTo(x, y float64) *Path {
 	return p
 }
 
+// zeroLength returns true if the path consists of a single subpath of zero length, such as M5 5L5 5 or M5 5z.
+func (p *Path) zeroLength() bool {
+	n := len(p.d)
+	return n == cmdLen(moveToCmd)+cmdLen(lineToCmd) && (p.d[n-1] == lineToCmd || p.d[n-1] == closeCmd) && Point{p.d[1], p.d[2]}.Equals(Point{p.d[n-3], p.d[n-2]})
+}
+
+// trimZeroLength removes a zero-length line that is the only segment of the last subpath, so that it does not remain when more segments follow.
+func (p *Path) trimZeroLength() {
+	n := len(p.d)
+	if cmdLen(moveToCmd)+cmdLen(lineToCmd) <= n && p.d[n-1] == lineToCmd && p.d[n-cmdLen(lineToCmd)-1] == moveToCmd && (&Path{p.d[n-cmdLen(moveToCmd)-cmdLen(lineToCmd):]}).zeroLength() {
+		p.d = p.d[:n-cmdLen(lineToCmd)]
+	}
+}
+
 // QuadTo adds a quadratic Bézier path with control point cpx,cpy and end point x,y.
 func (p *Path) QuadTo(cpx, cpy, x, y float64) *Path {
 	start := p.Pos()
@@ -247,6 +332,7 @@ func (p *Path) QuadTo(cpx, cpy, x, y float64) *Path {
 		return p.LineTo(end.X, end.Y)
 	}
 
+	p.trimZeroLength()
 	if len(p.d) == 0 {
 		p.MoveTo(0.0, 0.0)
 	} else if p.d[len(p.d)-1] == closeCmd {
@@ -268,6 +354,7 @@ func (p *Path) CubeTo(cpx1, cpy1, cpx2, cpy2, x, y float64) *Path {
 		return p.LineTo(end.X, end.Y)
 	}
 
+	p.trimZeroLength()
 	if len(p.d) == 0 {
 		p.MoveTo(0.0, 0.0)
 	} else if p.d[len(p.d)-1] == closeCmd {
@@ -310,6 +397,7 @@ func (p *Path) ArcTo(rx, ry, rot float64, large, sweep bool, x, y float64) *Path
 		ry *= lambda
 	}
 
+	p.trimZeroLength()
 	if len(p.d) == 0 {
 		p.MoveTo(0.0, 0.0)
 	} else if p.d[len(p.d)-1] == closeCmd {
@@ -354,9 +442,6 @@ func (p *Path) Close() *Path {
 	end := p.StartPos()
 	if len(p.d) == 0 || p.d[len(p.d)-1] == closeCmd {
 		return p
-	} else if p.d[len(p.d)-1] == moveToCmd {
-		p.d = p.d[:len(p.d)-cmdLen(moveToCmd)]
-		return p
 	} else if p.d[len(p.d)-1] == lineToCmd && equal(p.d[len(p.d)-3], end.X) && equal(p.d[len(p.d)-2], end.Y) {
 		p.d[len(p.d)-1] = closeCmd
 		p.d[len(p.d)-cmdLen(lineToCmd)] = closeCmd
@@ -446,6 +531,11 @@ func (p *Path) Filling(fillRule FillRule) []bool {
 
 	testPoints := make([]Point, 0, len(pls))
 	for i, pl := range pls {
+		if len(pl.coords) < 2 {
+			// subpath of a single point has no area, its test point is never used
+			testPoints = append(testPoints, Point{})
+			continue
+		}
 		offset := pl.coords[1].Sub(pl.coords[0]).Rot90CW().Norm(Epsilon)
 		if ccw[i] {
 			offset = offset.Neg()
@@ -462,7 +552,9 @@ func (p *Path) Filling(fillRule FillRule) []bool {
 
 	fillings := make([]bool, len(fillCounts))
 	for i := range fillCounts {
-		if fillRule == NonZero {
+		if len(pls[i].coords) < 2 {
+			fillings[i] = false
+		} else if fillRule == NonZero {
 			fillings[i] = fillCounts[i] != 0
 		} else {
 			fillings[i] = fillCounts[i]%2 != 0
@@ -730,14 +822,85 @@ func (p *Path) Translate(x, y float64) *Path {
 
 // Flatten flattens all Bézier and arc curves into linear segments and returns a new path. It uses Tolerance as the maximum deviation.
 func (p *Path) Flatten() *Path {
-	return p.replace(nil, flattenQuadraticBezier, flattenCubicBezier, flattenEllipticArc)
+	span := startTrace(FlatteningPhase)
+	q := p.replace(nil, flattenQuadraticBezier, flattenCubicBezier, flattenEllipticArc)
+	span.endPath(q)
+	return q
 }
 
-// ReplaceArcs replaces ArcTo commands by CubeTo commands.
+// ReplaceArcs replaces ArcTo commands by CubeTo commands. It uses Tolerance as the maximum deviation, using at least one cubic Bézier for every quarter of an ellipse.
 func (p *Path) ReplaceArcs() *Path {
 	return p.replace(nil, nil, nil, arcToCube)
 }
 
+// Simplify simplifies the path for display at a resolution where tolerance is the size of a pixel, and returns a new path. Subpaths that fit within tolerance are dropped, curves that deviate less than tolerance from their chord are replaced by lines, and consecutive lines that deviate less than tolerance from a straight line are merged using the Ramer-Douglas-Peucker algorithm.
+func (p *Path) Simplify(tolerance float64) *Path {
+	q := &Path{}
+	for _, ps := range p.Split() {
+		bounds := ps.Bounds()
+		if bounds.W < tolerance && bounds.H < tolerance {
+			continue
+		}
+
+		var start Point
+		poly := []Point{}
+		flush := func() {
+			poly = simplifyPolyline(poly, tolerance)
+			for _, pos := range poly[1:] {
+				q.LineTo(pos.X, pos.Y)
+			}
+			poly = poly[len(poly)-1:]
+		}
+		for i := 0; i < len(ps.d); {
+			cmd := ps.d[i]
+			end := Point{ps.d[i+cmdLen(cmd)-3], ps.d[i+cmdLen(cmd)-2]}
+			switch cmd {
+			case moveToCmd:
+				q.MoveTo(end.X, end.Y)
+				poly = append(poly, end)
+			case lineToCmd, closeCmd:
+				poly = append(poly, end)
+			case quadToCmd:
+				cp := Point{ps.d[i+1], ps.d[i+2]}
+				if distanceToSegment(cp, start, end) < tolerance {
+					poly = append(poly, end)
+				} else {
+					flush()
+					q.QuadTo(cp.X, cp.Y, end.X, end.Y)
+					poly = []Point{end}
+				}
+			case cubeToCmd:
+				cp1 := Point{ps.d[i+1], ps.d[i+2]}
+				cp2 := Point{ps.d[i+3], ps.d[i+4]}
+				if distanceToSegment(cp1, start, end) < tolerance && distanceToSegment(cp2, start, end) < tolerance {
+					poly = append(poly, end)
+				} else {
+					flush()
+					q.CubeTo(cp1.X, cp1.Y, cp2.X, cp2.Y, end.X, end.Y)
+					poly = []Point{end}
+				}
+			case arcToCmd:
+				rx, ry, phi := ps.d[i+1], ps.d[i+2], ps.d[i+3]
+				if 2.0*math.Max(rx, ry) < tolerance {
+					poly = append(poly, end)
+				} else {
+					flush()
+					large, sweep := toArcFlags(ps.d[i+4])
+					q.ArcTo(rx, ry, phi*180.0/math.Pi, large, sweep, end.X, end.Y)
+					poly = []Point{end}
+				}
+			}
+			i += cmdLen(cmd)
+			start = end
+		}
+		flush()
+		if ps.Closed() {
+			q.Close()
+		}
+	}
+	return q
+}
+
 // replace replaces path segments by their respective functions, each returning the path that will replace the segment or nil if no replacement is to be performed.
 // The line function will take the start and end points. The bezier function will take the start point, control point 1 and 2, and the end point (ie. a cubic Bézier, quadratic Béziers will be implicitly converted to cubic ones). The arc function will take a start point, the major and minor radii, the radial rotaton counter clockwise, the large and sweep booleans, and the end point.
 // The replacing path will replace the path segment without any checks, you need to make sure the be moved so that its start point connects with the last end point of the base path before the replacement. If the end point of the replacing path is different that the end point of what is replaced, the path that follows will be displaced.
@@ -807,6 +970,9 @@ func (p *Path) replace(
 func (p *Path) Markers(first, mid, last *Path, align bool) []*Path {
 	markers := []*Path{}
 	for _, ps := range p.Split() {
+		if ps.zeroLength() {
+			continue
+		}
 		isFirst := true
 		closed := ps.Closed()
 
@@ -1044,7 +1210,16 @@ func (p *Path) SplitAt(ts ...float64) []*Path {
 						theta := invL(ts[j] - T)
 						mid, large1, large2, ok := ellipseSplit(rx, ry, phi, cx, cy, startTheta, theta2, theta)
 						if !ok {
-							panic("theta not in elliptic arc range for splitting")
+							// the approximation of the inverse arc length is inaccurate for very flat ellipses, split at the nearest end instead
+							if math.Abs(theta-startTheta) < math.Abs(theta-theta2) {
+								theta = startTheta
+							} else {
+								theta = theta2
+							}
+							mid, large1, large2 = ellipsePos(rx, ry, phi, cx, cy, theta), false, false
+							if theta == theta2 {
+								mid, large1 = end, nextLarge
+							}
 						}
 
 						q.ArcTo(rx, ry, phi*180.0/math.Pi, large1, sweep, mid.X, mid.Y)
@@ -1215,6 +1390,7 @@ func (p *Path) Dash(offset float64, d ...float64) *Path {
 		d = append(d, d...)
 	}
 
+	span := startTrace(FlatteningPhase)
 	i0, pos0 := dashStart(offset, d)
 
 	q := &Path{}
@@ -1255,6 +1431,7 @@ func (p *Path) Dash(offset float64, d ...float64) *Path {
 		}
 		q = q.Append(qd)
 	}
+	span.endPath(q)
 	return q
 }
 
@@ -1430,7 +1607,9 @@ func ParseSVG(s string) (*Path, error) {
 			i += skipCommaWhitespace(path[i:])
 			if CMD == 'A' && (j == 3 || j == 4) {
 				// parse largeArc and sweep booleans for A command
-				if path[i] == '1' {
+				if len(path) <= i {
+					return nil, fmt.Errorf("bad path: %d numbers should follow command '%c' at position %d", cmdLens[CMD], cmd, i)
+				} else if path[i] == '1' {
 					f[j] = 1.0
 				} else if path[i] == '0' {
 					f[j] = 0.0
@@ -1595,7 +1774,9 @@ func (p *Path) ToSVG() string {
 			xStart, yStart := x, y
 			x, y = p.d[i+1], p.d[i+2]
 			if equal(x, xStart) && equal(y, yStart) {
-				// nothing
+				if p.d[i-1] == moveToCmd {
+					fmt.Fprintf(&sb, "H%v", num(x)) // zero-length subpath
+				}
 			} else if equal(x, xStart) {
 				fmt.Fprintf(&sb, "V%v", num(y))
 			} else if equal(y, yStart) {
@@ -0,0 +1,542 @@
+package canvas
+
+import (
+	"math"
+	"sort"
+)
+
+// booleanOp is a path boolean operation.
+type booleanOp int
+
+const (
+	opSettle booleanOp = iota
+	opAnd
+	opOr
+	opXor
+	opNot
+)
+
+// Settle returns the path with all overlapping and self-intersecting subpaths resolved, so that the result fills the same area as p with the given fill rule but has no overlaps. Outer contours are counter clockwise and holes are clockwise, so that the result fills identically for both the NonZero and EvenOdd fill rules. Curves are flattened using Tolerance.
+func (p *Path) Settle(fillRule FillRule) *Path {
+	return boolean(p, nil, opSettle, fillRule)
+}
+
+// And returns the boolean intersection of p and q, ie. the area filled by both. Both paths use the NonZero fill rule and curves are flattened using Tolerance.
+func (p *Path) And(q *Path) *Path {
+	return boolean(p, q, opAnd, NonZero)
+}
+
+// Or returns the boolean union of p and q, ie. the area filled by either. Both paths use the NonZero fill rule and curves are flattened using Tolerance.
+func (p *Path) Or(q *Path) *Path {
+	return boolean(p, q, opOr, NonZero)
+}
+
+// Xor returns the boolean exclusive-or of p and q, ie. the area filled by either but not both. Both paths use the NonZero fill rule and curves are flattened using Tolerance.
+func (p *Path) Xor(q *Path) *Path {
+	return boolean(p, q, opXor, NonZero)
+}
+
+// Not returns the boolean difference of p and q, ie. the area filled by p but not by q. Both paths use the NonZero fill rule and curves are flattened using Tolerance.
+func (p *Path) Not(q *Path) *Path {
+	return boolean(p, q, opNot, NonZero)
+}
+
+// booleanSegment is a line segment of a flattened path, operand is the path it belongs to (0 for p, 1 for q).
+type booleanSegment struct {
+	a, b    Point
+	operand int
+}
+
+func (seg booleanSegment) bounds() Rect {
+	x0, x1 := math.Min(seg.a.X, seg.b.X), math.Max(seg.a.X, seg.b.X)
+	y0, y1 := math.Min(seg.a.Y, seg.b.Y), math.Max(seg.a.Y, seg.b.Y)
+	return Rect{x0, y0, x1 - x0, y1 - y0}
+}
+
+// booleanPolygons flattens the path and returns its subpaths as closed polygons, open subpaths are closed implicitly and non-finite vertices are dropped.
+func booleanPolygons(p *Path) [][]Point {
+	polygons := [][]Point{}
+	if p == nil {
+		return polygons
+	}
+	for _, ps := range p.Flatten().Split() {
+		coords := []Point{}
+		for i := 0; i < len(ps.d); {
+			cmd := ps.d[i]
+			i += cmdLen(cmd)
+			if cmd == moveToCmd || cmd == lineToCmd || cmd == closeCmd {
+				end := Point{ps.d[i-3], ps.d[i-2]}
+				if math.IsNaN(end.X) || math.IsNaN(end.Y) || math.IsInf(end.X, 0) || math.IsInf(end.Y, 0) {
+					continue // non-finite vertices cannot be placed on the sweep, the polygon skips over them
+				} else if len(coords) == 0 || coords[len(coords)-1] != end {
+					coords = append(coords, end)
+				}
+			}
+		}
+		if 1 < len(coords) && coords[0] == coords[len(coords)-1] {
+			coords = coords[:len(coords)-1]
+		}
+		if 2 < len(coords) {
+			polygons = append(polygons, coords)
+		}
+	}
+	return polygons
+}
+
+func polygonBounds(coords []Point) Rect {
+	x0, y0, x1, y1 := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, c := range coords {
+		x0, y0 = math.Min(x0, c.X), math.Min(y0, c.Y)
+		x1, y1 = math.Max(x1, c.X), math.Max(y1, c.Y)
+	}
+	return Rect{x0, y0, x1 - x0, y1 - y0}
+}
+
+func boolean(p, q *Path, op booleanOp, fillRule FillRule) *Path {
+	type polygon struct {
+		coords  []Point
+		bounds  Rect
+		operand int
+	}
+	polygons := []polygon{}
+	for operand, path := range []*Path{p, q} {
+		for _, coords := range booleanPolygons(path) {
+			polygons = append(polygons, polygon{coords, polygonBounds(coords), operand})
+		}
+	}
+
+	// group polygons whose bounds overlap so that each group can be handled independently, this keeps the operation fast for many small and disjoint polygons such as text
+	group := make([]int, len(polygons))
+	for i := range group {
+		group[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if group[i] != i {
+			group[i] = find(group[i])
+		}
+		return group[i]
+	}
+	idx := make([]int, len(polygons))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool { return polygons[idx[i]].bounds.X < polygons[idx[j]].bounds.X })
+	for ii, i := range idx {
+		for _, j := range idx[ii+1:] {
+			if polygons[i].bounds.X+polygons[i].bounds.W < polygons[j].bounds.X {
+				break
+			} else if polygons[i].bounds.Overlaps(polygons[j].bounds) {
+				group[find(j)] = find(i)
+			}
+		}
+	}
+
+	groups := map[int][]booleanSegment{}
+	order := []int{}
+	for i, poly := range polygons {
+		g := find(i)
+		if _, ok := groups[g]; !ok {
+			order = append(order, g)
+		}
+		for k := range poly.coords {
+			a, b := poly.coords[k], poly.coords[(k+1)%len(poly.coords)]
+			groups[g] = append(groups[g], booleanSegment{a, b, poly.operand})
+		}
+	}
+
+	r := &Path{}
+	for _, g := range order {
+		r = r.Append(booleanGroup(groups[g], op, fillRule))
+	}
+	return r
+}
+
+// booleanGroup applies the boolean operation to a set of segments. It splits all segments at their intersections, then keeps the (sub)segments that have the result filled on one side but not on the other, and finally chains those into closed polygons.
+// To be robust against nearly-tangent and overlapping segments, it uses snap rounding: all endpoints and intersections are rounded to a fine grid and every segment is routed through the center of each grid cell (hot pixel) it passes. This ensures that segments that share an edge or touch end up with exactly the same coordinates without introducing new intersections.
+func booleanGroup(segs []booleanSegment, op booleanOp, fillRule FillRule) *Path {
+	// find all intersections, we sort by the left-most coordinate to only compare segments that overlap horizontally
+	bounds := make([]Rect, len(segs))
+	for i := range segs {
+		bounds[i] = segs[i].bounds()
+	}
+	idx := make([]int, len(segs))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool { return bounds[idx[i]].X < bounds[idx[j]].X })
+
+	grid := booleanSnapGrid()
+	hot := map[Point]bool{}
+	for _, seg := range segs {
+		hot[booleanSnap(seg.a, grid)] = true
+		hot[booleanSnap(seg.b, grid)] = true
+	}
+	for ii, i := range idx {
+		for _, j := range idx[ii+1:] {
+			if bounds[i].X+bounds[i].W < bounds[j].X {
+				break
+			}
+			if !bounds[i].Overlaps(bounds[j]) {
+				continue
+			}
+			for _, z := range intersectionSegmentSegment(segs[i].a, segs[i].b, segs[j].a, segs[j].b) {
+				hot[booleanSnap(z.p, grid)] = true
+			}
+		}
+	}
+
+	// route each segment through the hot pixels it passes
+	pixels := make([]Point, 0, len(hot))
+	for c := range hot {
+		pixels = append(pixels, c)
+	}
+	sort.Slice(pixels, func(i, j int) bool {
+		return pixels[i].X < pixels[j].X || pixels[i].X == pixels[j].X && pixels[i].Y < pixels[j].Y
+	})
+	index := NewQuadtree(polygonBounds(pixels))
+	for k, c := range pixels {
+		index.Insert(Rect{c.X - grid/2.0, c.Y - grid/2.0, grid, grid}, k)
+	}
+
+	type edge struct {
+		a, b    Point
+		operand int
+	}
+	snapped := []edge{}
+	for i, seg := range segs {
+		type pass struct {
+			t float64
+			p Point
+		}
+		passes := []pass{}
+		d := seg.b.Sub(seg.a)
+		query := Rect{bounds[i].X - grid, bounds[i].Y - grid, bounds[i].W + 2.0*grid, bounds[i].H + 2.0*grid}
+		index.Search(query, func(k int) bool {
+			c := pixels[k]
+			if segmentCrossesPixel(seg.a, seg.b, c, grid) {
+				passes = append(passes, pass{d.Dot(c.Sub(seg.a)), c})
+			}
+			return true
+		})
+		sort.Slice(passes, func(a, b int) bool { return passes[a].t < passes[b].t })
+
+		a := booleanSnap(seg.a, grid)
+		for _, pass := range passes {
+			if pass.p != a {
+				snapped = append(snapped, edge{a, pass.p, seg.operand})
+				a = pass.p
+			}
+		}
+		if b := booleanSnap(seg.b, grid); a != b {
+			snapped = append(snapped, edge{a, b, seg.operand})
+		}
+	}
+
+	// remove duplicate edges
+	type edgeKey struct {
+		a, b Point
+	}
+	edges := []edgeKey{}
+	keys := map[edgeKey]int{}
+	keyOf := func(a, b Point) edgeKey {
+		if b.X < a.X || b.X == a.X && b.Y < a.Y {
+			return edgeKey{b, a}
+		}
+		return edgeKey{a, b}
+	}
+	for _, e := range snapped {
+		key := keyOf(e.a, e.b)
+		if _, ok := keys[key]; !ok {
+			keys[key] = len(edges)
+			edges = append(edges, key)
+		}
+	}
+
+	filled := func(w [2]int) bool {
+		var in [2]bool
+		for k := range w {
+			if fillRule == NonZero {
+				in[k] = w[k] != 0
+			} else {
+				in[k] = w[k]%2 != 0
+			}
+		}
+		switch op {
+		case opAnd:
+			return in[0] && in[1]
+		case opOr:
+			return in[0] || in[1]
+		case opXor:
+			return in[0] != in[1]
+		case opNot:
+			return in[0] && !in[1]
+		}
+		return in[0]
+	}
+
+	// classify edges by the filling on both sides, sweeping upwards over the slabs between the distinct y-coordinates of the vertices. Snapped edges do not cross within a slab, so that the edges spanning a slab are ordered by x-coordinate and the winding numbers are carried along them from the right, where upward edges add one and downward edges subtract one.
+	type sweepEdge struct {
+		lo, hi       Point // lower and upper end
+		dir, operand int
+		x            float64 // x-coordinate in the middle of the current slab
+	}
+	xAt := func(e sweepEdge, y float64) float64 {
+		return e.lo.X + (y-e.lo.Y)*(e.hi.X-e.lo.X)/(e.hi.Y-e.lo.Y)
+	}
+	ys := make([]float64, 0, 2*len(snapped))
+	for _, e := range snapped {
+		ys = append(ys, e.a.Y, e.b.Y)
+	}
+	sort.Float64s(ys)
+	n := 0
+	for _, y := range ys {
+		if n == 0 || ys[n-1] != y {
+			ys[n] = y
+			n++
+		}
+	}
+	ys = ys[:n]
+	starts := make([][]sweepEdge, len(ys)) // edges by the index of the y-coordinate of their lower end
+	horizontals := make([][]int, len(ys))  // horizontal edges by the index of their y-coordinate
+	for _, e := range snapped {
+		if e.a.Y == e.b.Y {
+			continue
+		}
+		se := sweepEdge{lo: e.a, hi: e.b, dir: 1, operand: e.operand}
+		if e.b.Y < e.a.Y {
+			se = sweepEdge{lo: e.b, hi: e.a, dir: -1, operand: e.operand}
+		}
+		i := sort.SearchFloat64s(ys, se.lo.Y)
+		starts[i] = append(starts[i], se)
+	}
+	for k, e := range edges {
+		if e.a.Y == e.b.Y {
+			i := sort.SearchFloat64s(ys, e.a.Y)
+			horizontals[i] = append(horizontals[i], k)
+		}
+	}
+
+	// left and right are the filling on the left and right of the edges in the direction of their key, where horizontal edges point towards positive X and have their left side above
+	left, right := make([]bool, len(edges)), make([]bool, len(edges))
+	active := []sweepEdge{}
+	for i := 0; i+1 < len(ys); i++ {
+		m := 0
+		for _, e := range active {
+			if ys[i] < e.hi.Y {
+				active[m] = e
+				m++
+			}
+		}
+		active = append(active[:m], starts[i]...)
+		ym := (ys[i] + ys[i+1]) / 2.0
+		for j := range active {
+			active[j].x = xAt(active[j], ym)
+		}
+		sort.Slice(active, func(a, b int) bool { return active[a].x < active[b].x })
+		suffix := make([][2]int, len(active)+1)
+		for j := len(active) - 1; 0 <= j; j-- {
+			suffix[j] = suffix[j+1]
+			suffix[j][active[j].operand] += active[j].dir
+		}
+
+		// edges starting in this slab, where identical edges of both operands have the same x-coordinate
+		for j := 0; j < len(active); {
+			g := j + 1
+			for g < len(active) && active[g].x == active[j].x {
+				g++
+			}
+			for _, e := range active[j:g] {
+				if e.lo.Y == ys[i] {
+					k := keys[keyOf(e.lo, e.hi)]
+					if edges[k].a.Y < edges[k].b.Y {
+						left[k], right[k] = filled(suffix[j]), filled(suffix[g])
+					} else {
+						left[k], right[k] = filled(suffix[g]), filled(suffix[j])
+					}
+				}
+			}
+			j = g
+		}
+
+		// horizontal edges on the bottom and top of this slab
+		windingAt := func(x, y float64) [2]int {
+			return suffix[sort.Search(len(active), func(j int) bool { return x < xAt(active[j], y) })]
+		}
+		for _, k := range horizontals[i] {
+			left[k] = filled(windingAt(edges[k].a.Interpolate(edges[k].b, 0.5).X, ys[i]))
+		}
+		for _, k := range horizontals[i+1] {
+			right[k] = filled(windingAt(edges[k].a.Interpolate(edges[k].b, 0.5).X, ys[i+1]))
+		}
+	}
+
+	// keep the edges with the filling on one side, oriented such that the filled area is to their left
+	outgoing := map[Point][]int{}
+	kept := []edgeKey{}
+	for k, e := range edges {
+		if left[k] == right[k] {
+			continue
+		} else if right[k] {
+			e.a, e.b = e.b, e.a
+		}
+		outgoing[e.a] = append(outgoing[e.a], len(kept))
+		kept = append(kept, e)
+	}
+
+	// chain the edges into polygons
+	r := &Path{}
+	used := make([]bool, len(kept))
+	for i := range kept {
+		if used[i] {
+			continue
+		}
+		coords := []Point{kept[i].a}
+		used[i] = true
+		cur := kept[i]
+		for cur.b != coords[0] {
+			next := -1
+			for _, j := range outgoing[cur.b] {
+				if !used[j] {
+					next = j
+					break
+				}
+			}
+			if next == -1 {
+				break // should not happen
+			}
+			used[next] = true
+			coords = append(coords, cur.b)
+			cur = kept[next]
+		}
+
+		coords = removeCollinear(coords)
+		if len(coords) < 3 {
+			continue
+		}
+		r.MoveTo(coords[0].X, coords[0].Y)
+		for _, c := range coords[1:] {
+			r.LineTo(c.X, c.Y)
+		}
+		r.Close()
+	}
+	return r
+}
+
+// removeCollinear removes points of a closed polygon that lie on the straight line between their neighbours.
+func removeCollinear(coords []Point) []Point {
+	for k := 0; k < len(coords) && 2 < len(coords); {
+		prev, next := coords[(k+len(coords)-1)%len(coords)], coords[(k+1)%len(coords)]
+		if math.Abs(next.Sub(prev).PerpDot(coords[k].Sub(prev))) < 1e-12*next.Sub(prev).Length() {
+			coords = append(coords[:k], coords[k+1:]...)
+			if 0 < k {
+				k--
+			}
+		} else {
+			k++
+		}
+	}
+	return coords
+}
+
+type segmentIntersection struct {
+	p    Point
+	t, u float64
+}
+
+// intersectionSegmentSegment returns the intersections between line segments a0-a1 and b0-b1, with t and u the positions along a and b respectively. Collinear segments return the endpoints of their overlap.
+func intersectionSegmentSegment(a0, a1, b0, b1 Point) []segmentIntersection {
+	da, db := a1.Sub(a0), b1.Sub(b0)
+	denom := da.PerpDot(db)
+	lenA, lenB := da.Length(), db.Length()
+	if math.Abs(denom) <= 1e-12*lenA*lenB {
+		// parallel, check if collinear
+		if 1e-12*lenA < math.Abs(da.PerpDot(b0.Sub(a0)))/lenA {
+			return nil
+		}
+		zs := []segmentIntersection{}
+		ta0, ta1 := da.Dot(b0.Sub(a0))/(lenA*lenA), da.Dot(b1.Sub(a0))/(lenA*lenA)
+		if 0.0 < ta0 && ta0 < 1.0 {
+			zs = append(zs, segmentIntersection{b0, ta0, 0.0})
+		}
+		if 0.0 < ta1 && ta1 < 1.0 {
+			zs = append(zs, segmentIntersection{b1, ta1, 1.0})
+		}
+		tb0, tb1 := db.Dot(a0.Sub(b0))/(lenB*lenB), db.Dot(a1.Sub(b0))/(lenB*lenB)
+		if 0.0 < tb0 && tb0 < 1.0 {
+			zs = append(zs, segmentIntersection{a0, 0.0, tb0})
+		}
+		if 0.0 < tb1 && tb1 < 1.0 {
+			zs = append(zs, segmentIntersection{a1, 1.0, tb1})
+		}
+		return zs
+	}
+
+	t := db.PerpDot(a0.Sub(b0)) / denom
+	u := da.PerpDot(a0.Sub(b0)) / denom
+
+	// snap to endpoints so that both segments share exactly the same point
+	t, u = booleanSnapParam(t, lenA), booleanSnapParam(u, lenB)
+	if t < 0.0 || 1.0 < t || u < 0.0 || 1.0 < u {
+		return nil
+	}
+	p := a0.Interpolate(a1, t)
+	if t == 0.0 {
+		p = a0
+	} else if t == 1.0 {
+		p = a1
+	} else if u == 0.0 {
+		p = b0
+	} else if u == 1.0 {
+		p = b1
+	}
+	return []segmentIntersection{{p, t, u}}
+}
+
+// booleanSnapGrid returns the size in millimeters of the grid to which all coordinates are rounded in boolean operations. It is about 1e-6 mm, or larger when Epsilon is larger so that the vertices of the result are not merged by the path commands. It is a power of two so that integer and other dyadic coordinates are kept exactly.
+func booleanSnapGrid() float64 {
+	grid := 1.0 / (1 << 20)
+	for grid < Epsilon {
+		grid *= 2.0
+	}
+	return grid
+}
+
+// booleanSnap rounds a point to the center of its grid cell.
+func booleanSnap(p Point, grid float64) Point {
+	return Point{math.Round(p.X/grid) * grid, math.Round(p.Y/grid) * grid}
+}
+
+// segmentCrossesPixel returns true if segment a-b passes through the grid cell (hot pixel) centered at c, including its boundary.
+func segmentCrossesPixel(a, b, c Point, grid float64) bool {
+	// Liang-Barsky clipping of the segment by the cell
+	h := grid / 2.0
+	d := b.Sub(a)
+	t0, t1 := 0.0, 1.0
+	for _, edge := range [4][2]float64{{-d.X, a.X - (c.X - h)}, {d.X, (c.X + h) - a.X}, {-d.Y, a.Y - (c.Y - h)}, {d.Y, (c.Y + h) - a.Y}} {
+		p, q := edge[0], edge[1]
+		if p == 0.0 {
+			if q < 0.0 {
+				return false
+			}
+		} else if t := q / p; p < 0.0 {
+			t0 = math.Max(t0, t)
+		} else {
+			t1 = math.Min(t1, t)
+		}
+	}
+	return t0 <= t1
+}
+
+// booleanEpsilon is the distance in millimeters below which an intersection is snapped to a segment's endpoint.
+const booleanEpsilon = 1e-9
+
+// booleanSnapParam snaps the intersection position t along a segment of length l to its endpoints when close.
+func booleanSnapParam(t, l float64) float64 {
+	if math.Abs(t*l) < booleanEpsilon {
+		return 0.0
+	} else if math.Abs((1.0-t)*l) < booleanEpsilon {
+		return 1.0
+	}
+	return t
+}
@@ -0,0 +1,155 @@
+package canvas
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPathBoolean(t *testing.T) {
+	var tts = []struct {
+		op   string
+		p, q string
+		r    string
+	}{
+		{"or", "L10 0L10 10L0 10z", "M5 5L15 5L15 15L5 15z", "M0 0L10 0L10 5L15 5L15 15L5 15L5 10L0 10z"},
+		{"and", "L10 0L10 10L0 10z", "M5 5L15 5L15 15L5 15z", "M10 5L10 10L5 10L5 5z"},
+		{"not", "L10 0L10 10L0 10z", "M5 5L15 5L15 15L5 15z", "M0 0L10 0L10 5L5 5L5 10L0 10z"},
+		{"xor", "L10 0L10 10L0 10z", "M5 5L15 5L15 15L5 15z", "M0 0L10 0L10 5L5 5L5 10L10 10L10 5L15 5L15 15L5 15L5 10L0 10z"},
+		{"or", "L10 0L10 10L0 10z", "M10 0L20 0L20 10L10 10z", "M0 0L20 0L20 10L0 10z"}, // shared edge
+		{"or", "L10 0L10 10L0 10z", "M20 0L30 0L30 10L20 10z", "M0 0L10 0L10 10L0 10zM20 0L30 0L30 10L20 10z"},
+		{"and", "L10 0L10 10L0 10z", "M20 0L30 0L30 10L20 10z", ""},
+	}
+	for _, tt := range tts {
+		t.Run(tt.op+" "+tt.p+" "+tt.q, func(t *testing.T) {
+			p, q := MustParseSVG(tt.p), MustParseSVG(tt.q)
+			var r *Path
+			switch tt.op {
+			case "or":
+				r = p.Or(q)
+			case "and":
+				r = p.And(q)
+			case "not":
+				r = p.Not(q)
+			case "xor":
+				r = p.Xor(q)
+			}
+			test.T(t, r, MustParseSVG(tt.r))
+		})
+	}
+}
+
+func TestPathSettle(t *testing.T) {
+	test.T(t, MustParseSVG("L10 0L10 10L0 10zM2 2L8 2L8 8L2 8z").Settle(NonZero), MustParseSVG("L10 0L10 10L0 10z"))
+	test.T(t, MustParseSVG("L10 0L10 10L0 10zM2 2L8 2L8 8L2 8z").Settle(EvenOdd), MustParseSVG("L10 0L10 10L0 10zM8 2L2 2L2 8L8 8z"))
+
+	// self-intersection becomes two counter clockwise triangles
+	p := MustParseSVG("L10 10L10 0L0 10z").Settle(NonZero)
+	test.That(t, p.Interior(2.0, 5.0, EvenOdd))
+	test.That(t, p.Interior(8.0, 5.0, EvenOdd))
+	test.That(t, !p.Interior(5.0, 2.0, EvenOdd))
+	test.That(t, p.CCW())
+}
+
+// polygonArea returns the signed area of a path consisting of lines only, positive for counter clockwise subpaths.
+func polygonArea(p *Path) float64 {
+	area := 0.0
+	for _, ps := range p.Split() {
+		coords := ps.Coords()
+		for i := range coords {
+			a, b := coords[i], coords[(i+1)%len(coords)]
+			area += a.PerpDot(b) / 2.0
+		}
+	}
+	return area
+}
+
+func TestPathBooleanRobust(t *testing.T) {
+	defer func(epsilon, tolerance float64) {
+		Epsilon, Tolerance = epsilon, tolerance
+	}(Epsilon, Tolerance)
+	Epsilon = 1e-10
+	Tolerance = 0.01
+
+	square := MustParseSVG("L10 0L10 10L0 10z")
+
+	// adjacent squares sharing edges merge into one
+	grid := &Path{}
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 5; j++ {
+			grid = grid.Append(square.Translate(10.0*float64(i), 10.0*float64(j)))
+		}
+	}
+	test.T(t, grid.Settle(NonZero), MustParseSVG("L50 0L50 50L0 50z"))
+
+	// nearly coinciding squares do not leave slivers
+	shifted := square.Translate(1e-10, -1e-10)
+	test.That(t, square.Not(shifted).Empty())
+	test.That(t, square.Xor(shifted).Empty())
+	test.T(t, square.Or(shifted), square)
+	test.T(t, square.And(shifted), square)
+
+	// nearly tangent circles
+	circle := Circle(10.0)
+	tangent := circle.Translate(20.0+1e-10, 0.0)
+	test.Float(t, polygonArea(circle.Or(tangent)), 2.0*polygonArea(circle.Settle(NonZero)))
+	test.That(t, circle.And(tangent).Empty())
+
+	// overlapping circles, the sum of the union and intersection match the sum of both
+	rnd := rand.New(rand.NewSource(0))
+	for i := 0; i < 20; i++ {
+		p := Circle(1.0+10.0*rnd.Float64()).Translate(10.0*rnd.Float64(), 10.0*rnd.Float64())
+		q := Rectangle(1.0+10.0*rnd.Float64(), 1.0+10.0*rnd.Float64()).Transform(Identity.Rotate(360.0*rnd.Float64())).Translate(10.0*rnd.Float64(), 10.0*rnd.Float64())
+		areaP, areaQ := polygonArea(p.Settle(NonZero)), polygonArea(q.Settle(NonZero))
+		or, and := polygonArea(p.Or(q)), polygonArea(p.And(q))
+		not, xor := polygonArea(p.Not(q)), polygonArea(p.Xor(q))
+		test.That(t, math.Abs(or+and-areaP-areaQ) < 1e-4, "union and intersection")
+		test.That(t, math.Abs(not+and-areaP) < 1e-4, "difference")
+		test.That(t, math.Abs(xor+and-or) < 1e-4, "exclusive-or")
+	}
+
+	// self-intersecting star and rotated copies sharing the center
+	star := MustParseSVG("M0 10L5.878 -8.090L-9.511 3.090L9.511 3.090L-5.878 -8.090z")
+	for _, rot := range []float64{0.0, 1e-9, 36.0, 72.0} {
+		r := star.Or(star.Transform(Identity.Rotate(rot)))
+		test.That(t, !r.Empty(), "rotated star")
+		test.That(t, 0.0 < polygonArea(r), "rotated star")
+	}
+
+	// non-finite vertices are dropped
+	nan, inf := math.NaN(), math.Inf(1)
+	test.That(t, (&Path{}).MoveTo(nan, nan).LineTo(nan, nan).LineTo(1.0, 1.0).Close().Settle(NonZero).Empty())
+	test.T(t, (&Path{}).MoveTo(0.0, 0.0).LineTo(inf, 0.0).LineTo(10.0, 10.0).LineTo(0.0, 10.0).Close().Settle(NonZero), MustParseSVG("L10 10L0 10z"))
+	test.That(t, square.And((&Path{}).MoveTo(0.0, 0.0).LineTo(nan, 0.0).LineTo(-inf, 10.0).LineTo(5.0, 5.0).Close()).Empty())
+}
+
+func TestPathBooleanLarge(t *testing.T) {
+	defer func(epsilon float64) {
+		Epsilon = epsilon
+	}(Epsilon)
+	Epsilon = 1e-10
+
+	// polygons of many vertices, where each edge used to be classified by walking over all edges
+	polygon := func(n int, x float64) *Path {
+		p := &Path{}
+		for i := 0; i < n; i++ {
+			sin, cos := math.Sincos(2.0 * math.Pi * float64(i) / float64(n))
+			r := 10.0 + 0.5*math.Sin(40.0*math.Pi*float64(i)/float64(n))
+			if i == 0 {
+				p.MoveTo(x+r*cos, r*sin)
+			} else {
+				p.LineTo(x+r*cos, r*sin)
+			}
+		}
+		p.Close()
+		return p
+	}
+	p, q := polygon(4000, 0.0), polygon(4000, 8.0)
+	areaP, areaQ := polygonArea(p), polygonArea(q)
+	or, and := polygonArea(p.Or(q)), polygonArea(p.And(q))
+	test.That(t, math.Abs(or+and-areaP-areaQ) < 1e-4, "union and intersection")
+	test.That(t, math.Abs(polygonArea(p.Not(q))+and-areaP) < 1e-4, "difference")
+	test.T(t, len(p.And(q).Split()), 1)
+}
@@ -0,0 +1,227 @@
+package canvas
+
+import (
+	"math"
+	"sort"
+)
+
+// Clip clips the path to the rectangle, discarding the geometry outside of it. Segments are split where they cross the rectangle's boundary, so that lines and Béziers keep their exact shape inside the rectangle, arcs are converted to cubic Béziers. Closed subpaths are clipped for filling: parts outside the rectangle are projected onto its boundary so that the filled area is unchanged within the rectangle (a stroke would thus become visible along the boundary). Open subpaths are clipped for stroking: parts outside the rectangle are removed, which may split a subpath into several.
+func (p *Path) Clip(rect Rect) *Path {
+	x0, y0, x1, y1 := rect.X, rect.Y, rect.X+rect.W, rect.Y+rect.H
+	if x1 < x0 {
+		x0, x1 = x1, x0
+	}
+	if y1 < y0 {
+		y0, y1 = y1, y0
+	}
+	rect = Rect{x0, y0, x1 - x0, y1 - y0}
+
+	q := &Path{}
+	for _, ps := range p.Split() {
+		closed := ps.Closed()
+		bounds := ps.Bounds()
+		if x0 <= bounds.X && bounds.X+bounds.W <= x1 && y0 <= bounds.Y && bounds.Y+bounds.H <= y1 {
+			q = q.Append(ps) // fully inside
+			continue
+		} else if bounds.X+bounds.W < x0 || x1 < bounds.X || bounds.Y+bounds.H < y0 || y1 < bounds.Y {
+			continue // fully outside, cannot enclose the rectangle either
+		}
+
+		if closed {
+			q = q.Append(clipClosed(ps.ReplaceArcs(), rect))
+		} else {
+			q = q.Append(clipOpen(ps.ReplaceArcs(), rect))
+		}
+	}
+	return q
+}
+
+// clipSegments calls f for every piece of the segments of p after splitting those at the rectangle's boundary, with inside whether the piece lies within the rectangle. Each piece is given by its control points, ie. two for lines, three for quadratic and four for cubic Béziers. Paths must not contain arcs.
+func clipSegments(p *Path, rect Rect, f func(ctrl []Point, inside bool)) {
+	var start Point
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		end := Point{p.d[i+cmdLen(cmd)-3], p.d[i+cmdLen(cmd)-2]}
+		var ctrl []Point
+		switch cmd {
+		case lineToCmd, closeCmd:
+			ctrl = []Point{start, end}
+		case quadToCmd:
+			ctrl = []Point{start, {p.d[i+1], p.d[i+2]}, end}
+		case cubeToCmd:
+			ctrl = []Point{start, {p.d[i+1], p.d[i+2]}, {p.d[i+3], p.d[i+4]}, end}
+		}
+		i += cmdLen(cmd)
+		start = end
+		if ctrl == nil || cmd == closeCmd && ctrl[0].Equals(ctrl[1]) {
+			continue
+		}
+
+		type crossing struct {
+			t          float64
+			c          float64
+			horizontal bool
+		}
+		crossings := []crossing{}
+		for k, c := range [4]float64{rect.X, rect.X + rect.W, rect.Y, rect.Y + rect.H} {
+			for _, t := range segmentAxisRoots(ctrl, c, k < 2) {
+				crossings = append(crossings, crossing{t, c, k < 2})
+			}
+		}
+		sort.Slice(crossings, func(i, j int) bool { return crossings[i].t < crossings[j].t })
+		crossings = append(crossings, crossing{1.0, 0.0, false})
+
+		tPrev := 0.0
+		for k, crossing := range crossings {
+			t := crossing.t
+			if t-tPrev < Epsilon {
+				continue
+			}
+
+			// split at t relative to the remaining part that starts at tPrev
+			var piece []Point
+			piece, ctrl = segmentSplit(ctrl, (t-tPrev)/(1.0-tPrev))
+			if k+1 < len(crossings) {
+				// put split point exactly on the boundary
+				if crossing.horizontal {
+					piece[len(piece)-1].X = crossing.c
+					ctrl[0].X = crossing.c
+				} else {
+					piece[len(piece)-1].Y = crossing.c
+					ctrl[0].Y = crossing.c
+				}
+			}
+			mid := segmentPos(piece, 0.5)
+			inside := rect.X-Epsilon <= mid.X && mid.X <= rect.X+rect.W+Epsilon && rect.Y-Epsilon <= mid.Y && mid.Y <= rect.Y+rect.H+Epsilon
+			f(piece, inside)
+			tPrev = t
+		}
+	}
+}
+
+func clipClosed(p *Path, rect Rect) *Path {
+	clamp := func(p Point) Point {
+		return Point{math.Min(math.Max(p.X, rect.X), rect.X+rect.W), math.Min(math.Max(p.Y, rect.Y), rect.Y+rect.H)}
+	}
+
+	start := clamp(p.StartPos())
+	q := &Path{}
+	q.MoveTo(start.X, start.Y)
+	anyInside := false
+	clipSegments(p, rect, func(ctrl []Point, inside bool) {
+		if !inside {
+			// project onto the boundary, this doesn't change the filling inside the rectangle
+			end := clamp(ctrl[len(ctrl)-1])
+			q.LineTo(end.X, end.Y)
+			return
+		}
+		anyInside = true
+		end := clamp(ctrl[len(ctrl)-1])
+		switch len(ctrl) {
+		case 2:
+			q.LineTo(end.X, end.Y)
+		case 3:
+			q.QuadTo(ctrl[1].X, ctrl[1].Y, end.X, end.Y)
+		case 4:
+			q.CubeTo(ctrl[1].X, ctrl[1].Y, ctrl[2].X, ctrl[2].Y, end.X, end.Y)
+		}
+	})
+	q.Close()
+
+	if !anyInside {
+		// subpath runs along the boundary only, keep it only if it encloses the rectangle
+		area := 0.0
+		coords := q.Coords()
+		for i := 1; i < len(coords); i++ {
+			area += (coords[i].X - coords[i-1].X) * (coords[i-1].Y + coords[i].Y)
+		}
+		if math.Abs(area) < Epsilon {
+			return &Path{}
+		}
+	}
+	return q
+}
+
+func clipOpen(p *Path, rect Rect) *Path {
+	q := &Path{}
+	penDown := false
+	clipSegments(p, rect, func(ctrl []Point, inside bool) {
+		if !inside {
+			penDown = false
+			return
+		}
+		if !penDown {
+			q.MoveTo(ctrl[0].X, ctrl[0].Y)
+			penDown = true
+		}
+		end := ctrl[len(ctrl)-1]
+		switch len(ctrl) {
+		case 2:
+			q.LineTo(end.X, end.Y)
+		case 3:
+			q.QuadTo(ctrl[1].X, ctrl[1].Y, end.X, end.Y)
+		case 4:
+			q.CubeTo(ctrl[1].X, ctrl[1].Y, ctrl[2].X, ctrl[2].Y, end.X, end.Y)
+		}
+	})
+	return q
+}
+
+// segmentAxisRoots returns the positions t in (0,1) along a line or Bézier where its X (or Y if horizontal is false) coordinate equals c.
+func segmentAxisRoots(ctrl []Point, c float64, horizontal bool) []float64 {
+	v := make([]float64, len(ctrl))
+	for i := range ctrl {
+		if horizontal {
+			v[i] = ctrl[i].X
+		} else {
+			v[i] = ctrl[i].Y
+		}
+	}
+
+	var roots []float64
+	switch len(v) {
+	case 2:
+		if v[0] != v[1] {
+			roots = []float64{(c - v[0]) / (v[1] - v[0])}
+		}
+	case 3:
+		t0, t1 := solveQuadraticFormula(v[0]-2.0*v[1]+v[2], 2.0*(v[1]-v[0]), v[0]-c)
+		roots = []float64{t0, t1}
+	case 4:
+		t0, t1, t2 := solveCubicFormula(-v[0]+3.0*v[1]-3.0*v[2]+v[3], 3.0*v[0]-6.0*v[1]+3.0*v[2], -3.0*v[0]+3.0*v[1], v[0]-c)
+		roots = []float64{t0, t1, t2}
+	}
+
+	ts := []float64{}
+	for _, t := range roots {
+		if Epsilon < t && t < 1.0-Epsilon {
+			ts = append(ts, t)
+		}
+	}
+	return ts
+}
+
+// segmentSplit splits a line or Bézier given by its control points at t.
+func segmentSplit(ctrl []Point, t float64) ([]Point, []Point) {
+	switch len(ctrl) {
+	case 3:
+		q0, q1, q2, r0, r1, r2 := quadraticBezierSplit(ctrl[0], ctrl[1], ctrl[2], t)
+		return []Point{q0, q1, q2}, []Point{r0, r1, r2}
+	case 4:
+		q0, q1, q2, q3, r0, r1, r2, r3 := cubicBezierSplit(ctrl[0], ctrl[1], ctrl[2], ctrl[3], t)
+		return []Point{q0, q1, q2, q3}, []Point{r0, r1, r2, r3}
+	}
+	mid := ctrl[0].Interpolate(ctrl[1], t)
+	return []Point{ctrl[0], mid}, []Point{mid, ctrl[1]}
+}
+
+// segmentPos returns the position at t along a line or Bézier given by its control points.
+func segmentPos(ctrl []Point, t float64) Point {
+	switch len(ctrl) {
+	case 3:
+		return quadraticBezierPos(ctrl[0], ctrl[1], ctrl[2], t)
+	case 4:
+		return cubicBezierPos(ctrl[0], ctrl[1], ctrl[2], ctrl[3], t)
+	}
+	return ctrl[0].Interpolate(ctrl[1], t)
+}
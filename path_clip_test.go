@@ -0,0 +1,42 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPathClip(t *testing.T) {
+	var tts = []struct {
+		p string
+		r string
+	}{
+		// open paths are cut
+		{"M-5 5L15 5", "M0 5L10 5"},
+		{"M-5 5L5 5L5 -5L15 -5L15 5", "M0 5L5 5L5 0"},
+		{"M-5 5L5 5L5 15L8 15L8 5", "M0 5L5 5L5 10M8 10L8 5"},
+		{"M-5 5Q5 15 15 5", "M0 8.75Q2.5 10 5 10Q7.5 10 10 8.75"},
+		{"M-5 5C0 20 10 20 15 5", ""},
+		{"M20 20L30 30", ""},
+
+		// closed paths are projected onto the boundary
+		{"M2 2L8 2L8 8L2 8z", "M2 2L8 2L8 8L2 8z"},
+		{"M5 5L15 5L15 15L5 15z", "M5 5L10 5L10 10L5 10z"},
+		{"M-5 -5L15 -5L15 15L-5 15z", "M0 0L10 0L10 10L0 10z"},
+		{"M-5 5L5 -5L15 5L5 15z", "M0 5L0 0L10 0L10 10L0 10z"},
+		{"M20 20L30 20L30 30z", ""},
+		{"M-5 12L15 12L15 20L-5 20z", ""},
+	}
+	for _, tt := range tts {
+		t.Run(tt.p, func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.p).Clip(Rect{0.0, 0.0, 10.0, 10.0}), MustParseSVG(tt.r))
+		})
+	}
+
+	// filling is unchanged inside the rectangle
+	p := MustParseSVG("M5 5L8 5A3 3 0 0 1 11 8z")
+	q := p.Clip(Rect{0.0, 0.0, 10.0, 10.0})
+	test.That(t, q.Interior(9.0, 5.5, NonZero))
+	test.That(t, !q.Interior(6.0, 7.0, NonZero))
+	test.Float(t, q.Bounds().X+q.Bounds().W, 10.0)
+}
@@ -0,0 +1,34 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestFixedPath(t *testing.T) {
+	var tts = []struct {
+		orig string
+		res  string
+	}{
+		{"M0 0L10 0L10 10z", "M0 0L10 0L10 10z"},
+		{"M0.001 0L1.0025 0Q2 2 3 0", "M0 0L1.00390625 0Q2 2 3 0"},
+		{"M0 0C1 1 2 1 3 0A5 10 30 1 0 10 0", "M0 0C1 1 2 1 3 0A5 10 30 1 0 10 0"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.orig, func(t *testing.T) {
+			p := MustParseSVG(tt.orig).ToFixed()
+			test.T(t, p.ToPath(), MustParseSVG(tt.res))
+		})
+	}
+
+	p := MustParseSVG("M0 0L10 0L10 10z").ToFixed()
+	q := MustParseSVG("M0 0L10.0001 0L10 10z").ToFixed()
+	r := MustParseSVG("M0 0L10.01 0L10 10z").ToFixed()
+	test.That(t, p.Equals(q))
+	test.That(t, !p.Equals(r))
+	test.T(t, p.Hash(), q.Hash())
+	test.That(t, p.Hash() != r.Hash())
+	test.That(t, !p.Empty())
+	test.That(t, (&Path{}).ToFixed().Empty())
+}
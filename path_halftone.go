@@ -0,0 +1,192 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+)
+
+// StippleOptions are the options for Stipple to approximate the tone of an area by dots.
+type StippleOptions struct {
+	Spacing float64 // minimum distance between the centers of the dots in millimeters at full tone
+	DotSize float64 // diameter of the dots in millimeters
+	Seed    int64   // seed of the random generator, the same seed gives the same result
+}
+
+// DefaultStippleOptions are the default options for Stipple, for dots that are drawn by a fine pen plotter.
+var DefaultStippleOptions = StippleOptions{
+	Spacing: 1.0,
+	DotSize: 0.5,
+	Seed:    0,
+}
+
+// HalftoneShape is the shape of the elements of a halftone screen.
+type HalftoneShape int
+
+// see HalftoneShape
+const (
+	HalftoneDots  HalftoneShape = iota // circles of which the area follows the tone
+	HalftoneLines                      // parallel lines of which the width follows the tone
+)
+
+// HalftoneOptions are the options for Halftone to approximate the tone of an area by a regular screen.
+type HalftoneOptions struct {
+	Shape  HalftoneShape
+	Period float64 // distance between the dots or lines of the screen in millimeters
+	Angle  float64 // angle of the screen in degrees counter clockwise
+}
+
+// DefaultHalftoneOptions are the default options for Halftone, for a screen of dots at 45 degrees as in print.
+var DefaultHalftoneOptions = HalftoneOptions{
+	Shape:  HalftoneDots,
+	Period: 1.0,
+	Angle:  45.0,
+}
+
+// pathInterior returns a function that is true when a point is in the interior of the path with the NonZero fill rule, like Path.Interior but flattening the path only once.
+func pathInterior(p *Path) func(Point) bool {
+	polylines := []*Polyline{}
+	for _, ps := range p.Split() {
+		polylines = append(polylines, PolylineFromPath(ps))
+	}
+	return func(pos Point) bool {
+		fillCount := 0
+		for _, polyline := range polylines {
+			fillCount += polyline.FillCount(pos.X, pos.Y)
+		}
+		return fillCount != 0
+	}
+}
+
+// Stipple returns dots inside the path of which the density approximates the tone, which is a value in [0,1] for each point where zero is blank and one is the darkest, such as from ImageTone or func(x, y float64) float64 { return 0.5 } for a uniform tone. The dots are placed by blue-noise sampling, where random candidates are rejected when they are closer to a previous dot than opts.Spacing divided by the square root of the tone, so that dots are evenly spread without the artifacts of a regular screen. The centers of the dots are inside the path by the NonZero fill rule, and the result is meant to be filled. This is useful for plotters and stippled illustrations.
+func (p *Path) Stipple(tone func(x, y float64) float64, opts StippleOptions) *Path {
+	q := &Path{}
+	bounds := p.Bounds()
+	if opts.Spacing <= 0.0 || opts.DotSize <= 0.0 || bounds.W <= 0.0 || bounds.H <= 0.0 {
+		return q
+	}
+	interior := pathInterior(p)
+	rnd := rand.New(rand.NewSource(opts.Seed))
+
+	// light tones below minTone have the largest distance between dots, and their dots are thinned randomly
+	const minTone = 1.0 / 64.0
+	cell := opts.Spacing
+	nx, ny := int(bounds.W/cell)+1, int(bounds.H/cell)+1
+	grid := make([][]Point, nx*ny)
+	cellOf := func(pos Point) (int, int) {
+		return int((pos.X - bounds.X) / cell), int((pos.Y - bounds.Y) / cell)
+	}
+	candidates := int(10.0 * bounds.W * bounds.H / (opts.Spacing * opts.Spacing))
+	for i := 0; i < candidates; i++ {
+		pos := Point{bounds.X + rnd.Float64()*bounds.W, bounds.Y + rnd.Float64()*bounds.H}
+		t := math.Min(tone(pos.X, pos.Y), 1.0)
+		if t <= 0.0 || t < minTone && minTone*rnd.Float64() >= t || !interior(pos) {
+			continue
+		}
+		distance := opts.Spacing / math.Sqrt(math.Max(t, minTone))
+
+		free := true
+		cx, cy := cellOf(pos)
+		n := int(math.Ceil(distance / cell))
+		for y := cy - n; y <= cy+n && free; y++ {
+			for x := cx - n; x <= cx+n && free; x++ {
+				if x < 0 || nx <= x || y < 0 || ny <= y {
+					continue
+				}
+				for _, dot := range grid[y*nx+x] {
+					if dot.Sub(pos).Length() < distance {
+						free = false
+						break
+					}
+				}
+			}
+		}
+		if free {
+			grid[cy*nx+cx] = append(grid[cy*nx+cx], pos)
+			q = q.Append(Circle(opts.DotSize/2.0).Translate(pos.X, pos.Y))
+		}
+	}
+	return q
+}
+
+// Halftone returns a halftone screen inside the path that approximates the tone, which is a value in [0,1] for each point where zero is blank and one is the darkest, see Stipple. Dots of HalftoneDots are centered on a square grid of opts.Period rotated by opts.Angle, where the area of each dot is the tone times the area of its cell, and dots of which the center is outside the path are left out. Lines of HalftoneLines are spaced by opts.Period perpendicular to opts.Angle, where their width is the tone times the period, and they are clipped to the path. The path uses the NonZero fill rule, and the result is meant to be filled.
+func (p *Path) Halftone(tone func(x, y float64) float64, opts HalftoneOptions) *Path {
+	q := &Path{}
+	bounds := p.Bounds()
+	if opts.Period <= 0.0 || bounds.W <= 0.0 || bounds.H <= 0.0 {
+		return q
+	}
+
+	// the extent of the bounds in screen coordinates, with u along and v perpendicular to the angle
+	toScreen := Identity.Rotate(-opts.Angle)
+	toPath := Identity.Rotate(opts.Angle)
+	screen := bounds.Transform(toScreen)
+	u0, u1 := math.Floor(screen.X/opts.Period), math.Ceil((screen.X+screen.W)/opts.Period)
+	v0, v1 := math.Floor(screen.Y/opts.Period), math.Ceil((screen.Y+screen.H)/opts.Period)
+	toneAt := func(u, v float64) (Point, float64) {
+		pos := toPath.Dot(Point{u * opts.Period, v * opts.Period})
+		return pos, math.Max(0.0, math.Min(tone(pos.X, pos.Y), 1.0))
+	}
+
+	if opts.Shape == HalftoneLines {
+		steps := 4.0 // samples of the tone per period along the lines
+		for v := v0; v < v1; v++ {
+			var top, bottom []Point
+			band := func() {
+				if 1 < len(top) {
+					q.MoveTo(top[0].X, top[0].Y)
+					for _, pos := range top[1:] {
+						q.LineTo(pos.X, pos.Y)
+					}
+					for i := len(bottom) - 1; 0 <= i; i-- {
+						q.LineTo(bottom[i].X, bottom[i].Y)
+					}
+					q.Close()
+				}
+				top, bottom = top[:0], bottom[:0]
+			}
+			for u := u0 * steps; u <= u1*steps; u++ {
+				_, t := toneAt(u/steps, v+0.5)
+				if t <= 0.0 {
+					band()
+					continue
+				}
+				width := t * opts.Period
+				top = append(top, toPath.Dot(Point{u / steps * opts.Period, (v+0.5)*opts.Period + width/2.0}))
+				bottom = append(bottom, toPath.Dot(Point{u / steps * opts.Period, (v+0.5)*opts.Period - width/2.0}))
+			}
+			band()
+		}
+		return q.And(p)
+	}
+
+	interior := pathInterior(p)
+	for v := v0; v < v1; v++ {
+		for u := u0; u < u1; u++ {
+			pos, t := toneAt(u+0.5, v+0.5)
+			if t <= 0.0 || !interior(pos) {
+				continue
+			}
+			r := opts.Period * math.Sqrt(t/math.Pi)
+			q = q.Append(Circle(r).Translate(pos.X, pos.Y))
+		}
+	}
+	return q
+}
+
+// ImageTone returns the tone of an image that is stretched over a rectangle for Stipple and Halftone, which is the darkness of the nearest pixel from zero for white to one for black, multiplied by its opacity. Points outside the rectangle have zero tone.
+func ImageTone(img image.Image, rect Rect) func(x, y float64) float64 {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	return func(x, y float64) float64 {
+		if x < rect.X || rect.X+rect.W <= x || y < rect.Y || rect.Y+rect.H <= y || w == 0 || h == 0 {
+			return 0.0
+		}
+		px := int((x - rect.X) / rect.W * float64(w))
+		py := int((rect.Y + rect.H - y) / rect.H * float64(h)) // the y-axis of images points down
+		c := color.NRGBA64Model.Convert(img.At(bounds.Min.X+px, bounds.Min.Y+py)).(color.NRGBA64)
+		lum := (0.2126*float64(c.R) + 0.7152*float64(c.G) + 0.0722*float64(c.B)) / 65535.0
+		return (1.0 - lum) * float64(c.A) / 65535.0
+	}
+}
@@ -0,0 +1,79 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPathStipple(t *testing.T) {
+	p := Rectangle(20.0, 10.0)
+	opts := DefaultStippleOptions
+
+	// darker tones have more dots, which are kept apart by the spacing
+	light := p.Stipple(func(x, y float64) float64 { return 0.25 }, opts)
+	dark := p.Stipple(func(x, y float64) float64 { return 1.0 }, opts)
+	test.That(t, 0 < len(light.Split()))
+	test.That(t, 3*len(light.Split()) < 2*len(dark.Split()), len(light.Split()), len(dark.Split()))
+	dots := dark.Split()
+	for i := range dots {
+		for j := i + 1; j < len(dots); j++ {
+			a, b := dots[i].Bounds(), dots[j].Bounds()
+			d := Point{a.X + a.W/2.0, a.Y + a.H/2.0}.Sub(Point{b.X + b.W/2.0, b.Y + b.H/2.0}).Length()
+			test.That(t, opts.Spacing-Epsilon <= d, "dots too close")
+		}
+	}
+
+	// the same seed gives the same result, and dots follow the tone and stay inside
+	test.T(t, dark, p.Stipple(func(x, y float64) float64 { return 1.0 }, opts))
+	half := p.Stipple(func(x, y float64) float64 {
+		if x < 10.0 {
+			return 1.0
+		}
+		return 0.0
+	}, opts)
+	bounds := half.Bounds()
+	test.That(t, -opts.DotSize/2.0-Epsilon <= bounds.X && bounds.X+bounds.W <= 10.0+opts.DotSize/2.0+Epsilon, bounds)
+	test.T(t, len(p.Stipple(func(x, y float64) float64 { return 0.0 }, opts).Split()), 0)
+}
+
+func TestPathHalftone(t *testing.T) {
+	p := Rectangle(10.0, 10.0)
+	opts := DefaultHalftoneOptions
+	opts.Angle = 0.0
+
+	// the area of the dots follows the tone
+	q := p.Halftone(func(x, y float64) float64 { return 0.5 }, opts)
+	test.T(t, len(q.Split()), 100)
+	test.Float(t, q.Split()[0].Bounds().W, 2.0*math.Sqrt(0.5/math.Pi))
+	test.T(t, len(p.Halftone(func(x, y float64) float64 { return 0.0 }, opts).Split()), 0)
+
+	// lines are clipped to the path
+	opts.Shape = HalftoneLines
+	opts.Angle = 30.0
+	q = p.Halftone(func(x, y float64) float64 { return 0.5 }, opts)
+	test.T(t, q.Bounds().X >= -Epsilon && q.Bounds().X+q.Bounds().W <= 10.0+Epsilon, true)
+	test.Float(t, math.Round(halftoneArea(q)), 50.0)
+}
+
+func TestImageTone(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.Black)
+	img.Set(1, 0, color.White)
+	tone := ImageTone(img, Rect{0.0, 0.0, 20.0, 10.0})
+	test.Float(t, tone(5.0, 5.0), 1.0)
+	test.Float(t, tone(15.0, 5.0), 0.0)
+	test.Float(t, tone(25.0, 5.0), 0.0)
+}
+
+// halftoneArea returns the area of the subpaths of a path, which must not overlap.
+func halftoneArea(p *Path) float64 {
+	area := 0.0
+	for _, ps := range p.Split() {
+		area += math.Abs(outlineArea(ps.Flatten().Coords()))
+	}
+	return area
+}
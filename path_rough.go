@@ -0,0 +1,91 @@
+package canvas
+
+import (
+	"math"
+	"math/rand"
+)
+
+// RoughOptions are the options for Rough to render a path in a sketchy, hand-drawn style.
+type RoughOptions struct {
+	Roughness float64 // maximum random offset of the points in millimeters, zero gives the original path
+	Bowing    float64 // amount of bowing of straight lines relative to Roughness
+	Strokes   int     // number of strokes drawn over each other
+	Seed      int64   // seed of the random generator, the same seed gives the same result
+}
+
+// DefaultRoughOptions are the default options for Rough, similar to the defaults of rough.js.
+var DefaultRoughOptions = RoughOptions{
+	Roughness: 1.0,
+	Bowing:    1.0,
+	Strokes:   2,
+	Seed:      0,
+}
+
+// Rough returns a sketchy version of the path that looks drawn by hand, similar to rough.js. Each segment is drawn as an open cubic Bézier with randomly perturbed endpoints and control points, and straight lines are slightly bowed. Every segment is drawn opts.Strokes times with different perturbations. The result consists of open subpaths and is meant to be stroked.
+func (p *Path) Rough(opts RoughOptions) *Path {
+	strokes := opts.Strokes
+	if strokes < 1 {
+		strokes = 1
+	}
+	rnd := rand.New(rand.NewSource(opts.Seed))
+	random := func(offset float64) Point {
+		return Point{offset * (2.0*rnd.Float64() - 1.0), offset * (2.0*rnd.Float64() - 1.0)}
+	}
+
+	q := &Path{}
+	cube := func(p0, p1, p2, p3 Point, offset float64) {
+		p0 = p0.Add(random(offset))
+		q.MoveTo(p0.X, p0.Y)
+		p1, p2, p3 = p1.Add(random(offset)), p2.Add(random(offset)), p3.Add(random(offset))
+		q.CubeTo(p1.X, p1.Y, p2.X, p2.Y, p3.X, p3.Y)
+	}
+	line := func(a, b Point, overlay bool) {
+		length := b.Sub(a).Length()
+		if length < Epsilon {
+			return
+		}
+		offset := math.Min(opts.Roughness, length/10.0)
+		if overlay {
+			offset /= 2.0
+		}
+
+		// bow the line perpendicular to its direction and let the control points diverge along it
+		bow := b.Sub(a).Rot90CCW().Mul(opts.Bowing * opts.Roughness / 100.0 * (2.0*rnd.Float64() - 1.0))
+		diverge := 0.2 + 0.2*rnd.Float64()
+		p1 := a.Interpolate(b, diverge).Add(bow)
+		p2 := a.Interpolate(b, 2.0*diverge).Add(bow)
+		cube(a, p1, p2, b, offset)
+	}
+	curve := func(p0, p1, p2, p3 Point, overlay bool) {
+		length := cubicBezierLength(p0, p1, p2, p3)
+		if length < Epsilon {
+			return
+		}
+		offset := math.Min(opts.Roughness, length/10.0)
+		if overlay {
+			offset /= 2.0
+		}
+		cube(p0, p1, p2, p3, offset)
+	}
+
+	for i := 0; i < strokes; i++ {
+		overlay := 0 < i
+		p.Iterate(func(p0, p1 Point) {
+			// nothing
+		}, func(p0, p1 Point) {
+			line(p0, p1, overlay)
+		}, func(p0, p1, p2 Point) {
+			c1, c2 := quadraticToCubicBezier(p0, p1, p2)
+			curve(p0, c1, c2, p2, overlay)
+		}, func(p0, p1, p2, p3 Point) {
+			curve(p0, p1, p2, p3, overlay)
+		}, func(p0 Point, rx, ry, phi float64, large, sweep bool, p1 Point) {
+			for _, bezier := range ellipseToCubicBeziers(p0, rx, ry, phi*math.Pi/180.0, large, sweep, p1) {
+				curve(bezier[0], bezier[1], bezier[2], bezier[3], overlay)
+			}
+		}, func(p0, p1 Point) {
+			line(p0, p1, overlay)
+		})
+	}
+	return q
+}
@@ -0,0 +1,31 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPathRough(t *testing.T) {
+	p := MustParseSVG("L10 0L10 10L0 10zM20 0Q25 5 30 0C30 5 25 10 20 10A5 5 0 0 1 20 0")
+
+	// without roughness it follows the original path
+	opts := DefaultRoughOptions
+	opts.Roughness = 0.0
+	q := p.Rough(opts)
+	test.T(t, len(q.Split()), 2*(4+2+len(ellipseToCubicBeziers(Point{20, 10}, 5, 5, 0, false, true, Point{20, 0}))))
+	test.T(t, q.Bounds(), p.Bounds())
+
+	// the same seed gives the same result
+	q = p.Rough(DefaultRoughOptions)
+	test.T(t, q, p.Rough(DefaultRoughOptions))
+	opts = DefaultRoughOptions
+	opts.Seed = 1
+	test.That(t, !q.Equals(p.Rough(opts)))
+
+	// points stay within the roughness of the original
+	bounds := p.Bounds()
+	rough := q.Bounds()
+	test.That(t, bounds.X-1.0 <= rough.X && rough.X+rough.W <= bounds.X+bounds.W+1.0, "horizontal bounds")
+	test.That(t, bounds.Y-1.0 <= rough.Y && rough.Y+rough.H <= bounds.Y+bounds.H+1.0, "vertical bounds")
+}
@@ -0,0 +1,319 @@
+package canvas
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Inset insets the path by d and returns a new path, or outsets it if d is negative. Unlike Offset, it uses the straight skeleton of the path so that corners stay sharp and the topology changes correctly: parts that become too thin disappear, a polygon may split into several, and holes may close up or merge. The path is settled using the NonZero fill rule and flattened first.
+func (p *Path) Inset(d float64) *Path {
+	polygons := booleanPolygons(p.Settle(NonZero))
+	if d == 0.0 {
+		return polygonsToPath(polygons)
+	}
+
+	outset := d < 0.0
+	if outset {
+		// the outset is the inset of the area outside the path
+		for _, coords := range polygons {
+			reversePointsInPlace(coords)
+		}
+	}
+	s := newSkeleton(polygons)
+	s.run(math.Abs(d))
+	polygons = s.wavefront(math.Abs(d))
+	if outset {
+		for _, coords := range polygons {
+			reversePointsInPlace(coords)
+		}
+	}
+	return polygonsToPath(polygons)
+}
+
+// StraightSkeleton returns the straight skeleton of the path as a set of line segments. It is traced by the corners of the path as it is inset until nothing remains, and can be used to construct roofs or find the medial axis for placing labels. The path is settled using the NonZero fill rule and flattened first.
+func (p *Path) StraightSkeleton() *Path {
+	s := newSkeleton(booleanPolygons(p.Settle(NonZero)))
+	s.run(math.Inf(1))
+
+	q := &Path{}
+	for _, arc := range s.arcs {
+		q.MoveTo(arc[0].X, arc[0].Y)
+		q.LineTo(arc[1].X, arc[1].Y)
+	}
+	return q
+}
+
+func polygonsToPath(polygons [][]Point) *Path {
+	p := &Path{}
+	for _, coords := range polygons {
+		p.MoveTo(coords[0].X, coords[0].Y)
+		for _, coord := range coords[1:] {
+			p.LineTo(coord.X, coord.Y)
+		}
+		p.Close()
+	}
+	return p
+}
+
+func reversePointsInPlace(points []Point) {
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+}
+
+////////////////////////////////////////////////////////////////
+
+// skeletonEdge is an edge of the original polygon, which moves inwards with unit speed, ie. at time t it lies on the line n·x = n·a + t.
+type skeletonEdge struct {
+	a, d, n Point // point on the edge, unit direction and unit inward normal
+}
+
+// skeletonVertex is a vertex of the wavefront, which moves along the bisector of its edges.
+type skeletonVertex struct {
+	p           Point   // position at time t
+	t           float64 // creation time
+	v           Point   // velocity
+	left, right *skeletonEdge
+	prev, next  *skeletonVertex
+	active      bool
+	reflex      bool
+}
+
+func (v *skeletonVertex) pos(t float64) Point {
+	return v.p.Add(v.v.Mul(t - v.t))
+}
+
+type skeletonEvent struct {
+	t    float64
+	a, b *skeletonVertex // edge event between a and a.next, or split event of a
+	e    *skeletonEdge   // split event onto edge e
+}
+
+type skeletonEvents []skeletonEvent
+
+func (q skeletonEvents) Len() int            { return len(q) }
+func (q skeletonEvents) Less(i, j int) bool  { return q[i].t < q[j].t }
+func (q skeletonEvents) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *skeletonEvents) Push(x interface{}) { *q = append(*q, x.(skeletonEvent)) }
+func (q *skeletonEvents) Pop() interface{} {
+	old := *q
+	event := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return event
+}
+
+// skeleton simulates the wavefront of inwards moving edges, see "Straight Skeleton Implementation" by P. Felkel and S. Obdržálek from 1998. Polygons must have their interior to the left, ie. counter clockwise outer contours and clockwise holes.
+type skeleton struct {
+	vertices []*skeletonVertex
+	events   skeletonEvents
+	arcs     [][2]Point
+	eps      float64
+}
+
+func newSkeleton(polygons [][]Point) *skeleton {
+	s := &skeleton{}
+	bounds := Rect{}
+	for _, coords := range polygons {
+		bounds = bounds.Add(polygonBounds(coords))
+	}
+	s.eps = 1e-9 * math.Max(1.0, math.Max(bounds.W, bounds.H))
+
+	for _, coords := range polygons {
+		coords = removeCollinear(append([]Point{}, coords...))
+		if len(coords) < 3 {
+			continue
+		}
+		edges := make([]*skeletonEdge, len(coords))
+		for i, a := range coords {
+			d := coords[(i+1)%len(coords)].Sub(a).Norm(1.0)
+			edges[i] = &skeletonEdge{a, d, d.Rot90CCW()}
+		}
+		var first, last *skeletonVertex
+		for i, a := range coords {
+			v := s.newVertex(a, 0.0, edges[(i+len(edges)-1)%len(edges)], edges[i])
+			if first == nil {
+				first = v
+			} else {
+				last.next, v.prev = v, last
+			}
+			last = v
+		}
+		last.next, first.prev = first, last
+	}
+
+	for _, v := range s.vertices {
+		s.addEvents(v)
+	}
+	return s
+}
+
+func (s *skeleton) newVertex(p Point, t float64, left, right *skeletonEdge) *skeletonVertex {
+	// the velocity has unit speed perpendicular to both edges
+	var vel Point
+	if denom := 1.0 + left.n.Dot(right.n); Epsilon < denom {
+		vel = left.n.Add(right.n).Div(denom)
+	}
+	v := &skeletonVertex{
+		p:      p,
+		t:      t,
+		v:      vel,
+		left:   left,
+		right:  right,
+		active: true,
+		reflex: left.d.PerpDot(right.d) < 0.0,
+	}
+	s.vertices = append(s.vertices, v)
+	return v
+}
+
+// addEvents adds the edge events with its neighbours and split events for a reflex vertex.
+func (s *skeleton) addEvents(v *skeletonVertex) {
+	s.addEdgeEvent(v.prev)
+	s.addEdgeEvent(v)
+	if !v.reflex {
+		return
+	}
+
+	p0 := v.pos(0.0)
+	seen := map[*skeletonEdge]bool{}
+	for _, w := range s.vertices {
+		e := w.right
+		if !w.active || seen[e] || e == v.left || e == v.right {
+			continue
+		}
+		seen[e] = true
+		if rate := e.n.Dot(v.v) - 1.0; rate < 0.0 {
+			if t := (e.n.Dot(e.a) - e.n.Dot(p0)) / rate; v.t-s.eps <= t {
+				heap.Push(&s.events, skeletonEvent{math.Max(t, v.t), v, nil, e})
+			}
+		}
+	}
+}
+
+// addEdgeEvent adds the event where the edge between v and v.next shrinks to zero length.
+func (s *skeleton) addEdgeEvent(v *skeletonVertex) {
+	w := v.next
+	t := math.Max(v.t, w.t)
+	length := w.pos(t).Sub(v.pos(t)).Dot(v.right.d)
+	if rate := w.v.Sub(v.v).Dot(v.right.d); length <= s.eps {
+		heap.Push(&s.events, skeletonEvent{t, v, w, nil})
+	} else if rate < 0.0 {
+		heap.Push(&s.events, skeletonEvent{t - length/rate, v, w, nil})
+	}
+}
+
+// run processes all events up to time tmax.
+func (s *skeleton) run(tmax float64) {
+	for 0 < len(s.events) && s.events[0].t <= tmax {
+		event := heap.Pop(&s.events).(skeletonEvent)
+		if event.e == nil {
+			s.edgeEvent(event.t, event.a, event.b)
+		} else {
+			s.splitEvent(event.t, event.a, event.e)
+		}
+	}
+}
+
+func (s *skeleton) deactivate(v *skeletonVertex, t float64) {
+	v.active = false
+	if q := v.pos(t); !q.Equals(v.p) {
+		s.arcs = append(s.arcs, [2]Point{v.p, q})
+	}
+}
+
+func (s *skeleton) edgeEvent(t float64, a, b *skeletonVertex) {
+	if !a.active || !b.active || a.next != b {
+		return
+	}
+	p := a.pos(t).Interpolate(b.pos(t), 0.5)
+	s.deactivate(a, t)
+	s.deactivate(b, t)
+
+	prev, next := a.prev, b.next
+	if prev == b || prev == next {
+		// the loop collapses
+		for v := prev; v.active; v = v.next {
+			s.deactivate(v, t)
+		}
+		if last := prev.pos(t); !last.Equals(p) {
+			// the loop has collapsed into a line, eg. the ridge of a rectangle
+			s.arcs = append(s.arcs, [2]Point{last, p})
+		}
+		return
+	}
+
+	v := s.newVertex(p, t, a.left, b.right)
+	prev.next, v.prev = v, prev
+	v.next, next.prev = next, v
+	s.addEvents(v)
+}
+
+func (s *skeleton) splitEvent(t float64, v *skeletonVertex, e *skeletonEdge) {
+	if !v.active {
+		return
+	}
+
+	// find the part of the edge that is hit
+	p := v.pos(t)
+	var x *skeletonVertex
+	for _, w := range s.vertices {
+		if !w.active || w.right != e || w == v || w.next == v {
+			continue
+		}
+		a, b := w.pos(t), w.next.pos(t)
+		if pos := p.Sub(a).Dot(e.d); -s.eps <= pos && pos <= b.Sub(a).Dot(e.d)+s.eps {
+			x = w
+			break
+		}
+	}
+	if x == nil {
+		return
+	}
+	y := x.next
+	s.deactivate(v, t)
+
+	prev, next := v.prev, v.next
+	v1 := s.newVertex(p, t, v.left, e)
+	v2 := s.newVertex(p, t, e, v.right)
+	prev.next, v1.prev = v1, prev
+	v1.next, y.prev = y, v1
+	x.next, v2.prev = v2, x
+	v2.next, next.prev = next, v2
+
+	for _, w := range []*skeletonVertex{v1, v2} {
+		n := 1
+		for u := w.next; u != w; u = u.next {
+			n++
+		}
+		if n < 3 {
+			for u := w; u.active; u = u.next {
+				s.deactivate(u, t)
+			}
+		}
+	}
+	for _, w := range []*skeletonVertex{v1, v2} {
+		if w.active {
+			s.addEvents(w)
+		}
+	}
+}
+
+// wavefront returns the polygons of the wavefront at time t, which must be after all processed events.
+func (s *skeleton) wavefront(t float64) [][]Point {
+	polygons := [][]Point{}
+	visited := map[*skeletonVertex]bool{}
+	for _, v := range s.vertices {
+		if !v.active || visited[v] {
+			continue
+		}
+		coords := []Point{}
+		for w := v; !visited[w]; w = w.next {
+			visited[w] = true
+			coords = append(coords, w.pos(t))
+		}
+		if coords = removeCollinear(coords); 2 < len(coords) {
+			polygons = append(polygons, coords)
+		}
+	}
+	return polygons
+}
@@ -0,0 +1,36 @@
+package canvas
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPathInset(t *testing.T) {
+	var tts = []struct {
+		orig  string
+		d     float64
+		inset string
+	}{
+		{"M0 0L10 0L10 10L0 10z", 0.0, "M0 0L10 0L10 10L0 10z"},
+		{"M0 0L10 0L10 10L0 10z", 1.0, "M1 1L9 1L9 9L1 9z"},
+		{"M0 0L10 0L10 10L0 10z", 6.0, ""},
+		{"M0 0L10 0L10 10L0 10z", -1.0, "M-1 -1L11 -1L11 11L-1 11z"},
+		{"M0 0L20 0L20 10L10 10L10 20L0 20z", 2.0, "M2 2L18 2L18 8L8 8L8 18L2 18z"}, // reflex corner stays sharp
+		{"M0 0L10 0L10 4L20 4L20 0L30 0L30 10L20 10L20 6L10 6L10 10L0 10z", 1.5, "M1.5 1.5L8.5 1.5L8.5 8.5L1.5 8.5zM21.5 1.5L28.5 1.5L28.5 8.5L21.5 8.5z"}, // splits in two
+		{"M0 0L30 0L30 30L0 30zM10 10L10 20L20 20L20 10z", 2.0, "M2 2L28 2L28 28L2 28zM8 8L8 22L22 22L22 8z"},
+		{"M0 0L30 0L30 30L0 30zM10 10L10 20L20 20L20 10z", -4.0, "M-4 -4L34 -4L34 34L-4 34zM14 14L14 16L16 16L16 14z"},
+		{"M0 0L30 0L30 30L0 30zM10 10L10 20L20 20L20 10z", -6.0, "M-6 -6L36 -6L36 36L-6 36z"}, // hole disappears
+	}
+	for j, tt := range tts {
+		t.Run(fmt.Sprintf("%v", j), func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.orig).Inset(tt.d), MustParseSVG(tt.inset))
+		})
+	}
+}
+
+func TestPathStraightSkeleton(t *testing.T) {
+	test.T(t, MustParseSVG("M0 0L10 0L10 10L0 10z").StraightSkeleton(), MustParseSVG("M0 10L5 5M0 0L5 5M10 0L5 5M10 10L5 5"))
+	test.T(t, MustParseSVG("M0 0L20 0L20 10L0 10z").StraightSkeleton(), MustParseSVG("M0 10L5 5M0 0L5 5M20 0L15 5M20 10L15 5M5 5L15 5"))
+}
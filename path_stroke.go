@@ -301,9 +301,8 @@ type pathStrokeState struct {
 	large, sweep                bool    // arcs
 }
 
-// offsetSegment returns the rhs and lhs paths from offsetting a path segment.
-// It closes rhs and lhs when p is closed as well.
-func offsetSegment(p *Path, halfWidth float64, cr Capper, jr Joiner) (*Path, *Path) {
+// strokeStates returns the states of the path segments of a subpath for offsetting, and whether the subpath is closed.
+func strokeStates(p *Path, halfWidth float64) ([]pathStrokeState, bool) {
 	// only non-empty paths are evaluated
 	closed := false
 	states := []pathStrokeState{}
@@ -315,6 +314,9 @@ func offsetSegment(p *Path, halfWidth float64, cr Capper, jr Joiner) (*Path, *Pa
 			end = Point{p.d[i+1], p.d[i+2]}
 		case lineToCmd:
 			end = Point{p.d[i+1], p.d[i+2]}
+			if start.Equals(end) {
+				break // zero-length subpath
+			}
 			n := end.Sub(start).Rot90CW().Norm(halfWidth)
 			states = append(states, pathStrokeState{
 				cmd: lineToCmd,
@@ -336,21 +338,51 @@ func offsetSegment(p *Path, halfWidth float64, cr Capper, jr Joiner) (*Path, *Pa
 				cp2 = Point{p.d[i+3], p.d[i+4]}
 				end = Point{p.d[i+5], p.d[i+6]}
 			}
-			n0 := cubicBezierNormal(start, cp1, cp2, end, 0.0, halfWidth)
-			n1 := cubicBezierNormal(start, cp1, cp2, end, 1.0, halfWidth)
-			r0 := cubicBezierCurvatureRadius(start, cp1, cp2, end, 0.0)
-			r1 := cubicBezierCurvatureRadius(start, cp1, cp2, end, 1.0)
-			states = append(states, pathStrokeState{
-				cmd: cubeToCmd,
-				p0:  start,
-				p1:  end,
-				n0:  n0,
-				n1:  n1,
-				r0:  r0,
-				r1:  r1,
-				cp1: cp1,
-				cp2: cp2,
-			})
+			if ts, ok := cubicBezierCollinearTurns(start, cp1, cp2, end); ok {
+				// a straight curve is stroked as lines between the points where it turns back
+				p0 := start
+				for _, t := range ts {
+					p1 := cubicBezierPos(start, cp1, cp2, end, t)
+					if !p0.Equals(p1) {
+						n := p1.Sub(p0).Rot90CW().Norm(halfWidth)
+						states = append(states, pathStrokeState{
+							cmd: lineToCmd,
+							p0:  p0,
+							p1:  p1,
+							n0:  n,
+							n1:  n,
+							r0:  math.NaN(),
+							r1:  math.NaN(),
+						})
+					}
+					p0 = p1
+				}
+				break
+			}
+
+			// split at a cusp so that both parts are joined by the joiner, the offset curve cannot follow the reversal of direction
+			beziers := [][4]Point{{start, cp1, cp2, end}}
+			if t := cubicBezierCusp(start, cp1, cp2, end); !math.IsNaN(t) {
+				p0, p1, p2, p3, q0, q1, q2, q3 := cubicBezierSplit(start, cp1, cp2, end, t)
+				beziers = [][4]Point{{p0, p1, p2, p3}, {q0, q1, q2, q3}}
+			}
+			for _, b := range beziers {
+				n0 := cubicBezierNormal(b[0], b[1], b[2], b[3], 0.0, halfWidth)
+				n1 := cubicBezierNormal(b[0], b[1], b[2], b[3], 1.0, halfWidth)
+				r0 := cubicBezierCurvatureRadius(b[0], b[1], b[2], b[3], 0.0)
+				r1 := cubicBezierCurvatureRadius(b[0], b[1], b[2], b[3], 1.0)
+				states = append(states, pathStrokeState{
+					cmd: cubeToCmd,
+					p0:  b[0],
+					p1:  b[3],
+					n0:  n0,
+					n1:  n1,
+					r0:  r0,
+					r1:  r1,
+					cp1: b[1],
+					cp2: b[2],
+				})
+			}
 		case arcToCmd:
 			rx, ry, phi := p.d[i+1], p.d[i+2], p.d[i+3]
 			large, sweep := toArcFlags(p.d[i+4])
@@ -395,7 +427,16 @@ func offsetSegment(p *Path, halfWidth float64, cr Capper, jr Joiner) (*Path, *Pa
 		start = end
 		i += cmdLen(cmd)
 	}
+	return states, closed
+}
 
+// offsetSegment returns the rhs and lhs paths from offsetting a path segment.
+// It closes rhs and lhs when p is closed as well.
+func offsetSegment(p *Path, halfWidth float64, cr Capper, jr Joiner) (*Path, *Path) {
+	states, closed := strokeStates(p, halfWidth)
+	if len(states) == 0 {
+		return nil, nil
+	}
 	rhs, lhs := &Path{}, &Path{}
 	rStart := states[0].p0.Add(states[0].n0)
 	lStart := states[0].p0.Sub(states[0].n0)
@@ -405,33 +446,7 @@ func offsetSegment(p *Path, halfWidth float64, cr Capper, jr Joiner) (*Path, *Pa
 	rhsInnerBends := []int{}
 	lhsInnerBends := []int{}
 	for i, cur := range states {
-		switch cur.cmd {
-		case lineToCmd:
-			rEnd := cur.p1.Add(cur.n1)
-			lEnd := cur.p1.Sub(cur.n1)
-			rhs.LineTo(rEnd.X, rEnd.Y)
-			lhs.LineTo(lEnd.X, lEnd.Y)
-		case cubeToCmd:
-			rhs = rhs.Join(strokeCubicBezier(cur.p0, cur.cp1, cur.cp2, cur.p1, halfWidth, Tolerance))
-			lhs = lhs.Join(strokeCubicBezier(cur.p0, cur.cp1, cur.cp2, cur.p1, -halfWidth, Tolerance))
-		case arcToCmd:
-			rStart := cur.p0.Add(cur.n0)
-			lStart := cur.p0.Sub(cur.n0)
-			rEnd := cur.p1.Add(cur.n1)
-			lEnd := cur.p1.Sub(cur.n1)
-			dr := halfWidth
-			if !cur.sweep { // bend to the right, ie. CW
-				dr = -dr
-			}
-
-			rLambda := ellipseRadiiCorrection(rStart, cur.rx+dr, cur.ry+dr, cur.rot*math.Pi/180.0, rEnd)
-			lLambda := ellipseRadiiCorrection(lStart, cur.rx-dr, cur.ry-dr, cur.rot*math.Pi/180.0, lEnd)
-			if rLambda <= 1.0 && lLambda <= 1.0 {
-				rLambda, lLambda = 1.0, 1.0
-			}
-			rhs.ArcTo(rLambda*(cur.rx+dr), rLambda*(cur.ry+dr), cur.rot, cur.large, cur.sweep, rEnd.X, rEnd.Y)
-			lhs.ArcTo(lLambda*(cur.rx-dr), lLambda*(cur.ry-dr), cur.rot, cur.large, cur.sweep, lEnd.X, lEnd.Y)
-		}
+		rhs, lhs = cur.offset(rhs, lhs, halfWidth)
 
 		// join the cur and next path segments
 		if i+1 < len(states) || closed {
@@ -443,14 +458,15 @@ func offsetSegment(p *Path, halfWidth float64, cr Capper, jr Joiner) (*Path, *Pa
 			}
 
 			if !cur.n1.Equals(next.n0) {
+				rhsLen, lhsLen := len(rhs.d), len(lhs.d)
 				jr.Join(rhs, lhs, halfWidth, cur.p1, cur.n1, next.n0, cur.r1, next.r0)
 
 				if !cur.n1.Equals(next.n0.Neg()) {
-					// all turns except 0 degrees and 180 degrees are added
+					// all turns except 0 degrees and 180 degrees are added, unless the join added no line on the inner side since its ends coincide
 					cw := cur.n1.Rot90CW().Dot(next.n0) >= 0.0
-					if cw {
+					if cw && rhsLen < len(rhs.d) && rhs.d[len(rhs.d)-1] == lineToCmd {
 						rhsInnerBends = append(rhsInnerBends, len(rhs.d)-cmdLen(lineToCmd))
-					} else {
+					} else if !cw && lhsLen < len(lhs.d) && lhs.d[len(lhs.d)-1] == lineToCmd {
 						lhsInnerBends = append(lhsInnerBends, len(lhs.d)-cmdLen(lineToCmd))
 					}
 				}
@@ -476,6 +492,91 @@ func offsetSegment(p *Path, halfWidth float64, cr Capper, jr Joiner) (*Path, *Pa
 	return rhs, nil
 }
 
+// offset appends the offset of the segment to the rhs and lhs paths, which must end at the start of the offset segment.
+func (cur pathStrokeState) offset(rhs, lhs *Path, halfWidth float64) (*Path, *Path) {
+	switch cur.cmd {
+	case lineToCmd:
+		rEnd := cur.p1.Add(cur.n1)
+		lEnd := cur.p1.Sub(cur.n1)
+		rhs.LineTo(rEnd.X, rEnd.Y)
+		lhs.LineTo(lEnd.X, lEnd.Y)
+	case cubeToCmd:
+		rhs = rhs.Join(strokeCubicBezier(cur.p0, cur.cp1, cur.cp2, cur.p1, halfWidth, Tolerance))
+		lhs = lhs.Join(strokeCubicBezier(cur.p0, cur.cp1, cur.cp2, cur.p1, -halfWidth, Tolerance))
+	case arcToCmd:
+		rStart := cur.p0.Add(cur.n0)
+		lStart := cur.p0.Sub(cur.n0)
+		rEnd := cur.p1.Add(cur.n1)
+		lEnd := cur.p1.Sub(cur.n1)
+		dr := halfWidth
+		if !cur.sweep { // bend to the right, ie. CW
+			dr = -dr
+		}
+
+		rLambda := ellipseRadiiCorrection(rStart, cur.rx+dr, cur.ry+dr, cur.rot*math.Pi/180.0, rEnd)
+		lLambda := ellipseRadiiCorrection(lStart, cur.rx-dr, cur.ry-dr, cur.rot*math.Pi/180.0, lEnd)
+		if rLambda <= 1.0 && lLambda <= 1.0 {
+			rLambda, lLambda = 1.0, 1.0
+		}
+		rhs.ArcTo(rLambda*(cur.rx+dr), rLambda*(cur.ry+dr), cur.rot, cur.large, cur.sweep, rEnd.X, rEnd.Y)
+		lhs.ArcTo(lLambda*(cur.rx-dr), lLambda*(cur.ry-dr), cur.rot, cur.large, cur.sweep, lEnd.X, lEnd.Y)
+	}
+	return rhs, lhs
+}
+
+// cubicBezierCusp returns the position t of a cusp, where the curve stops and reverses direction, or NaN if there is none. Cusps are found at the local minima of the speed along the curve.
+func cubicBezierCusp(p0, p1, p2, p3 Point) float64 {
+	// B'(t) = 3(At^2 + Bt + C) and B''(t) = 3(2At + B), solve for B'(t)·B''(t) = 0
+	d0, d1, d2 := p1.Sub(p0), p2.Sub(p1), p3.Sub(p2)
+	A := d0.Sub(d1.Mul(2.0)).Add(d2)
+	B := d1.Sub(d0).Mul(2.0)
+	C := d0
+	t0, t1, t2 := solveCubicFormula(2.0*A.Dot(A), 3.0*A.Dot(B), B.Dot(B)+2.0*C.Dot(A), C.Dot(B))
+	for _, t := range []float64{t0, t1, t2} {
+		if math.IsNaN(t) || t <= Epsilon || 1.0-Epsilon <= t {
+			continue
+		} else if cubicBezierDeriv(p0, p1, p2, p3, t).Length() < Epsilon {
+			return t
+		}
+	}
+	return math.NaN()
+}
+
+// cubicBezierCollinearTurns returns whether all points of the curve lie on a straight line, and if so, the positions t where the curve turns back followed by t=1.
+func cubicBezierCollinearTurns(p0, p1, p2, p3 Point) ([]float64, bool) {
+	// take the direction towards the farthest point
+	dir := Point{}
+	for _, p := range []Point{p1, p2, p3} {
+		if d := p.Sub(p0); dir.Length() < d.Length() {
+			dir = d
+		}
+	}
+	if dir.Length() < Epsilon {
+		return nil, false
+	}
+	dir = dir.Norm(1.0)
+	for _, p := range []Point{p1, p2, p3} {
+		if Epsilon <= math.Abs(dir.PerpDot(p.Sub(p0))) {
+			return nil, false
+		}
+	}
+
+	// the derivative along the line is zero where the curve turns back
+	x1, x2, x3 := dir.Dot(p1.Sub(p0)), dir.Dot(p2.Sub(p0)), dir.Dot(p3.Sub(p0))
+	d0, d1, d2 := x1, x2-x1, x3-x2
+	t0, t1 := solveQuadraticFormula(d0-2.0*d1+d2, 2.0*(d1-d0), d0)
+	if t1 < t0 {
+		t0, t1 = t1, t0
+	}
+	ts := []float64{}
+	for _, t := range []float64{t0, t1} {
+		if !math.IsNaN(t) && Epsilon < t && t < 1.0-Epsilon {
+			ts = append(ts, t)
+		}
+	}
+	return append(ts, 1.0), true
+}
+
 func closeInnerBends(p *Path, indices []int, closed bool) {
 	// closed paths end with a LineTo to the original MoveTo but are not (yet) closed
 	di := 0
@@ -552,27 +653,378 @@ func (p *Path) Offset(w float64, fillRule FillRule) *Path {
 }
 
 // Stroke converts a path into a stroke of width w and returns a new path. It uses cr to cap the start and end of the path, and
-// jr to join all path elemtents. If the path closes itself, it will use a join between the start and end instead of capping them.
+// jr to join all path elemtents. If the path closes itself, it will use a join between the start and end instead of capping them. Zero-length subpaths are stroked as two caps.
 // The tolerance is the maximum deviation from the original path when flattening Béziers and optimizing the stroke.
 func (p *Path) Stroke(w float64, cr Capper, jr Joiner) *Path {
+	span := startTrace(FlatteningPhase)
 	q := &Path{}
 	halfWidth := w / 2.0
 	for _, ps := range p.Split() {
+		if ps.zeroLength() {
+			q = q.Append(strokeDot(ps.StartPos(), halfWidth, cr))
+			continue
+		}
 		rhs, lhs := offsetSegment(ps, halfWidth, cr, jr)
 		if lhs != nil { // closed path
-			// inner path should go opposite direction to cancel the outer path
-			if ps.CCW() {
-				lhs = lhs.Reverse()
-				q = q.Append(rhs)
-				q = q.Append(lhs)
+			outer, inner := rhs, lhs
+			if !ps.CCW() {
+				outer, inner = lhs, rhs
+			}
+			if offsetCollapsed(ps, inner, halfWidth) {
+				// the stroke is wider than (a part of) the path and the inner path folds over itself
+				q = q.Append(strokePieces(ps, halfWidth, cr, jr))
 			} else {
-				rhs = rhs.Reverse()
-				q = q.Append(lhs)
-				q = q.Append(rhs)
+				// inner path should go opposite direction to cancel the outer path
+				q = q.Append(outer)
+				q = q.Append(inner.Reverse())
 			}
 		} else {
 			q = q.Append(rhs)
 		}
 	}
+	span.endPath(q)
+	return q
+}
+
+// strokeDot returns the stroke of a zero-length subpath at pivot, which consists of two caps facing opposite directions so that round caps give a circle and square caps give a square. Butt caps have no area and give an empty path.
+func strokeDot(pivot Point, halfWidth float64, cr Capper) *Path {
+	if _, ok := cr.(ButtCapper); ok {
+		return &Path{}
+	}
+	n0 := Point{0.0, -halfWidth}
+	start := pivot.Add(n0)
+	p := &Path{}
+	p.MoveTo(start.X, start.Y)
+	cr.Cap(p, halfWidth, pivot, n0)
+	cr.Cap(p, halfWidth, pivot, n0.Neg())
+	return p.Close()
+}
+
+// WidthProfile returns the stroke width at position t in [0,1] along the arc length of a subpath.
+type WidthProfile func(t float64) float64
+
+// TaperProfile returns a width profile of width w that linearly tapers to zero at the start and end over the given fractions of the arc length.
+func TaperProfile(w, start, end float64) WidthProfile {
+	return func(t float64) float64 {
+		if t < start {
+			return w * t / start
+		} else if 1.0-end < t {
+			return w * (1.0 - t) / end
+		}
+		return w
+	}
+}
+
+// strokeProfileSamples is the minimum number of points along a subpath at which a width profile is evaluated.
+const strokeProfileSamples = 100
+
+// StrokeProfile converts a path into a stroke of variable width and returns a new path. The width along each subpath is given by profile, which allows for calligraphic and tapered strokes. It uses cr to cap the start and end of open subpaths. The path is flattened first and its vertices are joined by miters limited to twice the local width.
+func (p *Path) StrokeProfile(profile WidthProfile, cr Capper) *Path {
+	q := &Path{}
+	for _, ps := range p.Flatten().Split() {
+		q = q.Append(strokeProfile(ps, profile, cr, strokeProfileSamples))
+	}
 	return q
 }
+
+// StrokeWidths converts a path into a stroke of variable width and returns a new path, where widths gives the width at each coordinate of the path as returned by Coords, such as the pressure of a drawing tablet. The width is interpolated linearly along the arc length in between coordinates. It uses cr to cap the start and end of open subpaths.
+func (p *Path) StrokeWidths(widths []float64, cr Capper) *Path {
+	q := &Path{}
+	k := 0 // index into widths
+	for _, ps := range p.Split() {
+		// arc length position of each coordinate
+		ts := []float64{0.0}
+		ws := []float64{}
+		var start Point
+		for i := 0; i < len(ps.d); {
+			cmd := ps.d[i]
+			n := cmdLen(cmd)
+			end := Point{ps.d[i+n-3], ps.d[i+n-2]}
+			if cmd != moveToCmd {
+				if cmd == closeCmd && start.Equals(end) {
+					break
+				}
+				seg := (&Path{}).MoveTo(start.X, start.Y)
+				seg.d = append(seg.d, ps.d[i:i+n]...)
+				ts = append(ts, ts[len(ts)-1]+seg.Length())
+			}
+			if k < len(widths) {
+				ws = append(ws, widths[k])
+				k++
+			} else if 0 < len(ws) {
+				ws = append(ws, ws[len(ws)-1])
+			} else {
+				ws = append(ws, 0.0)
+			}
+			start = end
+			i += n
+		}
+		length := ts[len(ts)-1]
+		if length == 0.0 {
+			continue
+		}
+		for i := range ts {
+			ts[i] /= length
+		}
+
+		profile := func(t float64) float64 {
+			for i := 1; i < len(ts); i++ {
+				if t <= ts[i] {
+					if ts[i] == ts[i-1] {
+						return ws[i]
+					}
+					return ws[i-1] + (ws[i]-ws[i-1])*(t-ts[i-1])/(ts[i]-ts[i-1])
+				}
+			}
+			return ws[len(ws)-1]
+		}
+		q = q.Append(strokeProfile(ps.Flatten(), profile, cr, 0))
+	}
+	return q
+}
+
+// strokeProfile strokes a flattened subpath with a variable width. Segments are subdivided so that there are at least the given number of samples along the path.
+func strokeProfile(p *Path, profile WidthProfile, cr Capper, samples int) *Path {
+	closed := p.Closed()
+	maxLength := math.Inf(1)
+	if 0 < samples {
+		maxLength = p.Length() / float64(samples)
+	}
+	coords := []Point{}
+	for _, coord := range p.Coords() {
+		if len(coords) == 0 {
+			coords = append(coords, coord)
+		} else if prev := coords[len(coords)-1]; !prev.Equals(coord) {
+			n := math.Ceil(coord.Sub(prev).Length() / maxLength)
+			for j := 1.0; j < n; j++ {
+				coords = append(coords, prev.Interpolate(coord, j/n))
+			}
+			coords = append(coords, coord)
+		}
+	}
+	if closed && 1 < len(coords) && coords[0].Equals(coords[len(coords)-1]) {
+		coords = coords[:len(coords)-1]
+	}
+	if len(coords) < 2 {
+		return &Path{}
+	}
+
+	// normals of the segments, for closed paths this includes the segment back to the start
+	n := len(coords)
+	if !closed {
+		n--
+	}
+	normals := make([]Point, n)
+	ts := make([]float64, len(coords)+1)
+	for i := 0; i < n; i++ {
+		d := coords[(i+1)%len(coords)].Sub(coords[i])
+		normals[i] = d.Rot90CW().Norm(1.0)
+		ts[i+1] = ts[i] + d.Length()
+	}
+	length := ts[n]
+
+	rhs, lhs := &Path{}, &Path{}
+	ns := make([]Point, len(coords))
+	for i, coord := range coords {
+		halfWidth := math.Max(0.0, profile(ts[i]/length)/2.0)
+		var normal Point
+		if !closed && i == 0 {
+			normal = normals[0]
+		} else if !closed && i == len(coords)-1 {
+			normal = normals[n-1]
+		} else {
+			// miter along the bisector of both segments, limited to twice the width
+			n0, n1 := normals[(i-1+n)%n], normals[i%n]
+			normal = n0.Add(n1)
+			if normal.Length() < Epsilon {
+				normal = n1
+			} else {
+				normal = normal.Norm(1.0)
+				normal = normal.Mul(math.Min(1.0/normal.Dot(n1), 2.0))
+			}
+		}
+		ns[i] = normal.Mul(halfWidth)
+
+		rPos, lPos := coord.Add(ns[i]), coord.Sub(ns[i])
+		if i == 0 {
+			rhs.MoveTo(rPos.X, rPos.Y)
+			lhs.MoveTo(lPos.X, lPos.Y)
+		} else {
+			rhs.LineTo(rPos.X, rPos.Y)
+			lhs.LineTo(lPos.X, lPos.Y)
+		}
+	}
+
+	if closed {
+		rhs.Close()
+		lhs.Close()
+		// inner path should go opposite direction to cancel the outer path
+		if p.CCW() {
+			return rhs.Append(lhs.Reverse())
+		}
+		return lhs.Append(rhs.Reverse())
+	}
+
+	// default to CCW direction
+	last := len(coords) - 1
+	cr.Cap(rhs, ns[last].Length(), coords[last], ns[last])
+	rhs = rhs.Join(lhs.Reverse())
+	cr.Cap(rhs, ns[0].Length(), coords[0], ns[0].Neg())
+	return rhs.Close()
+}
+
+// Nib is the shape of a calligraphic pen tip, which is swept along a path to create a stroke whose width depends on the direction of the path.
+type Nib struct {
+	points []Point // convex polygon in CCW order centered at the origin
+}
+
+// FlatNib returns a flat nib of width w at an angle in degrees counter clockwise from the x-axis, such as a broad-edged pen. Lines along the angle are drawn thin, lines perpendicular to it are drawn with width w.
+func FlatNib(w, angle float64) Nib {
+	sin, cos := math.Sincos(angle * math.Pi / 180.0)
+	d := Point{cos, sin}.Mul(w / 2.0)
+	return Nib{[]Point{d.Neg(), d}}
+}
+
+// EllipseNib returns an elliptical nib with radii rx and ry, rotated by an angle in degrees counter clockwise. It is approximated by a polygon within Tolerance.
+func EllipseNib(rx, ry, angle float64) Nib {
+	r := math.Max(rx, ry)
+	n := 8
+	if Tolerance < r {
+		n = int(math.Ceil(math.Pi / math.Acos(1.0-Tolerance/r)))
+	}
+	n = clampInt(n, 8, 256)
+
+	phi := angle * math.Pi / 180.0
+	points := make([]Point, n)
+	for i := range points {
+		points[i] = ellipsePos(rx, ry, phi, 0.0, 0.0, 2.0*math.Pi*float64(i)/float64(n))
+	}
+	return Nib{points}
+}
+
+// StrokeNib converts a path into a stroke by sweeping the nib along it, and returns a new path. This produces calligraphic strokes for paths and single-stroke fonts. The path is flattened first and the stroke consists of a subpath for each segment, which overlap and must be filled with the NonZero fill rule.
+func (p *Path) StrokeNib(nib Nib) *Path {
+	q := &Path{}
+	if len(nib.points) == 0 {
+		return q
+	}
+	for _, ps := range p.Flatten().Split() {
+		coords := ps.Coords()
+		for i := 1; i < len(coords); i++ {
+			if !coords[i-1].Equals(coords[i]) {
+				q = q.Append(nibPolygon(nib.points, coords[i-1], coords[i]))
+			}
+		}
+	}
+	return q
+}
+
+// nibPolygon returns the convex hull of the nib at the start and end positions as a CCW path, ie. the area swept by the nib along a line.
+func nibPolygon(nib []Point, start, end Point) *Path {
+	points := make([]Point, 0, 2*len(nib))
+	for _, pos := range nib {
+		points = append(points, start.Add(pos), end.Add(pos))
+	}
+	hull := convexHull(points)
+	if len(hull) < 3 {
+		return &Path{}
+	}
+
+	p := &Path{}
+	p.MoveTo(hull[0].X, hull[0].Y)
+	for _, pos := range hull[1:] {
+		p.LineTo(pos.X, pos.Y)
+	}
+	return p.Close()
+}
+
+// offsetCollapsed returns true if the inner path q of the closed path p has folded over itself, which happens when the stroke is wider than (a part of) the path. The folded vertices of the inner path end up outside the path, small overshoots at sharp corners are allowed.
+func offsetCollapsed(p, inner *Path, halfWidth float64) bool {
+	if halfWidth <= 0.0 {
+		return false
+	}
+
+	// flatten relative to the stroke width, since Tolerance may be large in comparison, but not finer than a fraction of Tolerance so that hairlines are fast
+	flatness := math.Max(0.01*halfWidth, 0.01*Tolerance)
+	margin := math.Max(0.1*halfWidth, 2.0*flatness)
+	coords := p.ReplaceArcs().replace(nil, func(p0, p1, p2 Point) *Path {
+		cp1, cp2 := quadraticToCubicBezier(p0, p1, p2)
+		return strokeCubicBezier(p0, cp1, cp2, p2, 0.0, flatness)
+	}, func(p0, p1, p2, p3 Point) *Path {
+		return strokeCubicBezier(p0, p1, p2, p3, 0.0, flatness)
+	}, nil).Coords()
+	polyline := &Polyline{coords}
+
+	index := NewQuadtree(p.Bounds())
+	for i := 1; i < len(coords); i++ {
+		a, b := coords[i-1], coords[i]
+		bounds := Rect{math.Min(a.X, b.X) - margin, math.Min(a.Y, b.Y) - margin, math.Abs(b.X-a.X) + 2.0*margin, math.Abs(b.Y-a.Y) + 2.0*margin}
+		index.Insert(bounds, i)
+	}
+
+	for _, v := range inner.Coords() {
+		if polyline.Interior(v.X, v.Y, NonZero) {
+			continue
+		}
+		near := false
+		index.Search(Rect{v.X, v.Y, 0.0, 0.0}, func(i int) bool {
+			near = distanceToSegment(v, coords[i-1], coords[i]) <= margin
+			return !near
+		})
+		if !near {
+			return true
+		}
+	}
+	return false
+}
+
+// strokePieces strokes a subpath as the union of separate pieces for each segment, join and cap, which all have a CCW orientation so that they combine using the NonZero fill rule. It is used when the inner path of a closed subpath has collapsed.
+func strokePieces(p *Path, halfWidth float64, cr Capper, jr Joiner) *Path {
+	q := &Path{}
+	states, closed := strokeStates(p, halfWidth)
+	for i, cur := range states {
+		rhs, lhs := &Path{}, &Path{}
+		rStart := cur.p0.Add(cur.n0)
+		lStart := cur.p0.Sub(cur.n0)
+		rhs.MoveTo(rStart.X, rStart.Y)
+		lhs.MoveTo(lStart.X, lStart.Y)
+		rhs, lhs = cur.offset(rhs, lhs, halfWidth)
+		lEnd := cur.p1.Sub(cur.n1)
+		rhs.LineTo(lEnd.X, lEnd.Y)
+		q = q.Append(rhs.Join(lhs.Reverse()).Close())
+
+		if i+1 < len(states) || closed {
+			next := states[(i+1)%len(states)]
+			if !cur.n1.Equals(next.n0) {
+				// the join on both sides as wedges around the pivot
+				rhs, lhs := &Path{}, &Path{}
+				rStart := cur.p1.Add(cur.n1)
+				lStart := cur.p1.Sub(cur.n1)
+				rhs.MoveTo(cur.p1.X, cur.p1.Y).LineTo(rStart.X, rStart.Y)
+				lhs.MoveTo(cur.p1.X, cur.p1.Y).LineTo(lStart.X, lStart.Y)
+				jr.Join(rhs, lhs, halfWidth, cur.p1, cur.n1, next.n0, cur.r1, next.r0)
+				rhs.Close()
+				lhs.Close()
+				if cur.n1.Rot90CW().Dot(next.n0) >= 0.0 { // bend to the right, ie. CW
+					rhs, lhs = rhs.Reverse(), lhs.Reverse()
+				}
+				q = q.Append(rhs).Append(lhs)
+			}
+		}
+	}
+	if !closed {
+		first, last := states[0], states[len(states)-1]
+		start := first.p0.Sub(first.n0)
+		end := last.p1.Add(last.n1)
+		q = q.Append(capPiece(cr, halfWidth, start, first.p0, first.n0.Neg()))
+		q = q.Append(capPiece(cr, halfWidth, end, last.p1, last.n1))
+	}
+	return q
+}
+
+func capPiece(cr Capper, halfWidth float64, start, pivot, n Point) *Path {
+	p := &Path{}
+	p.MoveTo(start.X, start.Y)
+	cr.Cap(p, halfWidth, pivot, n)
+	return p.Close()
+}
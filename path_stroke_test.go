@@ -19,7 +19,10 @@ func TestPathStroke(t *testing.T) {
 		stroke string
 	}{
 		{"M10 10", 2.0, RoundCap, RoundJoin, ""},
-		{"M10 10z", 2.0, RoundCap, RoundJoin, ""},
+		{"M10 10z", 2.0, RoundCap, RoundJoin, "M10 9A1 1 0 0 1 10 11A1 1 0 0 1 10 9z"},
+		{"M10 10L10 10", 2.0, RoundCap, RoundJoin, "M10 9A1 1 0 0 1 10 11A1 1 0 0 1 10 9z"},
+		{"M10 10L10 10", 2.0, SquareCap, RoundJoin, "M10 9L11 9L11 11L9 11L9 9z"},
+		{"M10 10L10 10", 2.0, ButtCap, RoundJoin, ""},
 		{"M10 10L10 5", 2.0, RoundCap, RoundJoin, "M9 10L9 5A1 1 0 0 1 11 5L11 10A1 1 0 0 1 9 10z"},
 		{"M10 10L10 5", 2.0, ButtCap, RoundJoin, "M9 10L9 5L11 5L11 10z"},
 		{"M10 10L10 5", 2.0, SquareCap, RoundJoin, "M9 10L9 5L9 4L11 4L11 5L11 10L11 11L9 11z"},
@@ -76,6 +79,24 @@ func TestPathStroke(t *testing.T) {
 	}
 }
 
+func TestPathStrokeInnerBends(t *testing.T) {
+	// rotated and mirrored rounded rectangle where the ends of a join coincide on its inner side, which adds no line
+	p := RoundedRectangle(5491.75, 1.4805555555555556, 5223.555555555556).Transform(Identity.Rotate(-3252.24).Scale(2781.0, -93.66666666666667)).Reverse()
+	stroke := p.Stroke(4410.0, ButtCap, MiterClipJoin(BevelJoin, -26.5))
+	test.That(t, !stroke.Empty())
+}
+
+func TestPathStrokeHairline(t *testing.T) {
+	// zero and tiny widths must not flatten the path at a vanishing tolerance
+	for _, orig := range []string{"M0 0A5 5 0 0 1 10 0z", "M0 0C0 5 10 5 10 0z"} {
+		for _, w := range []float64{0.0, 1e-12, 1e-9} {
+			t.Run(fmt.Sprintf("%v/%v", orig, w), func(t *testing.T) {
+				MustParseSVG(orig).Stroke(w, ButtCap, BevelJoin)
+			})
+		}
+	}
+}
+
 func TestPathStrokeEllipse(t *testing.T) {
 	rx, ry := 20.0, 10.0
 	nphi := 12
@@ -101,6 +122,8 @@ func TestPathOffset(t *testing.T) {
 		{"M0 0L10 0L10 10L0 10", 1.0, ""},
 		{"M0 0L10 0L10 10L0 10z", 1.0, "M0 -1L10 -1A1 1 0 0 1 11 0L11 10A1 1 0 0 1 10 11L0 11A1 1 0 0 1 -1 10L-1 0A1 1 0 0 1 0 -1z"},
 		{"M0 0L10 0L10 10L0 10z", -1.0, "M1 1L9 1L9 9L1 9z"},
+		{"M1 1z", 1.0, ""},
+		{"M0 0L1e-12 0L1e-12 1e-12z", 1.0, ""},
 	}
 	for j, tt := range tts {
 		t.Run(fmt.Sprintf("%v", j), func(t *testing.T) {
@@ -109,3 +132,107 @@ func TestPathOffset(t *testing.T) {
 		})
 	}
 }
+
+func TestPathStrokeGeometry(t *testing.T) {
+	// with round caps and joins, the stroke covers exactly the points within half the stroke width of the path
+	Tolerance = 0.01
+	defer func() { Tolerance = 1.0 }()
+
+	var tts = []struct {
+		orig string
+		w    float64
+	}{
+		{"M0 0L10 0L0 0", 4.0},                     // 180 degree turn
+		{"M0 0L10 0L10.001 0.001L20 0", 4.0},       // tiny segment
+		{"M0 0L10 0L10 1L0 1", 4.0},                // short segment shorter than the width
+		{"M0 0L10 0L5 1", 4.0},                     // sharp turn
+		{"M0 0L10 0z", 4.0},                        // closed line
+		{"M0 0L1 0L1 1L0 1z", 4.0},                 // inner path collapses
+		{"M0 0L20 0L20 1L0 1z", 4.0},               // inner path collapses
+		{"M0 0L10 0L10 10L9 10L9 1L0 1z", 4.0},     // inner path partly collapses
+		{"M0 0C0 0 10 10 10 0", 4.0},               // control point on start
+		{"M0 0C10 0 10 0 0 0", 4.0},                // straight cubic turning back
+		{"M0 0C20 0 -10 0 10 0", 4.0},              // straight cubic turning back twice
+		{"M0 0Q10 0 5 0", 4.0},                     // straight quadratic turning back
+		{"M0 0C10 5 0 5 10 0", 4.0},                // cusp
+		{"M0 0C10 10 10 10 10 0", 4.0},             // coinciding control points
+		{"M0 0A1 1 0 0 1 2 0L4 0", 4.0},            // arc smaller than the width
+		{"M0 0A3 3 0 0 1 6 0A3 3 0 0 1 0 0z", 4.0}, // circle
+		{"M5 5L5 5", 2.0},                          // zero-length line
+		{"M5 5z", 2.0},                             // zero-length closed path
+	}
+	for j, tt := range tts {
+		t.Run(fmt.Sprintf("%v", j), func(t *testing.T) {
+			p := MustParseSVG(tt.orig)
+			stroke := p.Stroke(tt.w, RoundCap, RoundJoin)
+
+			coords := []Point{}
+			clipSegments(p.ReplaceArcs(), p.Bounds(), func(ctrl []Point, inside bool) {
+				for i := 0; i <= 100; i++ {
+					coords = append(coords, segmentPos(ctrl, float64(i)/100.0))
+				}
+			})
+
+			bounds := p.Bounds()
+			for x := bounds.X - tt.w; x <= bounds.X+bounds.W+tt.w; x += 0.23 {
+				for y := bounds.Y - tt.w; y <= bounds.Y+bounds.H+tt.w; y += 0.19 {
+					dist := Point{x, y}.Sub(p.StartPos()).Length()
+					for i := 1; i < len(coords); i++ {
+						dist = math.Min(dist, distanceToSegment(Point{x, y}, coords[i-1], coords[i]))
+					}
+					if math.Abs(dist-tt.w/2.0) < 0.05 {
+						continue // close to the border
+					}
+					test.That(t, stroke.Interior(x, y, NonZero) == (dist < tt.w/2.0), fmt.Sprintf("at (%g,%g) with distance %g", x, y, dist))
+				}
+			}
+		})
+	}
+}
+
+func TestPathStrokeProfile(t *testing.T) {
+	var tts = []struct {
+		orig   string
+		widths []float64
+		cp     Capper
+		stroke string
+	}{
+		{"M0 0L10 0", []float64{2.0, 4.0}, ButtCap, "M0 -1L10 -2L10 2L0 1z"},
+		{"M0 0L10 0L10 10", []float64{2.0, 4.0, 2.0}, RoundCap, "M0 -1L12 -2L11 10A1 1 0 0 1 9 10L8 2L0 1A1 1 0 0 1 0 -1z"},
+		{"M0 0L10 0L10 10L0 10z", []float64{2.0, 2.0, 4.0, 4.0, 2.0}, ButtCap, "M-1 -1L11 -1L12 12L-2 12zM1 1L2 8L8 8L9 1z"},
+	}
+	for j, tt := range tts {
+		t.Run(fmt.Sprintf("%v", j), func(t *testing.T) {
+			stroke := MustParseSVG(tt.orig).StrokeWidths(tt.widths, tt.cp)
+			test.T(t, stroke, MustParseSVG(tt.stroke))
+		})
+	}
+
+	// tapered at both ends
+	stroke := MustParseSVG("M0 0L10 0").StrokeProfile(TaperProfile(2.0, 0.5, 0.5), ButtCap)
+	test.T(t, stroke.Bounds(), Rect{0.0, -1.0, 10.0, 2.0})
+	test.That(t, stroke.Interior(5.0, 0.9, NonZero))
+	test.That(t, !stroke.Interior(1.0, 0.5, NonZero))
+}
+
+func TestPathStrokeNib(t *testing.T) {
+	var tts = []struct {
+		orig   string
+		nib    Nib
+		stroke string
+	}{
+		{"M0 0L10 0", FlatNib(2.0, 90.0), "M0 -1L10 -1L10 1L0 1z"},
+		{"M0 0L10 0", FlatNib(2.0, 0.0), ""},
+		{"M0 0L10 0L10 10", FlatNib(2.0, 0.0), "M9 0L11 0L11 10L9 10z"},
+		{"M0 0L10 10", FlatNib(2.0, 90.0), "M0 -1L10 9L10 11L0 1z"},
+	}
+	for j, tt := range tts {
+		t.Run(fmt.Sprintf("%v", j), func(t *testing.T) {
+			stroke := MustParseSVG(tt.orig).StrokeNib(tt.nib)
+			test.T(t, stroke, MustParseSVG(tt.stroke))
+		})
+	}
+
+	stroke := MustParseSVG("M0 0L10 0").StrokeNib(EllipseNib(2.0, 1.0, 0.0))
+	test.T(t, stroke.Bounds(), Rect{-2.0, -1.0, 14.0, 2.0})
+}
@@ -30,6 +30,34 @@ func TestPathEquals(t *testing.T) {
 	test.That(t, !MustParseSVG("M5 0L5 10").Equals(MustParseSVG("M5 0M5 10")))
 	test.That(t, !MustParseSVG("M5 0L5 10").Equals(MustParseSVG("M5 0L5 9")))
 	test.That(t, MustParseSVG("M5 0L5 10").Equals(MustParseSVG("M5 0L5 10")))
+
+	test.That(t, MustParseSVG("M5 0L5 10").EqualsEpsilon(MustParseSVG("M5 0L5 10.05"), 0.1))
+	test.That(t, !MustParseSVG("M5 0L5 10").EqualsEpsilon(MustParseSVG("M5 0L5 10.05"), 0.01))
+	test.That(t, !MustParseSVG("M5 0L5 10").EqualsEpsilon(MustParseSVG("M5 0Q10 5 5 10"), 1.0))
+	test.That(t, !MustParseSVG("M0 0A10 10 0 0 0 10 0").EqualsEpsilon(MustParseSVG("M0 0A10 10 0 1 0 10 0"), 1.0))
+}
+
+func TestPathHash(t *testing.T) {
+	p := MustParseSVG("M5 0L5 10Q10 10 10 5A5 5 0 0 1 5 0z")
+	test.T(t, p.Hash(), p.Copy().Hash())
+	test.That(t, p.Hash() != MustParseSVG("M5 0L5 10Q10 10 10 5A5 5 0 1 1 5 0z").Hash())
+	test.That(t, p.Hash() != MustParseSVG("M5 0L5 10").Hash())
+	test.T(t, MustParseSVG("M0 0L5 0").Hash(), (&Path{}).MoveTo(math.Copysign(0.0, -1.0), 0.0).LineTo(5.0, 0.0).Hash())
+}
+
+func TestPathReset(t *testing.T) {
+	p := GetPath()
+	test.That(t, p.Empty())
+	p.Grow(10)
+	capacity := cap(p.d)
+	p.MoveTo(5.0, 5.0).LineTo(10.0, 5.0).QuadTo(10.0, 10.0, 5.0, 10.0).Close()
+	test.T(t, p, MustParseSVG("M5 5L10 5Q10 10 5 10z"))
+	test.T(t, cap(p.d), capacity)
+
+	p.Reset()
+	test.That(t, p.Empty())
+	test.T(t, cap(p.d), capacity)
+	PutPath(p)
 }
 
 func TestPathClosed(t *testing.T) {
@@ -119,7 +147,10 @@ func TestPathCommands(t *testing.T) {
 		{(&Path{}).LineTo(5, 0).Close().ArcTo(5, 5, 0, false, false, 10, 0), "M0 0L5 0zM0 0A5 5 0 0 0 10 0"},
 
 		{(&Path{}).MoveTo(3, 4).MoveTo(5, 3), "M5 3"},
-		{(&Path{}).MoveTo(3, 4).Close(), ""},
+		{(&Path{}).MoveTo(3, 4).Close(), "M3 4z"},
+		{(&Path{}).MoveTo(3, 4).LineTo(3, 4), "M3 4L3 4"},
+		{(&Path{}).MoveTo(3, 4).LineTo(3, 4).LineTo(5, 4), "M3 4L5 4"},
+		{(&Path{}).MoveTo(3, 4).LineTo(3, 4).QuadTo(5, 4, 5, 6), "M3 4Q5 4 5 6"},
 		{(&Path{}).LineTo(3, 4).LineTo(0, 0).Close(), "M0 0L3 4z"},
 		{(&Path{}).LineTo(3, 4).LineTo(4, 0).LineTo(2, 0).Close(), "M0 0L3 4L4 0z"},
 		{(&Path{}).LineTo(3, 4).Close().Close(), "M0 0L3 4z"},
@@ -169,6 +200,14 @@ func TestPathFilling(t *testing.T) {
 	fillings = MustParseSVG("L10 10z").Filling(NonZero)
 	test.T(t, fillings[0], false)
 
+	fillings = MustParseSVG("M1 1z").Filling(NonZero)
+	test.T(t, fillings[0], false)
+
+	fillings = MustParseSVG("M0 0L1 0L1 1zM0 0z").Filling(NonZero)
+	test.T(t, len(fillings), 2)
+	test.T(t, fillings[0], true)
+	test.T(t, fillings[1], false)
+
 	fillings = MustParseSVG("C5 0 10 5 10 10z").Filling(NonZero)
 	test.T(t, fillings[0], true)
 
@@ -317,6 +356,30 @@ func TestPathReplace(t *testing.T) {
 	}
 }
 
+func TestPathSimplify(t *testing.T) {
+	var tts = []struct {
+		orig      string
+		tolerance float64
+		res       string
+	}{
+		{"M0 0L10 0.1L20 0L30 5", 1.0, "M0 0L20 0L30 5"},
+		{"M0 0L10 2L20 0", 1.0, "M0 0L10 2L20 0"},
+		{"M0 0L10 0L10 10L0 10zM20 20L20.5 20L20.5 20.5z", 1.0, "M0 0L10 0L10 10L0 10z"},
+		{"M0 0Q5 0.1 10 0L10 10", 1.0, "M0 0L10 0L10 10"},
+		{"M0 0Q5 5 10 0", 1.0, "M0 0Q5 5 10 0"},
+		{"M0 0C3 0.1 7 0.1 10 0", 1.0, "M0 0L10 0"},
+		{"M0 0L10 0A0.2 0.2 0 0 0 10 0.4L10 5L0 5z", 1.0, "M0 0L10 0L10 5L0 5z"},
+		{"M0 0L10 0A5 5 0 0 0 10 10", 1.0, "M0 0L10 0A5 5 0 0 0 10 10"},
+		{"M0 0L10 0L0 0", 0.0, "M0 0L10 0L0 0"},
+		{"M0 0L5 0L10 0", 0.0, "M0 0L10 0"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.orig, func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.orig).Simplify(tt.tolerance), MustParseSVG(tt.res))
+		})
+	}
+}
+
 func TestPathMarkers(t *testing.T) {
 	start := MustParseSVG("L1 0L0 1z")
 	mid := MustParseSVG("M-1 0A1 1 0 0 0 1 0z")
@@ -529,6 +592,10 @@ func TestPathDash(t *testing.T) {
 			test.T(t, MustParseSVG(tt.orig).Dash(tt.offset, tt.d...), MustParseSVG(tt.dashes))
 		})
 	}
+
+	// very flat ellipses are split at their ends when the inverse arc length is inaccurate
+	p := MustParseSVG("M2.9311424455385806 2.9708256355629152A1146.0582983941936 1.1460582983941936 70.81889966878848 0 1 1.5832827774512763 6.072534395455154")
+	test.That(t, 900 < len(p.Dash(0.0, 1.9604832377211567, 0.16890724674774396).Split()))
 }
 
 func TestPathReverse(t *testing.T) {
@@ -606,6 +673,7 @@ func TestPathParseSVGErrors(t *testing.T) {
 		{"MM", "bad path: 2 numbers should follow command 'M' at position 1"},
 		{"A10 10 000 20 0", "bad path: largeArc and sweep flags should be 0 or 1 in command 'A' at position 11"},
 		{"A10 10 0 23 20 0", "bad path: largeArc and sweep flags should be 0 or 1 in command 'A' at position 9"},
+		{"M0 0A10 20 30", "bad path: 7 numbers should follow command 'A' at position 13"},
 
 		// go-fuzz
 		{"V4-z\n0ìGßIzØ", "bad path: unknown command '0' at position 6"},
@@ -631,7 +699,8 @@ func TestPathToSVG(t *testing.T) {
 		{"A5 5 0 0 1 10 0", "M0 0A5 5 0 0110 0"},
 		{"A10 5 90 0 0 10 0", "M0 0A5 10 0 0010 0"},
 		{"A10 5 90 1 0 10 0", "M0 0A5 10 0 1010 0"},
-		{"M20 0L20 0", ""},
+		{"M20 0L20 0", "M20 0H20"},
+		{"M0 0L10 0M5 5L5 5", "M0 0H10M5 5H5"},
 	}
 	for _, tt := range tts {
 		t.Run(tt.orig, func(t *testing.T) {
@@ -0,0 +1,194 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// TraceOptions are the options for tracing images, see TraceImage.
+type TraceOptions struct {
+	Threshold float64 // luminance from 0 to 1 below which pixels are traced
+	MinArea   float64 // area in pixels of the smallest shape or hole that is traced, removing speckles
+	Tolerance float64 // maximum deviation in pixels of the polygon from the pixel outline
+	AlphaMax  float64 // corner threshold, where zero gives polygons and 4/3 gives no corners
+}
+
+// DefaultTraceOptions are the options for tracing scanned logos and signatures, which are similar to the defaults of potrace.
+var DefaultTraceOptions = TraceOptions{
+	Threshold: 0.5,
+	MinArea:   2.0,
+	Tolerance: 1.0,
+	AlphaMax:  1.0,
+}
+
+// TraceImage traces the dark pixels of an image into a path of smooth curves and corners, so that raster images such as scanned logos and signatures can be drawn as vectors. The path is in pixels with the origin at the bottom-left of the image and the y-axis pointing up, as images are drawn by Context.DrawImage. Outlines are counter clockwise and holes clockwise, so that the path can be filled with either fill rule. Transparent pixels are not traced.
+//
+// Like potrace, the outlines of the pixels are approximated by polygons which are smoothed by cubic Béziers, except at vertices that turn sharply enough to be corners. Diagonally adjacent pixels are connected.
+func TraceImage(img image.Image, opts TraceOptions) *Path {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	bitmap := make([]bool, w*h) // with the y-axis pointing up
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA64Model.Convert(img.At(bounds.Min.X+x, bounds.Max.Y-1-y)).(color.NRGBA64)
+			lum := (0.2126*float64(c.R) + 0.7152*float64(c.G) + 0.0722*float64(c.B)) / 65535.0
+			bitmap[y*w+x] = c.A >= 0x8000 && lum < opts.Threshold
+		}
+	}
+
+	p := &Path{}
+	for _, outline := range traceOutlines(bitmap, w, h) {
+		if math.Abs(outlineArea(outline)) < opts.MinArea {
+			continue
+		}
+		polygon := simplifyPolygon(outline, opts.Tolerance)
+		if len(polygon) < 3 {
+			polygon = outline // shapes smaller than the tolerance
+		}
+		p = p.Append(smoothPolygon(polygon, opts.AlphaMax))
+	}
+	return p
+}
+
+// traceOutlines returns the outlines along the edges of the set pixels of a bitmap with the y-axis pointing up, with the set pixels on the left. The outlines contain only the vertices where they change direction.
+func traceOutlines(bitmap []bool, w, h int) [][]Point {
+	set := func(x, y int) bool {
+		return 0 <= x && x < w && 0 <= y && y < h && bitmap[y*w+x]
+	}
+
+	// the four directions in counter clockwise order, and the outgoing edges of each grid vertex by direction
+	dirs := [4][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+	edges := make([][4]bool, (w+1)*(h+1))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if !set(x, y) {
+				continue
+			}
+			if !set(x, y-1) {
+				edges[y*(w+1)+x][0] = true // bottom
+			}
+			if !set(x+1, y) {
+				edges[y*(w+1)+x+1][1] = true // right
+			}
+			if !set(x, y+1) {
+				edges[(y+1)*(w+1)+x+1][2] = true // top
+			}
+			if !set(x-1, y) {
+				edges[(y+1)*(w+1)+x][3] = true // left
+			}
+		}
+	}
+
+	outlines := [][]Point{}
+	used := make([][4]bool, len(edges))
+	for start := range edges {
+		for startDir := 0; startDir < 4; startDir++ {
+			if !edges[start][startDir] || used[start][startDir] {
+				continue
+			}
+			outline := []Point{}
+			v, dir := start, startDir
+			for {
+				used[v][dir] = true
+				x, y := v%(w+1)+dirs[dir][0], v/(w+1)+dirs[dir][1]
+				v = y*(w+1) + x
+
+				// vertices between two diagonally adjacent pixels have two outgoing edges, where turning right connects the pixels
+				next := dir
+				for _, turn := range []int{3, 0, 1} {
+					if edges[v][(dir+turn)%4] {
+						next = (dir + turn) % 4
+						break
+					}
+				}
+				if next != dir {
+					outline = append(outline, Point{float64(x), float64(y)})
+				}
+				if v == start && next == startDir {
+					break
+				}
+				dir = next
+			}
+			outlines = append(outlines, outline)
+		}
+	}
+	return outlines
+}
+
+// outlineArea returns the signed area of a polygon, which is positive when it is counter clockwise.
+func outlineArea(polygon []Point) float64 {
+	area := 0.0
+	for i, a := range polygon {
+		b := polygon[(i+1)%len(polygon)]
+		area += a.PerpDot(b)
+	}
+	return area / 2.0
+}
+
+// simplifyPolygon simplifies a closed polygon using the Ramer-Douglas-Peucker algorithm. It is split at the bottom-left vertex and the vertex farthest from it, which are both kept.
+func simplifyPolygon(polygon []Point, tolerance float64) []Point {
+	first := 0
+	for i, pos := range polygon {
+		if pos.X < polygon[first].X || pos.X == polygon[first].X && pos.Y < polygon[first].Y {
+			first = i
+		}
+	}
+	polygon = append(polygon[first:len(polygon):len(polygon)], polygon[:first]...)
+
+	far := 0
+	for i, pos := range polygon {
+		if polygon[far].Sub(polygon[0]).Length() < pos.Sub(polygon[0]).Length() {
+			far = i
+		}
+	}
+	if far == 0 {
+		return polygon[:1]
+	}
+	a := simplifyPolyline(append([]Point{}, polygon[:far+1]...), tolerance)
+	b := simplifyPolyline(append(append([]Point{}, polygon[far:]...), polygon[0]), tolerance)
+	return append(a[:len(a)-1], b[:len(b)-1]...)
+}
+
+// smoothPolygon returns a closed path through the midpoints of the edges of a polygon. At each vertex it connects the midpoints by a cubic Bézier, or by lines through the vertex when it is a corner, using the same smoothness measure as potrace.
+func smoothPolygon(polygon []Point, alphaMax float64) *Path {
+	n := len(polygon)
+	p := &Path{}
+	start := polygon[n-1].Interpolate(polygon[0], 0.5)
+	p.MoveTo(start.X, start.Y)
+	for j := 0; j < n; j++ {
+		vi, vj, vk := polygon[(j+n-1)%n], polygon[j], polygon[(j+1)%n]
+		end := vj.Interpolate(vk, 0.5)
+
+		// alpha measures how far the vertex is from the line between its neighbors, relative to a unit square around the vertex
+		alpha := 4.0 / 3.0
+		dir := Point{-sign(vk.Y - vi.Y), sign(vk.X - vi.X)}
+		if denom := dir.Y*(vk.X-vi.X) - dir.X*(vk.Y-vi.Y); denom != 0.0 {
+			dd := math.Abs(vj.Sub(vi).PerpDot(vk.Sub(vi)) / denom)
+			alpha = 0.0
+			if 1.0 < dd {
+				alpha = (1.0 - 1.0/dd) / 0.75
+			}
+		}
+
+		if alphaMax <= alpha {
+			p.LineTo(vj.X, vj.Y)
+			p.LineTo(end.X, end.Y)
+		} else {
+			alpha = math.Max(0.55, math.Min(1.0, alpha))
+			cp1 := vi.Interpolate(vj, 0.5+0.5*alpha)
+			cp2 := vk.Interpolate(vj, 0.5+0.5*alpha)
+			p.CubeTo(cp1.X, cp1.Y, cp2.X, cp2.Y, end.X, end.Y)
+		}
+	}
+	return p.Close()
+}
+
+func sign(f float64) float64 {
+	if f < 0.0 {
+		return -1.0
+	} else if 0.0 < f {
+		return 1.0
+	}
+	return 0.0
+}
@@ -0,0 +1,83 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+// testTraceImage returns a white image of w by h pixels where pixels for which f returns true are black, with the y-axis pointing down.
+func testTraceImage(w, h int, f func(x, y int) bool) image.Image {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if !f(x, y) {
+				img.SetGray(x, y, color.Gray{0xFF})
+			}
+		}
+	}
+	return img
+}
+
+func TestTraceImage(t *testing.T) {
+	// rectangles keep their corners
+	img := testTraceImage(20, 16, func(x, y int) bool { return 2 <= x && x < 18 && 2 <= y && y < 14 })
+	p := TraceImage(img, DefaultTraceOptions)
+	test.T(t, p.Bounds(), Rect{2.0, 2.0, 16.0, 12.0})
+	test.That(t, p.CCW(), "outlines are counter clockwise")
+	test.That(t, p.Interior(10.0, 8.0, NonZero), "inside")
+	test.That(t, !p.Interior(18.5, 8.0, NonZero), "outside")
+	for i := 0; i < len(p.d); i += cmdLen(p.d[i]) {
+		test.That(t, p.d[i] != cubeToCmd, "no curves")
+	}
+
+	// disks are smooth and have a hole at the center
+	r0, r1 := 20.0, 8.0
+	img = testTraceImage(50, 50, func(x, y int) bool {
+		d := math.Hypot(float64(x)+0.5-25.0, float64(y)+0.5-25.0)
+		return d < r0 && r1 < d
+	})
+	p = TraceImage(img, DefaultTraceOptions)
+	ps := p.Split()
+	test.T(t, len(ps), 2)
+	test.That(t, ps[0].CCW() && !ps[1].CCW(), "hole is clockwise")
+	test.That(t, !p.Interior(25.0, 25.0, NonZero) && !p.Interior(25.0, 25.0, EvenOdd), "hole")
+	test.That(t, p.Interior(25.0, 25.0+(r0+r1)/2.0, NonZero), "ring")
+	bounds := ps[0].Bounds()
+	test.That(t, math.Abs(bounds.W-2.0*r0) < 1.0 && math.Abs(bounds.H-2.0*r0) < 1.0, bounds)
+	hasCurves := false
+	for i := 0; i < len(p.d); i += cmdLen(p.d[i]) {
+		hasCurves = hasCurves || p.d[i] == cubeToCmd
+	}
+	test.That(t, hasCurves, "curves")
+
+	// polygons have no curves
+	opts := DefaultTraceOptions
+	opts.AlphaMax = 0.0
+	p = TraceImage(img, opts)
+	for i := 0; i < len(p.d); i += cmdLen(p.d[i]) {
+		test.That(t, p.d[i] != cubeToCmd, "no curves")
+	}
+
+	// speckles are removed
+	img = testTraceImage(10, 10, func(x, y int) bool { return x == 5 && y == 5 })
+	test.That(t, TraceImage(img, DefaultTraceOptions).Empty(), "speckle removed")
+	opts.MinArea = 0.0
+	test.That(t, !TraceImage(img, opts).Empty(), "speckle kept")
+}
+
+func TestTraceOutlines(t *testing.T) {
+	// diagonally adjacent pixels are connected
+	outlines := traceOutlines([]bool{true, false, false, true}, 2, 2)
+	test.T(t, len(outlines), 1)
+	test.Float(t, outlineArea(outlines[0]), 2.0)
+
+	// holes are clockwise
+	outlines = traceOutlines([]bool{true, true, true, true, false, true, true, true, true}, 3, 3)
+	test.T(t, len(outlines), 2)
+	test.T(t, outlines[0], []Point{{3.0, 0.0}, {3.0, 3.0}, {0.0, 3.0}, {0.0, 0.0}})
+	test.Float(t, outlineArea(outlines[1]), -1.0)
+}
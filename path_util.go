@@ -2,6 +2,7 @@ package canvas
 
 import (
 	"math"
+	"sort"
 )
 
 func ellipsePos(rx, ry, phi, cx, cy, theta float64) Point {
@@ -188,15 +189,62 @@ func arcToCube(start Point, rx, ry, phi float64, large, sweep bool, end Point) *
 //	return ((0.02*ba*ba + 2.83*ba + 0.125) / (ba + 0.01)) * a * math.Exp(c0+c1*math.Abs(n2-n1))
 //}
 
+func ellipseQuadraticBezierKappa(dtheta float64) float64 {
+	return math.Tan(dtheta / 2.0)
+}
+
+func ellipseCubicBezierKappa(dtheta float64) float64 {
+	return math.Sin(dtheta) * (math.Sqrt(4.0+3.0*math.Pow(math.Tan(dtheta/2.0), 2.0)) - 1.0) / 3.0
+}
+
+// ellipseSegments returns the number of quadratic or cubic Béziers needed to approximate an elliptic arc spanning dtheta radians with at most tolerance deviation, with r the major radius. At least one Bézier is used for every quarter.
+func ellipseSegments(r, dtheta, tolerance float64, cubic bool) int {
+	n := int(math.Ceil(dtheta / (math.Pi / 2.0)))
+	if n < 1 {
+		n = 1
+	}
+	for n < 1024 && tolerance < r*unitArcBezierError(dtheta/float64(n), cubic) {
+		n++
+	}
+	return n
+}
+
+// unitArcBezierError returns the maximum deviation between a unit circle arc spanning dtheta radians and its approximating Bézier. Since an ellipse is an affine transformation of the circle with its major radius that only shrinks, the deviation for an ellipse is bounded by that of the circle scaled by its major radius.
+func unitArcBezierError(dtheta float64, cubic bool) float64 {
+	sin, cos := math.Sin(dtheta), math.Cos(dtheta)
+	p0, p3 := Point{1.0, 0.0}, Point{cos, sin}
+	d0, d3 := Point{0.0, 1.0}, Point{-sin, cos}
+
+	var p1, p2 Point
+	if cubic {
+		kappa := ellipseCubicBezierKappa(dtheta)
+		p1, p2 = p0.Add(d0.Mul(kappa)), p3.Sub(d3.Mul(kappa))
+	} else {
+		p1 = p0.Add(d0.Mul(ellipseQuadraticBezierKappa(dtheta)))
+	}
+
+	err := 0.0
+	for i := 1; i < 16; i++ {
+		t := float64(i) / 16.0
+		var pos Point
+		if cubic {
+			pos = cubicBezierPos(p0, p1, p2, p3, t)
+		} else {
+			pos = quadraticBezierPos(p0, p1, p3, t)
+		}
+		err = math.Max(err, math.Abs(pos.Length()-1.0))
+	}
+	return err
+}
+
 // see Drawing and elliptical arc using polylines, quadratic or cubic Bézier curves (2003), L. Maisonobe,
 // https://spaceroots.org/documents/ellipse/elliptical-arc.pdf
 func ellipseToQuadraticBeziers(start Point, rx, ry, phi float64, large, sweep bool, end Point) [][3]Point {
 	cx, cy, theta0, theta1 := ellipseToCenter(start.X, start.Y, rx, ry, phi, large, sweep, end.X, end.Y)
 
-	dtheta := math.Pi / 2.0 // TODO: use error measure to determine dtheta?
-	n := int(math.Ceil(math.Abs(theta1-theta0) / dtheta))
-	dtheta = math.Abs(theta1-theta0) / float64(n) // evenly spread the n points, dalpha will get smaller
-	kappa := math.Tan(dtheta / 2.0)
+	n := ellipseSegments(math.Max(rx, ry), math.Abs(theta1-theta0), Tolerance, false)
+	dtheta := math.Abs(theta1-theta0) / float64(n) // evenly spread the n points, dalpha will get smaller
+	kappa := ellipseQuadraticBezierKappa(dtheta)
 	if !sweep {
 		dtheta = -dtheta
 	}
@@ -272,10 +320,9 @@ func ellipseToQuadraticBeziers(start Point, rx, ry, phi float64, large, sweep bo
 func ellipseToCubicBeziers(start Point, rx, ry, phi float64, large, sweep bool, end Point) [][4]Point {
 	cx, cy, theta0, theta1 := ellipseToCenter(start.X, start.Y, rx, ry, phi, large, sweep, end.X, end.Y)
 
-	dtheta := math.Pi / 2.0 // TODO: use error measure to determine dtheta?
-	n := int(math.Ceil(math.Abs(theta1-theta0) / dtheta))
-	dtheta = math.Abs(theta1-theta0) / float64(n) // evenly spread the n points, dalpha will get smaller
-	kappa := math.Sin(dtheta) * (math.Sqrt(4.0+3.0*math.Pow(math.Tan(dtheta/2.0), 2.0)) - 1.0) / 3.0
+	n := ellipseSegments(math.Max(rx, ry), math.Abs(theta1-theta0), Tolerance, true)
+	dtheta := math.Abs(theta1-theta0) / float64(n) // evenly spread the n points, dalpha will get smaller
+	kappa := ellipseCubicBezierKappa(dtheta)
 	if !sweep {
 		dtheta = -dtheta
 	}
@@ -722,3 +769,79 @@ func strokeCubicBezier(p0, p1, p2, p3 Point, d, flatness float64) *Path {
 	}
 	return p
 }
+
+// distanceToSegment returns the distance from p to the line segment from a to b.
+func distanceToSegment(p, a, b Point) float64 {
+	ab := b.Sub(a)
+	if ab.IsZero() {
+		return p.Sub(a).Length()
+	}
+	t := math.Max(0.0, math.Min(1.0, p.Sub(a).Dot(ab)/ab.Dot(ab)))
+	return p.Sub(a.Add(ab.Mul(t))).Length()
+}
+
+// simplifyPolyline removes points from a polyline that deviate less than tolerance from the simplified polyline, using the Ramer-Douglas-Peucker algorithm. The first and last points are always kept.
+func simplifyPolyline(poly []Point, tolerance float64) []Point {
+	if len(poly) < 3 {
+		return poly
+	}
+
+	keep := make([]bool, len(poly))
+	keep[0], keep[len(poly)-1] = true, true
+	stack := [][2]int{{0, len(poly) - 1}}
+	for 0 < len(stack) {
+		i0, i1 := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+
+		imax, dmax := 0, 0.0
+		for i := i0 + 1; i < i1; i++ {
+			if d := distanceToSegment(poly[i], poly[i0], poly[i1]); dmax < d {
+				imax, dmax = i, d
+			}
+		}
+		if 0.0 < dmax && tolerance <= dmax {
+			keep[imax] = true
+			stack = append(stack, [2]int{i0, imax}, [2]int{imax, i1})
+		}
+	}
+
+	simple := []Point{}
+	for i, pos := range poly {
+		if keep[i] {
+			simple = append(simple, pos)
+		}
+	}
+	return simple
+}
+
+// convexHull returns the convex hull of the points in CCW order using the monotone chain algorithm. Collinear points are removed.
+func convexHull(points []Point) []Point {
+	points = append([]Point{}, points...)
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].X < points[j].X || points[i].X == points[j].X && points[i].Y < points[j].Y
+	})
+	if len(points) < 3 {
+		return points
+	}
+
+	hull := make([]Point, 0, 2*len(points))
+	for _, list := range [][]Point{points, reversePoints(points)} {
+		n := len(hull)
+		for _, p := range list {
+			for n+2 <= len(hull) && hull[len(hull)-1].Sub(hull[len(hull)-2]).PerpDot(p.Sub(hull[len(hull)-2])) <= 0.0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, p)
+		}
+		hull = hull[:len(hull)-1] // last point is the first point of the other chain
+	}
+	return hull
+}
+
+func reversePoints(points []Point) []Point {
+	r := make([]Point, len(points))
+	for i, p := range points {
+		r[len(points)-1-i] = p
+	}
+	return r
+}
@@ -90,12 +90,26 @@ func TestEllipseSplit(t *testing.T) {
 
 func TestArcToQuad(t *testing.T) {
 	Epsilon = 1e-2
+	Tolerance = 10.0
 	test.T(t, arcToQuad(Point{0.0, 0.0}, 100.0, 100.0, 0.0, false, false, Point{200.0, 0.0}), MustParseSVG("M0 0Q0 100 100 100Q200 100 200 0"))
 }
 
 func TestArcToCube(t *testing.T) {
 	Epsilon = 1e-2
+	Tolerance = 1.0
 	test.T(t, arcToCube(Point{0.0, 0.0}, 100.0, 100.0, 0.0, false, false, Point{200.0, 0.0}), MustParseSVG("M0 0C0 54.858 45.142 100 100 100C154.86 100 200 54.858 200 0"))
+
+	// more Béziers are used for a smaller tolerance
+	Tolerance = 0.01
+	beziers := ellipseToCubicBeziers(Point{0.0, 0.0}, 100.0, 100.0, 0.0, false, false, Point{200.0, 0.0})
+	test.T(t, len(beziers), 4)
+	for _, bezier := range beziers {
+		for _, t0 := range []float64{0.25, 0.5, 0.75} {
+			pos := cubicBezierPos(bezier[0], bezier[1], bezier[2], bezier[3], t0)
+			test.That(t, math.Abs(pos.Sub(Point{100.0, 0.0}).Length()-100.0) <= Tolerance)
+		}
+	}
+	Tolerance = 1.0
 }
 
 func TestFlattenEllipse(t *testing.T) {
@@ -287,3 +301,9 @@ func TestCubicBezierStroke(t *testing.T) {
 
 	test.T(t, strokeCubicBezier(Point{0, 0}, Point{30, 0}, Point{30, 10}, Point{25, 10}, 5.0, 0.01).Bounds(), Rect{0.0, -5.0, 32.478752, 20.0})
 }
+
+func TestConvexHull(t *testing.T) {
+	test.T(t, convexHull([]Point{{0, 0}, {1, 0}, {2, 0}, {1, 1}, {0, 1}, {0.5, 0.5}}), []Point{{0, 0}, {2, 0}, {1, 1}, {0, 1}})
+	test.T(t, convexHull([]Point{{0, 0}, {1, 1}, {2, 2}}), []Point{{0, 0}, {2, 2}})
+	test.T(t, len(convexHull([]Point{{1, 1}})), 1)
+}
@@ -3,11 +3,14 @@ package canvas
 import (
 	"bytes"
 	"compress/zlib"
+	"crypto/rand"
 	"encoding/ascii85"
 	"encoding/binary"
 	"fmt"
+	"hash"
 	"image"
 	"image/color"
+	"image/jpeg"
 	"io"
 	"math"
 	"sort"
@@ -39,6 +42,27 @@ func (r *PDF) SetImageEncoding(enc ImageEncoding) {
 	r.imgEnc = enc
 }
 
+// SetImageQuality sets the JPEG quality from 1 to 100 of images that are embedded with Lossy encoding, which is 75 by default. JPEG images that are not downsampled are embedded as is.
+func (r *PDF) SetImageQuality(quality int) {
+	r.w.pdf.imgQuality = quality
+}
+
+// SetImageDownsampling sets the maximum resolution in dots-per-millimeter of embedded images, where images drawn with a higher resolution are downsampled using the given resampling to reduce the file size. A resolution of zero, the default, disables downsampling.
+func (r *PDF) SetImageDownsampling(dpm float64, resampling Resampling) {
+	r.w.pdf.imgDPM = dpm
+	r.w.pdf.imgResampling = resampling
+}
+
+// SetPrepressMarks sets the crop marks, registration marks, and color bars that are drawn around every page of the document, and the bleed into which the drawing may extend. The pages are enlarged to hold them, with their trim and bleed boxes set to the size of the page and its bleed.
+func (r *PDF) SetPrepressMarks(marks PrepressMarks) {
+	r.w.pdf.marks = marks
+}
+
+// SetFontSubsetting sets whether TrueType fonts are embedded with only the outlines of the glyphs that are used, which is the default. Fonts are subset when the document is closed.
+func (r *PDF) SetFontSubsetting(subset bool) {
+	r.w.pdf.subsetFonts = subset
+}
+
 func (r *PDF) SetCompression(compress bool) {
 	r.w.pdf.SetCompression(compress)
 }
@@ -50,8 +74,25 @@ func (r *PDF) SetInfo(title, subject, keywords, author string) {
 	r.w.pdf.SetAuthor(author)
 }
 
+// NewPage starts a new page of width by height millimeters, to which all following drawing operations are rendered.
+func (r *PDF) NewPage(width, height float64) {
+	r.w = r.w.pdf.NewPage(width, height)
+	r.width = width
+	r.height = height
+}
+
 func (r *PDF) Close() error {
-	return r.w.pdf.Close()
+	span := startTrace(SerializingPhase)
+	err := r.w.pdf.Close()
+	span.end(r.w.pdf.pos)
+	return err
+}
+
+// setLink adds a link annotation over the bounds of the next element to the page, see Context.SetLink.
+func (r *PDF) setLink(url string, bounds Rect) {
+	if url != "" {
+		r.w.links = append(r.w.links, pdfLink{url, bounds})
+	}
 }
 
 func (r *PDF) Size() (float64, float64) {
@@ -81,6 +122,20 @@ func (r *PDF) RenderPath(path *Path, style Style, m Matrix) {
 	//	strokeUnsupported = true
 	//}
 
+	r.w.SetOverprint(style.Overprint)
+	if style.FillMesh != nil {
+		// paint the mesh clipped by the path and continue with the stroke
+		if fill {
+			r.w.paintMesh(path.Transform(m), style.FillRule, style.FillMesh, m)
+		}
+		style.FillMesh = nil
+		style.FillColor = Transparent
+		if !stroke {
+			return
+		}
+		fill, differentAlpha = false, false
+	}
+
 	closed := false
 	data := path.Transform(m).ToPDF()
 	if 1 < len(data) && data[len(data)-1] == 'h' {
@@ -90,7 +145,7 @@ func (r *PDF) RenderPath(path *Path, style Style, m Matrix) {
 
 	if !stroke || !strokeUnsupported {
 		if fill && !stroke {
-			r.w.SetFillColor(style.FillColor)
+			r.w.setFill(style.FillColor, style.FillSpot)
 			r.w.Write([]byte(" "))
 			r.w.Write([]byte(data))
 			r.w.Write([]byte(" f"))
@@ -98,7 +153,7 @@ func (r *PDF) RenderPath(path *Path, style Style, m Matrix) {
 				r.w.Write([]byte("*"))
 			}
 		} else if !fill && stroke {
-			r.w.SetStrokeColor(style.StrokeColor)
+			r.w.setStroke(style.StrokeColor, style.StrokeSpot)
 			r.w.SetLineWidth(style.StrokeWidth)
 			r.w.SetLineCap(style.StrokeCapper)
 			r.w.SetLineJoin(style.StrokeJoiner)
@@ -115,8 +170,8 @@ func (r *PDF) RenderPath(path *Path, style Style, m Matrix) {
 			}
 		} else if fill && stroke {
 			if !differentAlpha {
-				r.w.SetFillColor(style.FillColor)
-				r.w.SetStrokeColor(style.StrokeColor)
+				r.w.setFill(style.FillColor, style.FillSpot)
+				r.w.setStroke(style.StrokeColor, style.StrokeSpot)
 				r.w.SetLineWidth(style.StrokeWidth)
 				r.w.SetLineCap(style.StrokeCapper)
 				r.w.SetLineJoin(style.StrokeJoiner)
@@ -132,7 +187,7 @@ func (r *PDF) RenderPath(path *Path, style Style, m Matrix) {
 					r.w.Write([]byte("*"))
 				}
 			} else {
-				r.w.SetFillColor(style.FillColor)
+				r.w.setFill(style.FillColor, style.FillSpot)
 				r.w.Write([]byte(" "))
 				r.w.Write([]byte(data))
 				r.w.Write([]byte(" f"))
@@ -140,7 +195,7 @@ func (r *PDF) RenderPath(path *Path, style Style, m Matrix) {
 					r.w.Write([]byte("*"))
 				}
 
-				r.w.SetStrokeColor(style.StrokeColor)
+				r.w.setStroke(style.StrokeColor, style.StrokeSpot)
 				r.w.SetLineWidth(style.StrokeWidth)
 				r.w.SetLineCap(style.StrokeCapper)
 				r.w.SetLineJoin(style.StrokeJoiner)
@@ -160,7 +215,7 @@ func (r *PDF) RenderPath(path *Path, style Style, m Matrix) {
 	} else {
 		// stroke && strokeUnsupported
 		if fill {
-			r.w.SetFillColor(style.FillColor)
+			r.w.setFill(style.FillColor, style.FillSpot)
 			r.w.Write([]byte(" "))
 			r.w.Write([]byte(data))
 			r.w.Write([]byte(" f"))
@@ -175,7 +230,7 @@ func (r *PDF) RenderPath(path *Path, style Style, m Matrix) {
 		}
 		path = path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
 
-		r.w.SetFillColor(style.StrokeColor)
+		r.w.setFill(style.StrokeColor, style.StrokeSpot)
 		r.w.Write([]byte(" "))
 		r.w.Write([]byte(path.ToPDF()))
 		r.w.Write([]byte(" f"))
@@ -186,6 +241,13 @@ func (r *PDF) RenderPath(path *Path, style Style, m Matrix) {
 }
 
 func (r *PDF) RenderText(text *Text, m Matrix) {
+	if text.hasColorGlyphs() || text.vertical {
+		// color glyphs have colors and images that fonts in PDF cannot draw, and vertical text is drawn by the outlines of its glyphs
+		text.renderAsPaths(r, m)
+		return
+	}
+
+	r.w.SetOverprint(false)
 	r.w.StartTextObject()
 	decoPaths := []*Path{}
 	decoColors := []color.RGBA{}
@@ -193,8 +255,10 @@ func (r *PDF) RenderText(text *Text, m Matrix) {
 		for _, span := range line.spans {
 			r.w.SetFillColor(span.ff.color)
 			r.w.SetFont(span.ff.font, span.ff.size*span.ff.scale)
-			r.w.SetTextPosition(m.Translate(span.dx, line.y).Shear(span.ff.fauxItalic, 0.0))
-			r.w.SetTextCharSpace(span.glyphSpacing)
+			// glyph stretching scales the text space horizontally, which also scales character and word spacing
+			stretch := 1.0 + span.glyphStretch
+			r.w.SetTextPosition(m.Translate(span.dx, line.y).Scale(stretch, 1.0).Shear(span.ff.fauxItalic, 0.0))
+			r.w.SetTextCharSpace(span.glyphSpacing / stretch)
 
 			if 0.0 < span.ff.fauxBold {
 				r.w.SetTextRenderMode(2)
@@ -203,18 +267,17 @@ func (r *PDF) RenderText(text *Text, m Matrix) {
 				r.w.SetTextRenderMode(0)
 			}
 
-			i := 0
+			// split the shaped glyphs after the clusters of word and sentence boundaries to add their spacing
 			TJ := []interface{}{}
-			for _, boundary := range span.boundaries {
-				if boundary.kind == wordBoundary || boundary.kind == eofBoundary {
-					j := boundary.pos + boundary.size
-					TJ = append(TJ, span.text[i:j])
-					if boundary.kind == wordBoundary {
-						TJ = append(TJ, span.wordSpacing)
-					}
-					i = j
+			glyphs := []Glyph{}
+			for _, cluster := range span.glyphClusters() {
+				glyphs = append(glyphs, cluster.glyphs...)
+				if cluster.spacing != 0.0 {
+					TJ = append(TJ, glyphs, cluster.spacing/stretch)
+					glyphs = []Glyph{}
 				}
 			}
+			TJ = append(TJ, glyphs)
 			r.w.WriteText(TJ...)
 		}
 		for _, deco := range line.decos {
@@ -233,9 +296,20 @@ func (r *PDF) RenderText(text *Text, m Matrix) {
 }
 
 func (r *PDF) RenderImage(img image.Image, m Matrix) {
+	r.w.SetOverprint(false)
 	r.w.DrawImage(img, r.imgEnc, m)
 }
 
+// RenderPDFPage draws a page of another PDF document, such as a letterhead, with its bottom-left corner at the origin and with a size of page.W by page.H millimeters before being transformed by m. Drawn before other content, it is an underlay of the current page. The page is embedded once as a form XObject that is reused by all pages that draw it.
+func (r *PDF) RenderPDFPage(page *PDFPage, m Matrix) {
+	r.w.DrawPDFPage(page, m)
+}
+
+// RenderCanvas draws a canvas, such as a page of a Document, clipped to its size with its bottom-left corner at the origin before being transformed by m. The canvas is embedded once as a form XObject that is reused when it is drawn again.
+func (r *PDF) RenderCanvas(c *Canvas, m Matrix) {
+	r.w.DrawCanvas(c, r.imgEnc, m)
+}
+
 type pdfWriter struct {
 	w   io.Writer
 	err error
@@ -244,24 +318,55 @@ type pdfWriter struct {
 	objOffsets []int
 
 	fonts    map[*Font]pdfRef
+	fontList []*Font                   // fonts in order of use, which are written when closing
+	glyphs   map[*Font]map[uint16]bool // glyphs used per font
+	forms    map[*PDFPage]pdfRef
+	canvases map[*Canvas]pdfRef            // canvases drawn as form XObjects
+	profiles map[string]pdfRef             // ICC color profiles by their data
+	imported map[*pdfReader]map[int]pdfRef // objects copied from other documents by object number
 	pages    []*pdfPageWriter
 	compress bool
 	title    string
-	subject  string
-	keywords string
-	author   string
+
+	subsetFonts bool
+
+	imgQuality     int
+	imgDPM         float64 // maximum resolution of images, zero is unlimited
+	imgResampling  Resampling
+	marks          PrepressMarks
+	encryption     *pdfEncryption // nil if not encrypted
+	signature      *PDFSignature  // nil if not signed
+	hash           hash.Hash      // of the written bytes, for signatures
+	linearize      bool
+	objStreams     bool
+	objects        []interface{}     // objects by number that are written when closing, for linearization and object streams
+	renumber       map[pdfRef]pdfRef // new object numbers when writing linearized documents
+	objStreamIndex map[pdfRef][2]int // object stream and index of compressed objects
+	subject        string
+	keywords       string
+	author         string
 }
 
 func newPDFWriter(writer io.Writer) *pdfWriter {
 	w := &pdfWriter{
-		w:     writer,
-		fonts: map[*Font]pdfRef{},
+		w:        writer,
+		fonts:    map[*Font]pdfRef{},
+		glyphs:   map[*Font]map[uint16]bool{},
+		forms:    map[*PDFPage]pdfRef{},
+		canvases: map[*Canvas]pdfRef{},
+		profiles: map[string]pdfRef{},
+
+		subsetFonts: true,
+		imgQuality:  jpeg.DefaultQuality,
+		imported:    map[*pdfReader]map[int]pdfRef{},
 	}
 
-	w.write("%%PDF-1.7\n")
+	w.writeBytes([]byte(pdfHeader))
 	return w
 }
 
+const pdfHeader = "%PDF-1.7\n"
+
 func (w *pdfWriter) SetCompression(compress bool) {
 	w.compress = compress
 }
@@ -287,6 +392,9 @@ func (w *pdfWriter) writeBytes(b []byte) {
 		return
 	}
 	n, err := w.w.Write(b)
+	if w.hash != nil {
+		w.hash.Write(b[:n])
+	}
 	w.pos += n
 	w.err = err
 }
@@ -295,9 +403,7 @@ func (w *pdfWriter) write(s string, v ...interface{}) {
 	if w.err != nil {
 		return
 	}
-	n, err := fmt.Fprintf(w.w, s, v...)
-	w.pos += n
-	w.err = err
+	w.writeBytes([]byte(fmt.Sprintf(s, v...)))
 }
 
 type pdfRef int
@@ -313,10 +419,29 @@ type pdfStream struct {
 const (
 	pdfFilterASCII85 pdfFilter = "ASCII85Decode"
 	pdfFilterFlate   pdfFilter = "FlateDecode"
+	pdfFilterDCT     pdfFilter = "DCTDecode"
 )
 
+// escapePDFName escapes the characters of a name that are not regular characters as a number sign followed by their hexadecimal code, such as the spaces in the names of spot colors.
+func escapePDFName(name string) string {
+	regular := func(c byte) bool {
+		return '!' <= c && c <= '~' && strings.IndexByte("()<>[]{}/%#", c) == -1
+	}
+	sb := strings.Builder{}
+	for i := 0; i < len(name); i++ {
+		if regular(name[i]) {
+			sb.WriteByte(name[i])
+		} else {
+			fmt.Fprintf(&sb, "#%02X", name[i])
+		}
+	}
+	return sb.String()
+}
+
 func (w *pdfWriter) writeVal(i interface{}) {
 	switch v := i.(type) {
+	case nil:
+		w.write("null")
 	case bool:
 		if v {
 			w.write("true")
@@ -328,13 +453,27 @@ func (w *pdfWriter) writeVal(i interface{}) {
 	case float64:
 		w.write("%v", dec(v))
 	case string:
+		if w.encryption != nil {
+			w.writeVal(w.encryption.encrypt([]byte(v)))
+			break
+		}
 		v = strings.Replace(v, `\`, `\\`, -1)
 		v = strings.Replace(v, `(`, `\(`, -1)
 		v = strings.Replace(v, `)`, `\)`, -1)
+		v = strings.Replace(v, "\r", `\r`, -1)
 		w.write("(%v)", v)
+	case []byte:
+		w.write("<%X>", v) // hexadecimal string that is not encrypted
 	case pdfRef:
+		if w.renumber != nil {
+			v = w.renumber[v]
+		}
 		w.write("%v 0 R", v)
-	case pdfName, pdfFilter:
+	case pdfLinearized:
+		w.write("%010d", v)
+	case pdfName:
+		w.write("/%v", escapePDFName(string(v)))
+	case pdfFilter:
 		w.write("/%v", v)
 	case pdfArray:
 		w.write("[")
@@ -399,9 +538,14 @@ func (w *pdfWriter) writeVal(i interface{}) {
 				w := zlib.NewWriter(&b2)
 				w.Write(b)
 				w.Close()
+			case pdfFilterDCT:
+				b2.Write(b) // the stream is encoded as a JPEG image already
 			}
 			b = b2.Bytes()
 		}
+		if w.encryption != nil {
+			b = w.encryption.encrypt(b)
+		}
 
 		v.dict["Length"] = len(b)
 		w.writeVal(v.dict)
@@ -414,6 +558,11 @@ func (w *pdfWriter) writeVal(i interface{}) {
 }
 
 func (w *pdfWriter) writeObject(val interface{}) pdfRef {
+	if w.linearize || w.objStreams {
+		w.objOffsets = append(w.objOffsets, 0)
+		w.objects = append(w.objects, val)
+		return pdfRef(len(w.objOffsets))
+	}
 	w.objOffsets = append(w.objOffsets, w.pos)
 	w.write("%v 0 obj\n", len(w.objOffsets))
 	w.writeVal(val)
@@ -421,11 +570,46 @@ func (w *pdfWriter) writeObject(val interface{}) pdfRef {
 	return pdfRef(len(w.objOffsets))
 }
 
+// reserveObject returns the reference of an object that is written later by writeObjectAt, so that objects can refer to objects that have not been written yet.
+func (w *pdfWriter) reserveObject() pdfRef {
+	if w.linearize || w.objStreams {
+		w.objects = append(w.objects, nil)
+	}
+	w.objOffsets = append(w.objOffsets, 0)
+	return pdfRef(len(w.objOffsets))
+}
+
+func (w *pdfWriter) writeObjectAt(ref pdfRef, val interface{}) {
+	if w.linearize || w.objStreams {
+		w.objects[ref-1] = val
+		return
+	}
+	w.objOffsets[ref-1] = w.pos
+	w.write("%v 0 obj\n", ref)
+	w.writeVal(val)
+	w.write("\nendobj\n")
+}
+
+// getFont returns the reference of a font, which is written when closing so that only the glyphs that are used are embedded.
 func (w *pdfWriter) getFont(font *Font) pdfRef {
 	if ref, ok := w.fonts[font]; ok {
 		return ref
 	}
+	ref := w.reserveObject()
+	w.fonts[font] = ref
+	w.fontList = append(w.fontList, font)
+	w.glyphs[font] = map[uint16]bool{}
+	return ref
+}
 
+// useGlyphs marks the glyphs as used by a font.
+func (w *pdfWriter) useGlyphs(font *Font, indices []uint16) {
+	for _, index := range indices {
+		w.glyphs[font][index] = true
+	}
+}
+
+func (w *pdfWriter) writeFont(font *Font, ref pdfRef, n int) {
 	mimetype, b := font.Raw()
 	if mimetype != "font/truetype" && mimetype != "font/opentype" {
 		var err error
@@ -481,6 +665,22 @@ func (w *pdfWriter) getFont(font *Font) pdfRef {
 	}
 
 	baseFont := strings.ReplaceAll(font.name, " ", "_")
+	if w.subsetFonts && mimetype == "font/truetype" {
+		glyphs := []uint16{}
+		for glyph := range w.glyphs[font] {
+			glyphs = append(glyphs, glyph)
+		}
+		if subset, err := canvasFont.SubsetSFNT(b, glyphs); err == nil {
+			// subset fonts are tagged by six uppercase letters that are unique in the document
+			tag := []byte("AAAAAA+")
+			for k := 5; 0 <= k; k-- {
+				tag[k] += byte(n % 26)
+				n /= 26
+			}
+			b = subset
+			baseFont = string(tag) + baseFont
+		}
+	}
 	fontfileRef := w.writeObject(pdfStream{
 		dict: pdfDict{
 			"Subtype": pdfName(ffSubtype),
@@ -488,7 +688,7 @@ func (w *pdfWriter) getFont(font *Font) pdfRef {
 		},
 		stream: b,
 	})
-	ref := w.writeObject(pdfDict{
+	w.writeObjectAt(ref, pdfDict{
 		"Type":     pdfName("Font"),
 		"Subtype":  pdfName("Type0"),
 		"BaseFont": pdfName(baseFont),
@@ -520,12 +720,20 @@ func (w *pdfWriter) getFont(font *Font) pdfRef {
 			},
 		}},
 	})
-	w.fonts[font] = ref
-	return ref
 }
 
 func (w *pdfWriter) Close() error {
-	parent := pdfRef(len(w.objOffsets) + 2 + len(w.pages))
+	for i, font := range w.fontList {
+		w.writeFont(font, w.fonts[font], i)
+	}
+
+	var refField, refSig pdfRef
+	if w.signature != nil {
+		refField, refSig = w.reserveObject(), w.reserveObject()
+		w.pages[0].annots = append(w.pages[0].annots, refField)
+	}
+
+	parent := pdfRef(len(w.objOffsets) + 2*len(w.pages) + 1) // each page writes its contents and itself
 	kids := pdfArray{}
 	for _, p := range w.pages {
 		kids = append(kids, p.writePage(parent))
@@ -555,24 +763,89 @@ func (w *pdfWriter) Close() error {
 	}
 	refInfo := w.writeObject(info)
 
-	refCatalog := w.writeObject(pdfDict{
+	catalog := pdfDict{
 		"Type":  pdfName("Catalog"),
 		"Pages": refPages,
-	})
+	}
+	if w.signature != nil {
+		// invisible signature field that is printed and locked
+		w.writeObjectAt(refField, pdfDict{
+			"Type":    pdfName("Annot"),
+			"Subtype": pdfName("Widget"),
+			"FT":      pdfName("Sig"),
+			"T":       "Signature1",
+			"V":       refSig,
+			"Rect":    pdfArray{0, 0, 0, 0},
+			"F":       132,
+			"P":       kids[0],
+		})
+		catalog["AcroForm"] = pdfDict{
+			"Fields":   pdfArray{refField},
+			"SigFlags": 3,
+		}
+	}
+	if w.encryption != nil {
+		// AES-256 is an extension of PDF 1.7
+		catalog["Extensions"] = pdfDict{
+			"ADBE": pdfDict{
+				"BaseVersion":    pdfName("1.7"),
+				"ExtensionLevel": 8,
+			},
+		}
+	}
+	refCatalog := w.writeObject(catalog)
 
+	trailer := pdfDict{
+		"Root": refCatalog,
+		"Info": refInfo,
+	}
+	if w.encryption != nil {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return err
+		}
+		enc := w.encryption
+		w.encryption = nil // the encryption dictionary is not encrypted
+		trailer["Encrypt"] = w.writeObject(enc.dict)
+		trailer["ID"] = pdfArray{id, id}
+		w.encryption = enc
+	}
+	if w.linearize && w.objStreams {
+		return fmt.Errorf("linearized documents cannot have object streams")
+	} else if w.linearize {
+		pages := []pdfRef{}
+		for _, kid := range kids {
+			pages = append(pages, kid.(pdfRef))
+		}
+		return w.writeLinearized(trailer, pages)
+	} else if w.objStreams {
+		w.writeObjectStreams(trailer["Encrypt"])
+	}
+	if w.signature != nil {
+		return w.writeSigned(refSig, trailer)
+	}
+	w.writeXref(trailer)
+	return w.err
+}
+
+// writeXref writes the cross-reference table and the trailer, which is the end of the document.
+func (w *pdfWriter) writeXref(trailer pdfDict) {
+	if w.objStreams {
+		w.writeXrefStream(trailer)
+		return
+	}
 	xrefOffset := w.pos
 	w.write("xref\n0 %d\n0000000000 65535 f\n", len(w.objOffsets)+1)
 	for _, objOffset := range w.objOffsets {
 		w.write("%010d 00000 n\n", objOffset)
 	}
 	w.write("trailer\n")
-	w.writeVal(pdfDict{
-		"Root": refCatalog,
-		"Size": len(w.objOffsets) + 1,
-		"Info": refInfo,
-	})
+	trailer["Size"] = len(w.objOffsets) + 1
+	enc := w.encryption
+	w.encryption = nil // the trailer is not encrypted
+	w.writeVal(trailer)
+	w.encryption = enc
 	w.write("\nstartxref\n%v\n%%%%EOF", xrefOffset)
-	return w.err
 }
 
 type pdfPageWriter struct {
@@ -583,6 +856,8 @@ type pdfPageWriter struct {
 
 	graphicsStates map[float64]pdfName
 	alpha          float64
+	overprint      bool
+	spotColors     map[string]pdfName
 	fillColor      color.RGBA
 	strokeColor    color.RGBA
 	lineWidth      float64
@@ -595,10 +870,26 @@ type pdfPageWriter struct {
 	inTextObject   bool
 	textPosition   Matrix
 	textCharSpace  float64
+	textRise       float64
 	textRenderMode int
+	annots         pdfArray
+	links          []pdfLink
+}
+
+// pdfLink is a link annotation of a page with its area in millimeters.
+type pdfLink struct {
+	url  string
+	rect Rect
 }
 
 func (w *pdfWriter) NewPage(width, height float64) *pdfPageWriter {
+	page := w.newPageWriter(width, height)
+	w.pages = append(w.pages, page)
+	return page
+}
+
+// newPageWriter returns a writer of the contents of a page or form of width by height millimeters.
+func (w *pdfWriter) newPageWriter(width, height float64) *pdfPageWriter {
 	// for defaults see https://help.adobe.com/pdfl_sdk/15/PDFL_SDK_HTMLHelp/PDFL_SDK_HTMLHelp/API_References/PDFL_API_Reference/PDFEdit_Layer/General.html#_t_PDEGraphicState
 	page := &pdfPageWriter{
 		Buffer:         &bytes.Buffer{},
@@ -622,7 +913,6 @@ func (w *pdfWriter) NewPage(width, height float64) *pdfPageWriter {
 		textCharSpace:  0.0,
 		textRenderMode: 0,
 	}
-	w.pages = append(w.pages, page)
 
 	m := Identity.Scale(ptPerMm, ptPerMm)
 	fmt.Fprintf(page, " %v %v %v %v %v %v cm", dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]))
@@ -634,6 +924,10 @@ func (w *pdfPageWriter) writePage(parent pdfRef) pdfRef {
 	if 0 < len(b) && b[0] == ' ' {
 		b = b[1:]
 	}
+	margin := w.pdf.marks.margin()
+	if 0.0 < margin {
+		b = w.writeMarks(b, margin)
+	}
 	stream := pdfStream{
 		dict:   pdfDict{},
 		stream: b,
@@ -642,10 +936,10 @@ func (w *pdfPageWriter) writePage(parent pdfRef) pdfRef {
 		stream.dict["Filter"] = pdfFilterFlate
 	}
 	contents := w.pdf.writeObject(stream)
-	return w.pdf.writeObject(pdfDict{
+	page := pdfDict{
 		"Type":      pdfName("Page"),
 		"Parent":    parent,
-		"MediaBox":  pdfArray{0.0, 0.0, w.width * ptPerMm, w.height * ptPerMm},
+		"MediaBox":  pdfArray{0.0, 0.0, (w.width + 2.0*margin) * ptPerMm, (w.height + 2.0*margin) * ptPerMm},
 		"Resources": w.resources,
 		"Group": pdfDict{
 			"Type": pdfName("Group"),
@@ -654,7 +948,61 @@ func (w *pdfPageWriter) writePage(parent pdfRef) pdfRef {
 			"CS":   pdfName("DeviceRGB"),
 		},
 		"Contents": contents,
-	})
+	}
+	for _, link := range w.links {
+		r := link.rect
+		w.annots = append(w.annots, pdfDict{
+			"Type":    pdfName("Annot"),
+			"Subtype": pdfName("Link"),
+			"Rect":    pdfArray{(margin + r.X) * ptPerMm, (margin + r.Y) * ptPerMm, (margin + r.X + r.W) * ptPerMm, (margin + r.Y + r.H) * ptPerMm},
+			"Border":  pdfArray{0, 0, 0},
+			"A": pdfDict{
+				"S":   pdfName("URI"),
+				"URI": link.url,
+			},
+		})
+	}
+	if 0 < len(w.annots) {
+		page["Annots"] = w.annots
+	}
+	if 0.0 < margin {
+		bleed := w.pdf.marks.Bleed
+		page["TrimBox"] = pdfArray{margin * ptPerMm, margin * ptPerMm, (margin + w.width) * ptPerMm, (margin + w.height) * ptPerMm}
+		page["BleedBox"] = pdfArray{(margin - bleed) * ptPerMm, (margin - bleed) * ptPerMm, (margin + w.width + bleed) * ptPerMm, (margin + w.height + bleed) * ptPerMm}
+	}
+	return w.pdf.writeObject(page)
+}
+
+// writeMarks returns the contents of the page moved by the margin and clipped to the bleed, followed by the prepress marks. Registration marks are drawn in the All separation color so that they print on every plate.
+func (w *pdfPageWriter) writeMarks(contents []byte, margin float64) []byte {
+	marks := w.pdf.marks
+	bleed := marks.Bleed
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "q 1 0 0 1 %v %v cm %v %v %v %v re W n ", dec(margin*ptPerMm), dec(margin*ptPerMm), dec(-bleed*ptPerMm), dec(-bleed*ptPerMm), dec((w.width+2.0*bleed)*ptPerMm), dec((w.height+2.0*bleed)*ptPerMm))
+	b.Write(contents)
+	fmt.Fprintf(b, " Q q %v 0 0 %v %v %v cm", dec(ptPerMm), dec(ptPerMm), dec(margin*ptPerMm), dec(margin*ptPerMm))
+	for _, mark := range marks.marks(w.width, w.height) {
+		if mark.registration {
+			colorSpaces, ok := w.resources["ColorSpace"].(pdfDict)
+			if !ok {
+				colorSpaces = pdfDict{}
+				w.resources["ColorSpace"] = colorSpaces
+			}
+			colorSpaces["All"] = pdfArray{pdfName("Separation"), pdfName("All"), pdfName("DeviceCMYK"), pdfDict{
+				"FunctionType": 2,
+				"Domain":       pdfArray{0, 1},
+				"C0":           pdfArray{0, 0, 0, 0},
+				"C1":           pdfArray{1, 1, 1, 1},
+				"N":            1,
+			}}
+			b.WriteString(" /All cs 1 scn")
+		} else {
+			fmt.Fprintf(b, " %v %v %v %v k", dec(mark.cmyk[0]), dec(mark.cmyk[1]), dec(mark.cmyk[2]), dec(mark.cmyk[3]))
+		}
+		fmt.Fprintf(b, " %v f", mark.path.ToPDF())
+	}
+	b.WriteString(" Q")
+	return b.Bytes()
 }
 
 func (w *pdfPageWriter) SetAlpha(alpha float64) {
@@ -691,6 +1039,133 @@ func (w *pdfPageWriter) SetStrokeColor(strokeColor color.RGBA) {
 	w.SetAlpha(a)
 }
 
+// SetOverprint sets whether fills and strokes overprint the inks underneath, where zero components of process colors leave the inks underneath as well (overprint mode 1).
+func (w *pdfPageWriter) SetOverprint(overprint bool) {
+	if overprint != w.overprint {
+		name := pdfName("OP0")
+		if overprint {
+			name = "OP1"
+		}
+		if _, ok := w.resources["ExtGState"]; !ok {
+			w.resources["ExtGState"] = pdfDict{}
+		}
+		w.resources["ExtGState"].(pdfDict)[name] = pdfDict{
+			"OP":  overprint,
+			"op":  overprint,
+			"OPM": 1,
+		}
+		fmt.Fprintf(w, " /%v gs", name)
+		w.overprint = overprint
+	}
+}
+
+// paintMesh paints a mesh gradient transformed by m as a shading of Coons patches (type 6) that is clipped by the path. Shadings have no opacity per point, so that the opacity of the mesh is the average of its colors.
+func (w *pdfPageWriter) paintMesh(path *Path, fillRule FillRule, mesh *MeshGradient, m Matrix) {
+	if len(mesh.Patches) == 0 {
+		return
+	}
+	bounds := mesh.Bounds().Transform(m)
+	if bounds.W == 0.0 || bounds.H == 0.0 {
+		return
+	}
+
+	// coordinates are mapped from 32-bit integers to the bounds by /Decode, colors are 8-bit
+	b := &bytes.Buffer{}
+	coord := func(v, min, size float64) {
+		n := uint32(math.Max(0.0, math.Min((v-min)/size*math.MaxUint32+0.5, math.MaxUint32)))
+		b.Write([]byte{byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)})
+	}
+	for _, patch := range mesh.Patches {
+		b.WriteByte(0) // new patch without shared edges
+		for _, p := range patch.Points {
+			p = m.Dot(p)
+			coord(p.X, bounds.X, bounds.W)
+			coord(p.Y, bounds.Y, bounds.H)
+		}
+		for _, col := range patch.Colors {
+			if col.A == 0 {
+				b.Write([]byte{0, 0, 0})
+				continue
+			}
+			a := float64(col.A) / 255.0
+			b.Write([]byte{byte(float64(col.R)/a + 0.5), byte(float64(col.G)/a + 0.5), byte(float64(col.B)/a + 0.5)})
+		}
+	}
+	dict := pdfDict{
+		"ShadingType":       6,
+		"ColorSpace":        pdfName("DeviceRGB"),
+		"BitsPerCoordinate": 32,
+		"BitsPerComponent":  8,
+		"BitsPerFlag":       8,
+		"Decode":            pdfArray{bounds.X, bounds.X + bounds.W, bounds.Y, bounds.Y + bounds.H, 0, 1, 0, 1, 0, 1},
+	}
+	if w.pdf.compress {
+		dict["Filter"] = pdfFilterFlate
+	}
+	ref := w.pdf.writeObject(pdfStream{dict, b.Bytes()})
+
+	if _, ok := w.resources["Shading"]; !ok {
+		w.resources["Shading"] = pdfDict{}
+	}
+	name := pdfName(fmt.Sprintf("Sh%d", len(w.resources["Shading"].(pdfDict))))
+	w.resources["Shading"].(pdfDict)[name] = ref
+
+	w.SetAlpha(float64(mesh.averageColor().A) / 255.0)
+	clip := " W"
+	if fillRule == EvenOdd {
+		clip = " W*"
+	}
+	fmt.Fprintf(w, " q %v%v n /%v sh Q", path.ToPDF(), clip, name)
+}
+
+// getSpotColorSpace returns the name of the Separation color space of the spot color, with its process color as alternate.
+func (w *pdfPageWriter) getSpotColorSpace(spot SpotColor) pdfName {
+	if name, ok := w.spotColors[spot.Name]; ok {
+		return name
+	}
+	if w.spotColors == nil {
+		w.spotColors = map[string]pdfName{}
+	}
+	name := pdfName(fmt.Sprintf("Spot%d", len(w.spotColors)))
+	w.spotColors[spot.Name] = name
+
+	colorSpaces, ok := w.resources["ColorSpace"].(pdfDict)
+	if !ok {
+		colorSpaces = pdfDict{}
+		w.resources["ColorSpace"] = colorSpaces
+	}
+	colorSpaces[name] = pdfArray{pdfName("Separation"), pdfName(spot.Name), pdfName("DeviceCMYK"), pdfDict{
+		"FunctionType": 2,
+		"Domain":       pdfArray{0, 1},
+		"C0":           pdfArray{0, 0, 0, 0},
+		"C1":           pdfArray{spot.CMYK[0], spot.CMYK[1], spot.CMYK[2], spot.CMYK[3]},
+		"N":            1,
+	}}
+	return name
+}
+
+// setFill sets the fill color, or the tint of the spot color when set, with the opacity of the color.
+func (w *pdfPageWriter) setFill(fillColor color.RGBA, spot *SpotColor) {
+	if spot == nil {
+		w.SetFillColor(fillColor)
+		return
+	}
+	fmt.Fprintf(w, " /%v cs %v scn", w.getSpotColorSpace(*spot), dec(spot.Tint))
+	w.fillColor = Transparent // is never set as a color
+	w.SetAlpha(float64(fillColor.A) / 255.0)
+}
+
+// setStroke sets the stroke color, or the tint of the spot color when set, with the opacity of the color.
+func (w *pdfPageWriter) setStroke(strokeColor color.RGBA, spot *SpotColor) {
+	if spot == nil {
+		w.SetStrokeColor(strokeColor)
+		return
+	}
+	fmt.Fprintf(w, " /%v CS %v SCN", w.getSpotColorSpace(*spot), dec(spot.Tint))
+	w.strokeColor = Transparent // is never set as a color
+	w.SetAlpha(float64(strokeColor.A) / 255.0)
+}
+
 func (w *pdfPageWriter) SetLineWidth(lineWidth float64) {
 	if lineWidth != w.lineWidth {
 		fmt.Fprintf(w, " %v w", dec(lineWidth))
@@ -862,55 +1337,84 @@ func (w *pdfPageWriter) WriteText(TJ ...interface{}) {
 		return
 	}
 
-	units := float64(w.font.sfnt.UnitsPerEm())
-
-	first := true
-	write := func(s string) {
-		if first {
-			fmt.Fprintf(w, "(")
-			first = false
-		} else {
-			fmt.Fprintf(w, " (")
+	// adjustments are in thousandths of the font size, and glyphs are written as indices for Identity-H encoding
+	var sfntBuffer sfnt.Buffer
+	inArray := false
+	open := func() {
+		if !inArray {
+			fmt.Fprintf(w, "[")
+			inArray = true
 		}
-		indices := w.font.toIndices(s)
-		binary.Write(w, binary.BigEndian, indices)
-		fmt.Fprintf(w, ")")
 	}
-
-	var sfntBuffer sfnt.Buffer
-	fmt.Fprintf(w, "[")
 	for _, tj := range TJ {
 		switch val := tj.(type) {
-		case string:
-			i := 0
-			var rPrev rune
-			for j, r := range val {
-				if i < j {
-					i0, err0 := w.font.sfnt.GlyphIndex(&sfntBuffer, rPrev)
-					i1, err1 := w.font.sfnt.GlyphIndex(&sfntBuffer, r)
-					if err0 == nil && err1 == nil {
-						kern, err := w.font.sfnt.Kern(&sfntBuffer, i0, i1, toI26_6(units), font.HintingNone)
-						if err == nil && kern != 0.0 {
-							write(val[i:j])
-							fmt.Fprintf(w, " %d", -int(fromI26_6(kern)*1000.0/units+0.5))
-							i = j
-						}
+		case []Glyph:
+			for i, glyph := range val {
+				if glyph.YOffset != w.textRise {
+					// marks are raised by the text rise, which cannot change within a TJ array
+					if inArray {
+						fmt.Fprintf(w, "]TJ")
+						inArray = false
 					}
+					fmt.Fprintf(w, " %v Ts", dec(glyph.YOffset))
+					w.textRise = glyph.YOffset
+				}
+				open()
+				if i == 0 && glyph.XOffset != 0.0 {
+					fmt.Fprintf(w, " %d", -int(math.Round(glyph.XOffset*1000.0/w.fontSize)))
+				}
+				index := []uint16{glyph.ID}
+				w.pdf.useGlyphs(w.font, index)
+				fmt.Fprintf(w, "(")
+				binary.Write(w, binary.BigEndian, index)
+				fmt.Fprintf(w, ")")
+
+				// the shaped advance replaces the advance of the glyph and the character spacing within clusters, and moves to the offset of the next glyph
+				adjust := (glyph.XAdvance - glyph.XOffset) * 1000.0 / w.fontSize
+				if advance, err := w.font.sfnt.GlyphAdvance(&sfntBuffer, sfnt.GlyphIndex(glyph.ID), toI26_6(w.fontSize), font.HintingNone); err == nil {
+					adjust -= fromI26_6(advance) * 1000.0 / w.fontSize
+				}
+				if i+1 < len(val) {
+					adjust += val[i+1].XOffset * 1000.0 / w.fontSize
+					if val[i+1].Cluster == glyph.Cluster {
+						adjust -= w.textCharSpace * 1000.0 / w.fontSize
+					}
+				}
+				if adjust := -int(math.Round(adjust)); adjust != 0 {
+					fmt.Fprintf(w, " %d", adjust)
 				}
-				rPrev = r
 			}
-			write(val[i:])
 		case float64:
+			open()
 			fmt.Fprintf(w, " %d", -int(val*1000.0/w.fontSize+0.5))
 		case int:
+			open()
 			fmt.Fprintf(w, " %d", -int(float64(val)*1000.0/w.fontSize+0.5))
 		}
 	}
-	fmt.Fprintf(w, "]TJ")
+	if inArray {
+		fmt.Fprintf(w, "]TJ")
+	}
+	if w.textRise != 0.0 {
+		fmt.Fprintf(w, " 0 Ts")
+		w.textRise = 0.0
+	}
 }
 
 func (w *pdfPageWriter) DrawImage(img image.Image, enc ImageEncoding, m Matrix) {
 	size := img.Bounds().Size()
+	if 0.0 < w.pdf.imgDPM {
+		// downsample images drawn with a resolution, in pixels per millimeter, higher than the maximum
+		sx := math.Hypot(m[0][0], m[1][0]) * w.pdf.imgDPM
+		sy := math.Hypot(m[0][1], m[1][1]) * w.pdf.imgDPM
+		if sx < 1.0 || sy < 1.0 {
+			width := clampInt(int(math.Ceil(float64(size.X)*math.Min(sx, 1.0))), 1, size.X)
+			height := clampInt(int(math.Ceil(float64(size.Y)*math.Min(sy, 1.0))), 1, size.Y)
+			img = resizeImage(img, width, height, w.pdf.imgResampling)
+			m = m.Scale(float64(size.X)/float64(width), float64(size.Y)/float64(height))
+			size = img.Bounds().Size()
+		}
+	}
 
 	// add clipping path around image for smooth edges when rotating
 	outerRect := Rect{0.0, 0.0, float64(size.X), float64(size.Y)}.Transform(m)
@@ -927,7 +1431,82 @@ func (w *pdfPageWriter) DrawImage(img image.Image, enc ImageEncoding, m Matrix)
 	fmt.Fprintf(w, " %v %v %v %v %v %v cm /%v Do Q", dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]), name)
 }
 
+func (w *pdfPageWriter) DrawPDFPage(page *PDFPage, m Matrix) {
+	w.drawForm(w.pdf.getPDFPage(page), m)
+}
+
+// DrawCanvas draws the canvas clipped to its size as a form XObject, which is written once for each canvas.
+func (w *pdfPageWriter) DrawCanvas(c *Canvas, enc ImageEncoding, m Matrix) {
+	ref, ok := w.pdf.canvases[c]
+	if !ok {
+		// the form inherits the graphics state where it is drawn, which is set to the defaults assumed by the writer
+		form := w.pdf.newPageWriter(c.W, c.H)
+		c.Render(&PDF{w: form, width: c.W, height: c.H, imgEnc: enc})
+		b := append([]byte("0 g 0 G 1 w 0 J 0 j 10 M [] 0 d 0 Tc 0 Tr"), form.Bytes()...)
+		dict := pdfDict{
+			"Type":      pdfName("XObject"),
+			"Subtype":   pdfName("Form"),
+			"BBox":      pdfArray{0.0, 0.0, c.W * ptPerMm, c.H * ptPerMm},
+			"Resources": form.resources,
+		}
+		if w.pdf.compress {
+			dict["Filter"] = pdfFilterFlate
+		}
+		ref = w.pdf.writeObject(pdfStream{dict, b})
+		w.pdf.canvases[c] = ref
+	}
+	w.drawForm(ref, m)
+}
+
+// drawForm draws a form XObject of which the contents are in points.
+func (w *pdfPageWriter) drawForm(ref pdfRef, m Matrix) {
+	if _, ok := w.resources["XObject"]; !ok {
+		w.resources["XObject"] = pdfDict{}
+	}
+	xobjects := w.resources["XObject"].(pdfDict)
+	name := pdfName(fmt.Sprintf("Fm%d", len(xobjects)))
+	for key, val := range xobjects {
+		if val == ref {
+			name = key
+		}
+	}
+	xobjects[name] = ref
+
+	// the content of the page is in points and is drawn with its own graphics state
+	m = m.Scale(mmPerPt, mmPerPt)
+	w.SetAlpha(1.0)
+	fmt.Fprintf(w, " q %v %v %v %v %v %v cm /%v Do Q", dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]), name)
+}
+
 func (w *pdfPageWriter) embedImage(img image.Image, enc ImageEncoding) pdfName {
+	// images with an RGB color profile are tagged with that profile, others are converted to sRGB
+	var colorSpace interface{} = pdfName("DeviceRGB")
+	jpg, isJPEG := img.(*JPEGImage)
+	if isJPEG && jpg.iccColorSpace() == "RGB " {
+		colorSpace = pdfArray{pdfName("ICCBased"), w.pdf.getICCProfile(jpg.ICC)}
+	} else {
+		img = srgbImage(img)
+	}
+
+	if isJPEG && enc == Lossy && jpg.data != nil && (jpg.ICC == nil || jpg.iccColorSpace() == "RGB ") {
+		if _, ok := jpg.Image.(*image.YCbCr); ok {
+			// embed the JPEG file as is, which is decoded by the PDF reader
+			return w.addImage(pdfStream{
+				dict: pdfDict{
+					"Type":             pdfName("XObject"),
+					"Subtype":          pdfName("Image"),
+					"Width":            jpg.Bounds().Dx(),
+					"Height":           jpg.Bounds().Dy(),
+					"ColorSpace":       colorSpace,
+					"BitsPerComponent": 8,
+					"Interpolate":      true,
+					"Filter":           pdfFilterDCT,
+				},
+				stream: jpg.data,
+			})
+		}
+	}
+
 	size := img.Bounds().Size()
 	b := make([]byte, size.X*size.Y*3)
 	bMask := make([]byte, size.X*size.Y)
@@ -953,7 +1532,7 @@ func (w *pdfPageWriter) embedImage(img image.Image, enc ImageEncoding) pdfName {
 		"Subtype":          pdfName("Image"),
 		"Width":            size.X,
 		"Height":           size.Y,
-		"ColorSpace":       pdfName("DeviceRGB"),
+		"ColorSpace":       colorSpace,
 		"BitsPerComponent": 8,
 		"Interpolate":      true,
 		"Filter":           pdfFilterFlate,
@@ -975,12 +1554,28 @@ func (w *pdfPageWriter) embedImage(img image.Image, enc ImageEncoding) pdfName {
 		})
 	}
 
-	// TODO: (PDF) implement JPXFilter for lossy image compression
-	ref := w.pdf.writeObject(pdfStream{
+	if enc == Lossy {
+		rgba := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+		for i := 0; i < size.X*size.Y; i++ {
+			copy(rgba.Pix[i*4:], b[i*3:i*3+3])
+			rgba.Pix[i*4+3] = 0xFF
+		}
+		buf := &bytes.Buffer{}
+		if err := jpeg.Encode(buf, rgba, &jpeg.Options{Quality: w.pdf.imgQuality}); err != nil {
+			panic(err)
+		}
+		dict["Filter"] = pdfFilterDCT
+		b = buf.Bytes()
+	}
+	return w.addImage(pdfStream{
 		dict:   dict,
 		stream: b,
 	})
+}
 
+// addImage writes an image XObject and adds it to the resources of the page.
+func (w *pdfPageWriter) addImage(xobject pdfStream) pdfName {
+	ref := w.pdf.writeObject(xobject)
 	if _, ok := w.resources["XObject"]; !ok {
 		w.resources["XObject"] = pdfDict{}
 	}
@@ -989,6 +1584,23 @@ func (w *pdfPageWriter) embedImage(img image.Image, enc ImageEncoding) pdfName {
 	return name
 }
 
+// getICCProfile returns the ICC color profile of RGB images, which is written once for all images that use it.
+func (w *pdfWriter) getICCProfile(icc []byte) pdfRef {
+	if ref, ok := w.profiles[string(icc)]; ok {
+		return ref
+	}
+	ref := w.writeObject(pdfStream{
+		dict: pdfDict{
+			"N":         3,
+			"Alternate": pdfName("DeviceRGB"),
+			"Filter":    pdfFilterFlate,
+		},
+		stream: icc,
+	})
+	w.profiles[string(icc)] = ref
+	return ref
+}
+
 func (w *pdfPageWriter) getOpacityGS(a float64) pdfName {
 	if name, ok := w.graphicsStates[a]; ok {
 		return name
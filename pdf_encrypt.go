@@ -0,0 +1,171 @@
+package canvas
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"hash"
+)
+
+// PDFPermissions are the operations that readers of an encrypted PDF document allow to users who open it with the user password. Users who open it with the owner password have all permissions.
+type PDFPermissions uint32
+
+// see PDFPermissions
+const (
+	PDFPrint            PDFPermissions = 1 << 2  // print, in low quality unless PDFPrintHighQuality is set
+	PDFModify           PDFPermissions = 1 << 3  // modify the contents
+	PDFCopy             PDFPermissions = 1 << 4  // copy or extract text and graphics
+	PDFAnnotate         PDFPermissions = 1 << 5  // add or modify annotations and fill in forms
+	PDFFillForms        PDFPermissions = 1 << 8  // fill in forms and sign
+	PDFExtract          PDFPermissions = 1 << 9  // extract text and graphics for accessibility
+	PDFAssemble         PDFPermissions = 1 << 10 // insert, rotate, or delete pages
+	PDFPrintHighQuality PDFPermissions = 1 << 11 // print faithfully
+	PDFAllPermissions   PDFPermissions = PDFPrint | PDFModify | PDFCopy | PDFAnnotate | PDFFillForms | PDFExtract | PDFAssemble | PDFPrintHighQuality
+)
+
+// SetEncryption encrypts the document with AES-256 (PDF 2.0, revision 6 of the standard security handler). The user password is needed to open the document, and may be empty so that anyone can open it with the given permissions, while the owner password gives all permissions. Passwords are truncated to 127 bytes of UTF-8. It must be called before drawing.
+func (r *PDF) SetEncryption(userPassword, ownerPassword string, permissions PDFPermissions) {
+	r.w.pdf.SetEncryption(userPassword, ownerPassword, permissions)
+}
+
+// pdfEncryption encrypts the strings and streams of a document with its file key.
+type pdfEncryption struct {
+	key  []byte // file encryption key
+	dict pdfDict
+}
+
+func (w *pdfWriter) SetEncryption(userPassword, ownerPassword string, permissions PDFPermissions) {
+	if len(w.objOffsets) != 0 {
+		w.err = fmt.Errorf("encryption must be set before drawing")
+		return
+	}
+
+	// see ISO 32000-2, section 7.6.4.4.7 and 7.6.4.4.8
+	random := make([]byte, 32+4*8+4)
+	if _, err := rand.Read(random); err != nil {
+		w.err = err
+		return
+	}
+	key := random[:32]
+	user, owner := pdfPassword(userPassword), pdfPassword(ownerPassword)
+	userValidation, userKey := random[32:40], random[40:48]
+	ownerValidation, ownerKey := random[48:56], random[56:64]
+
+	u := append(append(pdfHash(user, userValidation, nil), userValidation...), userKey...)
+	ue := pdfEncryptKey(pdfHash(user, userKey, nil), key)
+	o := append(append(pdfHash(owner, ownerValidation, u), ownerValidation...), ownerKey...)
+	oe := pdfEncryptKey(pdfHash(owner, ownerKey, u), key)
+
+	// reserved bits 7, 8, and 13-32 must be set
+	p := uint32(permissions&PDFAllPermissions) | 0xFFFFF0C0
+	perms := make([]byte, 16)
+	binary.LittleEndian.PutUint32(perms, p)
+	copy(perms[4:], []byte{0xFF, 0xFF, 0xFF, 0xFF, 'T', 'a', 'd', 'b'})
+	copy(perms[12:], random[64:68])
+	block, _ := aes.NewCipher(key)
+	block.Encrypt(perms, perms)
+
+	w.encryption = &pdfEncryption{
+		key: key,
+		dict: pdfDict{
+			"Filter": pdfName("Standard"),
+			"V":      5,
+			"R":      6,
+			"Length": 256,
+			"CF": pdfDict{
+				"StdCF": pdfDict{
+					"AuthEvent": pdfName("DocOpen"),
+					"CFM":       pdfName("AESV3"),
+					"Length":    32,
+				},
+			},
+			"StmF":            pdfName("StdCF"),
+			"StrF":            pdfName("StdCF"),
+			"O":               o,
+			"U":               u,
+			"OE":              oe,
+			"UE":              ue,
+			"P":               int(int32(p)),
+			"Perms":           perms,
+			"EncryptMetadata": true,
+		},
+	}
+}
+
+// encrypt encrypts a string or stream using AES-256 in CBC mode with a random initialization vector that is prepended.
+func (e *pdfEncryption) encrypt(b []byte) []byte {
+	n := aes.BlockSize - len(b)%aes.BlockSize // PKCS#7 padding
+	dst := make([]byte, aes.BlockSize+len(b)+n)
+	rand.Read(dst[:aes.BlockSize])
+	copy(dst[aes.BlockSize:], b)
+	for i := len(dst) - n; i < len(dst); i++ {
+		dst[i] = byte(n)
+	}
+	block, _ := aes.NewCipher(e.key)
+	cipher.NewCBCEncrypter(block, dst[:aes.BlockSize]).CryptBlocks(dst[aes.BlockSize:], dst[aes.BlockSize:])
+	return dst
+}
+
+// pdfPassword returns the password as UTF-8 truncated to 127 bytes. Passwords are not normalized by SASLprep.
+func pdfPassword(password string) []byte {
+	b := []byte(password)
+	if 127 < len(b) {
+		b = b[:127]
+	}
+	return b
+}
+
+// pdfHash computes the hash of a password with a salt and user key, see ISO 32000-2, algorithm 2.B.
+func pdfHash(password, salt, userKey []byte) []byte {
+	h := sha256.New()
+	h.Write(password)
+	h.Write(salt)
+	h.Write(userKey)
+	k := h.Sum(nil)
+	for round := 0; ; {
+		k1 := []byte{}
+		for i := 0; i < 64; i++ {
+			k1 = append(k1, password...)
+			k1 = append(k1, k...)
+			k1 = append(k1, userKey...)
+		}
+		block, _ := aes.NewCipher(k[:16])
+		e := make([]byte, len(k1))
+		cipher.NewCBCEncrypter(block, k[16:32]).CryptBlocks(e, k1)
+
+		// the first 16 bytes as a big-endian number modulo 3 select the hash function, which equals the sum of the bytes modulo 3 as 256 is 1 modulo 3
+		sum := 0
+		for _, c := range e[:16] {
+			sum += int(c)
+		}
+		var h hash.Hash
+		switch sum % 3 {
+		case 0:
+			h = sha256.New()
+		case 1:
+			h = sha512.New384()
+		case 2:
+			h = sha512.New()
+		}
+		h.Write(e)
+		k = h.Sum(nil)
+
+		round++
+		if 64 <= round && int(e[len(e)-1]) <= round-32 {
+			break
+		}
+	}
+	return k[:32]
+}
+
+// pdfEncryptKey encrypts the file key with AES-256 in CBC mode without initialization vector and padding.
+func pdfEncryptKey(key, fileKey []byte) []byte {
+	block, _ := aes.NewCipher(key)
+	dst := make([]byte, len(fileKey))
+	cipher.NewCBCEncrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(dst, fileKey)
+	return dst
+}
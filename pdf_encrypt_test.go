@@ -0,0 +1,75 @@
+package canvas
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"image"
+	"regexp"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+// pdfHexString returns the hexadecimal string of the key in the PDF document.
+func pdfHexString(t *testing.T, b []byte, key string) []byte {
+	match := regexp.MustCompile(`/` + key + ` <([0-9A-F]*)>`).FindSubmatch(b)
+	test.That(t, match != nil, key)
+	s, err := hex.DecodeString(string(match[1]))
+	test.Error(t, err)
+	return s
+}
+
+func TestPDFEncryption(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf := NewPDF(buf, 210.0, 297.0)
+	pdf.SetCompression(false)
+	pdf.SetEncryption("user", "owner", PDFPrint|PDFCopy)
+	pdf.SetInfo("Contract", "", "", "")
+	pdf.RenderPath(Rectangle(10.0, 10.0), DefaultStyle, Identity)
+	test.Error(t, pdf.Close())
+
+	b := buf.Bytes()
+	test.That(t, bytes.Contains(b, []byte("/Filter /Standard")), "encryption dictionary")
+	test.That(t, bytes.Contains(b, []byte("/P -3884")), "permissions")
+	test.That(t, regexp.MustCompile(`/ID \[<[0-9A-F]{32}> <[0-9A-F]{32}>\]`).Match(b), "document ID")
+	test.That(t, !bytes.Contains(b, []byte("Contract")) && !bytes.Contains(b, []byte(" re ")) && !bytes.Contains(b, []byte(" l ")), "strings and streams are encrypted")
+
+	// authenticate the passwords and obtain the file key, see ISO 32000-2, algorithm 2.A
+	u, ue := pdfHexString(t, b, "U"), pdfHexString(t, b, "UE")
+	o, oe := pdfHexString(t, b, "O"), pdfHexString(t, b, "OE")
+	test.Bytes(t, pdfHash([]byte("user"), u[32:40], nil), u[:32])
+	test.Bytes(t, pdfHash([]byte("owner"), o[32:40], u), o[:32])
+	test.That(t, !bytes.Equal(pdfHash([]byte("wrong"), u[32:40], nil), u[:32]), "wrong password")
+	decryptKey := func(key, encrypted []byte) []byte {
+		block, _ := aes.NewCipher(key)
+		dst := make([]byte, len(encrypted))
+		cipher.NewCBCDecrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(dst, encrypted)
+		return dst
+	}
+	key := decryptKey(pdfHash([]byte("user"), u[40:48], nil), ue)
+	test.Bytes(t, decryptKey(pdfHash([]byte("owner"), o[40:48], u), oe), key)
+
+	perms := pdfHexString(t, b, "Perms")
+	block, _ := aes.NewCipher(key)
+	block.Decrypt(perms, perms)
+	test.T(t, int32(binary.LittleEndian.Uint32(perms)), int32(-3884))
+	test.Bytes(t, perms[8:12], []byte("Tadb"))
+
+	// the page contents are decrypted by the file key
+	match := regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`).FindSubmatch(b)
+	test.That(t, match != nil, "stream")
+	stream := match[1]
+	contents := make([]byte, len(stream)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, stream[:aes.BlockSize]).CryptBlocks(contents, stream[aes.BlockSize:])
+	contents = contents[:len(contents)-int(contents[len(contents)-1])]
+	test.String(t, string(contents), "2.8346457 0 0 2.8346457 0 0 cm 0 0 m 10 0 l 10 10 l 0 10 l f")
+
+	// encryption must be set before drawing
+	pdf = NewPDF(&bytes.Buffer{}, 210.0, 297.0)
+	pdf.RenderImage(image.NewRGBA(image.Rect(0, 0, 1, 1)), Identity)
+	pdf.SetEncryption("", "owner", PDFAllPermissions)
+	test.That(t, pdf.Close() != nil, "too late")
+}
@@ -0,0 +1,956 @@
+package canvas
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// pdfMaxNesting is the maximum depth of nested arrays and dictionaries, and of references that are followed while reading an object.
+const pdfMaxNesting = 256
+
+// PDFPage is a page of an existing PDF document, which the PDF renderer draws as a vector underlay such as a letterhead or the background of a form, see PDF.RenderPDFPage. Its content and the objects it uses are copied once into each document it is drawn in.
+type PDFPage struct {
+	W, H float64 // size of the crop box in millimeters, rotated by the rotation of the page
+
+	doc       *pdfReader
+	content   []byte
+	resources interface{}
+	group     interface{}
+	box       Rect   // crop box in points
+	m         Matrix // transformation from the crop box to the rotated page at the origin
+}
+
+// ReadPDFPages reads the pages of a PDF document. It supports cross-reference tables and streams, object streams, and the common stream filters, and rebuilds the cross-reference table of damaged documents. Encrypted documents are not supported.
+func ReadPDFPages(r io.Reader) ([]*PDFPage, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := newPDFReader(b)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := doc.trailer["Encrypt"]; ok {
+		return nil, fmt.Errorf("unsupported PDF: encrypted documents are not supported")
+	}
+	catalog, _ := doc.resolve(doc.trailer["Root"]).(pdfDict)
+	root, ok := doc.resolve(catalog["Pages"]).(pdfDict)
+	if !ok {
+		return nil, fmt.Errorf("bad PDF: no page tree")
+	}
+	pages := []*PDFPage{}
+	if err := doc.pages(root, pdfDict{}, map[pdfObjRef]bool{}, &pages); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// pdfObjRef is a reference to an object of a document that is being read, given by its object number.
+type pdfObjRef int
+
+// pdfXref is the location of an object, either at an offset in the file or in an object stream.
+type pdfXref struct {
+	offset int // -1 for free objects
+	stream int // object number of the object stream, or zero
+}
+
+type pdfReader struct {
+	b       []byte
+	xref    map[int]pdfXref
+	trailer pdfDict
+	objects map[int]interface{}
+	streams map[int]*pdfLexer // decoded object streams, positioned at their first object
+	offsets map[int]map[int]int
+	loading map[int]bool
+}
+
+func newPDFReader(b []byte) (*pdfReader, error) {
+	r := &pdfReader{
+		b:       b,
+		xref:    map[int]pdfXref{},
+		objects: map[int]interface{}{},
+		streams: map[int]*pdfLexer{},
+		offsets: map[int]map[int]int{},
+		loading: map[int]bool{},
+	}
+	if header := b[:clampInt(len(b), 0, 1024)]; !bytes.Contains(header, []byte("%PDF-")) {
+		return nil, fmt.Errorf("bad PDF: no header")
+	}
+
+	i := bytes.LastIndex(b, []byte("startxref"))
+	if i != -1 {
+		l := &pdfLexer{b: b, pos: i + len("startxref")}
+		if offset, err := strconv.Atoi(l.token()); err == nil {
+			if err := r.readXref(offset); err != nil {
+				r.xref, r.trailer = map[int]pdfXref{}, nil
+			}
+		}
+	}
+	if r.trailer == nil {
+		r.rebuildXref()
+	}
+	if r.trailer == nil {
+		return nil, fmt.Errorf("bad PDF: no trailer")
+	}
+	return r, nil
+}
+
+// readXref reads the cross-reference sections from the offset onwards by following their Prev entries, where the entries of newer sections take precedence.
+func (r *pdfReader) readXref(offset int) error {
+	visited := map[int]bool{}
+	for 0 <= offset && offset < len(r.b) && !visited[offset] {
+		visited[offset] = true
+		l := &pdfLexer{b: r.b, pos: offset}
+		var trailer pdfDict
+		if l.token() == "xref" {
+			for {
+				tok := l.token()
+				if tok == "trailer" {
+					val, err := l.value(0)
+					if err != nil {
+						return err
+					}
+					trailer, _ = val.(pdfDict)
+					break
+				}
+				start, err := strconv.Atoi(tok)
+				if err != nil {
+					return fmt.Errorf("bad PDF: invalid cross-reference table")
+				}
+				count, err := strconv.Atoi(l.token())
+				if err != nil {
+					return fmt.Errorf("bad PDF: invalid cross-reference table")
+				}
+				for i := 0; i < count; i++ {
+					off, err := strconv.Atoi(l.token())
+					l.token() // generation
+					kind := l.token()
+					if err != nil || kind != "n" && kind != "f" || kind == "n" && (off < 0 || len(r.b) <= off) {
+						return fmt.Errorf("bad PDF: invalid cross-reference table")
+					}
+					if _, ok := r.xref[start+i]; !ok {
+						if kind == "f" {
+							off = -1
+						}
+						r.xref[start+i] = pdfXref{offset: off}
+					}
+				}
+			}
+			if trailer == nil {
+				return fmt.Errorf("bad PDF: invalid trailer")
+			}
+			if stm, ok := trailer["XRefStm"].(int); ok {
+				// hybrid files have a cross-reference stream for the objects in object streams
+				if _, err := r.readXrefStream(stm); err != nil {
+					return err
+				}
+			}
+		} else {
+			var err error
+			if trailer, err = r.readXrefStream(offset); err != nil {
+				return err
+			}
+		}
+		if r.trailer == nil {
+			r.trailer = trailer
+		}
+		offset = -1
+		if prev, ok := trailer["Prev"].(int); ok {
+			offset = prev
+		}
+	}
+	return nil
+}
+
+// readXrefStream reads a cross-reference stream and returns its dictionary, which is the trailer.
+func (r *pdfReader) readXrefStream(offset int) (pdfDict, error) {
+	_, val, err := r.parseObject(offset)
+	if err != nil {
+		return nil, err
+	}
+	stream, ok := val.(pdfStream)
+	if !ok || stream.dict["Type"] != pdfName("XRef") {
+		return nil, fmt.Errorf("bad PDF: invalid cross-reference stream")
+	}
+	b, err := r.decode(stream)
+	if err != nil {
+		return nil, err
+	}
+	widths := [3]int{}
+	array, _ := stream.dict["W"].(pdfArray)
+	if len(array) != 3 {
+		return nil, fmt.Errorf("bad PDF: invalid cross-reference stream")
+	}
+	n := 0
+	for i := range widths {
+		widths[i], _ = array[i].(int)
+		if widths[i] < 0 || 8 < widths[i] {
+			return nil, fmt.Errorf("bad PDF: invalid cross-reference stream")
+		}
+		n += widths[i]
+	}
+	index, _ := stream.dict["Index"].(pdfArray)
+	if index == nil {
+		size, _ := stream.dict["Size"].(int)
+		index = pdfArray{0, size}
+	}
+	for i := 0; i+1 < len(index); i += 2 {
+		start, _ := index[i].(int)
+		count, _ := index[i+1].(int)
+		for j := 0; j < count && n <= len(b); j++ {
+			fields := [3]int{1, 0, 0} // the type defaults to one when it has no width
+			for k, width := range widths {
+				if 0 < width {
+					fields[k] = 0
+					for _, c := range b[:width] {
+						fields[k] = fields[k]<<8 | int(c)
+					}
+					b = b[width:]
+				}
+			}
+			if _, ok := r.xref[start+j]; ok {
+				continue
+			}
+			switch fields[0] {
+			case 0:
+				r.xref[start+j] = pdfXref{offset: -1}
+			case 1:
+				if len(r.b) <= fields[1] {
+					return nil, fmt.Errorf("bad PDF: invalid cross-reference stream")
+				}
+				r.xref[start+j] = pdfXref{offset: fields[1]}
+			case 2:
+				r.xref[start+j] = pdfXref{stream: fields[1]}
+			}
+		}
+	}
+	return stream.dict, nil
+}
+
+var pdfObjectHeader = regexp.MustCompile(`(?:^|[^0-9])([0-9]+)[\x00\t\n\f\r ]+[0-9]+[\x00\t\n\f\r ]+obj\b`)
+
+// rebuildXref rebuilds the cross-reference table by finding all objects in the file, for documents of which the cross-reference table is missing or damaged.
+func (r *pdfReader) rebuildXref() {
+	streams := []int{}
+	for _, match := range pdfObjectHeader.FindAllSubmatchIndex(r.b, -1) {
+		num, err := strconv.Atoi(string(r.b[match[2]:match[3]]))
+		if err != nil {
+			continue
+		}
+		r.xref[num] = pdfXref{offset: match[2]}
+		if _, val, err := r.parseObject(match[2]); err == nil {
+			if stream, ok := val.(pdfStream); ok {
+				if stream.dict["Type"] == pdfName("ObjStm") {
+					streams = append(streams, num)
+				} else if stream.dict["Type"] == pdfName("XRef") {
+					r.trailer = stream.dict
+				}
+			}
+		}
+	}
+	for _, num := range streams {
+		if _, ok := r.objectStream(num); ok {
+			for obj := range r.offsets[num] {
+				if _, ok := r.xref[obj]; !ok {
+					r.xref[obj] = pdfXref{stream: num}
+				}
+			}
+		}
+	}
+	if i := bytes.LastIndex(r.b, []byte("trailer")); i != -1 {
+		l := &pdfLexer{b: r.b, pos: i + len("trailer")}
+		if val, err := l.value(0); err == nil {
+			if trailer, ok := val.(pdfDict); ok {
+				r.trailer = trailer
+			}
+		}
+	}
+}
+
+// parseObject parses the indirect object at the offset and returns its object number.
+func (r *pdfReader) parseObject(offset int) (int, interface{}, error) {
+	if offset < 0 || len(r.b) <= offset {
+		return 0, nil, fmt.Errorf("bad PDF: invalid object offset %d", offset)
+	}
+	l := &pdfLexer{b: r.b, pos: offset}
+	num, err := strconv.Atoi(l.token())
+	if err != nil {
+		return 0, nil, fmt.Errorf("bad PDF: invalid object at %d", offset)
+	}
+	l.token()
+	if l.token() != "obj" {
+		return 0, nil, fmt.Errorf("bad PDF: invalid object at %d", offset)
+	}
+	val, err := l.value(0)
+	if err != nil {
+		return 0, nil, err
+	}
+	dict, ok := val.(pdfDict)
+	if !ok {
+		return num, val, nil
+	}
+	l.skip()
+	if !bytes.HasPrefix(r.b[l.pos:], []byte("stream")) {
+		return num, val, nil
+	}
+	start := l.pos + len("stream")
+	if start < len(r.b) && r.b[start] == '\r' {
+		start++
+	}
+	if start < len(r.b) && r.b[start] == '\n' {
+		start++
+	}
+	if length, ok := r.resolve(dict["Length"]).(int); ok && 0 <= length && start+length <= len(r.b) {
+		end := &pdfLexer{b: r.b, pos: start + length}
+		if end.token() == "endstream" {
+			return num, pdfStream{dict, r.b[start : start+length]}, nil
+		}
+	}
+	// the length is wrong or missing
+	end := bytes.Index(r.b[start:], []byte("endstream"))
+	if end == -1 {
+		return 0, nil, fmt.Errorf("bad PDF: unterminated stream at %d", offset)
+	}
+	data := bytes.TrimSuffix(r.b[start:start+end], []byte("\n"))
+	data = bytes.TrimSuffix(data, []byte("\r"))
+	return num, pdfStream{dict, data}, nil
+}
+
+// object returns the object by its number, or nil when it does not exist.
+func (r *pdfReader) object(num int) interface{} {
+	if val, ok := r.objects[num]; ok {
+		return val
+	}
+	xref, ok := r.xref[num]
+	if !ok || xref.offset < 0 || r.loading[num] || pdfMaxNesting < len(r.loading) {
+		return nil
+	}
+	r.loading[num] = true
+	defer delete(r.loading, num)
+
+	var val interface{}
+	if xref.stream != 0 {
+		if l, ok := r.objectStream(xref.stream); ok {
+			if offset, ok := r.offsets[xref.stream][num]; ok {
+				l := &pdfLexer{b: l.b, pos: l.pos + offset}
+				val, _ = l.value(0)
+			}
+		}
+	} else {
+		_, val, _ = r.parseObject(xref.offset)
+	}
+	r.objects[num] = val
+	return val
+}
+
+// objectStream decodes an object stream and indexes the offsets of its objects.
+func (r *pdfReader) objectStream(num int) (*pdfLexer, bool) {
+	if l, ok := r.streams[num]; ok {
+		return l, l != nil
+	}
+	r.streams[num] = nil
+	stream, ok := r.object(num).(pdfStream)
+	if !ok {
+		return nil, false
+	}
+	b, err := r.decode(stream)
+	if err != nil {
+		return nil, false
+	}
+	n, _ := stream.dict["N"].(int)
+	first, _ := stream.dict["First"].(int)
+	if first < 0 || len(b) < first {
+		return nil, false
+	}
+	offsets := map[int]int{}
+	l := &pdfLexer{b: b[:first]}
+	for i := 0; i < n; i++ {
+		obj, err1 := strconv.Atoi(l.token())
+		offset, err2 := strconv.Atoi(l.token())
+		if err1 != nil || err2 != nil {
+			break
+		}
+		offsets[obj] = offset
+	}
+	r.offsets[num] = offsets
+	r.streams[num] = &pdfLexer{b: b, pos: first}
+	return r.streams[num], true
+}
+
+// resolve returns the object that a value refers to, or the value itself when it is not a reference.
+func (r *pdfReader) resolve(val interface{}) interface{} {
+	for i := 0; i < pdfMaxNesting; i++ {
+		ref, ok := val.(pdfObjRef)
+		if !ok {
+			return val
+		}
+		val = r.object(int(ref))
+	}
+	return nil
+}
+
+// number returns the value of an integer or real number.
+func (r *pdfReader) number(val interface{}) (float64, bool) {
+	switch v := r.resolve(val).(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0.0, false
+}
+
+// rect returns the rectangle of an array of four numbers.
+func (r *pdfReader) rect(val interface{}) (Rect, bool) {
+	array, ok := r.resolve(val).(pdfArray)
+	if !ok || len(array) != 4 {
+		return Rect{}, false
+	}
+	v := [4]float64{}
+	for i := range v {
+		if v[i], ok = r.number(array[i]); !ok {
+			return Rect{}, false
+		}
+	}
+	x0, x1 := math.Min(v[0], v[2]), math.Max(v[0], v[2])
+	y0, y1 := math.Min(v[1], v[3]), math.Max(v[1], v[3])
+	return Rect{x0, y0, x1 - x0, y1 - y0}, true
+}
+
+// pages appends the pages of a node of the page tree, where the attributes of the page are inherited from its ancestors.
+func (r *pdfReader) pages(node, inherited pdfDict, visited map[pdfObjRef]bool, pages *[]*PDFPage) error {
+	attrs := pdfDict{}
+	for key, val := range inherited {
+		attrs[key] = val
+	}
+	for _, key := range []pdfName{"Resources", "MediaBox", "CropBox", "Rotate"} {
+		if val, ok := node[key]; ok {
+			attrs[key] = val
+		}
+	}
+
+	if node["Type"] != pdfName("Page") {
+		kids, _ := r.resolve(node["Kids"]).(pdfArray)
+		for _, kid := range kids {
+			if ref, ok := kid.(pdfObjRef); !ok || visited[ref] {
+				continue // kids must be references, which are visited once to break cycles
+			} else {
+				visited[ref] = true
+			}
+			if dict, ok := r.resolve(kid).(pdfDict); ok {
+				if err := r.pages(dict, attrs, visited, pages); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	box, ok := r.rect(attrs["MediaBox"])
+	if !ok {
+		box = Rect{0.0, 0.0, 612.0, 792.0} // US Letter
+	}
+	if crop, ok := r.rect(attrs["CropBox"]); ok {
+		x0, y0 := math.Max(box.X, crop.X), math.Max(box.Y, crop.Y)
+		x1, y1 := math.Min(box.X+box.W, crop.X+crop.W), math.Min(box.Y+box.H, crop.Y+crop.H)
+		if x0 < x1 && y0 < y1 {
+			box = Rect{x0, y0, x1 - x0, y1 - y0}
+		}
+	}
+	rotate := 0
+	if val, ok := r.number(attrs["Rotate"]); ok {
+		rotate = ((int(val)%360 + 360) % 360) / 90 * 90
+	}
+
+	content := []byte{}
+	contents := r.resolve(node["Contents"])
+	if stream, ok := contents.(pdfStream); ok {
+		contents = pdfArray{stream}
+	}
+	array, _ := contents.(pdfArray)
+	for _, val := range array {
+		if stream, ok := r.resolve(val).(pdfStream); ok {
+			b, err := r.decode(stream)
+			if err != nil {
+				return err
+			}
+			content = append(append(content, b...), '\n')
+		}
+	}
+
+	page := &PDFPage{
+		doc:       r,
+		content:   content,
+		resources: attrs["Resources"],
+		group:     node["Group"],
+		box:       box,
+	}
+	x0, y0, x1, y1 := box.X, box.Y, box.X+box.W, box.Y+box.H
+	switch rotate {
+	case 0:
+		page.W, page.H = box.W, box.H
+		page.m = Matrix{{1.0, 0.0, -x0}, {0.0, 1.0, -y0}}
+	case 90:
+		page.W, page.H = box.H, box.W
+		page.m = Matrix{{0.0, 1.0, -y0}, {-1.0, 0.0, x1}}
+	case 180:
+		page.W, page.H = box.W, box.H
+		page.m = Matrix{{-1.0, 0.0, x1}, {0.0, -1.0, y1}}
+	case 270:
+		page.W, page.H = box.H, box.W
+		page.m = Matrix{{0.0, -1.0, y1}, {1.0, 0.0, -x0}}
+	}
+	page.W *= mmPerPt
+	page.H *= mmPerPt
+	*pages = append(*pages, page)
+	return nil
+}
+
+// decode returns the data of a stream with its filters undone.
+func (r *pdfReader) decode(stream pdfStream) ([]byte, error) {
+	filters := r.resolve(stream.dict["Filter"])
+	parms := r.resolve(stream.dict["DecodeParms"])
+	if name, ok := filters.(pdfName); ok {
+		filters, parms = pdfArray{name}, pdfArray{parms}
+	}
+	filterArray, _ := filters.(pdfArray)
+	parmsArray, _ := parms.(pdfArray)
+
+	b := stream.stream
+	for i, filter := range filterArray {
+		parm := pdfDict{}
+		if i < len(parmsArray) {
+			if dict, ok := r.resolve(parmsArray[i]).(pdfDict); ok {
+				parm = dict
+			}
+		}
+
+		var err error
+		switch r.resolve(filter) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			var zr io.ReadCloser
+			if zr, err = zlib.NewReader(bytes.NewReader(b)); err != nil {
+				zr = flate.NewReader(bytes.NewReader(b)) // raw deflate without a zlib header
+			}
+			b, err = ioutil.ReadAll(zr)
+			if err == io.ErrUnexpectedEOF && 0 < len(b) {
+				err = nil // truncated streams are common, use what has been decompressed
+			}
+			if err == nil {
+				b, err = pdfPredictor(b, parm)
+			}
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			hexadecimal := []byte{}
+			for _, c := range b {
+				if c == '>' {
+					break
+				} else if '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F' {
+					hexadecimal = append(hexadecimal, c)
+				}
+			}
+			if len(hexadecimal)%2 == 1 {
+				hexadecimal = append(hexadecimal, '0')
+			}
+			b = make([]byte, len(hexadecimal)/2)
+			_, err = hex.Decode(b, hexadecimal)
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			b = bytes.TrimPrefix(bytes.TrimSpace(b), []byte("<~"))
+			if end := bytes.Index(b, []byte("~>")); end != -1 {
+				b = b[:end]
+			}
+			b, err = ioutil.ReadAll(ascii85.NewDecoder(bytes.NewReader(b)))
+		case pdfName("RunLengthDecode"), pdfName("RL"):
+			decoded := []byte{}
+			for j := 0; j < len(b) && b[j] != 128; {
+				if n := int(b[j]); n < 128 {
+					end := clampInt(j+2+n, 0, len(b))
+					decoded = append(decoded, b[j+1:end]...)
+					j = end
+				} else if j+1 < len(b) {
+					decoded = append(decoded, bytes.Repeat(b[j+1:j+2], 257-n)...)
+					j += 2
+				} else {
+					break
+				}
+			}
+			b = decoded
+		default:
+			return nil, fmt.Errorf("unsupported PDF: filter %v", r.resolve(filter))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("bad PDF: %v", err)
+		}
+	}
+	return b, nil
+}
+
+// pdfPredictor undoes the PNG predictors of the decode parameters of a Flate stream.
+func pdfPredictor(b []byte, parm pdfDict) ([]byte, error) {
+	predictor, _ := parm["Predictor"].(int)
+	if predictor < 2 {
+		return b, nil
+	} else if predictor == 2 {
+		return nil, fmt.Errorf("unsupported TIFF predictor")
+	}
+	colors, bpc, columns := 1, 8, 1
+	if val, ok := parm["Colors"].(int); ok && 0 < val {
+		colors = val
+	}
+	if val, ok := parm["BitsPerComponent"].(int); ok && 0 < val {
+		bpc = val
+	}
+	if val, ok := parm["Columns"].(int); ok && 0 < val {
+		columns = val
+	}
+	bpp := (colors*bpc + 7) / 8
+	n := (columns*colors*bpc + 7) / 8
+
+	decoded := make([]byte, 0, len(b))
+	prev := make([]byte, n)
+	for i := 0; i+1+n <= len(b); i += 1 + n {
+		row := append([]byte{}, b[i+1:i+1+n]...)
+		for j := range row {
+			left, upLeft := byte(0), byte(0)
+			if bpp <= j {
+				left, upLeft = row[j-bpp], prev[j-bpp]
+			}
+			switch b[i] {
+			case 1:
+				row[j] += left
+			case 2:
+				row[j] += prev[j]
+			case 3:
+				row[j] += byte((int(left) + int(prev[j])) / 2)
+			case 4:
+				// Paeth
+				p := int(left) + int(prev[j]) - int(upLeft)
+				pa, pb, pc := math.Abs(float64(p-int(left))), math.Abs(float64(p-int(prev[j]))), math.Abs(float64(p-int(upLeft)))
+				if pa <= pb && pa <= pc {
+					row[j] += left
+				} else if pb <= pc {
+					row[j] += prev[j]
+				} else {
+					row[j] += upLeft
+				}
+			}
+		}
+		decoded = append(decoded, row...)
+		prev = row
+	}
+	return decoded, nil
+}
+
+// pdfLexer parses the objects of PDF documents.
+type pdfLexer struct {
+	b   []byte
+	pos int
+}
+
+func isPDFWhitespace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelimiter(c byte) bool {
+	return c == '(' || c == ')' || c == '<' || c == '>' || c == '[' || c == ']' || c == '{' || c == '}' || c == '/' || c == '%'
+}
+
+// skip skips whitespace and comments. Positions outside of the buffer are moved to its end, so that nothing is read from them.
+func (l *pdfLexer) skip() {
+	if l.pos < 0 || len(l.b) < l.pos {
+		l.pos = len(l.b)
+	}
+	for l.pos < len(l.b) {
+		if c := l.b[l.pos]; isPDFWhitespace(c) {
+			l.pos++
+		} else if c == '%' {
+			for l.pos < len(l.b) && l.b[l.pos] != '\n' && l.b[l.pos] != '\r' {
+				l.pos++
+			}
+		} else {
+			break
+		}
+	}
+}
+
+// token returns the next keyword or number.
+func (l *pdfLexer) token() string {
+	l.skip()
+	start := l.pos
+	for l.pos < len(l.b) && !isPDFWhitespace(l.b[l.pos]) && !isPDFDelimiter(l.b[l.pos]) {
+		l.pos++
+	}
+	return string(l.b[start:l.pos])
+}
+
+// value parses the next object.
+func (l *pdfLexer) value(depth int) (interface{}, error) {
+	l.skip()
+	if len(l.b) <= l.pos {
+		return nil, fmt.Errorf("bad PDF: unexpected end of file")
+	} else if pdfMaxNesting < depth {
+		return nil, fmt.Errorf("bad PDF: objects nested too deeply")
+	}
+
+	switch c := l.b[l.pos]; {
+	case c == '/':
+		l.pos++
+		name := []byte{}
+		for l.pos < len(l.b) && !isPDFWhitespace(l.b[l.pos]) && !isPDFDelimiter(l.b[l.pos]) {
+			if l.b[l.pos] == '#' && l.pos+2 < len(l.b) {
+				if v, err := strconv.ParseUint(string(l.b[l.pos+1:l.pos+3]), 16, 8); err == nil {
+					name = append(name, byte(v))
+					l.pos += 3
+					continue
+				}
+			}
+			name = append(name, l.b[l.pos])
+			l.pos++
+		}
+		return pdfName(name), nil
+	case c == '(':
+		return l.literal(), nil
+	case c == '<' && l.pos+1 < len(l.b) && l.b[l.pos+1] == '<':
+		l.pos += 2
+		dict := pdfDict{}
+		for {
+			l.skip()
+			if l.pos+1 < len(l.b) && l.b[l.pos] == '>' && l.b[l.pos+1] == '>' {
+				l.pos += 2
+				return dict, nil
+			}
+			key, err := l.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(pdfName)
+			if !ok {
+				return nil, fmt.Errorf("bad PDF: invalid dictionary key at %d", l.pos)
+			}
+			val, err := l.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			dict[name] = val
+		}
+	case c == '<':
+		l.pos++
+		hexadecimal := []byte{}
+		for l.pos < len(l.b) && l.b[l.pos] != '>' {
+			if !isPDFWhitespace(l.b[l.pos]) {
+				hexadecimal = append(hexadecimal, l.b[l.pos])
+			}
+			l.pos++
+		}
+		if l.pos < len(l.b) {
+			l.pos++
+		}
+		if len(hexadecimal)%2 == 1 {
+			hexadecimal = append(hexadecimal, '0')
+		}
+		s := make([]byte, len(hexadecimal)/2)
+		if _, err := hex.Decode(s, hexadecimal); err != nil {
+			return nil, fmt.Errorf("bad PDF: %v", err)
+		}
+		return string(s), nil
+	case c == '[':
+		l.pos++
+		array := pdfArray{}
+		for {
+			l.skip()
+			if l.pos < len(l.b) && l.b[l.pos] == ']' {
+				l.pos++
+				return array, nil
+			}
+			val, err := l.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, val)
+		}
+	case c == '+' || c == '-' || c == '.' || '0' <= c && c <= '9':
+		tok := l.token()
+		num, err := strconv.Atoi(tok)
+		if err != nil {
+			f, _ := strconv.ParseFloat(tok, 64)
+			return f, nil
+		}
+		// an object reference is an object number, a generation number, and R
+		pos := l.pos
+		if _, err := strconv.Atoi(l.token()); err == nil && 0 <= num && l.token() == "R" {
+			return pdfObjRef(num), nil
+		}
+		l.pos = pos
+		return num, nil
+	default:
+		switch tok := l.token(); tok {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		case "":
+			return nil, fmt.Errorf("bad PDF: unexpected %q at %d", c, l.pos)
+		default:
+			return nil, fmt.Errorf("bad PDF: unexpected %s at %d", tok, l.pos)
+		}
+	}
+}
+
+// literal parses a literal string, which has balanced parentheses and escape sequences.
+func (l *pdfLexer) literal() string {
+	l.pos++
+	s := []byte{}
+	depth := 1
+	for l.pos < len(l.b) {
+		c := l.b[l.pos]
+		l.pos++
+		switch c {
+		case '\\':
+			if len(l.b) <= l.pos {
+				break
+			}
+			c = l.b[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				s = append(s, '\n')
+			case 'r':
+				s = append(s, '\r')
+			case 't':
+				s = append(s, '\t')
+			case 'b':
+				s = append(s, '\b')
+			case 'f':
+				s = append(s, '\f')
+			case '\r':
+				if l.pos < len(l.b) && l.b[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+				// line continuation
+			default:
+				if '0' <= c && c <= '7' {
+					v := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.b) && '0' <= l.b[l.pos] && l.b[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.b[l.pos]-'0')
+						l.pos++
+					}
+					s = append(s, byte(v))
+				} else {
+					s = append(s, c)
+				}
+			}
+		case '(':
+			depth++
+			s = append(s, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return string(s)
+			}
+			s = append(s, c)
+		case '\r':
+			// end-of-line markers are read as a line feed
+			if l.pos < len(l.b) && l.b[l.pos] == '\n' {
+				l.pos++
+			}
+			s = append(s, '\n')
+		default:
+			s = append(s, c)
+		}
+	}
+	return string(s)
+}
+
+////////////////////////////////////////////////////////////////
+
+// pdfImporter copies objects of a document that is being read into the writer, where references are replaced by references to the copied objects.
+type pdfImporter struct {
+	w     *pdfWriter
+	doc   *pdfReader
+	refs  map[int]pdfRef
+	queue []int
+}
+
+// value returns the copy of a value, where the objects that it refers to are queued to be written.
+func (imp *pdfImporter) value(val interface{}) interface{} {
+	switch v := val.(type) {
+	case pdfObjRef:
+		if ref, ok := imp.refs[int(v)]; ok {
+			return ref
+		}
+		ref := imp.w.reserveObject()
+		imp.refs[int(v)] = ref
+		imp.queue = append(imp.queue, int(v))
+		return ref
+	case pdfArray:
+		array := make(pdfArray, len(v))
+		for i := range v {
+			array[i] = imp.value(v[i])
+		}
+		return array
+	case pdfDict:
+		dict := make(pdfDict, len(v))
+		for key := range v {
+			dict[key] = imp.value(v[key])
+		}
+		return dict
+	case pdfStream:
+		dict := imp.value(v.dict).(pdfDict)
+		delete(dict, "Length") // which may be a reference, it is set when written
+		return pdfStream{dict, v.stream}
+	}
+	return val
+}
+
+// flush writes the queued objects and the objects that they refer to.
+func (imp *pdfImporter) flush() {
+	for 0 < len(imp.queue) {
+		num := imp.queue[0]
+		imp.queue = imp.queue[1:]
+		imp.w.writeObjectAt(imp.refs[num], imp.value(imp.doc.object(num)))
+	}
+}
+
+// getPDFPage writes a page of another document as a form XObject, once for each page, and returns its reference.
+func (w *pdfWriter) getPDFPage(page *PDFPage) pdfRef {
+	if ref, ok := w.forms[page]; ok {
+		return ref
+	}
+	refs, ok := w.imported[page.doc]
+	if !ok {
+		refs = map[int]pdfRef{}
+		w.imported[page.doc] = refs
+	}
+	imp := &pdfImporter{w: w, doc: page.doc, refs: refs}
+	dict := pdfDict{
+		"Type":      pdfName("XObject"),
+		"Subtype":   pdfName("Form"),
+		"BBox":      pdfArray{page.box.X, page.box.Y, page.box.X + page.box.W, page.box.Y + page.box.H},
+		"Matrix":    pdfArray{page.m[0][0], page.m[1][0], page.m[0][1], page.m[1][1], page.m[0][2], page.m[1][2]},
+		"Resources": imp.value(page.resources),
+	}
+	if page.resources == nil {
+		dict["Resources"] = pdfDict{}
+	}
+	if page.group != nil {
+		dict["Group"] = imp.value(page.group)
+	}
+	if w.compress {
+		dict["Filter"] = pdfFilterFlate
+	}
+	ref := w.writeObject(pdfStream{dict, page.content})
+	imp.flush()
+	w.forms[page] = ref
+	return ref
+}
@@ -0,0 +1,151 @@
+package canvas
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPDFLexer(t *testing.T) {
+	l := &pdfLexer{b: []byte(`<< /Name#20A (a (b) \(c\)\n\101\
+d) /Hex <48 65 6C6> /Array [1 -2.5 .5 3 0 R true null] /Dict << /A /B >> >>`)}
+	val, err := l.value(0)
+	test.Error(t, err)
+	dict := val.(pdfDict)
+	test.T(t, dict["Name A"], "a (b) (c)\nAd")
+	test.T(t, dict["Hex"], "Hel`")
+	test.T(t, dict["Array"], pdfArray{1, -2.5, 0.5, pdfObjRef(3), true, nil})
+	test.T(t, dict["Dict"], pdfDict{"A": pdfName("B")})
+
+	_, err = (&pdfLexer{b: []byte(strings.Repeat("[", 1000))}).value(0)
+	test.That(t, err != nil, "nested too deeply")
+}
+
+func TestReadPDFPages(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf := NewPDF(buf, 210.0, 297.0)
+	pdf.RenderPath(Rectangle(100.0, 10.0), DefaultStyle, Identity)
+	pdf.NewPage(100.0, 50.0)
+	pdf.RenderPath(Circle(10.0), DefaultStyle, Identity)
+	test.Error(t, pdf.Close())
+
+	pages, err := ReadPDFPages(bytes.NewReader(buf.Bytes()))
+	test.Error(t, err)
+	test.T(t, len(pages), 2)
+	test.Float(t, pages[0].W, 595.27559*mmPerPt) // the size is written with limited precision
+	test.Float(t, pages[0].H, 841.88976*mmPerPt)
+	test.Float(t, pages[1].W, 283.46457*mmPerPt)
+	test.That(t, bytes.Contains(pages[0].content, []byte("cm 0 0 m 100 0 l 100 10 l 0 10 l f")), string(pages[0].content))
+
+	// the page is embedded once and is drawn by both pages
+	buf2 := &bytes.Buffer{}
+	pdf = NewPDF(buf2, 210.0, 297.0)
+	pdf.RenderPDFPage(pages[0], Identity)
+	pdf.NewPage(210.0, 297.0)
+	pdf.RenderPDFPage(pages[0], Identity.Translate(10.0, 0.0))
+	test.Error(t, pdf.Close())
+	test.T(t, bytes.Count(buf2.Bytes(), []byte("/Subtype /Form")), 1)
+	test.T(t, bytes.Count(buf2.Bytes(), []byte("/XObject << /Fm0 1 0 R >>")), 2)
+
+	pagesWithUnderlay, err := ReadPDFPages(bytes.NewReader(buf2.Bytes()))
+	test.Error(t, err)
+	test.T(t, len(pagesWithUnderlay), 2)
+	test.That(t, bytes.Contains(pagesWithUnderlay[1].content, []byte("q .35277778 0 0 .35277778 10 0 cm /Fm0 Do Q")), string(pagesWithUnderlay[1].content))
+
+	// the underlay can be imported into a document again
+	pdf = NewPDF(&bytes.Buffer{}, 210.0, 297.0)
+	pdf.RenderPDFPage(pagesWithUnderlay[0], Identity)
+	test.Error(t, pdf.Close())
+}
+
+// testPDF15 returns a PDF document with a cross-reference stream, an object stream, and a compressed content stream, where the page inherits its media box and rotation.
+func testPDF15() []byte {
+	compress := func(b []byte) []byte {
+		buf := &bytes.Buffer{}
+		w := zlib.NewWriter(buf)
+		w.Write(b)
+		w.Close()
+		return buf.Bytes()
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("%PDF-1.5\n")
+	offsets := map[int]int{}
+	offsets[1] = buf.Len()
+	buf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 3 0 R >>\nendobj\n")
+
+	content := compress([]byte("0 0 100 50 re f"))
+	offsets[2] = buf.Len()
+	fmt.Fprintf(buf, "2 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream\nendobj\n", len(content), content)
+
+	objects := []string{"<< /Type /Pages /Kids [4 0 R] /Count 1 /MediaBox [0 0 200 100] /Rotate 90 >>", "<< /Type /Page /Parent 3 0 R /Contents 2 0 R >>"}
+	header := fmt.Sprintf("3 0 4 %d ", len(objects[0])+1)
+	objstm := compress([]byte(header + objects[0] + " " + objects[1]))
+	offsets[5] = buf.Len()
+	fmt.Fprintf(buf, "5 0 obj\n<< /Type /ObjStm /N 2 /First %d /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream\nendobj\n", len(header), len(objstm), objstm)
+
+	// cross-reference entries with the PNG Up predictor
+	entries := [][4]byte{{0, 0, 0, 0}, {1, 0, byte(offsets[1]), 0}, {1, byte(offsets[2] >> 8), byte(offsets[2]), 0}, {2, 0, 5, 0}, {2, 0, 5, 1}, {1, byte(offsets[5] >> 8), byte(offsets[5]), 0}, {1, byte(buf.Len() >> 8), byte(buf.Len()), 0}}
+	rows := []byte{}
+	prev := [4]byte{}
+	for _, entry := range entries {
+		rows = append(rows, 2)
+		for i := range entry {
+			rows = append(rows, entry[i]-prev[i])
+		}
+		prev = entry
+	}
+	xref := compress(rows)
+	offset := buf.Len()
+	fmt.Fprintf(buf, "6 0 obj\n<< /Type /XRef /Size 7 /W [1 2 1] /Root 1 0 R /Filter /FlateDecode /DecodeParms << /Predictor 12 /Columns 4 >> /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(xref), xref)
+	fmt.Fprintf(buf, "startxref\n%d\n%%%%EOF\n", offset)
+	return buf.Bytes()
+}
+
+func TestReadPDFXrefStream(t *testing.T) {
+	pages, err := ReadPDFPages(bytes.NewReader(testPDF15()))
+	test.Error(t, err)
+	test.T(t, len(pages), 1)
+	test.Float(t, pages[0].W, 100.0*mmPerPt)
+	test.Float(t, pages[0].H, 200.0*mmPerPt)
+	test.T(t, string(pages[0].content), "0 0 100 50 re f\n")
+	test.T(t, Rect{0.0, 0.0, 200.0, 100.0}.Transform(pages[0].m), Rect{0.0, 0.0, 100.0, 200.0})
+}
+
+func TestReadPDFErrors(t *testing.T) {
+	// the cross-reference table is rebuilt when it is damaged
+	b := testPDF15()
+	i := bytes.LastIndex(b, []byte("startxref"))
+	b = append(b[:i:i], []byte("startxref\n9\n%%EOF\n")...)
+	pages, err := ReadPDFPages(bytes.NewReader(b))
+	test.Error(t, err)
+	test.T(t, len(pages), 1)
+	test.T(t, string(pages[0].content), "0 0 100 50 re f\n")
+
+	// offsets past the end of the file are rejected
+	_, err = ReadPDFPages(strings.NewReader("%PDF-1.4\nxref\n0 2\n0000000000 65535 f \n0000009749 00000 n \ntrailer\n<< /Root 1 0 R >>\nstartxref\n9\n%%EOF\n"))
+	test.That(t, err != nil)
+	_, err = ReadPDFPages(strings.NewReader("%PDF-1.4\n1 0 obj\n<48"))
+	test.That(t, err != nil)
+
+	_, err = ReadPDFPages(strings.NewReader("<svg/>"))
+	test.That(t, err != nil, "not a PDF")
+	_, err = ReadPDFPages(strings.NewReader("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\ntrailer\n<< /Root 1 0 R /Encrypt << >> >>\n"))
+	test.That(t, err != nil && strings.Contains(err.Error(), "encrypted"), err)
+}
+
+func FuzzReadPDFPages(f *testing.F) {
+	buf := &bytes.Buffer{}
+	pdf := NewPDF(buf, 210.0, 297.0)
+	pdf.RenderPath(Rectangle(100.0, 10.0), DefaultStyle, Identity)
+	pdf.Close()
+	f.Add(buf.Bytes())
+	f.Add(testPDF15())
+	f.Fuzz(func(t *testing.T, b []byte) {
+		ReadPDFPages(bytes.NewReader(b))
+	})
+}
@@ -0,0 +1,437 @@
+package canvas
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// SetLinearization writes a linearized PDF (fast web view) when enabled, so that readers display the first page before the rest of the document is downloaded and can fetch other pages by HTTP range requests. The document is kept in memory until it is closed. It must be set before drawing and cannot be combined with signatures.
+func (r *PDF) SetLinearization(linearize bool) {
+	r.w.pdf.SetLinearization(linearize)
+}
+
+func (w *pdfWriter) SetLinearization(linearize bool) {
+	if len(w.objOffsets) != 0 {
+		w.err = fmt.Errorf("linearization must be set before drawing")
+		return
+	}
+	w.linearize = linearize
+}
+
+// pdfRefs appends the references of the value that are not yet seen, in order of the sorted keys of dictionaries and without following links to parents.
+func pdfRefs(refs []pdfRef, seen map[pdfRef]bool, val interface{}) []pdfRef {
+	switch v := val.(type) {
+	case pdfRef:
+		if !seen[v] {
+			seen[v] = true
+			refs = append(refs, v)
+		}
+	case pdfArray:
+		for _, item := range v {
+			refs = pdfRefs(refs, seen, item)
+		}
+	case pdfDict:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			if key != "Parent" && key != "P" {
+				keys = append(keys, string(key))
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			refs = pdfRefs(refs, seen, v[pdfName(key)])
+		}
+	case pdfStream:
+		refs = pdfRefs(refs, seen, v.dict)
+	}
+	return refs
+}
+
+// closure returns the object and all objects that it refers to, without following links to parents.
+func (w *pdfWriter) closure(ref pdfRef, seen map[pdfRef]bool) []pdfRef {
+	if seen[ref] {
+		return nil
+	}
+	seen[ref] = true
+	refs := []pdfRef{ref}
+	for i := 0; i < len(refs); i++ {
+		refs = pdfRefs(refs, seen, w.objects[refs[i]-1])
+	}
+	return refs
+}
+
+// pdfBits writes values of a number of bits with the most significant bit first, as used by hint tables.
+type pdfBits struct {
+	bytes.Buffer
+	cur, n uint
+}
+
+func (b *pdfBits) write(v, bits int) {
+	for i := bits - 1; 0 <= i; i-- {
+		b.cur = b.cur<<1 | uint(v>>uint(i))&1
+		b.n++
+		if b.n == 8 {
+			b.WriteByte(byte(b.cur))
+			b.cur, b.n = 0, 0
+		}
+	}
+}
+
+// flush pads the last byte with zeros.
+func (b *pdfBits) flush() {
+	if 0 < b.n {
+		b.write(0, int(8-b.n))
+	}
+}
+
+// bitLength returns the number of bits needed to represent v.
+func bitLength(v int) int {
+	n := 0
+	for ; 0 < v; v >>= 1 {
+		n++
+	}
+	return n
+}
+
+// pdfHintTable holds the least value of an item of the hint tables and the number of bits to represent the differences with the least value.
+type pdfHintTable struct {
+	least, bits int
+}
+
+func newPDFHintTable(values []int) pdfHintTable {
+	if len(values) == 0 {
+		return pdfHintTable{}
+	}
+	least, greatest := values[0], values[0]
+	for _, v := range values[1:] {
+		if v < least {
+			least = v
+		} else if greatest < v {
+			greatest = v
+		}
+	}
+	return pdfHintTable{least, bitLength(greatest - least)}
+}
+
+// writeLinearized writes the buffered objects as a linearized document, see ISO 32000-1, Annex F. The objects of the first page are written first with their own cross-reference section, after the catalog and the hint stream that gives the location of the objects of the other pages. Objects that are used by more than one of the other pages are written after those pages as shared objects.
+func (w *pdfWriter) writeLinearized(trailer pdfDict, pages []pdfRef) error {
+	if w.err != nil {
+		return w.err
+	} else if w.signature != nil {
+		return fmt.Errorf("linearized documents cannot be signed")
+	}
+
+	// the document level objects, and the objects of each page in the order in which they are written
+	seen := map[pdfRef]bool{}
+	document := []pdfRef{trailer["Root"].(pdfRef)}
+	seen[document[0]] = true
+	if encrypt, ok := trailer["Encrypt"].(pdfRef); ok {
+		document = append(document, encrypt)
+		seen[encrypt] = true
+	}
+	pageObjects := make([][]pdfRef, len(pages))
+	pageObjects[0] = w.closure(pages[0], seen)
+	users := map[pdfRef]int{} // number of other pages that use an object
+	for i := 1; i < len(pages); i++ {
+		pageSeen := map[pdfRef]bool{}
+		for ref := range seen {
+			pageSeen[ref] = true
+		}
+		pageObjects[i] = w.closure(pages[i], pageSeen)
+		for _, ref := range pageObjects[i] {
+			users[ref]++
+		}
+	}
+	shared := []pdfRef{}
+	for i := 1; i < len(pages); i++ {
+		own := pageObjects[i][:0:0]
+		for _, ref := range pageObjects[i] {
+			if users[ref] == 1 {
+				own = append(own, ref)
+			} else if !seen[ref] {
+				shared = append(shared, ref)
+			}
+			seen[ref] = true
+		}
+		pageObjects[i] = own
+	}
+	rest := []pdfRef{}
+	for i := range w.objects {
+		if ref := pdfRef(i + 1); !seen[ref] {
+			rest = append(rest, ref)
+		}
+	}
+
+	// number the objects after the first page from one, followed by the linearization dictionary, the document level objects, the hint stream, and the objects of the first page
+	order := []pdfRef{}
+	for _, objects := range pageObjects[1:] {
+		order = append(order, objects...)
+	}
+	order = append(order, shared...)
+	order = append(order, rest...)
+	first := len(order) + 1 // number of the linearization dictionary
+	order = append(order, document...)
+	order = append(order, pageObjects[0]...)
+	w.renumber = map[pdfRef]pdfRef{}
+	for i, ref := range order {
+		w.renumber[ref] = pdfRef(i + 1)
+		if first+len(document) <= i+1 {
+			w.renumber[ref] += 2 // after the linearization dictionary and the hint stream
+		} else if first <= i+1 {
+			w.renumber[ref]++ // after the linearization dictionary
+		}
+	}
+	size := len(order) + 3
+	hintRef := pdfRef(first + 1 + len(document))
+
+	// write the objects to find their lengths, the offsets in the hint tables are as if the hint stream is absent
+	writer, pos := w.w, w.pos
+	serialize := func(ref pdfRef, val interface{}) []byte {
+		b := &bytes.Buffer{}
+		w.w = b
+		w.write("%v 0 obj\n", ref)
+		w.writeVal(val)
+		w.write("\nendobj\n")
+		return b.Bytes()
+	}
+	objects := map[pdfRef][]byte{}
+	for _, ref := range order {
+		objects[ref] = serialize(w.renumber[ref], w.objects[ref-1])
+	}
+	length := func(refs []pdfRef) int {
+		n := 0
+		for _, ref := range refs {
+			n += len(objects[ref])
+		}
+		return n
+	}
+
+	// the linearization dictionary and first-page trailer have fixed widths so that their length is known
+	linearization := func(l, hintOffset, hintLength, e, t int) []byte {
+		return serialize(pdfRef(first), pdfDict{
+			"Linearized": 1,
+			"L":          pdfLinearized(l),
+			"H":          pdfArray{pdfLinearized(hintOffset), pdfLinearized(hintLength)},
+			"O":          int(w.renumber[pages[0]]),
+			"E":          pdfLinearized(e),
+			"N":          len(pages),
+			"T":          pdfLinearized(t),
+		})
+	}
+	firstXref := func(offsets []int, prev int) []byte {
+		b := &bytes.Buffer{}
+		w.w = b
+		w.write("xref\n%d %d\n", first, len(offsets))
+		for _, offset := range offsets {
+			w.write("%010d 00000 n \n", offset)
+		}
+		w.write("trailer\n")
+		firstTrailer := pdfDict{"Prev": pdfLinearized(prev)}
+		for key, val := range trailer {
+			firstTrailer[key] = val
+		}
+		firstTrailer["Size"] = size
+		enc := w.encryption
+		w.encryption = nil // the trailer is not encrypted
+		w.writeVal(firstTrailer)
+		w.encryption = enc
+		w.write("\nstartxref\n0\n%%%%EOF\n")
+		return b.Bytes()
+	}
+	firstXrefOffset := pos + len(linearization(0, 0, 0, 0, 0))
+	start := firstXrefOffset + len(firstXref(make([]int, size-first), 0))
+	firstPage := start + length(document) // offset of the first page without hint stream
+	otherPages := firstPage + length(pageObjects[0])
+	sharedOffset := otherPages
+	for _, objects := range pageObjects[1:] {
+		sharedOffset += length(objects)
+	}
+
+	// page offset hint table
+	nObjects, pageLengths, contentOffsets, contentLengths, nShared := []int{}, []int{}, []int{}, []int{}, []int{}
+	sharedIDs := map[pdfRef]int{} // index in the shared object hint table
+	for i, ref := range pageObjects[0] {
+		sharedIDs[ref] = i
+	}
+	for i, ref := range shared {
+		sharedIDs[ref] = len(pageObjects[0]) + i
+	}
+	pageShared := make([][]int, len(pages))
+	for i, ref := range pages {
+		objects := pageObjects[i]
+		nObjects = append(nObjects, len(objects))
+		pageLengths = append(pageLengths, length(objects))
+		contentOffset, contentLength := 0, 0
+		if page, ok := w.objects[ref-1].(pdfDict); ok {
+			if contents, ok := page["Contents"].(pdfRef); ok {
+				for j, obj := range objects {
+					if obj == contents {
+						contentOffset, contentLength = length(objects[:j]), length(objects[j:j+1])
+					}
+				}
+			}
+		}
+		contentOffsets = append(contentOffsets, contentOffset)
+		contentLengths = append(contentLengths, contentLength)
+		if 0 < i {
+			for _, obj := range w.closure(ref, map[pdfRef]bool{}) {
+				if id, ok := sharedIDs[obj]; ok {
+					pageShared[i] = append(pageShared[i], id)
+				}
+			}
+		}
+		nShared = append(nShared, len(pageShared[i]))
+	}
+	objectsHint := newPDFHintTable(nObjects)
+	lengthsHint := newPDFHintTable(pageLengths)
+	contentOffsetsHint := newPDFHintTable(contentOffsets)
+	contentLengthsHint := newPDFHintTable(contentLengths)
+	maxShared := 0
+	for _, n := range nShared {
+		if maxShared < n {
+			maxShared = n
+		}
+	}
+	sharedBits := bitLength(maxShared)
+	sharedIDBits := bitLength(len(pageObjects[0]) + len(shared) - 1)
+
+	hints := &pdfBits{}
+	hints.write(objectsHint.least, 32)
+	hints.write(firstPage, 32)
+	hints.write(objectsHint.bits, 16)
+	hints.write(lengthsHint.least, 32)
+	hints.write(lengthsHint.bits, 16)
+	hints.write(contentOffsetsHint.least, 32)
+	hints.write(contentOffsetsHint.bits, 16)
+	hints.write(contentLengthsHint.least, 32)
+	hints.write(contentLengthsHint.bits, 16)
+	hints.write(sharedBits, 16)
+	hints.write(sharedIDBits, 16)
+	hints.write(0, 16) // no fractional positions of shared objects
+	hints.write(1, 16)
+	for _, items := range []struct {
+		values []int
+		table  pdfHintTable
+	}{{nObjects, objectsHint}, {pageLengths, lengthsHint}} {
+		for _, v := range items.values {
+			hints.write(v-items.table.least, items.table.bits)
+		}
+		hints.flush()
+	}
+	for _, n := range nShared {
+		hints.write(n, sharedBits)
+	}
+	hints.flush()
+	for _, ids := range pageShared {
+		for _, id := range ids {
+			hints.write(id, sharedIDBits)
+		}
+	}
+	hints.flush()
+	for _, items := range []struct {
+		values []int
+		table  pdfHintTable
+	}{{contentOffsets, contentOffsetsHint}, {contentLengths, contentLengthsHint}} {
+		for _, v := range items.values {
+			hints.write(v-items.table.least, items.table.bits)
+		}
+		hints.flush()
+	}
+
+	// shared object hint table, with a group for each object of the first page and each shared object
+	sharedTable := hints.Len()
+	groups := append(append([]pdfRef{}, pageObjects[0]...), shared...)
+	groupLengths := []int{}
+	for _, ref := range groups {
+		groupLengths = append(groupLengths, len(objects[ref]))
+	}
+	groupsHint := newPDFHintTable(groupLengths)
+	if 0 < len(shared) {
+		hints.write(int(w.renumber[shared[0]]), 32)
+		hints.write(sharedOffset, 32)
+	} else {
+		hints.write(0, 32)
+		hints.write(0, 32)
+	}
+	hints.write(len(pageObjects[0]), 32)
+	hints.write(len(groups), 32)
+	hints.write(0, 16) // one object per group
+	hints.write(groupsHint.least, 32)
+	hints.write(groupsHint.bits, 16)
+	for _, v := range groupLengths {
+		hints.write(v-groupsHint.least, groupsHint.bits)
+	}
+	hints.flush()
+	for range groups {
+		hints.write(0, 1) // no MD5 signature
+	}
+	hints.flush()
+	hint := serialize(hintRef, pdfStream{
+		dict:   pdfDict{"S": sharedTable},
+		stream: hints.Bytes(),
+	})
+
+	// the actual offsets, where the hint stream precedes the first page
+	offsets := map[pdfRef]int{}
+	offset := start
+	for _, ref := range document {
+		offsets[ref] = offset
+		offset += len(objects[ref])
+	}
+	hintOffset := offset
+	offset += len(hint)
+	for _, ref := range pageObjects[0] {
+		offsets[ref] = offset
+		offset += len(objects[ref])
+	}
+	endFirstPage := offset
+	for _, ref := range order[:first-1] {
+		offsets[ref] = offset
+		offset += len(objects[ref])
+	}
+	mainXref := offset
+
+	// main cross-reference section of the objects after the first page
+	main := &bytes.Buffer{}
+	w.w = main
+	w.write("xref\n0 %d\n0000000000 65535 f \n", first)
+	for _, ref := range order[:first-1] {
+		w.write("%010d 00000 n \n", offsets[ref])
+	}
+	w.write("trailer\n")
+	w.writeVal(pdfDict{"Size": size})
+	w.write("\nstartxref\n%v\n%%%%EOF", firstXrefOffset)
+	fileLength := mainXref + main.Len()
+
+	firstOffsets := []int{pos}
+	for _, ref := range order[first-1 : first-1+len(document)] {
+		firstOffsets = append(firstOffsets, offsets[ref])
+	}
+	firstOffsets = append(firstOffsets, hintOffset)
+	for _, ref := range order[first-1+len(document):] {
+		firstOffsets = append(firstOffsets, offsets[ref])
+	}
+
+	b := &bytes.Buffer{}
+	b.Write(linearization(fileLength, hintOffset, len(hint), endFirstPage, mainXref+len("xref\n0 ")+len(fmt.Sprint(first))))
+	b.Write(firstXref(firstOffsets, mainXref))
+	for _, ref := range document {
+		b.Write(objects[ref])
+	}
+	b.Write(hint)
+	for _, ref := range pageObjects[0] {
+		b.Write(objects[ref])
+	}
+	for _, ref := range order[:first-1] {
+		b.Write(objects[ref])
+	}
+	b.Write(main.Bytes())
+	w.w, w.pos = writer, pos
+	w.renumber = nil
+	w.writeBytes(b.Bytes())
+	return w.err
+}
+
+// pdfLinearized is an integer written with a fixed width, so that values of the linearization dictionary can be filled in afterwards.
+type pdfLinearized int
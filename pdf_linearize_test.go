@@ -0,0 +1,73 @@
+package canvas
+
+import (
+	"bytes"
+	"image"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPDFLinearization(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img2 := image.NewRGBA(image.Rect(0, 0, 3, 3))
+
+	// the first image is shared with the first page, the font and the second image are shared by the other pages
+	buf := &bytes.Buffer{}
+	pdf := NewPDF(buf, 210.0, 297.0)
+	pdf.SetLinearization(true)
+	pdf.RenderPath(Rectangle(10.0, 10.0), DefaultStyle, Identity)
+	pdf.RenderImage(img, Identity)
+	pdf.NewPage(210.0, 297.0)
+	pdf.RenderText(NewTextLine(face, "second", Left), Identity.Translate(10.0, 10.0))
+	pdf.RenderImage(img2, Identity)
+	pdf.NewPage(148.0, 210.0)
+	pdf.RenderText(NewTextLine(face, "third", Left), Identity.Translate(10.0, 10.0))
+	pdf.RenderImage(img, Identity)
+	pdf.RenderImage(img2, Identity)
+	test.Error(t, pdf.Close())
+
+	// the linearization dictionary is the first object
+	b := buf.Bytes()
+	match := regexp.MustCompile(`^%PDF-1.7\n(\d+) 0 obj\n<< /E (\d+) /H \[(\d+) (\d+)\] /L (\d+) /Linearized 1 /N 3 /O (\d+) /T (\d+) >>\nendobj\nxref\n`).FindSubmatch(b)
+	test.That(t, match != nil, "linearization dictionary")
+	vals := [7]int{}
+	for i := range vals {
+		vals[i], _ = strconv.Atoi(string(match[i+1]))
+	}
+	first, e, hintOffset, hintLength, l, o, xref := vals[0], vals[1], vals[2], vals[3], vals[4], vals[5], vals[6]
+	test.T(t, l, len(b))
+	test.That(t, bytes.HasPrefix(b[hintOffset:], []byte(strconv.Itoa(first+2)+" 0 obj\n<< /Length ")), "hint stream")
+	test.That(t, bytes.HasSuffix(b[:hintOffset+hintLength], []byte("endstream\nendobj\n")), "hint stream length")
+	test.That(t, bytes.Contains(b[hintOffset:e], []byte(strconv.Itoa(o)+" 0 obj\n<< /Type /Page ")), "first page")
+	test.That(t, bytes.HasPrefix(b[xref:], []byte("\n0000000000 65535 f \n")), "main cross-reference table")
+	test.That(t, bytes.HasPrefix(b[e:], []byte("1 0 obj\n<< /Type /Page ")), "second page")
+
+	// readers follow the first-page cross-reference section to the main section
+	pages, err := ReadPDFPages(bytes.NewReader(b))
+	test.Error(t, err)
+	test.T(t, len(pages), 3)
+	test.Float(t, pages[2].W, 148.0)
+
+	// the first-page trailer refers to the encryption dictionary
+	buf.Reset()
+	pdf = NewPDF(buf, 210.0, 297.0)
+	pdf.SetLinearization(true)
+	pdf.SetEncryption("", "owner", PDFPrint)
+	pdf.NewPage(210.0, 297.0)
+	test.Error(t, pdf.Close())
+	match = regexp.MustCompile(`trailer\n<< /Encrypt (\d+) 0 R /ID \[<[0-9A-F]{32}> <[0-9A-F]{32}>\] /Info \d+ 0 R /Prev \d+ /Root (\d+) 0 R /Size \d+ >>\nstartxref\n0\n`).FindSubmatch(buf.Bytes())
+	test.That(t, match != nil, "first-page trailer")
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("\n"+string(match[1])+" 0 obj\n<< /CF ")), "encryption dictionary")
+
+	pdf = NewPDF(&bytes.Buffer{}, 210.0, 297.0)
+	pdf.RenderImage(img, Identity)
+	pdf.SetLinearization(true)
+	test.That(t, pdf.Close() != nil, "linearization after drawing")
+}
@@ -0,0 +1,131 @@
+package canvas
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// SetObjectStreams compresses the objects of the document other than streams into object streams and writes a cross-reference stream instead of a cross-reference table (PDF 1.5), which makes documents with many pages, fonts, or annotations considerably smaller. The document is kept in memory until it is closed. It must be set before drawing and cannot be combined with linearization.
+func (r *PDF) SetObjectStreams(objectStreams bool) {
+	r.w.pdf.SetObjectStreams(objectStreams)
+}
+
+func (w *pdfWriter) SetObjectStreams(objectStreams bool) {
+	if len(w.objOffsets) != 0 {
+		w.err = fmt.Errorf("object streams must be set before drawing")
+		return
+	}
+	w.objStreams = objectStreams
+	w.objStreamIndex = map[pdfRef][2]int{}
+}
+
+// pdfObjStreamSize is the maximum number of objects in an object stream, so that readers need not decompress large streams to read a single object.
+const pdfObjStreamSize = 100
+
+// marshal returns the value as it would be written.
+func (w *pdfWriter) marshal(val interface{}) []byte {
+	writer, pos, h := w.w, w.pos, w.hash
+	b := &bytes.Buffer{}
+	w.w, w.hash = b, nil
+	w.writeVal(val)
+	w.w, w.pos, w.hash = writer, pos, h
+	return b.Bytes()
+}
+
+// writeObjectStreams writes the buffered objects, where streams and the encryption dictionary are written as usual and all other objects are compressed in object streams, see ISO 32000-1, section 7.5.7. Reserved objects that have not been written yet, such as the signature, are written afterwards.
+func (w *pdfWriter) writeObjectStreams(encrypt interface{}) {
+	refs := []pdfRef{}
+	for i, val := range w.objects {
+		ref := pdfRef(i + 1)
+		if val == nil {
+			continue
+		} else if _, ok := val.(pdfStream); ok || ref == encrypt {
+			w.objOffsets[i] = w.pos
+			w.write("%v 0 obj\n", ref)
+			w.writeVal(val)
+			w.write("\nendobj\n")
+		} else {
+			refs = append(refs, ref)
+		}
+	}
+
+	for start := 0; start < len(refs); start += pdfObjStreamSize {
+		end := start + pdfObjStreamSize
+		if len(refs) < end {
+			end = len(refs)
+		}
+
+		// strings of compressed objects are encrypted as part of the object stream
+		enc := w.encryption
+		w.encryption = nil
+		stream := pdfRef(len(w.objOffsets) + 1)
+		header, body := &bytes.Buffer{}, &bytes.Buffer{}
+		for i, ref := range refs[start:end] {
+			fmt.Fprintf(header, "%v %v ", ref, body.Len())
+			body.Write(w.marshal(w.objects[ref-1]))
+			body.WriteByte('\n')
+			w.objStreamIndex[ref] = [2]int{int(stream), i}
+		}
+		w.encryption = enc
+
+		w.objOffsets = append(w.objOffsets, w.pos)
+		w.write("%v 0 obj\n", stream)
+		w.writeVal(pdfStream{
+			dict: pdfDict{
+				"Type":   pdfName("ObjStm"),
+				"N":      end - start,
+				"First":  header.Len(),
+				"Filter": pdfFilterFlate,
+			},
+			stream: append(header.Bytes(), body.Bytes()...),
+		})
+		w.write("\nendobj\n")
+	}
+}
+
+// writeXrefStream writes the cross-reference stream, which includes the entries of the trailer and is the end of the document, see ISO 32000-1, section 7.5.8.
+func (w *pdfWriter) writeXrefStream(trailer pdfDict) {
+	xrefOffset := w.pos
+	w.objOffsets = append(w.objOffsets, xrefOffset)
+	ref := len(w.objOffsets)
+
+	width := 1 // of the offsets
+	for 1<<uint(8*width) <= xrefOffset {
+		width++
+	}
+	b := &bytes.Buffer{}
+	entry := func(kind, field2, field3 int) {
+		b.WriteByte(byte(kind))
+		for i := width - 1; 0 <= i; i-- {
+			b.WriteByte(byte(field2 >> uint(8*i)))
+		}
+		b.Write([]byte{byte(field3 >> 8), byte(field3)})
+	}
+	entry(0, 0, 65535)
+	for i, objOffset := range w.objOffsets {
+		if index, ok := w.objStreamIndex[pdfRef(i+1)]; ok {
+			entry(2, index[0], index[1])
+		} else {
+			entry(1, objOffset, 0)
+		}
+	}
+
+	dict := pdfDict{}
+	for key, val := range trailer {
+		dict[key] = val
+	}
+	dict["Type"] = pdfName("XRef")
+	dict["Size"] = ref + 1
+	dict["W"] = pdfArray{1, width, 2}
+	dict["Filter"] = pdfFilterFlate
+
+	w.write("%v 0 obj\n", ref)
+	enc := w.encryption
+	w.encryption = nil // the cross-reference stream is not encrypted
+	w.writeVal(pdfStream{
+		dict:   dict,
+		stream: b.Bytes(),
+	})
+	w.encryption = enc
+	w.write("\nendobj\nstartxref\n%v\n%%%%EOF", xrefOffset)
+}
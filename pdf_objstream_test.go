@@ -0,0 +1,59 @@
+package canvas
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPDFObjectStreams(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	write := func(objectStreams bool) []byte {
+		buf := &bytes.Buffer{}
+		pdf := NewPDF(buf, 210.0, 297.0)
+		pdf.SetObjectStreams(objectStreams)
+		for i := 0; i < 150; i++ {
+			if 0 < i {
+				pdf.NewPage(210.0, 297.0)
+			}
+			pdf.RenderText(NewTextLine(face, "page", Left), Identity.Translate(10.0, 10.0))
+		}
+		test.Error(t, pdf.Close())
+		return buf.Bytes()
+	}
+	b := write(true)
+	test.That(t, bytes.Contains(b, []byte("<< /Type /ObjStm /Filter /FlateDecode /First ")), "object stream")
+	test.That(t, bytes.Contains(b, []byte("<< /Type /XRef /Filter /FlateDecode ")), "cross-reference stream")
+	test.That(t, !bytes.Contains(b, []byte("\nxref\n")), "no cross-reference table")
+	test.That(t, !bytes.Contains(b, []byte("<< /Type /Page ")), "pages are compressed")
+	test.That(t, len(b) < len(write(false))-len(b)/10, "smaller documents")
+
+	pages, err := ReadPDFPages(bytes.NewReader(b))
+	test.Error(t, err)
+	test.T(t, len(pages), 150)
+
+	// signed documents end with a cross-reference stream
+	buf := &bytes.Buffer{}
+	pdf := NewPDF(buf, 210.0, 297.0)
+	pdf.SetObjectStreams(true)
+	pdf.SetSignature(PDFSignature{
+		Size: 2,
+		Sign: func(d []byte) ([]byte, error) {
+			return []byte{0xCA, 0xFE}, nil
+		},
+	})
+	test.Error(t, pdf.Close())
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("/Contents <CAFE>")), "signature")
+	pages, err = ReadPDFPages(bytes.NewReader(buf.Bytes()))
+	test.Error(t, err)
+	test.T(t, len(pages), 1)
+
+	pdf = NewPDF(&bytes.Buffer{}, 210.0, 297.0)
+	pdf.SetObjectStreams(true)
+	pdf.SetLinearization(true)
+	test.That(t, pdf.Close() != nil, "linearized object streams")
+}
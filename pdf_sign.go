@@ -0,0 +1,94 @@
+package canvas
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"time"
+)
+
+// PDFSignature is the digital signature of a PDF document, which is signed by Sign with a detached PKCS#7 (CMS) signature. The signature covers the whole document except for the signature itself, and is shown by readers in an invisible signature field on the first page.
+type PDFSignature struct {
+	Name, Reason, Location, ContactInfo string
+	Time                                time.Time // signing time, zero is the time of writing
+
+	// Size is the maximum size in bytes of the signature, which is reserved in the document, zero is 8192.
+	Size int
+
+	// Sign returns the DER-encoded PKCS#7 (CMS) SignedData of the SHA-256 digest of the signed bytes of the document, such as created by a signing library or a hardware security module, with the digest as its message digest attribute and without encapsulated content.
+	Sign func(digest []byte) ([]byte, error)
+}
+
+// SetSignature signs the document with a digital signature when it is closed. It must be called before drawing.
+func (r *PDF) SetSignature(signature PDFSignature) {
+	r.w.pdf.SetSignature(signature)
+}
+
+func (w *pdfWriter) SetSignature(signature PDFSignature) {
+	if len(w.objOffsets) != 0 {
+		w.err = fmt.Errorf("signature must be set before drawing")
+		return
+	} else if signature.Sign == nil {
+		w.err = fmt.Errorf("signature must have a signing function")
+		return
+	}
+	if signature.Size == 0 {
+		signature.Size = 8192
+	}
+	if signature.Time.IsZero() {
+		signature.Time = time.Now()
+	}
+	w.signature = &signature
+	w.hash = sha256.New()
+	w.hash.Write([]byte(pdfHeader))
+}
+
+// writeSigned writes the signature object, the cross-reference table, and the trailer. The bytes of the document are signed except for the contents of the signature, which are written afterwards, as is given by the byte range of the signature.
+func (w *pdfWriter) writeSigned(ref pdfRef, trailer pdfDict) error {
+	if w.err != nil {
+		return w.err
+	}
+	writer, h, start := w.w, w.hash, w.pos
+	tail := &bytes.Buffer{}
+	w.w, w.hash = tail, nil
+
+	sig := w.signature
+	w.objOffsets[ref-1] = w.pos
+	w.write("%v 0 obj\n<< /Type /Sig /Filter /Adobe.PPKLite /SubFilter /adbe.pkcs7.detached /ByteRange ", ref)
+	byteRange := w.pos - start
+	w.write("[0 0000000000 0000000000 0000000000] /Contents <")
+	contents := w.pos - start
+	w.writeBytes(bytes.Repeat([]byte("0"), 2*sig.Size))
+	w.write("> /M ")
+	w.writeVal(sig.Time.Format("D:20060102150405Z0700"))
+	for _, field := range []struct {
+		key, val string
+	}{{"Name", sig.Name}, {"Reason", sig.Reason}, {"Location", sig.Location}, {"ContactInfo", sig.ContactInfo}} {
+		if field.val != "" {
+			w.write(" /%v ", field.key)
+			w.writeVal(field.val)
+		}
+	}
+	w.write(" >>\nendobj\n")
+	w.writeXref(trailer)
+	w.w = writer
+	if w.err != nil {
+		return w.err
+	}
+
+	// the signed ranges exclude the contents between the angle brackets
+	b := tail.Bytes()
+	a, c := start+contents-1, start+contents+2*sig.Size+1
+	copy(b[byteRange:], fmt.Sprintf("[0 %010d %010d %010d]", a, c, w.pos-c))
+	h.Write(b[:contents-1])
+	h.Write(b[contents+2*sig.Size+1:])
+	signature, err := sig.Sign(h.Sum(nil))
+	if err != nil {
+		return err
+	} else if sig.Size < len(signature) {
+		return fmt.Errorf("signature of %d bytes exceeds the reserved size of %d bytes", len(signature), sig.Size)
+	}
+	copy(b[contents:], fmt.Sprintf("%X", signature))
+	_, err = w.w.Write(b)
+	return err
+}
@@ -0,0 +1,88 @@
+package canvas
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPDFSignature(t *testing.T) {
+	var digest []byte
+	buf := &bytes.Buffer{}
+	pdf := NewPDF(buf, 210.0, 297.0)
+	pdf.SetSignature(PDFSignature{
+		Name:   "Jane Doe",
+		Reason: "Approved",
+		Time:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Size:   16,
+		Sign: func(d []byte) ([]byte, error) {
+			digest = d
+			return []byte{0xCA, 0xFE}, nil
+		},
+	})
+	pdf.RenderPath(Rectangle(10.0, 10.0), DefaultStyle, Identity)
+	test.Error(t, pdf.Close())
+
+	b := buf.Bytes()
+	test.That(t, bytes.Contains(b, []byte("/Contents <CAFE0000000000000000000000000000> /M (D:20200102030405Z) /Name (Jane Doe) /Reason (Approved)")), "signature")
+	test.That(t, bytes.Contains(b, []byte("/AcroForm << /Fields [1 0 R] /SigFlags 3 >>")), "signature field")
+	test.That(t, bytes.Contains(b, []byte("/Annots [1 0 R]")), "signature widget on the first page")
+
+	// the byte range covers everything but the contents of the signature
+	match := regexp.MustCompile(`/ByteRange \[0 (\d+) (\d+) (\d+)\]`).FindSubmatch(b)
+	test.That(t, match != nil, "byte range")
+	ranges := [3]int{}
+	for i := range ranges {
+		ranges[i], _ = strconv.Atoi(string(match[i+1]))
+	}
+	test.T(t, string(b[ranges[0]:ranges[1]]), "<CAFE0000000000000000000000000000>")
+	test.T(t, ranges[1]+ranges[2], len(b))
+	h := sha256.New()
+	h.Write(b[:ranges[0]])
+	h.Write(b[ranges[1]:])
+	test.Bytes(t, digest, h.Sum(nil))
+
+	// the cross-reference table is valid
+	pages, err := ReadPDFPages(bytes.NewReader(b))
+	test.Error(t, err)
+	test.T(t, len(pages), 1)
+
+	// the contents of the signature are not encrypted
+	buf.Reset()
+	pdf = NewPDF(buf, 210.0, 297.0)
+	pdf.SetEncryption("", "owner", PDFPrint)
+	pdf.SetSignature(PDFSignature{
+		Name: "Jane Doe",
+		Size: 2,
+		Sign: func(d []byte) ([]byte, error) {
+			return []byte{0xCA, 0xFE}, nil
+		},
+	})
+	test.Error(t, pdf.Close())
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("/Contents <CAFE> /M <")), "signature of encrypted document")
+	test.That(t, !bytes.Contains(buf.Bytes(), []byte("Jane Doe")), "name is encrypted")
+
+	// signatures must fit the reserved size
+	pdf = NewPDF(&bytes.Buffer{}, 210.0, 297.0)
+	pdf.SetSignature(PDFSignature{
+		Size: 1,
+		Sign: func(d []byte) ([]byte, error) {
+			return []byte{0xCA, 0xFE}, nil
+		},
+	})
+	test.That(t, pdf.Close() != nil, "signature too large")
+
+	pdf = NewPDF(&bytes.Buffer{}, 210.0, 297.0)
+	pdf.SetSignature(PDFSignature{
+		Sign: func(d []byte) ([]byte, error) {
+			return nil, fmt.Errorf("no key")
+		},
+	})
+	test.That(t, pdf.Close() != nil, "signing failed")
+}
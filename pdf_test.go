@@ -3,6 +3,7 @@ package canvas
 import (
 	"bytes"
 	"image"
+	"math"
 	"testing"
 
 	"github.com/tdewolff/test"
@@ -44,6 +45,15 @@ func TestPDF(t *testing.T) {
 	//%%EOF`)
 }
 
+func TestPDFPages(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf := NewPDF(buf, 10.0, 10.0)
+	pdf.NewPage(10.0, 10.0)
+	test.Error(t, pdf.Close())
+	test.T(t, bytes.Count(buf.Bytes(), []byte("/Parent 5 0 R")), 2) // the contents and page objects of both pages precede the page tree
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("5 0 obj\n<< /Type /Pages")), buf.String())
+}
+
 func TestPDFPath(t *testing.T) {
 	buf := &bytes.Buffer{}
 	pdf := newPDFWriter(buf).NewPage(210.0, 297.0)
@@ -90,3 +100,117 @@ func TestPDFImage(t *testing.T) {
 	pdf.DrawImage(img, Lossless, Identity)
 	test.String(t, pdf.String(), " 2.8346457 0 0 2.8346457 0 0 cm q 0 0 2 2 re W n 0 0 m 0 2 l 2 2 l 2 0 l h W n 2 0 0 2 0 0 cm /Im0 Do Q")
 }
+
+func TestPDFNewPage(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf := NewPDF(buf, 210.0, 297.0)
+	pdf.SetCompression(false)
+	pdf.RenderPath(Rectangle(10.0, 10.0), DefaultStyle, Identity)
+	pdf.NewPage(100.0, 50.0)
+	test.Float(t, pdf.width, 100.0)
+	test.Float(t, pdf.height, 50.0)
+	pdf.RenderPath(Rectangle(10.0, 10.0), DefaultStyle, Identity)
+	test.Error(t, pdf.Close())
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("/Count 2")), "expected two pages")
+}
+
+func TestPDFImageOptions(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+
+	// images are downsampled to the maximum resolution and encoded as JPEG
+	buf := &bytes.Buffer{}
+	pdf := NewPDF(buf, 210.0, 297.0)
+	pdf.SetImageEncoding(Lossy)
+	pdf.SetImageQuality(50)
+	pdf.SetImageDownsampling(2.0, Lanczos)
+	pdf.RenderImage(img, Identity)                 // 1 dot per millimeter
+	pdf.RenderImage(img, Identity.Scale(0.1, 0.1)) // 10 dots per millimeter
+	test.Error(t, pdf.Close())
+	test.T(t, bytes.Count(buf.Bytes(), []byte("/Filter /DCTDecode")), 2)
+	test.T(t, bytes.Count(buf.Bytes(), []byte("/Height 50 /Interpolate true")), 1)
+	test.T(t, bytes.Count(buf.Bytes(), []byte("/Height 10 /Interpolate true")), 1)
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("10 0 0 5 0 0 cm /Im1 Do")), "the downsampled image has the same size")
+
+	// JPEG images are embedded as is
+	jpg, err := ReadJPEG(bytes.NewReader(testJPEG(1, testICCProfile())))
+	test.Error(t, err)
+	buf.Reset()
+	pdf = NewPDF(buf, 210.0, 297.0)
+	pdf.SetImageEncoding(Lossy)
+	pdf.RenderImage(jpg, Identity)
+	test.Error(t, pdf.Close())
+	test.That(t, bytes.Contains(buf.Bytes(), jpg.data), "JPEG file embedded")
+}
+
+func TestPDFPrepressMarks(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf := NewPDF(buf, 100.0, 50.0)
+	pdf.SetCompression(false)
+	pdf.SetPrepressMarks(DefaultPrepressMarks)
+	pdf.RenderPath(Rectangle(10.0, 10.0), DefaultStyle, Identity)
+	test.Error(t, pdf.Close())
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("/BleedBox [14.173228 14.173228 314.64567 172.91339]")), "bleed box")
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("/MediaBox [0 0 328.8189 187.08661]")), "media box")
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("/TrimBox [22.677165 22.677165 306.14173 164.40945]")), "trim box")
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("q 1 0 0 1 22.677165 22.677165 cm -8.503937 -8.503937 300.47244 158.74016 re W n 2.8346457 0 0 2.8346457 0 0 cm")), "drawing moved and clipped to bleed")
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("/All cs 1 scn")), "registration color")
+	test.T(t, bytes.Count(buf.Bytes(), []byte(" k ")), 10)
+
+	pages, err := ReadPDFPages(bytes.NewReader(buf.Bytes()))
+	test.Error(t, err)
+	test.Float(t, math.Round(pages[0].W), 116.0) // the media box
+	test.Float(t, math.Round(pages[0].H), 66.0)
+}
+
+func TestPDFSpotColors(t *testing.T) {
+	spot := SpotColor{"PANTONE 185 C", [4]float64{0.0, 0.93, 0.79, 0.0}, 0.5}
+	buf := &bytes.Buffer{}
+	pdf := NewPDF(buf, 100.0, 50.0)
+	pdf.SetCompression(false)
+	ctx := NewContext(pdf)
+	ctx.SetFillSpotColor(spot)
+	ctx.SetStrokeSpotColor(spot)
+	ctx.SetOverprint(true)
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	ctx.SetFillColor(Red)
+	ctx.SetOverprint(false)
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	test.Error(t, pdf.Close())
+
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("/Spot0 [/Separation /PANTONE#20185#20C /DeviceCMYK << /C0 [0 0 0 0] /C1 [0 .93 .79 0] /Domain [0 1] /FunctionType 2 /N 1 >>]")), "separation color space")
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("/OP1 gs /Spot0 cs .5 scn /Spot0 CS .5 SCN")), "overprinted spot color")
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("/OP0 gs 1 0 0 rg")), "process color")
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("/OP1 << /OP true /OPM 1 /op true >>")), "overprint graphics state")
+
+	pages, err := ReadPDFPages(bytes.NewReader(buf.Bytes()))
+	test.Error(t, err)
+	test.T(t, len(pages), 1)
+}
+
+func TestPDFFontSubsetting(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular))
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	write := func(subset bool) []byte {
+		buf := &bytes.Buffer{}
+		pdf := NewPDF(buf, 100.0, 50.0)
+		pdf.SetCompression(false)
+		pdf.SetFontSubsetting(subset)
+		ctx := NewContext(pdf)
+		ctx.DrawText(0.0, 10.0, NewTextLine(face, "Subset", Left))
+		test.Error(t, pdf.Close())
+		return buf.Bytes()
+	}
+	full, subset := write(false), write(true)
+	test.That(t, bytes.Contains(full, []byte("/BaseFont /dejavu-serif")), "full font")
+	test.That(t, bytes.Contains(subset, []byte("/BaseFont /AAAAAA+dejavu-serif")), "subset font tag")
+	test.That(t, len(subset) < len(full)/2, "subset font is smaller")
+
+	pages, err := ReadPDFPages(bytes.NewReader(subset))
+	test.Error(t, err)
+	test.T(t, len(pages), 1)
+}
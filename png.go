@@ -0,0 +1,110 @@
+package canvas
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+)
+
+// PNGOptions are the options for EncodePNG.
+type PNGOptions struct {
+	DPM         float64           // dots-per-millimeter of the physical size, zero omits it
+	Text        map[string]string // metadata by keyword, such as Title, Author, Description, Copyright, or Software
+	ICC         []byte            // ICC color profile of the pixels, nil omits it
+	Compression png.CompressionLevel
+}
+
+// EncodePNG writes the image to w as PNG with metadata: the physical resolution in a pHYs chunk so that printed output comes out at the intended size, the text in iTXt chunks as UTF-8, and the color profile in an iCCP chunk. Keywords must have 1 to 79 printable Latin-1 characters without leading, trailing, or consecutive spaces.
+func EncodePNG(w io.Writer, img image.Image, opts PNGOptions) error {
+	span := startTrace(SerializingPhase)
+	// chunks are inserted after IHDR, which is before PLTE and IDAT as required for pHYs and iCCP
+	chunks := &bytes.Buffer{}
+	if opts.DPM != 0.0 {
+		if opts.DPM < 0.0 || math.MaxUint32 < opts.DPM*1000.0 {
+			return fmt.Errorf("invalid PNG resolution")
+		}
+		ppm := uint32(opts.DPM*1000.0 + 0.5) // pixels per meter
+		data := make([]byte, 9)
+		binary.BigEndian.PutUint32(data[0:], ppm)
+		binary.BigEndian.PutUint32(data[4:], ppm)
+		data[8] = 1 // unit is the meter
+		writePNGChunk(chunks, "pHYs", data)
+	}
+	if opts.ICC != nil {
+		data := &bytes.Buffer{}
+		data.WriteString("ICC Profile\x00\x00") // name and compression method
+		zw := zlib.NewWriter(data)
+		zw.Write(opts.ICC)
+		zw.Close()
+		writePNGChunk(chunks, "iCCP", data.Bytes())
+	}
+	keywords := make([]string, 0, len(opts.Text))
+	for keyword := range opts.Text {
+		if !validPNGKeyword(keyword) {
+			return fmt.Errorf("invalid PNG keyword '%s'", keyword)
+		}
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	for _, keyword := range keywords {
+		// keyword, uncompressed, and empty language tag and translated keyword
+		data := []byte{}
+		for _, r := range keyword {
+			data = append(data, byte(r)) // Latin-1
+		}
+		data = append(data, 0, 0, 0, 0, 0)
+		writePNGChunk(chunks, "iTXt", append(data, opts.Text[keyword]...))
+	}
+
+	b := &bytes.Buffer{}
+	enc := &png.Encoder{CompressionLevel: opts.Compression}
+	if err := enc.Encode(b, img); err != nil {
+		return err
+	}
+	ihdr := 8 + 8 + 13 + 4 // signature and IHDR chunk
+	if _, err := w.Write(b.Bytes()[:ihdr]); err != nil {
+		return err
+	} else if _, err := w.Write(chunks.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.Write(b.Bytes()[ihdr:])
+	span.end(b.Len() + chunks.Len())
+	return err
+}
+
+// writePNGChunk writes a chunk of given type with its length and CRC.
+func writePNGChunk(w *bytes.Buffer, typ string, data []byte) {
+	length := [4]byte{}
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	w.Write(length[:])
+	w.WriteString(typ)
+	w.Write(data)
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(data)
+	sum := [4]byte{}
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	w.Write(sum[:])
+}
+
+// validPNGKeyword returns true if the keyword of a text chunk is valid according to the PNG specification.
+func validPNGKeyword(keyword string) bool {
+	n := 0
+	prev := ' '
+	for _, r := range keyword {
+		if r < 32 || 126 < r && r < 161 || 255 < r || r == ' ' && prev == ' ' {
+			return false
+		}
+		prev = r
+		n++
+	}
+	return 0 < n && n < 80 && prev != ' '
+}
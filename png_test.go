@@ -0,0 +1,80 @@
+package canvas
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+// pngChunks returns the types and data of the chunks of a PNG file.
+func pngChunks(t *testing.T, b []byte) ([]string, [][]byte) {
+	types, datas := []string{}, [][]byte{}
+	for b = b[8:]; 12 <= len(b); {
+		n := int(binary.BigEndian.Uint32(b))
+		types = append(types, string(b[4:8]))
+		datas = append(datas, b[8:8+n])
+		b = b[12+n:]
+	}
+	test.T(t, len(b), 0)
+	return types, datas
+}
+
+func TestEncodePNG(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	icc := []byte("profile")
+	b := &bytes.Buffer{}
+	test.Error(t, EncodePNG(b, img, PNGOptions{
+		DPM:  300.0 / 25.4,
+		Text: map[string]string{"Title": "Drawing", "Author": "Dürer"},
+		ICC:  icc,
+	}))
+
+	types, datas := pngChunks(t, b.Bytes())
+	test.T(t, types, []string{"IHDR", "pHYs", "iCCP", "iTXt", "iTXt", "IDAT", "IEND"})
+	test.Bytes(t, datas[1], []byte{0, 0, 0x2E, 0x23, 0, 0, 0x2E, 0x23, 1}) // 11811 pixels per meter
+	test.Bytes(t, datas[2][:13], []byte("ICC Profile\x00\x00"))
+	zr, err := zlib.NewReader(bytes.NewReader(datas[2][13:]))
+	test.Error(t, err)
+	profile, err := ioutil.ReadAll(zr)
+	test.Error(t, err)
+	test.Bytes(t, profile, icc)
+	test.Bytes(t, datas[3], []byte("Author\x00\x00\x00\x00\x00Dürer"))
+	test.Bytes(t, datas[4], []byte("Title\x00\x00\x00\x00\x00Drawing"))
+
+	// checksums are valid
+	dec, err := png.Decode(bytes.NewReader(b.Bytes()))
+	test.Error(t, err)
+	test.T(t, dec.Bounds(), img.Bounds())
+
+	// keywords are validated
+	test.That(t, EncodePNG(&bytes.Buffer{}, img, PNGOptions{Text: map[string]string{"": "text"}}) != nil, "empty keyword")
+	test.That(t, EncodePNG(&bytes.Buffer{}, img, PNGOptions{Text: map[string]string{"Title ": "text"}}) != nil, "trailing space")
+	test.That(t, EncodePNG(&bytes.Buffer{}, img, PNGOptions{Text: map[string]string{"Tïtle": "text"}}) == nil, "Latin-1 keyword")
+	test.That(t, EncodePNG(&bytes.Buffer{}, img, PNGOptions{DPM: -1.0}) != nil, "negative resolution")
+}
+
+func TestSavePNG(t *testing.T) {
+	c := New(10.0, 5.0)
+	ctx := NewContext(c)
+	ctx.DrawPath(0.0, 0.0, Rectangle(5.0, 5.0))
+
+	dir, err := ioutil.TempDir("", "canvas")
+	test.Error(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "canvas.png")
+	test.Error(t, c.SavePNG(filename, 10.0))
+	b, err := ioutil.ReadFile(filename)
+	test.Error(t, err)
+
+	types, datas := pngChunks(t, b)
+	test.T(t, types[1], "pHYs")
+	test.Bytes(t, datas[1], []byte{0, 0, 0x27, 0x10, 0, 0, 0x27, 0x10, 1}) // 10000 pixels per meter
+}
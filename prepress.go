@@ -0,0 +1,93 @@
+package canvas
+
+import "math"
+
+// PrepressMarks are the marks for commercial printing that are drawn outside the trim box, which is the size of the page. The drawing may extend into the bleed around the trim box, so that no white edges remain when the printed sheet is cut. A zero PrepressMarks draws no marks and no bleed.
+type PrepressMarks struct {
+	Bleed             float64 // in mm, the drawing is clipped to the trim box enlarged by the bleed
+	Offset            float64 // distance in mm of the marks from the trim box, which should be at least the bleed
+	Length            float64 // in mm, the length of crop marks and the size of registration marks and color bars
+	LineWidth         float64 // in mm
+	CropMarks         bool    // at the corners, along the edges of the trim box
+	RegistrationMarks bool    // at the middle of each side
+	ColorBars         bool    // of the process colors, their combinations and tints of black
+}
+
+// DefaultPrepressMarks are the marks commonly requested by print shops, with a bleed of 3mm.
+var DefaultPrepressMarks = PrepressMarks{
+	Bleed:             3.0,
+	Offset:            3.0,
+	Length:            5.0,
+	LineWidth:         0.1,
+	CropMarks:         true,
+	RegistrationMarks: true,
+	ColorBars:         true,
+}
+
+type prepressMark struct {
+	path         *Path      // filled outline in mm with the origin at the bottom-left of the trim box
+	cmyk         [4]float64 // process color, if not registration
+	registration bool       // on all separations, as needed to align them
+}
+
+// margin returns the width around the trim box of the media box that holds the bleed and the marks.
+func (marks PrepressMarks) margin() float64 {
+	margin := marks.Bleed
+	if marks.CropMarks || marks.RegistrationMarks || marks.ColorBars {
+		margin = math.Max(margin, marks.Offset+marks.Length)
+	}
+	return margin
+}
+
+// marks returns the marks around a trim box of width by height mm.
+func (marks PrepressMarks) marks(width, height float64) []prepressMark {
+	o, l := marks.Offset, marks.Length
+	line := func(x0, y0, x1, y1 float64) *Path {
+		p := &Path{}
+		p.MoveTo(x0, y0)
+		p.LineTo(x1, y1)
+		return p
+	}
+
+	ms := []prepressMark{}
+	if marks.CropMarks {
+		p := &Path{}
+		for _, corner := range []Point{{0.0, 0.0}, {width, 0.0}, {width, height}, {0.0, height}} {
+			dx, dy := 1.0, 1.0 // direction away from the trim box
+			if corner.X == 0.0 {
+				dx = -1.0
+			}
+			if corner.Y == 0.0 {
+				dy = -1.0
+			}
+			p = p.Append(line(corner.X+dx*o, corner.Y, corner.X+dx*(o+l), corner.Y))
+			p = p.Append(line(corner.X, corner.Y+dy*o, corner.X, corner.Y+dy*(o+l)))
+		}
+		ms = append(ms, prepressMark{path: p.Stroke(marks.LineWidth, ButtCap, MiterJoin), registration: true})
+	}
+	if marks.RegistrationMarks {
+		p := &Path{}
+		for _, center := range []Point{{width / 2.0, -o - l/2.0}, {width + o + l/2.0, height / 2.0}, {width / 2.0, height + o + l/2.0}, {-o - l/2.0, height / 2.0}} {
+			p = p.Append(Circle(0.3*l).Translate(center.X, center.Y))
+			p = p.Append(line(center.X-l/2.0, center.Y, center.X+l/2.0, center.Y))
+			p = p.Append(line(center.X, center.Y-l/2.0, center.X, center.Y+l/2.0))
+		}
+		ms = append(ms, prepressMark{path: p.Stroke(marks.LineWidth, ButtCap, MiterJoin), registration: true})
+	}
+	if marks.ColorBars {
+		// from the top-left corner to the registration mark at the middle of the top
+		colors := [][4]float64{
+			{1.0, 0.0, 0.0, 0.0}, {0.0, 1.0, 0.0, 0.0}, {0.0, 0.0, 1.0, 0.0}, {0.0, 0.0, 0.0, 1.0},
+			{0.0, 1.0, 1.0, 0.0}, {1.0, 0.0, 1.0, 0.0}, {1.0, 1.0, 0.0, 0.0},
+			{0.0, 0.0, 0.0, 0.25}, {0.0, 0.0, 0.0, 0.5}, {0.0, 0.0, 0.0, 0.75},
+		}
+		size := math.Min(l, (width/2.0-l)/float64(len(colors)))
+		if 0.0 < size {
+			for i, cmyk := range colors {
+				patch := Rectangle(size, size).Translate(float64(i)*size, height+o+(l-size)/2.0)
+				ms = append(ms, prepressMark{path: patch, cmyk: cmyk})
+			}
+		}
+	}
+	return ms
+}
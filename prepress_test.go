@@ -0,0 +1,32 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPrepressMarks(t *testing.T) {
+	marks := DefaultPrepressMarks
+	test.Float(t, marks.margin(), 8.0)
+	ms := marks.marks(100.0, 50.0)
+	test.T(t, len(ms), 12) // crop marks, registration marks, and ten color patches
+	test.That(t, ms[0].registration && ms[1].registration && !ms[2].registration, "registration color")
+
+	bounds := ms[0].path.Bounds()
+	test.Float(t, bounds.X, -8.0)
+	test.Float(t, bounds.W, 116.0)
+	test.That(t, !ms[0].path.Interior(0.0, 1.0, NonZero), "crop marks outside the trim box")
+	test.That(t, ms[0].path.Interior(-4.0, 0.0, NonZero), "crop mark along the bottom edge")
+	test.That(t, ms[1].path.Interior(50.0, -5.5, NonZero), "registration mark below the trim box")
+
+	// patches are in the top margin and scaled to fit left of the registration mark
+	test.T(t, ms[2].cmyk, [4]float64{1.0, 0.0, 0.0, 0.0})
+	test.T(t, ms[2].path.Bounds(), Rect{0.0, 53.25, 4.5, 4.5})
+
+	// bleed only
+	marks = PrepressMarks{Bleed: 3.0}
+	test.Float(t, marks.margin(), 3.0)
+	test.T(t, len(marks.marks(100.0, 50.0)), 0)
+	test.Float(t, PrepressMarks{}.margin(), 0.0)
+}
@@ -0,0 +1,139 @@
+package canvas
+
+// quadtreeCapacity is the number of items a quadtree node holds before it is subdivided.
+const quadtreeCapacity = 16
+
+// quadtreeMaxDepth is the maximum depth of a quadtree, limiting subdivision for many overlapping items.
+const quadtreeMaxDepth = 16
+
+type quadtreeItem struct {
+	bounds Rect
+	id     int
+}
+
+type quadtreeNode struct {
+	bounds   Rect
+	depth    int
+	items    []quadtreeItem // items that do not fit in a single child
+	children *[4]quadtreeNode
+}
+
+// Quadtree is a spatial index of rectangular bounds, which allows to find the items that overlap a given area in O(log n) time on average. Items that fall outside of the quadtree's bounds are kept in a list that is searched linearly.
+type Quadtree struct {
+	root    quadtreeNode
+	outside []quadtreeItem
+	n       int
+}
+
+// NewQuadtree returns a new quadtree covering bounds.
+func NewQuadtree(bounds Rect) *Quadtree {
+	return &Quadtree{
+		root: quadtreeNode{bounds: bounds},
+	}
+}
+
+// Len returns the number of items in the quadtree.
+func (q *Quadtree) Len() int {
+	return q.n
+}
+
+// Insert adds an item with the given bounds and identifier.
+func (q *Quadtree) Insert(bounds Rect, id int) {
+	q.n++
+	item := quadtreeItem{bounds, id}
+	if !rectContainsRect(q.root.bounds, bounds) {
+		q.outside = append(q.outside, item)
+		return
+	}
+	q.root.insert(item)
+}
+
+// Search calls f for every item whose bounds overlap with rect (including touching), until f returns false. Items are not given in any particular order.
+func (q *Quadtree) Search(rect Rect, f func(id int) bool) {
+	for _, item := range q.outside {
+		if item.bounds.Overlaps(rect) && !f(item.id) {
+			return
+		}
+	}
+	q.root.search(rect, f)
+}
+
+// Query returns the identifiers of all items whose bounds overlap with rect, in no particular order.
+func (q *Quadtree) Query(rect Rect) []int {
+	ids := []int{}
+	q.Search(rect, func(id int) bool {
+		ids = append(ids, id)
+		return true
+	})
+	return ids
+}
+
+func (n *quadtreeNode) insert(item quadtreeItem) {
+	for {
+		if n.children == nil {
+			if len(n.items) < quadtreeCapacity || quadtreeMaxDepth <= n.depth {
+				n.items = append(n.items, item)
+				return
+			}
+			n.subdivide()
+		}
+
+		child := n.childFor(item.bounds)
+		if child == nil {
+			n.items = append(n.items, item)
+			return
+		}
+		n = child
+	}
+}
+
+func (n *quadtreeNode) subdivide() {
+	w, h := n.bounds.W/2.0, n.bounds.H/2.0
+	x, y := n.bounds.X, n.bounds.Y
+	n.children = &[4]quadtreeNode{
+		{bounds: Rect{x, y, w, h}, depth: n.depth + 1},
+		{bounds: Rect{x + w, y, w, h}, depth: n.depth + 1},
+		{bounds: Rect{x, y + h, w, h}, depth: n.depth + 1},
+		{bounds: Rect{x + w, y + h, w, h}, depth: n.depth + 1},
+	}
+
+	items := n.items
+	n.items = nil
+	for _, item := range items {
+		if child := n.childFor(item.bounds); child != nil {
+			child.insert(item)
+		} else {
+			n.items = append(n.items, item)
+		}
+	}
+}
+
+// childFor returns the child node that fully contains bounds, or nil if none.
+func (n *quadtreeNode) childFor(bounds Rect) *quadtreeNode {
+	for i := range n.children {
+		if rectContainsRect(n.children[i].bounds, bounds) {
+			return &n.children[i]
+		}
+	}
+	return nil
+}
+
+func (n *quadtreeNode) search(rect Rect, f func(id int) bool) bool {
+	for _, item := range n.items {
+		if item.bounds.Overlaps(rect) && !f(item.id) {
+			return false
+		}
+	}
+	if n.children != nil {
+		for i := range n.children {
+			if n.children[i].bounds.Overlaps(rect) && !n.children[i].search(rect, f) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func rectContainsRect(r, q Rect) bool {
+	return r.X <= q.X && q.X+q.W <= r.X+r.W && r.Y <= q.Y && q.Y+q.H <= r.Y+r.H
+}
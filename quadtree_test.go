@@ -0,0 +1,35 @@
+package canvas
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestQuadtree(t *testing.T) {
+	q := NewQuadtree(Rect{0.0, 0.0, 100.0, 100.0})
+	n := 0
+	for y := 0.0; y < 100.0; y += 5.0 {
+		for x := 0.0; x < 100.0; x += 5.0 {
+			q.Insert(Rect{x, y, 4.0, 4.0}, n)
+			n++
+		}
+	}
+	q.Insert(Rect{-10.0, -10.0, 200.0, 200.0}, n) // larger than the quadtree
+	test.T(t, q.Len(), 401)
+
+	ids := q.Query(Rect{6.0, 6.0, 5.0, 5.0})
+	sort.Ints(ids)
+	test.T(t, ids, []int{21, 22, 41, 42, 400})
+
+	ids = q.Query(Rect{4.5, 4.5, 0.0, 0.0})
+	test.T(t, ids, []int{400})
+
+	count := 0
+	q.Search(Rect{0.0, 0.0, 100.0, 100.0}, func(int) bool {
+		count++
+		return count < 10
+	})
+	test.T(t, count, 10)
+}
@@ -11,6 +11,12 @@ import (
 type Rasterizer struct {
 	img draw.Image
 	dpm float64
+	lod float64 // tolerance in pixels for level-of-detail simplification
+
+	resampling Resampling
+
+	budget   *budget             // nil when unlimited
+	deadline *rasterizerDeadline // nil without deadline
 }
 
 // NewRasterizer creates a renderer that draws to a rasterized image.
@@ -21,19 +27,62 @@ func NewRasterizer(img draw.Image, dpm float64) *Rasterizer {
 	}
 }
 
+// SetLevelOfDetail sets the tolerance in pixels for simplifying paths before rasterization, see Path.Simplify. Subpaths smaller than the tolerance are dropped and details smaller than the tolerance are removed, which is relative to the current scale so that zoomed out scenes render faster. Paths stroked wider than the tolerance are not simplified. A tolerance of zero, the default, disables simplification.
+func (r *Rasterizer) SetLevelOfDetail(tolerance float64) {
+	r.lod = tolerance
+}
+
+// SetResampling sets the interpolation used to draw images, which is CatmullRom by default.
+func (r *Rasterizer) SetResampling(resampling Resampling) {
+	r.resampling = resampling
+}
+
+// SetLimits sets resource limits on the drawing that is rendered, and starts its time budget. Once a limit is exceeded nothing further is drawn and Err returns the error. The size of the image must be within the pixel limit, which is best checked before allocating it, see Canvas.WriteImageWithLimits.
+func (r *Rasterizer) SetLimits(limits Limits) {
+	r.budget = newBudget(limits)
+	size := r.img.Bounds().Size()
+	r.budget.checkSize(float64(size.X), float64(size.Y))
+}
+
+// Err returns the error, which wraps ErrLimitExceeded, when a limit set by SetLimits has been exceeded.
+func (r *Rasterizer) Err() error {
+	if r.budget == nil {
+		return nil
+	}
+	return r.budget.err
+}
+
 func (r *Rasterizer) Size() (float64, float64) {
 	size := r.img.Bounds().Size()
 	return float64(size.X) / r.dpm, float64(size.Y) / r.dpm
 }
 
 func (r *Rasterizer) RenderPath(path *Path, style Style, m Matrix) {
+	if style.FillMesh != nil {
+		renderMesh(r, path, style, m, 2.0/r.dpm)
+		return
+	}
+	r.renderPath(path, style, m, r.deadline.progress())
+}
+
+// renderPath draws the path at the degradation level, see SetDeadline.
+func (r *Rasterizer) renderPath(path *Path, style Style, m Matrix, level int) {
 	// TODO: use fill rule (EvenOdd, NonZero) for rasterizer
+	if r.budget != nil && !r.budget.addPath(path, style, m) {
+		return
+	}
 	path = path.Transform(m)
 
 	strokeWidth := 0.0
 	if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth {
 		strokeWidth = style.StrokeWidth
 	}
+	if 0.0 < r.lod && strokeWidth*r.dpm < r.lod {
+		path = path.Simplify(r.lod / r.dpm)
+		if path.Empty() {
+			return
+		}
+	}
 
 	size := r.img.Bounds().Size()
 	bounds := path.Bounds()
@@ -64,10 +113,33 @@ func (r *Rasterizer) RenderPath(path *Path, style Style, m Matrix) {
 		return // has no size
 	}
 
+	defer startTrace(RasterizingPhase).end(1)
+	if 2 <= level {
+		r.deadline.degraded |= CoarseFlattening | NoAntialiasing
+		toPixels := Identity.Translate(0.0, float64(size.Y)).Scale(r.dpm, -r.dpm)
+		if style.FillColor.A != 0 {
+			fillAliased(r.img, path.Transform(toPixels).flattenTolerance(1.0), style.FillColor)
+		}
+		if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth {
+			if 0 < len(style.Dashes) {
+				path = path.Dash(style.DashOffset, style.Dashes...)
+			}
+			path = path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
+			fillAliased(r.img, path.Transform(toPixels).flattenTolerance(1.0), style.StrokeColor)
+		}
+		return
+	} else if level == 1 {
+		r.deadline.degraded |= CoarseFlattening
+	}
+
 	path = path.Translate(-float64(x)/r.dpm, -float64(y)/r.dpm)
 	if style.FillColor.A != 0 {
+		fill := path
+		if level == 1 {
+			fill = fill.flattenTolerance(0.5 / r.dpm)
+		}
 		ras := vector.NewRasterizer(w, h)
-		path.ToRasterizer(ras, r.dpm)
+		fill.ToRasterizer(ras, r.dpm)
 		ras.Draw(r.img, image.Rect(x, size.Y-y, x+w, size.Y-y-h), image.NewUniform(style.FillColor), image.Point{dx, dy})
 	}
 	if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth {
@@ -75,6 +147,9 @@ func (r *Rasterizer) RenderPath(path *Path, style Style, m Matrix) {
 			path = path.Dash(style.DashOffset, style.Dashes...)
 		}
 		path = path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
+		if level == 1 {
+			path = path.flattenTolerance(0.5 / r.dpm)
+		}
 
 		ras := vector.NewRasterizer(w, h)
 		path.ToRasterizer(ras, r.dpm)
@@ -83,26 +158,53 @@ func (r *Rasterizer) RenderPath(path *Path, style Style, m Matrix) {
 }
 
 func (r *Rasterizer) RenderText(text *Text, m Matrix) {
-	paths, colors := text.ToPaths()
+	level := r.deadline.progress()
+	if r.budget != nil {
+		for _, line := range text.lines {
+			for _, span := range line.spans {
+				if !r.budget.addFontSize(span.ff.size * ptPerMm) {
+					return
+				}
+			}
+		}
+	}
+	paths, colors, images := text.toPaths(true, nil)
 	for i, path := range paths {
 		style := DefaultStyle
 		style.FillColor = colors[i]
-		r.RenderPath(path, style, m)
+		r.renderPath(path, style, m, level)
+		PutPath(path)
+	}
+	for _, img := range images {
+		r.RenderImage(img.img, m.Mul(img.m))
 	}
 }
 
 func (r *Rasterizer) RenderImage(img image.Image, m Matrix) {
-	origin := m.Dot(Point{0, float64(img.Bounds().Size().Y)}).Mul(r.dpm)
-	m = m.Scale(r.dpm, r.dpm)
-
-	h := float64(r.img.Bounds().Size().Y)
-	aff3 := f64.Aff3{m[0][0], -m[0][1], origin.X, -m[1][0], m[1][1], h - origin.Y}
+	level := r.deadline.progress()
+	if r.budget != nil && (r.budget.expired() || !r.budget.addPixels(img.Bounds().Dx(), img.Bounds().Dy())) {
+		return
+	}
+	defer startTrace(RasterizingPhase).end(1)
+	img = srgbImage(img)
 
 	// add transparent margin to image for smooth borders when rotating
 	margin := 4
 	size := img.Bounds().Size()
 	img2 := image.NewRGBA(image.Rect(0, 0, size.X+margin*2, size.Y+margin*2))
-	draw.Draw(img2, image.Rect(margin, margin, size.X, size.Y), img, image.Point{}, draw.Over)
+	draw.Draw(img2, image.Rect(margin, margin, size.X+margin, size.Y+margin), img, img.Bounds().Min, draw.Over)
+
+	// the origin is the top-left of the margin
+	origin := m.Dot(Point{-float64(margin), float64(size.Y + margin)}).Mul(r.dpm)
+	m = m.Scale(r.dpm, r.dpm)
 
-	draw.CatmullRom.Transform(r.img, aff3, img2, img2.Bounds(), draw.Over, nil)
+	h := float64(r.img.Bounds().Size().Y)
+	aff3 := f64.Aff3{m[0][0], -m[0][1], origin.X, -m[1][0], m[1][1], h - origin.Y}
+
+	resampling := r.resampling
+	if 3 <= level {
+		r.deadline.degraded |= NearestResampling
+		resampling = NearestNeighbor
+	}
+	resampling.interpolator().Transform(r.img, aff3, img2, img2.Bounds(), draw.Over, nil)
 }
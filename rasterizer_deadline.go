@@ -0,0 +1,186 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+// Degradation is a set of reductions of quality that the rasterizer applied to finish by its deadline, see Rasterizer.SetDeadline.
+type Degradation int
+
+// see Degradation
+const (
+	CoarseFlattening  Degradation = 1 << iota // curves are flattened into lines that deviate up to half a pixel
+	NoAntialiasing                            // paths are filled by sampling the centers of pixels and curves deviate up to a pixel
+	NearestResampling                         // images are drawn using nearest-neighbor resampling
+)
+
+func (d Degradation) String() string {
+	names := []string{}
+	for i, name := range []string{"coarse flattening", "no anti-aliasing", "nearest resampling"} {
+		if d&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// rasterizerDeadline keeps track of the progress of drawing towards a deadline.
+type rasterizerDeadline struct {
+	start, deadline time.Time
+	n, done         int // expected and started number of drawing operations
+	level           int // number of degradations in the order of Degradation
+	degraded        Degradation
+}
+
+// SetDeadline makes the rasterizer degrade the quality of drawing progressively so that it finishes by the deadline, instead of taking longer. Before each path, text, or image the time to finish is projected from the time spent so far on the previous ones, where n is the number of paths, texts, and images that will be drawn, or zero when unknown and the elapsed fraction of the time until the deadline is used. The more the projection exceeds the deadline, the further is quality reduced: first curves are flattened coarsely, then anti-aliasing is disabled, and then images are resampled by nearest neighbor. Quality is not restored for the remainder of the drawing, and everything is drawn even past the deadline. Degraded returns which reductions have been applied.
+func (r *Rasterizer) SetDeadline(deadline time.Time, n int) {
+	r.deadline = &rasterizerDeadline{
+		start:    time.Now(),
+		deadline: deadline,
+		n:        n,
+	}
+}
+
+// Degraded returns the reductions of quality that have been applied to finish by the deadline, see SetDeadline.
+func (r *Rasterizer) Degraded() Degradation {
+	if r.deadline == nil {
+		return 0
+	}
+	return r.deadline.degraded
+}
+
+// progress starts a drawing operation and returns the degradation level to use.
+func (d *rasterizerDeadline) progress() int {
+	if d == nil {
+		return 0
+	}
+	now := time.Now()
+	elapsed, budget := now.Sub(d.start).Seconds(), d.deadline.Sub(d.start).Seconds()
+	if !now.Before(d.deadline) {
+		d.level = 3
+	} else if 0.0 < budget {
+		projected := 2.0 * elapsed // level one at half the time to the deadline when the number of operations is unknown
+		if 0 < d.n && 0 < d.done {
+			projected = elapsed * float64(d.n) / float64(d.done)
+		}
+		ratio, level := projected/budget, 0
+		if 2.0 < ratio {
+			level = 3
+		} else if 1.5 < ratio {
+			level = 2
+		} else if 1.0 < ratio {
+			level = 1
+		}
+		if d.level < level {
+			d.level = level
+		}
+	}
+	d.done++
+	return d.level
+}
+
+// flattenTolerance flattens curves into lines that deviate at most by tolerance from the curves.
+func (p *Path) flattenTolerance(tolerance float64) *Path {
+	quad := func(p0, p1, p2 Point) *Path {
+		cp1, cp2 := quadraticToCubicBezier(p0, p1, p2)
+		return strokeCubicBezier(p0, cp1, cp2, p2, 0.0, tolerance)
+	}
+	cube := func(p0, p1, p2, p3 Point) *Path {
+		return strokeCubicBezier(p0, p1, p2, p3, 0.0, tolerance)
+	}
+	return p.replace(nil, nil, nil, arcToCube).replace(nil, quad, cube, nil)
+}
+
+// fillAliased fills the path in pixel coordinates without anti-aliasing, where a pixel is filled if its center is inside the path by the non-zero winding rule. The path must be flattened.
+func fillAliased(img draw.Image, p *Path, col color.RGBA) {
+	type edge struct {
+		x0, y0, x1, y1 float64 // y0 < y1
+		dir            int
+	}
+	edges := []edge{}
+	addEdge := func(a, b Point) {
+		if a.Y == b.Y {
+			return
+		} else if a.Y < b.Y {
+			edges = append(edges, edge{a.X, a.Y, b.X, b.Y, 1})
+		} else {
+			edges = append(edges, edge{b.X, b.Y, a.X, a.Y, -1})
+		}
+	}
+	var start, cur Point
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		end := Point{p.d[i+cmdLen(cmd)-3], p.d[i+cmdLen(cmd)-2]}
+		if cmd == moveToCmd {
+			addEdge(cur, start) // subpaths are closed implicitly
+			start = end
+		} else {
+			addEdge(cur, end)
+		}
+		cur = end
+		i += cmdLen(cmd)
+	}
+	addEdge(cur, start)
+	if len(edges) == 0 {
+		return
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].y0 < edges[j].y0 })
+
+	bounds := img.Bounds()
+	y0 := clampInt(int(math.Floor(edges[0].y0)), bounds.Min.Y, bounds.Max.Y)
+	src := image.NewUniform(col)
+	type crossing struct {
+		x   float64
+		dir int
+	}
+	active, crossings := []edge{}, []crossing{}
+	next := 0 // index of the first edge that is not active yet
+	for y := y0; y < bounds.Max.Y; y++ {
+		yc := float64(y) + 0.5
+		for next < len(edges) && edges[next].y0 <= yc {
+			active = append(active, edges[next])
+			next++
+		}
+		crossings = crossings[:0]
+		k := 0
+		for _, e := range active {
+			if yc < e.y1 {
+				active[k] = e
+				k++
+				if e.y0 <= yc {
+					crossings = append(crossings, crossing{e.x0 + (yc-e.y0)*(e.x1-e.x0)/(e.y1-e.y0), e.dir})
+				}
+			}
+		}
+		active = active[:k]
+		if len(active) == 0 && next == len(edges) {
+			break
+		}
+		sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+
+		winding, x0 := 0, 0.0
+		for _, c := range crossings {
+			if winding == 0 {
+				x0 = c.x
+			}
+			winding += c.dir
+			if winding == 0 {
+				// pixels whose centers are in [x0,x)
+				span := image.Rect(int(math.Ceil(x0-0.5)), y, int(math.Ceil(c.x-0.5)), y+1).Intersect(bounds)
+				if !span.Empty() {
+					draw.Draw(img, span, src, image.Point{}, draw.Over)
+				}
+			}
+		}
+	}
+}
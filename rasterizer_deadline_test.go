@@ -0,0 +1,77 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tdewolff/test"
+)
+
+func TestFillAliased(t *testing.T) {
+	count := func(img *image.RGBA) int {
+		n := 0
+		for i := 3; i < len(img.Pix); i += 4 {
+			if img.Pix[i] == 0xff {
+				n++
+			} else if img.Pix[i] != 0x00 {
+				return -1 // anti-aliased
+			}
+		}
+		return n
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	fillAliased(img, Rectangle(4.0, 3.0).Translate(1.2, 2.0), Black)
+	test.T(t, count(img), 12)
+	test.T(t, img.RGBAAt(1, 2), color.RGBA{0, 0, 0, 255})
+	test.T(t, img.RGBAAt(5, 2), color.RGBA{0, 0, 0, 0})
+
+	// holes by the non-zero winding rule and clipping to the image
+	img = image.NewRGBA(image.Rect(0, 0, 10, 10))
+	fillAliased(img, Rectangle(8.0, 8.0).Append(Rectangle(4.0, 4.0).Translate(2.0, 2.0).Reverse()).Translate(-4.0, -4.0), Black)
+	test.T(t, count(img), 16-4)
+
+	img = image.NewRGBA(image.Rect(0, 0, 100, 100))
+	fillAliased(img, Circle(40.0).Translate(50.0, 50.0).flattenTolerance(0.01), Black)
+	test.That(t, math.Abs(float64(count(img))-1600.0*math.Pi) < 20.0, count(img))
+}
+
+func TestRasterizerDeadline(t *testing.T) {
+	c := New(10.0, 10.0)
+	ctx := NewContext(c)
+	ctx.DrawPath(5.0, 5.0, Circle(3.0))
+	ctx.SetStrokeColor(Black)
+	ctx.SetStrokeWidth(0.5)
+	ctx.DrawPath(0.0, 0.0, Rectangle(2.0, 2.0))
+	ctx.DrawImage(8.0, 8.0, image.NewRGBA(image.Rect(0, 0, 2, 2)), 1.0)
+
+	img, degraded := c.WriteImageWithDeadline(10.0, time.Now().Add(time.Hour))
+	test.T(t, degraded, Degradation(0))
+	test.Bytes(t, img.Pix, c.WriteImage(10.0).Pix)
+
+	// past the deadline all quality reductions apply but everything is drawn
+	img, degraded = c.WriteImageWithDeadline(10.0, time.Now())
+	test.T(t, degraded, CoarseFlattening|NoAntialiasing|NearestResampling)
+	test.T(t, degraded.String(), "coarse flattening, no anti-aliasing, nearest resampling")
+	test.T(t, img.RGBAAt(50, 50), color.RGBA{0, 0, 0, 255})
+	gray := 0
+	for i := 0; i < len(img.Pix); i++ {
+		if img.Pix[i] != 0x00 && img.Pix[i] != 0xff {
+			gray++
+		}
+	}
+	test.T(t, gray, 0)
+
+	// the projected time to finish degrades progressively
+	d := &rasterizerDeadline{start: time.Now().Add(-time.Second), deadline: time.Now().Add(time.Second), n: 10, done: 4}
+	test.T(t, d.progress(), 1)
+	d = &rasterizerDeadline{start: time.Now().Add(-time.Second), deadline: time.Now().Add(time.Second), n: 10, done: 3}
+	test.T(t, d.progress(), 2)
+	test.T(t, d.progress(), 2) // never restored
+	d = &rasterizerDeadline{start: time.Now().Add(-time.Second), deadline: time.Now().Add(9 * time.Second)}
+	test.T(t, d.progress(), 0)
+	test.T(t, Degradation(0).String(), "none")
+}
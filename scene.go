@@ -0,0 +1,903 @@
+package canvas
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+
+	canvasFont "github.com/tdewolff/canvas/font"
+	"golang.org/x/image/font/sfnt"
+)
+
+// sceneVersion is the version of the scene format written by WriteScene, scenes of later versions are not read.
+const sceneVersion = 1
+
+// SceneOptions are the options to write a scene, see Canvas.WriteScene.
+type SceneOptions struct {
+	SubsetFonts bool // remove the outlines of glyphs that are not used from TrueType fonts
+}
+
+// sceneDecorators are the font decorations that are written to scenes by name.
+var sceneDecorators = []struct {
+	name string
+	deco FontDecorator
+}{
+	{"underline", FontUnderline},
+	{"overline", FontOverline},
+	{"strikethrough", FontStrikethrough},
+	{"doubleUnderline", FontDoubleUnderline},
+	{"dottedUnderline", FontDottedUnderline},
+	{"dashedUnderline", FontDashedUnderline},
+	{"sineUnderline", FontSineUnderline},
+	{"sawtoothUnderline", FontSawtoothUnderline},
+}
+
+// SaveScene saves the canvas to a scene file, see WriteScene.
+func (c *Canvas) SaveScene(filename string, opts SceneOptions) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := c.WriteScene(f, opts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteScene writes the canvas as a scene, which is a versioned CBOR file (RFC 8949) of its size and its layers with their paths and styles, texts with their font faces and layout, and images. Fonts are embedded, and with SubsetFonts only with the glyphs that are used. Callbacks such as glyph substitutions, and decorations and text effects not of this package are not supported. Scenes are read by ReadScene to render them again to any renderer, eg. as a project file.
+func (c *Canvas) WriteScene(w io.Writer, opts SceneOptions) error {
+	c.merge()
+	s := &sceneWriter{
+		opts:      opts,
+		fontIndex: map[*Font]int{},
+		fonts:     []interface{}{},
+		faces:     []interface{}{},
+	}
+	layers := []interface{}{}
+	for _, l := range c.layers {
+		v, err := s.layer(l)
+		if err != nil {
+			return err
+		}
+		layers = append(layers, v)
+	}
+	fonts, err := s.embedFonts()
+	if err != nil {
+		return err
+	}
+
+	b := appendCBORHead(nil, 6, cborSelfDescribe)
+	b = appendCBOR(b, map[string]interface{}{
+		"version": sceneVersion,
+		"width":   c.W,
+		"height":  c.H,
+		"fonts":   fonts,
+		"faces":   s.faces,
+		"layers":  layers,
+	})
+	_, err = w.Write(b)
+	return err
+}
+
+type sceneWriter struct {
+	opts      SceneOptions
+	fontIndex map[*Font]int
+	fontList  []*Font
+	glyphs    []map[uint16]bool // glyphs used per font
+	fonts     []interface{}
+	faceList  []FontFace
+	faces     []interface{}
+}
+
+func (s *sceneWriter) layer(l layer) (map[string]interface{}, error) {
+	v := map[string]interface{}{
+		"matrix": []float64{l.m[0][0], l.m[1][0], l.m[0][1], l.m[1][1], l.m[0][2], l.m[1][2]},
+	}
+	if l.path != nil {
+		style, err := sceneStyle(l.style)
+		if err != nil {
+			return nil, err
+		}
+		v["path"] = scenePath(l.path)
+		v["style"] = style
+	} else if l.text != nil {
+		text, err := s.text(l.text)
+		if err != nil {
+			return nil, err
+		}
+		v["text"] = text
+	} else if l.img != nil {
+		if jpg, ok := l.img.(*JPEGImage); ok && jpg.data != nil {
+			v["image"] = map[string]interface{}{"jpeg": jpg.data, "orientation": jpg.Orientation}
+		} else {
+			b := &bytes.Buffer{}
+			if err := png.Encode(b, srgbImage(l.img)); err != nil {
+				return nil, err
+			}
+			v["image"] = map[string]interface{}{"png": b.Bytes()}
+		}
+	}
+	if l.link != "" {
+		v["link"] = l.link
+	}
+	return v, nil
+}
+
+func scenePath(p *Path) map[string]interface{} {
+	cmds := []byte{}
+	args := []float64{}
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		switch cmd {
+		case moveToCmd:
+			cmds = append(cmds, 'M')
+			args = append(args, p.d[i+1], p.d[i+2])
+		case lineToCmd:
+			cmds = append(cmds, 'L')
+			args = append(args, p.d[i+1], p.d[i+2])
+		case quadToCmd:
+			cmds = append(cmds, 'Q')
+			args = append(args, p.d[i+1], p.d[i+2], p.d[i+3], p.d[i+4])
+		case cubeToCmd:
+			cmds = append(cmds, 'C')
+			args = append(args, p.d[i+1], p.d[i+2], p.d[i+3], p.d[i+4], p.d[i+5], p.d[i+6])
+		case arcToCmd:
+			large, sweep := toArcFlags(p.d[i+4])
+			cmds = append(cmds, 'A')
+			args = append(args, p.d[i+1], p.d[i+2], p.d[i+3]*180.0/math.Pi, boolToFloat(large), boolToFloat(sweep), p.d[i+5], p.d[i+6])
+		case closeCmd:
+			cmds = append(cmds, 'Z')
+		}
+		i += cmdLen(cmd)
+	}
+	return map[string]interface{}{"cmds": string(cmds), "args": args}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1.0
+	}
+	return 0.0
+}
+
+func sceneColor(col color.RGBA) []byte {
+	return []byte{col.R, col.G, col.B, col.A}
+}
+
+func sceneSpotColor(spot *SpotColor) interface{} {
+	if spot == nil {
+		return nil
+	}
+	return map[string]interface{}{"name": spot.Name, "cmyk": spot.CMYK[:], "tint": spot.Tint}
+}
+
+func sceneMesh(mesh *MeshGradient) interface{} {
+	if mesh == nil {
+		return nil
+	}
+	patches := []interface{}{}
+	for _, patch := range mesh.Patches {
+		points := make([]float64, 0, 24)
+		for _, p := range patch.Points {
+			points = append(points, p.X, p.Y)
+		}
+		colors := []interface{}{}
+		for _, col := range patch.Colors {
+			colors = append(colors, sceneColor(col))
+		}
+		patches = append(patches, map[string]interface{}{"points": points, "colors": colors})
+	}
+	return patches
+}
+
+func sceneJoiner(j joinerJSON) map[string]interface{} {
+	v := map[string]interface{}{"type": j.Type}
+	if j.Limit != nil {
+		v["limit"] = *j.Limit
+	}
+	if j.Gap != nil {
+		v["gap"] = sceneJoiner(*j.Gap)
+	}
+	return v
+}
+
+func sceneStyle(style Style) (map[string]interface{}, error) {
+	capper, err := toCapperJSON(style.StrokeCapper)
+	if err != nil {
+		return nil, err
+	}
+	joiner, err := toJoinerJSON(style.StrokeJoiner)
+	if err != nil {
+		return nil, err
+	}
+	fillRule := "nonzero"
+	if style.FillRule == EvenOdd {
+		fillRule = "evenodd"
+	}
+	dashes := style.Dashes
+	if dashes == nil {
+		dashes = []float64{}
+	}
+	return map[string]interface{}{
+		"fill":        sceneColor(style.FillColor),
+		"stroke":      sceneColor(style.StrokeColor),
+		"strokeWidth": style.StrokeWidth,
+		"strokeCap":   capper,
+		"strokeJoin":  sceneJoiner(joiner),
+		"dashOffset":  style.DashOffset,
+		"dashes":      dashes,
+		"fillRule":    fillRule,
+		"fillSpot":    sceneSpotColor(style.FillSpot),
+		"strokeSpot":  sceneSpotColor(style.StrokeSpot),
+		"fillMesh":    sceneMesh(style.FillMesh),
+		"overprint":   style.Overprint,
+	}, nil
+}
+
+func (s *sceneWriter) text(t *Text) (map[string]interface{}, error) {
+	lines := []interface{}{}
+	for _, line := range t.lines {
+		spans := []interface{}{}
+		for _, span := range line.spans {
+			face, err := s.face(span.ff)
+			if err != nil {
+				return nil, err
+			}
+			boundaries := []interface{}{}
+			for _, boundary := range span.boundaries {
+				boundaries = append(boundaries, int(boundary.kind), boundary.pos, boundary.size)
+			}
+			s.addGlyphs(span)
+			spans = append(spans, map[string]interface{}{
+				"face":            face,
+				"text":            span.text,
+				"width":           span.width,
+				"boundaries":      boundaries,
+				"dx":              span.dx,
+				"sentenceSpacing": span.sentenceSpacing,
+				"wordSpacing":     span.wordSpacing,
+				"glyphSpacing":    span.glyphSpacing,
+				"glyphStretch":    span.glyphStretch,
+				"level":           int(span.level),
+			})
+		}
+		decos := []interface{}{}
+		for _, deco := range line.decos {
+			face, err := s.face(deco.ff)
+			if err != nil {
+				return nil, err
+			}
+			decos = append(decos, map[string]interface{}{"face": face, "x0": deco.x0, "x1": deco.x1})
+		}
+		lines = append(lines, map[string]interface{}{"y": line.y, "spans": spans, "decos": decos})
+	}
+	if t.vertical {
+		return map[string]interface{}{"lines": lines, "vertical": true, "width": t.width}, nil
+	}
+	return map[string]interface{}{"lines": lines}, nil
+}
+
+// face returns the index of the font face in the list of faces of the scene.
+func (s *sceneWriter) face(ff FontFace) (int, error) {
+	for i, face := range s.faceList {
+		if face.family == ff.family && face.Equals(ff) && face.scale == ff.scale && face.voffset == ff.voffset && face.fauxBold == ff.fauxBold && face.fauxItalic == ff.fauxItalic {
+			return i, nil
+		}
+	}
+
+	decos := []interface{}{}
+Decorators:
+	for _, deco := range ff.deco {
+		for _, d := range sceneDecorators {
+			if reflect.TypeOf(deco) == reflect.TypeOf(d.deco) {
+				decos = append(decos, d.name)
+				continue Decorators
+			}
+		}
+		return 0, fmt.Errorf("unsupported font decorator %T", deco)
+	}
+	effects := []interface{}{}
+	for _, effect := range ff.effects {
+		switch e := effect.(type) {
+		case TextShadow:
+			effects = append(effects, map[string]interface{}{"type": "shadow", "offset": []float64{e.Offset.X, e.Offset.Y}, "color": sceneColor(e.Color)})
+		case TextOutline:
+			effects = append(effects, map[string]interface{}{"type": "outline", "width": e.Width, "color": sceneColor(e.Color)})
+		case TextGlow:
+			effects = append(effects, map[string]interface{}{"type": "glow", "radius": e.Radius, "offset": []float64{e.Offset.X, e.Offset.Y}, "color": sceneColor(e.Color), "resolution": e.Resolution})
+		default:
+			return 0, fmt.Errorf("unsupported text effect %T", effect)
+		}
+	}
+	features := []interface{}{}
+	for _, tag := range ff.features {
+		features = append(features, tag)
+	}
+
+	font, ok := s.fontIndex[ff.font]
+	if !ok {
+		font = len(s.fontList)
+		s.fontIndex[ff.font] = font
+		s.fontList = append(s.fontList, ff.font)
+		s.glyphs = append(s.glyphs, map[uint16]bool{})
+
+		family, style, options := "", ff.style, TypographicOptions(0)
+		if ff.family != nil {
+			family, options = ff.family.name, ff.family.options
+			if key, ok := ff.family.styleOf(ff.font); ok {
+				style = key
+			}
+		}
+		s.fonts = append(s.fonts, map[string]interface{}{"name": ff.font.name, "family": family, "style": int(style), "options": int(options)})
+	}
+
+	s.faceList = append(s.faceList, ff)
+	s.faces = append(s.faces, map[string]interface{}{
+		"font":       font,
+		"size":       ff.size,
+		"style":      int(ff.style),
+		"variant":    int(ff.variant),
+		"color":      sceneColor(ff.color),
+		"deco":       decos,
+		"effects":    effects,
+		"scale":      ff.scale,
+		"voffset":    ff.voffset,
+		"fauxBold":   ff.fauxBold,
+		"fauxItalic": ff.fauxItalic,
+		"vertical":   ff.vertical,
+		"hinting":    int(ff.hinting),
+		"dpm":        ff.dpm,
+		"features":   features,
+	})
+	return len(s.faceList) - 1, nil
+}
+
+func (s *sceneWriter) addGlyphs(span textSpan) {
+	glyphs := s.glyphs[s.fontIndex[span.ff.font]]
+	buffer := &sfnt.Buffer{}
+	for _, r := range span.text {
+		if index, err := span.ff.font.glyphIndex(buffer, r); err == nil {
+			glyphs[uint16(index)] = true
+		}
+	}
+	for _, cluster := range span.glyphClusters() {
+		for _, glyph := range cluster.glyphs {
+			glyphs[glyph.ID] = true // substituted by shaping
+		}
+	}
+}
+
+// embedFonts adds the data of the fonts, which are subset when enabled.
+func (s *sceneWriter) embedFonts() ([]interface{}, error) {
+	for i, font := range s.fontList {
+		data := font.raw
+		if s.opts.SubsetFonts {
+			var err error
+			if data, _, err = canvasFont.ToSFNT(font.raw); err != nil {
+				return nil, err
+			}
+			glyphs := []uint16{}
+			for glyph := range s.glyphs[i] {
+				glyphs = append(glyphs, glyph)
+			}
+			if data, err = canvasFont.SubsetSFNT(data, glyphs); err != nil {
+				return nil, fmt.Errorf("font %s: %w", font.name, err)
+			}
+		}
+		s.fonts[i].(map[string]interface{})["data"] = data
+	}
+	return s.fonts, nil
+}
+
+// LoadScene loads a canvas from a scene file, see ReadScene.
+func LoadScene(filename string) (*Canvas, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadScene(f)
+}
+
+// ReadScene reads a canvas from a scene as written by WriteScene.
+func ReadScene(r io.Reader) (*Canvas, error) {
+	return ReadSceneWithLimits(r, Limits{})
+}
+
+// ReadSceneWithLimits reads a canvas like ReadScene and enforces the limits on the path segments, the font sizes of texts, and the size of images, which are checked before they are decoded. It returns an error wrapping ErrLimitExceeded when a limit is exceeded.
+func ReadSceneWithLimits(r io.Reader, limits Limits) (*Canvas, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	d := &cborDecoder{b: b}
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("bad scene: %w", err)
+	} else if d.pos != len(b) {
+		return nil, fmt.Errorf("bad scene: trailing data")
+	}
+
+	s := &sceneReader{budget: newBudget(limits)}
+	scene := s.obj(v, "scene")
+	if version := s.int(scene, "version"); s.err == nil && (version < 1 || sceneVersion < version) {
+		return nil, fmt.Errorf("bad scene: unsupported version %d", version)
+	}
+	width, height := s.num(scene, "width"), s.num(scene, "height")
+	if s.err == nil && (width < 0.0 || height < 0.0) {
+		return nil, fmt.Errorf("bad scene: negative size")
+	}
+
+	families := map[string]*FontFamily{}
+	for _, item := range s.list(scene, "fonts") {
+		v := s.obj(item, "font")
+		if s.err != nil {
+			break
+		}
+		font, err := parseFont(s.str(v, "name"), s.bytes(v, "data"))
+		if err != nil {
+			return nil, fmt.Errorf("bad scene: font %d: %w", len(s.fonts), err)
+		}
+		name := s.str(v, "family")
+		family, ok := families[name]
+		if !ok {
+			family = NewFontFamily(name)
+			family.options = TypographicOptions(s.int(v, "options"))
+			families[name] = family
+		}
+		family.fonts[FontStyle(s.int(v, "style"))] = font
+		font.Use(family.options)
+		s.fonts = append(s.fonts, font)
+		s.families = append(s.families, family)
+	}
+	for _, item := range s.list(scene, "faces") {
+		s.faces = append(s.faces, s.face(item))
+	}
+
+	c := New(width, height)
+	for _, item := range s.list(scene, "layers") {
+		v := s.obj(item, "layer")
+		if s.err != nil {
+			break
+		}
+		m := Identity
+		if _, ok := v["matrix"]; ok {
+			f := s.nums(v, "matrix", 6)
+			if s.err == nil {
+				m = Matrix{{f[0], f[2], f[4]}, {f[1], f[3], f[5]}}
+			}
+		}
+		link := s.str(v, "link")
+		if _, ok := v["path"]; ok {
+			path := s.path(v["path"])
+			style := s.style(v["style"])
+			if s.err == nil && !s.budget.addPath(path, style, m) {
+				return nil, s.budget.err
+			}
+			c.layers = append(c.layers, layer{path: path, m: m, style: style, link: link})
+		} else if _, ok := v["text"]; ok {
+			text := s.text(v["text"])
+			c.layers = append(c.layers, layer{text: text, m: m, link: link})
+		} else if _, ok := v["image"]; ok {
+			img := s.image(v["image"])
+			c.layers = append(c.layers, layer{img: img, m: m, link: link})
+		} else {
+			s.fail("layer %d should have a path, text, or image", len(c.layers))
+		}
+		if s.err != nil {
+			break
+		}
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return c, nil
+}
+
+// sceneReader decodes the values of a scene, where the first error is kept and following values are zero.
+type sceneReader struct {
+	budget   *budget
+	fonts    []*Font
+	families []*FontFamily
+	faces    []FontFace
+	err      error
+}
+
+func (s *sceneReader) fail(format string, args ...interface{}) {
+	if s.err == nil {
+		s.err = fmt.Errorf("bad scene: "+format, args...)
+	}
+}
+
+func (s *sceneReader) obj(v interface{}, name string) map[string]interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		s.fail("%s should be a map", name)
+	}
+	return m
+}
+
+func (s *sceneReader) num(m map[string]interface{}, key string) float64 {
+	switch v := m[key].(type) {
+	case nil:
+		return 0.0 // missing
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	}
+	s.fail("%s should be a number", key)
+	return 0.0
+}
+
+func (s *sceneReader) int(m map[string]interface{}, key string) int {
+	v, ok := m[key].(int)
+	if !ok && m[key] != nil {
+		s.fail("%s should be an integer", key)
+	}
+	return v
+}
+
+func (s *sceneReader) str(m map[string]interface{}, key string) string {
+	v, ok := m[key].(string)
+	if !ok && m[key] != nil {
+		s.fail("%s should be a string", key)
+	}
+	return v
+}
+
+func (s *sceneReader) bool(m map[string]interface{}, key string) bool {
+	v, ok := m[key].(bool)
+	if !ok && m[key] != nil {
+		s.fail("%s should be a boolean", key)
+	}
+	return v
+}
+
+func (s *sceneReader) bytes(m map[string]interface{}, key string) []byte {
+	v, ok := m[key].([]byte)
+	if !ok {
+		s.fail("%s should be bytes", key)
+	}
+	return v
+}
+
+func (s *sceneReader) list(m map[string]interface{}, key string) []interface{} {
+	v, ok := m[key].([]interface{})
+	if !ok && m[key] != nil {
+		s.fail("%s should be an array", key)
+	}
+	return v
+}
+
+// nums returns an array of numbers, which must have length n if positive.
+func (s *sceneReader) nums(m map[string]interface{}, key string, n int) []float64 {
+	items := s.list(m, key)
+	if 0 < n && len(items) != n {
+		s.fail("%s should have %d numbers", key, n)
+		return make([]float64, n)
+	}
+	f := make([]float64, len(items))
+	for i, item := range items {
+		f[i] = s.num(map[string]interface{}{key: item}, key)
+	}
+	return f
+}
+
+func (s *sceneReader) color(m map[string]interface{}, key string) color.RGBA {
+	v, ok := m[key].([]byte)
+	if !ok || len(v) != 4 {
+		s.fail("%s should be a color", key)
+		return color.RGBA{}
+	}
+	return color.RGBA{v[0], v[1], v[2], v[3]}
+}
+
+func (s *sceneReader) point(m map[string]interface{}, key string) Point {
+	f := s.nums(m, key, 2)
+	return Point{f[0], f[1]}
+}
+
+func (s *sceneReader) path(v interface{}) *Path {
+	m := s.obj(v, "path")
+	cmds, args := s.str(m, "cmds"), s.nums(m, "args", 0)
+	n := map[byte]int{'M': 2, 'L': 2, 'Q': 4, 'C': 6, 'A': 7, 'Z': 0}
+	p := &Path{}
+	for i := 0; i < len(cmds) && s.err == nil; i++ {
+		cmd := cmds[i]
+		if _, ok := n[cmd]; !ok {
+			s.fail("unknown path command '%c'", cmd)
+			break
+		} else if len(args) < n[cmd] {
+			s.fail("too few path arguments")
+			break
+		}
+		f := args[:n[cmd]]
+		args = args[n[cmd]:]
+		switch cmd {
+		case 'M':
+			p.MoveTo(f[0], f[1])
+		case 'L':
+			p.LineTo(f[0], f[1])
+		case 'Q':
+			p.QuadTo(f[0], f[1], f[2], f[3])
+		case 'C':
+			p.CubeTo(f[0], f[1], f[2], f[3], f[4], f[5])
+		case 'A':
+			p.ArcTo(f[0], f[1], f[2], f[3] == 1.0, f[4] == 1.0, f[5], f[6])
+		case 'Z':
+			p.Close()
+		}
+	}
+	if s.err == nil && len(args) != 0 {
+		s.fail("too many path arguments")
+	}
+	return p
+}
+
+func (s *sceneReader) spotColor(v interface{}) *SpotColor {
+	if v == nil {
+		return nil
+	}
+	m := s.obj(v, "spot color")
+	cmyk := s.nums(m, "cmyk", 4)
+	return &SpotColor{s.str(m, "name"), [4]float64{cmyk[0], cmyk[1], cmyk[2], cmyk[3]}, s.num(m, "tint")}
+}
+
+func (s *sceneReader) mesh(v interface{}) *MeshGradient {
+	if v == nil {
+		return nil
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		s.fail("fillMesh should be an array")
+	}
+	mesh := NewMeshGradient()
+	for _, item := range items {
+		m := s.obj(item, "mesh patch")
+		f := s.nums(m, "points", 24)
+		colors := s.list(m, "colors")
+		if s.err != nil {
+			return mesh
+		} else if len(colors) != 4 {
+			s.fail("colors should have 4 colors")
+			return mesh
+		}
+		patch := MeshPatch{}
+		for i := range patch.Points {
+			patch.Points[i] = Point{f[2*i], f[2*i+1]}
+		}
+		for i := range patch.Colors {
+			patch.Colors[i] = s.color(map[string]interface{}{"colors": colors[i]}, "colors")
+		}
+		mesh.Patches = append(mesh.Patches, patch)
+	}
+	return mesh
+}
+
+func (s *sceneReader) joiner(v interface{}) joinerJSON {
+	m := s.obj(v, "joiner")
+	j := joinerJSON{Type: s.str(m, "type")}
+	if _, ok := m["limit"]; ok {
+		limit := s.num(m, "limit")
+		j.Limit = &limit
+	}
+	if gap, ok := m["gap"]; ok && s.err == nil {
+		g := s.joiner(gap)
+		j.Gap = &g
+	}
+	return j
+}
+
+func (s *sceneReader) style(v interface{}) Style {
+	m := s.obj(v, "style")
+	style := Style{
+		FillColor:   s.color(m, "fill"),
+		StrokeColor: s.color(m, "stroke"),
+		StrokeWidth: s.num(m, "strokeWidth"),
+		DashOffset:  s.num(m, "dashOffset"),
+		Dashes:      s.nums(m, "dashes", 0),
+		FillSpot:    s.spotColor(m["fillSpot"]),
+		StrokeSpot:  s.spotColor(m["strokeSpot"]),
+		FillMesh:    s.mesh(m["fillMesh"]),
+		Overprint:   s.bool(m, "overprint"),
+	}
+	if s.err != nil {
+		return style
+	}
+
+	var err error
+	if style.StrokeCapper, err = capperJSON(s.str(m, "strokeCap")); err != nil {
+		s.fail("%v", err)
+	} else if joiner := s.joiner(m["strokeJoin"]); s.err == nil {
+		if style.StrokeJoiner, err = joiner.joiner(); err != nil {
+			s.fail("%v", err)
+		}
+	}
+	switch fillRule := s.str(m, "fillRule"); fillRule {
+	case "nonzero":
+		style.FillRule = NonZero
+	case "evenodd":
+		style.FillRule = EvenOdd
+	default:
+		s.fail("unknown fill rule '%s'", fillRule)
+	}
+	return style
+}
+
+func (s *sceneReader) face(v interface{}) FontFace {
+	m := s.obj(v, "face")
+	i := s.int(m, "font")
+	if s.err != nil {
+		return FontFace{}
+	} else if i < 0 || len(s.fonts) <= i {
+		s.fail("unknown font %d", i)
+		return FontFace{}
+	}
+	ff := FontFace{
+		family:     s.families[i],
+		font:       s.fonts[i],
+		size:       s.num(m, "size"),
+		style:      FontStyle(s.int(m, "style")),
+		variant:    FontVariant(s.int(m, "variant")),
+		color:      s.color(m, "color"),
+		scale:      s.num(m, "scale"),
+		voffset:    s.num(m, "voffset"),
+		fauxBold:   s.num(m, "fauxBold"),
+		fauxItalic: s.num(m, "fauxItalic"),
+		vertical:   s.bool(m, "vertical"),
+		hinting:    FontHinting(s.int(m, "hinting")),
+		dpm:        s.num(m, "dpm"),
+	}
+	for _, item := range s.list(m, "features") {
+		tag, ok := item.(string)
+		if !ok {
+			s.fail("features should be strings")
+			break
+		}
+		ff.features = append(ff.features, tag)
+	}
+	if s.err == nil && !s.budget.addFontSize(ff.size*ptPerMm) {
+		s.err = s.budget.err
+	}
+Decorators:
+	for _, item := range s.list(m, "deco") {
+		name, _ := item.(string)
+		for _, d := range sceneDecorators {
+			if d.name == name {
+				ff.deco = append(ff.deco, d.deco)
+				continue Decorators
+			}
+		}
+		s.fail("unknown font decorator '%v'", item)
+	}
+	for _, item := range s.list(m, "effects") {
+		e := s.obj(item, "effect")
+		switch typ := s.str(e, "type"); typ {
+		case "shadow":
+			ff.effects = append(ff.effects, TextShadow{s.point(e, "offset"), s.color(e, "color")})
+		case "outline":
+			ff.effects = append(ff.effects, TextOutline{s.num(e, "width"), s.color(e, "color")})
+		case "glow":
+			ff.effects = append(ff.effects, TextGlow{s.num(e, "radius"), s.point(e, "offset"), s.color(e, "color"), s.num(e, "resolution")})
+		default:
+			s.fail("unknown text effect '%s'", typ)
+		}
+	}
+	return ff
+}
+
+func (s *sceneReader) faceAt(m map[string]interface{}) FontFace {
+	i := s.int(m, "face")
+	if s.err != nil {
+		return FontFace{}
+	} else if i < 0 || len(s.faces) <= i {
+		s.fail("unknown face %d", i)
+		return FontFace{}
+	}
+	return s.faces[i]
+}
+
+func (s *sceneReader) text(v interface{}) *Text {
+	t := &Text{fonts: map[*Font]bool{}}
+	obj := s.obj(v, "text")
+	t.vertical, t.width = s.bool(obj, "vertical"), s.num(obj, "width")
+	for _, item := range s.list(obj, "lines") {
+		m := s.obj(item, "line")
+		if s.err != nil {
+			break
+		}
+		l := line{y: s.num(m, "y")}
+		for _, item := range s.list(m, "spans") {
+			m := s.obj(item, "span")
+			if s.err != nil {
+				break
+			}
+			span := textSpan{
+				ff:              s.faceAt(m),
+				text:            s.str(m, "text"),
+				width:           s.num(m, "width"),
+				dx:              s.num(m, "dx"),
+				sentenceSpacing: s.num(m, "sentenceSpacing"),
+				wordSpacing:     s.num(m, "wordSpacing"),
+				glyphSpacing:    s.num(m, "glyphSpacing"),
+				glyphStretch:    s.num(m, "glyphStretch"),
+				level:           uint8(s.int(m, "level")),
+			}
+			boundaries := s.list(m, "boundaries")
+			if len(boundaries)%3 != 0 {
+				s.fail("boundaries should be triples")
+			}
+			for i := 0; i+2 < len(boundaries) && s.err == nil; i += 3 {
+				b := map[string]interface{}{"kind": boundaries[i], "pos": boundaries[i+1], "size": boundaries[i+2]}
+				span.boundaries = append(span.boundaries, textBoundary{textBoundaryKind(s.int(b, "kind")), s.int(b, "pos"), s.int(b, "size")})
+			}
+			span.altText, span.altWidth, span.altBoundaries = span.text, span.width, span.boundaries
+			if s.err == nil {
+				t.fonts[span.ff.font] = true
+			}
+			l.spans = append(l.spans, span)
+		}
+		for _, item := range s.list(m, "decos") {
+			m := s.obj(item, "decoration")
+			if s.err != nil {
+				break
+			}
+			l.decos = append(l.decos, decoSpan{s.faceAt(m), s.num(m, "x0"), s.num(m, "x1")})
+		}
+		t.lines = append(t.lines, l)
+	}
+	return t
+}
+
+func (s *sceneReader) image(v interface{}) image.Image {
+	m := s.obj(v, "image")
+	if s.err != nil {
+		return nil
+	}
+	_, isJPEG := m["jpeg"]
+	data := []byte{}
+	if isJPEG {
+		data = s.bytes(m, "jpeg")
+	} else {
+		data = s.bytes(m, "png")
+	}
+	if s.err != nil {
+		return nil
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		s.fail("image: %v", err)
+		return nil
+	} else if !s.budget.addPixels(config.Width, config.Height) {
+		s.err = s.budget.err
+		return nil
+	}
+	if isJPEG {
+		jpg, err := ReadJPEG(bytes.NewReader(data))
+		if err != nil {
+			s.fail("image: %v", err)
+			return nil
+		}
+		jpg.Orientation = s.int(m, "orientation")
+		if jpg.Orientation < 1 || 8 < jpg.Orientation {
+			s.fail("bad JPEG orientation %d", jpg.Orientation)
+		}
+		return jpg
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		s.fail("image: %v", err)
+		return nil
+	}
+	return img
+}
@@ -0,0 +1,106 @@
+package canvas
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestScene(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	if err := family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular); err != nil {
+		test.Error(t, err)
+		return
+	}
+	face := family.Face(12.0*ptPerMm, Blue, FontRegular, FontNormal, FontUnderline)
+	face.effects = []TextEffect{TextShadow{Point{.5, -.5}, Red}}
+
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, Green)
+
+	c := New(50.0, 30.0)
+	ctx := NewContext(c)
+	ctx.SetFillColor(Red)
+	ctx.SetStrokeColor(Black)
+	ctx.SetStrokeWidth(.5)
+	ctx.SetDashes(.5, 1.0, 2.0)
+	ctx.Style.StrokeJoiner = MiterJoin
+	ctx.Style.FillSpot = &SpotColor{"PANTONE 185 C", [4]float64{0.0, .93, .79, 0.0}, 1.0}
+	ctx.DrawPath(5.0, 5.0, MustParseSVG("M0 0L10 0Q15 5 10 10C5 15 0 10 0 5A2 3 30 1 0 5 0z"))
+	ctx.DrawText(5.0, 25.0, NewTextLine(face, "Office fi", Left))
+	ctx.SetLink("https://example.com/")
+	ctx.DrawImage(40.0, 0.0, img, 1.0)
+
+	b := &bytes.Buffer{}
+	test.Error(t, c.WriteScene(b, SceneOptions{}))
+	test.T(t, b.Bytes()[:3], []byte{0xD9, 0xD9, 0xF7}) // self-describe tag
+
+	c2, err := ReadScene(bytes.NewReader(b.Bytes()))
+	test.Error(t, err)
+	test.T(t, c2.W, c.W)
+	test.T(t, c2.H, c.H)
+	test.T(t, len(c2.layers), 4) // with the text shadow
+	test.That(t, c2.layers[0].path.Equals(c.layers[0].path))
+	test.T(t, c2.layers[0].m, c.layers[0].m)
+	test.T(t, c2.layers[0].style.Dashes, c.layers[0].style.Dashes)
+	test.T(t, *c2.layers[0].style.FillSpot, *c.layers[0].style.FillSpot)
+	test.T(t, c2.layers[0].style.StrokeJoiner, MiterJoin)
+	ff := c2.layers[2].text.lines[0].spans[0].ff
+	ff.font = face.font
+	test.That(t, ff.Equals(face))
+	test.T(t, ff.family.name, "dejavu-serif")
+	test.T(t, c2.layers[2].text.lines[0].spans[0].text, c.layers[2].text.lines[0].spans[0].text)
+	test.T(t, len(c2.layers[2].text.lines[0].decos), 1)
+	test.T(t, color.RGBAModel.Convert(c2.layers[3].img.At(0, 0)), color.Color(Green))
+	test.T(t, c2.layers[3].link, "https://example.com/")
+
+	// both canvases render the same
+	test.T(t, c2.WriteImage(2.0).Pix, c.WriteImage(2.0).Pix)
+
+	// subset fonts are smaller and render the same
+	subset := &bytes.Buffer{}
+	test.Error(t, c.WriteScene(subset, SceneOptions{SubsetFonts: true}))
+	test.That(t, subset.Len() < b.Len()/2)
+	c3, err := ReadScene(bytes.NewReader(subset.Bytes()))
+	test.Error(t, err)
+	test.T(t, c3.WriteImage(2.0).Pix, c.WriteImage(2.0).Pix)
+
+	_, err = ReadSceneWithLimits(bytes.NewReader(b.Bytes()), Limits{MaxFontSize: 10.0})
+	test.That(t, err != nil)
+}
+
+func TestSceneErrors(t *testing.T) {
+	scene := func(layers ...interface{}) []byte {
+		return appendCBOR(nil, map[string]interface{}{"version": 1, "width": 10.0, "height": 10.0, "layers": layers})
+	}
+	var tests = []struct {
+		b   []byte
+		err string
+	}{
+		{[]byte{}, "bad scene: unexpected end of data"},
+		{[]byte{0x9F}, "bad scene: unsupported length"},
+		{[]byte{0x5A, 0xFF, 0xFF, 0xFF, 0xFF}, "bad scene: unexpected end of data"},
+		{append(scene(), 0x00), "bad scene: trailing data"},
+		{appendCBOR(nil, []interface{}{}), "bad scene: scene should be a map"},
+		{appendCBOR(nil, map[string]interface{}{"version": 2}), "bad scene: unsupported version 2"},
+		{scene(map[string]interface{}{}), "bad scene: layer 0 should have a path, text, or image"},
+		{scene(map[string]interface{}{"path": map[string]interface{}{"cmds": "MX", "args": []float64{0.0, 0.0}}}), "bad scene: unknown path command 'X'"},
+		{scene(map[string]interface{}{"path": map[string]interface{}{"cmds": "ML", "args": []float64{0.0, 0.0, 1.0}}}), "bad scene: too few path arguments"},
+		{scene(map[string]interface{}{"matrix": []float64{1.0}}), "bad scene: matrix should have 6 numbers"},
+		{scene(map[string]interface{}{"text": map[string]interface{}{"lines": []interface{}{map[string]interface{}{"spans": []interface{}{map[string]interface{}{"face": 0}}}}}}), "bad scene: unknown face 0"},
+		{scene(map[string]interface{}{"image": map[string]interface{}{"png": []byte("png")}}), "bad scene: image: image: unknown format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err, func(t *testing.T) {
+			_, err := ReadScene(bytes.NewReader(tt.b))
+			if err == nil {
+				test.Fail(t, "expected error")
+				return
+			}
+			test.T(t, err.Error(), tt.err)
+		})
+	}
+}
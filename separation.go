@@ -0,0 +1,146 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// SpotColor is a named ink, such as a Pantone color, that is printed on its own plate instead of being mixed from the process inks cyan, magenta, yellow, and black. The tint is the fraction of ink in [0,1]. The process color of the ink at full tint is used by printers and viewers that do not have the ink.
+type SpotColor struct {
+	Name string
+	CMYK [4]float64
+	Tint float64
+}
+
+// RGBA returns the appearance of the tint of the spot color on screen, converted from its process color.
+func (spot SpotColor) RGBA() (r, g, b, a uint32) {
+	rgb := func(c float64) uint32 {
+		return uint32((1.0-c*spot.Tint)*(1.0-spot.CMYK[3]*spot.Tint)*0xffff + 0.5)
+	}
+	return rgb(spot.CMYK[0]), rgb(spot.CMYK[1]), rgb(spot.CMYK[2]), 0xffff
+}
+
+// SetFillSpotColor sets the spot color to be used for filling operations by PDF and EPS, and its appearance as the fill color for other renderers.
+func (c *Context) SetFillSpotColor(spot SpotColor) {
+	c.SetFillColor(spot)
+	c.Style.FillSpot = &spot
+}
+
+// SetStrokeSpotColor sets the spot color to be used for stroking operations by PDF and EPS, and its appearance as the stroke color for other renderers.
+func (c *Context) SetStrokeSpotColor(spot SpotColor) {
+	c.SetStrokeColor(spot)
+	c.Style.StrokeSpot = &spot
+}
+
+// SetOverprint sets whether fills and strokes overprint the inks underneath by PDF and EPS, so that plates on which they have no ink are not knocked out, such as for black text on a colored background.
+func (c *Context) SetOverprint(overprint bool) {
+	c.Style.Overprint = overprint
+}
+
+// ProcessPlates are the names of the plates of the process inks, see Canvas.WriteSeparation.
+var ProcessPlates = []string{"Cyan", "Magenta", "Yellow", "Black"}
+
+// rgbToCMYK returns the process inks of the color with the naive conversion that uses as much black as possible.
+func rgbToCMYK(col color.RGBA) [4]float64 {
+	if col.A == 0 {
+		return [4]float64{}
+	}
+	r, g, b := float64(col.R)/float64(col.A), float64(col.G)/float64(col.A), float64(col.B)/float64(col.A)
+	k := 1.0 - r
+	if 1.0-g < k {
+		k = 1.0 - g
+	}
+	if 1.0-b < k {
+		k = 1.0 - b
+	}
+	if 1.0-Epsilon < k {
+		return [4]float64{0.0, 0.0, 0.0, 1.0}
+	}
+	return [4]float64{(1.0 - r - k) / (1.0 - k), (1.0 - g - k) / (1.0 - k), (1.0 - b - k) / (1.0 - k), k}
+}
+
+// Plates returns the names of the plates that the canvas is printed on, which are the process plates followed by the spot colors in alphabetical order.
+func (c *Canvas) Plates() []string {
+	c.merge()
+	spots := map[string]bool{}
+	for _, l := range c.layers {
+		if l.path != nil && l.style.FillSpot != nil {
+			spots[l.style.FillSpot.Name] = true
+		}
+		if l.path != nil && l.style.StrokeSpot != nil {
+			spots[l.style.StrokeSpot.Name] = true
+		}
+	}
+	names := []string{}
+	for name := range spots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append(append([]string{}, ProcessPlates...), names...)
+}
+
+// WriteSeparation rasterizes the plate of a process ink or spot color as a preview of the separation for printing, where black is full coverage by the ink, see Plates. Colors are converted to process inks naively and spot colors are only printed on their own plate. Drawing knocks out the inks underneath, except where it overprints with no ink on the plate.
+func (c *Canvas) WriteSeparation(dpm float64, plate string) *image.Gray {
+	c.merge()
+	process := -1
+	for i, name := range ProcessPlates {
+		if name == plate {
+			process = i
+		}
+	}
+	ink := func(col color.RGBA, spot *SpotColor) (color.RGBA, bool) {
+		v := 0.0
+		if spot != nil {
+			if spot.Name == plate {
+				v = spot.Tint
+			}
+		} else if 0 <= process {
+			v = rgbToCMYK(col)[process]
+		}
+		y := uint8(v*float64(col.A) + 0.5)
+		return color.RGBA{y, y, y, col.A}, v != 0.0
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, int(c.W*dpm+0.5), int(c.H*dpm+0.5)))
+	r := NewRasterizer(img, dpm)
+	for _, l := range c.layers {
+		if l.path != nil {
+			style := l.style
+			fill, hasFill := ink(style.FillColor, style.FillSpot)
+			stroke, hasStroke := ink(style.StrokeColor, style.StrokeSpot)
+			if style.Overprint && !hasFill {
+				fill = Transparent
+			}
+			if style.Overprint && !hasStroke {
+				stroke = Transparent
+			}
+			style.FillColor, style.StrokeColor = fill, stroke
+			r.RenderPath(l.path, style, l.m)
+		} else if l.text != nil {
+			paths, colors := l.text.ToPaths()
+			for i, path := range paths {
+				style := DefaultStyle
+				style.FillColor, _ = ink(colors[i], nil)
+				r.RenderPath(path, style, l.m)
+			}
+		} else if l.img != nil {
+			bounds := l.img.Bounds()
+			plateImg := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+			for y := 0; y < bounds.Dy(); y++ {
+				for x := 0; x < bounds.Dx(); x++ {
+					col := color.RGBAModel.Convert(l.img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.RGBA)
+					col, _ = ink(col, nil)
+					plateImg.SetRGBA(x, y, col)
+				}
+			}
+			r.RenderImage(plateImg, l.m)
+		}
+	}
+
+	gray := image.NewGray(img.Bounds())
+	for i := range gray.Pix {
+		gray.Pix[i] = 255 - img.Pix[4*i]
+	}
+	return gray
+}
@@ -0,0 +1,51 @@
+package canvas
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestSpotColor(t *testing.T) {
+	spot := SpotColor{"Orange", [4]float64{0.0, 0.5, 1.0, 0.0}, 1.0}
+	r, g, b, a := spot.RGBA()
+	test.T(t, [4]uint32{r, g, b, a}, [4]uint32{0xffff, 0x8000, 0x0000, 0xffff})
+	spot.Tint = 0.5
+	r, g, b, _ = spot.RGBA()
+	test.T(t, [3]uint32{r, g, b}, [3]uint32{0xffff, 0xbfff, 0x8000})
+
+	test.T(t, rgbToCMYK(Red), [4]float64{0.0, 1.0, 1.0, 0.0})
+	test.T(t, rgbToCMYK(Black), [4]float64{0.0, 0.0, 0.0, 1.0})
+	test.T(t, rgbToCMYK(White), [4]float64{0.0, 0.0, 0.0, 0.0})
+	test.T(t, rgbToCMYK(color.RGBA{0, 128, 128, 255}), [4]float64{1.0, 0.0, 0.0, 1.0 - 128.0/255.0})
+}
+
+func TestWriteSeparation(t *testing.T) {
+	spot := SpotColor{"PANTONE 185 C", [4]float64{0.0, 0.93, 0.79, 0.0}, 1.0}
+	c := New(30.0, 10.0)
+	ctx := NewContext(c)
+	ctx.SetFillColor(color.RGBA{0, 255, 255, 255}) // cyan
+	ctx.DrawPath(0.0, 0.0, Rectangle(30.0, 10.0))
+	ctx.SetFillSpotColor(spot)
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0)) // knocks out
+	ctx.SetOverprint(true)
+	ctx.DrawPath(10.0, 0.0, Rectangle(10.0, 10.0))
+	ctx.SetFillColor(Black)
+	ctx.DrawPath(20.0, 0.0, Rectangle(10.0, 10.0))
+	test.T(t, c.Plates(), []string{"Cyan", "Magenta", "Yellow", "Black", "PANTONE 185 C"})
+
+	cyan := c.WriteSeparation(1.0, "Cyan")
+	test.T(t, cyan.GrayAt(5, 5).Y, uint8(255))
+	test.T(t, cyan.GrayAt(15, 5).Y, uint8(0))
+	test.T(t, cyan.GrayAt(25, 5).Y, uint8(0))
+
+	pantone := c.WriteSeparation(1.0, "PANTONE 185 C")
+	test.T(t, pantone.GrayAt(5, 5).Y, uint8(0))
+	test.T(t, pantone.GrayAt(15, 5).Y, uint8(0))
+	test.T(t, pantone.GrayAt(25, 5).Y, uint8(255))
+
+	black := c.WriteSeparation(1.0, "Black")
+	test.T(t, black.GrayAt(15, 5).Y, uint8(255))
+	test.T(t, black.GrayAt(25, 5).Y, uint8(0))
+}
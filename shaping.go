@@ -0,0 +1,380 @@
+package canvas
+
+import (
+	"strings"
+	"unicode"
+
+	canvasFont "github.com/tdewolff/canvas/font"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Glyph is a glyph of a shaped glyph run, see FontFace.Shape.
+type Glyph struct {
+	ID       uint16  // glyph index of the font
+	Cluster  int     // byte position in the text of the first character of the cluster of the glyph, where marks belong to the cluster of their base
+	XAdvance float64 // in mm
+	XOffset  float64 // offset from the pen position in mm, eg. of marks attached to their base glyph
+	YOffset  float64
+	Upright  bool // upright glyph of vertical text, where XAdvance is its vertical advance, see FontFace.Vertical
+}
+
+// shapingScript is a script that is shaped with the features of its OpenType script tags, in order of preference.
+type shapingScript struct {
+	table *unicode.RangeTable
+	tags  []string
+}
+
+var shapingScripts = []shapingScript{
+	{unicode.Latin, []string{"latn"}},
+	{unicode.Arabic, []string{"arab"}},
+	{unicode.Hebrew, []string{"hebr"}},
+	{unicode.Devanagari, []string{"dev2", "deva"}},
+	{unicode.Bengali, []string{"bng2", "beng"}},
+	{unicode.Gurmukhi, []string{"gur2", "guru"}},
+	{unicode.Gujarati, []string{"gjr2", "gujr"}},
+	{unicode.Oriya, []string{"ory2", "orya"}},
+	{unicode.Tamil, []string{"tml2", "taml"}},
+	{unicode.Telugu, []string{"tel2", "telu"}},
+	{unicode.Kannada, []string{"knd2", "knda"}},
+	{unicode.Malayalam, []string{"mlm2", "mlym"}},
+	{unicode.Thai, []string{"thai"}},
+	{unicode.Lao, []string{"lao "}},
+	{unicode.Greek, []string{"grek"}},
+	{unicode.Cyrillic, []string{"cyrl"}},
+	{unicode.Armenian, []string{"armn"}},
+	{unicode.Georgian, []string{"geor"}},
+	{unicode.Hangul, []string{"hang"}},
+	{unicode.Hiragana, []string{"kana"}},
+	{unicode.Katakana, []string{"kana"}},
+	{unicode.Han, []string{"hani"}},
+}
+
+// scriptIndex returns the index of the script of a rune in shapingScripts, or -1 for characters that are common to all scripts, such as spaces, digits, punctuation, and combining marks.
+func scriptIndex(r rune) int {
+	if r < 0x80 && !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z') || unicode.In(r, unicode.Common, unicode.Inherited) {
+		return -1
+	}
+	for i, script := range shapingScripts {
+		if unicode.Is(script.table, r) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isIndicScript returns true for the scripts that are shaped with the Indic features.
+func isIndicScript(script int) bool {
+	return 3 <= script && script <= 11
+}
+
+// masks of the glyphs for the features that apply to them, where Arabic letters get the feature of their joining form
+const (
+	globalMask uint32 = 1 << iota
+	isolMask
+	finaMask
+	mediMask
+	initMask
+	uprightMask // upright characters of vertical text
+)
+
+// Shape shapes a string into a run of glyphs in logical order, by the substitutions of the GSUB table and the positioning of the GPOS table of the font, or the kern table if it has no kerning in GPOS. The string is shaped in runs of the same script, where Arabic letters take their joining forms and pre-base matras of Indic scripts are moved before their consonant cluster, but the reph is not reordered. Required ligatures, the ligatures that are enabled by FontFamily.Use, and the features of FontFace.WithFeatures are applied, and marks are attached to their base glyphs. Upright glyphs of vertical font faces advance by their vertical advance, see FontFace.Vertical. Glyphs of right-to-left scripts are returned in logical order and are not mirrored.
+func (ff FontFace) Shape(s string) []Glyph {
+	return ff.shape(s, false)
+}
+
+// WithFeatures returns the font face with OpenType features of the GSUB and GPOS tables that are applied when its text is shaped, in addition to the default features such as ligatures and kerning, eg. face.WithFeatures("smcp", "onum", "tnum", "ss01") for small capitals, oldstyle and tabular figures, and the first stylistic set. Default features are disabled by a leading minus, such as "-liga" or "-kern". Features that the font does not have are ignored, see Font.Features. Alternate substitutions use their first alternate.
+func (ff FontFace) WithFeatures(features ...string) FontFace {
+	ff.features = append(ff.features[:len(ff.features):len(ff.features)], features...)
+	return ff
+}
+
+// withFeatures returns the features with those enabled by the font face added and those disabled removed, where the last occurrence of a feature takes precedence, see WithFeatures.
+func (ff FontFace) withFeatures(features []canvasFont.Feature) []canvasFont.Feature {
+	if len(ff.features) == 0 {
+		return features
+	}
+	enabled := []canvasFont.Feature{}
+	for _, feature := range features {
+		if !ff.disables(feature.Tag) {
+			enabled = append(enabled, feature)
+		}
+	}
+	for i, tag := range ff.features {
+		if !strings.HasPrefix(tag, "-") && ff.lastFeature(tag) == i {
+			enabled = append(enabled, canvasFont.Feature{Tag: tag, Mask: globalMask})
+		}
+	}
+	return enabled
+}
+
+// disables returns true if the feature is disabled for the font face, see WithFeatures.
+func (ff FontFace) disables(tag string) bool {
+	i := ff.lastFeature(tag)
+	return i != -1 && strings.HasPrefix(ff.features[i], "-")
+}
+
+// lastFeature returns the index of the last occurrence of the feature in the features of the font face, whether enabled or disabled, or -1 if it does not occur.
+func (ff FontFace) lastFeature(tag string) int {
+	for i := len(ff.features) - 1; 0 <= i; i-- {
+		if strings.TrimPrefix(ff.features[i], "-") == tag {
+			return i
+		}
+	}
+	return -1
+}
+
+// shape shapes a string, where characters with a mirrored glyph are replaced by their mirror in right-to-left text.
+func (ff FontFace) shape(s string, rtl bool) []Glyph {
+	glyphs := []Glyph{}
+	buffer := &sfnt.Buffer{}
+	start, script := 0, -1
+	for i, r := range s {
+		if next := scriptIndex(r); next != -1 && next != script {
+			if script != -1 {
+				glyphs = ff.shapeRun(glyphs, buffer, s, start, i, script, rtl)
+				start = i
+			}
+			script = next
+		}
+	}
+	if script == -1 {
+		script = 0
+	}
+	return ff.shapeRun(glyphs, buffer, s, start, len(s), script, rtl)
+}
+
+// clusters returns the number of clusters of the shaped glyphs of a string, which are the units that are spaced apart by glyph spacing.
+func (ff FontFace) clusters(s string) int {
+	n := 0
+	glyphs := ff.Shape(s)
+	for i, glyph := range glyphs {
+		if i == 0 || glyph.Cluster != glyphs[i-1].Cluster {
+			n++
+		}
+	}
+	return n
+}
+
+// shapeRun appends the glyphs of s[start:end], which is written in a single script.
+func (ff FontFace) shapeRun(glyphs []Glyph, buffer *sfnt.Buffer, s string, start, end, script int, rtl bool) []Glyph {
+	f := ff.font
+	runes, clusters := []rune{}, []int{}
+	for i, r := range s[start:end] {
+		if mirror, ok := bidiMirrors[r]; ok && rtl {
+			r = mirror
+		}
+		runes = append(runes, r)
+		clusters = append(clusters, start+i)
+		if 1 < len(runes) && unicode.In(r, unicode.Mn, unicode.Mc, unicode.Me) {
+			clusters[len(clusters)-1] = clusters[len(clusters)-2] // marks belong to the cluster of their base
+		}
+	}
+	masks := make([]uint32, len(runes))
+	for i := range masks {
+		masks[i] = globalMask
+		if ff.vertical && isUpright(runes[i]) {
+			masks[i] |= uprightMask
+		}
+	}
+	if shapingScripts[script].tags[0] == "arab" {
+		arabicForms(runes, masks)
+	} else if isIndicScript(script) {
+		reorderIndic(runes, clusters)
+	}
+
+	run := make([]canvasFont.Glyph, 0, len(runes))
+	for i, r := range runes {
+		if index, err := f.glyphIndex(buffer, r); err == nil {
+			run = append(run, canvasFont.Glyph{ID: uint16(index), Cluster: clusters[i], Mask: masks[i]})
+		}
+	}
+
+	tag := shapingScripts[script].tags[0]
+	if f.shaper != nil {
+		for _, t := range shapingScripts[script].tags {
+			if f.shaper.HasScript(t) {
+				tag = t
+				break
+			}
+		}
+		features := f.substitutionFeatures(tag, isIndicScript(script))
+		if ff.vertical {
+			features = append(features, shapingFeatures(uprightMask, "vert")...)
+		}
+		run = f.shaper.Substitute(run, tag, ff.withFeatures(features))
+	}
+
+	// advances and positioning in font units
+	units := fixed.I(int(f.sfnt.UnitsPerEm()))
+	advances := make([]int32, len(run))
+	for i := range run {
+		if advance, err := f.sfnt.GlyphAdvance(buffer, sfnt.GlyphIndex(run[i].ID), units, font.HintingNone); err == nil {
+			run[i].XAdvance = int32(advance >> 6)
+		}
+		advances[i] = run[i].XAdvance
+	}
+	if (f.kerning == nil || f.shaper == nil) && !ff.disables("kern") {
+		// kerning of the kern table, or of the GPOS table when its other lookups are broken
+		for i := 1; i < len(run); i++ {
+			if kern, err := f.kern(buffer, sfnt.GlyphIndex(run[i-1].ID), sfnt.GlyphIndex(run[i].ID), units, font.HintingNone); err == nil {
+				run[i-1].XAdvance += int32(kern >> 6)
+			}
+		}
+	}
+	if f.shaper != nil {
+		f.shaper.Position(run, tag, ff.withFeatures(shapingFeatures(globalMask, "kern", "mark", "mkmk", "dist", "abvm", "blwm")))
+	}
+
+	// glyph advances are rounded as by sfnt and adjusted by the positioning, which are rounded to whole pixels for full hinting
+	ppem, hinting := ff.ppem(), ff.advanceHinting()
+	for i, g := range run {
+		glyph := Glyph{
+			ID:      g.ID,
+			Cluster: g.Cluster,
+			XOffset: ff.fromPixels(f.scaleUnits(g.XOffset, ppem)),
+			YOffset: ff.fromPixels(f.scaleUnits(g.YOffset, ppem)),
+		}
+		if g.XAdvance != 0 {
+			if advance, err := f.sfnt.GlyphAdvance(buffer, sfnt.GlyphIndex(g.ID), ppem, hinting); err == nil {
+				glyph.XAdvance = ff.fromPixels(advance)
+			}
+			adjustment := f.scaleUnits(g.XAdvance-advances[i], ppem)
+			if hinting == font.HintingFull {
+				adjustment = (adjustment + 32) &^ 63
+			}
+			glyph.XAdvance += ff.fromPixels(adjustment)
+		}
+		if g.Mask&uprightMask != 0 {
+			// upright glyphs advance vertically and are not positioned by the horizontal features
+			if g.XAdvance != 0 {
+				glyph.XAdvance = ff.verticalAdvance(sfnt.GlyphIndex(g.ID))
+			}
+			glyph.XOffset, glyph.YOffset = 0.0, 0.0
+			glyph.Upright = true
+		}
+		glyphs = append(glyphs, glyph)
+	}
+	return glyphs
+}
+
+// substitutionFeatures returns the features of the GSUB table that are applied to a script.
+func (f *Font) substitutionFeatures(script string, indic bool) []canvasFont.Feature {
+	features := shapingFeatures(globalMask, "ccmp", "locl")
+	if script == "arab" {
+		features = append(features, shapingFeatures(isolMask, "isol")...)
+		features = append(features, shapingFeatures(finaMask, "fina")...)
+		features = append(features, shapingFeatures(mediMask, "medi")...)
+		features = append(features, shapingFeatures(initMask, "init")...)
+		features = append(features, shapingFeatures(globalMask, "calt")...)
+	} else if indic {
+		features = append(features, shapingFeatures(globalMask, "nukt", "akhn", "rphf", "rkrf", "pref", "blwf", "abvf", "half", "pstf", "vatu", "cjct", "pres", "abvs", "blws", "psts", "haln")...)
+	}
+	if f.options&NoRequiredLigatures == 0 {
+		features = append(features, shapingFeatures(globalMask, "rlig")...)
+	}
+	if f.options&CommonLigatures != 0 {
+		features = append(features, shapingFeatures(globalMask, "liga", "clig")...)
+	}
+	if f.options&DiscretionaryLigatures != 0 {
+		features = append(features, shapingFeatures(globalMask, "dlig")...)
+	}
+	if f.options&HistoricalLigatures != 0 {
+		features = append(features, shapingFeatures(globalMask, "hlig")...)
+	}
+	return features
+}
+
+// shapingFeatures returns the features of the tags that apply to glyphs with the mask.
+func shapingFeatures(mask uint32, tags ...string) []canvasFont.Feature {
+	features := make([]canvasFont.Feature, 0, len(tags))
+	for _, tag := range tags {
+		features = append(features, canvasFont.Feature{Tag: tag, Mask: mask})
+	}
+	return features
+}
+
+// arabicForms sets the masks of the Arabic letters to their joining form, where letters join to their neighbours across transparent marks.
+func arabicForms(runes []rune, masks []uint32) {
+	joinsPrev := make([]bool, len(runes))
+	prev := -1
+	for i, r := range runes {
+		if isArabicTransparent(r) {
+			continue
+		}
+		if 0 <= prev && arabicJoinsNext(runes[prev]) && arabicJoinsPrev(r) {
+			joinsPrev[i] = true
+		}
+		prev = i
+	}
+
+	next := false // the next letter joins to the current letter
+	for i := len(runes) - 1; 0 <= i; i-- {
+		r := runes[i]
+		if isArabicTransparent(r) {
+			continue
+		} else if arabicJoinsPrev(r) {
+			if joinsPrev[i] && next {
+				masks[i] |= mediMask
+			} else if joinsPrev[i] {
+				masks[i] |= finaMask
+			} else if next {
+				masks[i] |= initMask
+			} else {
+				masks[i] |= isolMask
+			}
+		}
+		next = joinsPrev[i]
+	}
+}
+
+// indicPreBaseMatras are the dependent vowel signs of Indic scripts that are written before their consonant cluster.
+var indicPreBaseMatras = map[rune]bool{
+	0x093F: true, 0x094E: true, // Devanagari
+	0x09BF: true, 0x09C7: true, 0x09C8: true, // Bengali
+	0x0A3F: true,                             // Gurmukhi
+	0x0ABF: true,                             // Gujarati
+	0x0B47: true,                             // Oriya
+	0x0BC6: true, 0x0BC7: true, 0x0BC8: true, // Tamil
+	0x0D46: true, 0x0D47: true, 0x0D48: true, // Malayalam
+}
+
+// isIndicVirama returns true for the virama (halant) of an Indic script, which follows a consonant that joins the next consonant into a cluster.
+func isIndicVirama(r rune) bool {
+	return 0x0900 <= r && r <= 0x0DFF && r&0x7F == 0x4D
+}
+
+// reorderIndic moves pre-base matras before the consonant cluster that they follow in logical order, such as the i-matra of Devanagari, and assigns them the cluster of the first consonant.
+func reorderIndic(runes []rune, clusters []int) {
+	for i, r := range runes {
+		if !indicPreBaseMatras[r] {
+			continue
+		}
+		// find the start of the cluster of consonants, optionally with nuktas, that are joined by viramas
+		j := i
+		for {
+			k := j - 1
+			for 0 <= k && unicode.Is(unicode.Mn, runes[k]) && !isIndicVirama(runes[k]) {
+				k-- // nukta
+			}
+			if k < 0 || !unicode.IsLetter(runes[k]) {
+				break
+			}
+			j = k
+			if j == 0 || !isIndicVirama(runes[j-1]) {
+				break
+			}
+			j-- // continue before the virama
+		}
+		for j < i && !unicode.IsLetter(runes[j]) {
+			j++ // the cluster starts with a consonant
+		}
+		if j == i {
+			continue
+		}
+		copy(runes[j+1:i+1], runes[j:i])
+		copy(clusters[j+1:i+1], clusters[j:i])
+		runes[j] = r
+	}
+}
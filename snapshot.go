@@ -0,0 +1,164 @@
+package canvas
+
+import (
+	"reflect"
+)
+
+// Snapshot is an immutable version of the drawing of a canvas, see Canvas.Snapshot. Snapshots share their layers with the canvas and with each other, and the canvas only copies its layers when it changes them in place, so that taking a snapshot after every edit is cheap.
+type Snapshot struct {
+	layers  []layer
+	anchors map[string]Point
+	W, H    float64
+}
+
+// Snapshot returns the current drawing of the canvas, including its layers created with NewLayer and its anchors, which can be restored later using Restore.
+func (c *Canvas) Snapshot() *Snapshot {
+	c.merge()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.shared = true
+	anchors := make(map[string]Point, len(c.anchors))
+	for name, p := range c.anchors {
+		anchors[name] = p
+	}
+	return &Snapshot{c.layers, anchors, c.W, c.H}
+}
+
+// Restore replaces the drawing of the canvas by the snapshot, and invalidates the areas of the layers that differ for Redraw.
+func (c *Canvas) Restore(s *Snapshot) {
+	c.merge()
+	c.mu.Lock()
+	if c.W != s.W || c.H != s.H {
+		c.invalidate(Rect{0.0, 0.0, c.W, c.H})
+		c.invalidate(Rect{0.0, 0.0, s.W, s.H})
+	} else {
+		removed, added := diffLayers(c.layers, s.layers)
+		for _, i := range removed {
+			c.invalidate(c.layers[i].Bounds())
+		}
+		for _, i := range added {
+			c.invalidate(s.layers[i].Bounds())
+		}
+	}
+	n := len(s.layers)
+	c.layers = s.layers[:n:n] // appending does not overwrite layers of later snapshots
+	c.shared = true
+	c.anchors = make(map[string]Point, len(s.anchors))
+	for name, p := range s.anchors {
+		c.anchors[name] = p
+	}
+	c.groups = nil
+	c.index = nil
+	c.W, c.H = s.W, s.H
+	c.mu.Unlock()
+}
+
+// Size returns the size of the snapshot in mm.
+func (s *Snapshot) Size() (float64, float64) {
+	return s.W, s.H
+}
+
+// Len returns the number of layers of the snapshot.
+func (s *Snapshot) Len() int {
+	return len(s.layers)
+}
+
+// Diff returns the indices of the layers of s that were removed and the indices of the layers of t that were added, when changing the drawing from s to t. Layers are matched from the start and from the end of the drawing, so that all layers in between the first and the last changed layer are reported, which is exact for a single insertion, removal, or replacement.
+func (s *Snapshot) Diff(t *Snapshot) ([]int, []int) {
+	return diffLayers(s.layers, t.layers)
+}
+
+func diffLayers(a, b []layer) ([]int, []int) {
+	i := 0
+	for i < len(a) && i < len(b) && a[i].equals(b[i]) {
+		i++
+	}
+	j, k := len(a), len(b)
+	for i < j && i < k && a[j-1].equals(b[k-1]) {
+		j--
+		k--
+	}
+	removed, added := []int{}, []int{}
+	for n := i; n < j; n++ {
+		removed = append(removed, n)
+	}
+	for n := i; n < k; n++ {
+		added = append(added, n)
+	}
+	return removed, added
+}
+
+// equals returns true if the layers draw the same objects, which are compared by identity.
+func (l layer) equals(b layer) bool {
+	if l.path != b.path || l.text != b.text || l.m != b.m {
+		return false
+	} else if l.img != nil || b.img != nil {
+		if l.img == nil || b.img == nil || reflect.TypeOf(l.img) != reflect.TypeOf(b.img) || !reflect.TypeOf(l.img).Comparable() || l.img != b.img {
+			return false
+		}
+	}
+	return reflect.DeepEqual(l.style, b.style)
+}
+
+// History is a stack of snapshots of a canvas to undo and redo its changes, such as for editors. Changes are recorded as a step by Commit.
+type History struct {
+	c          *Canvas
+	current    *Snapshot
+	undo, redo []*Snapshot
+}
+
+// NewHistory returns an empty history of the canvas, starting with its current drawing.
+func NewHistory(c *Canvas) *History {
+	return &History{
+		c:       c,
+		current: c.Snapshot(),
+	}
+}
+
+// Commit records the changes to the canvas since the last commit as a step that can be undone, and clears the steps that can be redone. It returns false if the canvas did not change.
+func (h *History) Commit() bool {
+	s := h.c.Snapshot()
+	if s.W == h.current.W && s.H == h.current.H {
+		if removed, added := h.current.Diff(s); len(removed) == 0 && len(added) == 0 {
+			return false
+		}
+	}
+	h.undo = append(h.undo, h.current)
+	h.redo = nil
+	h.current = s
+	return true
+}
+
+// CanUndo returns true if there is a step that can be undone.
+func (h *History) CanUndo() bool {
+	return 0 < len(h.undo)
+}
+
+// CanRedo returns true if there is a step that can be redone.
+func (h *History) CanRedo() bool {
+	return 0 < len(h.redo)
+}
+
+// Undo restores the canvas to before the last committed step and returns true, or returns false if there is none. Changes that were not committed are discarded.
+func (h *History) Undo() bool {
+	if len(h.undo) == 0 {
+		return false
+	}
+	h.redo = append(h.redo, h.current)
+	h.current = h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	h.c.Restore(h.current)
+	return true
+}
+
+// Redo restores the canvas to after the last undone step and returns true, or returns false if there is none. Changes that were not committed are discarded.
+func (h *History) Redo() bool {
+	if len(h.redo) == 0 {
+		return false
+	}
+	h.undo = append(h.undo, h.current)
+	h.current = h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	h.c.Restore(h.current)
+	return true
+}
@@ -0,0 +1,86 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestSnapshot(t *testing.T) {
+	c := New(100.0, 100.0)
+	ctx := NewContext(c)
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	ctx.DrawPath(20.0, 0.0, Rectangle(10.0, 10.0))
+	s := c.Snapshot()
+	test.T(t, s.Len(), 2)
+
+	ctx.DrawPath(40.0, 0.0, Rectangle(10.0, 10.0))
+	t1 := c.Snapshot()
+	removed, added := s.Diff(t1)
+	test.T(t, removed, []int{})
+	test.T(t, added, []int{2})
+
+	// changing in place does not change the snapshots
+	c.Fit(5.0)
+	test.T(t, c.layers[0].Bounds(), Rect{5.0, 5.0, 10.0, 10.0})
+	test.T(t, t1.layers[0].Bounds(), Rect{0.0, 0.0, 10.0, 10.0})
+	removed, added = t1.Diff(c.Snapshot())
+	test.T(t, removed, []int{0, 1, 2})
+	test.T(t, added, []int{0, 1, 2})
+
+	c.Reset()
+	ctx.DrawPath(0.0, 0.0, Circle(5.0))
+	test.T(t, t1.Len(), 3)
+	test.T(t, s.Len(), 2)
+	removed, added = s.Diff(c.Snapshot())
+	test.T(t, removed, []int{0, 1})
+	test.T(t, added, []int{0})
+
+	c.dirty = nil
+	c.Restore(s)
+	test.T(t, len(c.layers), 2)
+	test.T(t, c.dirty, []Rect{{0.0, 0.0, 60.0, 20.0}, {0.0, 0.0, 100.0, 100.0}})
+
+	c.dirty = nil
+	c.Restore(t1)
+	test.T(t, c.dirty, []Rect{{40.0, 0.0, 10.0, 10.0}})
+	c.Restore(s)
+
+	// appending after restoring does not overwrite later snapshots
+	ctx.DrawPath(60.0, 0.0, Rectangle(10.0, 10.0))
+	removed, added = s.Diff(t1)
+	test.T(t, removed, []int{})
+	test.T(t, added, []int{2})
+	test.T(t, t1.layers[2].Bounds(), Rect{40.0, 0.0, 10.0, 10.0})
+}
+
+func TestHistory(t *testing.T) {
+	c := New(100.0, 100.0)
+	ctx := NewContext(c)
+	h := NewHistory(c)
+	test.That(t, !h.Commit(), "no changes")
+	test.That(t, !h.Undo(), "nothing to undo")
+
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	test.That(t, h.Commit())
+	ctx.DrawPath(20.0, 0.0, Rectangle(10.0, 10.0))
+	test.That(t, h.Commit())
+	test.That(t, h.CanUndo() && !h.CanRedo())
+
+	test.That(t, h.Undo())
+	test.T(t, len(c.layers), 1)
+	test.That(t, h.Undo())
+	test.T(t, len(c.layers), 0)
+	test.That(t, !h.CanUndo() && h.CanRedo())
+	test.That(t, h.Redo())
+	test.T(t, len(c.layers), 1)
+
+	// a new step clears the steps that can be redone
+	ctx.DrawPath(40.0, 0.0, Rectangle(10.0, 10.0))
+	test.That(t, h.Commit())
+	test.That(t, !h.Redo())
+	test.T(t, c.layers[1].Bounds(), Rect{40.0, 0.0, 10.0, 10.0})
+	test.That(t, h.Undo())
+	test.That(t, h.Redo())
+	test.T(t, len(c.layers), 2)
+}
@@ -0,0 +1,230 @@
+package canvas
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Node is a node of a scene graph, which draws a path or text and its child nodes. Its style is resolved from a stylesheet by its type, id, and classes, so that the drawing logic of an application is separated from its styling, see Stylesheet.
+type Node struct {
+	Type    string   // such as "axis" or "label", matched by type selectors
+	ID      string   // matched by id selectors
+	Classes []string // matched by class selectors
+	Style   string   // declarations such as "fill:red; stroke-width:0.5", which take precedence over the stylesheet
+	Matrix  Matrix   // transformation of the node and its children relative to its parent
+	Path    *Path
+	Text    *Text
+
+	parent   *Node
+	children []*Node
+}
+
+// NewNode returns a node of the given type and classes without content.
+func NewNode(typ string, classes ...string) *Node {
+	return &Node{
+		Type:    typ,
+		Classes: classes,
+		Matrix:  Identity,
+	}
+}
+
+// Add appends child nodes and returns the node.
+func (n *Node) Add(children ...*Node) *Node {
+	for _, child := range children {
+		child.parent = n
+	}
+	n.children = append(n.children, children...)
+	return n
+}
+
+// Parent returns the parent node, or nil for the root node.
+func (n *Node) Parent() *Node {
+	return n.parent
+}
+
+// Children returns the child nodes.
+func (n *Node) Children() []*Node {
+	return n.children
+}
+
+// Stylesheet is a set of CSS style rules that resolve the styles of nodes. Selectors match the type, id, classes, and the structure of nodes, such as "axis > .tick:first-child", and the properties are those of SVG: fill, stroke, their opacities, opacity, fill-rule, the stroke-width, stroke-linecap, stroke-linejoin, stroke-miterlimit, stroke-dasharray, and stroke-dashoffset, color, display, and visibility. Lengths without a unit are in millimeters. Properties are inherited by child nodes as in SVG, and otherwise take their value from the style of the context.
+type Stylesheet struct {
+	rules []cssRule
+}
+
+// ParseStylesheet parses a stylesheet. Rules with unsupported selectors or inside at-rules are skipped.
+func ParseStylesheet(css string) *Stylesheet {
+	rules := parseCSSRules(css)
+	sortCSSRules(rules)
+	return &Stylesheet{rules}
+}
+
+// cascade resolves the properties of all nodes of the tree of the node, which are mirrored by elements so that selectors match them as SVG elements.
+func (sheet *Stylesheet) cascade(node *Node) map[*Node]*svgElement {
+	root := node
+	for root.parent != nil {
+		root = root.parent
+	}
+
+	els := map[*Node]*svgElement{}
+	var mirror func(*Node, *svgElement) *svgElement
+	mirror = func(n *Node, parent *svgElement) *svgElement {
+		attrs := map[string]string{}
+		if n.ID != "" {
+			attrs["id"] = n.ID
+		}
+		if 0 < len(n.Classes) {
+			attrs["class"] = strings.Join(n.Classes, " ")
+		}
+		if n.Style != "" {
+			attrs["style"] = n.Style
+		}
+		el := &svgElement{tag: n.Type, attrs: attrs, parent: parent}
+		for _, child := range n.children {
+			el.children = append(el.children, mirror(child, el))
+		}
+		els[n] = el
+		return el
+	}
+
+	var rules []cssRule
+	if sheet != nil {
+		rules = sheet.rules
+	}
+	cascadeCSS(mirror(root, nil), rules, newBudget(Limits{}))
+	return els
+}
+
+// Style returns the style of a path of the node, which inherits the properties of its ancestors and otherwise those of base.
+func (sheet *Stylesheet) Style(node *Node, base Style) Style {
+	el := sheet.cascade(node)[node]
+	return nodeStyle(base, svgInheritedProps(el), nodeOpacity(el))
+}
+
+// Draw draws the node and its descendants with their resolved styles and transformations at the current view of the context. The node inherits the properties of its ancestors, but not their transformations. Texts are drawn with the colors of their font faces, and are only hidden by display or visibility.
+func (sheet *Stylesheet) Draw(ctx *Context, node *Node) {
+	el := sheet.cascade(node)[node]
+	props := svgInheritedProps(el.parent)
+	opacity := nodeOpacity(el.parent)
+	sheet.draw(ctx, node, el, props, opacity, ctx.Style)
+}
+
+func (sheet *Stylesheet) draw(ctx *Context, node *Node, el *svgElement, props map[string]string, opacity float64, base Style) {
+	if el.style["display"] == "none" {
+		return
+	}
+	props = svgInherit(props, el)
+	if val, ok := el.style["opacity"]; ok {
+		opacity *= svgOpacity(val)
+	}
+
+	view := ctx.View()
+	ctx.ComposeView(node.Matrix)
+	if visibility := props["visibility"]; visibility != "hidden" && visibility != "collapse" {
+		if node.Path != nil {
+			style := ctx.Style
+			ctx.Style = nodeStyle(base, props, opacity)
+			ctx.DrawPath(0.0, 0.0, node.Path)
+			ctx.Style = style
+		}
+		if node.Text != nil {
+			ctx.DrawText(0.0, 0.0, node.Text)
+		}
+	}
+	for i, child := range node.children {
+		sheet.draw(ctx, child, el.children[i], props, opacity, base)
+	}
+	ctx.SetView(view)
+}
+
+// nodeOpacity returns the product of the opacities of the element and its ancestors.
+func nodeOpacity(el *svgElement) float64 {
+	opacity := 1.0
+	for ; el != nil; el = el.parent {
+		if val, ok := el.style["opacity"]; ok {
+			opacity *= svgOpacity(val)
+		}
+	}
+	return opacity
+}
+
+// nodeStyle returns the style of the properties, where properties that are not set keep the value of base.
+func nodeStyle(base Style, props map[string]string, opacity float64) Style {
+	style := base
+	for _, key := range []string{"fill", "stroke"} {
+		col := &style.FillColor
+		if key == "stroke" {
+			col = &style.StrokeColor
+		}
+		if val, ok := props[key]; ok {
+			if val == "currentColor" {
+				val = props["color"]
+			}
+			if c, err := ParseCSSColor(val); err == nil {
+				*col = c
+			}
+		}
+		*col = scaleAlpha(*col, svgOpacity(props[key+"-opacity"])*opacity)
+	}
+	switch props["fill-rule"] {
+	case "nonzero":
+		style.FillRule = NonZero
+	case "evenodd":
+		style.FillRule = EvenOdd
+	}
+
+	if width, ok := nodeLength(props["stroke-width"]); ok && 0.0 <= width {
+		style.StrokeWidth = width
+	}
+	switch props["stroke-linecap"] {
+	case "butt":
+		style.StrokeCapper = ButtCap
+	case "round":
+		style.StrokeCapper = RoundCap
+	case "square":
+		style.StrokeCapper = SquareCap
+	}
+	miterLimit := 4.0
+	if val, ok := props["stroke-miterlimit"]; ok {
+		if limit, err := strconv.ParseFloat(val, 64); err == nil && 1.0 <= limit {
+			miterLimit = limit
+		}
+	}
+	switch props["stroke-linejoin"] {
+	case "miter":
+		style.StrokeJoiner = MiterJoiner{BevelJoin, miterLimit}
+	case "round":
+		style.StrokeJoiner = RoundJoin
+	case "bevel":
+		style.StrokeJoiner = BevelJoin
+	case "arcs":
+		style.StrokeJoiner = ArcsJoiner{BevelJoin, miterLimit}
+	}
+
+	if val, ok := props["stroke-dasharray"]; ok {
+		style.Dashes = nil
+		if dashes := svgNumbers(val); 0 < len(dashes) {
+			style.Dashes = dashes
+		}
+	}
+	if offset, ok := nodeLength(props["stroke-dashoffset"]); ok {
+		style.DashOffset = offset
+	}
+	return style
+}
+
+// nodeLength parses a length in millimeters, or in another unit such as px or pt. It returns false when empty or invalid.
+func nodeLength(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, true
+	}
+	i := len(s)
+	for 0 < i && 'a' <= s[i-1] && s[i-1] <= 'z' {
+		i--
+	}
+	if _, err := strconv.ParseFloat(s[:i], 64); err != nil || i == len(s) {
+		return 0.0, false
+	}
+	return svgLength(s, 0.0) * mmPerPx, true
+}
@@ -0,0 +1,64 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestStylesheet(t *testing.T) {
+	sheet := ParseStylesheet(`
+		axis { stroke: black; stroke-width: 0.5; fill: none }
+		.major { stroke-width: 1pt }
+		axis > .tick:first-child { stroke: red }
+		#grid { stroke-dasharray: 1 2; opacity: 0.5 }
+		.hidden { display: none }
+	`)
+
+	root := NewNode("chart")
+	axis := NewNode("axis")
+	tick0 := NewNode("line", "tick", "major")
+	tick1 := NewNode("line", "tick")
+	tick1.Style = "stroke-linecap: round; stroke: currentColor; color: blue"
+	grid := NewNode("line")
+	grid.ID = "grid"
+	root.Add(axis.Add(tick0, tick1), grid, NewNode("line", "hidden"))
+	test.T(t, tick0.Parent(), axis)
+	test.T(t, len(axis.Children()), 2)
+
+	style := sheet.Style(axis, DefaultStyle)
+	test.T(t, style.StrokeColor, Black)
+	test.T(t, style.StrokeWidth, 0.5)
+	test.T(t, style.FillColor, Transparent)
+
+	style = sheet.Style(tick0, DefaultStyle)
+	test.T(t, style.StrokeColor, Red)
+	test.Float(t, style.StrokeWidth, 25.4/72.0)
+
+	style = sheet.Style(tick1, DefaultStyle)
+	test.T(t, style.StrokeColor, Blue)
+	test.T(t, style.StrokeWidth, 0.5)
+	test.T(t, style.StrokeCapper, RoundCap)
+
+	style = sheet.Style(grid, DefaultStyle)
+	test.T(t, style.FillColor, scaleAlpha(Black, 0.5))
+	test.T(t, style.Dashes, []float64{1.0, 2.0})
+
+	// draw lines of all nodes, except the hidden node
+	for _, node := range []*Node{axis, tick0, tick1, grid, root.Children()[2]} {
+		node.Path = MustParseSVG("M0 0H10")
+	}
+	tick1.Matrix = Identity.Translate(0.0, 5.0)
+	c := New(20.0, 20.0)
+	ctx := NewContext(c)
+	sheet.Draw(ctx, root)
+	test.T(t, len(c.layers), 4)
+	test.T(t, c.layers[0].style.StrokeColor, Black)
+	test.T(t, c.layers[1].style.StrokeColor, Red)
+	test.T(t, c.layers[2].m, Identity.Translate(0.0, 5.0))
+	test.T(t, ctx.View(), Identity)
+
+	// inline styles without a stylesheet
+	var none *Stylesheet
+	test.T(t, none.Style(tick1, DefaultStyle).StrokeColor, Blue)
+}
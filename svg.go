@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"encoding/base64"
 	"fmt"
+	"html"
 	"image"
 	"image/color"
 	"image/jpeg"
 	"image/png"
 	"io"
 	"math"
+	"reflect"
 	"strings"
 )
 
@@ -22,6 +24,7 @@ type SVG struct {
 	imgEnc        ImageEncoding
 
 	classes []string
+	link    string // URL of the open <a> element
 }
 
 // NewSVG creates a scalable vector graphics renderer.
@@ -40,10 +43,24 @@ func NewSVG(w io.Writer, width, height float64) *SVG {
 }
 
 func (r *SVG) Close() error {
+	r.setLink("", Rect{})
 	_, err := fmt.Fprintf(r.w, "</svg>")
 	return err
 }
 
+// setLink wraps the following elements in an <a> element, see Context.SetLink.
+func (r *SVG) setLink(url string, bounds Rect) {
+	if url == r.link {
+		return
+	} else if r.link != "" {
+		fmt.Fprintf(r.w, "</a>")
+	}
+	if url != "" {
+		fmt.Fprintf(r.w, `<a xlink:href="%s">`, html.EscapeString(url))
+	}
+	r.link = url
+}
+
 func (r *SVG) AddClass(class string) {
 	if class == "" {
 		return
@@ -107,6 +124,10 @@ func (r *SVG) Size() (float64, float64) {
 }
 
 func (r *SVG) RenderPath(path *Path, style Style, m Matrix) {
+	if style.FillMesh != nil {
+		renderMesh(r, path, style, m, meshPieceSize)
+		return
+	}
 	fill := style.FillColor.A != 0
 	stroke := style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth
 
@@ -227,6 +248,7 @@ func (r *SVG) writeFontStyle(ff, ffMain FontFace) {
 	if ff.color != ffMain.color {
 		differences++
 	}
+	inStyle := true
 	if ff.font.name != ffMain.font.name || ff.size*ff.scale != ffMain.size || differences == 3 {
 		fmt.Fprintf(r.w, `" style="font:`)
 
@@ -252,6 +274,7 @@ func (r *SVG) writeFontStyle(ff, ffMain FontFace) {
 		}
 	} else if differences == 1 && ff.color != ffMain.color {
 		fmt.Fprintf(r.w, `" fill="%v`, CSSColor(ff.color))
+		inStyle = false
 	} else if 0 < differences {
 		fmt.Fprintf(r.w, `" style="`)
 		buf := &bytes.Buffer{}
@@ -269,10 +292,42 @@ func (r *SVG) writeFontStyle(ff, ffMain FontFace) {
 		}
 		buf.ReadByte()
 		buf.WriteTo(r.w)
+	} else {
+		inStyle = false
+	}
+	if !reflect.DeepEqual(ff.features, ffMain.features) {
+		if inStyle {
+			fmt.Fprintf(r.w, `;`)
+		} else {
+			fmt.Fprintf(r.w, `" style="`)
+		}
+		fmt.Fprintf(r.w, `font-feature-settings:%s`, cssFeatureSettings(ff.features))
+	}
+}
+
+// cssFeatureSettings returns the value of the font-feature-settings property of CSS for the features of a font face, see FontFace.WithFeatures.
+func cssFeatureSettings(features []string) string {
+	if len(features) == 0 {
+		return "normal"
+	}
+	settings := make([]string, len(features))
+	for i, feature := range features {
+		if strings.HasPrefix(feature, "-") {
+			settings[i] = fmt.Sprintf("'%s' 0", feature[1:])
+		} else {
+			settings[i] = fmt.Sprintf("'%s'", feature)
+		}
 	}
+	return strings.Join(settings, ",")
 }
 
 func (r *SVG) RenderText(text *Text, m Matrix) {
+	if text.hasColorGlyphs() || text.vertical {
+		// color glyphs have colors and images that fonts in SVG cannot draw, and vertical text is drawn by the outlines of its glyphs
+		text.renderAsPaths(r, m)
+		return
+	}
+
 	if r.embedFonts {
 		r.writeFonts(text.Fonts())
 	}
@@ -305,6 +360,9 @@ func (r *SVG) RenderText(text *Text, m Matrix) {
 	if ffMain.color != Black {
 		fmt.Fprintf(r.w, `;fill:%v`, CSSColor(ffMain.color))
 	}
+	if 0 < len(ffMain.features) {
+		fmt.Fprintf(r.w, `;font-feature-settings:%s`, cssFeatureSettings(ffMain.features))
+	}
 	r.writeClasses(r.w)
 	fmt.Fprintf(r.w, `">`)
 
@@ -312,15 +370,23 @@ func (r *SVG) RenderText(text *Text, m Matrix) {
 	decoColors := []color.RGBA{}
 	for _, line := range text.lines {
 		for _, span := range line.spans {
-			fmt.Fprintf(r.w, `<tspan x="%v" y="%v`, num(x0+span.dx), num(y0-line.y-span.ff.voffset))
+			if span.level%2 == 1 {
+				// right-to-left runs start at their right edge, and are not reordered again by the bidirectional algorithm
+				fmt.Fprintf(r.w, `<tspan x="%v" y="%v" direction="rtl" unicode-bidi="bidi-override`, num(x0+span.dx+span.width), num(y0-line.y-span.ff.voffset))
+			} else {
+				fmt.Fprintf(r.w, `<tspan x="%v" y="%v`, num(x0+span.dx), num(y0-line.y-span.ff.voffset))
+			}
 			if span.wordSpacing > 0.0 {
 				fmt.Fprintf(r.w, `" word-spacing="%v`, num(span.wordSpacing))
 			}
 			if span.glyphSpacing > 0.0 {
 				fmt.Fprintf(r.w, `" letter-spacing="%v`, num(span.glyphSpacing))
 			}
+			if span.glyphStretch != 0.0 {
+				fmt.Fprintf(r.w, `" textLength="%v" lengthAdjust="spacingAndGlyphs`, num(span.width))
+			}
 			r.writeFontStyle(span.ff, ffMain)
-			s := span.text
+			s := span.ff.font.expandLigatureGlyphs(span.text)
 			s = strings.ReplaceAll(s, `"`, `&quot;`)
 			r.writeClasses(r.w)
 			fmt.Fprintf(r.w, `">%s</tspan>`, s)
@@ -343,7 +409,11 @@ func (r *SVG) RenderText(text *Text, m Matrix) {
 func (r *SVG) RenderImage(img image.Image, m Matrix) {
 	refMask := ""
 	mimetype := "image/png"
-	if r.imgEnc == Lossy {
+	jpg, isJPEG := img.(*JPEGImage)
+	if isJPEG {
+		// embed the file so that its ICC profile is kept, but not its EXIF orientation which is applied by m
+		mimetype = "image/jpeg"
+	} else if r.imgEnc == Lossy {
 		mimetype = "image/jpg"
 		if opaqueImg, ok := img.(interface{ Opaque() bool }); !ok || !opaqueImg.Opaque() {
 			hasMask := false
@@ -388,7 +458,11 @@ func (r *SVG) RenderImage(img image.Image, m Matrix) {
 		m.ToSVG(r.height), img.Bounds().Size().X, img.Bounds().Size().Y, mimetype)
 
 	encoder := base64.NewEncoder(base64.StdEncoding, r.w)
-	if mimetype == "image/jpg" {
+	if isJPEG {
+		if _, err := encoder.Write(jpg.data); err != nil {
+			panic(err)
+		}
+	} else if mimetype == "image/jpg" {
 		if err := jpeg.Encode(encoder, img, nil); err != nil {
 			panic(err)
 		}
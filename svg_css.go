@@ -0,0 +1,458 @@
+package canvas
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tdewolff/parse/v2/css"
+)
+
+// svgProperties are the presentation attributes that can also be set by CSS.
+var svgProperties = append([]string{"display", "opacity", "transform", "clip-path", "mask", "mask-type", "stop-color", "stop-opacity"}, svgInherited...)
+
+// cssDeclaration is a property and its value of a style rule or style attribute.
+type cssDeclaration struct {
+	property, value string
+	important       bool
+}
+
+// cssAttrSelector matches an attribute by its name, and by its value when op is not empty, where op is one of =, ~=, |=, ^=, $=, or *=.
+type cssAttrSelector struct {
+	name, op, value string
+}
+
+// cssCompound is a compound selector, such as rect.a#b, with the combinator that relates it to the compound selector before it, which is one of ' ', '>', '+', or '~'.
+type cssCompound struct {
+	combinator byte
+	tag        string // empty matches any element
+	id         string
+	classes    []string
+	attrs      []cssAttrSelector
+	pseudos    []string // structural pseudo-classes
+}
+
+// cssSelector is a complex selector of compound selectors from left to right.
+type cssSelector []cssCompound
+
+// cssRule is a style rule with a single selector, rules with a list of selectors are split into a rule per selector.
+type cssRule struct {
+	selector     cssSelector
+	specificity  int
+	declarations []cssDeclaration
+}
+
+// parseCSSRules parses the style rules of a stylesheet. Rules with unsupported selectors and rules inside at-rules, such as @media, are skipped.
+func parseCSSRules(sheet string) []cssRule {
+	rules := []cssRule{}
+	block := []cssRule{} // rules of the current ruleset
+	depth := 0
+	p := css.NewParser(bytes.NewBufferString(sheet), false)
+	for {
+		gt, _, data := p.Next()
+		switch gt {
+		case css.ErrorGrammar:
+			if p.Err() != nil {
+				return rules // io.EOF or a syntax error
+			}
+		case css.BeginAtRuleGrammar:
+			depth++
+		case css.EndAtRuleGrammar:
+			depth--
+		case css.QualifiedRuleGrammar, css.BeginRulesetGrammar:
+			if sel, ok := parseCSSSelector(p.Values()); ok && depth == 0 {
+				block = append(block, cssRule{sel, sel.specificity(), nil})
+			}
+		case css.DeclarationGrammar:
+			if decl, ok := parseCSSDeclaration(string(data), p.Values()); ok {
+				for i := range block {
+					block[i].declarations = append(block[i].declarations, decl)
+				}
+			}
+		case css.EndRulesetGrammar:
+			rules = append(rules, block...)
+			block = []cssRule{}
+		}
+	}
+}
+
+// parseCSSInline parses the declarations of a style attribute.
+func parseCSSInline(style string) []cssDeclaration {
+	decls := []cssDeclaration{}
+	p := css.NewParser(bytes.NewBufferString(style), true)
+	for {
+		gt, _, data := p.Next()
+		if gt == css.ErrorGrammar {
+			if p.Err() != nil {
+				return decls
+			}
+		} else if gt == css.DeclarationGrammar {
+			if decl, ok := parseCSSDeclaration(string(data), p.Values()); ok {
+				decls = append(decls, decl)
+			}
+		}
+	}
+}
+
+// parseCSSDeclaration converts the tokens of a declaration into the value of the equivalent presentation attribute, which for transforms means that the units of lengths and angles are converted to user units and degrees. The font shorthand is handled by cssFont.
+func parseCSSDeclaration(property string, values []css.Token) (cssDeclaration, bool) {
+	property = strings.ToLower(property)
+	decl := cssDeclaration{property: property}
+	if n := len(values); 2 <= n && values[n-2].TokenType == css.DelimToken && values[n-2].Data[0] == '!' && strings.EqualFold(string(values[n-1].Data), "important") {
+		decl.important = true
+		values = values[:n-2]
+	}
+
+	sb := strings.Builder{}
+	for _, val := range values {
+		data := string(val.Data)
+		if property == "transform" && val.TokenType == css.DimensionToken {
+			data = cssTransformUnit(data)
+		} else if val.TokenType == css.WhitespaceToken {
+			data = " "
+		} else if val.TokenType == css.CommaToken {
+			data = ", " // the lexer drops whitespace after commas
+		}
+		sb.WriteString(data)
+	}
+	decl.value = strings.TrimSpace(sb.String())
+	return decl, decl.value != ""
+}
+
+// cssTransformUnit converts a length or angle of a CSS transform function into user units or degrees respectively.
+func cssTransformUnit(s string) string {
+	i := len(s)
+	for 0 < i && ('a' <= s[i-1] && s[i-1] <= 'z' || 'A' <= s[i-1] && s[i-1] <= 'Z') {
+		i--
+	}
+	f, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return s
+	}
+	switch strings.ToLower(s[i:]) {
+	case "deg", "px":
+		return s[:i]
+	case "rad":
+		f *= 180.0 / math.Pi
+	case "grad":
+		f *= 0.9
+	case "turn":
+		f *= 360.0
+	default:
+		f = svgLength(s, 0.0)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// cssFont expands the font shorthand, such as "italic bold 12px/1.5 Georgia, serif", into the properties for the style, weight, size, and family. The line height is ignored.
+func cssFont(value string) []cssDeclaration {
+	decls := []cssDeclaration{{property: "font-style", value: "normal"}, {property: "font-weight", value: "normal"}}
+	fields := strings.Fields(strings.Replace(value, "/", " / ", 1))
+	for i := 0; i < len(fields); i++ {
+		switch field := fields[i]; field {
+		case "normal", "small-caps":
+		case "italic", "oblique":
+			decls[0].value = field
+		case "bold", "bolder", "lighter", "100", "200", "300", "400", "500", "600", "700", "800", "900":
+			decls[1].value = field
+		default:
+			decls = append(decls, cssDeclaration{property: "font-size", value: field})
+			if i+2 < len(fields) && fields[i+1] == "/" {
+				i += 2
+			}
+			if i+1 < len(fields) {
+				decls = append(decls, cssDeclaration{property: "font-family", value: strings.Join(fields[i+1:], " ")})
+			}
+			return decls
+		}
+	}
+	return decls
+}
+
+// parseCSSSelector parses the tokens of a selector, and returns false if it uses unsupported features such as pseudo-elements or functional pseudo-classes, which therefore never match.
+func parseCSSSelector(tokens []css.Token) (cssSelector, bool) {
+	sel := cssSelector{}
+	cur := cssCompound{combinator: ' '}
+	empty := true
+	for i := 0; i < len(tokens); i++ {
+		tt, data := tokens[i].TokenType, string(tokens[i].Data)
+		switch {
+		case tt == css.WhitespaceToken:
+			if !empty {
+				sel = append(sel, cur)
+				cur, empty = cssCompound{combinator: ' '}, true
+			}
+		case tt == css.DelimToken && (data == ">" || data == "+" || data == "~"):
+			if !empty {
+				sel = append(sel, cur)
+				cur, empty = cssCompound{}, true
+			} else if len(sel) == 0 {
+				return nil, false
+			}
+			cur.combinator = data[0]
+		case tt == css.IdentToken && empty:
+			cur.tag, empty = data, false
+		case tt == css.DelimToken && data == "*" && empty:
+			empty = false
+		case tt == css.HashToken:
+			cur.id, empty = data[1:], false
+		case tt == css.DelimToken && data == "." && i+1 < len(tokens) && tokens[i+1].TokenType == css.IdentToken:
+			cur.classes = append(cur.classes, string(tokens[i+1].Data))
+			i++
+			empty = false
+		case tt == css.ColonToken && i+1 < len(tokens) && tokens[i+1].TokenType == css.IdentToken:
+			pseudo := strings.ToLower(string(tokens[i+1].Data))
+			if pseudo != "first-child" && pseudo != "last-child" && pseudo != "only-child" && pseudo != "root" {
+				return nil, false // dynamic pseudo-classes such as :hover never match
+			}
+			cur.pseudos = append(cur.pseudos, pseudo)
+			i++
+			empty = false
+		case tt == css.LeftBracketToken:
+			attr := cssAttrSelector{}
+			j := i + 1
+			for ; j < len(tokens) && tokens[j].TokenType != css.RightBracketToken; j++ {
+				switch tokens[j].TokenType {
+				case css.IdentToken, css.StringToken, css.NumberToken:
+					s := string(tokens[j].Data)
+					if tokens[j].TokenType == css.StringToken && 2 <= len(s) {
+						s = s[1 : len(s)-1]
+					}
+					if attr.name == "" {
+						attr.name = s
+					} else {
+						attr.value = s
+					}
+				case css.DelimToken, css.IncludeMatchToken, css.DashMatchToken, css.PrefixMatchToken, css.SuffixMatchToken, css.SubstringMatchToken:
+					attr.op = string(tokens[j].Data)
+				}
+			}
+			if j == len(tokens) || attr.name == "" || attr.op != "" && attr.op != "=" && attr.op != "~=" && attr.op != "|=" && attr.op != "^=" && attr.op != "$=" && attr.op != "*=" {
+				return nil, false
+			}
+			cur.attrs = append(cur.attrs, attr)
+			i = j
+			empty = false
+		default:
+			return nil, false
+		}
+	}
+	if empty {
+		if len(sel) == 0 || cur.combinator != ' ' {
+			return nil, false // empty selector or trailing combinator
+		}
+	} else {
+		sel = append(sel, cur)
+	}
+	return sel, true
+}
+
+// specificity returns the specificity of the selector, which counts the ids, the classes, attributes and pseudo-classes, and the types.
+func (sel cssSelector) specificity() int {
+	a, b, c := 0, 0, 0
+	for _, compound := range sel {
+		if compound.id != "" {
+			a++
+		}
+		b += len(compound.classes) + len(compound.attrs) + len(compound.pseudos)
+		if compound.tag != "" {
+			c++
+		}
+	}
+	return a<<16 | b<<8 | c
+}
+
+// matches returns true if the selector matches the element.
+func (sel cssSelector) matches(el *svgElement) bool {
+	return sel.matchesAt(len(sel)-1, el)
+}
+
+func (sel cssSelector) matchesAt(i int, el *svgElement) bool {
+	if !sel[i].matches(el) {
+		return false
+	} else if i == 0 {
+		return true
+	}
+	switch sel[i].combinator {
+	case '>':
+		return el.parent != nil && sel.matchesAt(i-1, el.parent)
+	case '+':
+		prev := el.sibling(-1)
+		return prev != nil && sel.matchesAt(i-1, prev)
+	case '~':
+		for prev := el.sibling(-1); prev != nil; prev = prev.sibling(-1) {
+			if sel.matchesAt(i-1, prev) {
+				return true
+			}
+		}
+	default:
+		for parent := el.parent; parent != nil; parent = parent.parent {
+			if sel.matchesAt(i-1, parent) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (compound cssCompound) matches(el *svgElement) bool {
+	if compound.tag != "" && compound.tag != el.tag || compound.id != "" && compound.id != el.attrs["id"] {
+		return false
+	}
+	if 0 < len(compound.classes) {
+		classes := strings.Fields(el.attrs["class"])
+		for _, class := range compound.classes {
+			found := false
+			for _, c := range classes {
+				if c == class {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	for _, attr := range compound.attrs {
+		val, ok := el.attrs[attr.name]
+		if !ok {
+			return false
+		}
+		switch attr.op {
+		case "=":
+			ok = val == attr.value
+		case "~=":
+			ok = false
+			for _, field := range strings.Fields(val) {
+				ok = ok || field == attr.value
+			}
+		case "|=":
+			ok = val == attr.value || strings.HasPrefix(val, attr.value+"-")
+		case "^=":
+			ok = attr.value != "" && strings.HasPrefix(val, attr.value)
+		case "$=":
+			ok = attr.value != "" && strings.HasSuffix(val, attr.value)
+		case "*=":
+			ok = attr.value != "" && strings.Contains(val, attr.value)
+		}
+		if !ok {
+			return false
+		}
+	}
+	for _, pseudo := range compound.pseudos {
+		switch pseudo {
+		case "first-child":
+			if el.sibling(-1) != nil {
+				return false
+			}
+		case "last-child":
+			if el.sibling(1) != nil {
+				return false
+			}
+		case "only-child":
+			if el.sibling(-1) != nil || el.sibling(1) != nil {
+				return false
+			}
+		case "root":
+			if el.parent != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// sibling returns the previous (d = -1) or next (d = 1) sibling element, or nil.
+func (el *svgElement) sibling(d int) *svgElement {
+	if el.parent == nil {
+		return nil
+	}
+	for i, child := range el.parent.children {
+		if child == el {
+			if j := i + d; 0 <= j && j < len(el.parent.children) {
+				return el.parent.children[j]
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// cascadeSVGStyles resolves the properties of every element from its presentation attributes, the rules of the style elements of the document, and its style attribute, in increasing order of precedence, where important declarations take precedence over normal declarations. Properties that are not set are inherited or take their initial value when drawing.
+func cascadeSVGStyles(root *svgElement, b *budget) error {
+	rules := []cssRule{}
+	var collect func(*svgElement)
+	collect = func(el *svgElement) {
+		if el.tag == "style" && (el.attrs["type"] == "" || el.attrs["type"] == "text/css") {
+			rules = append(rules, parseCSSRules(el.text)...)
+		}
+		for _, child := range el.children {
+			collect(child)
+		}
+	}
+	collect(root)
+	sortCSSRules(rules)
+	return cascadeCSS(root, rules, b)
+}
+
+// sortCSSRules sorts the rules by increasing specificity, keeping the order of rules of equal specificity, so that later rules take precedence.
+func sortCSSRules(rules []cssRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].specificity < rules[j].specificity
+	})
+}
+
+// cascadeCSS resolves the properties of the element and its descendants from their presentation attributes, the sorted rules, and their style attributes.
+func cascadeCSS(root *svgElement, rules []cssRule, b *budget) error {
+	var cascade func(*svgElement)
+	cascade = func(el *svgElement) {
+		if b.expired() {
+			return
+		}
+		el.style = map[string]string{}
+		for _, key := range svgProperties {
+			if val, ok := el.attrs[key]; ok {
+				el.style[key] = strings.TrimSpace(val)
+			}
+		}
+		matched := []cssRule{}
+		for _, rule := range rules {
+			if rule.selector.matches(el) {
+				matched = append(matched, rule)
+			}
+		}
+		var inline []cssDeclaration
+		if style, ok := el.attrs["style"]; ok {
+			inline = parseCSSInline(style)
+		}
+		for _, important := range []bool{false, true} {
+			for _, rule := range matched {
+				applyCSS(el.style, rule.declarations, important)
+			}
+			applyCSS(el.style, inline, important)
+		}
+		for _, child := range el.children {
+			cascade(child)
+		}
+	}
+	cascade(root)
+	return b.err
+}
+
+// applyCSS sets the properties of either the normal or the important declarations.
+func applyCSS(style map[string]string, decls []cssDeclaration, important bool) {
+	for _, decl := range decls {
+		if decl.important != important {
+			continue
+		} else if decl.property == "font" {
+			for _, font := range cssFont(decl.value) {
+				style[font.property] = font.value
+			}
+		} else {
+			style[decl.property] = decl.value
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package canvas
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestReadSVGStyles(t *testing.T) {
+	c, err := ReadSVG(strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg" width="100" height="100">
+	<style type="text/css"><![CDATA[
+		.st0 { fill: #00F; stroke: red; stroke-width: 2px }
+		g > .st0 { fill: lime }
+		#special, rect[data-x="1"] { fill: yellow !important }
+		.hidden { display: none }
+		rect:hover { fill: black }
+		@media print { rect { fill: black } }
+	]]></style>
+	<rect class="st0" width="10" height="10"/>
+	<g><rect class="st0 other" width="10" height="10"/></g>
+	<rect id="special" class="st0" width="10" height="10" style="fill: black"/>
+	<rect data-x="1" width="10" height="10" fill="black"/>
+	<rect class="st0" width="10" height="10" style="fill: rgb(0, 0, 0); stroke: none"/>
+	<rect class="hidden" width="10" height="10"/>
+	<rect width="10" height="10" style="transform: translate(10px, 20px) rotate(0.25turn)"/>
+</svg>`))
+	test.Error(t, err)
+	test.T(t, len(c.layers), 6)
+	test.T(t, c.layers[0].style.FillColor, Blue)
+	test.T(t, c.layers[0].style.StrokeColor, Red)
+	test.Float(t, c.layers[0].style.StrokeWidth, 2.0*mmPerPx)
+	test.T(t, c.layers[1].style.FillColor, Lime)
+	test.T(t, c.layers[2].style.FillColor, Yellow)
+	test.T(t, c.layers[3].style.FillColor, Yellow)
+	test.T(t, c.layers[4].style.FillColor, Black)
+	test.T(t, c.layers[4].style.StrokeColor, Transparent)
+	test.T(t, c.layers[5].path.Transform(c.layers[5].m).Transform(Identity.Scale(1.0/mmPerPx, 1.0/mmPerPx)), MustParseSVG("M10 80V70H0V80z"))
+}
+
+func TestCSSSelector(t *testing.T) {
+	root, err := parseSVGTree(strings.NewReader(`<svg><g id="a" class="x y"><rect/><circle lang="en-US"/></g><path/></svg>`), newBudget(Limits{}))
+	test.Error(t, err)
+	g := root.children[0]
+	rect, circle, path := g.children[0], g.children[1], root.children[1]
+
+	var tts = []struct {
+		selector string
+		el       *svgElement
+		matches  bool
+	}{
+		{"rect", rect, true},
+		{"*", rect, true},
+		{"g rect", rect, true},
+		{"svg rect", rect, true},
+		{"svg > rect", rect, false},
+		{"#a > rect", rect, true},
+		{".x.y rect", rect, true},
+		{".x.z rect", rect, false},
+		{"rect + circle", circle, true},
+		{"rect ~ circle", circle, true},
+		{"circle ~ rect", rect, false},
+		{"g + path", path, true},
+		{"rect:first-child", rect, true},
+		{"circle:first-child", circle, false},
+		{"circle:last-child", circle, true},
+		{"svg:root", root, true},
+		{"g:root", g, false},
+		{"[lang]", circle, true},
+		{"[lang|=en]", circle, true},
+		{"[lang^='en']", circle, true},
+		{"[lang$=US]", circle, true},
+		{"[lang*=n-U]", circle, true},
+		{"[lang=en]", circle, false},
+		{"[class~=y]", g, true},
+	}
+	for _, tt := range tts {
+		t.Run(tt.selector, func(t *testing.T) {
+			rules := parseCSSRules(tt.selector + "{fill:red}")
+			test.T(t, len(rules), 1)
+			test.T(t, rules[0].selector.matches(tt.el), tt.matches)
+		})
+	}
+
+	test.T(t, len(parseCSSRules("a::before,a:not(.b),> a{fill:red}")), 0)
+}
+
+func TestCSSSpecificity(t *testing.T) {
+	rules := parseCSSRules("#a .b rect[x], g rect {fill:red}")
+	test.T(t, len(rules), 2)
+	test.T(t, rules[0].specificity, 1<<16|2<<8|1)
+	test.T(t, rules[1].specificity, 2)
+}
+
+func TestCSSFont(t *testing.T) {
+	style := map[string]string{}
+	applyCSS(style, parseCSSInline(`font: italic bold 12px/1.5 "Foo Bar", serif`), false)
+	test.T(t, style["font-style"], "italic")
+	test.T(t, style["font-weight"], "bold")
+	test.T(t, style["font-size"], "12px")
+	test.T(t, style["font-family"], `"Foo Bar", serif`)
+}
@@ -0,0 +1,741 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tdewolff/parse/v2/xml"
+)
+
+// mmPerPx is the size of an SVG user unit or CSS pixel in millimeters.
+const mmPerPx = 25.4 / 96.0
+
+// svgElement is an element of an SVG document with its attributes, child elements, and character data.
+type svgElement struct {
+	tag      string
+	attrs    map[string]string
+	style    map[string]string // properties resolved from the presentation attributes and CSS, see cascadeSVGStyles
+	parent   *svgElement       // nil for the root element
+	children []*svgElement
+	text     string // character data before the first child element
+	tail     string // character data after the element up to its next sibling
+}
+
+// svgInherited are the properties that are inherited by child elements.
+var svgInherited = []string{"fill", "fill-opacity", "fill-rule", "clip-rule", "stroke", "stroke-width", "stroke-opacity", "stroke-linecap", "stroke-linejoin", "stroke-miterlimit", "stroke-dasharray", "stroke-dashoffset", "visibility", "color", "font-family", "font-size", "font-style", "font-weight", "text-anchor"}
+
+// svgMaxNesting is the maximum depth of nested elements, beyond which documents are rejected since they are drawn recursively.
+const svgMaxNesting = 1024
+
+var svgEntities = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", "\"", "&apos;", "'", "&amp;", "&")
+
+// parseSVGTree parses an XML document into a tree of elements and returns the root element. Elements of other namespaces than SVG, such as those of editors, are skipped.
+func parseSVGTree(r io.Reader, b *budget) (*svgElement, error) {
+	root := &svgElement{}
+	stack := []*svgElement{root}
+	l := xml.NewLexer(r)
+	for {
+		tt, data := l.Next()
+		switch tt {
+		case xml.ErrorToken:
+			if l.Err() != io.EOF {
+				return nil, l.Err()
+			}
+			for _, el := range root.children {
+				if el.tag == "svg" {
+					return el, nil
+				}
+			}
+			return nil, fmt.Errorf("bad SVG: no svg element")
+		case xml.StartTagToken:
+			if b.expired() {
+				return nil, b.err
+			} else if svgMaxNesting < len(stack) {
+				return nil, fmt.Errorf("bad SVG: elements nested too deeply")
+			}
+			el := &svgElement{
+				tag:   strings.TrimPrefix(string(l.Text()), "svg:"),
+				attrs: map[string]string{},
+			}
+			parent := stack[len(stack)-1]
+			if parent != nil && !strings.ContainsRune(el.tag, ':') {
+				if parent != root {
+					el.parent = parent
+				}
+				parent.children = append(parent.children, el)
+			} else {
+				el = nil
+			}
+			stack = append(stack, el)
+			for {
+				tt, _ = l.Next()
+				if tt != xml.AttributeToken {
+					break
+				}
+				val := l.AttrVal()
+				if 1 < len(val) && (val[0] == '\'' || val[0] == '"') && val[0] == val[len(val)-1] {
+					val = val[1 : len(val)-1]
+				}
+				if el != nil {
+					el.attrs[string(l.Text())] = svgEntities.Replace(string(val))
+				}
+			}
+			if tt == xml.StartTagCloseVoidToken {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.EndTagToken:
+			if 1 < len(stack) {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.TextToken, xml.CDATAToken:
+			if el := stack[len(stack)-1]; el != nil {
+				if tt == xml.CDATAToken {
+					data = bytes.TrimSuffix(bytes.TrimPrefix(data, []byte("<![CDATA[")), []byte("]]>"))
+				} else {
+					data = []byte(svgEntities.Replace(string(data)))
+				}
+				if 0 < len(el.children) {
+					el.children[len(el.children)-1].tail += string(data)
+				} else {
+					el.text += string(data)
+				}
+			}
+		}
+	}
+}
+
+// svgState is the state that is passed down to child elements, with m the transformation from user units to the canvas.
+type svgState struct {
+	m        Matrix
+	props    map[string]string
+	opacity  float64
+	viewport [2]float64 // width and height of the viewport in user units
+	clip     *Path      // clipping region in canvas coordinates, or nil
+	mask     []svgMaskPiece
+	masked   bool
+}
+
+type svgImporter struct {
+	c           *Canvas
+	ids         map[string]*svgElement
+	using       map[*svgElement]bool // elements that are being instantiated by use elements
+	depth       int
+	budget      *budget
+	fonts       *FontRegistry
+	textToPaths bool
+}
+
+// SVGOptions are the options of ReadSVGWithOptions.
+type SVGOptions struct {
+	Limits      Limits        // resource limits for untrusted documents, see SafeLimits
+	Fonts       *FontRegistry // fonts of text elements, which are not drawn when nil
+	TextToPaths bool          // draw text as outlines, so that the output does not depend on the fonts of the renderer
+}
+
+// ReadSVG reads an SVG document and returns a canvas that draws it, with the size of the canvas given by the width and height of the document, where one user unit or pixel is 1/96 inch. It supports paths and the basic shapes, groups, nested SVG elements, use elements that refer to other elements, embedded images, transforms, and the fill and stroke properties, which are set by presentation attributes or by CSS in style elements and style attributes using type, class, id, and attribute selectors. Linear and radial gradients are approximated by bands of flat colors, since canvas has no gradient fills, and patterns are drawn tile by tile. Clip paths and masks are applied by intersecting the shapes, where masks are approximated by regions of flat opacity from the luminance of their content, and do not apply to images. Group opacity is approximated by applying it to the colors of each element. Text elements are not drawn, since they require fonts, see ReadSVGWithOptions.
+func ReadSVG(r io.Reader) (*Canvas, error) {
+	return ReadSVGWithOptions(r, SVGOptions{})
+}
+
+// ReadSVGWithOptions reads an SVG document like ReadSVG. The limits of the options are enforced on the whole document, where path segments are counted for every time that a use element draws them, and images are checked before they are decoded. It returns an error wrapping ErrLimitExceeded when a limit is exceeded. Text elements are drawn in the fonts of the options, which are resolved by their font-family, font-weight, and font-style, and are laid out with tspan elements, lists of character positions, and text-anchor, where whitespace is collapsed unless xml:space is preserve.
+func ReadSVGWithOptions(r io.Reader, opts SVGOptions) (*Canvas, error) {
+	b := newBudget(opts.Limits)
+	root, err := parseSVGTree(r, b)
+	if err != nil {
+		return nil, err
+	} else if err := cascadeSVGStyles(root, b); err != nil {
+		return nil, err
+	}
+
+	viewBox, hasViewBox := parseSVGViewBox(root.attrs["viewBox"])
+	width, height := 300.0, 150.0
+	if hasViewBox {
+		width, height = viewBox.W, viewBox.H
+	}
+	if w, ok := root.attrs["width"]; ok && !strings.HasSuffix(w, "%") {
+		width = svgLength(w, width)
+		if !hasViewBox {
+			viewBox.W = width
+		}
+	}
+	if h, ok := root.attrs["height"]; ok && !strings.HasSuffix(h, "%") {
+		height = svgLength(h, height)
+		if !hasViewBox {
+			viewBox.H = height
+		}
+	}
+	if !hasViewBox {
+		viewBox = Rect{0.0, 0.0, width, height}
+	}
+
+	s := &svgImporter{
+		c:           New(width*mmPerPx, height*mmPerPx),
+		ids:         map[string]*svgElement{},
+		using:       map[*svgElement]bool{},
+		budget:      b,
+		fonts:       opts.Fonts,
+		textToPaths: opts.TextToPaths,
+	}
+	s.index(root)
+	if width <= 0.0 || height <= 0.0 {
+		return s.c, nil // a viewport of zero size disables rendering
+	}
+	view := Identity.Translate(0.0, height*mmPerPx).Scale(mmPerPx, -mmPerPx)
+	view = view.Mul(svgViewBoxTransform(viewBox, Rect{0.0, 0.0, width, height}, root.attrs["preserveAspectRatio"]))
+	state := svgState{
+		m:        view,
+		props:    map[string]string{},
+		opacity:  1.0,
+		viewport: [2]float64{viewBox.W, viewBox.H},
+	}
+	s.children(root, state)
+	if b.err != nil {
+		return nil, b.err
+	}
+	return s.c, nil
+}
+
+// readSVGElement reads an SVG document and draws only the element with the given id, such as a glyph of the SVG table of a font, with m the transformation from the user units of the document to the canvas, including the transformations of the ancestors of the element. It returns nil if the document has no such element.
+func readSVGElement(r io.Reader, id string, m Matrix, viewport [2]float64, limits Limits) (*Canvas, error) {
+	b := newBudget(limits)
+	root, err := parseSVGTree(r, b)
+	if err != nil {
+		return nil, err
+	} else if err := cascadeSVGStyles(root, b); err != nil {
+		return nil, err
+	}
+
+	s := &svgImporter{
+		c:      New(0.0, 0.0),
+		ids:    map[string]*svgElement{},
+		using:  map[*svgElement]bool{},
+		budget: b,
+	}
+	s.index(root)
+	el, ok := s.ids[id]
+	if !ok {
+		return nil, nil
+	}
+	ancestors := []*svgElement{}
+	for parent := el.parent; parent != nil; parent = parent.parent {
+		ancestors = append(ancestors, parent)
+	}
+	for i := len(ancestors) - 1; 0 <= i; i-- {
+		m = m.Mul(parseSVGTransform(ancestors[i].style["transform"]))
+	}
+	state := svgState{
+		m:        m,
+		props:    svgInheritedProps(el.parent),
+		opacity:  1.0,
+		viewport: viewport,
+	}
+	s.element(el, state)
+	if b.err != nil {
+		return nil, b.err
+	}
+	return s.c, nil
+}
+
+// index registers all elements with an id.
+func (s *svgImporter) index(el *svgElement) {
+	if id, ok := el.attrs["id"]; ok {
+		s.ids[id] = el
+	}
+	for _, child := range el.children {
+		s.index(child)
+	}
+}
+
+func (s *svgImporter) children(el *svgElement, state svgState) {
+	for _, child := range el.children {
+		if child.tag != "symbol" {
+			s.element(child, state) // symbols are only drawn by use elements
+		}
+	}
+}
+
+// element draws an element and its children.
+func (s *svgImporter) element(el *svgElement, state svgState) {
+	if el.style["display"] == "none" || s.budget.expired() {
+		return
+	}
+	state.props = svgInherit(state.props, el)
+	if opacity, ok := el.style["opacity"]; ok {
+		state.opacity *= svgOpacity(opacity)
+	}
+	state.m = state.m.Mul(parseSVGTransform(el.style["transform"]))
+	if !svgInvertible(state.m) {
+		return // a singular transformation disables rendering
+	}
+	if id, _ := svgURL(el.style["clip-path"]); id != "" {
+		if ref, ok := s.ids[id]; ok && ref.tag == "clipPath" {
+			clip := s.clipPath(ref, el, state)
+			if state.clip != nil {
+				clip = clip.And(state.clip)
+			}
+			if clip.Empty() {
+				return
+			}
+			state.clip = clip
+		}
+	}
+	if id, _ := svgURL(el.style["mask"]); id != "" {
+		if ref, ok := s.ids[id]; ok && ref.tag == "mask" {
+			state.mask, state.masked = s.maskPieces(ref, el, state), true
+			if len(state.mask) == 0 {
+				return
+			}
+		}
+	}
+
+	switch el.tag {
+	case "g", "a", "switch":
+		s.children(el, state)
+	case "svg", "symbol":
+		x := svgLength(el.attrs["x"], state.viewport[0])
+		y := svgLength(el.attrs["y"], state.viewport[1])
+		w, h := state.viewport[0], state.viewport[1]
+		if width, ok := el.attrs["width"]; ok {
+			w = svgLength(width, state.viewport[0])
+		}
+		if height, ok := el.attrs["height"]; ok {
+			h = svgLength(height, state.viewport[1])
+		}
+		if w <= 0.0 || h <= 0.0 {
+			return
+		}
+		viewport := Rect{x, y, w, h}
+		viewBox, ok := parseSVGViewBox(el.attrs["viewBox"])
+		if !ok {
+			viewBox = viewport
+		}
+		state.m = state.m.Mul(svgViewBoxTransform(viewBox, viewport, el.attrs["preserveAspectRatio"]))
+		state.viewport = [2]float64{viewBox.W, viewBox.H}
+		s.children(el, state)
+	case "use":
+		target, ok := s.ids[svgHref(el)]
+		if !ok || 32 < s.depth || s.using[target] || svgIsAncestor(target, el) {
+			return // missing or circular reference, which is an error
+		}
+		ref := target
+		state.m = state.m.Translate(svgLength(el.attrs["x"], state.viewport[0]), svgLength(el.attrs["y"], state.viewport[1]))
+		if ref.tag == "symbol" || ref.tag == "svg" {
+			// the size of the use element overrides the size of the symbol
+			ref = &svgElement{ref.tag, copySVGAttrs(ref.attrs, "x", "y"), ref.style, ref.parent, ref.children, ref.text, ref.tail}
+			for _, key := range []string{"width", "height"} {
+				if val, ok := el.attrs[key]; ok {
+					ref.attrs[key] = val
+				}
+			}
+		}
+		s.depth++
+		s.using[target] = true
+		s.element(ref, state)
+		delete(s.using, target)
+		s.depth--
+	case "path", "rect", "circle", "ellipse", "line", "polyline", "polygon":
+		if p := svgShape(el, state.viewport); p != nil && !p.Empty() && s.visible(state) {
+			s.drawPath(p, state)
+		}
+	case "image":
+		if s.visible(state) {
+			s.image(el, state)
+		}
+	case "text":
+		s.text(el, state)
+	}
+}
+
+// svgIsAncestor returns true if el is a descendant of ancestor or is ancestor itself.
+func svgIsAncestor(ancestor, el *svgElement) bool {
+	for ; el != nil; el = el.parent {
+		if el == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// svgInherit returns the properties of an element given the properties inherited from its parent. Font sizes are resolved to user units, since relative sizes are relative to the font size of the parent.
+func svgInherit(props map[string]string, el *svgElement) map[string]string {
+	inherited := make(map[string]string, len(props))
+	for key, val := range props {
+		inherited[key] = val
+	}
+	for _, key := range svgInherited {
+		if val, ok := el.style[key]; ok && val != "inherit" {
+			inherited[key] = val
+		}
+	}
+	if val, ok := el.style["font-size"]; ok && val != "inherit" {
+		inherited["font-size"] = strconv.FormatFloat(svgRelativeFontSize(val, svgFontSize(props)), 'g', -1, 64)
+	}
+	return inherited
+}
+
+// svgInheritedProps returns the properties of an element that is not drawn through its ancestors, such as the children of clip paths.
+func svgInheritedProps(el *svgElement) map[string]string {
+	if el == nil {
+		return map[string]string{}
+	}
+	return svgInherit(svgInheritedProps(el.parent), el)
+}
+
+func (s *svgImporter) visible(state svgState) bool {
+	visibility := state.props["visibility"]
+	return visibility != "hidden" && visibility != "collapse"
+}
+
+// image draws an image element of which the image is embedded as a data URI.
+func (s *svgImporter) image(el *svgElement, state svgState) {
+	href, ok := el.attrs["href"]
+	if !ok {
+		href = el.attrs["xlink:href"]
+	}
+	comma := strings.IndexByte(href, ',')
+	if !strings.HasPrefix(href, "data:") || comma == -1 || !strings.HasSuffix(href[:comma], ";base64") {
+		return
+	}
+	data := strings.TrimSpace(href[comma+1:])
+	if config, _, err := image.DecodeConfig(base64.NewDecoder(base64.StdEncoding, strings.NewReader(data))); err != nil || !s.budget.addPixels(config.Width, config.Height) {
+		return
+	}
+	img, _, err := image.Decode(base64.NewDecoder(base64.StdEncoding, strings.NewReader(data)))
+	if err != nil {
+		return
+	}
+	size := img.Bounds().Size()
+	w, h := float64(size.X), float64(size.Y)
+	x := svgLength(el.attrs["x"], state.viewport[0])
+	y := svgLength(el.attrs["y"], state.viewport[1])
+	if width, ok := el.attrs["width"]; ok {
+		w = svgLength(width, state.viewport[0])
+	}
+	if height, ok := el.attrs["height"]; ok {
+		h = svgLength(height, state.viewport[1])
+	}
+	if w <= 0.0 || h <= 0.0 || size.X == 0 || size.Y == 0 {
+		return
+	}
+	// images have their origin at the bottom-left with the y-axis pointing up
+	m := state.m.Mul(svgViewBoxTransform(Rect{0.0, 0.0, float64(size.X), float64(size.Y)}, Rect{x, y, w, h}, el.attrs["preserveAspectRatio"]))
+	s.c.RenderImage(img, m.Translate(0.0, float64(size.Y)).Scale(1.0, -1.0))
+}
+
+func copySVGAttrs(attrs map[string]string, skip ...string) map[string]string {
+	copied := make(map[string]string, len(attrs))
+	for key, val := range attrs {
+		copied[key] = val
+	}
+	for _, key := range skip {
+		delete(copied, key)
+	}
+	return copied
+}
+
+// svgShape returns the path of a shape element in user units.
+func svgShape(el *svgElement, viewport [2]float64) *Path {
+	length := func(key string, ref float64) float64 {
+		return svgLength(el.attrs[key], ref)
+	}
+	diagonal := math.Hypot(viewport[0], viewport[1]) / math.Sqrt2
+
+	p := &Path{}
+	switch el.tag {
+	case "path":
+		var err error
+		if p, err = ParseSVG(strings.TrimSpace(el.attrs["d"])); err != nil {
+			return nil
+		}
+	case "rect":
+		x, y := length("x", viewport[0]), length("y", viewport[1])
+		w, h := length("width", viewport[0]), length("height", viewport[1])
+		rx, hasRx := el.attrs["rx"]
+		ry, hasRy := el.attrs["ry"]
+		if !hasRx {
+			rx = ry
+		} else if !hasRy {
+			ry = rx
+		}
+		radiusX := math.Min(svgLength(rx, viewport[0]), w/2.0)
+		radiusY := math.Min(svgLength(ry, viewport[1]), h/2.0)
+		if w <= 0.0 || h <= 0.0 {
+			return nil
+		} else if radiusX <= 0.0 || radiusY <= 0.0 {
+			return Rectangle(w, h).Translate(x, y)
+		}
+		p.MoveTo(x+radiusX, y)
+		p.LineTo(x+w-radiusX, y)
+		p.ArcTo(radiusX, radiusY, 0.0, false, true, x+w, y+radiusY)
+		p.LineTo(x+w, y+h-radiusY)
+		p.ArcTo(radiusX, radiusY, 0.0, false, true, x+w-radiusX, y+h)
+		p.LineTo(x+radiusX, y+h)
+		p.ArcTo(radiusX, radiusY, 0.0, false, true, x, y+h-radiusY)
+		p.LineTo(x, y+radiusY)
+		p.ArcTo(radiusX, radiusY, 0.0, false, true, x+radiusX, y)
+		p.Close()
+	case "circle":
+		r := length("r", diagonal)
+		if r <= 0.0 {
+			return nil
+		}
+		p = Circle(r).Translate(length("cx", viewport[0]), length("cy", viewport[1]))
+	case "ellipse":
+		rx, ry := length("rx", viewport[0]), length("ry", viewport[1])
+		if rx <= 0.0 || ry <= 0.0 {
+			return nil
+		}
+		p = Ellipse(rx, ry).Translate(length("cx", viewport[0]), length("cy", viewport[1]))
+	case "line":
+		p.MoveTo(length("x1", viewport[0]), length("y1", viewport[1]))
+		p.LineTo(length("x2", viewport[0]), length("y2", viewport[1]))
+	case "polyline", "polygon":
+		nums := svgNumbers(el.attrs["points"])
+		for i := 0; i+1 < len(nums); i += 2 {
+			if i == 0 {
+				p.MoveTo(nums[i], nums[i+1])
+			} else {
+				p.LineTo(nums[i], nums[i+1])
+			}
+		}
+		if el.tag == "polygon" && 2 < len(nums) {
+			p.Close()
+		}
+	}
+	return p
+}
+
+// svgStyle returns the style of the element from its properties, with the stroke width and dashes scaled to the canvas.
+func svgStyle(state svgState) Style {
+	props := state.props
+	style := DefaultStyle
+	style.FillColor = svgPaint(props, "fill", Black, svgOpacity(props["fill-opacity"])*state.opacity)
+	style.StrokeColor = svgPaint(props, "stroke", Transparent, svgOpacity(props["stroke-opacity"])*state.opacity)
+	if props["fill-rule"] == "evenodd" {
+		style.FillRule = EvenOdd
+	}
+
+	// strokes are applied after transforming the path
+	scale := math.Sqrt(math.Abs(state.m.Det()))
+	strokeWidth := 1.0
+	if val, ok := props["stroke-width"]; ok {
+		strokeWidth = svgLength(val, math.Hypot(state.viewport[0], state.viewport[1])/math.Sqrt2)
+	}
+	style.StrokeWidth = strokeWidth * scale
+
+	switch props["stroke-linecap"] {
+	case "round":
+		style.StrokeCapper = RoundCap
+	case "square":
+		style.StrokeCapper = SquareCap
+	}
+	miterLimit := 4.0
+	if val, ok := props["stroke-miterlimit"]; ok {
+		if limit, err := strconv.ParseFloat(val, 64); err == nil && 1.0 <= limit {
+			miterLimit = limit
+		}
+	}
+	switch props["stroke-linejoin"] {
+	case "round":
+		style.StrokeJoiner = RoundJoin
+	case "bevel":
+		style.StrokeJoiner = BevelJoin
+	case "arcs":
+		style.StrokeJoiner = ArcsJoiner{BevelJoin, miterLimit}
+	default:
+		style.StrokeJoiner = MiterJoiner{BevelJoin, miterLimit * style.StrokeWidth / 2.0}
+	}
+
+	if dashes := svgNumbers(props["stroke-dasharray"]); 0 < len(dashes) {
+		sum := 0.0
+		for i := range dashes {
+			sum += dashes[i]
+			dashes[i] *= scale
+		}
+		if 0.0 < sum {
+			style.Dashes = dashes
+			style.DashOffset = svgLength(props["stroke-dashoffset"], 0.0) * scale
+		}
+	}
+	return style
+}
+
+// svgPaint returns the color of a fill or stroke property. For references to paint servers it returns the fallback color, which is only used when the paint server does not exist.
+func svgPaint(props map[string]string, key string, initial color.RGBA, opacity float64) color.RGBA {
+	val, ok := props[key]
+	if !ok {
+		return scaleAlpha(initial, opacity)
+	} else if strings.HasPrefix(val, "url(") {
+		_, val = svgURL(val)
+	}
+	if val == "currentColor" {
+		val = props["color"]
+	}
+	col, err := ParseCSSColor(val)
+	if err != nil {
+		return Transparent
+	}
+	return scaleAlpha(col, opacity)
+}
+
+// scaleAlpha multiplies the alpha of a premultiplied color.
+func scaleAlpha(col color.RGBA, f float64) color.RGBA {
+	if 1.0 <= f {
+		return col
+	}
+	f = math.Max(0.0, f)
+	return color.RGBA{uint8(float64(col.R)*f + 0.5), uint8(float64(col.G)*f + 0.5), uint8(float64(col.B)*f + 0.5), uint8(float64(col.A)*f + 0.5)}
+}
+
+// svgOpacity parses an opacity as a number or percentage, which is one when empty or invalid.
+func svgOpacity(s string) float64 {
+	s = strings.TrimSpace(s)
+	percentage := strings.HasSuffix(s, "%")
+	f, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return 1.0
+	} else if percentage {
+		f /= 100.0
+	}
+	return math.Max(0.0, math.Min(1.0, f))
+}
+
+// svgLength parses a length with an optional unit into user units, where percentages are relative to ref. It returns zero when empty or invalid.
+func svgLength(s string, ref float64) float64 {
+	s = strings.TrimSpace(s)
+	i := len(s)
+	for 0 < i && ('a' <= s[i-1] && s[i-1] <= 'z' || s[i-1] == '%') {
+		i--
+	}
+	f, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0.0
+	}
+	switch s[i:] {
+	case "mm":
+		f /= mmPerPx
+	case "cm":
+		f *= 10.0 / mmPerPx
+	case "in":
+		f *= 96.0
+	case "pt":
+		f *= 96.0 / 72.0
+	case "pc":
+		f *= 16.0
+	case "em":
+		f *= 16.0
+	case "ex":
+		f *= 8.0
+	case "%":
+		f *= ref / 100.0
+	}
+	return f
+}
+
+// svgNumbers parses a list of numbers separated by commas or whitespace.
+func svgNumbers(s string) []float64 {
+	nums := []float64{}
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r' }) {
+		f, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nums
+		}
+		nums = append(nums, f)
+	}
+	return nums
+}
+
+func parseSVGViewBox(s string) (Rect, bool) {
+	nums := svgNumbers(s)
+	if len(nums) != 4 || nums[2] <= 0.0 || nums[3] <= 0.0 {
+		return Rect{}, false
+	}
+	return Rect{nums[0], nums[1], nums[2], nums[3]}, true
+}
+
+// svgViewBoxTransform returns the transformation that maps the view box onto the viewport, respecting the preserveAspectRatio attribute.
+func svgViewBoxTransform(viewBox, viewport Rect, preserveAspectRatio string) Matrix {
+	sx, sy := viewport.W/viewBox.W, viewport.H/viewBox.H
+	fields := strings.Fields(preserveAspectRatio)
+	align, slice := "xMidYMid", false
+	if 0 < len(fields) {
+		align = fields[0]
+		slice = 1 < len(fields) && fields[1] == "slice"
+	}
+	tx, ty := viewport.X, viewport.Y
+	if align != "none" {
+		if slice {
+			sx = math.Max(sx, sy)
+		} else {
+			sx = math.Min(sx, sy)
+		}
+		sy = sx
+		if strings.Contains(align, "xMid") {
+			tx += (viewport.W - viewBox.W*sx) / 2.0
+		} else if strings.Contains(align, "xMax") {
+			tx += viewport.W - viewBox.W*sx
+		}
+		if strings.Contains(align, "YMid") {
+			ty += (viewport.H - viewBox.H*sy) / 2.0
+		} else if strings.Contains(align, "YMax") {
+			ty += viewport.H - viewBox.H*sy
+		}
+	}
+	return Identity.Translate(tx, ty).Scale(sx, sy).Translate(-viewBox.X, -viewBox.Y)
+}
+
+// svgInvertible returns whether a transformation is finite and can be inverted.
+func svgInvertible(m Matrix) bool {
+	for _, v := range []float64{m[0][0], m[0][1], m[0][2], m[1][0], m[1][1], m[1][2]} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return !equal(m.Det(), 0.0)
+}
+
+// parseSVGTransform parses the transform attribute, and returns the identity for invalid transforms.
+func parseSVGTransform(s string) Matrix {
+	m := Identity
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeft(s, " ,\t\n\r") {
+		open, end := strings.IndexByte(s, '('), strings.IndexByte(s, ')')
+		if open == -1 || end < open {
+			return Identity
+		}
+		name := strings.TrimSpace(s[:open])
+		v := svgNumbers(s[open+1 : end])
+		s = s[end+1:]
+		n := len(v)
+		switch {
+		case name == "matrix" && n == 6:
+			m = m.Mul(Matrix{{v[0], v[2], v[4]}, {v[1], v[3], v[5]}})
+		case name == "translate" && n == 1:
+			m = m.Translate(v[0], 0.0)
+		case name == "translate" && n == 2:
+			m = m.Translate(v[0], v[1])
+		case name == "scale" && n == 1:
+			m = m.Scale(v[0], v[0])
+		case name == "scale" && n == 2:
+			m = m.Scale(v[0], v[1])
+		case name == "rotate" && n == 1:
+			// same matrix as Rotate, which is clockwise when the y-axis points down
+			m = m.Rotate(v[0])
+		case name == "rotate" && n == 3:
+			m = m.RotateAbout(v[0], v[1], v[2])
+		case name == "skewX" && n == 1:
+			m = m.Shear(math.Tan(v[0]*math.Pi/180.0), 0.0)
+		case name == "skewY" && n == 1:
+			m = m.Shear(0.0, math.Tan(v[0]*math.Pi/180.0))
+		default:
+			return Identity
+		}
+	}
+	return m
+}
@@ -0,0 +1,78 @@
+package canvas
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestReadSVG(t *testing.T) {
+	c, err := ReadSVG(strings.NewReader(`<?xml version="1.0"?>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="20mm" height="10mm" viewBox="0 0 200 100">
+	<sodipodi:namedview pagecolor="#fff"/>
+	<defs><rect id="r" width="10" height="10"/></defs>
+	<g fill="red" stroke="blue" stroke-width="2" transform="translate(10,0)">
+		<rect x="10" y="20" width="30" height="40" fill-opacity=".5"/>
+		<circle cx="50" cy="50" r="10" stroke="none"/>
+	</g>
+	<use xlink:href="#r" x="100" y="50"/>
+	<path d="M0 0L10 10" display="none"/>
+</svg>`))
+	test.Error(t, err)
+	test.Float(t, c.W, 20.0)
+	test.Float(t, c.H, 10.0)
+	test.T(t, len(c.layers), 3)
+
+	// one user unit is 0.1mm and the y-axis is flipped
+	rect := c.layers[0]
+	test.T(t, rect.path.Transform(rect.m), MustParseSVG("M2 8H5V4H2z"))
+	test.T(t, rect.style.FillColor, scaleAlpha(Red, 0.5))
+	test.T(t, rect.style.StrokeColor, Blue)
+	test.Float(t, rect.style.StrokeWidth, 0.2)
+	test.T(t, c.layers[1].style.StrokeColor, Transparent)
+	test.T(t, c.layers[2].path.Transform(c.layers[2].m), MustParseSVG("M10 5H11V4H10z"))
+	test.T(t, c.layers[2].style.FillColor, Black)
+
+	_, err = ReadSVG(strings.NewReader(`<html></html>`))
+	test.That(t, err != nil)
+}
+
+func TestReadSVGCircularUse(t *testing.T) {
+	c, err := ReadSVG(strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"><g id="a"><rect width="1" height="1"/><use href="#a"/><use href="#a"/></g><use id="b" href="#b"/></svg>`))
+	test.Error(t, err)
+	test.T(t, len(c.layers), 1)
+}
+
+func TestSVGLength(t *testing.T) {
+	var tts = []struct {
+		s      string
+		length float64
+	}{
+		{"10", 10.0},
+		{"1in", 96.0},
+		{"2.54cm", 96.0},
+		{"72pt", 96.0},
+		{"50%", 50.0},
+		{"", 0.0},
+	}
+	for _, tt := range tts {
+		t.Run(tt.s, func(t *testing.T) {
+			test.Float(t, svgLength(tt.s, 100.0), tt.length)
+		})
+	}
+}
+
+func TestParseSVGTransform(t *testing.T) {
+	test.T(t, parseSVGTransform("translate(1,2) scale(2)"), Identity.Translate(1.0, 2.0).Scale(2.0, 2.0))
+	test.T(t, parseSVGTransform("matrix(1 2 3 4 5 6)"), Matrix{{1.0, 3.0, 5.0}, {2.0, 4.0, 6.0}})
+	test.T(t, parseSVGTransform("rotate(90 10 10)"), Identity.RotateAbout(90.0, 10.0, 10.0))
+	test.T(t, parseSVGTransform("scale(1,2,3)"), Identity)
+}
+
+func TestSVGViewBoxTransform(t *testing.T) {
+	viewBox := Rect{0.0, 0.0, 10.0, 10.0}
+	test.T(t, svgViewBoxTransform(viewBox, Rect{0.0, 0.0, 20.0, 40.0}, ""), Identity.Translate(0.0, 10.0).Scale(2.0, 2.0))
+	test.T(t, svgViewBoxTransform(viewBox, Rect{0.0, 0.0, 20.0, 40.0}, "xMinYMax slice"), Identity.Translate(0.0, 0.0).Scale(4.0, 4.0))
+	test.T(t, svgViewBoxTransform(viewBox, Rect{0.0, 0.0, 20.0, 40.0}, "none"), Identity.Scale(2.0, 4.0))
+}
@@ -0,0 +1,655 @@
+package canvas
+
+import (
+	"image/color"
+	"math"
+	"strings"
+)
+
+// svgMaxTiles is the maximum number of tiles that a pattern draws for a single shape.
+const svgMaxTiles = 4096
+
+// svgBandWidth is the width in millimeters below which gradient bands are not subdivided further.
+const svgBandWidth = 0.1
+
+// svgMaskPiece is a region of a mask in canvas coordinates with its opacity.
+type svgMaskPiece struct {
+	path  *Path
+	alpha float64
+}
+
+// svgStop is a gradient stop with a color that is not premultiplied, in the range [0,1].
+type svgStop struct {
+	offset     float64
+	r, g, b, a float64
+}
+
+// svgBand is a band of a gradient between two values of its parameter t with a flat color.
+type svgBand struct {
+	t0, t1 float64
+	color  color.RGBA
+}
+
+// svgURL parses a reference such as url(#id) and returns the id and the fallback that follows it.
+func svgURL(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "url(") {
+		return "", s
+	}
+	end := strings.IndexByte(s, ')')
+	if end == -1 {
+		return "", ""
+	}
+	ref := strings.Trim(strings.TrimSpace(s[4:end]), `'"`)
+	if !strings.HasPrefix(ref, "#") {
+		return "", strings.TrimSpace(s[end+1:])
+	}
+	return ref[1:], strings.TrimSpace(s[end+1:])
+}
+
+// svgHref returns the id that the href attribute of an element refers to, or an empty string.
+func svgHref(el *svgElement) string {
+	href, ok := el.attrs["href"]
+	if !ok {
+		href = el.attrs["xlink:href"]
+	}
+	if !strings.HasPrefix(href, "#") {
+		return ""
+	}
+	return href[1:]
+}
+
+// paintServer returns the gradient or pattern element that a fill or stroke property refers to, or nil.
+func (s *svgImporter) paintServer(val string) *svgElement {
+	if id, _ := svgURL(val); id != "" {
+		if el, ok := s.ids[id]; ok && (el.tag == "linearGradient" || el.tag == "radialGradient" || el.tag == "pattern") {
+			return el
+		}
+	}
+	return nil
+}
+
+// inheritedAttr returns an attribute of a gradient or pattern, which is inherited from the elements that it refers to by href when it is not specified.
+func (s *svgImporter) inheritedAttr(el *svgElement, key, initial string) string {
+	for i := 0; i < 32 && el != nil; i++ {
+		if val, ok := el.attrs[key]; ok {
+			return val
+		}
+		el = s.ids[svgHref(el)]
+	}
+	return initial
+}
+
+// inheritedContent returns the first gradient or pattern in the chain of elements referred to by href that has child elements, which are the stops or the content of the pattern.
+func (s *svgImporter) inheritedContent(el *svgElement) *svgElement {
+	for i := 0; i < 32 && el != nil; i++ {
+		for _, child := range el.children {
+			if el.tag == "pattern" || child.tag == "stop" {
+				return el
+			}
+		}
+		el = s.ids[svgHref(el)]
+	}
+	return nil
+}
+
+// bounds returns the bounding box of the geometry of an element in its user space, which is used for objectBoundingBox units.
+func (s *svgImporter) bounds(el *svgElement, viewport [2]float64) Rect {
+	switch el.tag {
+	case "g", "a", "switch":
+		r := Rect{}
+		for _, child := range el.children {
+			r = r.Add(s.bounds(child, viewport).Transform(parseSVGTransform(child.style["transform"])))
+		}
+		return r
+	case "use":
+		if ref, ok := s.ids[svgHref(el)]; ok && ref != el && s.depth < 32 {
+			s.depth++
+			r := s.bounds(ref, viewport).Transform(parseSVGTransform(ref.style["transform"]))
+			s.depth--
+			return r.Move(Point{svgLength(el.attrs["x"], viewport[0]), svgLength(el.attrs["y"], viewport[1])})
+		}
+	case "text":
+		r := Rect{}
+		for _, run := range s.textRuns(el, svgState{m: Identity, props: svgInheritedProps(el), opacity: 1.0, viewport: viewport}) {
+			r = r.Add(run.text.Bounds().Transform(run.m()))
+		}
+		return r
+	case "image":
+		return Rect{svgLength(el.attrs["x"], viewport[0]), svgLength(el.attrs["y"], viewport[1]), svgLength(el.attrs["width"], viewport[0]), svgLength(el.attrs["height"], viewport[1])}
+	default:
+		if p := svgShape(el, viewport); p != nil {
+			return p.Bounds()
+		}
+	}
+	return Rect{}
+}
+
+// drawPath draws a shape, where shapes that are painted by gradients or patterns, or that are clipped or masked, are converted to filled regions in canvas coordinates.
+func (s *svgImporter) drawPath(p *Path, state svgState) {
+	style := svgStyle(state)
+	fill, stroke := s.paintServer(state.props["fill"]), s.paintServer(state.props["stroke"])
+	if !s.budget.addPath(p, style, state.m) {
+		return
+	} else if state.clip == nil && !state.masked && fill == nil && stroke == nil {
+		s.c.RenderPath(p, style, state.m)
+		return
+	}
+
+	bbox := p.Bounds()
+	p = p.Transform(state.m)
+	if fill != nil || style.FillColor.A != 0 {
+		opacity := svgOpacity(state.props["fill-opacity"]) * state.opacity
+		s.fillRegion(p.Settle(style.FillRule), fill, style.FillColor, opacity, bbox, state)
+	}
+	if (stroke != nil || style.StrokeColor.A != 0) && 0.0 < style.StrokeWidth {
+		if 0 < len(style.Dashes) {
+			p = p.Dash(style.DashOffset, style.Dashes...)
+		}
+		opacity := svgOpacity(state.props["stroke-opacity"]) * state.opacity
+		s.fillRegion(p.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner).Settle(NonZero), stroke, style.StrokeColor, opacity, bbox, state)
+	}
+}
+
+// fillRegion fills a region in canvas coordinates with a flat color, or with the gradient or pattern of server, where bbox is the bounding box in user units of the shape that is painted.
+func (s *svgImporter) fillRegion(region *Path, server *svgElement, col color.RGBA, opacity float64, bbox Rect, state svgState) {
+	if state.clip != nil {
+		region = region.And(state.clip)
+	}
+	if region.Empty() || s.budget.expired() {
+		return
+	}
+	switch {
+	case server == nil:
+		s.emit(region, col, state)
+	case server.tag == "pattern":
+		s.pattern(server, region, opacity, bbox, state)
+	default:
+		for _, band := range s.gradient(server, region, opacity, bbox, state) {
+			if s.budget.expired() {
+				return
+			}
+			s.emit(band.path, band.color, state)
+		}
+	}
+}
+
+// emit draws a region in canvas coordinates with a flat color, which is drawn once for each piece of the mask.
+func (s *svgImporter) emit(region *Path, col color.RGBA, state svgState) {
+	if col.A == 0 {
+		return
+	}
+	style := DefaultStyle
+	style.FillColor = col
+	if !state.masked {
+		s.c.RenderPath(region, style, Identity)
+		return
+	}
+	for _, piece := range state.mask {
+		if masked := region.And(piece.path); !masked.Empty() {
+			style.FillColor = scaleAlpha(col, piece.alpha)
+			s.c.RenderPath(masked, style, Identity)
+		}
+	}
+}
+
+// svgPiece is a region in canvas coordinates with a flat color.
+type svgPiece struct {
+	path  *Path
+	color color.RGBA
+}
+
+// gradient slices a region into pieces of flat colors that approximate a linear or radial gradient, since canvas has no gradient fills. Opaque pieces overlap the pieces drawn before them to hide the seams between them, translucent pieces may show hairline seams due to anti-aliasing.
+func (s *svgImporter) gradient(el *svgElement, region *Path, opacity float64, bbox Rect, state svgState) []svgPiece {
+	stops := s.gradientStops(el)
+	if len(stops) == 0 {
+		return nil // paints nothing
+	}
+
+	objectBoundingBox := s.inheritedAttr(el, "gradientUnits", "objectBoundingBox") != "userSpaceOnUse"
+	m := state.m
+	w, h := state.viewport[0], state.viewport[1]
+	if objectBoundingBox {
+		if bbox.W == 0.0 || bbox.H == 0.0 {
+			return nil
+		}
+		m = m.Translate(bbox.X, bbox.Y).Scale(bbox.W, bbox.H)
+		w, h = 1.0, 1.0
+	}
+	m = m.Mul(parseSVGTransform(s.inheritedAttr(el, "gradientTransform", "")))
+	if !svgInvertible(m) {
+		return nil
+	}
+	length := func(key, initial string, ref float64) float64 {
+		return svgLength(s.inheritedAttr(el, key, initial), ref)
+	}
+	spread := s.inheritedAttr(el, "spreadMethod", "pad")
+	opaque := stops[0].a == 1.0 && opacity == 1.0
+	for _, stop := range stops {
+		opaque = opaque && stop.a == 1.0
+	}
+	last := stops[len(stops)-1]
+
+	pieces := []svgPiece{}
+	if el.tag == "linearGradient" {
+		x1, y1 := length("x1", "0%", w), length("y1", "0%", h)
+		x2, y2 := length("x2", "100%", w), length("y2", "0%", h)
+		d := Point{x2 - x1, y2 - y1}
+		if d.IsZero() {
+			return []svgPiece{{region, svgStopColor(last, opacity)}}
+		}
+		// map the gradient vector to the unit interval on the x-axis, the parameter t of the gradient is then the x-coordinate
+		n := m.Translate(x1, y1).Rotate(d.Angle()*180.0/math.Pi).Scale(d.Length(), d.Length())
+		bounds := region.Transform(n.Inv()).Bounds()
+		scale := math.Hypot(n[0][0], n[1][0]) // millimeters per unit of t
+		for _, band := range svgGradientBands(stops, spread, bounds.X, bounds.X+bounds.W, scale, opacity) {
+			t1 := band.t1
+			if opaque {
+				t1 = bounds.X + bounds.W // overlaps all following bands
+			}
+			rect := Rectangle(t1-band.t0, bounds.H+2.0).Translate(band.t0, bounds.Y-1.0).Transform(n)
+			pieces = append(pieces, svgPiece{region.And(rect), band.color})
+		}
+		return pieces
+	}
+
+	cx, cy, r := length("cx", "50%", w), length("cy", "50%", h), length("r", "50%", math.Hypot(w, h)/math.Sqrt2)
+	fx, fy := cx, cy
+	if val := s.inheritedAttr(el, "fx", ""); val != "" {
+		fx = svgLength(val, w)
+	}
+	if val := s.inheritedAttr(el, "fy", ""); val != "" {
+		fy = svgLength(val, h)
+	}
+	fr := length("fr", "0%", math.Hypot(w, h)/math.Sqrt2)
+	if r <= fr {
+		return []svgPiece{{region, svgStopColor(last, opacity)}}
+	}
+	// the circle of parameter t moves from the focal circle at t=0 to the end circle at t=1
+	disc := func(t float64) *Path {
+		radius := fr + (r-fr)*t
+		if radius <= 0.0 {
+			return &Path{}
+		}
+		d := Circle(radius).Translate(fx+(cx-fx)*t, fy+(cy-fy)*t)
+		if q := d.Transform(m); svgFinite(q) {
+			return q
+		}
+		// the radii of very eccentric arcs may overflow, but Béziers transform exactly
+		if q := d.ReplaceArcs().Transform(m); svgFinite(q) {
+			return q
+		}
+		return &Path{}
+	}
+	bounds := region.Transform(m.Inv()).Bounds()
+	tmax := 1.0
+	for _, corner := range []Point{{bounds.X, bounds.Y}, {bounds.X + bounds.W, bounds.Y}, {bounds.X, bounds.Y + bounds.H}, {bounds.X + bounds.W, bounds.Y + bounds.H}} {
+		// upper bound of t for which the circle contains the corner
+		tmax = math.Max(tmax, (corner.Sub(Point{fx, fy}).Length()+Point{cx - fx, cy - fy}.Length())/(r-fr))
+	}
+	if spread == "pad" {
+		outside := region
+		if !opaque {
+			outside = region.Not(disc(1.0))
+		}
+		pieces = append(pieces, svgPiece{outside, svgStopColor(last, opacity)})
+		tmax = 1.0
+	}
+	scale := (r - fr) * math.Sqrt(math.Abs(m.Det()))
+	bands := svgGradientBands(stops, spread, 0.0, tmax, scale, opacity)
+	if opaque {
+		// draw discs from the outside inwards
+		for i := len(bands) - 1; 0 <= i; i-- {
+			pieces = append(pieces, svgPiece{region.And(disc(bands[i].t1)), bands[i].color})
+		}
+	} else {
+		for _, band := range bands {
+			pieces = append(pieces, svgPiece{region.And(disc(band.t1).Not(disc(band.t0))), band.color})
+		}
+	}
+	return pieces
+}
+
+// svgFinite returns whether all coordinates of a path are finite.
+func svgFinite(p *Path) bool {
+	for _, v := range p.d {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// gradientStops returns the stops of a gradient with increasing offsets.
+func (s *svgImporter) gradientStops(el *svgElement) []svgStop {
+	el = s.inheritedContent(el)
+	if el == nil {
+		return nil
+	}
+	stops := []svgStop{}
+	for _, child := range el.children {
+		if child.tag != "stop" {
+			continue
+		}
+		offset := svgOpacity(child.attrs["offset"]) // a number or percentage clamped to [0,1]
+		if _, ok := child.attrs["offset"]; !ok {
+			offset = 0.0
+		}
+		if 0 < len(stops) {
+			offset = math.Max(offset, stops[len(stops)-1].offset)
+		}
+		col, err := ParseCSSColor(child.style["stop-color"])
+		if _, ok := child.style["stop-color"]; !ok || err != nil {
+			col = Black
+		}
+		col = scaleAlpha(col, svgOpacity(child.style["stop-opacity"]))
+		stop := svgStop{offset: offset, a: float64(col.A) / 255.0}
+		if col.A != 0 {
+			stop.r = float64(col.R) / float64(col.A)
+			stop.g = float64(col.G) / float64(col.A)
+			stop.b = float64(col.B) / float64(col.A)
+		}
+		stops = append(stops, stop)
+	}
+	return stops
+}
+
+// svgGradientBands returns bands of flat colors in the range [tmin,tmax] of the gradient parameter, of which the colors are interpolated between the stops and repeated according to the spread method. The number of bands between two stops depends on their difference in color, where scale is the length in millimeters of the unit interval so that bands are no smaller than about svgBandWidth.
+func svgGradientBands(stops []svgStop, spread string, tmin, tmax, scale, opacity float64) []svgBand {
+	// bands of the unit interval
+	unit := []svgBand{}
+	if 0.0 < stops[0].offset {
+		unit = append(unit, svgBand{0.0, stops[0].offset, svgStopColor(stops[0], opacity)})
+	}
+	for i := 0; i+1 < len(stops); i++ {
+		a, b := stops[i], stops[i+1]
+		if a.offset == b.offset {
+			continue
+		}
+		diff := math.Max(math.Max(math.Abs(b.r-a.r), math.Abs(b.g-a.g)), math.Max(math.Abs(b.b-a.b), math.Abs(b.a-a.a)))
+		n := int(math.Ceil(diff * 255.0 / 2.0)) // steps of two levels of color
+		n = int(math.Max(1.0, math.Min(float64(n), math.Ceil((b.offset-a.offset)*scale/svgBandWidth))))
+		for j := 0; j < n; j++ {
+			t := (float64(j) + 0.5) / float64(n)
+			mid := svgStop{0.0, a.r + (b.r-a.r)*t, a.g + (b.g-a.g)*t, a.b + (b.b-a.b)*t, a.a + (b.a-a.a)*t}
+			t0 := a.offset + (b.offset-a.offset)*float64(j)/float64(n)
+			t1 := a.offset + (b.offset-a.offset)*float64(j+1)/float64(n)
+			unit = append(unit, svgBand{t0, t1, svgStopColor(mid, opacity)})
+		}
+	}
+	if last := stops[len(stops)-1]; last.offset < 1.0 {
+		unit = append(unit, svgBand{last.offset, 1.0, svgStopColor(last, opacity)})
+	}
+
+	bands := []svgBand{}
+	if spread != "repeat" && spread != "reflect" {
+		if tmin < 0.0 {
+			bands = append(bands, svgBand{tmin, 0.0, svgStopColor(stops[0], opacity)})
+		}
+		for _, band := range unit {
+			if tmin < band.t1 && band.t0 < tmax {
+				bands = append(bands, svgBand{math.Max(tmin, band.t0), math.Min(tmax, band.t1), band.color})
+			}
+		}
+		if 1.0 < tmax {
+			bands = append(bands, svgBand{math.Max(tmin, 1.0), tmax, svgStopColor(stops[len(stops)-1], opacity)})
+		}
+		return bands
+	}
+
+	first, last := math.Floor(tmin), math.Ceil(tmax)
+	if 256.0 < last-first {
+		// too many repetitions, paint the average color instead
+		return []svgBand{{tmin, tmax, unit[len(unit)/2].color}}
+	}
+	for k := first; k < last; k++ {
+		reflect := spread == "reflect" && math.Mod(math.Abs(k), 2.0) == 1.0
+		for i := range unit {
+			band := unit[i]
+			t0, t1 := k+band.t0, k+band.t1
+			if reflect {
+				band = unit[len(unit)-1-i]
+				t0, t1 = k+1.0-band.t1, k+1.0-band.t0
+			}
+			if tmin < t1 && t0 < tmax {
+				bands = append(bands, svgBand{math.Max(tmin, t0), math.Min(tmax, t1), band.color})
+			}
+		}
+	}
+	return bands
+}
+
+// svgStopColor returns the premultiplied color of a stop with its alpha scaled by opacity.
+func svgStopColor(stop svgStop, opacity float64) color.RGBA {
+	a := stop.a * math.Max(0.0, math.Min(1.0, opacity))
+	return color.RGBA{uint8(stop.r*a*255.0 + 0.5), uint8(stop.g*a*255.0 + 0.5), uint8(stop.b*a*255.0 + 0.5), uint8(a*255.0 + 0.5)}
+}
+
+// pattern fills a region in canvas coordinates by drawing the content of the pattern for every tile that overlaps it, clipped to the region.
+func (s *svgImporter) pattern(el *svgElement, region *Path, opacity float64, bbox Rect, state svgState) {
+	content := s.inheritedContent(el)
+	if content == nil || 32 < s.depth {
+		return
+	}
+
+	m := state.m.Mul(parseSVGTransform(s.inheritedAttr(el, "patternTransform", "")))
+	w, h := state.viewport[0], state.viewport[1]
+	x, y := svgLength(s.inheritedAttr(el, "x", "0"), w), svgLength(s.inheritedAttr(el, "y", "0"), h)
+	width, height := svgLength(s.inheritedAttr(el, "width", "0"), w), svgLength(s.inheritedAttr(el, "height", "0"), h)
+	if s.inheritedAttr(el, "patternUnits", "objectBoundingBox") != "userSpaceOnUse" {
+		x, y = bbox.X+x*bbox.W, bbox.Y+y*bbox.H
+		width, height = width*bbox.W, height*bbox.H
+	}
+	if width <= 0.0 || height <= 0.0 || !svgInvertible(m) {
+		return
+	}
+
+	tile := Identity
+	viewport := [2]float64{width, height}
+	if viewBox, ok := parseSVGViewBox(s.inheritedAttr(el, "viewBox", "")); ok {
+		tile = svgViewBoxTransform(viewBox, Rect{0.0, 0.0, width, height}, s.inheritedAttr(el, "preserveAspectRatio", ""))
+		viewport = [2]float64{viewBox.W, viewBox.H}
+	} else if s.inheritedAttr(el, "patternContentUnits", "userSpaceOnUse") == "objectBoundingBox" {
+		if bbox.W == 0.0 || bbox.H == 0.0 {
+			return
+		}
+		tile = Identity.Scale(bbox.W, bbox.H)
+	}
+
+	props := map[string]string{}
+	for _, key := range svgInherited {
+		if val, ok := content.style[key]; ok && val != "inherit" {
+			props[key] = val
+		}
+	}
+	bounds := region.Transform(m.Inv()).Bounds()
+	i0, i1 := math.Floor((bounds.X-x)/width), math.Ceil((bounds.X+bounds.W-x)/width)
+	j0, j1 := math.Floor((bounds.Y-y)/height), math.Ceil((bounds.Y+bounds.H-y)/height)
+	if svgMaxTiles < (i1-i0)*(j1-j0) {
+		return
+	}
+	s.depth++
+	for j := j0; j < j1; j++ {
+		for i := i0; i < i1; i++ {
+			tx, ty := x+i*width, y+j*height
+			clip := region.And(Rectangle(width, height).Translate(tx, ty).Transform(m))
+			if clip.Empty() {
+				continue
+			} else if s.budget.expired() {
+				s.depth--
+				return
+			}
+			s.children(content, svgState{
+				m:        m.Translate(tx, ty).Mul(tile),
+				props:    props,
+				opacity:  opacity,
+				viewport: viewport,
+				clip:     clip,
+				mask:     state.mask,
+				masked:   state.masked,
+			})
+		}
+	}
+	s.depth--
+}
+
+// clipPath returns the clipping region in canvas coordinates of a clipPath element applied to el, which is the union of the shapes of its children.
+func (s *svgImporter) clipPath(ref, el *svgElement, state svgState) *Path {
+	m := state.m.Mul(parseSVGTransform(ref.style["transform"]))
+	if ref.attrs["clipPathUnits"] == "objectBoundingBox" {
+		bbox := s.bounds(el, state.viewport)
+		if bbox.W == 0.0 || bbox.H == 0.0 {
+			return &Path{} // clips everything
+		}
+		m = m.Translate(bbox.X, bbox.Y).Scale(bbox.W, bbox.H)
+	}
+
+	clip := &Path{}
+	var add func(*svgElement, Matrix)
+	add = func(child *svgElement, m Matrix) {
+		if child.style["display"] == "none" || child.style["visibility"] == "hidden" || 32 < s.depth {
+			return
+		}
+		m = m.Mul(parseSVGTransform(child.style["transform"]))
+		if !svgInvertible(m) {
+			return
+		}
+		if child.tag == "use" {
+			if ref, ok := s.ids[svgHref(child)]; ok {
+				s.depth++
+				add(ref, m.Translate(svgLength(child.attrs["x"], state.viewport[0]), svgLength(child.attrs["y"], state.viewport[1])))
+				s.depth--
+			}
+			return
+		}
+		var p *Path
+		if child.tag == "text" {
+			p = s.textPath(child, svgState{m: m, props: svgInheritedProps(child), opacity: 1.0, viewport: state.viewport})
+		} else {
+			p = svgShape(child, state.viewport)
+		}
+		if p == nil || p.Empty() || !s.budget.addPath(p, Style{}, m) {
+			return
+		}
+		fillRule := NonZero
+		if rule, ok := child.style["clip-rule"]; ok && rule == "evenodd" || !ok && ref.style["clip-rule"] == "evenodd" {
+			fillRule = EvenOdd
+		}
+		p = p.Transform(m).Settle(fillRule)
+		if id, _ := svgURL(child.style["clip-path"]); id != "" {
+			if childRef, ok := s.ids[id]; ok && childRef.tag == "clipPath" {
+				s.depth++
+				p = p.And(s.clipPath(childRef, child, svgState{m: m, viewport: state.viewport}))
+				s.depth--
+			}
+		}
+		clip = clip.Append(p)
+	}
+	for _, child := range ref.children {
+		add(child, m)
+	}
+	clip = clip.Settle(NonZero)
+
+	// a clip path may itself be clipped
+	if id, _ := svgURL(ref.style["clip-path"]); id != "" && s.depth < 32 {
+		if refRef, ok := s.ids[id]; ok && refRef.tag == "clipPath" && refRef != ref {
+			s.depth++
+			clip = clip.And(s.clipPath(refRef, el, state))
+			s.depth--
+		}
+	}
+	return clip
+}
+
+// maskPieces returns the regions in canvas coordinates of a mask element applied to el, with their opacity given by the luminance of the content of the mask, or by its alpha for masks of the alpha type. The content is drawn into regions of flat colors, of which the topmost covers the ones below.
+func (s *svgImporter) maskPieces(ref, el *svgElement, state svgState) []svgMaskPiece {
+	bbox := s.bounds(el, state.viewport)
+	w, h := state.viewport[0], state.viewport[1]
+	x, y := svgLength(ref.attrs["x"], w), svgLength(ref.attrs["y"], h)
+	width, height := svgLength(ref.attrs["width"], w), svgLength(ref.attrs["height"], h)
+	if ref.attrs["maskUnits"] != "userSpaceOnUse" {
+		fraction := func(key string, initial float64) float64 {
+			if val, ok := ref.attrs[key]; ok {
+				return svgLength(val, 1.0)
+			}
+			return initial
+		}
+		x, y = bbox.X+fraction("x", -0.1)*bbox.W, bbox.Y+fraction("y", -0.1)*bbox.H
+		width, height = fraction("width", 1.2)*bbox.W, fraction("height", 1.2)*bbox.H
+	} else {
+		if _, ok := ref.attrs["x"]; !ok {
+			x = -0.1 * w
+		}
+		if _, ok := ref.attrs["y"]; !ok {
+			y = -0.1 * h
+		}
+		if _, ok := ref.attrs["width"]; !ok {
+			width = 1.2 * w
+		}
+		if _, ok := ref.attrs["height"]; !ok {
+			height = 1.2 * h
+		}
+	}
+	if width <= 0.0 || height <= 0.0 || 32 < s.depth {
+		return nil
+	}
+
+	m := state.m
+	if ref.attrs["maskContentUnits"] == "objectBoundingBox" {
+		if bbox.W == 0.0 || bbox.H == 0.0 {
+			return nil
+		}
+		m = m.Translate(bbox.X, bbox.Y).Scale(bbox.W, bbox.H)
+	}
+	props := svgInherit(map[string]string{}, ref)
+
+	// draw the content into a separate canvas, which consists of filled regions only since the content is clipped
+	c := s.c
+	s.c = New(c.W, c.H)
+	s.depth++
+	s.children(ref, svgState{
+		m:        m,
+		props:    props,
+		opacity:  1.0,
+		viewport: state.viewport,
+		clip:     Rectangle(width, height).Translate(x, y).Transform(state.m),
+	})
+	s.depth--
+	layers := s.c.layers
+	s.c = c
+
+	alphaMask := ref.style["mask-type"] == "alpha"
+	pieces := []svgMaskPiece{}
+	covered := &Path{}
+	for i := len(layers) - 1; 0 <= i; i-- {
+		l := layers[i]
+		if l.path == nil || s.budget.expired() {
+			continue
+		}
+		region := l.path.Transform(l.m)
+		col := l.style.FillColor
+		alpha := float64(col.A) / 255.0
+		if !alphaMask {
+			// luminance of the premultiplied color, which includes its alpha
+			alpha = (0.2125*float64(col.R) + 0.7154*float64(col.G) + 0.0721*float64(col.B)) / 255.0
+		}
+		if piece := region.Not(covered); 0.0 < alpha && !piece.Empty() {
+			pieces = append(pieces, svgMaskPiece{piece, alpha})
+		}
+		covered = covered.Or(region)
+	}
+
+	if !state.masked {
+		return pieces
+	}
+	// intersect with the mask of the parent
+	combined := []svgMaskPiece{}
+	for _, outer := range state.mask {
+		for _, inner := range pieces {
+			if piece := inner.path.And(outer.path); !piece.Empty() {
+				combined = append(combined, svgMaskPiece{piece, inner.alpha * outer.alpha})
+			}
+		}
+	}
+	return combined
+}
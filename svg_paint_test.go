@@ -0,0 +1,136 @@
+package canvas
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestSVGURL(t *testing.T) {
+	id, fallback := svgURL("url(#a)")
+	test.T(t, id, "a")
+	test.T(t, fallback, "")
+	id, fallback = svgURL(`url('#b') red`)
+	test.T(t, id, "b")
+	test.T(t, fallback, "red")
+	id, fallback = svgURL("blue")
+	test.T(t, id, "")
+	test.T(t, fallback, "blue")
+}
+
+func TestSVGGradientBands(t *testing.T) {
+	stops := []svgStop{{0.0, 1.0, 0.0, 0.0, 1.0}, {1.0, 0.0, 0.0, 1.0, 1.0}}
+	bands := svgGradientBands(stops, "pad", -1.0, 2.0, 0.3, 1.0)
+	test.T(t, len(bands), 5) // the gradient is 0.3mm long so it has three bands
+	test.T(t, bands[0], svgBand{-1.0, 0.0, Red})
+	test.T(t, bands[1].t1, 1.0/3.0)
+	test.T(t, bands[4], svgBand{1.0, 2.0, Blue})
+
+	bands = svgGradientBands(stops, "repeat", 0.0, 2.0, 0.1, 1.0)
+	test.T(t, len(bands), 2)
+	test.T(t, bands[1], svgBand{1.0, 2.0, bands[0].color})
+
+	bands = svgGradientBands(stops, "reflect", 0.0, 2.0, 0.2, 1.0)
+	test.T(t, len(bands), 4)
+	test.T(t, bands[2].color, bands[1].color)
+	test.T(t, bands[3].color, bands[0].color)
+
+	// colors are interpolated without premultiplied alpha
+	stops = []svgStop{{0.0, 1.0, 1.0, 1.0, 1.0}, {1.0, 1.0, 1.0, 1.0, 0.0}}
+	bands = svgGradientBands(stops, "pad", 0.0, 1.0, 0.1, 0.5)
+	test.T(t, bands[0].color, scaleAlpha(White, 0.25))
+}
+
+func TestReadSVGPaintServers(t *testing.T) {
+	Epsilon = 1e-10 // path booleans snap to a grid that depends on Epsilon
+	c, err := ReadSVG(strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="100" height="100">
+	<linearGradient id="a"><stop offset="0" stop-color="red"/><stop offset="1" stop-color="blue"/></linearGradient>
+	<linearGradient id="b" xlink:href="#a" x2="0" y2="1"/>
+	<pattern id="p" width="10" height="10" patternUnits="userSpaceOnUse"><rect width="5" height="5" fill="lime"/></pattern>
+	<rect width="100" height="10" fill="url(#a)"/>
+	<rect width="10" height="100" fill="url(#b)"/>
+	<rect width="20" height="10" fill="url(#p)"/>
+	<rect width="10" height="10" fill="url(#missing) yellow"/>
+</svg>`))
+	test.Error(t, err)
+
+	// the bands of the first gradient span increasing parts of the rectangle, the first of which is red
+	first := c.layers[0]
+	test.That(t, 250 < first.style.FillColor.R, first.style.FillColor)
+	test.Float(t, first.path.Bounds().X, 0.0)
+	test.Float(t, first.path.Bounds().W, 100.0*mmPerPx)
+	second := c.layers[1]
+	test.Float(t, second.path.Bounds().W, 100.0*mmPerPx-(second.path.Bounds().X-first.path.Bounds().X))
+
+	// the pattern draws two tiles with one rectangle each
+	layers := []layer{}
+	for _, l := range c.layers {
+		if l.style.FillColor == Lime {
+			layers = append(layers, l)
+		}
+	}
+	test.T(t, len(layers), 2)
+	testSVGBounds(t, layers[1].path.Bounds(), Rect{10.0 * mmPerPx, 95.0 * mmPerPx, 5.0 * mmPerPx, 5.0 * mmPerPx})
+	test.T(t, c.layers[len(c.layers)-1].style.FillColor, Yellow)
+}
+
+func TestReadSVGClipMask(t *testing.T) {
+	Epsilon = 1e-10 // path booleans snap to a grid that depends on Epsilon
+	c, err := ReadSVG(strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg" width="100" height="100">
+	<clipPath id="c"><rect x="10" y="10" width="20" height="20"/></clipPath>
+	<clipPath id="bbox" clipPathUnits="objectBoundingBox"><rect width="0.5" height="1"/></clipPath>
+	<mask id="m"><rect width="100" height="50" fill="white"/><rect y="50" width="100" height="50" fill="#808080"/></mask>
+	<g clip-path="url(#c)"><rect width="100" height="100" fill="red"/></g>
+	<rect x="40" width="20" height="20" fill="blue" clip-path="url(#bbox)"/>
+	<rect width="100" height="100" fill="lime" mask="url(#m)"/>
+	<rect width="100" height="100" clip-path="url(#c)" style="display:none"/>
+</svg>`))
+	test.Error(t, err)
+	test.T(t, len(c.layers), 4)
+	testSVGBounds(t, c.layers[0].path.Bounds(), Rect{10.0 * mmPerPx, 70.0 * mmPerPx, 20.0 * mmPerPx, 20.0 * mmPerPx})
+	test.T(t, c.layers[0].style.FillColor, Red)
+	testSVGBounds(t, c.layers[1].path.Bounds(), Rect{40.0 * mmPerPx, 80.0 * mmPerPx, 10.0 * mmPerPx, 20.0 * mmPerPx})
+
+	// the mask draws the rectangle opaque at the top and translucent at the bottom
+	test.T(t, c.layers[2].style.FillColor, scaleAlpha(Lime, 128.0/255.0))
+	testSVGBounds(t, c.layers[2].path.Bounds(), Rect{0.0, 0.0, 100.0 * mmPerPx, 50.0 * mmPerPx})
+	test.T(t, c.layers[3].style.FillColor, Lime)
+}
+
+func TestReadSVGDegenerate(t *testing.T) {
+	var tts = []struct {
+		name   string
+		svg    string
+		layers int
+	}{
+		{"singular transform", `<clipPath id="c"><circle r="5"/></clipPath><circle r="5" transform="scale(0)" clip-path="url(#c)"/>`, 0},
+		{"zero-area clip box", `<clipPath id="c" clipPathUnits="objectBoundingBox"><circle cx="0.5" cy="0.5" r="0.5"/></clipPath><line x2="10" stroke="black" clip-path="url(#c)"/>`, 0},
+		{"zero-size nested viewport", `<svg width="10" height="0"><circle r="5"/></svg>`, 0},
+		{"huge radial gradient", `<radialGradient id="g"><stop offset="0" stop-color="red"/><stop offset="1" stop-color="blue"/></radialGradient><rect width="30" height="9999999940" transform="rotate(30)" fill="url(#g)"/>`, -1},
+	}
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ReadSVGWithOptions(strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg" width="100" height="100">`+tt.svg+`</svg>`), SVGOptions{Limits: SafeLimits})
+			test.Error(t, err)
+			if tt.layers == -1 {
+				test.That(t, 0 < len(c.layers))
+			} else {
+				test.T(t, len(c.layers), tt.layers)
+			}
+		})
+	}
+
+	c, err := ReadSVG(strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg" width="10" height="0"><circle r="5"/></svg>`))
+	test.Error(t, err)
+	test.T(t, len(c.layers), 0)
+}
+
+// testSVGBounds compares bounds of regions, which are slightly off due to the snap rounding of path booleans.
+func testSVGBounds(t *testing.T, r, expected Rect) {
+	t.Helper()
+	test.Float(t, r.X, expected.X)
+	test.Float(t, r.Y, expected.Y)
+	test.Float(t, r.W, expected.W)
+	test.Float(t, r.H, expected.H)
+}
@@ -0,0 +1,303 @@
+package canvas
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// svgTextRun is a run of characters of a text element with the same style, laid out as one line of which the baseline starts at x,y in user units.
+type svgTextRun struct {
+	text  *Text
+	x, y  float64
+	state svgState
+}
+
+// m returns the transformation from the coordinates of the text in millimeters to user units, where the y-axis points down.
+func (run svgTextRun) m() Matrix {
+	return Identity.Translate(run.x, run.y).Scale(1.0/mmPerPx, -1.0/mmPerPx)
+}
+
+// path returns the glyph outlines of the run in user units.
+func (run svgTextRun) path() *Path {
+	p := &Path{}
+	paths, _ := run.text.ToPaths()
+	for _, glyphs := range paths {
+		p = p.Append(glyphs)
+	}
+	return p.Transform(run.m())
+}
+
+// svgTextPositions are the x, y, dx, and dy lists of a text or tspan element, which position its characters from the index start onwards.
+type svgTextPositions struct {
+	lists [4][]float64
+	start int
+}
+
+// svgTextLayout lays out the characters of a text element into runs, where characters with an absolute position start a new text chunk to which the text-anchor applies.
+type svgTextLayout struct {
+	s        *svgImporter
+	ctx      *TypographicContext
+	preserve bool // xml:space="preserve"
+	runs     []svgTextRun
+
+	x, y   float64 // current text position
+	chunk  int     // index of the first run of the current chunk
+	x0     float64 // start of the current chunk
+	anchor string
+
+	buf   []rune
+	state svgState // of the characters in buf
+	index int      // index of the next character
+	space bool     // previous character was a space, which collapses subsequent spaces
+	stack []svgTextPositions
+}
+
+// textRuns lays out a text element with its tspan children into runs of characters using the fonts of the importer. Per glyph rotation and text on a path are not supported.
+func (s *svgImporter) textRuns(el *svgElement, state svgState) []svgTextRun {
+	l := &svgTextLayout{
+		s:        s,
+		ctx:      NewTypographicContext(),
+		preserve: el.attrs["xml:space"] == "preserve",
+		anchor:   state.props["text-anchor"],
+		space:    true,
+	}
+	l.layout(el, state)
+	if !l.preserve {
+		for 0 < len(l.buf) && l.buf[len(l.buf)-1] == ' ' {
+			l.buf = l.buf[:len(l.buf)-1]
+		}
+	}
+	l.endChunk()
+	return l.runs
+}
+
+func (l *svgTextLayout) layout(el *svgElement, state svgState) {
+	pos := svgTextPositions{start: l.index}
+	for i, key := range []string{"x", "y", "dx", "dy"} {
+		pos.lists[i] = svgLengths(el.attrs[key], state.viewport[i%2])
+	}
+	l.stack = append(l.stack, pos)
+	l.content(el.text, state)
+	for _, child := range el.children {
+		if (child.tag == "tspan" || child.tag == "a") && child.style["display"] != "none" {
+			childState := state
+			childState.props = svgInherit(state.props, child)
+			l.layout(child, childState)
+		}
+		l.content(child.tail, state)
+	}
+	l.stack = l.stack[:len(l.stack)-1]
+}
+
+// content adds the character data of an element. Unless whitespace is preserved, newlines are removed and consecutive spaces and tabs are collapsed into one space.
+func (l *svgTextLayout) content(s string, state svgState) {
+	l.flush()
+	for _, r := range s {
+		if r == '\n' || r == '\r' {
+			if !l.preserve {
+				continue
+			}
+			r = ' '
+		} else if r == '\t' {
+			r = ' '
+		}
+		if r == ' ' && l.space && !l.preserve {
+			continue
+		}
+		l.space = r == ' '
+
+		x, hasX := l.position(0)
+		y, hasY := l.position(1)
+		dx, hasDX := l.position(2)
+		dy, hasDY := l.position(3)
+		if hasX || hasY {
+			l.endChunk()
+			if hasX {
+				l.x = x
+			}
+			if hasY {
+				l.y = y
+			}
+			l.x0 = l.x
+			l.anchor = state.props["text-anchor"]
+		} else if hasDX || hasDY {
+			l.flush()
+		}
+		l.x += dx
+		l.y += dy
+		l.buf = append(l.buf, r)
+		l.state = state
+		l.index++
+	}
+}
+
+// position returns the value of the list i of the innermost element that positions the next character.
+func (l *svgTextLayout) position(i int) (float64, bool) {
+	for j := len(l.stack) - 1; 0 <= j; j-- {
+		if list := l.stack[j].lists[i]; l.index-l.stack[j].start < len(list) {
+			return list[l.index-l.stack[j].start], true
+		}
+	}
+	return 0.0, false
+}
+
+// flush lays out the buffered characters as a run and advances the text position.
+func (l *svgTextLayout) flush() {
+	if len(l.buf) == 0 {
+		return
+	}
+	s := string(l.buf)
+	l.buf = l.buf[:0]
+	ff, ok := l.s.fontFace(l.state)
+	if !ok {
+		return
+	}
+	text := NewTextLineWithContext(l.ctx, ff, s, Left)
+	l.runs = append(l.runs, svgTextRun{text, l.x, l.y, l.state})
+	for _, line := range text.lines {
+		for _, span := range line.spans {
+			l.x = math.Max(l.x, l.runs[len(l.runs)-1].x+(span.dx+span.width)/mmPerPx)
+		}
+	}
+}
+
+// endChunk ends the current text chunk and aligns its runs by the text-anchor.
+func (l *svgTextLayout) endChunk() {
+	l.flush()
+	shift := 0.0
+	if l.anchor == "middle" {
+		shift = -(l.x - l.x0) / 2.0
+	} else if l.anchor == "end" {
+		shift = -(l.x - l.x0)
+	}
+	for i := l.chunk; i < len(l.runs); i++ {
+		l.runs[i].x += shift
+	}
+	l.chunk = len(l.runs)
+}
+
+// fontFace returns the font face of the text properties, with the size in user units and the color of the fill. It returns false when no font is found or the font size exceeds the limits.
+func (s *svgImporter) fontFace(state svgState) (FontFace, bool) {
+	size := svgFontSize(state.props)
+	if s.fonts == nil || size <= 0.0 || !s.budget.addFontSize(size*math.Sqrt(math.Abs(state.m.Det()))*ptPerMm) {
+		return FontFace{}, false
+	}
+	return s.fonts.Face(state.props["font-family"], size*mmPerPx*ptPerMm, svgStyle(state).FillColor, svgFontStyle(state.props), FontNormal)
+}
+
+// text draws a text element. Text that is stroked, painted by a gradient or pattern, clipped, or masked is drawn as outlines, as is all text with the TextToPaths option.
+func (s *svgImporter) text(el *svgElement, state svgState) {
+	for _, run := range s.textRuns(el, state) {
+		if !s.visible(run.state) {
+			continue
+		}
+		style := svgStyle(run.state)
+		fill, stroke := s.paintServer(run.state.props["fill"]), s.paintServer(run.state.props["stroke"])
+		if s.textToPaths || run.state.clip != nil || run.state.masked || fill != nil || stroke != nil || style.StrokeColor.A != 0 {
+			if p := run.path(); !p.Empty() {
+				s.drawPath(p, run.state)
+			}
+		} else if style.FillColor.A != 0 {
+			s.c.RenderText(run.text, run.state.m.Mul(run.m()))
+		}
+	}
+}
+
+// textPath returns the glyph outlines of a text element in user units, which is used for clip paths.
+func (s *svgImporter) textPath(el *svgElement, state svgState) *Path {
+	p := &Path{}
+	for _, run := range s.textRuns(el, state) {
+		if s.visible(run.state) {
+			p = p.Append(run.path())
+		}
+	}
+	return p
+}
+
+// svgFontSize returns the font size in user units of the properties, which has been resolved by svgInherit.
+func svgFontSize(props map[string]string) float64 {
+	if size, err := strconv.ParseFloat(props["font-size"], 64); err == nil {
+		return size
+	}
+	return 16.0
+}
+
+// svgRelativeFontSize parses a font size, where keywords, percentages, and the em and ex units are relative to the font size of the parent.
+func svgRelativeFontSize(s string, parent float64) float64 {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "xx-small":
+		return 9.0
+	case "x-small":
+		return 10.0
+	case "small":
+		return 13.0
+	case "medium":
+		return 16.0
+	case "large":
+		return 18.0
+	case "x-large":
+		return 24.0
+	case "xx-large":
+		return 32.0
+	case "larger":
+		return parent * 1.2
+	case "smaller":
+		return parent / 1.2
+	}
+	if strings.HasSuffix(s, "em") {
+		return svgLength(s[:len(s)-2], 0.0) * parent
+	} else if strings.HasSuffix(s, "ex") {
+		return svgLength(s[:len(s)-2], 0.0) * parent / 2.0
+	}
+	return svgLength(s, parent)
+}
+
+// svgFontStyle returns the font style of the font-weight and font-style properties, where weights are rounded to the nearest hundred.
+func svgFontStyle(props map[string]string) FontStyle {
+	weight := 400
+	switch val := props["font-weight"]; val {
+	case "bold", "bolder":
+		weight = 700
+	case "lighter":
+		weight = 100
+	default:
+		if w, err := strconv.ParseFloat(val, 64); err == nil {
+			weight = int(math.Round(math.Max(100.0, math.Min(900.0, w))/100.0)) * 100
+		}
+	}
+
+	style := FontRegular
+	switch weight {
+	case 100:
+		style = FontExtraLight
+	case 200:
+		style = FontLight
+	case 300:
+		style = FontBook
+	case 500:
+		style = FontMedium
+	case 600:
+		style = FontSemibold
+	case 700:
+		style = FontBold
+	case 800:
+		style = FontBlack
+	case 900:
+		style = FontExtraBlack
+	}
+	if val := props["font-style"]; val == "italic" || strings.HasPrefix(val, "oblique") {
+		style |= FontItalic
+	}
+	return style
+}
+
+// svgLengths parses a list of lengths separated by commas or whitespace.
+func svgLengths(s string, ref float64) []float64 {
+	lengths := []float64{}
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r' }) {
+		lengths = append(lengths, svgLength(field, ref))
+	}
+	return lengths
+}
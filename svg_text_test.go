@@ -0,0 +1,102 @@
+package canvas
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func testSVGFonts(t *testing.T) *FontRegistry {
+	family := NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular))
+	fonts := NewFontRegistry()
+	fonts.Add("DejaVu Serif", family)
+	return fonts
+}
+
+func TestReadSVGText(t *testing.T) {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" width="200" height="100">
+	<text x="10" y="20" font-family="'DejaVu Serif', serif" font-size="20">Hello <tspan fill="red" font-size="2em" font-weight="bold">world</tspan>!</text>
+	<text x="190" y="50" text-anchor="end">  end
+		anchored  </text>
+	<text x="10 20" y="80" dx="0 0 5">abc</text>
+</svg>`
+	c, err := ReadSVG(strings.NewReader(svg))
+	test.Error(t, err)
+	test.T(t, len(c.layers), 0) // text is not drawn without fonts
+
+	c, err = ReadSVGWithOptions(strings.NewReader(svg), SVGOptions{Fonts: testSVGFonts(t)})
+	test.Error(t, err)
+	test.T(t, len(c.layers), 7)
+
+	// the baseline is at y and the tspan continues after the preceding text
+	hello, world := c.layers[0].text.lines[0].spans[0], c.layers[1].text.lines[0].spans[0]
+	test.T(t, hello.text, "Hello ")
+	test.Float(t, hello.ff.size, 20.0*mmPerPx)
+	test.T(t, c.layers[0].m, Identity.Translate(10.0*mmPerPx, 80.0*mmPerPx))
+	test.T(t, world.text, "world")
+	test.Float(t, world.ff.size, 40.0*mmPerPx)
+	test.T(t, world.ff.style, FontBold)
+	test.T(t, world.ff.color, Red)
+	test.Float(t, c.layers[1].m[0][2], 10.0*mmPerPx+hello.width)
+
+	// whitespace is collapsed and the chunk ends at x
+	end := c.layers[3].text.lines[0].spans[0]
+	test.T(t, end.text, "end anchored")
+	test.Float(t, c.layers[3].m[0][2]+end.width, 190.0*mmPerPx)
+
+	// characters are positioned by the lists of x and dx
+	test.T(t, c.layers[4].text.lines[0].spans[0].text, "a")
+	test.Float(t, c.layers[5].m[0][2], 20.0*mmPerPx)
+	test.T(t, c.layers[6].text.lines[0].spans[0].text, "c")
+	test.Float(t, c.layers[6].m[0][2], 25.0*mmPerPx+c.layers[5].text.lines[0].spans[0].width)
+}
+
+func TestReadSVGTextToPaths(t *testing.T) {
+	Epsilon = 1e-10 // path booleans snap to a grid that depends on Epsilon
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" width="200" height="100">
+	<clipPath id="c"><text x="10" y="50" font-size="40">O</text></clipPath>
+	<text x="10" y="20" font-size="20">a</text>
+	<text x="10" y="50" font-size="20" fill="none" stroke="blue">b</text>
+	<rect width="200" height="100" clip-path="url(#c)"/>
+</svg>`
+	c, err := ReadSVGWithOptions(strings.NewReader(svg), SVGOptions{Fonts: testSVGFonts(t), TextToPaths: true})
+	test.Error(t, err)
+	test.T(t, len(c.layers), 3)
+
+	// text is drawn as outlines with the same bounds
+	text, err := ReadSVGWithOptions(strings.NewReader(svg), SVGOptions{Fonts: testSVGFonts(t)})
+	test.Error(t, err)
+	test.That(t, c.layers[0].text == nil && c.layers[0].path != nil, "outlines")
+	test.That(t, text.layers[0].text != nil, "text")
+	testSVGBounds(t, c.layers[0].path.Bounds().Transform(c.layers[0].m), text.layers[0].text.Bounds().Transform(text.layers[0].m))
+
+	// stroked text is always drawn as outlines
+	test.That(t, text.layers[1].text == nil, "stroke outlines")
+	test.T(t, text.layers[1].style.StrokeColor, Blue)
+
+	// the rectangle is clipped to the glyph, which has a hole
+	glyph := c.layers[2].path.Bounds()
+	test.That(t, 10.0*mmPerPx < glyph.X && glyph.X+glyph.W < 45.0*mmPerPx, glyph)
+	test.That(t, 45.0*mmPerPx < glyph.Y && glyph.Y+glyph.H < 90.0*mmPerPx, glyph)
+	test.T(t, len(c.layers[2].path.Split()), 2)
+	test.T(t, c.layers[2].style.FillColor, Black)
+}
+
+func TestSVGFontProperties(t *testing.T) {
+	test.Float(t, svgRelativeFontSize("2em", 10.0), 20.0)
+	test.Float(t, svgRelativeFontSize("50%", 10.0), 5.0)
+	test.Float(t, svgRelativeFontSize("larger", 10.0), 12.0)
+	test.Float(t, svgRelativeFontSize("12pt", 10.0), 16.0)
+	test.Float(t, svgRelativeFontSize("medium", 10.0), 16.0)
+
+	root := &svgElement{style: map[string]string{"font-size": "20px"}}
+	child := &svgElement{style: map[string]string{"font-size": "1.5em"}, parent: root}
+	test.Float(t, svgFontSize(svgInheritedProps(child)), 30.0)
+
+	test.T(t, svgFontStyle(map[string]string{}), FontRegular)
+	test.T(t, svgFontStyle(map[string]string{"font-weight": "bold", "font-style": "italic"}), FontBold|FontItalic)
+	test.T(t, svgFontStyle(map[string]string{"font-weight": "640"}), FontSemibold)
+	test.T(t, svgFontStyle(map[string]string{"font-weight": "950", "font-style": "oblique 10deg"}), FontExtraBlack|FontItalic)
+}
@@ -0,0 +1,148 @@
+package canvas
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+)
+
+// TerminalMode defines the characters with which WriteTerminal draws.
+type TerminalMode int
+
+// see TerminalMode
+const (
+	HalfBlocks TerminalMode = iota // upper and lower half blocks, one by two pixels per character
+	Braille                        // braille patterns, two by four dots per character
+)
+
+// TerminalOptions are the options for WriteTerminal.
+type TerminalOptions struct {
+	Columns   int // width in characters
+	Mode      TerminalMode
+	Color     bool      // use ANSI escape codes with 24-bit colors
+	Dithering Dithering // of pixels that are black or white, when not using colors
+}
+
+// DefaultTerminalOptions are the options for previews in terminals with 80 columns.
+var DefaultTerminalOptions = TerminalOptions{
+	Columns:   80,
+	Mode:      HalfBlocks,
+	Dithering: FloydSteinbergDithering,
+}
+
+// WriteTerminal writes the canvas as lines of Unicode characters for previews in terminals and logs where graphics are not available. The canvas is rasterized to fit the number of columns, assuming characters are twice as high as they are wide. Without colors, dark pixels are drawn in the color of the text, and with colors the half blocks are drawn in the colors of the pixels and braille dots in the average color of their character.
+func (c *Canvas) WriteTerminal(w io.Writer, opts TerminalOptions) error {
+	if opts.Columns <= 0 || c.W <= 0.0 || c.H <= 0.0 {
+		return fmt.Errorf("canvas and columns must not be empty")
+	}
+	cw, ch := 1, 2 // pixels per character
+	if opts.Mode == Braille {
+		cw, ch = 2, 4
+	}
+	dpm := float64(opts.Columns*cw) / c.W
+	rows := int(math.Ceil(c.H * dpm / float64(ch)))
+	img := image.NewRGBA(image.Rect(0, 0, opts.Columns*cw, rows*ch))
+	draw.Draw(img, img.Bounds(), image.NewUniform(White), image.Point{}, draw.Src)
+
+	// align the canvas with the top of the image, which is rounded up to whole characters
+	ras := NewRasterizer(img, dpm)
+	view := Identity.Translate(0.0, float64(rows*ch)/dpm-c.H)
+	c.merge()
+	for _, l := range c.layers {
+		l.render(ras, view)
+	}
+
+	// pixels that are ink, which is black or any color other than the white background
+	var ink *Bitmap
+	if opts.Color {
+		size := img.Bounds().Size()
+		ink = &Bitmap{Width: size.X, Height: size.Y, Stride: (size.X + 7) / 8}
+		ink.Pix = make([]byte, ink.Stride*ink.Height)
+		for y := 0; y < size.Y; y++ {
+			for x := 0; x < size.X; x++ {
+				if px := img.RGBAAt(x, y); px.R < 0xF0 || px.G < 0xF0 || px.B < 0xF0 {
+					ink.Pix[y*ink.Stride+x/8] |= 0x80 >> uint(x%8)
+				}
+			}
+		}
+	} else {
+		ink = NewBitmap(img, opts.Dithering)
+	}
+
+	bw := bufio.NewWriter(w)
+	for row := 0; row < rows; row++ {
+		var fg, bg color.RGBA
+		first := true
+		for col := 0; col < opts.Columns; col++ {
+			x, y := col*cw, row*ch
+			if opts.Mode == Braille {
+				r := brailleRune(ink, x, y)
+				if opts.Color && r != 0x2800 {
+					if avg := averageInk(img, ink, x, y); first || avg != fg {
+						fmt.Fprintf(bw, "\x1b[38;2;%d;%d;%dm", avg.R, avg.G, avg.B)
+						fg, first = avg, false
+					}
+				}
+				bw.WriteRune(r)
+			} else if opts.Color {
+				top, bottom := img.RGBAAt(x, y), img.RGBAAt(x, y+1)
+				if first || top != fg {
+					fmt.Fprintf(bw, "\x1b[38;2;%d;%d;%dm", top.R, top.G, top.B)
+				}
+				if first || bottom != bg {
+					fmt.Fprintf(bw, "\x1b[48;2;%d;%d;%dm", bottom.R, bottom.G, bottom.B)
+				}
+				fg, bg, first = top, bottom, false
+				bw.WriteRune('▀')
+			} else {
+				bw.WriteRune([4]rune{' ', '▀', '▄', '█'}[boolToInt(ink.At(x, y))+2*boolToInt(ink.At(x, y+1))])
+			}
+		}
+		if opts.Color && !first {
+			bw.WriteString("\x1b[0m")
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// brailleRune returns the braille pattern of the two by four dots of the bitmap at (x,y).
+func brailleRune(b *Bitmap, x, y int) rune {
+	// dots are numbered in columns, except for the bottom row
+	bits := [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+	r := rune(0x2800)
+	for j := 0; j < 4; j++ {
+		for i := 0; i < 2; i++ {
+			if b.At(x+i, y+j) {
+				r |= bits[j][i]
+			}
+		}
+	}
+	return r
+}
+
+// averageInk returns the average color of the ink pixels in the two by four pixels at (x,y).
+func averageInk(img *image.RGBA, ink *Bitmap, x, y int) color.RGBA {
+	sum, n := [3]int{}, 0
+	for j := 0; j < 4; j++ {
+		for i := 0; i < 2; i++ {
+			if ink.At(x+i, y+j) {
+				px := img.RGBAAt(x+i, y+j)
+				sum[0], sum[1], sum[2] = sum[0]+int(px.R), sum[1]+int(px.G), sum[2]+int(px.B)
+				n++
+			}
+		}
+	}
+	return color.RGBA{uint8(sum[0] / n), uint8(sum[1] / n), uint8(sum[2] / n), 0xFF}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
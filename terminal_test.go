@@ -0,0 +1,44 @@
+package canvas
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestWriteTerminal(t *testing.T) {
+	// a black square in the left half of the canvas
+	c := New(8.0, 4.0)
+	ctx := NewContext(c)
+	ctx.DrawPath(0.0, 0.0, Rectangle(4.0, 4.0))
+
+	buf := &bytes.Buffer{}
+	opts := DefaultTerminalOptions
+	opts.Columns = 8
+	test.Error(t, c.WriteTerminal(buf, opts))
+	test.T(t, buf.String(), "████    \n████    \n")
+
+	buf.Reset()
+	opts.Mode = Braille
+	opts.Columns = 4
+	test.Error(t, c.WriteTerminal(buf, opts))
+	test.T(t, buf.String(), "⣿⣿⠀⠀\n")
+
+	buf.Reset()
+	ctx.SetFillColor(Red)
+	ctx.DrawPath(4.0, 0.0, Rectangle(4.0, 4.0))
+	opts.Mode = HalfBlocks
+	opts.Columns = 2
+	opts.Color = true
+	test.Error(t, c.WriteTerminal(buf, opts))
+	test.T(t, strings.Split(buf.String(), "\n")[0], "\x1b[38;2;0;0;0m\x1b[48;2;255;255;255m▀\x1b[38;2;255;0;0m▀\x1b[0m") // the bottom half is below the canvas
+
+	buf.Reset()
+	opts.Mode = Braille
+	test.Error(t, c.WriteTerminal(buf, opts))
+	test.T(t, buf.String(), "\x1b[38;2;0;0;0m⠛\x1b[38;2;255;0;0m⠛\x1b[0m\n")
+
+	test.That(t, c.WriteTerminal(buf, TerminalOptions{}) != nil, "no columns")
+}
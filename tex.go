@@ -56,8 +56,7 @@ func (r *TeX) RenderPath(path *Path, style Style, m Matrix) {
 	if path.Empty() {
 		return
 	}
-	path = path.Transform(m)
-	path = path.ReplaceArcs()
+	path = path.Transform(m) // arcs are drawn natively by \pgfpatharcto
 
 	path.Iterate(func(start, end Point) {
 		fmt.Fprintf(r.w, "\n\\pgfpathmoveto{\\pgfpoint{%vmm}{%vmm}}", dec(end.X), dec(end.Y))
@@ -1,11 +1,15 @@
 package canvas
 
 import (
+	"image"
 	"image/color"
 	"math"
 	"sort"
+	"strings"
 	"unicode"
 	"unicode/utf8"
+
+	"golang.org/x/image/font/sfnt"
 )
 
 // MaxSentenceSpacing is the maximum amount times the x-height of the font that sentence spaces can expand.
@@ -17,6 +21,12 @@ const MaxWordSpacing = 2.5
 // MaxGlyphSpacing is the maximum amount times the x-height of the font that glyphs can be spaced.
 const MaxGlyphSpacing = 0.5
 
+// MaxTrackingSpacing is the maximum amount times the x-height of the font that glyphs can be spaced when using JustifyTracking. It is much smaller than MaxGlyphSpacing as it is meant for micro-adjustments only.
+const MaxTrackingSpacing = 0.05
+
+// MaxGlyphStretch is the maximum fraction by which glyphs can be expanded horizontally when using JustifyTracking, eg. 0.02 allows glyphs to become 2% wider.
+const MaxGlyphStretch = 0.02
+
 // TextAlign specifies how the text should align or whether it should be justified.
 type TextAlign int
 
@@ -28,6 +38,7 @@ const (
 	Top
 	Bottom
 	Justify
+	JustifyTracking // justify by distributing space over word spaces, glyph spacing and glyph expansion simultaneously (hz-style)
 )
 
 type line struct {
@@ -52,13 +63,21 @@ func (l line) Heights() (float64, float64, float64, float64) {
 
 // Text holds the representation of text using lines and text spans.
 type Text struct {
-	lines []line
-	fonts map[*Font]bool
+	lines    []line
+	fonts    map[*Font]bool
+	vertical bool    // lines are columns from right to left, see RichText.SetWritingMode
+	width    float64 // of the box of vertical text
 }
 
 // NewTextLine is a simple text line using a font face, a string (supporting new lines) and horizontal alignment (Left, Center, Right).
 func NewTextLine(ff FontFace, s string, halign TextAlign) *Text {
-	s, _, _ = ff.font.substituteTypography(s, false, false)
+	return NewTextLineWithContext(NewTypographicContext(), ff, s, halign)
+}
+
+// NewTextLineWithContext is like NewTextLine but uses and updates the given typographic context, so that the typographic state such as opened quotes persists across successive texts.
+func NewTextLineWithContext(ctx *TypographicContext, ff FontFace, s string, halign TextAlign) *Text {
+	defer startTrace(ShapingPhase).end(utf8.RuneCountInString(s))
+	s = ff.font.substituteTypography(s, ctx)
 
 	ascent, descent, spacing := ff.Metrics().Ascent, ff.Metrics().Descent, ff.Metrics().LineHeight-ff.Metrics().Ascent-ff.Metrics().Descent
 
@@ -77,7 +96,8 @@ func NewTextLine(ff FontFace, s string, halign TextAlign) *Text {
 					span.dx = -span.width
 				}
 
-				l.spans = append(l.spans, span)
+				l.spans = bidiSplit([]textSpan{span}, bidiParagraphLevel(span.altText, AutoDirection))
+				bidiReorder(l.spans)
 				if len(ff.deco) != 0 {
 					l.decos = append(l.decos, decoSpan{ff, span.dx, span.dx + span.width})
 				}
@@ -87,7 +107,7 @@ func NewTextLine(ff FontFace, s string, halign TextAlign) *Text {
 			i = j
 		}
 	}
-	return &Text{lines, map[*Font]bool{ff.font: true}}
+	return &Text{lines: lines, fonts: map[*Font]bool{ff.font: true}}
 }
 
 // NewTextBox is an advanced text formatter that will calculate text placement based on the setteings. It takes a font face, a string, the width or height of the box (can be zero for no limit), horizontal and vertical alignment (Left, Center, Right, Top, Bottom or Justify), text indentation for the first line and line stretch (percentage to stretch the line based on the line height).
@@ -95,23 +115,94 @@ func NewTextBox(ff FontFace, s string, width, height float64, halign, valign Tex
 	return NewRichText().Add(ff, s).ToText(width, height, halign, valign, indent, lineStretch)
 }
 
+// FitText returns the text laid out with the largest font size between minSize and maxSize (in points) at which the wrapped text fits within the width and height of box, together with that font size. The text is top-left aligned, so draw it at (box.X, box.Y+box.H) to place it inside box. If the text does not fit at minSize, it is laid out at minSize and truncated at the bottom of the box.
+func FitText(box Rect, s string, ff FontFace, minSize, maxSize float64) (*Text, float64) {
+	if maxSize < minSize {
+		minSize, maxSize = maxSize, minSize
+	}
+
+	fits := func(size float64) bool {
+		face := ff.withSize(size)
+		text := NewTextBox(face, s, box.W, 0.0, Left, Top, 0.0, 0.0)
+		if box.H < text.Height() {
+			return false
+		}
+		for _, l := range text.lines {
+			lastSpan := l.spans[len(l.spans)-1]
+			if box.W < lastSpan.dx+lastSpan.width-Epsilon {
+				return false // word too long to fit
+			}
+		}
+		return true
+	}
+
+	size := minSize
+	if fits(maxSize) {
+		size = maxSize
+	} else if fits(minSize) {
+		// binary search until we are within 0.01pt
+		lo, hi := minSize, maxSize
+		for 0.01 < hi-lo {
+			mid := (lo + hi) / 2.0
+			if fits(mid) {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		size = lo
+	}
+	face := ff.withSize(size)
+	return NewTextBox(face, s, box.W, box.H, Left, Top, 0.0, 0.0), size
+}
+
 // RichText allows to build up a rich text with text spans of different font faces and by fitting that into a box.
 type RichText struct {
-	spans                        []textSpan
-	fonts                        map[*Font]bool
-	inSingleQuote, inDoubleQuote bool
-	text                         string
+	spans []textSpan
+	fonts map[*Font]bool
+	ctx   *TypographicContext
+	text  string
+	lang  string
+	dir   TextDirection
+	mode  WritingMode
 }
 
 // NewRichText returns a new RichText.
 func NewRichText() *RichText {
+	return NewRichTextWithContext(NewTypographicContext())
+}
+
+// NewRichTextWithContext returns a new RichText that uses and updates the given typographic context, so that the typographic state such as opened quotes persists across successive texts.
+func NewRichTextWithContext(ctx *TypographicContext) *RichText {
 	return &RichText{
 		fonts: map[*Font]bool{},
+		ctx:   ctx,
 	}
 }
 
+// SetLanguage sets the language of the text as a BCP 47 language tag such as "en" or "ar-EG". It selects language specific behavior, such as justification by kashida insertion for Arabic script languages.
+func (rt *RichText) SetLanguage(lang string) *RichText {
+	rt.lang = lang
+	return rt
+}
+
+// SetDirection sets the base direction of the paragraphs of the text, which is AutoDirection by default where the first strong character of each paragraph determines its direction. Runs of right-to-left and left-to-right text are reordered for display by the Unicode Bidirectional Algorithm, but the horizontal alignment is not mirrored for right-to-left paragraphs.
+func (rt *RichText) SetDirection(dir TextDirection) *RichText {
+	rt.dir = dir
+	return rt
+}
+
+// SetWritingMode sets the writing mode of the text, which is HorizontalTB by default. For VerticalRL, the text is written in columns from top to bottom that are placed from right to left, using the vertical font faces of the text spans that are added afterwards, see FontFace.Vertical. Text justification, indentation, and line stretch apply to the columns as they do to lines.
+func (rt *RichText) SetWritingMode(mode WritingMode) *RichText {
+	rt.mode = mode
+	return rt
+}
+
 // Add adds a new text span element.
 func (rt *RichText) Add(ff FontFace, s string) *RichText {
+	if rt.mode == VerticalRL {
+		ff = ff.Vertical()
+	}
 	if 0 < len(s) {
 		rPrev := ' '
 		rNext, size := utf8.DecodeRuneInString(s)
@@ -123,7 +214,7 @@ func (rt *RichText) Add(ff FontFace, s string) *RichText {
 		}
 	}
 
-	s, rt.inSingleQuote, rt.inDoubleQuote = ff.font.substituteTypography(s, rt.inSingleQuote, rt.inDoubleQuote)
+	s = ff.font.substituteTypography(s, rt.ctx)
 	start := len(rt.text)
 	rt.text += s
 
@@ -172,12 +263,20 @@ func (rt *RichText) halign(lines []line, yoverflow bool, width float64, halign T
 				l.spans[i].dx += dx
 			}
 		}
-	} else if 0.0 < width && halign == Justify {
+	} else if 0.0 < width && (halign == Justify || halign == JustifyTracking) {
 		n := len(lines) - 1
 		if yoverflow {
 			n++
 		}
 		for _, l := range lines[:n] {
+			if halign == JustifyTracking {
+				justifyTracking(l, width)
+				continue
+			} else if usesKashida(rt.lang) {
+				// elongate words using tatweels first and distribute the remainder over the spaces below
+				justifyKashida(l, width)
+			}
+
 			// get the width range of our spans (eg. for text width can increase with extra character spacing)
 			textWidth, maxSentenceSpacing, maxWordSpacing, maxGlyphSpacing := 0.0, 0.0, 0.0, 0.0
 			for i, span := range l.spans {
@@ -189,7 +288,7 @@ func (rt *RichText) halign(lines []line, yoverflow bool, width float64, halign T
 						words++
 					}
 				}
-				glyphs := utf8.RuneCountInString(span.altText)
+				glyphs := span.ff.clusters(span.altText)
 				if i+1 == len(l.spans) {
 					glyphs--
 				}
@@ -239,7 +338,7 @@ func (rt *RichText) halign(lines []line, yoverflow bool, width float64, halign T
 							words++
 						}
 					}
-					glyphs := utf8.RuneCountInString(span.text)
+					glyphs := span.ff.clusters(span.text)
 					if i+1 == len(l.spans) {
 						glyphs--
 					}
@@ -262,6 +361,194 @@ func (rt *RichText) halign(lines []line, yoverflow bool, width float64, halign T
 	}
 }
 
+// justifyTracking justifies a line by expanding the word and sentence spaces, the glyph spacing (tracking) and the glyph widths by the same fraction of their respective maximums. Spreading the extra space over all glyphs prevents wide word spaces and thus reduces rivers in narrow columns.
+func justifyTracking(l line, width float64) {
+	// use non-ligature versions so we can stretch glyph spacings
+	textWidth, maxSpacing, maxStretch := 0.0, 0.0, 0.0
+	for i, span := range l.spans {
+		sentences, words := 0, 0
+		for _, boundary := range span.altBoundaries {
+			if boundary.kind == sentenceBoundary {
+				sentences++
+			} else if boundary.kind == wordBoundary {
+				words++
+			}
+		}
+		glyphs := span.ff.clusters(span.altText)
+		if i+1 == len(l.spans) {
+			glyphs--
+		}
+
+		textWidth += span.altWidth
+		if i == 0 {
+			textWidth += span.dx
+		}
+
+		xHeight := span.ff.Metrics().XHeight
+		maxSpacing += float64(sentences) * MaxSentenceSpacing * xHeight
+		maxSpacing += float64(words) * MaxWordSpacing * xHeight
+		maxSpacing += float64(glyphs) * MaxTrackingSpacing * xHeight
+		maxStretch += span.altWidth * MaxGlyphStretch
+	}
+
+	// only expand if we can reach the line width
+	if width-textWidth < Epsilon || textWidth+maxSpacing+maxStretch < width {
+		return
+	}
+	factor := (width - textWidth) / (maxSpacing + maxStretch)
+
+	dx := 0.0
+	for i, span := range l.spans {
+		span.text = span.altText
+		span.width = span.altWidth
+		span.boundaries = span.altBoundaries
+
+		sentences, words := 0, 0
+		for _, boundary := range span.boundaries {
+			if boundary.kind == sentenceBoundary {
+				sentences++
+			} else if boundary.kind == wordBoundary {
+				words++
+			}
+		}
+		glyphs := span.ff.clusters(span.text)
+		if i+1 == len(l.spans) {
+			glyphs--
+		}
+
+		xHeight := span.ff.Metrics().XHeight
+		span.sentenceSpacing = MaxSentenceSpacing * xHeight * factor
+		span.wordSpacing = MaxWordSpacing * xHeight * factor
+		span.glyphSpacing = MaxTrackingSpacing * xHeight * factor
+		span.glyphStretch = MaxGlyphStretch * factor
+
+		w := span.width*(1.0+span.glyphStretch) + float64(sentences)*span.sentenceSpacing + float64(words)*span.wordSpacing + float64(glyphs)*span.glyphSpacing
+		span.dx += dx
+		dx += w - span.width
+		span.width = w
+		l.spans[i] = span
+	}
+}
+
+// usesKashida returns true if the language is written in Arabic script and justifies text using kashidas.
+func usesKashida(lang string) bool {
+	if i := strings.IndexAny(lang, "-_"); i != -1 {
+		lang = lang[:i]
+	}
+	switch strings.ToLower(lang) {
+	case "ar", "fa", "ur", "ps", "sd", "ug", "ckb", "ku":
+		return true
+	}
+	return false
+}
+
+// justifyKashida justifies a line by inserting tatweel glyphs (kashidas) in between joining Arabic letters. It inserts as many as fit within the line width, spreading them evenly over all valid positions, and the remaining space is left for the other justification methods.
+func justifyKashida(l line, width float64) {
+	textWidth := 0.0
+	for i, span := range l.spans {
+		textWidth += span.width
+		if i == 0 {
+			textWidth += span.dx
+		}
+	}
+
+	type kashida struct {
+		span, pos int
+		width     float64
+	}
+	kashidas := []kashida{}
+	for i, span := range l.spans {
+		buffer := &sfnt.Buffer{}
+		if index, err := span.ff.font.glyphIndex(buffer, '\u0640'); err != nil || index == 0 {
+			continue // font has no tatweel glyph
+		}
+		w := span.ff.TextWidth("\u0640")
+		for _, pos := range kashidaPositions(span.altText) {
+			kashidas = append(kashidas, kashida{i, pos, w})
+		}
+	}
+	if len(kashidas) == 0 {
+		return
+	}
+
+	// add kashidas round-robin to all positions until the line is full
+	counts := make([]int, len(kashidas))
+	widthLeft := width - textWidth
+	for added := true; added; {
+		added = false
+		for k, kashida := range kashidas {
+			if kashida.width <= widthLeft {
+				counts[k]++
+				widthLeft -= kashida.width
+				added = true
+			}
+		}
+	}
+
+	dx := 0.0
+	k := 0
+	for i, span := range l.spans {
+		sb := strings.Builder{}
+		prev := 0
+		for ; k < len(kashidas) && kashidas[k].span == i; k++ {
+			sb.WriteString(span.altText[prev:kashidas[k].pos])
+			sb.WriteString(strings.Repeat("\u0640", counts[k]))
+			prev = kashidas[k].pos
+		}
+		if prev == 0 {
+			l.spans[i].dx += dx
+			continue // no kashidas
+		}
+		sb.WriteString(span.altText[prev:])
+
+		newSpan := newTextSpan(span.ff, sb.String(), 0)
+		newSpan.dx = span.dx + dx
+		newSpan.level = span.level
+		dx += newSpan.width - span.width
+		l.spans[i] = newSpan
+	}
+}
+
+// kashidaPositions returns the byte positions in s where a kashida can be inserted, ie. in between an Arabic letter that joins to the following letter and that following letter.
+func kashidaPositions(s string) []int {
+	positions := []int{}
+	var prev rune
+	for i, r := range s {
+		if isArabicTransparent(r) {
+			continue // diacritics do not affect joining, insert after them
+		}
+		if 0 < i && arabicJoinsNext(prev) && arabicJoinsPrev(r) {
+			positions = append(positions, i)
+		}
+		prev = r
+	}
+	return positions
+}
+
+// isArabicTransparent returns true for Arabic combining marks that are transparent to joining.
+func isArabicTransparent(r rune) bool {
+	return 0x0610 <= r && r <= 0x061A || 0x064B <= r && r <= 0x065F || r == 0x0670 || 0x06D6 <= r && r <= 0x06ED && r != 0x06DD && r != 0x06DE && r != 0x06E5 && r != 0x06E6 && r != 0x06E9
+}
+
+// arabicJoinsPrev returns true for Arabic letters that join to the preceding letter, ie. right-joining and dual-joining letters.
+func arabicJoinsPrev(r rune) bool {
+	return (0x0620 <= r && r <= 0x064A || 0x066E <= r && r <= 0x06D5 || 0x06EE <= r && r <= 0x06FF && r != 0x06FD && r != 0x06FE) && r != 0x0621 && r != 0x0674
+}
+
+// arabicJoinsNext returns true for Arabic letters that join to the following letter, ie. dual-joining letters.
+func arabicJoinsNext(r rune) bool {
+	if !arabicJoinsPrev(r) {
+		return false
+	}
+	switch {
+	case 0x0622 <= r && r <= 0x0625, r == 0x0627, r == 0x0629, 0x062F <= r && r <= 0x0632, r == 0x0648,
+		0x0671 <= r && r <= 0x0673, 0x0675 <= r && r <= 0x0677, 0x0688 <= r && r <= 0x0699, r == 0x06C0,
+		0x06C3 <= r && r <= 0x06CB, r == 0x06CD, r == 0x06CF, r == 0x06D2, r == 0x06D3, r == 0x06D5, r == 0x06EE, r == 0x06EF:
+		return false // right-joining
+	}
+	return true
+}
+
 func (rt *RichText) valign(lines []line, h, height float64, valign TextAlign) {
 	dy := 0.0
 	extraLineSpacing := 0.0
@@ -302,20 +589,39 @@ func (rt *RichText) decorate(lines []line) {
 	}
 }
 
-// ToText takes the added text spans and fits them within a given box of certain width and height.
+// ToText takes the added text spans and fits them within a given box of certain width and height. For vertical text, the horizontal alignment aligns the text within the columns, where Left is at the top, and the vertical alignment aligns the columns within the box, where Top is at the right, see SetWritingMode.
 func (rt *RichText) ToText(width, height float64, halign, valign TextAlign, indent, lineStretch float64) *Text {
+	if rt.mode != VerticalRL {
+		return rt.toText(width, height, halign, valign, indent, lineStretch)
+	}
+
+	// columns are laid out as lines along the height of the box, which are rotated into the box
+	text := rt.toText(height, width, halign, valign, indent, lineStretch)
+	text.vertical, text.width = true, width
+	if width == 0.0 {
+		text.width = text.lineHeight()
+	}
+	return text
+}
+
+func (rt *RichText) toText(width, height float64, halign, valign TextAlign, indent, lineStretch float64) *Text {
 	if len(rt.spans) == 0 {
-		return &Text{[]line{}, rt.fonts}
+		return &Text{lines: []line{}, fonts: rt.fonts}
 	}
+	defer startTrace(ShapingPhase).end(utf8.RuneCountInString(rt.text))
 	spans := []textSpan{rt.spans[0]}
 
 	k := 0 // index into rt.spans and rt.positions
 	lines := []line{}
+	paragraphs := []int{} // index of the first line of each paragraph
 	yoverflow := false
 	y, prevLineSpacing := 0.0, 0.0
+	endsParagraph := true
 	for k < len(rt.spans) {
 		dx := indent
 		indent = 0.0
+		startsParagraph := endsParagraph
+		endsParagraph = false
 
 		// trim left spaces
 		spans[0] = spans[0].TrimLeft()
@@ -345,6 +651,7 @@ func (rt *RichText) ToText(width, height float64, halign, valign TextAlign, inde
 			}
 
 			newline := 1 < len(spans[0].boundaries) && spans[0].boundaries[len(spans[0].boundaries)-2].kind == lineBoundary
+			endsParagraph = newline
 			if newline {
 				spans[0], _ = spans[0].split(len(spans[0].boundaries) - 2)
 			}
@@ -394,23 +701,46 @@ func (rt *RichText) ToText(width, height float64, halign, valign TextAlign, inde
 			yoverflow = true
 			break
 		}
+		if startsParagraph {
+			paragraphs = append(paragraphs, len(lines))
+		}
 		lines = append(lines, l)
 	}
 
 	if len(lines) == 0 {
-		return &Text{lines, rt.fonts}
+		return &Text{lines: lines, fonts: rt.fonts}
+	}
+
+	// split lines into runs of the same direction
+	paragraphs = append(paragraphs, len(lines))
+	for i := 0; i+1 < len(paragraphs); i++ {
+		text := ""
+		for _, l := range lines[paragraphs[i]:paragraphs[i+1]] {
+			for _, span := range l.spans {
+				text += span.altText
+			}
+		}
+		level := bidiParagraphLevel(text, rt.dir)
+		for j := paragraphs[i]; j < paragraphs[i+1]; j++ {
+			lines[j].spans = bidiSplit(lines[j].spans, level)
+		}
 	}
 
 	// apply horizontal alignment
 	rt.halign(lines, yoverflow, width, halign)
 
+	// display runs of the same direction in visual order
+	for _, l := range lines {
+		bidiReorder(l.spans)
+	}
+
 	// apply vertical alignment
 	rt.valign(lines, -y, height, valign)
 
 	// set decorations
 	rt.decorate(lines)
 
-	return &Text{lines, rt.fonts}
+	return &Text{lines: lines, fonts: rt.fonts}
 }
 
 // Empty is true if there are no text lines or no text spans.
@@ -425,6 +755,22 @@ func (t *Text) Empty() bool {
 
 // Height returns the height of the text using the font metrics, this is usually more than the bounds of the glyph outlines.
 func (t *Text) Height() float64 {
+	if t.vertical {
+		return t.lineWidth()
+	}
+	return t.lineHeight()
+}
+
+// Width returns the width of the text using the advances of the glyphs, this is usually more than the bounds of the glyph outlines.
+func (t *Text) Width() float64 {
+	if t.vertical {
+		return t.lineHeight()
+	}
+	return t.lineWidth()
+}
+
+// lineHeight returns the height of the lines from the top of the first line.
+func (t *Text) lineHeight() float64 {
 	if len(t.lines) == 0 {
 		return 0.0
 	}
@@ -433,6 +779,17 @@ func (t *Text) Height() float64 {
 	return -lastLine.y + descent
 }
 
+// lineWidth returns the width of the longest line from the start of the lines.
+func (t *Text) lineWidth() float64 {
+	width := 0.0
+	for _, line := range t.lines {
+		for _, span := range line.spans {
+			width = math.Max(width, span.dx+span.width)
+		}
+	}
+	return width
+}
+
 // Bounds returns the rectangle that contains the entire text box, ie. the glyph outlines.
 func (t *Text) Bounds() Rect {
 	if len(t.lines) == 0 || len(t.lines[0].spans) == 0 {
@@ -446,7 +803,15 @@ func (t *Text) Bounds() Rect {
 			r = r.Add(spanBounds)
 		}
 	}
-	return r
+	return r.Transform(t.lineView())
+}
+
+// lineView returns the transformation from the lines to the text, which rotates the columns of vertical text into their box.
+func (t *Text) lineView() Matrix {
+	if t.vertical {
+		return Identity.Translate(t.width, 0.0).Rotate(-90.0)
+	}
+	return Identity
 }
 
 // Fonts returns list of fonts used.
@@ -466,6 +831,23 @@ func (t *Text) Fonts() []*Font {
 	return fonts
 }
 
+// hasColorGlyphs returns true if the text uses color glyphs of the SVG, COLR, sbix, or CBDT tables of a font, which renderers that write text natively draw as paths and images instead.
+func (t *Text) hasColorGlyphs() bool {
+	for _, line := range t.lines {
+		for _, span := range line.spans {
+			if !span.ff.font.hasColorGlyphs() {
+				continue
+			}
+			for _, g := range span.ff.shape(span.text, span.level%2 == 1) {
+				if span.ff.font.colorGlyph(sfnt.GlyphIndex(g.ID)) != nil {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
 func (t *Text) mostCommonFontFace() FontFace {
 	families := map[*FontFamily]int{}
 	sizes := map[float64]int{}
@@ -514,25 +896,100 @@ func (t *Text) mostCommonFontFace() FontFace {
 	return family.Face(size*ptPerMm, col, style, variant)
 }
 
-// ToPaths makes a path out of the text, with x,y the top-left point of the rectangle that fits the text (ie. y is not the text base)
-func (t *Text) ToPaths() ([]*Path, []color.RGBA) {
+// TextPathOptions are the options for converting text to paths.
+type TextPathOptions int
+
+// see TextPathOptions
+const (
+	TextPathUnion TextPathOptions = 1 << iota // union overlapping glyph outlines and decorations of the same color into one path without overlaps
+)
+
+// ToPaths makes a path out of the text, with x,y the top-left point of the rectangle that fits the text (ie. y is not the text base). Color glyphs of the SVG and COLR tables of a font, such as those of color emoji fonts, are returned as paths in their own colors, while glyphs that are images of the sbix and CBDT tables are returned by their outlines, if any. The paths may be returned to the pool with PutPath when they are no longer used.
+func (t *Text) ToPaths(options ...TextPathOptions) ([]*Path, []color.RGBA) {
+	paths, colors, _ := t.toPaths(false, options)
+	return paths, colors
+}
+
+// textImage is an image of a color glyph of a text with the transformation from its pixels.
+type textImage struct {
+	img image.Image
+	m   Matrix
+}
+
+// renderAsPaths renders the text by its paths and the images of its color glyphs, for renderers that do not write the text natively.
+func (t *Text) renderAsPaths(r Renderer, m Matrix) {
+	paths, colors, images := t.toPaths(true, nil)
+	for i, path := range paths {
+		style := DefaultStyle
+		style.FillColor = colors[i]
+		r.RenderPath(path, style, m)
+	}
+	for _, img := range images {
+		r.RenderImage(img.img, m.Mul(img.m))
+	}
+}
+
+// toPaths returns the paths and colors of the text as ToPaths, and the images of its color glyphs of the sbix and CBDT tables instead of their outlines if images is true.
+func (t *Text) toPaths(images bool, options []TextPathOptions) ([]*Path, []color.RGBA, []textImage) {
 	paths := []*Path{}
 	colors := []color.RGBA{}
+	imgs := []textImage{}
+	view := t.lineView()
 	for _, line := range t.lines {
 		for _, span := range line.spans {
-			p, _, col := span.ToPath(span.width)
-			p = p.Translate(span.dx, line.y)
+			p := GetPath()
+			m := view.Translate(span.dx, line.y)
+			if span.ff.font.hasColorGlyphs() {
+				// color glyphs are drawn in their own colors instead of by their outlines
+				buffer := &sfnt.Buffer{}
+				span.layoutGlyphs(m, func(g Glyph, m Matrix) {
+					if glyphPaths, glyphColors, ok := span.ff.colorGlyphPaths(sfnt.GlyphIndex(g.ID), m); ok {
+						paths = append(paths, glyphPaths...)
+						colors = append(colors, glyphColors...)
+					} else if img, imgM, ok := span.ff.colorGlyphImage(sfnt.GlyphIndex(g.ID), m); ok && images {
+						imgs = append(imgs, textImage{img, imgM})
+					} else {
+						span.ff.appendGlyph(p, buffer, sfnt.GlyphIndex(g.ID), 0.0, m)
+					}
+				})
+			} else {
+				span.appendPath(p, m)
+			}
 			paths = append(paths, p)
-			colors = append(colors, col)
+			colors = append(colors, span.ff.color)
 		}
 		for _, deco := range line.decos {
 			p := deco.ff.Decorate(deco.x1 - deco.x0)
-			p = p.Translate(deco.x0, line.y)
+			p = p.Transform(view.Translate(deco.x0, line.y))
 			paths = append(paths, p)
 			colors = append(colors, deco.ff.color)
 		}
 	}
-	return paths, colors
+
+	opts := TextPathOptions(0)
+	for _, option := range options {
+		opts |= option
+	}
+	if opts&TextPathUnion != 0 {
+		// merge paths of the same color so that overlapping glyphs, spans and decorations are removed, which fills correctly with the EvenOdd rule and cuts cleanly
+		unionPaths := []*Path{}
+		unionColors := []color.RGBA{}
+		index := map[color.RGBA]int{}
+		for i, p := range paths {
+			if j, ok := index[colors[i]]; ok {
+				unionPaths[j] = unionPaths[j].Append(p)
+			} else {
+				index[colors[i]] = len(unionPaths)
+				unionPaths = append(unionPaths, p)
+				unionColors = append(unionColors, colors[i])
+			}
+		}
+		for i := range unionPaths {
+			unionPaths[i] = unionPaths[i].Settle(NonZero)
+		}
+		paths, colors = unionPaths, unionColors
+	}
+	return paths, colors, imgs
 }
 
 ////////////////////////////////////////////////////////////////
@@ -555,6 +1012,8 @@ type textSpan struct {
 	sentenceSpacing float64
 	wordSpacing     float64
 	glyphSpacing    float64
+	glyphStretch    float64 // horizontal expansion of glyphs, eg. 0.02 is 2% wider
+	level           uint8   // bidirectional embedding level, which is odd for right-to-left text
 }
 
 func newTextSpan(ff FontFace, text string, i int) textSpan {
@@ -596,8 +1055,11 @@ func (span textSpan) TrimRight() textSpan {
 }
 
 func (span textSpan) Bounds(width float64) Rect {
-	p, deco, _ := span.ToPath(width)
-	return p.Bounds().Add(deco.Bounds()) // TODO: make more efficient?
+	p := GetPath()
+	span.appendPath(p, Identity)
+	bounds := p.Bounds().Add(span.ff.Decorate(width).Bounds()) // TODO: make more efficient?
+	PutPath(p)
+	return bounds
 }
 
 func (span textSpan) split(i int) (textSpan, textSpan) {
@@ -615,6 +1077,7 @@ func (span textSpan) split(i int) (textSpan, textSpan) {
 	span0.altWidth = span.ff.TextWidth(span0.altText)
 	span0.altBoundaries = append(span.altBoundaries[:i:i], textBoundary{eofBoundary, len(span0.altText), 0})
 	span0.dx = span.dx
+	span0.level = span.level
 
 	span1 := textSpan{}
 	span1.ff = span.ff
@@ -627,6 +1090,7 @@ func (span textSpan) split(i int) (textSpan, textSpan) {
 	span1.altBoundaries = make([]textBoundary, len(span.altBoundaries)-i-1)
 	copy(span1.altBoundaries, span.altBoundaries[i+1:])
 	span1.dx = span.dx
+	span1.level = span.level
 	for j := range span1.boundaries {
 		span1.boundaries[j].pos -= span.boundaries[i].pos + span.boundaries[i].size
 		span1.altBoundaries[j].pos -= span.altBoundaries[i].pos + span.altBoundaries[i].size
@@ -654,35 +1118,82 @@ func (span textSpan) Split(width float64) ([]textSpan, bool) {
 	return []textSpan{span}, false
 }
 
+// appendPath appends the glyphs of the span to p transformed by m.
 // TODO: transform to Draw to canvas and cache the glyph rasterizations?
-func (span textSpan) ToPath(width float64) (*Path, *Path, color.RGBA) {
-	iBoundary := 0
+func (span textSpan) appendPath(p *Path, m Matrix) {
+	buffer := &sfnt.Buffer{}
+	span.layoutGlyphs(m, func(g Glyph, m Matrix) {
+		span.ff.appendGlyph(p, buffer, sfnt.GlyphIndex(g.ID), 0.0, m)
+	})
+}
 
+// layoutGlyphs calls glyph for every shaped glyph of the span in visual order with the transformation to its position. The glyph spacing and the word and sentence spacing are added after each cluster.
+func (span textSpan) layoutGlyphs(m Matrix, glyph func(Glyph, Matrix)) {
 	x := 0.0
-	p := &Path{}
-	var rPrev rune
-	for i, r := range span.text {
-		if i > 0 {
-			x += span.ff.Kerning(rPrev, r)
+	stretch := 1.0 + span.glyphStretch
+	var buffer *sfnt.Buffer
+	var center float64
+	for _, cluster := range span.glyphClusters() {
+		for _, g := range cluster.glyphs {
+			if g.Upright {
+				// upright glyphs are rotated against the column, with their vertical origin at the center of the column
+				if buffer == nil {
+					buffer = &sfnt.Buffer{}
+					metrics := span.ff.Metrics()
+					center = (metrics.Ascent - metrics.Descent) / 2.0
+				}
+				origin := span.ff.verticalOrigin(buffer, sfnt.GlyphIndex(g.ID))
+				glyph(g, m.Translate(x, center).Rotate(90.0).Translate(-origin.X, -origin.Y))
+			} else {
+				glyph(g, m.Translate(x, 0.0).Scale(stretch, 1.0).Translate(g.XOffset+span.ff.fauxItalic*g.YOffset, g.YOffset))
+			}
+			x += g.XAdvance * stretch
 		}
+		x += span.glyphSpacing + cluster.spacing
+	}
+}
 
-		pr, advance := span.ff.ToPath(string(r))
-		pr = pr.Translate(x, 0.0)
-		p = p.Append(pr)
+// glyphCluster is a cluster of shaped glyphs, such as a base glyph and its marks, and the word and sentence spacing that follows it.
+type glyphCluster struct {
+	glyphs  []Glyph
+	spacing float64
+}
+
+// glyphClusters returns the clusters of the shaped glyphs of the span in visual order, where the clusters of right-to-left spans are reversed while the glyphs of each cluster keep their order.
+func (span textSpan) glyphClusters() []glyphCluster {
+	rtl := span.level%2 == 1
+	glyphs := span.ff.shape(span.text, rtl)
+	clusters := []glyphCluster{}
+	iBoundary := 0
+	for i := 0; i < len(glyphs); {
+		j := i + 1
+		for j < len(glyphs) && glyphs[j].Cluster == glyphs[i].Cluster {
+			j++
+		}
+		next := len(span.text)
+		if j < len(glyphs) {
+			next = glyphs[j].Cluster
+		}
 
-		x += advance + span.glyphSpacing
-		if iBoundary < len(span.boundaries) && span.boundaries[iBoundary].pos == i {
+		cluster := glyphCluster{glyphs: glyphs[i:j]}
+		for iBoundary < len(span.boundaries) && span.boundaries[iBoundary].pos < next {
 			boundary := span.boundaries[iBoundary]
 			if boundary.kind == sentenceBoundary {
-				x += span.sentenceSpacing
+				cluster.spacing += span.sentenceSpacing
 			} else if boundary.kind == wordBoundary {
-				x += span.wordSpacing
+				cluster.spacing += span.wordSpacing
 			}
 			iBoundary++
 		}
-		rPrev = r
+		clusters = append(clusters, cluster)
+		i = j
+	}
+	if rtl {
+		for a, b := 0, len(clusters)-1; a < b; a, b = a+1, b-1 {
+			clusters[a], clusters[b] = clusters[b], clusters[a]
+		}
 	}
-	return p, span.ff.Decorate(width), span.ff.color
+	return clusters
 }
 
 ////////////////////////////////////////////////////////////////
@@ -744,6 +1255,9 @@ func calcTextBoundaries(s string, a, b int) []textBoundary {
 			}
 		} else if r == '\u200b' {
 			boundaries = mergeBoundaries(boundaries, []textBoundary{{breakBoundary, i, size}})
+		} else if isIdeographic(rPrev) && isIdeographic(r) && !isSmallKana(r) {
+			// ideographic scripts break between characters
+			boundaries = mergeBoundaries(boundaries, []textBoundary{{wordBoundary, i, 0}})
 		}
 		rPrevPrev = rPrev
 		rPrev = r
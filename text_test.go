@@ -6,6 +6,24 @@ import (
 	"github.com/tdewolff/test"
 )
 
+// newArabicTestFace returns a face of DejaVu Serif, which has no Arabic glyphs, that draws beh, seen, meem, and tatweel by the glyphs of b, s, m, and the underscore.
+func newArabicTestFace() FontFace {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	indices := map[rune]uint16{}
+	for arabic, latin := range map[rune]rune{'ب': 'b', 'س': 's', 'م': 'm', '\u0640': '_'} {
+		index, _ := family.font(FontRegular).sfnt.GlyphIndex(nil, latin)
+		indices[arabic] = uint16(index)
+	}
+	family.SetGlyphIndexSubstitution(func(r rune, index uint16) uint16 {
+		if latin, ok := indices[r]; ok {
+			return latin
+		}
+		return index
+	})
+	return family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+}
+
 func TestTextLine(t *testing.T) {
 	family := NewFontFamily("dejavu-serif")
 	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
@@ -70,6 +88,14 @@ func TestRichText(t *testing.T) {
 	test.Float(t, text.lines[1].spans[0].dx, 0.0)
 	test.Float(t, text.lines[1].spans[0].width, 45.5) // last row does not justify
 
+	text = rt.ToText(55.0, 50.0, JustifyTracking, Top, 0.0, 0.0)
+	test.Float(t, text.lines[0].spans[0].dx, 0.0)
+	test.Float(t, text.lines[0].spans[1].dx+text.lines[0].spans[1].width, 55.0)
+	test.That(t, 0.0 < text.lines[0].spans[0].glyphStretch && text.lines[0].spans[0].glyphStretch <= MaxGlyphStretch, "glyphs must be stretched")
+	test.Float(t, text.lines[0].spans[0].glyphStretch, text.lines[0].spans[1].glyphStretch)
+	test.That(t, text.lines[0].spans[0].wordSpacing < MaxWordSpacing*face.Metrics().XHeight, "word spacing must be less than maximum")
+	test.Float(t, text.lines[1].spans[0].width, 45.5) // last row does not justify
+
 	// test valign
 	text = rt.ToText(55.0, 50.0, Left, Bottom, 0.0, 0.0)
 	test.Float(t, text.lines[0].y, -33.203125)
@@ -169,3 +195,223 @@ func TestTextBounds(t *testing.T) {
 	test.Float(t, bounds.W, face8.TextWidth("test")+face12.TextWidth("test"))
 	test.Float(t, bounds.H, 10.40625)
 }
+
+func TestFitText(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0, Black, FontRegular, FontNormal)
+
+	box := Rect{0.0, 0.0, 40.0, 20.0}
+	text, size := FitText(box, "mm mm mm", face, 4.0, 100.0)
+	test.That(t, 4.0 < size && size < 100.0, "size must be between bounds")
+	test.That(t, text.Height() <= box.H, "text must fit height")
+	test.T(t, len(text.lines), 2)
+
+	_, larger := FitText(box, "mm mm mm", face, size+0.1, 100.0)
+	test.Float(t, larger, size+0.1) // does not fit, falls back to minimum size
+
+	_, size = FitText(box, "m", face, 4.0, 10.0)
+	test.Float(t, size, 10.0)
+
+	// other settings of the font face are retained
+	face = family.Face(12.0, Black, FontRegular, FontSubscript).Hinting(FullHinting, 4.0)
+	text, size = FitText(box, "mm mm mm", face, 4.0, 100.0)
+	ff := text.lines[0].spans[0].ff
+	test.T(t, ff.hinting, FullHinting)
+	test.T(t, ff.variant, FontSubscript)
+	test.Float(t, ff.size, size*mmPerPt)
+	test.Float(t, ff.voffset, -0.33*size*mmPerPt)
+}
+
+func TestTextToPathsUnion(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0, Black, FontRegular, FontNormal, FontUnderline)
+
+	text := NewTextLine(face, "gj", Left)
+	paths, colors := text.ToPaths()
+	test.T(t, len(paths), 2) // text and underline
+
+	paths, colors = text.ToPaths(TextPathUnion)
+	test.T(t, len(paths), 1)
+	test.T(t, colors[0], Black)
+
+	bounds := paths[0].Bounds()
+	test.That(t, 0.0 < bounds.W && 0.0 < bounds.H, "union must not be empty")
+}
+
+func TestKashida(t *testing.T) {
+	test.That(t, usesKashida("ar"))
+	test.That(t, usesKashida("fa-IR"))
+	test.That(t, !usesKashida("en"))
+	test.That(t, !usesKashida(""))
+
+	test.T(t, kashidaPositions("بسم"), []int{2, 4})  // beh-seen-meem joins everywhere
+	test.T(t, kashidaPositions("دار"), []int{})      // dal and alef do not join to the next letter
+	test.T(t, kashidaPositions("بَيت"), []int{4, 6}) // after the fatha
+	test.T(t, kashidaPositions("abc بب"), []int{6})
+
+	// font without tatweel falls back to space expansion
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	text := NewRichText().Add(face, "mm mm mm mmmm").ToText(55.0, 50.0, Justify, Top, 0.0, 0.0)
+	textArabic := NewRichText().SetLanguage("ar").Add(face, "mm mm mm mmmm").ToText(55.0, 50.0, Justify, Top, 0.0, 0.0)
+	test.Float(t, textArabic.lines[0].spans[0].width, text.lines[0].spans[0].width)
+	test.Float(t, textArabic.lines[0].spans[0].wordSpacing, text.lines[0].spans[0].wordSpacing)
+
+	face = newArabicTestFace()
+	textArabic = NewRichText().SetLanguage("ar").Add(face, "بسم بسم بسم بسمبسم").ToText(70.0, 50.0, Justify, Top, 0.0, 0.0)
+	test.T(t, len(textArabic.lines), 3)
+	test.T(t, textArabic.lines[0].spans[0].altText, "بـسـم بسم") // kashidas round-robin until the remainder is too small
+	test.Float(t, textArabic.lines[0].spans[0].width, 70.0)
+	test.That(t, 0.0 < textArabic.lines[0].spans[0].wordSpacing)
+	test.T(t, textArabic.lines[1].spans[0].altText, "بــــســـم")
+	test.Float(t, textArabic.lines[1].spans[0].width, 70.0)
+	test.T(t, textArabic.lines[2].spans[0].altText, "بسمبسم") // last line is not justified
+
+	// justified lines stay right-to-left
+	for _, line := range textArabic.lines {
+		test.T(t, line.spans[0].level, uint8(1))
+	}
+	glyphs := face.Shape("m___s____b") // visual order
+	clusters := textArabic.lines[1].spans[0].glyphClusters()
+	test.T(t, len(clusters), len(glyphs))
+	for i, cluster := range clusters {
+		test.T(t, cluster.glyphs[0].ID, glyphs[i].ID)
+	}
+}
+
+func TestBidiLevels(t *testing.T) {
+	var tts = []struct {
+		s         string
+		paragraph uint8
+		levels    []uint8
+	}{
+		{"abc אבג def", 0, []uint8{0, 0, 0, 0, 1, 1, 1, 0, 0, 0, 0}},
+		{"אבג 123", 1, []uint8{1, 1, 1, 1, 2, 2, 2}},
+		{"א 12", 0, []uint8{1, 1, 2, 2}},
+		{"١٢", 0, []uint8{2, 2}},
+		{"a(b)ג", 0, []uint8{0, 0, 0, 0, 1}},
+		{"א(b)ג", 1, []uint8{1, 1, 2, 1, 1}},
+		{"abc  ", 1, []uint8{2, 2, 2, 1, 1}},
+		{"a\u202Bb\u202Cc", 0, []uint8{0, 0, 2, 2, 0}},
+		{"\u2067abc\u2069", 0, []uint8{0, 2, 2, 2, 0}},
+	}
+	for _, tt := range tts {
+		t.Run(tt.s, func(t *testing.T) {
+			test.T(t, bidiLevels([]rune(tt.s), tt.paragraph), tt.levels)
+		})
+	}
+
+	test.T(t, bidiParagraphLevel("123 א abc", AutoDirection), uint8(1))
+	test.T(t, bidiParagraphLevel("\u2067א\u2069 abc", AutoDirection), uint8(0))
+	test.T(t, bidiParagraphLevel("א", LeftToRight), uint8(0))
+	test.T(t, bidiParagraphLevel("abc", RightToLeft), uint8(1))
+}
+
+func TestTextBidi(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	text := NewTextLine(face, "abc אבג def", Left)
+	spans := text.lines[0].spans
+	test.T(t, len(spans), 3)
+	test.String(t, spans[0].altText, "abc ")
+	test.String(t, spans[1].altText, "אבג")
+	test.T(t, spans[1].level, uint8(1))
+	test.String(t, spans[2].altText, " def")
+	test.Float(t, spans[1].dx, spans[0].width)
+	test.Float(t, spans[2].dx, spans[0].width+spans[1].width)
+
+	// the right-to-left paragraph displays the left-to-right run on the right
+	text = NewRichText().SetDirection(RightToLeft).Add(face, "abc א").ToText(0.0, 0.0, Left, Top, 0.0, 0.0)
+	spans = text.lines[0].spans
+	test.T(t, len(spans), 2)
+	test.String(t, spans[0].altText, " א")
+	test.T(t, spans[0].level, uint8(1))
+	test.String(t, spans[1].altText, "abc")
+	test.T(t, spans[1].level, uint8(2))
+	test.Float(t, spans[0].dx, 0.0)
+	test.Float(t, spans[1].dx, spans[0].width)
+
+	text = NewRichText().Add(face, "abc א").ToText(0.0, 0.0, Left, Top, 0.0, 0.0)
+	test.String(t, text.lines[0].spans[0].altText, "abc ")
+
+	// glyphs are mirrored and clusters are reversed in right-to-left runs
+	span := newTextSpan(face, "(a", 0)
+	span.level = 1
+	clusters := span.glyphClusters()
+	test.T(t, len(clusters), 2)
+	test.T(t, clusters[0].glyphs[0].ID, face.Shape("a")[0].ID)
+	test.T(t, clusters[1].glyphs[0].ID, face.Shape(")")[0].ID)
+
+	// kashida justification keeps the right-to-left level
+	face = newArabicTestFace()
+	text = NewRichText().SetLanguage("ar").Add(face, "بسم بسم بسمبسم").ToText(70.0, 50.0, Justify, Top, 0.0, 0.0)
+	spans = text.lines[0].spans
+	test.T(t, len(spans), 1)
+	test.T(t, spans[0].altText, "بـسـم بسم")
+	test.T(t, spans[0].level, uint8(1))
+	glyphs := face.Shape("msb m_s_b") // visual order
+	clusters = spans[0].glyphClusters()
+	test.T(t, len(clusters), len(glyphs))
+	for i, cluster := range clusters {
+		test.T(t, cluster.glyphs[0].ID, glyphs[i].ID)
+	}
+}
+
+func TestTextVertical(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	indexA, _ := family.font(FontRegular).sfnt.GlyphIndex(nil, 'A')
+	family.SetGlyphIndexSubstitution(func(r rune, index uint16) uint16 {
+		if r == '日' {
+			return uint16(indexA) // draw the ideograph by the glyph of A
+		}
+		return index
+	})
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	metrics := face.Metrics()
+
+	glyphs := face.Vertical().Shape("日A")
+	test.That(t, glyphs[0].Upright)
+	test.That(t, !glyphs[1].Upright)
+	test.Float(t, glyphs[0].XAdvance, metrics.Ascent+metrics.Descent)
+	test.Float(t, glyphs[1].XAdvance, face.TextWidth("A"))
+
+	// upright glyphs keep their orientation while other glyphs are rotated clockwise
+	pathA, _ := face.ToPath("A")
+	boundsA := pathA.Bounds()
+	text := NewRichText().SetWritingMode(VerticalRL).Add(face, "日").ToText(0.0, 0.0, Left, Top, 0.0, 0.0)
+	test.Float(t, text.Bounds().W, boundsA.W)
+	test.Float(t, text.Bounds().H, boundsA.H)
+	text = NewRichText().SetWritingMode(VerticalRL).Add(face, "A").ToText(0.0, 0.0, Left, Top, 0.0, 0.0)
+	test.Float(t, text.Bounds().W, boundsA.H)
+	test.Float(t, text.Bounds().H, boundsA.W)
+
+	// columns from right to left that break between ideographs
+	height := 2.5 * (metrics.Ascent + metrics.Descent)
+	text = NewRichText().SetWritingMode(VerticalRL).Add(face, "日日日日").ToText(100.0, height, Left, Top, 0.0, 0.0)
+	test.T(t, len(text.lines), 2)
+	test.That(t, text.vertical)
+	test.Float(t, text.Height(), 2.0*(metrics.Ascent+metrics.Descent))
+	test.Float(t, text.Width(), 2.0*metrics.LineHeight)
+	paths, _ := text.ToPaths()
+	test.T(t, len(paths), 2)
+	first, second := paths[0].Bounds(), paths[1].Bounds()
+	test.That(t, second.X+second.W < first.X)
+	test.That(t, 100.0-metrics.LineHeight < first.X)
+	test.That(t, -height < first.Y && first.Y+first.H < 0.0)
+}
+
+func TestTextIdeographicBreaks(t *testing.T) {
+	boundaries := calcTextBoundaries("日本語ですと", 0, len("日本語ですと"))
+	test.T(t, boundaries, []textBoundary{{wordBoundary, 3, 0}, {wordBoundary, 6, 0}, {wordBoundary, 9, 0}, {wordBoundary, 12, 0}, {wordBoundary, 15, 0}, {eofBoundary, 18, 0}})
+
+	// no breaks before small kana
+	boundaries = calcTextBoundaries("キャ", 0, len("キャ"))
+	test.T(t, boundaries, []textBoundary{{eofBoundary, 6, 0}})
+}
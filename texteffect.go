@@ -0,0 +1,191 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"reflect"
+
+	"golang.org/x/image/vector"
+)
+
+// TextEffect is an effect that is drawn behind the text of a font face, such as a shadow, an outline, or a glow, see FontFace.WithEffects. Effects are drawn as paths and images so that they look the same for all renderers.
+type TextEffect interface {
+	// RenderEffect draws the effect for the outline of the text p, where the text is drawn with the transformation m.
+	RenderEffect(r Renderer, p *Path, m Matrix)
+}
+
+// TextShadow draws the text offset in a color, such as for a hard drop shadow.
+type TextShadow struct {
+	Offset Point
+	Color  color.RGBA
+}
+
+// RenderEffect draws the shadow.
+func (e TextShadow) RenderEffect(r Renderer, p *Path, m Matrix) {
+	style := DefaultStyle
+	style.FillColor = e.Color
+	r.RenderPath(p.Translate(e.Offset.X, e.Offset.Y), style, m)
+}
+
+// TextOutline draws the text expanded by a width in a color, so that the text is outlined without the outline covering its fill.
+type TextOutline struct {
+	Width float64
+	Color color.RGBA
+}
+
+// RenderEffect draws the outline.
+func (e TextOutline) RenderEffect(r Renderer, p *Path, m Matrix) {
+	style := DefaultStyle
+	style.FillColor = e.Color
+	style.StrokeColor = e.Color
+	style.StrokeWidth = 2.0 * e.Width
+	style.StrokeCapper = RoundCap
+	style.StrokeJoiner = RoundJoin
+	r.RenderPath(p, style, m)
+}
+
+// textGlowResolution is the default resolution in dots per millimeter of the image of a glow.
+const textGlowResolution = 10.0
+
+// textGlowMaxPixels is the maximum number of pixels of the image of a glow, which lowers the resolution for large texts.
+const textGlowMaxPixels = 1 << 22
+
+// TextGlow draws the text blurred and offset in a color, such as for a glow or a soft drop shadow. The radius of the blur is twice the standard deviation of the Gaussian blur, as for CSS shadows. The blur is rasterized into an image at the resolution in dots per millimeter, or 10 when zero.
+type TextGlow struct {
+	Radius     float64
+	Offset     Point
+	Color      color.RGBA
+	Resolution float64
+}
+
+// RenderEffect draws the glow.
+func (e TextGlow) RenderEffect(r Renderer, p *Path, m Matrix) {
+	bounds := p.Bounds()
+	margin := 1.5 * math.Max(e.Radius, 0.0) // three standard deviations
+	bounds = Rect{bounds.X - margin, bounds.Y - margin, bounds.W + 2.0*margin, bounds.H + 2.0*margin}
+	dpm := e.Resolution
+	if dpm <= 0.0 {
+		dpm = textGlowResolution
+	}
+	if textGlowMaxPixels < bounds.W*bounds.H*dpm*dpm {
+		dpm = math.Sqrt(textGlowMaxPixels / (bounds.W * bounds.H))
+	}
+	w, h := int(math.Ceil(bounds.W*dpm)), int(math.Ceil(bounds.H*dpm))
+	if w <= 0 || h <= 0 {
+		return
+	}
+
+	ras := vector.NewRasterizer(w, h)
+	p.Translate(-bounds.X, -bounds.Y).ToRasterizer(ras, dpm)
+	mask := image.NewAlpha(image.Rect(0, 0, w, h))
+	ras.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	gaussianBlurAlpha(mask, e.Radius/2.0*dpm)
+
+	img := image.NewRGBA(mask.Bounds())
+	for i, a := range mask.Pix {
+		f := float64(a) / 255.0
+		img.Pix[4*i+0] = uint8(float64(e.Color.R)*f + 0.5)
+		img.Pix[4*i+1] = uint8(float64(e.Color.G)*f + 0.5)
+		img.Pix[4*i+2] = uint8(float64(e.Color.B)*f + 0.5)
+		img.Pix[4*i+3] = uint8(float64(e.Color.A)*f + 0.5)
+	}
+	r.RenderImage(img, m.Translate(bounds.X+e.Offset.X, bounds.Y+e.Offset.Y).Scale(1.0/dpm, 1.0/dpm))
+}
+
+// gaussianBlurAlpha blurs the image by approximating a Gaussian blur of the standard deviation in pixels with three box blurs in both directions, see W. Wells, Efficient Synthesis of Gaussian Filters by Cascaded Uniform Filters, 1986.
+func gaussianBlurAlpha(img *image.Alpha, sigma float64) {
+	// three box blurs of width 2*radius+1 have a variance of (width^2-1)/4, radius is rounded
+	radius := int(math.Sqrt(4.0*sigma*sigma+1.0) / 2.0)
+	if radius < 1 {
+		return
+	}
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	buf := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			buf[y*w+x] = float64(img.Pix[y*img.Stride+x])
+		}
+	}
+	size := w
+	if size < h {
+		size = h
+	}
+	line := make([]float64, size)
+	blur := func(offset, stride, n int) {
+		// running sum over the window, where pixels outside the image are transparent
+		for i := 0; i < n; i++ {
+			line[i] = buf[offset+i*stride]
+		}
+		sum := 0.0
+		for i := 0; i < radius && i < n; i++ {
+			sum += line[i]
+		}
+		for i := 0; i < n; i++ {
+			if i+radius < n {
+				sum += line[i+radius]
+			}
+			if 0 <= i-radius-1 {
+				sum -= line[i-radius-1]
+			}
+			buf[offset+i*stride] = sum / float64(2*radius+1)
+		}
+	}
+	for pass := 0; pass < 3; pass++ {
+		for y := 0; y < h; y++ {
+			blur(y*w, 1, w)
+		}
+		for x := 0; x < w; x++ {
+			blur(x, w, h)
+		}
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Pix[y*img.Stride+x] = uint8(math.Min(buf[y*w+x]+0.5, 255.0))
+		}
+	}
+}
+
+// WithEffects returns the font face with effects that are drawn behind its text by Context.DrawText, in order from back to front. Equal effects of the spans of a text are drawn together, so that a glow or outline is continuous between spans.
+func (ff FontFace) WithEffects(effects ...TextEffect) FontFace {
+	ff.effects = append(ff.effects[:len(ff.effects):len(ff.effects)], effects...)
+	return ff
+}
+
+// renderEffects draws the effects of the font faces of the text, which is drawn with the transformation m.
+func (t *Text) renderEffects(r Renderer, m Matrix) {
+	type group struct {
+		effects []TextEffect
+		p       *Path
+	}
+	groups := []group{}
+	add := func(effects []TextEffect, p *Path) {
+		for i := range groups {
+			if reflect.DeepEqual(groups[i].effects, effects) {
+				groups[i].p = groups[i].p.Append(p)
+				return
+			}
+		}
+		groups = append(groups, group{effects, p})
+	}
+	view := t.lineView()
+	for _, line := range t.lines {
+		for _, span := range line.spans {
+			if 0 < len(span.ff.effects) {
+				p := &Path{}
+				span.appendPath(p, view.Translate(span.dx, line.y))
+				add(span.ff.effects, p)
+			}
+		}
+		for _, deco := range line.decos {
+			if 0 < len(deco.ff.effects) {
+				add(deco.ff.effects, deco.ff.Decorate(deco.x1-deco.x0).Transform(view.Translate(deco.x0, line.y)))
+			}
+		}
+	}
+	for _, g := range groups {
+		for _, effect := range g.effects {
+			effect.RenderEffect(r, g.p, m)
+		}
+	}
+}
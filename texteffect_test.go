@@ -0,0 +1,62 @@
+package canvas
+
+import (
+	"image"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestTextEffects(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	effectFace := face.WithEffects(TextGlow{Radius: 1.0, Color: Yellow}, TextShadow{Point{0.5, -0.5}, Gray}, TextOutline{0.2, White})
+	test.That(t, !face.Equals(effectFace), "effects differ")
+	test.That(t, effectFace.Equals(face.WithEffects(TextGlow{Radius: 1.0, Color: Yellow}).WithEffects(TextShadow{Point{0.5, -0.5}, Gray}, TextOutline{0.2, White})), "effects are equal")
+
+	rt := NewRichText()
+	rt.Add(effectFace, "Glow")
+	rt.Add(effectFace, " and")
+	rt.Add(face, " plain")
+	text := rt.ToText(100.0, 20.0, Left, Top, 0.0, 0.0)
+
+	c := New(100.0, 20.0)
+	ctx := NewContext(c)
+	ctx.DrawText(0.0, 20.0, text)
+	test.T(t, len(c.layers), 4) // spans with equal effects are drawn together
+	test.That(t, c.layers[0].img != nil, "glow is an image")
+	test.T(t, c.layers[1].style.FillColor, Gray)
+	test.T(t, c.layers[2].style.StrokeColor, White)
+	test.T(t, c.layers[2].style.StrokeWidth, 0.4)
+	test.T(t, c.layers[3].text, text)
+
+	p, _ := effectFace.ToPath("Glow and")
+	test.T(t, c.layers[1].path.Bounds(), p.Bounds().Move(Point{0.5, text.lines[0].y - 0.5}))
+
+	// the glow fades out around the text
+	img := c.layers[0].img.(*image.RGBA)
+	bounds := img.Bounds()
+	test.T(t, img.RGBAAt(0, 0).A, uint8(0))
+	test.T(t, img.RGBAAt(bounds.Max.X-1, bounds.Max.Y-1).A, uint8(0))
+	peak := uint8(0)
+	for i := 3; i < len(img.Pix); i += 4 {
+		if peak < img.Pix[i] {
+			peak = img.Pix[i]
+		}
+	}
+	test.That(t, 128 < peak && peak < 255, peak)
+}
+
+func TestGaussianBlurAlpha(t *testing.T) {
+	img := image.NewAlpha(image.Rect(0, 0, 21, 21))
+	img.Pix[10*21+10] = 255
+	gaussianBlurAlpha(img, 2.0)
+	sum := 0
+	for _, a := range img.Pix {
+		sum += int(a)
+	}
+	test.That(t, 200 < sum && sum < 300, sum) // mass is retained, up to rounding of pixels
+	test.That(t, img.Pix[10*21+10] > img.Pix[10*21+12] && img.Pix[10*21+12] > img.Pix[10*21+14], "falls off from the center")
+	test.T(t, img.Pix[10*21+12], img.Pix[12*21+10])
+}
@@ -0,0 +1,70 @@
+package canvas
+
+import (
+	"image/color"
+)
+
+// Theme is a set of default colors, fonts, and widths of a document, which contexts and charts resolve their styles against, so that a whole document can be switched between eg. light, dark, or brand themes. See Context.SetTheme.
+type Theme struct {
+	Background color.RGBA   // color of the page, see Context.DrawBackground
+	Foreground color.RGBA   // color of text and of filled paths
+	Muted      color.RGBA   // color of secondary lines such as grid lines
+	Palette    []color.RGBA // categorical colors, such as of data series
+
+	Font        *FontFamily // font of text, which charts do not draw when nil
+	FontSize    float64     // in points
+	StrokeWidth float64     // in millimeters
+	LineWidth   float64     // width of thin lines such as axes in millimeters
+}
+
+// Tableau10 is the categorical Tableau 10 palette.
+var Tableau10 = []color.RGBA{
+	{0x4e, 0x79, 0xa7, 0xff},
+	{0xf2, 0x8e, 0x2b, 0xff},
+	{0xe1, 0x57, 0x59, 0xff},
+	{0x76, 0xb7, 0xb2, 0xff},
+	{0x59, 0xa1, 0x4f, 0xff},
+	{0xed, 0xc9, 0x48, 0xff},
+	{0xb0, 0x7a, 0xa1, 0xff},
+	{0xff, 0x9d, 0xa7, 0xff},
+	{0x9c, 0x75, 0x5f, 0xff},
+	{0xba, 0xb0, 0xac, 0xff},
+}
+
+// LightTheme is the default theme of black on white, whose style is DefaultStyle.
+var LightTheme = Theme{
+	Background:  White,
+	Foreground:  Black,
+	Muted:       color.RGBA{0xdd, 0xdd, 0xdd, 0xff},
+	Palette:     Tableau10,
+	FontSize:    8.0,
+	StrokeWidth: 1.0,
+	LineWidth:   0.2,
+}
+
+// DarkTheme is a theme of light gray on dark gray.
+var DarkTheme = Theme{
+	Background:  color.RGBA{0x1e, 0x1e, 0x1e, 0xff},
+	Foreground:  color.RGBA{0xe6, 0xe6, 0xe6, 0xff},
+	Muted:       color.RGBA{0x44, 0x44, 0x44, 0xff},
+	Palette:     Tableau10,
+	FontSize:    8.0,
+	StrokeWidth: 1.0,
+	LineWidth:   0.2,
+}
+
+// Color returns the i-th color of the palette, which repeats when i exceeds its length, or the foreground color if the palette is empty.
+func (theme Theme) Color(i int) color.RGBA {
+	if len(theme.Palette) == 0 {
+		return theme.Foreground
+	}
+	return theme.Palette[i%len(theme.Palette)]
+}
+
+// Style returns the default style of the theme, which fills with the foreground color and strokes with the stroke width.
+func (theme Theme) Style() Style {
+	style := DefaultStyle
+	style.FillColor = theme.Foreground
+	style.StrokeWidth = theme.StrokeWidth
+	return style
+}
@@ -0,0 +1,29 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestTheme(t *testing.T) {
+	test.T(t, LightTheme.Style(), DefaultStyle)
+	test.T(t, DarkTheme.Color(0), Tableau10[0])
+	test.T(t, DarkTheme.Color(len(Tableau10)+1), Tableau10[1])
+	test.T(t, Theme{Foreground: Red}.Color(3), Red)
+
+	c := New(10.0, 5.0)
+	ctx := NewContext(c)
+	test.T(t, ctx.Theme().Foreground, Black)
+	ctx.SetTheme(DarkTheme)
+	test.T(t, ctx.FillColor, DarkTheme.Foreground)
+	ctx.SetFillColor(Red)
+	ctx.ResetStyle()
+	test.T(t, ctx.FillColor, DarkTheme.Foreground)
+
+	ctx.Translate(2.0, 2.0)
+	ctx.DrawBackground()
+	test.T(t, len(c.layers), 1)
+	test.T(t, c.layers[0].style.FillColor, DarkTheme.Background)
+	test.T(t, c.layers[0].Bounds(), Rect{0.0, 0.0, 10.0, 5.0}) // regardless of the view
+}
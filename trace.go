@@ -0,0 +1,130 @@
+package canvas
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Phase is a phase of rendering that is reported to the tracer, see SetTracer.
+type Phase int
+
+// see Phase
+const (
+	ShapingPhase     Phase = iota // laying out text, counting characters
+	FlatteningPhase               // flattening curves, dashing, and stroking paths, counting the resulting segments
+	RasterizingPhase              // drawing paths and images to raster images, counting paths and images
+	SerializingPhase              // writing PDF and PNG files when they are closed or encoded, counting bytes
+)
+
+var phaseNames = [...]string{"shaping", "flattening", "rasterizing", "serializing"}
+var phaseUnits = [...]string{"characters", "segments", "paths", "bytes"}
+
+func (phase Phase) String() string {
+	if phase < 0 || len(phaseNames) <= int(phase) {
+		return fmt.Sprintf("Phase(%d)", int(phase))
+	}
+	return phaseNames[phase]
+}
+
+// Tracer receives the duration of each call of a phase of rendering together with the number of items it processed, to diagnose why a document renders slowly.
+type Tracer interface {
+	Trace(phase Phase, d time.Duration, count int)
+}
+
+// TracerFunc is a function that is used as a Tracer.
+type TracerFunc func(phase Phase, d time.Duration, count int)
+
+// Trace calls f.
+func (f TracerFunc) Trace(phase Phase, d time.Duration, count int) {
+	f(phase, d, count)
+}
+
+// tracerValue holds the tracer, as atomic.Value does not hold nil.
+type tracerValue struct {
+	Tracer
+}
+
+var tracer atomic.Value
+
+// SetTracer sets the tracer that receives the phases of rendering of all canvases, a nil tracer disables tracing, which is the default. The tracer is called by the goroutine that renders and must be safe for concurrent use when rendering concurrently. Phases may be nested, such as flattening that is part of laying out text.
+func SetTracer(t Tracer) {
+	tracer.Store(tracerValue{t})
+}
+
+// traceSpan is a call of a phase being traced.
+type traceSpan struct {
+	tracer Tracer // nil when tracing is disabled
+	phase  Phase
+	start  time.Time
+}
+
+// startTrace starts tracing a call of the phase, which does not measure the time when tracing is disabled.
+func startTrace(phase Phase) traceSpan {
+	t, _ := tracer.Load().(tracerValue)
+	if t.Tracer == nil {
+		return traceSpan{}
+	}
+	return traceSpan{t.Tracer, phase, time.Now()}
+}
+
+// end reports the call with the number of processed items to the tracer.
+func (span traceSpan) end(count int) {
+	if span.tracer != nil {
+		span.tracer.Trace(span.phase, time.Since(span.start), count)
+	}
+}
+
+// endPath reports the call with the number of segments of the resulting path, which are only counted when tracing is enabled.
+func (span traceSpan) endPath(p *Path) {
+	if span.tracer != nil {
+		span.end(p.segments())
+	}
+}
+
+// TraceStats are the totals of calls of a phase.
+type TraceStats struct {
+	Calls    int
+	Duration time.Duration
+	Count    int
+}
+
+// TraceSummary is a tracer that sums the calls per phase, for example to print a report after rendering a document. It is safe for concurrent use.
+type TraceSummary struct {
+	mu     sync.Mutex
+	phases [len(phaseNames)]TraceStats
+}
+
+// Trace adds a call of the phase.
+func (s *TraceSummary) Trace(phase Phase, d time.Duration, count int) {
+	if phase < 0 || len(s.phases) <= int(phase) {
+		return
+	}
+	s.mu.Lock()
+	s.phases[phase].Calls++
+	s.phases[phase].Duration += d
+	s.phases[phase].Count += count
+	s.mu.Unlock()
+}
+
+// Stats returns the totals of the phase.
+func (s *TraceSummary) Stats(phase Phase) TraceStats {
+	if phase < 0 || len(s.phases) <= int(phase) {
+		return TraceStats{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.phases[phase]
+}
+
+// String returns the totals of each phase, one per line.
+func (s *TraceSummary) String() string {
+	sb := strings.Builder{}
+	for i := range s.phases {
+		stats := s.Stats(Phase(i))
+		fmt.Fprintf(&sb, "%v: %d calls in %v, %d %v\n", Phase(i), stats.Calls, stats.Duration, stats.Count, phaseUnits[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,53 @@
+package canvas
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tdewolff/test"
+)
+
+func TestTracer(t *testing.T) {
+	summary := &TraceSummary{}
+	SetTracer(summary)
+	defer SetTracer(nil)
+
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	c := New(20.0, 20.0)
+	ctx := NewContext(c)
+	ctx.DrawText(2.0, 10.0, NewTextLine(face, "text", Left))
+	ctx.SetStrokeColor(Black)
+	ctx.SetDashes(0.0, 1.0)
+	ctx.DrawPath(2.0, 2.0, Circle(5.0))
+	test.T(t, summary.Stats(ShapingPhase), TraceStats{Calls: 1, Duration: summary.Stats(ShapingPhase).Duration, Count: 4})
+
+	img := c.WriteImage(1.0)
+	test.That(t, 0 < summary.Stats(FlatteningPhase).Calls, "flattening")
+	test.That(t, 0 < summary.Stats(RasterizingPhase).Count, "rasterizing")
+
+	buf := &bytes.Buffer{}
+	test.Error(t, EncodePNG(buf, img, PNGOptions{}))
+	test.T(t, summary.Stats(SerializingPhase).Count, buf.Len())
+	pdf := NewPDF(&bytes.Buffer{}, 20.0, 20.0)
+	c.Render(pdf)
+	test.Error(t, pdf.Close())
+	test.T(t, summary.Stats(SerializingPhase).Calls, 2)
+	test.That(t, strings.HasPrefix(summary.String(), "shaping: 1 calls in "), summary.String())
+
+	phases := []Phase{}
+	SetTracer(TracerFunc(func(phase Phase, d time.Duration, count int) {
+		phases = append(phases, phase)
+	}))
+	NewTextLine(face, "text", Left)
+	test.T(t, phases, []Phase{ShapingPhase})
+	test.T(t, Phase(7).String(), "Phase(7)")
+
+	SetTracer(nil)
+	NewTextLine(face, "text", Left)
+	test.T(t, len(phases), 1)
+}
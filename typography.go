@@ -0,0 +1,217 @@
+package canvas
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TypographicContext holds the state of the typographic substitutions between successive texts, such as whether a quote has been opened and the preceding character. Sharing a context between the texts of the same paragraph makes sure quotes that are split over several texts pair correctly.
+type TypographicContext struct {
+	inSingleQuote, inDoubleQuote bool
+	prev                         rune
+	prevLetter                   rune // last letter, to determine the script of the text
+}
+
+// NewTypographicContext returns a new TypographicContext.
+func NewTypographicContext() *TypographicContext {
+	return &TypographicContext{}
+}
+
+// Reset resets the context, eg. at the start of a new paragraph.
+func (ctx *TypographicContext) Reset() {
+	*ctx = TypographicContext{}
+}
+
+// textScript is the writing system that determines typographic conventions such as quotation marks.
+type textScript int
+
+const (
+	latinScript textScript = iota
+	hebrewScript
+	arabicScript
+)
+
+// quoteMarks are the opening and closing double quotes and the opening and closing single quotes for each script. Text is in logical order, so for right-to-left scripts the opening quote is on the right once displayed. Guillemets are mirrored by the bidi algorithm, so that the opening guillemet points outwards for Arabic.
+var quoteMarks = map[textScript][4]string{
+	latinScript:  {"\u201C", "\u201D", "\u2018", "\u2019"},
+	hebrewScript: {"\u201E", "\u201D", "\u201A", "\u2019"},
+	arabicScript: {"\u00AB", "\u00BB", "\u2039", "\u203A"},
+}
+
+// scriptOf returns the script of a letter, which is latinScript for all left-to-right scripts.
+func scriptOf(r rune) textScript {
+	if unicode.Is(unicode.Hebrew, r) {
+		return hebrewScript
+	} else if unicode.In(r, unicode.Arabic, unicode.Syriac, unicode.Thaana, unicode.Nko) {
+		return arabicScript
+	}
+	return latinScript
+}
+
+// TypographicRule is a typographic substitution that replaces Src by Dst. If WordBoundary is set, the rule only applies when Src is preceded and followed by a word boundary (whitespace, punctuation other than a slash, or the start or end of the text), such as for fractions.
+type TypographicRule struct {
+	Src          string
+	Dst          string
+	WordBoundary bool `json:",omitempty"`
+}
+
+// TypographicRules is a set of typographic substitution rules. At each position the rule with the longest matching source is applied, where earlier rules take precedence for sources of equal length.
+type TypographicRules []TypographicRule
+
+// EllipsisRules replace three dots by an ellipsis.
+var EllipsisRules = TypographicRules{
+	{"...", "…", false},
+	{". . .", "…", false},
+}
+
+// DashRules replace two and three hyphens by en- and em-dashes respectively.
+var DashRules = TypographicRules{
+	{"---", "—", false}, // em-dash
+	{"--", "–", false},  // en-dash
+}
+
+// SymbolRules replace (c), (r) and (tm) by their symbols.
+var SymbolRules = TypographicRules{
+	{"(c)", "©", false},  // copyright
+	{"(r)", "®", false},  // registered
+	{"(tm)", "™", false}, // trademark
+}
+
+// FractionRules replace common fractions and plus-minus by their symbols.
+var FractionRules = TypographicRules{
+	{"1/2", "½", true}, // one half
+	{"1/4", "¼", true}, // one quarter
+	{"3/4", "¾", true}, // three quarters
+	{"+/-", "±", true}, // plus-minus
+}
+
+// DefaultTypographicRules are the typographic substitution rules used by default. Extend them by copying, eg. append(TypographicRules{{"->", "→", false}}, DefaultTypographicRules...).
+var DefaultTypographicRules = concatTypographicRules(EllipsisRules, DashRules, SymbolRules, FractionRules)
+
+func concatTypographicRules(ruless ...TypographicRules) TypographicRules {
+	rules := TypographicRules{}
+	for _, r := range ruless {
+		rules = append(rules, r...)
+	}
+	return rules
+}
+
+// TypographicSubstituteOptions are the options for TypographicSubstitute.
+type TypographicSubstituteOptions struct {
+	Rules       TypographicRules    // substitution rules, eg. DefaultTypographicRules or a subset such as DashRules
+	SmartQuotes bool                // replace straight quotes by opening and closing quotes
+	Context     *TypographicContext // optional, persists the state such as opened quotes across calls
+}
+
+// DefaultTypographicSubstituteOptions are the options used by TypographicSubstitute when none are given, they are identical to the substitutions applied to text drawn with a font.
+var DefaultTypographicSubstituteOptions = TypographicSubstituteOptions{
+	Rules:       DefaultTypographicRules,
+	SmartQuotes: true,
+}
+
+// TypographicSubstitute applies typographic substitutions to s, such as smart quotes, dashes, ellipses and fractions, with the same behavior as for text drawn with a font. Quotes follow the conventions of the script of the surrounding text, so that Hebrew and Arabic text in logical order receives the appropriate quotation marks, and straight quotes within Hebrew words become geresh and gershayim. Dashes and ellipses are direction neutral and are placed by the bidirectional algorithm. This allows to preprocess strings for other uses such as web output. Only the first options are used, and DefaultTypographicSubstituteOptions if none are given.
+func TypographicSubstitute(s string, opts ...TypographicSubstituteOptions) string {
+	options := DefaultTypographicSubstituteOptions
+	if 0 < len(opts) {
+		options = opts[0]
+	}
+	ctx := options.Context
+	if ctx == nil {
+		ctx = NewTypographicContext()
+	}
+	return substituteTypography(s, options.Rules, options.SmartQuotes, ctx)
+}
+
+func substituteTypography(s string, rules TypographicRules, quotes bool, ctx *TypographicContext) string {
+	var rPrev rune
+	r := ctx.prev
+	var i, size int
+	for {
+		rPrev = r
+		if unicode.IsLetter(rPrev) {
+			ctx.prevLetter = rPrev
+		}
+		i += size
+		if i >= len(s) {
+			break
+		}
+
+		r, size = utf8.DecodeRuneInString(s[i:])
+		if rule, ok := rules.match(s, i, rPrev); ok {
+			s, size = stringReplace(s, i, len(rule.Src), rule.Dst)
+			continue
+		}
+
+		// quotes
+		if quotes && (s[i] == '"' || s[i] == '\'') {
+			var rNext rune
+			if i+1 < len(s) {
+				rNext, _ = utf8.DecodeRuneInString(s[i+1:])
+			}
+
+			// Hebrew uses geresh and gershayim for abbreviations and transliterations
+			if scriptOf(rPrev) == hebrewScript {
+				if s[i] == '"' && scriptOf(rNext) == hebrewScript {
+					s, size = stringReplace(s, i, 1, "\u05F4") // gershayim
+					continue
+				} else if s[i] == '\'' && !ctx.inSingleQuote {
+					s, size = stringReplace(s, i, 1, "\u05F3") // geresh
+					continue
+				}
+			}
+
+			prevScript := scriptOf(ctx.prevLetter)
+			nextScript := prevScript
+			if unicode.IsLetter(rNext) {
+				nextScript = scriptOf(rNext)
+			}
+			if s[i] == '"' {
+				s, size = quoteReplace(s, i, rPrev, r, rNext, &ctx.inDoubleQuote, prevScript, nextScript)
+			} else {
+				s, size = quoteReplace(s, i, rPrev, r, rNext, &ctx.inSingleQuote, prevScript, nextScript)
+			}
+		}
+	}
+	if 0 < len(s) {
+		ctx.prev, _ = utf8.DecodeLastRuneInString(s)
+	}
+	return s
+}
+
+// LoadTypographicRules loads typographic substitution rules from JSON, as written by TypographicRules.Save.
+func LoadTypographicRules(r io.Reader) (TypographicRules, error) {
+	rules := TypographicRules{}
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Save writes the typographic substitution rules as JSON.
+func (rules TypographicRules) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(rules)
+}
+
+// match returns the rule that matches s at position i, with rPrev the preceding rune.
+func (rules TypographicRules) match(s string, i int, rPrev rune) (TypographicRule, bool) {
+	best, ok := TypographicRule{}, false
+	for _, rule := range rules {
+		if rule.Src == "" || len(rule.Src) <= len(best.Src) || !strings.HasPrefix(s[i:], rule.Src) {
+			continue
+		}
+		if rule.WordBoundary {
+			var rNext rune
+			if i+len(rule.Src) < len(s) {
+				rNext, _ = utf8.DecodeRuneInString(s[i+len(rule.Src):])
+			}
+			if !isWordBoundary(rPrev) || rPrev == '/' || !isWordBoundary(rNext) || rNext == '/' {
+				continue
+			}
+		}
+		best, ok = rule, true
+	}
+	return best, ok
+}
@@ -42,6 +42,15 @@ func angleBetween(theta, lower, upper float64) bool {
 	return false
 }
 
+func clampInt(i, lower, upper int) int {
+	if i < lower {
+		return lower
+	} else if upper < i {
+		return upper
+	}
+	return i
+}
+
 func float64sEqual(a, b []float64) bool {
 	if len(a) != len(b) {
 		return false
@@ -263,6 +272,16 @@ func (r Rect) Add(q Rect) Rect {
 	return Rect{x0, y0, x1 - x0, y1 - y0}
 }
 
+// Overlaps returns true if both rectangles overlap or touch.
+func (r Rect) Overlaps(q Rect) bool {
+	return r.X <= q.X+q.W && q.X <= r.X+r.W && r.Y <= q.Y+q.H && q.Y <= r.Y+r.H
+}
+
+// Contains returns true if the point lies within the rectangle or on its boundary.
+func (r Rect) Contains(p Point) bool {
+	return r.X <= p.X && p.X <= r.X+r.W && r.Y <= p.Y && p.Y <= r.Y+r.H
+}
+
 // Transform transforms the rectangle by affine transformation matrix m and returns the new bounds of that rectangle.
 func (r Rect) Transform(m Matrix) Rect {
 	p0 := m.Dot(Point{r.X, r.Y})
@@ -586,6 +605,8 @@ func solveCubicFormula(a, b, c, d float64) (float64, float64, float64) {
 				x1 = -tmp - bthird
 				x2 = tmp - bthird
 				x3 = 0.0 - bthird
+			} else {
+				x1 = 0.0 - bthird
 			}
 		} else if equal(c1, 0.0) {
 			if 0.0 < c0 {
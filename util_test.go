@@ -198,6 +198,11 @@ func TestSolveCubicFormula(t *testing.T) {
 	test.Float(t, x2, 1.0)
 	test.Float(t, x3, 4.0)
 
+	x1, x2, x3 = solveCubicFormula(1.0, -3.0, 4.0, -2.0) // c0 == 0, 0 < c1
+	test.Float(t, x1, 1.0)
+	test.Float(t, x2, math.NaN())
+	test.Float(t, x3, math.NaN())
+
 	x1, x2, x3 = solveCubicFormula(1.0, -15.0, 75.0, -124.0) // c1 == 0, 0 < c0
 	test.Float(t, x1, 4.0)
 	test.Float(t, x2, math.NaN())
@@ -0,0 +1,116 @@
+package canvas
+
+import (
+	"unicode"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+)
+
+// WritingMode is the direction in which the lines of a text are written.
+type WritingMode int
+
+// see WritingMode
+const (
+	HorizontalTB WritingMode = iota // horizontal lines from top to bottom
+	VerticalRL                      // vertical columns from right to left, as in Japanese and Chinese typesetting
+)
+
+// uprightRunes are the characters that are set upright in vertical text, which is a simplification of the Vertical_Orientation property of Unicode. Other characters, such as Latin, are rotated by 90 degrees clockwise.
+// See https://www.unicode.org/reports/tr50/
+var uprightRunes = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x1100, 0x11FF, 1}, // Hangul Jamo
+		{0x2E80, 0x2FFF, 1}, // CJK Radicals, Kangxi Radicals, and Ideographic Description Characters
+		{0x3000, 0x9FFF, 1}, // CJK Symbols and Punctuation, Kana, Bopomofo, Hangul Compatibility Jamo, Kanbun, enclosed and compatibility characters, and CJK Unified Ideographs
+		{0xA000, 0xA4CF, 1}, // Yi
+		{0xA960, 0xA97F, 1}, // Hangul Jamo Extended-A
+		{0xAC00, 0xD7FF, 1}, // Hangul Syllables and Hangul Jamo Extended-B
+		{0xF900, 0xFAFF, 1}, // CJK Compatibility Ideographs
+		{0xFE10, 0xFE1F, 1}, // Vertical Forms
+		{0xFE30, 0xFE4F, 1}, // CJK Compatibility Forms
+		{0xFF01, 0xFF60, 1}, // Fullwidth Forms
+		{0xFFE0, 0xFFE7, 1}, // Fullwidth Signs
+	},
+	R32: []unicode.Range32{
+		{0x1F200, 0x1F2FF, 1}, // Enclosed Ideographic Supplement
+		{0x1F300, 0x1F64F, 1}, // Miscellaneous Symbols and Pictographs, and Emoticons
+		{0x1F900, 0x1F9FF, 1}, // Supplemental Symbols and Pictographs
+		{0x20000, 0x3FFFF, 1}, // CJK Unified Ideographs Extension B and later
+	},
+}
+
+// isUpright returns true if the character is set upright in vertical text.
+func isUpright(r rune) bool {
+	return unicode.Is(uprightRunes, r)
+}
+
+// isIdeographic returns true for the characters of scripts that are written without spaces between words, which allow a line break between any two of them.
+func isIdeographic(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+// isSmallKana returns true for the small kana and the prolonged sound mark of Japanese, which may not start a line.
+func isSmallKana(r rune) bool {
+	switch r {
+	case 'ぁ', 'ぃ', 'ぅ', 'ぇ', 'ぉ', 'っ', 'ゃ', 'ゅ', 'ょ', 'ゎ', 'ゕ', 'ゖ', 'ァ', 'ィ', 'ゥ', 'ェ', 'ォ', 'ッ', 'ャ', 'ュ', 'ョ', 'ヮ', 'ヵ', 'ヶ', 'ー':
+		return true
+	}
+	return false
+}
+
+// Vertical returns the font face for vertical writing, see RichText.SetWritingMode. Characters of CJK scripts and fullwidth forms are set upright, where they are substituted by their vertical alternates of the vert feature of the GSUB table, and advance by their vertical advance of the vmtx table or by the height of the font if it has no vertical metrics. Other characters are shaped as in horizontal text and are rotated with the column.
+func (ff FontFace) Vertical() FontFace {
+	ff.vertical = true
+	return ff
+}
+
+// verticalAdvance returns the vertical advance of an upright glyph in mm.
+func (ff FontFace) verticalAdvance(index sfnt.GlyphIndex) float64 {
+	if ff.font.vertical == nil {
+		metrics := ff.Metrics()
+		return metrics.Ascent + metrics.Descent
+	}
+	advance, _ := ff.font.vertical.Advance(uint16(index))
+	return ff.units(float64(advance))
+}
+
+// verticalOrigin returns the vertical origin of an upright glyph in mm relative to its horizontal origin, which is the center of the top of the glyph that is placed at the center of the column. It is the point of the VORG table, or at the top side bearing of the vmtx table above the glyph, or at the ascent of the font if it has no vertical metrics.
+func (ff FontFace) verticalOrigin(buffer *sfnt.Buffer, index sfnt.GlyphIndex) Point {
+	origin := Point{}
+	if advance, err := ff.font.sfnt.GlyphAdvance(buffer, index, toI26_6(ff.size*ff.scale), font.HintingNone); err == nil {
+		origin.X = fromI26_6(advance) / 2.0
+	}
+	if ff.font.vertical == nil {
+		origin.Y = ff.Metrics().Ascent
+	} else if y, ok := ff.font.vertical.OriginY(uint16(index)); ok {
+		origin.Y = ff.units(float64(y))
+	} else {
+		_, tsb := ff.font.vertical.Advance(uint16(index))
+		units := toI26_6(float64(ff.font.sfnt.UnitsPerEm()))
+		if segments, err := ff.font.loadGlyph(buffer, index, units); err == nil && 0 < len(segments) {
+			// the y-axis of the segments points down
+			top := segments[0].Args[0].Y
+			for _, segment := range segments {
+				n := 1
+				if segment.Op == sfnt.SegmentOpQuadTo {
+					n = 2
+				} else if segment.Op == sfnt.SegmentOpCubeTo {
+					n = 3
+				}
+				for _, arg := range segment.Args[:n] {
+					if arg.Y < top {
+						top = arg.Y
+					}
+				}
+			}
+			origin.Y = ff.units(-fromI26_6(top) + float64(tsb))
+		}
+	}
+	return origin
+}
+
+// units converts font units to mm.
+func (ff FontFace) units(v float64) float64 {
+	return v * ff.size * ff.scale / float64(ff.font.sfnt.UnitsPerEm())
+}
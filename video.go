@@ -0,0 +1,168 @@
+package canvas
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// FrameWriter writes rasterized frames of a video.
+type FrameWriter interface {
+	WriteFrame(img image.Image) error
+	Close() error
+}
+
+// WriteFrames rasterizes n canvases returned by frame for each frame index with given DPM (dots-per-millimeter) and writes them to w. It does not close w.
+func WriteFrames(w FrameWriter, n int, dpm float64, frame func(i int) *Canvas) error {
+	for i := 0; i < n; i++ {
+		if err := w.WriteFrame(frame(i).WriteImage(dpm)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// frameRate returns the frame rate as a fraction, recognizing the NTSC rates such as 29.97 frames per second.
+func frameRate(fps float64) (int, int) {
+	if fps == math.Floor(fps) {
+		return int(fps), 1
+	} else if ntsc := fps * 1001.0 / 1000.0; math.Abs(ntsc-math.Floor(ntsc+0.5)) < 1e-3 {
+		return int(ntsc+0.5) * 1000, 1001
+	}
+	num, den := int(fps*1000.0+0.5), 1000
+	for a, b := num, den; ; {
+		if b == 0 {
+			return num / a, den / a
+		}
+		a, b = b, a%b
+	}
+}
+
+// Y4M writes frames as an uncompressed YUV4MPEG2 stream, which is read by video encoders such as ffmpeg (with "-f yuv4mpegpipe") and x264. Frames are converted to BT.601 Y'CbCr in limited range without chroma subsampling, and transparent pixels are composited onto white.
+type Y4M struct {
+	w             *bufio.Writer
+	width, height int
+	planes        []byte
+	err           error
+}
+
+// NewY4M returns a YUV4MPEG2 writer of frames of width by height pixels at fps frames per second. All frames must have the same size.
+func NewY4M(w io.Writer, width, height int, fps float64) *Y4M {
+	num, den := frameRate(fps)
+	bw := bufio.NewWriter(w)
+	_, err := fmt.Fprintf(bw, "YUV4MPEG2 W%d H%d F%d:%d Ip A1:1 C444\n", width, height, num, den)
+	return &Y4M{
+		w:      bw,
+		width:  width,
+		height: height,
+		planes: make([]byte, 3*width*height),
+		err:    err,
+	}
+}
+
+// WriteFrame writes a frame.
+func (w *Y4M) WriteFrame(img image.Image) error {
+	if w.err != nil {
+		return w.err
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() != w.width || bounds.Dy() != w.height {
+		return fmt.Errorf("frame of %dx%d pixels does not match video of %dx%d pixels", bounds.Dx(), bounds.Dy(), w.width, w.height)
+	}
+
+	n := w.width * w.height
+	for y := 0; y < w.height; y++ {
+		for x := 0; x < w.width; x++ {
+			col := color.RGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.RGBA)
+			r := float64(col.R+(0xff-col.A)) / 255.0 // over white, colors are premultiplied
+			g := float64(col.G+(0xff-col.A)) / 255.0
+			b := float64(col.B+(0xff-col.A)) / 255.0
+			i := y*w.width + x
+			w.planes[i] = uint8(16.0 + 65.481*r + 128.553*g + 24.966*b + 0.5)
+			w.planes[n+i] = uint8(128.0 - 37.797*r - 74.203*g + 112.0*b + 0.5)
+			w.planes[2*n+i] = uint8(128.0 + 112.0*r - 93.786*g - 18.214*b + 0.5)
+		}
+	}
+	if _, w.err = w.w.WriteString("FRAME\n"); w.err != nil {
+		return w.err
+	}
+	_, w.err = w.w.Write(w.planes)
+	return w.err
+}
+
+// Close flushes the stream, it does not close the underlying writer.
+func (w *Y4M) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+// ffmpegCommand is the name of the ffmpeg executable.
+var ffmpegCommand = "ffmpeg"
+
+// FFmpeg pipes frames as a PNG image sequence into an ffmpeg process that encodes the video, which must be installed.
+type FFmpeg struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr *bytes.Buffer
+	enc    *png.Encoder
+	err    error
+}
+
+// NewFFmpeg starts ffmpeg to encode a video of fps frames per second to filename, where the container and codec are chosen by ffmpeg from the file extension, such as .mp4 or .webm. The arguments are passed to ffmpeg as output options, such as "-c:v", "libvpx-vp9". When no arguments are given, the video uses the widely supported yuv420p pixel format and is padded to an even width and height, which it requires.
+func NewFFmpeg(filename string, fps float64, args ...string) (*FFmpeg, error) {
+	num, den := frameRate(fps)
+	if len(args) == 0 {
+		args = []string{"-pix_fmt", "yuv420p", "-vf", "pad=ceil(iw/2)*2:ceil(ih/2)*2"}
+	}
+	cmdArgs := []string{"-y", "-loglevel", "error", "-f", "image2pipe", "-framerate", strconv.Itoa(num) + "/" + strconv.Itoa(den), "-c:v", "png", "-i", "-"}
+	cmdArgs = append(cmdArgs, args...)
+	cmdArgs = append(cmdArgs, filename)
+
+	cmd := exec.Command(ffmpegCommand, cmdArgs...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &FFmpeg{
+		cmd:    cmd,
+		stdin:  stdin,
+		stderr: stderr,
+		enc:    &png.Encoder{CompressionLevel: png.BestSpeed},
+	}, nil
+}
+
+// WriteFrame writes a frame.
+func (w *FFmpeg) WriteFrame(img image.Image) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.enc.Encode(w.stdin, img)
+	return w.err
+}
+
+// Close waits for ffmpeg to finish encoding the video, and returns an error including the output of ffmpeg if it failed.
+func (w *FFmpeg) Close() error {
+	w.stdin.Close()
+	if err := w.cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(w.stderr.String()); msg != "" {
+			return fmt.Errorf("ffmpeg: %v: %s", err, msg)
+		}
+		return fmt.Errorf("ffmpeg: %v", err)
+	}
+	return w.err
+}
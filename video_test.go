@@ -0,0 +1,96 @@
+package canvas
+
+import (
+	"bytes"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestFrameRate(t *testing.T) {
+	var tests = []struct {
+		fps      float64
+		num, den int
+	}{
+		{30.0, 30, 1},
+		{29.97, 30000, 1001},
+		{23.976, 24000, 1001},
+		{12.5, 25, 2},
+	}
+	for _, tt := range tests {
+		num, den := frameRate(tt.fps)
+		test.T(t, num, tt.num)
+		test.T(t, den, tt.den)
+	}
+}
+
+func TestY4M(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 1))
+	img.Set(0, 0, White)
+	img.Set(1, 0, Black)
+	img.Set(2, 0, Red)
+
+	buf := &bytes.Buffer{}
+	w := NewY4M(buf, 3, 1, 25.0)
+	test.Error(t, w.WriteFrame(img))
+	test.Error(t, w.Close())
+	test.String(t, buf.String(), "YUV4MPEG2 W3 H1 F25:1 Ip A1:1 C444\nFRAME\n"+string([]byte{235, 16, 81, 128, 128, 90, 128, 128, 240}))
+
+	test.That(t, w.WriteFrame(image.NewRGBA(image.Rect(0, 0, 2, 2))) != nil, "frame size must match")
+}
+
+func TestWriteFrames(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewY4M(buf, 4, 2, 25.0)
+	test.Error(t, WriteFrames(w, 3, 1.0, func(i int) *Canvas {
+		c := New(4.0, 2.0)
+		c.RenderPath(Rectangle(float64(i), 2.0), DefaultStyle, Identity)
+		return c
+	}))
+	test.Error(t, w.Close())
+	test.T(t, bytes.Count(buf.Bytes(), []byte("FRAME\n")), 3)
+	test.T(t, buf.Len(), len("YUV4MPEG2 W4 H2 F25:1 Ip A1:1 C444\n")+3*(len("FRAME\n")+3*4*2))
+}
+
+func TestFFmpeg(t *testing.T) {
+	command := ffmpegCommand
+	defer func() { ffmpegCommand = command }()
+	ffmpegCommand = "ffmpeg-does-not-exist"
+	_, err := NewFFmpeg("video.mp4", 25.0)
+	test.That(t, err != nil, "missing ffmpeg")
+	if runtime.GOOS == "windows" {
+		t.Skip("no shell")
+	}
+
+	// fake ffmpeg that writes its input to the output file
+	dir, err := ioutil.TempDir("", "canvas")
+	test.Error(t, err)
+	defer os.RemoveAll(dir)
+	ffmpegCommand = filepath.Join(dir, "ffmpeg")
+	test.Error(t, ioutil.WriteFile(ffmpegCommand, []byte("#!/bin/sh\nfor last; do :; done\ncat > \"$last\"\n"), 0755))
+
+	filename := filepath.Join(dir, "video.mp4")
+	w, err := NewFFmpeg(filename, 25.0)
+	test.Error(t, err)
+	test.T(t, w.cmd.Args[len(w.cmd.Args)-5:], []string{"-pix_fmt", "yuv420p", "-vf", "pad=ceil(iw/2)*2:ceil(ih/2)*2", filename})
+	test.Error(t, WriteFrames(w, 5, 4.0, func(i int) *Canvas {
+		c := New(10.0, 5.0)
+		c.RenderPath(Circle(float64(i)), DefaultStyle, Identity.Translate(5.0, 2.5))
+		return c
+	}))
+	test.Error(t, w.Close())
+	b, err := ioutil.ReadFile(filename)
+	test.Error(t, err)
+	test.T(t, bytes.Count(b, []byte("\x89PNG")), 5)
+
+	// errors include the output of ffmpeg
+	test.Error(t, ioutil.WriteFile(ffmpegCommand, []byte("#!/bin/sh\necho invalid codec >&2\nexit 1\n"), 0755))
+	w, err = NewFFmpeg(filename, 25.0)
+	test.Error(t, err)
+	test.T(t, w.Close().Error(), "ffmpeg: exit status 1: invalid codec")
+}
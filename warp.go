@@ -0,0 +1,151 @@
+package canvas
+
+import "math"
+
+// Warp is a nonlinear distortion of the plane, such as an envelope or a mesh, see Path.Warp.
+type Warp interface {
+	Warp(Point) Point
+}
+
+// WarpFunc is a function that is used as a Warp.
+type WarpFunc func(Point) Point
+
+// Warp calls f(p).
+func (f WarpFunc) Warp(p Point) Point {
+	return f(p)
+}
+
+// Envelope is a distortion that maps a rectangle onto the region bounded by four cubic Bézier edges, to bend logos and text into arcs, bulges, or flags. The bottom and top edges run from left to right and the left and right edges from bottom to top, so that their ends meet at the corners. The region is interpolated between the edges as a Coons patch, and points outside the rectangle are extrapolated.
+type Envelope struct {
+	Rect                     Rect
+	Bottom, Right, Top, Left [4]Point // control points of the edges
+}
+
+// NewEnvelope returns the envelope that maps the rectangle onto the quadrilateral with the given corners in the order bottom-left, bottom-right, top-right, and top-left, with straight edges that can be bent by moving their control points.
+func NewEnvelope(rect Rect, corners [4]Point) Envelope {
+	line := func(a, b Point) [4]Point {
+		return [4]Point{a, a.Interpolate(b, 1.0/3.0), a.Interpolate(b, 2.0/3.0), b}
+	}
+	return Envelope{
+		Rect:   rect,
+		Bottom: line(corners[0], corners[1]),
+		Right:  line(corners[1], corners[2]),
+		Top:    line(corners[3], corners[2]),
+		Left:   line(corners[0], corners[3]),
+	}
+}
+
+// ArchEnvelope returns the envelope that bends the rectangle into an arch, where the middle of the top and bottom edges is raised by bend, or lowered when negative.
+func ArchEnvelope(rect Rect, bend float64) Envelope {
+	e := NewEnvelope(rect, [4]Point{{rect.X, rect.Y}, {rect.X + rect.W, rect.Y}, {rect.X + rect.W, rect.Y + rect.H}, {rect.X, rect.Y + rect.H}})
+	for _, edge := range []*[4]Point{&e.Bottom, &e.Top} {
+		// the middle of a cubic Bézier is at 3/4 of the offset of its control points
+		edge[1].Y += 4.0 / 3.0 * bend
+		edge[2].Y += 4.0 / 3.0 * bend
+	}
+	return e
+}
+
+// Warp maps a point in the rectangle to the region bounded by the edges.
+func (e Envelope) Warp(p Point) Point {
+	u, v := (p.X-e.Rect.X)/e.Rect.W, (p.Y-e.Rect.Y)/e.Rect.H
+	bottom := cubicBezierPos(e.Bottom[0], e.Bottom[1], e.Bottom[2], e.Bottom[3], u)
+	top := cubicBezierPos(e.Top[0], e.Top[1], e.Top[2], e.Top[3], u)
+	left := cubicBezierPos(e.Left[0], e.Left[1], e.Left[2], e.Left[3], v)
+	right := cubicBezierPos(e.Right[0], e.Right[1], e.Right[2], e.Right[3], v)
+
+	// sum of the interpolations between the opposite edges minus the bilinear interpolation of the corners
+	q := bottom.Mul(1.0 - v).Add(top.Mul(v)).Add(left.Mul(1.0 - u)).Add(right.Mul(u))
+	q = q.Sub(e.Bottom[0].Mul((1.0 - u) * (1.0 - v))).Sub(e.Bottom[3].Mul(u * (1.0 - v)))
+	return q.Sub(e.Top[0].Mul((1.0 - u) * v)).Sub(e.Top[3].Mul(u * v))
+}
+
+// Mesh is a distortion that maps a rectangle divided into a grid of cells onto a grid of points, such as to wave a flag. Points in between are interpolated smoothly by bicubic Catmull-Rom splines that pass through the grid points, and points outside the rectangle are extrapolated.
+type Mesh struct {
+	Rect   Rect
+	Points [][]Point // rows of points from bottom to top, each with points from left to right, of at least 2x2 points
+}
+
+// NewMesh returns the mesh of the rectangle with cols by rows cells, of which the points are placed regularly over the rectangle so that they can be moved. There is at least one column and row.
+func NewMesh(rect Rect, cols, rows int) *Mesh {
+	if cols < 1 {
+		cols = 1
+	}
+	if rows < 1 {
+		rows = 1
+	}
+	points := make([][]Point, rows+1)
+	for j := range points {
+		points[j] = make([]Point, cols+1)
+		for i := range points[j] {
+			points[j][i] = Point{rect.X + rect.W*float64(i)/float64(cols), rect.Y + rect.H*float64(j)/float64(rows)}
+		}
+	}
+	return &Mesh{rect, points}
+}
+
+// point returns the grid point at column i and row j, extrapolated linearly for one point beyond the edges.
+func (m *Mesh) point(i, j int) Point {
+	cols, rows := len(m.Points[0])-1, len(m.Points)-1
+	if j < 0 {
+		return m.point(i, 0).Mul(2.0).Sub(m.point(i, 1))
+	} else if rows < j {
+		return m.point(i, rows).Mul(2.0).Sub(m.point(i, rows-1))
+	} else if i < 0 {
+		return m.Points[j][0].Mul(2.0).Sub(m.Points[j][1])
+	} else if cols < i {
+		return m.Points[j][cols].Mul(2.0).Sub(m.Points[j][cols-1])
+	}
+	return m.Points[j][i]
+}
+
+// Warp maps a point in the rectangle to the mesh.
+func (m *Mesh) Warp(p Point) Point {
+	cols, rows := len(m.Points[0])-1, len(m.Points)-1
+	u, v := (p.X-m.Rect.X)/m.Rect.W*float64(cols), (p.Y-m.Rect.Y)/m.Rect.H*float64(rows)
+	i, j := clampInt(int(math.Floor(u)), 0, cols-1), clampInt(int(math.Floor(v)), 0, rows-1)
+	s, t := u-float64(i), v-float64(j)
+
+	var col [4]Point
+	for k := range col {
+		col[k] = catmullRomPos(m.point(i-1, j-1+k), m.point(i, j-1+k), m.point(i+1, j-1+k), m.point(i+2, j-1+k), s)
+	}
+	return catmullRomPos(col[0], col[1], col[2], col[3], t)
+}
+
+// catmullRomPos returns the position at t in [0,1] of the Catmull-Rom spline between p1 and p2.
+func catmullRomPos(p0, p1, p2, p3 Point, t float64) Point {
+	a := p1.Mul(2.0)
+	b := p2.Sub(p0).Mul(t)
+	c := p0.Mul(2.0).Sub(p1.Mul(5.0)).Add(p2.Mul(4.0)).Sub(p3).Mul(t * t)
+	d := p1.Sub(p2).Mul(3.0).Add(p3).Sub(p0).Mul(t * t * t)
+	return a.Add(b).Add(c).Add(d).Mul(0.5)
+}
+
+// Warp applies the distortion to the path and returns a new path, such as text converted by Text.ToPaths. Lines and curves are converted to cubic Béziers that are subdivided until they deviate less than Tolerance from the distorted path, including the segments that close subpaths.
+func (p *Path) Warp(w Warp) *Path {
+	p = p.toCubes()
+	q := &Path{}
+	var cur Point
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		end := Point{p.d[i+cmdLen(cmd)-3], p.d[i+cmdLen(cmd)-2]}
+		switch cmd {
+		case moveToCmd:
+			pos := w.Warp(end)
+			q.MoveTo(pos.X, pos.Y)
+		case lineToCmd, closeCmd:
+			if cmd == lineToCmd || !cur.Equals(end) {
+				mapCubicBezier(q, w.Warp, cur, cur.Interpolate(end, 1.0/3.0), cur.Interpolate(end, 2.0/3.0), end, 0)
+			}
+			if cmd == closeCmd {
+				q.Close()
+			}
+		case cubeToCmd:
+			mapCubicBezier(q, w.Warp, cur, Point{p.d[i+1], p.d[i+2]}, Point{p.d[i+3], p.d[i+4]}, end, 0)
+		}
+		cur = end
+		i += cmdLen(cmd)
+	}
+	return q
+}
@@ -0,0 +1,72 @@
+package canvas
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestEnvelope(t *testing.T) {
+	rect := Rect{1.0, 2.0, 10.0, 4.0}
+	e := NewEnvelope(rect, [4]Point{{1.0, 2.0}, {11.0, 2.0}, {11.0, 6.0}, {1.0, 6.0}})
+	for _, pos := range []Point{{1.0, 2.0}, {4.0, 3.0}, {11.0, 6.0}, {13.0, 0.0}} {
+		test.T(t, e.Warp(pos), pos)
+	}
+
+	e = NewEnvelope(rect, [4]Point{{0.0, 0.0}, {20.0, 0.0}, {15.0, 10.0}, {5.0, 10.0}})
+	test.T(t, e.Warp(Point{11.0, 2.0}), Point{20.0, 0.0})
+	test.T(t, e.Warp(Point{1.0, 6.0}), Point{5.0, 10.0})
+	test.T(t, e.Warp(Point{6.0, 4.0}), Point{10.0, 5.0})
+
+	e = ArchEnvelope(rect, 2.0)
+	test.T(t, e.Warp(Point{6.0, 2.0}), Point{6.0, 4.0})
+	test.T(t, e.Warp(Point{6.0, 6.0}), Point{6.0, 8.0})
+	test.T(t, e.Warp(Point{1.0, 2.0}), Point{1.0, 2.0})
+	test.T(t, e.Warp(Point{11.0, 4.0}), Point{11.0, 4.0})
+}
+
+func TestMesh(t *testing.T) {
+	rect := Rect{0.0, 0.0, 9.0, 4.0}
+	m := NewMesh(rect, 3, 2)
+	test.T(t, len(m.Points), 3)
+	test.T(t, len(m.Points[0]), 4)
+	for _, pos := range []Point{{0.0, 0.0}, {1.5, 0.5}, {9.0, 4.0}, {10.0, -1.0}} {
+		test.T(t, m.Warp(pos), pos)
+	}
+
+	// the mesh passes through its points and is smooth in between
+	m.Points[1][1] = Point{3.0, 3.0}
+	test.T(t, m.Warp(Point{3.0, 2.0}), Point{3.0, 3.0})
+	test.T(t, m.Warp(Point{6.0, 2.0}), Point{6.0, 2.0})
+	const h = 1e-6
+	left, right := m.Warp(Point{3.0 - h, 1.0}), m.Warp(Point{3.0 + h, 1.0})
+	mid := m.Warp(Point{3.0, 1.0})
+	test.That(t, mid.Sub(left).Sub(right.Sub(mid)).Length() < 1e-9, "continuous derivative")
+
+	test.T(t, NewMesh(rect, 0, 0).Warp(Point{2.0, 3.0}), Point{2.0, 3.0})
+}
+
+func TestPathWarp(t *testing.T) {
+	defer func(tolerance float64) { Tolerance = tolerance }(Tolerance)
+	Tolerance = 0.01
+
+	rect := Rect{0.0, 0.0, 10.0, 5.0}
+	p := Rectangle(10.0, 5.0).Warp(NewEnvelope(rect, [4]Point{{0.0, 0.0}, {10.0, 0.0}, {10.0, 5.0}, {0.0, 5.0}}))
+	test.T(t, p.Bounds(), rect)
+	test.That(t, p.Closed(), "closed")
+
+	// the straight top and bottom of the rectangle are arched, including the segment that closes it
+	e := ArchEnvelope(rect, 2.0)
+	p = MustParseSVG("M0 0L10 0L10 5L0 5z").Warp(e)
+	test.T(t, p.Bounds(), Rect{0.0, 0.0, 10.0, 7.0})
+	for _, pos := range p.Flatten().Coords() {
+		test.That(t, e.Warp(Point{pos.X, 0.0}).Y-Tolerance <= pos.Y, pos)
+	}
+	test.That(t, 4 < len(p.Coords()), "lines are subdivided")
+
+	p = MustParseSVG("M0 0L10 0").Warp(e)
+	for _, pos := range p.Flatten().Coords() {
+		test.That(t, math.Abs(e.Warp(Point{pos.X, 0.0}).Y-pos.Y) < 2.0*Tolerance, pos)
+	}
+}